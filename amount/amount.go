@@ -0,0 +1,311 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package amount converts between *big.Int values and the
+// strings of Rosetta amounts. Amounts are never converted to
+// float64. Parsed values must be canonical (no sign other than
+// a leading "-", no leading zeros, and no "-0") and must fit in
+// 256 bits, like every balance and transfer value on chain.
+package amount
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+// MaxBits is the maximum number of bits
+// of the absolute value of an amount.
+const MaxBits = 256
+
+// maxDigits is the number of digits of 2^256.
+const maxDigits = 78
+
+var (
+	// ErrSyntax is returned when a value
+	// is not a canonical base-10 number.
+	ErrSyntax = errors.New("amount is not a canonical base-10 number")
+
+	// ErrNegativeZero is returned when
+	// a value is "-0" (or "-0.0").
+	ErrNegativeZero = errors.New("amount is negative zero")
+
+	// ErrOverflow is returned when the absolute
+	// value of an amount does not fit in MaxBits.
+	ErrOverflow = errors.New("amount does not fit in 256 bits")
+
+	// ErrNegative is returned when a
+	// value must not be negative.
+	ErrNegative = errors.New("amount is negative")
+
+	// ErrPrecision is returned when a decimal value has more
+	// fractional digits than the decimals of its currency.
+	ErrPrecision = errors.New("amount has more fractional digits than its currency")
+
+	// ErrCurrencyMismatch is returned when an amount
+	// is not in the expected currency.
+	ErrCurrencyMismatch = errors.New("amount is in an unexpected currency")
+
+	// ErrDecimalsInvalid is returned when
+	// currency decimals are negative.
+	ErrDecimalsInvalid = errors.New("currency decimals are negative")
+)
+
+// Parse parses value, an amount in atomic units (i.e.
+// "-1000000000000000000" for -1 CORE).
+func Parse(value string) (*big.Int, error) {
+	negative, digits, err := split(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDigits(value, negative, digits)
+}
+
+// ParseUnsigned parses value like Parse
+// but rejects negative values.
+func ParseUnsigned(value string) (*big.Int, error) {
+	parsed, err := Parse(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsed.Sign() < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNegative, value)
+	}
+
+	return parsed, nil
+}
+
+// ParseHex parses value, an amount in atomic units encoded as
+// a 0x-prefixed hex quantity (i.e. a balance returned by the
+// node). Leading zeros are allowed, as in genesis allocations.
+// Hex quantities are never negative.
+func ParseHex(value string) (*big.Int, error) {
+	if !strings.HasPrefix(value, "0x") && !strings.HasPrefix(value, "0X") {
+		return nil, fmt.Errorf("%w: %q", ErrSyntax, value)
+	}
+
+	parsed, ok := new(big.Int).SetString(value[2:], 16) // nolint:gomnd
+	if !ok || !isHexDigits(value[2:]) {
+		return nil, fmt.Errorf("%w: %q", ErrSyntax, value)
+	}
+
+	if parsed.BitLen() > MaxBits {
+		return nil, fmt.Errorf("%w: %s", ErrOverflow, value)
+	}
+
+	return parsed, nil
+}
+
+// ParseDecimal parses value, an amount in whole units of a
+// currency with decimals (i.e. "-1.5" for -1.5 CORE), into
+// atomic units. value must not have more fractional digits
+// than decimals.
+func ParseDecimal(value string, decimals int32) (*big.Int, error) {
+	if decimals < 0 {
+		return nil, fmt.Errorf("%w: %d", ErrDecimalsInvalid, decimals)
+	}
+
+	negative, digits, err := split(value)
+	if err != nil {
+		return nil, err
+	}
+
+	fraction := ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		digits, fraction = digits[:i], digits[i+1:]
+		if len(fraction) == 0 || !isDigits(fraction) {
+			return nil, fmt.Errorf("%w: %s", ErrSyntax, value)
+		}
+	}
+	if int64(len(fraction)) > int64(decimals) {
+		return nil, fmt.Errorf("%w: %s has more than %d decimals", ErrPrecision, value, decimals)
+	}
+	if !isCanonical(digits) {
+		return nil, fmt.Errorf("%w: %s", ErrSyntax, value)
+	}
+
+	atomic := strings.TrimLeft(digits+fraction+strings.Repeat("0", int(decimals)-len(fraction)), "0")
+	if len(atomic) == 0 {
+		atomic = "0"
+	}
+
+	return parseDigits(value, negative, atomic)
+}
+
+// Format returns the string of value in atomic units.
+// A nil value is formatted as "0".
+func Format(value *big.Int) string {
+	if value == nil {
+		return "0"
+	}
+
+	return value.String()
+}
+
+// FormatNeg returns the string of -value in atomic units.
+// A nil value is formatted as "0".
+func FormatNeg(value *big.Int) string {
+	if value == nil {
+		return "0"
+	}
+
+	return new(big.Int).Neg(value).String()
+}
+
+// FormatDecimal returns the string of value (in atomic units)
+// in whole units of a currency with decimals, without trailing
+// zeros (i.e. "1.5" for 1500000000000000000 with 18 decimals).
+func FormatDecimal(value *big.Int, decimals int32) string {
+	digits := Format(value)
+	if decimals <= 0 {
+		return digits
+	}
+
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	if len(digits) <= int(decimals) {
+		digits = strings.Repeat("0", int(decimals)-len(digits)+1) + digits
+	}
+
+	whole, fraction := digits[:len(digits)-int(decimals)], strings.TrimRight(digits[len(digits)-int(decimals):], "0")
+	if len(fraction) == 0 {
+		return sign + whole
+	}
+
+	return sign + whole + "." + fraction
+}
+
+// New returns a *types.Amount of value in currency.
+func New(value *big.Int, currency *types.Currency) *types.Amount {
+	return &types.Amount{
+		Value:    Format(value),
+		Currency: currency,
+	}
+}
+
+// NewNeg returns a *types.Amount of -value in currency.
+func NewNeg(value *big.Int, currency *types.Currency) *types.Amount {
+	return &types.Amount{
+		Value:    FormatNeg(value),
+		Currency: currency,
+	}
+}
+
+// Value returns the value of a. If currency is not nil, the
+// currency of a must be identical (same symbol, decimals, and
+// metadata): tokens sharing a symbol are distinguished by the
+// contract address in their metadata.
+func Value(a *types.Amount, currency *types.Currency) (*big.Int, error) {
+	if a == nil || a.Currency == nil {
+		return nil, errors.New("amount and its currency must be populated")
+	}
+
+	if a.Currency.Decimals < 0 {
+		return nil, fmt.Errorf("%w: %d", ErrDecimalsInvalid, a.Currency.Decimals)
+	}
+
+	if currency != nil && types.Hash(a.Currency) != types.Hash(currency) {
+		return nil, fmt.Errorf(
+			"%w: %s is not %s",
+			ErrCurrencyMismatch,
+			types.PrintStruct(a.Currency),
+			types.PrintStruct(currency),
+		)
+	}
+
+	return Parse(a.Value)
+}
+
+// split returns whether value is negative and
+// the rest of value, which is not empty.
+func split(value string) (bool, string, error) {
+	digits := strings.TrimPrefix(value, "-")
+	if len(digits) == 0 {
+		return false, "", fmt.Errorf("%w: %q", ErrSyntax, value)
+	}
+
+	return len(digits) < len(value), digits, nil
+}
+
+// parseDigits parses digits, which must be canonical,
+// and negates the result if negative.
+func parseDigits(value string, negative bool, digits string) (*big.Int, error) {
+	if !isCanonical(digits) {
+		return nil, fmt.Errorf("%w: %q", ErrSyntax, value)
+	}
+
+	if len(digits) > maxDigits {
+		return nil, fmt.Errorf("%w: %s", ErrOverflow, value)
+	}
+
+	parsed, ok := new(big.Int).SetString(digits, 10) // nolint:gomnd
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrSyntax, value)
+	}
+
+	if parsed.BitLen() > MaxBits {
+		return nil, fmt.Errorf("%w: %s", ErrOverflow, value)
+	}
+
+	if negative {
+		if parsed.Sign() == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrNegativeZero, value)
+		}
+		parsed.Neg(parsed)
+	}
+
+	return parsed, nil
+}
+
+// isCanonical returns whether digits is a
+// non-empty number without leading zeros.
+func isCanonical(digits string) bool {
+	return isDigits(digits) && (digits == "0" || digits[0] != '0')
+}
+
+func isHexDigits(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') && (c < 'A' || c > 'F') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isDigits(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,284 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package amount
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	core = &types.Currency{Symbol: "CORE", Decimals: 18}
+
+	// maxValue is 2^256-1.
+	maxValue = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), MaxBits), big.NewInt(1))
+)
+
+func mustBig(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic(s)
+	}
+
+	return v
+}
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		value  string
+		result *big.Int
+		err    error
+	}{
+		"zero":             {value: "0", result: big.NewInt(0)},
+		"positive":         {value: "1000000000000000000", result: mustBig("1000000000000000000")},
+		"negative":         {value: "-1", result: big.NewInt(-1)},
+		"max":              {value: maxValue.String(), result: maxValue},
+		"min":              {value: "-" + maxValue.String(), result: new(big.Int).Neg(maxValue)},
+		"beyond int64":     {value: "9223372036854775808", result: mustBig("9223372036854775808")},
+		"empty":            {value: "", err: ErrSyntax},
+		"sign only":        {value: "-", err: ErrSyntax},
+		"double sign":      {value: "--1", err: ErrSyntax},
+		"plus sign":        {value: "+1", err: ErrSyntax},
+		"leading zero":     {value: "01", err: ErrSyntax},
+		"double zero":      {value: "00", err: ErrSyntax},
+		"negative leading": {value: "-01", err: ErrSyntax},
+		"negative zero":    {value: "-0", err: ErrNegativeZero},
+		"whitespace":       {value: " 1", err: ErrSyntax},
+		"trailing space":   {value: "1 ", err: ErrSyntax},
+		"decimal":          {value: "1.0", err: ErrSyntax},
+		"exponent":         {value: "1e18", err: ErrSyntax},
+		"hex":              {value: "0x10", err: ErrSyntax},
+		"underscore":       {value: "1_000", err: ErrSyntax},
+		"overflow":         {value: new(big.Int).Add(maxValue, big.NewInt(1)).String(), err: ErrOverflow},
+		"negative overflow": {
+			value: "-" + new(big.Int).Add(maxValue, big.NewInt(1)).String(),
+			err:   ErrOverflow,
+		},
+		"very long": {value: strings.Repeat("9", 10000), err: ErrOverflow},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := Parse(test.value)
+			if test.err != nil {
+				assert.Nil(t, result)
+				assert.True(t, errors.Is(err, test.err), err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, 0, test.result.Cmp(result))
+			assert.Equal(t, test.value, Format(result))
+		})
+	}
+}
+
+func TestParseUnsigned(t *testing.T) {
+	result, err := ParseUnsigned("10")
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(10), result)
+
+	result, err = ParseUnsigned("0")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Sign())
+
+	_, err = ParseUnsigned("-10")
+	assert.True(t, errors.Is(err, ErrNegative))
+
+	_, err = ParseUnsigned("-0")
+	assert.True(t, errors.Is(err, ErrNegativeZero))
+}
+
+func TestParseHex(t *testing.T) {
+	tests := map[string]struct {
+		value    string
+		expected *big.Int
+		err      error
+	}{
+		"zero":          {value: "0x0", expected: big.NewInt(0)},
+		"balance":       {value: "0xde0b6b3a7640000", expected: mustBig("1000000000000000000")},
+		"leading zeros": {value: "0x00ff", expected: big.NewInt(255)},
+		"upper case":    {value: "0XFF", expected: big.NewInt(255)},
+		"max": {
+			value:    "0x" + strings.Repeat("f", 64),
+			expected: new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), MaxBits), big.NewInt(1)),
+		},
+		"overflow":      {value: "0x1" + strings.Repeat("0", 64), err: ErrOverflow},
+		"no prefix":     {value: "ff", err: ErrSyntax},
+		"empty":         {value: "0x", err: ErrSyntax},
+		"negative":      {value: "-0x1", err: ErrSyntax},
+		"signed digits": {value: "0x-1", err: ErrSyntax},
+		"not hex":       {value: "0xg", err: ErrSyntax},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := ParseHex(test.value)
+			if test.err != nil {
+				assert.Nil(t, result)
+				assert.True(t, errors.Is(err, test.err))
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, 0, test.expected.Cmp(result))
+		})
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := map[string]struct {
+		value    string
+		decimals int32
+		result   *big.Int
+		err      error
+	}{
+		"whole":             {value: "1", decimals: 18, result: mustBig("1000000000000000000")},
+		"fraction":          {value: "1.5", decimals: 18, result: mustBig("1500000000000000000")},
+		"smallest unit":     {value: "0.000000000000000001", decimals: 18, result: big.NewInt(1)},
+		"negative fraction": {value: "-0.5", decimals: 18, result: mustBig("-500000000000000000")},
+		"trailing zeros":    {value: "2.50", decimals: 2, result: big.NewInt(250)},
+		"zero":              {value: "0.0", decimals: 1, result: big.NewInt(0)},
+		"no decimals":       {value: "42", decimals: 0, result: big.NewInt(42)},
+		"too precise":       {value: "0.0000000000000000001", decimals: 18, err: ErrPrecision},
+		"fraction without decimals": {
+			value:    "1.0",
+			decimals: 0,
+			err:      ErrPrecision,
+		},
+		"negative zero":     {value: "-0.0", decimals: 18, err: ErrNegativeZero},
+		"missing whole":     {value: ".5", decimals: 18, err: ErrSyntax},
+		"missing fraction":  {value: "5.", decimals: 18, err: ErrSyntax},
+		"two points":        {value: "1.2.3", decimals: 18, err: ErrSyntax},
+		"leading zero":      {value: "01.5", decimals: 18, err: ErrSyntax},
+		"signed fraction":   {value: "1.-5", decimals: 18, err: ErrSyntax},
+		"negative decimals": {value: "1", decimals: -1, err: ErrDecimalsInvalid},
+		"overflow": {
+			value:    maxValue.String(),
+			decimals: 1,
+			err:      ErrOverflow,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := ParseDecimal(test.value, test.decimals)
+			if test.err != nil {
+				assert.Nil(t, result)
+				assert.True(t, errors.Is(err, test.err), err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, 0, test.result.Cmp(result))
+		})
+	}
+}
+
+func TestFormatDecimal(t *testing.T) {
+	tests := map[string]struct {
+		value    *big.Int
+		decimals int32
+		result   string
+	}{
+		"whole":         {value: mustBig("1000000000000000000"), decimals: 18, result: "1"},
+		"fraction":      {value: mustBig("1500000000000000000"), decimals: 18, result: "1.5"},
+		"smallest unit": {value: big.NewInt(1), decimals: 18, result: "0.000000000000000001"},
+		"negative":      {value: big.NewInt(-250), decimals: 3, result: "-0.25"},
+		"zero":          {value: big.NewInt(0), decimals: 18, result: "0"},
+		"nil":           {decimals: 18, result: "0"},
+		"no decimals":   {value: big.NewInt(42), decimals: 0, result: "42"},
+		"max":           {value: maxValue, decimals: 0, result: maxValue.String()},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := FormatDecimal(test.value, test.decimals)
+			assert.Equal(t, test.result, result)
+
+			if test.value == nil {
+				return
+			}
+			parsed, err := ParseDecimal(result, test.decimals)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, test.value.Cmp(parsed))
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "0", Format(nil))
+	assert.Equal(t, "0", FormatNeg(nil))
+	assert.Equal(t, "0", FormatNeg(big.NewInt(0)))
+	assert.Equal(t, "-5", FormatNeg(big.NewInt(5)))
+	assert.Equal(t, "5", FormatNeg(big.NewInt(-5)))
+
+	value := big.NewInt(7)
+	assert.Equal(t, &types.Amount{Value: "7", Currency: core}, New(value, core))
+	assert.Equal(t, &types.Amount{Value: "-7", Currency: core}, NewNeg(value, core))
+	assert.Equal(t, big.NewInt(7), value)
+}
+
+func TestValue(t *testing.T) {
+	value, err := Value(&types.Amount{Value: "-7", Currency: core}, core)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(-7), value)
+
+	value, err = Value(&types.Amount{Value: "7", Currency: &types.Currency{Symbol: "TKN"}}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(7), value)
+
+	_, err = Value(&types.Amount{Value: "7", Currency: &types.Currency{Symbol: "CORE", Decimals: 8}}, core)
+	assert.True(t, errors.Is(err, ErrCurrencyMismatch))
+
+	// Tokens sharing a symbol are distinct currencies.
+	usdt := &types.Currency{
+		Symbol:   "USDT",
+		Decimals: 6,
+		Metadata: map[string]interface{}{"token_address": "0x900101d06A7426441Ae63e9AB3B9b0F63Be145F1"},
+	}
+	fakeUSDT := &types.Currency{
+		Symbol:   "USDT",
+		Decimals: 6,
+		Metadata: map[string]interface{}{"token_address": "0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d"},
+	}
+	_, err = Value(&types.Amount{Value: "7", Currency: fakeUSDT}, usdt)
+	assert.True(t, errors.Is(err, ErrCurrencyMismatch))
+	_, err = Value(
+		&types.Amount{Value: "7", Currency: &types.Currency{Symbol: "usdt", Decimals: 6}},
+		&types.Currency{Symbol: "USDT", Decimals: 6},
+	)
+	assert.True(t, errors.Is(err, ErrCurrencyMismatch))
+	value, err = Value(&types.Amount{Value: "7", Currency: usdt}, usdt)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(7), value)
+
+	_, err = Value(&types.Amount{Value: "7", Currency: &types.Currency{Symbol: "TKN", Decimals: -1}}, nil)
+	assert.True(t, errors.Is(err, ErrDecimalsInvalid))
+
+	_, err = Value(&types.Amount{Value: "-0", Currency: core}, core)
+	assert.True(t, errors.Is(err, ErrNegativeZero))
+
+	_, err = Value(&types.Amount{Value: "7"}, nil)
+	assert.Error(t, err)
+
+	_, err = Value(nil, core)
+	assert.Error(t, err)
+}
@@ -0,0 +1,349 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package archive exports converted blocks to object storage
+// (S3, GCS, or a local directory) and serves them back, so
+// historical blocks can be served without the node.
+//
+// An archive contains:
+//
+//	manifest.json                 the Manifest
+//	segments/<start>.json         a Segment for every SegmentSize blocks
+//	blocks/<index>.json           the converted *types.Block at every index
+//
+// Indices are zero-padded to 12 digits so keys sort by index.
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/metrics"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+const (
+	// Confirmations is the number of blocks a block must be
+	// buried under before it is archived, so archived blocks
+	// never need to be rewritten after a reorg.
+	Confirmations = 30
+
+	// SegmentSize is the number of blocks
+	// listed in each segment manifest.
+	SegmentSize = 1000
+
+	// pollInterval is how long the exporter waits before
+	// checking for new blocks once it has caught up.
+	pollInterval = 5 * time.Second
+
+	manifestKey = "manifest.json"
+
+	headMetric = "block_archive/head"
+)
+
+var (
+	// ErrParentMismatch is returned when a block does
+	// not build on the last archived block.
+	ErrParentMismatch = errors.New("block does not build on last archived block")
+
+	// ErrNetworkMismatch is returned when an archive
+	// was written for another network.
+	ErrNetworkMismatch = errors.New("archive was written for another network")
+)
+
+// Client is used by the exporter to fetch blocks.
+type Client interface {
+	Status(context.Context) (
+		*types.BlockIdentifier,
+		int64,
+		*types.SyncStatus,
+		[]*types.Peer,
+		error,
+	)
+
+	Block(
+		context.Context,
+		*types.PartialBlockIdentifier,
+	) (*types.Block, error)
+}
+
+// Manifest describes the contents of an archive. Every
+// block from the genesis block up to Head is archived.
+type Manifest struct {
+	Network     *types.NetworkIdentifier `json:"network_identifier"`
+	Head        *types.BlockIdentifier   `json:"head_block_identifier,omitempty"`
+	SegmentSize int64                    `json:"segment_size"`
+
+	// UpdatedAt is the time the manifest
+	// was written, in milliseconds.
+	UpdatedAt int64 `json:"updated_at"`
+}
+
+// Segment lists the blocks archived from Start
+// to Start+SegmentSize-1.
+type Segment struct {
+	Start  int64                    `json:"start_index"`
+	Blocks []*types.BlockIdentifier `json:"blocks"`
+}
+
+// Archive exports blocks to a Store (see Run)
+// and serves them back (see Block).
+type Archive struct {
+	store   Store
+	client  Client
+	network *types.NetworkIdentifier
+
+	manifest *Manifest
+	segment  *Segment
+}
+
+// New creates an *Archive of network blocks backed by
+// store. client is only used to export blocks and can
+// be nil if the archive is only read.
+func New(store Store, client Client, network *types.NetworkIdentifier) *Archive {
+	return &Archive{
+		store:   store,
+		client:  client,
+		network: network,
+	}
+}
+
+// Manifest returns the manifest of the archive. If the archive
+// is empty, a manifest without a Head is returned. If the archive
+// was written for another network, ErrNetworkMismatch is returned.
+func (a *Archive) Manifest(ctx context.Context) (*Manifest, error) {
+	var manifest Manifest
+	err := a.get(ctx, manifestKey, &manifest)
+	if errors.Is(err, ErrNotFound) {
+		return &Manifest{Network: a.network, SegmentSize: SegmentSize}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get manifest", err)
+	}
+
+	if types.Hash(manifest.Network) != types.Hash(a.network) {
+		return nil, fmt.Errorf(
+			"%w: %s is not %s",
+			ErrNetworkMismatch,
+			types.PrintStruct(manifest.Network),
+			types.PrintStruct(a.network),
+		)
+	}
+
+	return &manifest, nil
+}
+
+// Block returns the archived block at index. If the
+// block is not archived, ErrNotFound is returned.
+func (a *Archive) Block(ctx context.Context, index int64) (*types.Block, error) {
+	var block types.Block
+	if err := a.get(ctx, blockKey(index), &block); err != nil {
+		return nil, err
+	}
+
+	return &block, nil
+}
+
+// Run exports confirmed blocks until ctx is canceled,
+// resuming after the head of the manifest.
+func (a *Archive) Run(ctx context.Context) error {
+	for {
+		if err := a.sync(ctx); err != nil {
+			if errors.Is(err, ErrParentMismatch) || errors.Is(err, ErrNetworkMismatch) {
+				return err
+			}
+
+			if ctx.Err() == nil {
+				log.Printf("block archive export failed: %s", err.Error())
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// sync exports all blocks that are confirmed but not
+// yet archived. The manifest is written whenever a
+// segment is completed and once all blocks are exported.
+func (a *Archive) sync(ctx context.Context) error {
+	if err := a.load(ctx); err != nil {
+		return err
+	}
+
+	head, _, _, _, err := a.client.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: unable to get chain head", err)
+	}
+
+	next := int64(0)
+	if a.manifest.Head != nil {
+		next = a.manifest.Head.Index + 1
+	}
+
+	exported := false
+	for ; next <= head.Index-Confirmations; next++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		index := next
+		block, err := a.client.Block(ctx, &types.PartialBlockIdentifier{Index: &index})
+		if err != nil {
+			return fmt.Errorf("%w: unable to get block %d", err, index)
+		}
+
+		if err := a.ExportBlock(ctx, block); err != nil {
+			return fmt.Errorf("%w: unable to export block %d", err, index)
+		}
+		exported = true
+
+		if len(a.segment.Blocks) == SegmentSize {
+			if err := a.flush(ctx); err != nil {
+				return err
+			}
+			exported = false
+		}
+	}
+
+	if exported {
+		return a.flush(ctx)
+	}
+
+	return nil
+}
+
+// load reads the manifest and the segment of the
+// block after its head, if they are not loaded yet.
+func (a *Archive) load(ctx context.Context) error {
+	if a.manifest != nil {
+		return nil
+	}
+
+	manifest, err := a.Manifest(ctx)
+	if err != nil {
+		return err
+	}
+
+	next := int64(0)
+	if manifest.Head != nil {
+		next = manifest.Head.Index + 1
+	}
+
+	segment := &Segment{Start: next - next%SegmentSize}
+	if next%SegmentSize != 0 {
+		if err := a.get(ctx, segmentKey(segment.Start), segment); err != nil {
+			return fmt.Errorf("%w: unable to get segment %d", err, segment.Start)
+		}
+
+		if int64(len(segment.Blocks)) < next-segment.Start {
+			return fmt.Errorf("segment %d does not reach the head of the manifest", segment.Start)
+		}
+
+		// Blocks written after the manifest are
+		// exported again.
+		segment.Blocks = segment.Blocks[:next-segment.Start]
+	}
+
+	a.manifest = manifest
+	a.segment = segment
+	return nil
+}
+
+// ExportBlock writes block to the archive. The block must
+// build on the head of the manifest. The manifest is not
+// written until the next flush.
+func (a *Archive) ExportBlock(ctx context.Context, block *types.Block) error {
+	if err := a.load(ctx); err != nil {
+		return err
+	}
+
+	head := a.manifest.Head
+	if (head == nil && block.BlockIdentifier.Index != 0) ||
+		(head != nil && (block.BlockIdentifier.Index != head.Index+1 ||
+			block.ParentBlockIdentifier.Hash != head.Hash)) {
+		return fmt.Errorf(
+			"%w: %s does not build on %s",
+			ErrParentMismatch,
+			types.PrintStruct(block.BlockIdentifier),
+			types.PrintStruct(head),
+		)
+	}
+
+	if err := a.put(ctx, blockKey(block.BlockIdentifier.Index), block); err != nil {
+		return err
+	}
+
+	if len(a.segment.Blocks) == SegmentSize {
+		a.segment = &Segment{Start: block.BlockIdentifier.Index}
+	}
+	a.segment.Blocks = append(a.segment.Blocks, block.BlockIdentifier)
+	a.manifest.Head = block.BlockIdentifier
+
+	return nil
+}
+
+// flush writes the current segment and then the manifest,
+// so the manifest never references missing blocks.
+func (a *Archive) flush(ctx context.Context) error {
+	if err := a.put(ctx, segmentKey(a.segment.Start), a.segment); err != nil {
+		return fmt.Errorf("%w: unable to put segment %d", err, a.segment.Start)
+	}
+
+	a.manifest.UpdatedAt = time.Now().UnixNano() / int64(time.Millisecond)
+	if err := a.put(ctx, manifestKey, a.manifest); err != nil {
+		return fmt.Errorf("%w: unable to put manifest", err)
+	}
+	metrics.Gauge(headMetric).Update(a.manifest.Head.Index)
+
+	return nil
+}
+
+func (a *Archive) get(ctx context.Context, key string, v interface{}) error {
+	body, err := a.store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("%w: unable to decode %s", err, key)
+	}
+
+	return nil
+}
+
+func (a *Archive) put(ctx context.Context, key string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("%w: unable to encode %s", err, key)
+	}
+
+	return a.store.Put(ctx, key, body)
+}
+
+func blockKey(index int64) string {
+	return fmt.Sprintf("blocks/%012d.json", index)
+}
+
+func segmentKey(start int64) string {
+	return fmt.Sprintf("segments/%012d.json", start)
+}
@@ -0,0 +1,172 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/archive"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var network = &types.NetworkIdentifier{
+	Blockchain: "Corechain",
+	Network:    "Core",
+}
+
+// memoryStore is a Store kept in memory.
+type memoryStore struct {
+	mutex   sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{objects: map[string][]byte{}}
+}
+
+func (s *memoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	body, ok := s.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+
+	return body, nil
+}
+
+func (s *memoryStore) Put(ctx context.Context, key string, body []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.objects[key] = body
+	return nil
+}
+
+func blockIdentifier(index int64) *types.BlockIdentifier {
+	return &types.BlockIdentifier{
+		Index: index,
+		Hash:  fmt.Sprintf("block %d", index),
+	}
+}
+
+func block(index int64) *types.Block {
+	parent := blockIdentifier(index - 1)
+	if index == 0 {
+		parent = blockIdentifier(0)
+	}
+
+	return &types.Block{
+		BlockIdentifier:       blockIdentifier(index),
+		ParentBlockIdentifier: parent,
+		Timestamp:             index * 1000,
+	}
+}
+
+// mockChain returns a client on a chain of blocks with head at head.
+func mockChain(head int64) *mocks.Client {
+	client := &mocks.Client{}
+	client.On("Status", mock.Anything).Return(blockIdentifier(head), int64(0), nil, nil, nil)
+	client.On("Block", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, blockIdentifier *types.PartialBlockIdentifier) *types.Block {
+			return block(*blockIdentifier.Index)
+		},
+		nil,
+	)
+
+	return client
+}
+
+func TestArchive_Sync(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+
+	a := New(store, mockChain(SegmentSize+4+Confirmations), network)
+	assert.NoError(t, a.sync(ctx))
+
+	manifest, err := a.Manifest(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, network, manifest.Network)
+	assert.Equal(t, blockIdentifier(SegmentSize+4), manifest.Head)
+	assert.Equal(t, int64(SegmentSize), manifest.SegmentSize)
+
+	var segment Segment
+	assert.NoError(t, a.get(ctx, segmentKey(0), &segment))
+	assert.Len(t, segment.Blocks, SegmentSize)
+	assert.NoError(t, a.get(ctx, segmentKey(SegmentSize), &segment))
+	assert.Equal(t, int64(SegmentSize), segment.Start)
+	assert.Len(t, segment.Blocks, 5)
+
+	archived, err := a.Block(ctx, SegmentSize+4)
+	assert.NoError(t, err)
+	assert.Equal(t, block(SegmentSize+4), archived)
+
+	// Unconfirmed blocks are not archived.
+	_, err = a.Block(ctx, SegmentSize+5)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	// A new exporter resumes after the head of the manifest.
+	a = New(store, mockChain(SegmentSize+6+Confirmations), network)
+	assert.NoError(t, a.sync(ctx))
+
+	manifest, err = a.Manifest(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, blockIdentifier(SegmentSize+6), manifest.Head)
+	assert.NoError(t, a.get(ctx, segmentKey(SegmentSize), &segment))
+	assert.Equal(t, []*types.BlockIdentifier{
+		blockIdentifier(SegmentSize),
+		blockIdentifier(SegmentSize + 1),
+		blockIdentifier(SegmentSize + 2),
+		blockIdentifier(SegmentSize + 3),
+		blockIdentifier(SegmentSize + 4),
+		blockIdentifier(SegmentSize + 5),
+		blockIdentifier(SegmentSize + 6),
+	}, segment.Blocks)
+}
+
+func TestArchive_ExportBlock_ParentMismatch(t *testing.T) {
+	ctx := context.Background()
+	a := New(newMemoryStore(), nil, network)
+
+	err := a.ExportBlock(ctx, block(1))
+	assert.True(t, errors.Is(err, ErrParentMismatch))
+
+	assert.NoError(t, a.ExportBlock(ctx, block(0)))
+	orphan := block(1)
+	orphan.ParentBlockIdentifier.Hash = "orphan"
+	err = a.ExportBlock(ctx, orphan)
+	assert.True(t, errors.Is(err, ErrParentMismatch))
+}
+
+func TestArchive_NetworkMismatch(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	assert.NoError(t, New(store, mockChain(Confirmations), network).sync(ctx))
+
+	a := New(store, nil, &types.NetworkIdentifier{
+		Blockchain: "Corechain",
+		Network:    "Buffalo",
+	})
+	_, err := a.Manifest(ctx)
+	assert.True(t, errors.Is(err, ErrNetworkMismatch))
+}
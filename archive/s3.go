@@ -0,0 +1,257 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// s3Timeout is the timeout of a single object request.
+	s3Timeout = 60 * time.Second
+
+	// gcsEndpoint is the S3-compatible XML API of Google Cloud
+	// Storage. It accepts requests signed with GCS HMAC keys.
+	gcsEndpoint = "https://storage.googleapis.com"
+
+	// amzDateFormat is the format of the x-amz-date header.
+	amzDateFormat = "20060102T150405Z"
+)
+
+// s3Store is a Store backed by an S3 bucket. Google Cloud
+// Storage buckets are accessed through its S3-compatible
+// XML API.
+type s3Store struct {
+	endpoint *url.URL
+	bucket   string
+	prefix   string
+
+	// virtualHost is true if the bucket is part of
+	// the host rather than the path of requests.
+	virtualHost bool
+
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+
+	client *http.Client
+	now    func() time.Time
+}
+
+// newS3Store returns a Store for bucket. Credentials are read
+// from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and (optionally)
+// AWS_SESSION_TOKEN. For GCS buckets (scheme "gs"), these must be
+// GCS HMAC keys. For S3 buckets, the region is read from AWS_REGION
+// (or AWS_DEFAULT_REGION) and AWS_ENDPOINT_URL can be set to use an
+// S3-compatible service instead of Amazon S3.
+func newS3Store(scheme string, bucket string, prefix string) (*s3Store, error) {
+	s := &s3Store{
+		bucket:       bucket,
+		prefix:       prefix,
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		client:       &http.Client{Timeout: s3Timeout},
+		now:          time.Now,
+	}
+	if len(s.accessKey) == 0 || len(s.secretKey) == 0 {
+		return nil, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be populated")
+	}
+
+	endpoint := os.Getenv("AWS_ENDPOINT_URL")
+	s.region = os.Getenv("AWS_REGION")
+	if len(s.region) == 0 {
+		s.region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if len(s.region) == 0 {
+		s.region = "us-east-1"
+	}
+
+	switch {
+	case scheme == "gs":
+		endpoint = gcsEndpoint
+		s.region = "auto"
+	case len(endpoint) == 0:
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", s.region)
+		s.virtualHost = true
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to parse endpoint %s", err, endpoint)
+	}
+	s.endpoint = u
+
+	return s, nil
+}
+
+// objectURL returns the URL of the object at key.
+func (s *s3Store) objectURL(key string) *url.URL {
+	if len(s.prefix) > 0 {
+		key = s.prefix + "/" + key
+	}
+
+	u := *s.endpoint
+	if s.virtualHost {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = "/" + key
+	} else {
+		u.Path = "/" + s.bucket + "/" + key
+	}
+
+	return &u
+}
+
+// Get implements Store.
+func (s *s3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	body, err := s.do(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// Put implements Store.
+func (s *s3Store) Put(ctx context.Context, key string, body []byte) error {
+	_, err := s.do(ctx, http.MethodPut, key, body)
+	return err
+}
+
+// do sends a signed request for the object at key
+// and returns the body of the response.
+func (s *s3Store) do(ctx context.Context, method string, key string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key).String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	s.sign(req, body)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to read response", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	case method == http.MethodGet && resp.StatusCode == http.StatusForbidden &&
+		bytes.Contains(respBody, []byte("<Code>AccessDenied</Code>")):
+		// Without permission to list the bucket, S3 denies
+		// access to missing keys instead of reporting them
+		// missing. Invalid credentials and signatures have
+		// other error codes.
+		return nil, fmt.Errorf("%w: %s (access denied)", ErrNotFound, key)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%s %s returned %d: %s", method, key, resp.StatusCode, respBody)
+	}
+
+	return respBody, nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization
+// header to req, signing the host and every header
+// already set on req.
+func (s *s3Store) sign(req *http.Request, body []byte) {
+	now := s.now().UTC()
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", now.Format(amzDateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if len(s.sessionToken) > 0 {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	date := now.Format("20060102")
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format(amzDateFormat),
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey,
+		scope,
+		signedHeaders,
+		signature,
+	))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data)) // nolint:errcheck
+	return mac.Sum(nil)
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound is returned by a Store when
+// an object does not exist.
+var ErrNotFound = errors.New("archive object not found")
+
+// Store is the object storage an archive is written to.
+// Keys are slash-separated paths relative to the root
+// of the archive (i.e. "blocks/000000000042.json").
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, body []byte) error
+}
+
+// OpenStore returns the Store at rawURL, which is one of:
+//
+//	s3://<bucket>/<prefix>     an Amazon S3 (or S3-compatible) bucket
+//	gs://<bucket>/<prefix>     a Google Cloud Storage bucket
+//	file:///<path>             a local directory
+//
+// S3 and GCS credentials are read from the environment
+// (see newS3Store).
+func OpenStore(rawURL string) (Store, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to parse archive url", err)
+	}
+
+	switch u.Scheme {
+	case "s3", "gs":
+		if len(u.Host) == 0 {
+			return nil, fmt.Errorf("%s is missing a bucket", rawURL)
+		}
+
+		return newS3Store(u.Scheme, u.Host, strings.Trim(u.Path, "/"))
+	case "file":
+		if len(u.Path) == 0 {
+			return nil, fmt.Errorf("%s is missing a path", rawURL)
+		}
+
+		return &fileStore{root: u.Path}, nil
+	default:
+		return nil, fmt.Errorf("%s is not a supported archive url (s3://, gs://, or file://)", rawURL)
+	}
+}
+
+// fileStore is a Store backed by a local directory.
+type fileStore struct {
+	root string
+}
+
+// Get implements Store.
+func (s *fileStore) Get(ctx context.Context, key string) ([]byte, error) {
+	body, err := ioutil.ReadFile(filepath.Join(s.root, filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+
+	return body, err
+}
+
+// Put implements Store. Objects are written to a temporary
+// file first, so readers never see a partial object.
+func (s *fileStore) Put(ctx context.Context, key string, body []byte) error {
+	path := filepath.Join(s.root, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, body, 0600); err != nil { // nolint:gomnd
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
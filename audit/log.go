@@ -0,0 +1,259 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// genesisHash is the PreviousHash of the
+// first record of a log.
+var genesisHash = strings.Repeat("0", sha256.Size*2)
+
+// ErrChainBroken is returned when a record of a
+// log does not chain to the record before it.
+var ErrChainBroken = errors.New("audit log hash chain is broken")
+
+// Recipient is an account credited by
+// an audited transaction.
+type Recipient struct {
+	Address string `json:"address"`
+	Amount  string `json:"amount"`
+}
+
+// AccessTuple is an entry of the access
+// list of an audited transaction.
+type AccessTuple struct {
+	Address     string   `json:"address"`
+	StorageKeys []string `json:"storage_keys"`
+}
+
+// Intent is every signed field of an audited
+// transaction (everything but the signature).
+// Fee fields that do not apply to the Type of
+// the transaction are omitted.
+type Intent struct {
+	Type       uint8          `json:"type"`
+	ChainID    string         `json:"chain_id"`
+	Nonce      uint64         `json:"nonce"`
+	To         string         `json:"to,omitempty"`
+	Value      string         `json:"value"`
+	Data       string         `json:"data"`
+	Gas        uint64         `json:"gas"`
+	GasPrice   string         `json:"gas_price,omitempty"`
+	GasFeeCap  string         `json:"max_fee_per_gas,omitempty"`
+	GasTipCap  string         `json:"max_priority_fee_per_gas,omitempty"`
+	AccessList []*AccessTuple `json:"access_list,omitempty"`
+}
+
+// Entry is the audited content of a submitted
+// transaction. It never contains the signature.
+//
+// Intent is omitted from the records written
+// before it was recorded, so that they still
+// verify.
+type Entry struct {
+	Timestamp       string       `json:"timestamp"`
+	TransactionHash string       `json:"transaction_hash"`
+	Sender          string       `json:"sender"`
+	Recipients      []*Recipient `json:"recipients"`
+	Intent          *Intent      `json:"intent,omitempty"`
+}
+
+// record is a line of the log. Hash commits to the
+// Entry and to the Hash of the previous record, so
+// modifying, removing, or reordering any record breaks
+// the chain of every record after it.
+type record struct {
+	Entry
+	PreviousHash string `json:"previous_hash"`
+	Hash         string `json:"hash"`
+}
+
+// Log is an append-only, hash-chained log of
+// submitted transactions. When a key is provided,
+// records are chained with HMAC-SHA256 so that the
+// log cannot be rewritten without the key.
+type Log struct {
+	file *os.File
+	key  []byte
+	now  func() time.Time
+
+	mutex    sync.Mutex
+	lastHash string
+}
+
+// Open opens (or creates) the log at path. The existing
+// records are verified before any record is appended. A
+// torn last record (see verify) is truncated, so that the
+// next record starts on its own line.
+func Open(path string, key []byte) (*Log, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to open audit log %s", err, path)
+	}
+
+	lastHash, size, err := verify(file, key)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%w: unable to verify audit log %s", err, path)
+	}
+
+	if err := file.Truncate(size); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%w: unable to truncate audit log %s", err, path)
+	}
+
+	return &Log{
+		file:     file,
+		key:      key,
+		now:      time.Now,
+		lastHash: lastHash,
+	}, nil
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	return l.file.Close()
+}
+
+// Record appends entry to the log. The Timestamp of entry
+// is populated with the current time. The record is synced
+// to disk before Record returns.
+func (l *Log) Record(entry *Entry) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	r := &record{
+		Entry:        *entry,
+		PreviousHash: l.lastHash,
+	}
+	r.Timestamp = l.now().UTC().Format(time.RFC3339Nano)
+
+	h, err := recordHash(l.key, r)
+	if err != nil {
+		return err
+	}
+	r.Hash = h
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("%w: unable to marshal audit record", err)
+	}
+
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("%w: unable to write audit record", err)
+	}
+
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("%w: unable to sync audit log", err)
+	}
+
+	l.lastHash = r.Hash
+	return nil
+}
+
+// Verify checks that every record of the log
+// at path is correctly chained using key. A torn
+// last record (see verify) is ignored.
+func Verify(path string, key []byte) error {
+	file, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return fmt.Errorf("%w: unable to open audit log %s", err, path)
+	}
+	defer file.Close()
+
+	_, _, err = verify(file, key)
+	return err
+}
+
+// verify checks the chain of records in r and returns
+// the Hash of the last record and the size of the log
+// up to the end of that record.
+//
+// Records are written with their newline in a single
+// write, so a last line without a newline is a record
+// torn by a crash before Record returned (which was
+// therefore never acknowledged). It is not verified.
+func verify(r io.Reader, key []byte) (string, int64, error) {
+	lastHash := genesisHash
+	var size int64
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		content, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return lastHash, size, nil
+		}
+		if err != nil {
+			return "", 0, fmt.Errorf("%w: unable to read audit log", err)
+		}
+
+		var rec record
+		if err := json.Unmarshal(content, &rec); err != nil {
+			return "", 0, fmt.Errorf("%w: unable to parse audit record on line %d", err, line)
+		}
+
+		if rec.PreviousHash != lastHash {
+			return "", 0, fmt.Errorf(
+				"%w: record on line %d does not follow the previous record",
+				ErrChainBroken,
+				line,
+			)
+		}
+
+		expected, err := recordHash(key, &rec)
+		if err != nil {
+			return "", 0, err
+		}
+		if !hmac.Equal([]byte(expected), []byte(rec.Hash)) {
+			return "", 0, fmt.Errorf("%w: record on line %d has been modified", ErrChainBroken, line)
+		}
+
+		lastHash = rec.Hash
+		size += int64(len(content))
+	}
+}
+
+// recordHash computes the Hash of r over its PreviousHash
+// and the JSON encoding of its Entry.
+func recordHash(key []byte, r *record) (string, error) {
+	entry, err := json.Marshal(&r.Entry)
+	if err != nil {
+		return "", fmt.Errorf("%w: unable to marshal audit entry", err)
+	}
+
+	var h hash.Hash
+	if len(key) > 0 {
+		h = hmac.New(sha256.New, key)
+	} else {
+		h = sha256.New()
+	}
+
+	h.Write([]byte(r.PreviousHash))
+	h.Write(entry)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// entries are a record written before intents were
+// recorded and a record of a dynamic fee transaction.
+var entries = []*Entry{
+	{
+		TransactionHash: "0xc3b6b5f8e4a5c4c1b9d4f2b1a8c0e7d6b5a4c3b2a1f0e9d8c7b6a5f4e3d2c1b0",
+		Sender:          "0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309",
+		Recipients: []*Recipient{
+			{
+				Address: "0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d",
+				Amount:  "1000",
+			},
+		},
+	},
+	{
+		TransactionHash: "0x0e9d8c7b6a5f4e3d2c1b0c3b6b5f8e4a5c4c1b9d4f2b1a8c0e7d6b5a4c3b2a1f",
+		Sender:          "0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d",
+		Recipients: []*Recipient{
+			{
+				Address: "0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309",
+				Amount:  "42",
+			},
+		},
+		Intent: &Intent{
+			Type:      2,
+			ChainID:   "1116",
+			Nonce:     7,
+			To:        "0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309",
+			Value:     "42",
+			Data:      "0x",
+			Gas:       21000,
+			GasFeeCap: "2000000000",
+			GasTipCap: "1000000000",
+		},
+	},
+}
+
+func writeLog(t *testing.T, path string, key []byte, entries []*Entry) {
+	l, err := Open(path, key)
+	assert.NoError(t, err)
+	l.now = func() time.Time { return time.Unix(1600000000, 0) }
+
+	for _, entry := range entries {
+		assert.NoError(t, l.Record(entry))
+	}
+	assert.NoError(t, l.Close())
+}
+
+func TestLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	key := []byte("secret")
+	path := filepath.Join(dir, "audit.log")
+
+	// Records appended after reopening
+	// continue the existing chain.
+	writeLog(t, path, key, entries[:1])
+	writeLog(t, path, key, entries[1:])
+	assert.NoError(t, Verify(path, key))
+
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"previous_hash":"`+genesisHash+`"`)
+	assert.Contains(t, lines[0], `"timestamp":"2020-09-13T12:26:40Z"`)
+	assert.NotContains(t, lines[0], `"intent"`)
+
+	// A different key does not verify
+	err = Verify(path, []byte("other"))
+	assert.True(t, errors.Is(err, ErrChainBroken))
+	_, err = Open(path, nil)
+	assert.True(t, errors.Is(err, ErrChainBroken))
+
+	// Modified records do not verify
+	modified := strings.Replace(string(content), `"amount":"1000"`, `"amount":"1"`, 1)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(modified), 0600))
+	err = Verify(path, key)
+	assert.True(t, errors.Is(err, ErrChainBroken))
+	assert.Contains(t, err.Error(), "line 1")
+	modified = strings.Replace(string(content), `"nonce":7`, `"nonce":8`, 1)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(modified), 0600))
+	err = Verify(path, key)
+	assert.True(t, errors.Is(err, ErrChainBroken))
+	assert.Contains(t, err.Error(), "line 2")
+
+	// A torn last record is ignored, and truncated
+	// when the log is opened again
+	torn := string(content) + lines[1][:len(lines[1])/2]
+	assert.NoError(t, ioutil.WriteFile(path, []byte(torn), 0600))
+	assert.NoError(t, Verify(path, key))
+	writeLog(t, path, key, entries[:1])
+	assert.NoError(t, Verify(path, key))
+	truncated, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(truncated), string(content)))
+	assert.Len(t, strings.Split(strings.TrimSpace(string(truncated)), "\n"), 3)
+
+	// Removed records do not verify
+	assert.NoError(t, ioutil.WriteFile(path, []byte(lines[1]+"\n"), 0600))
+	err = Verify(path, key)
+	assert.True(t, errors.Is(err, ErrChainBroken))
+	assert.Contains(t, err.Error(), "line 1")
+}
@@ -0,0 +1,220 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package canonical encodes JSON canonically, following the JSON
+// Canonicalization Scheme (RFC 8785): object keys are sorted,
+// insignificant whitespace is removed, strings are minimally
+// escaped, and numbers have a single representation. The same
+// value is always encoded to the same bytes, regardless of the
+// order of struct fields or of the platform, so digests of the
+// encoding are stable.
+//
+// Unlike RFC 8785, integers are written exactly rather than
+// rounded to float64, so amounts and indexes never lose
+// precision.
+package canonical
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ErrInvalidNumber is returned when a
+// number is not finite.
+var ErrInvalidNumber = errors.New("number is not finite")
+
+// Marshal returns the canonical JSON encoding of v.
+func Marshal(v interface{}) ([]byte, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return Transform(encoded)
+}
+
+// Transform returns the canonical encoding
+// of the JSON document data.
+func Transform(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after JSON document")
+	}
+
+	var buf bytes.Buffer
+	if err := encode(&buf, value); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func encode(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		number, err := formatNumber(v)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(number)
+	case string:
+		encodeString(buf, v)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, element := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encode(buf, element); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return lessUTF16(keys[i], keys[j])
+		})
+
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			encodeString(buf, key)
+			buf.WriteByte(':')
+			if err := encode(buf, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("unexpected JSON value %T", value)
+	}
+
+	return nil
+}
+
+// lessUTF16 compares a and b by their UTF-16 code
+// units, as required by RFC 8785.
+func lessUTF16(a string, b string) bool {
+	ua := utf16.Encode([]rune(a))
+	ub := utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+
+	return len(ua) < len(ub)
+}
+
+// formatNumber returns the canonical representation of number.
+// Integers are written in decimal without a sign for zero, and
+// all other numbers as ECMAScript formats a float64.
+func formatNumber(number json.Number) (string, error) {
+	s := string(number)
+	if !strings.ContainsAny(s, ".eE") {
+		if strings.TrimLeft(s, "-0") == "" {
+			return "0", nil
+		}
+
+		return s, nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidNumber, s)
+	}
+
+	return formatFloat(f), nil
+}
+
+// formatFloat formats f like ECMAScript's Number.prototype.toString:
+// the shortest representation that round-trips, in fixed notation
+// unless its magnitude is below 1e-6 or at least 1e21.
+func formatFloat(f float64) string {
+	if f == 0 {
+		return "0"
+	}
+
+	if abs := math.Abs(f); abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	// strconv writes exponents with at least two
+	// digits (1e-07), ECMAScript with as few as
+	// possible (1e-7).
+	formatted := strconv.FormatFloat(f, 'e', -1, 64)
+	e := strings.IndexByte(formatted, 'e')
+	mantissa, sign, exponent := formatted[:e], formatted[e+1], strings.TrimLeft(formatted[e+2:], "0")
+
+	return mantissa + "e" + string(sign) + exponent
+}
+
+// encodeString writes the canonical encoding of s: only
+// quotation marks, backslashes, and control characters are
+// escaped. Invalid UTF-8 is replaced with U+FFFD.
+func encodeString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buf, `\u%04x`, r)
+				continue
+			}
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+}
@@ -0,0 +1,108 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package canonical
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransform(t *testing.T) {
+	var tests = map[string]struct {
+		input    string
+		expected string
+		err      bool
+	}{
+		"sorted keys": {
+			input:    `{ "b": 1, "a": { "d": [3, 2], "c": null }, "A": true }`,
+			expected: `{"A":true,"a":{"c":null,"d":[3,2]},"b":1}`,
+		},
+		"utf16 key order": {
+			input:    `{"😀": 1, "ﬁ": 2, "z": 3}`,
+			expected: `{"z":3,"😀":1,"ﬁ":2}`,
+		},
+		"integers are exact": {
+			input:    `[115792089237316195423570985008687907853269984665640564039457584007913129639935, -0, 0, -12]`,
+			expected: `[115792089237316195423570985008687907853269984665640564039457584007913129639935,0,0,-12]`,
+		},
+		"floats": {
+			input:    `[1.0, 1.50, -0.0, 1e21, 1e20, 0.000001, 1.5e-7, 333333333.33333329, 4.50e-10]`,
+			expected: `[1,1.5,0,1e+21,100000000000000000000,0.000001,1.5e-7,333333333.3333333,4.5e-10]`,
+		},
+		"strings": {
+			input:    `["<a&b>", "é\n\t\"\\\/", "\u0001\u001f\u007f"]`,
+			expected: "[\"<a&b>\",\"é\\n\\t\\\"\\\\/\",\"\\u0001\\u001f\x7f\"]",
+		},
+		"scalar": {
+			input:    ` "x" `,
+			expected: `"x"`,
+		},
+		"invalid": {
+			input: `{"a":`,
+			err:   true,
+		},
+		"trailing data": {
+			input: `{} {}`,
+			err:   true,
+		},
+		"overflow": {
+			input: `1e400`,
+			err:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			output, err := Transform([]byte(test.input))
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, string(output))
+		})
+	}
+}
+
+func TestTransform_InvalidNumber(t *testing.T) {
+	_, err := Transform([]byte(`-1e999`))
+	assert.True(t, errors.Is(err, ErrInvalidNumber))
+}
+
+func TestMarshal(t *testing.T) {
+	type inner struct {
+		Z string `json:"z"`
+		Y int    `json:"y"`
+	}
+	type outer struct {
+		Inner inner                  `json:"inner"`
+		Map   map[string]interface{} `json:"map"`
+	}
+
+	output, err := Marshal(&outer{
+		Inner: inner{Z: "<>", Y: 2},
+		Map:   map[string]interface{}{"b": 0.5, "a": uint64(1 << 63)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"inner":{"y":2,"z":"<>"},"map":{"a":9223372036854775808,"b":0.5}}`, string(output))
+
+	// Encoding the decoded output again is a no-op.
+	again, err := Transform(output)
+	assert.NoError(t, err)
+	assert.Equal(t, output, again)
+}
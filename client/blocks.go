@@ -0,0 +1,141 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+// maxReorgDepth is the number of blocks a BlockIterator
+// remembers to detect reorgs.
+const maxReorgDepth = 128
+
+// ErrReorg is returned by BlockIterator.Next when the last
+// block it returned was removed from the canonical chain.
+var ErrReorg = errors.New("block was reorged out")
+
+// BlockIterator iterates over the blocks of the canonical
+// chain, in order, waiting for new blocks once it reaches
+// the head of the chain.
+type BlockIterator struct {
+	client *Client
+	next   int64
+	head   int64
+
+	// returned holds the identifiers of the
+	// last blocks returned, oldest first.
+	returned []*types.BlockIdentifier
+}
+
+// Blocks returns a BlockIterator starting at
+// the block with index start.
+func (c *Client) Blocks(start int64) *BlockIterator {
+	return &BlockIterator{
+		client: c,
+		next:   start,
+		head:   start - 1,
+	}
+}
+
+// Next returns the next block of the canonical chain. If the
+// block does not exist yet, Next polls /network/status until
+// it does or ctx is done.
+//
+// If the next block does not extend the last block returned,
+// ErrReorg is returned and the iterator steps back, so the next
+// call returns the block that replaced it (or ErrReorg again,
+// for deeper reorgs). Callers should revert the last block they
+// processed when they get ErrReorg.
+func (it *BlockIterator) Next(ctx context.Context) (*types.Block, error) {
+	for it.head < it.next {
+		status, err := it.client.Status(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		it.head = status.CurrentBlockIdentifier.Index
+		if it.head >= it.next {
+			break
+		}
+
+		// The last block returned may be reorged
+		// out without the chain growing.
+		last := it.last()
+		if last != nil &&
+			status.CurrentBlockIdentifier.Index == last.Index &&
+			status.CurrentBlockIdentifier.Hash != last.Hash {
+			return nil, it.reorg(last, status.CurrentBlockIdentifier.Hash)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(it.client.pollInterval):
+		}
+	}
+
+	block, err := it.client.Block(ctx, &types.PartialBlockIdentifier{Index: &it.next})
+	if err != nil {
+		return nil, err
+	}
+
+	if last := it.last(); last != nil && block.ParentBlockIdentifier.Hash != last.Hash {
+		return nil, it.reorg(last, block.ParentBlockIdentifier.Hash)
+	}
+
+	it.returned = append(it.returned, block.BlockIdentifier)
+	if len(it.returned) > maxReorgDepth {
+		it.returned = it.returned[1:]
+	}
+	it.next = block.BlockIdentifier.Index + 1
+
+	return block, nil
+}
+
+// reorg steps back before last, which was
+// replaced by the block with hash canonical.
+func (it *BlockIterator) reorg(last *types.BlockIdentifier, canonical string) error {
+	it.returned = it.returned[:len(it.returned)-1]
+	it.next = last.Index
+	it.head = last.Index - 1
+
+	return fmt.Errorf(
+		"%w: block %d (%s) was replaced by %s",
+		ErrReorg,
+		last.Index,
+		last.Hash,
+		canonical,
+	)
+}
+
+// last returns the identifier of the last block returned
+// by Next, if it is the parent of the next block.
+func (it *BlockIterator) last() *types.BlockIdentifier {
+	if len(it.returned) == 0 {
+		return nil
+	}
+
+	last := it.returned[len(it.returned)-1]
+	if last.Index != it.next-1 {
+		return nil
+	}
+
+	return last
+}
@@ -0,0 +1,203 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package client is a typed Go client for rosetta-core. It wraps
+// the Rosetta API served by a deployment (retrying failed requests
+// with exponential backoff), pages through /events/blocks, and
+// iterates over the blocks of the canonical chain as they are
+// produced (see BlockIterator).
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/coinbase/rosetta-sdk-go/fetcher"
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+// defaultPollInterval is the interval at which a BlockIterator
+// polls /network/status once it reaches the head of the chain.
+const defaultPollInterval = time.Second
+
+// Error is returned when rosetta-core responds to a
+// request with an error. Its code is one of those
+// listed in /network/options.
+type Error struct {
+	// Err is the error returned by rosetta-core.
+	Err *types.Error
+
+	err error
+}
+
+// Error returns the error message.
+func (e *Error) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// wrapErr converts the error returned by the fetcher into an
+// *Error if rosetta-core responded with one.
+func wrapErr(fetchErr *fetcher.Error) error {
+	if fetchErr.ClientErr != nil {
+		return &Error{Err: fetchErr.ClientErr, err: fetchErr.Err}
+	}
+
+	return fetchErr.Err
+}
+
+// Client is a client of a rosetta-core deployment.
+type Client struct {
+	fetcher      *fetcher.Fetcher
+	network      *types.NetworkIdentifier
+	pollInterval time.Duration
+}
+
+// New creates a Client for the deployment at url. If network is
+// nil, the network served by the deployment is used. The options
+// configure retries (see fetcher.WithMaxRetries and
+// fetcher.WithRetryElapsedTime), timeouts, and the HTTP client.
+// Responses are validated against the /network/options of the
+// deployment.
+func New(
+	ctx context.Context,
+	url string,
+	network *types.NetworkIdentifier,
+	options ...fetcher.Option,
+) (*Client, error) {
+	f := fetcher.New(url, options...)
+	network, _, fetchErr := f.InitializeAsserter(ctx, network, "")
+	if fetchErr != nil {
+		return nil, fmt.Errorf("%w: unable to initialize %s", wrapErr(fetchErr), url)
+	}
+
+	return &Client{
+		fetcher:      f,
+		network:      network,
+		pollInterval: defaultPollInterval,
+	}, nil
+}
+
+// Network returns the network of the Client.
+func (c *Client) Network() *types.NetworkIdentifier {
+	return c.network
+}
+
+// Status returns the /network/status of the deployment.
+func (c *Client) Status(ctx context.Context) (*types.NetworkStatusResponse, error) {
+	status, fetchErr := c.fetcher.NetworkStatusRetry(ctx, c.network, nil)
+	if fetchErr != nil {
+		return nil, wrapErr(fetchErr)
+	}
+
+	return status, nil
+}
+
+// Options returns the /network/options of the deployment.
+func (c *Client) Options(ctx context.Context) (*types.NetworkOptionsResponse, error) {
+	options, fetchErr := c.fetcher.NetworkOptionsRetry(ctx, c.network, nil)
+	if fetchErr != nil {
+		return nil, wrapErr(fetchErr)
+	}
+
+	return options, nil
+}
+
+// Block returns the block identified by block. The transactions
+// the deployment does not return inline (see
+// BLOCK_INLINE_TRANSACTIONS) are fetched from /block/transaction.
+func (c *Client) Block(
+	ctx context.Context,
+	block *types.PartialBlockIdentifier,
+) (*types.Block, error) {
+	result, fetchErr := c.fetcher.BlockRetry(ctx, c.network, block)
+	if fetchErr != nil {
+		return nil, wrapErr(fetchErr)
+	}
+
+	return result, nil
+}
+
+// Balance returns the balances of account at block (or at the
+// head of the chain if block is nil) and the block they were
+// read at. If currencies is empty, the CORE balance is returned.
+func (c *Client) Balance(
+	ctx context.Context,
+	account *types.AccountIdentifier,
+	block *types.PartialBlockIdentifier,
+	currencies []*types.Currency,
+) (*types.BlockIdentifier, []*types.Amount, error) {
+	blockIdentifier, balances, _, fetchErr := c.fetcher.AccountBalanceRetry(
+		ctx,
+		c.network,
+		account,
+		block,
+		currencies,
+	)
+	if fetchErr != nil {
+		return nil, nil, wrapErr(fetchErr)
+	}
+
+	return blockIdentifier, balances, nil
+}
+
+// Call calls the /call method with parameters and decodes
+// its result into result. parameters and result are encoded
+// as JSON, so they can be structs like
+// ethereum.TokenInventoryInput.
+func (c *Client) Call(
+	ctx context.Context,
+	method string,
+	parameters interface{},
+	result interface{},
+) error {
+	var request map[string]interface{}
+	if parameters != nil {
+		raw, err := json.Marshal(parameters)
+		if err != nil {
+			return fmt.Errorf("%w: unable to encode parameters", err)
+		}
+		if err := json.Unmarshal(raw, &request); err != nil {
+			return fmt.Errorf("%w: parameters must be a JSON object", err)
+		}
+	}
+	if request == nil {
+		request = map[string]interface{}{}
+	}
+
+	response, _, fetchErr := c.fetcher.CallRetry(ctx, c.network, method, request)
+	if fetchErr != nil {
+		return wrapErr(fetchErr)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	raw, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("%w: unable to encode %s result", err, method)
+	}
+	if err := json.Unmarshal(raw, result); err != nil {
+		return fmt.Errorf("%w: unable to decode %s result", err, method)
+	}
+
+	return nil
+}
@@ -0,0 +1,279 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coinbase/rosetta-sdk-go/fetcher"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	network = &types.NetworkIdentifier{
+		Blockchain: "Core",
+		Network:    "Mainnet",
+	}
+
+	unknownMethod = &types.Error{
+		Code:    22,
+		Message: "Call method invalid",
+	}
+)
+
+// testServer is a fake rosetta-core deployment
+// serving the blocks of chain.
+type testServer struct {
+	mutex  sync.Mutex
+	chain  []*types.Block
+	events []*types.BlockEvent
+}
+
+func testBlock(index int64, branch string) *types.Block {
+	parent := index - 1
+	if parent < 0 {
+		parent = 0
+	}
+
+	return &types.Block{
+		BlockIdentifier: &types.BlockIdentifier{
+			Index: index,
+			Hash:  fmt.Sprintf("%s%d", branch, index),
+		},
+		ParentBlockIdentifier: &types.BlockIdentifier{
+			Index: parent,
+			Hash:  fmt.Sprintf("%s%d", branch, parent),
+		},
+		Timestamp:    1600000000000 + index,
+		Transactions: []*types.Transaction{},
+	}
+}
+
+// extend appends the blocks of branch up to index.
+func (s *testServer) extend(index int64, branch string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := int64(len(s.chain)); i <= index; i++ {
+		s.chain = append(s.chain, testBlock(i, branch))
+	}
+}
+
+// reorg replaces the blocks from index with those of branch.
+func (s *testServer) reorg(index int64, branch string) {
+	s.mutex.Lock()
+	s.chain = s.chain[:index]
+	s.chain = append(s.chain, testBlock(index, branch))
+	s.chain[index].ParentBlockIdentifier = s.chain[index-1].BlockIdentifier
+	s.mutex.Unlock()
+}
+
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	status := http.StatusOK
+	var response interface{}
+	switch r.URL.Path {
+	case "/network/list":
+		response = &types.NetworkListResponse{
+			NetworkIdentifiers: []*types.NetworkIdentifier{network},
+		}
+	case "/network/options":
+		response = &types.NetworkOptionsResponse{
+			Version: &types.Version{
+				RosettaVersion: "1.4.10",
+				NodeVersion:    "1.0.0",
+			},
+			Allow: &types.Allow{
+				OperationStatuses: []*types.OperationStatus{
+					{Status: "SUCCESS", Successful: true},
+				},
+				OperationTypes: []string{"CALL"},
+				Errors:         []*types.Error{unknownMethod},
+				CallMethods:    []string{"echo"},
+			},
+		}
+	case "/network/status":
+		head := s.chain[len(s.chain)-1]
+		response = &types.NetworkStatusResponse{
+			CurrentBlockIdentifier: head.BlockIdentifier,
+			CurrentBlockTimestamp:  head.Timestamp,
+			GenesisBlockIdentifier: s.chain[0].BlockIdentifier,
+			Peers:                  []*types.Peer{},
+		}
+	case "/block":
+		var request types.BlockRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		response = &types.BlockResponse{Block: s.chain[*request.BlockIdentifier.Index]}
+	case "/events/blocks":
+		var request types.EventsBlocksRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		events := s.events[*request.Offset:]
+		if int64(len(events)) > *request.Limit {
+			events = events[:*request.Limit]
+		}
+		response = &types.EventsBlocksResponse{
+			MaxSequence: int64(len(s.events) - 1),
+			Events:      events,
+		}
+	case "/call":
+		var request types.CallRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if request.Method != "echo" {
+			status = http.StatusInternalServerError
+			response = unknownMethod
+			break
+		}
+		response = &types.CallResponse{Result: request.Parameters, Idempotent: true}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response) // nolint:errcheck
+}
+
+func newTestClient(t *testing.T, s *testServer) *Client {
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+
+	c, err := New(
+		context.Background(),
+		server.URL,
+		nil,
+		fetcher.WithMaxRetries(0),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, network, c.Network())
+	c.pollInterval = time.Millisecond
+
+	return c
+}
+
+func TestBlockIterator(t *testing.T) {
+	ctx := context.Background()
+	s := &testServer{}
+	s.extend(2, "a")
+	c := newTestClient(t, s)
+
+	it := c.Blocks(1)
+	for _, hash := range []string{"a1", "a2"} {
+		block, err := it.Next(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, hash, block.BlockIdentifier.Hash)
+	}
+
+	// Next waits for the next block.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		s.extend(3, "a")
+	}()
+	block, err := it.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "a3", block.BlockIdentifier.Hash)
+
+	// a2 and a3 are replaced by b2 and b3.
+	s.reorg(2, "b")
+	s.extend(3, "b")
+	_, err = it.Next(ctx)
+	assert.True(t, errors.Is(err, ErrReorg))
+	_, err = it.Next(ctx)
+	assert.True(t, errors.Is(err, ErrReorg))
+	for _, hash := range []string{"b2", "b3"} {
+		block, err := it.Next(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, hash, block.BlockIdentifier.Hash)
+	}
+
+	// Next stops waiting when ctx is done.
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = it.Next(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestEventsBlocks(t *testing.T) {
+	s := &testServer{}
+	s.extend(0, "a")
+	for i := int64(0); i < 250; i++ {
+		s.events = append(s.events, &types.BlockEvent{
+			Sequence:        i,
+			BlockIdentifier: testBlock(i, "a").BlockIdentifier,
+			Type:            types.ADDED,
+		})
+	}
+	c := newTestClient(t, s)
+
+	var sequences []int64
+	offset, err := c.EventsBlocks(context.Background(), 10, func(event *types.BlockEvent) error {
+		sequences = append(sequences, event.Sequence)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(250), offset)
+	assert.Len(t, sequences, 240)
+	assert.Equal(t, int64(10), sequences[0])
+	assert.Equal(t, int64(249), sequences[239])
+
+	// Iteration stops at the first error.
+	stop := errors.New("stop")
+	offset, err = c.EventsBlocks(context.Background(), 10, func(event *types.BlockEvent) error {
+		if event.Sequence == 120 {
+			return stop
+		}
+		return nil
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, int64(120), offset)
+}
+
+func TestCall(t *testing.T) {
+	s := &testServer{}
+	s.extend(0, "a")
+	c := newTestClient(t, s)
+
+	type echo struct {
+		Address string `json:"address"`
+	}
+	var result echo
+	assert.NoError(t, c.Call(context.Background(), "echo", &echo{Address: "0x1"}, &result))
+	assert.Equal(t, echo{Address: "0x1"}, result)
+
+	err := c.Call(context.Background(), "unknown", nil, nil)
+	var clientErr *Error
+	assert.True(t, errors.As(err, &clientErr))
+	assert.Equal(t, unknownMethod.Code, clientErr.Err.Code)
+}
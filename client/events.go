@@ -0,0 +1,61 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+// eventsPageSize is the number of events
+// requested from /events/blocks at once.
+const eventsPageSize = 100
+
+// EventsBlocks calls fn with every event of /events/blocks,
+// in order, starting from the event with sequence offset. It
+// pages through the events until it reaches the last one and
+// returns the offset to resume from. The deployment only keeps
+// the latest events (see BLOCK_EVENTS_HISTORY), so an offset that is too
+// old is rejected with an *Error.
+func (c *Client) EventsBlocks(
+	ctx context.Context,
+	offset int64,
+	fn func(*types.BlockEvent) error,
+) (int64, error) {
+	limit := int64(eventsPageSize)
+	for {
+		maxSequence, events, fetchErr := c.fetcher.EventsBlocksRetry(
+			ctx,
+			c.network,
+			&offset,
+			&limit,
+		)
+		if fetchErr != nil {
+			return offset, wrapErr(fetchErr)
+		}
+
+		for _, event := range events {
+			if err := fn(event); err != nil {
+				return offset, err
+			}
+			offset = event.Sequence + 1
+		}
+
+		if len(events) == 0 || offset > maxSequence {
+			return offset, nil
+		}
+	}
+}
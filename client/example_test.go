@@ -0,0 +1,110 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/client"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+
+	"github.com/coinbase/rosetta-sdk-go/fetcher"
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+func ExampleClient_Blocks() {
+	ctx := context.Background()
+	c, err := client.New(
+		ctx,
+		"http://localhost:8080",
+		nil,
+		fetcher.WithRetryElapsedTime(time.Minute),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	it := c.Blocks(0)
+	for {
+		block, err := it.Next(ctx)
+		if errors.Is(err, client.ErrReorg) {
+			// Revert the last block processed.
+			continue
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Println(block.BlockIdentifier.Index, len(block.Transactions))
+	}
+}
+
+func ExampleClient_Call() {
+	ctx := context.Background()
+	c, err := client.New(ctx, "http://localhost:8080", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var inventory struct {
+		BlockIdentifier *types.BlockIdentifier `json:"block_identifier"`
+		Tokens          []struct {
+			TokenAddress string `json:"token_address"`
+			Balance      string `json:"balance"`
+		} `json:"tokens"`
+	}
+	err = c.Call(
+		ctx,
+		ethereum.TokenInventoryMethod,
+		&ethereum.TokenInventoryInput{Address: "0x9b2E2D1C3E9a4d2d7C5F8B26f0ebA6F7a4C6d9E0"},
+		&inventory,
+	)
+	var clientErr *client.Error
+	if errors.As(err, &clientErr) {
+		log.Fatalf("rosetta-core error %d: %s", clientErr.Err.Code, clientErr.Err.Message)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, token := range inventory.Tokens {
+		fmt.Println(token.TokenAddress, token.Balance)
+	}
+}
+
+func ExampleClient_EventsBlocks() {
+	ctx := context.Background()
+	c, err := client.New(ctx, "http://localhost:8080", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var offset int64
+	for {
+		offset, err = c.EventsBlocks(ctx, offset, func(event *types.BlockEvent) error {
+			fmt.Println(event.Sequence, event.Type, event.BlockIdentifier.Index)
+			return nil
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		time.Sleep(time.Second)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/services"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/coinbase/rosetta-sdk-go/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	asserterConfigCmd = &cobra.Command{
+		Use:   "asserter-config",
+		Short: "Export the asserter configuration of this deployment",
+		Long: `rosetta-cli can validate responses using an asserter
+configuration file instead of the /network/options response.
+This command writes the asserter configuration (operation types,
+statuses, errors, and timestamp start index) matching the
+environment variables rosetta-core would be run with, so the
+rosetta-cli configuration never drifts from the server. In online
+mode, the timestamp start index is found from the node unless
+TIMESTAMP_START_INDEX is set.
+
+When --out is not provided, the configuration is printed.`,
+		RunE: runAsserterConfigCmd,
+		Args: cobra.NoArgs,
+	}
+
+	asserterConfigOut string
+)
+
+func init() {
+	asserterConfigCmd.Flags().StringVar(
+		&asserterConfigOut,
+		"out",
+		"",
+		"location to write the asserter configuration file",
+	)
+}
+
+func runAsserterConfigCmd(cmd *cobra.Command, args []string) error {
+	cfg, err := configuration.LoadConfiguration()
+	if err != nil {
+		return fmt.Errorf("%w: unable to load configuration", err)
+	}
+
+	// The index is found from the node like rosetta-core
+	// does, so the configuration matches /network/options.
+	if cfg.Mode == configuration.Online && cfg.TimestampStartIndex == nil {
+		client, err := newClient(cfg)
+		if err != nil {
+			return fmt.Errorf("%w: cannot initialize ethereum client", err)
+		}
+		defer client.Close()
+
+		index, err := client.TimestampStartIndex(context.Background())
+		if err != nil {
+			return fmt.Errorf("%w: unable to find timestamp start index", err)
+		}
+		cfg.TimestampStartIndex = &index
+	}
+
+	asserterConfig := services.AsserterConfiguration(cfg)
+	if len(asserterConfigOut) == 0 {
+		fmt.Println(types.PrettyPrintStruct(asserterConfig))
+		return nil
+	}
+
+	if err := utils.SerializeAndWrite(asserterConfigOut, asserterConfig); err != nil {
+		return fmt.Errorf("%w: could not write asserter configuration", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/coinbase/rosetta-ethereum/compare"
+
+	"github.com/coinbase/rosetta-sdk-go/fetcher"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/coinbase/rosetta-sdk-go/utils"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	compareCmd = &cobra.Command{
+		Use:   "compare",
+		Short: "Diff the blocks served by two deployments",
+		Long: `Fetches the blocks from --start to --end (inclusive) from two
+rosetta-core deployments (i.e. the current and the next release)
+and reports every difference in their block identifiers,
+transactions, and operations (type, status, account, amount, and
+related operations). Metadata is only compared with
+--include-metadata.
+
+The report is printed or, with --out, written to a file. The
+command fails if any discrepancy is found, so it can gate the
+rollout of changes to the conversion logic.`,
+		RunE: runCompareCmd,
+		Args: cobra.NoArgs,
+	}
+
+	compareURL             string
+	compareOtherURL        string
+	compareStart           int64
+	compareEnd             int64
+	compareIncludeMetadata bool
+	compareOut             string
+)
+
+// errDiscrepancies is returned when the
+// compared deployments do not agree.
+var errDiscrepancies = errors.New("deployments do not agree")
+
+func init() {
+	compareCmd.Flags().StringVar(&compareURL, "url", "http://localhost:8080", "url of the deployment")
+	compareCmd.Flags().StringVar(&compareOtherURL, "other-url", "", "url of the deployment to compare with")
+	compareCmd.Flags().Int64Var(&compareStart, "start", 0, "index of the first block to compare")
+	compareCmd.Flags().Int64Var(&compareEnd, "end", -1, "index of the last block to compare")
+	compareCmd.Flags().BoolVar(&compareIncludeMetadata, "include-metadata", false, "compare metadata")
+	compareCmd.Flags().StringVar(&compareOut, "out", "", "location to write the report")
+	compareCmd.MarkFlagRequired("other-url") // nolint:errcheck
+	compareCmd.MarkFlagRequired("end")       // nolint:errcheck
+}
+
+func runCompareCmd(cmd *cobra.Command, args []string) error {
+	if compareEnd < compareStart {
+		return fmt.Errorf("end %d is before start %d", compareEnd, compareStart)
+	}
+
+	ctx := context.Background()
+	f, network, err := newCompareFetcher(ctx, compareURL)
+	if err != nil {
+		return err
+	}
+	other, otherNetwork, err := newCompareFetcher(ctx, compareOtherURL)
+	if err != nil {
+		return err
+	}
+	if types.Hash(network) != types.Hash(otherNetwork) {
+		return fmt.Errorf(
+			"%s serves %s but %s serves %s",
+			compareURL,
+			types.PrintStruct(network),
+			compareOtherURL,
+			types.PrintStruct(otherNetwork),
+		)
+	}
+
+	report := compare.NewReport(compareStart, compareEnd)
+	for index := compareStart; index <= compareEnd; index++ {
+		var block, otherBlock *types.Block
+		g, gctx := errgroup.WithContext(ctx)
+		g.Go(func() error {
+			var err error
+			block, err = fetchCompareBlock(gctx, f, network, compareURL, index)
+			return err
+		})
+		g.Go(func() error {
+			var err error
+			otherBlock, err = fetchCompareBlock(gctx, other, network, compareOtherURL, index)
+			return err
+		})
+		if err := g.Wait(); err != nil {
+			return err
+		}
+
+		report.Add(block, otherBlock, compareIncludeMetadata)
+	}
+
+	if len(compareOut) == 0 {
+		fmt.Println(types.PrettyPrintStruct(report))
+	} else if err := utils.SerializeAndWrite(compareOut, report); err != nil {
+		return fmt.Errorf("%w: could not write report", err)
+	}
+
+	if len(report.Discrepancies) > 0 {
+		return fmt.Errorf(
+			"%w: found %d discrepancies in %d blocks",
+			errDiscrepancies,
+			len(report.Discrepancies),
+			report.BlocksCompared,
+		)
+	}
+
+	return nil
+}
+
+// newCompareFetcher returns a fetcher for the deployment
+// at url and the network it serves.
+func newCompareFetcher(
+	ctx context.Context,
+	url string,
+) (*fetcher.Fetcher, *types.NetworkIdentifier, error) {
+	f := fetcher.New(url)
+	network, _, fetchErr := f.InitializeAsserter(ctx, nil, "")
+	if fetchErr != nil {
+		return nil, nil, fmt.Errorf("%w: unable to initialize %s", fetchErr.Err, url)
+	}
+
+	return f, network, nil
+}
+
+// fetchCompareBlock fetches the block at index (including
+// its other transactions) from the deployment at url.
+func fetchCompareBlock(
+	ctx context.Context,
+	f *fetcher.Fetcher,
+	network *types.NetworkIdentifier,
+	url string,
+	index int64,
+) (*types.Block, error) {
+	block, fetchErr := f.BlockRetry(ctx, network, &types.PartialBlockIdentifier{Index: &index})
+	if fetchErr != nil {
+		return nil, fmt.Errorf("%w: unable to fetch block %d from %s", fetchErr.Err, index, url)
+	}
+
+	return block, nil
+}
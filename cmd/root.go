@@ -16,10 +16,13 @@ package cmd
 
 import (
 	"context"
+	"log"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"github.com/coinbase/rosetta-ethereum/configuration"
+
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
@@ -28,8 +31,16 @@ var (
 	rootCmd = &cobra.Command{
 		Use:   "rosetta-core",
 		Short: "CoreChain implementation of the Rosetta API",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if len(network) > 0 {
+				os.Setenv(configuration.NetworkEnv, network)
+			}
+		},
 	}
 
+	// network overrides NetworkEnv when populated.
+	network string
+
 	// SignalReceived is set to true when a signal causes us to exit. This makes
 	// determining the error message to show on exit much more easy.
 	SignalReceived = false
@@ -42,8 +53,19 @@ func Execute() error {
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringVar(
+		&network,
+		"network",
+		"",
+		"network preset to use (overrides the NETWORK environment variable)",
+	)
+
 	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(utilsBootstrapCmd)
+	rootCmd.AddCommand(asserterConfigCmd)
+	rootCmd.AddCommand(verifyAuditLogCmd)
+	rootCmd.AddCommand(compareCmd)
+	rootCmd.AddCommand(validateConfigCmd)
 }
 
 // handleSignals handles OS signals so we can ensure we close database
@@ -61,3 +83,26 @@ func handleSignals(listeners []context.CancelFunc) {
 		}
 	}()
 }
+
+// handleReload calls reload every time SIGHUP is
+// received until ctx is done.
+func handleReload(ctx context.Context, reload func() error) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(sigs)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigs:
+				if err := reload(); err != nil {
+					log.Printf("unable to reload configuration: %s", err.Error())
+					continue
+				}
+
+				log.Println("configuration reloaded")
+			}
+		}
+	}()
+}
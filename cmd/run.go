@@ -20,11 +20,21 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"sync"
 	"time"
 
+	"github.com/coinbase/rosetta-ethereum/archive"
+	"github.com/coinbase/rosetta-ethereum/audit"
 	"github.com/coinbase/rosetta-ethereum/configuration"
 	"github.com/coinbase/rosetta-ethereum/ethereum"
+	"github.com/coinbase/rosetta-ethereum/indexer"
+	"github.com/coinbase/rosetta-ethereum/metrics"
+	"github.com/coinbase/rosetta-ethereum/nonce"
+	"github.com/coinbase/rosetta-ethereum/redact"
 	"github.com/coinbase/rosetta-ethereum/services"
+	"github.com/coinbase/rosetta-ethereum/submit"
+	"github.com/coinbase/rosetta-ethereum/watermark"
 
 	"github.com/coinbase/rosetta-sdk-go/asserter"
 	"github.com/coinbase/rosetta-sdk-go/server"
@@ -34,18 +44,9 @@ import (
 )
 
 const (
-	// readTimeout is the maximum duration for reading the entire
-	// request, including the body.
-	readTimeout = 5 * time.Second
-
-	// writeTimeout is the maximum duration before timing out
-	// writes of the response. It is reset whenever a new
-	// request's header is read.
-	writeTimeout = 120 * time.Second
-
-	// idleTimeout is the maximum amount of time to wait for the
-	// next request when keep-alives are enabled.
-	idleTimeout = 30 * time.Second
+	// timestampStartIndexRetryInterval is how long to wait
+	// before retrying to find the timestamp start index.
+	timestampStartIndexRetryInterval = 5 * time.Second
 )
 
 var (
@@ -62,13 +63,64 @@ func runRunCmd(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("%w: unable to load configuration", err)
 	}
 
+	// Metrics are only recorded when they are exported,
+	// and must be enabled before any of them is used.
+	if cfg.EnableMetrics || cfg.EnableAdminDebugUI {
+		metrics.Enable()
+	}
+
+	if err := ethereum.SetSystemContracts(cfg.SystemContracts); err != nil {
+		return fmt.Errorf("%w: invalid system contracts", err)
+	}
+	if cfg.MulticallContract != nil {
+		ethereum.MulticallContract = *cfg.MulticallContract
+	}
+
+	redactor, err := redact.New(cfg.LogRedaction)
+	if err != nil {
+		return fmt.Errorf("%w: unable to initialize log redaction", err)
+	}
+	redact.Install(redactor)
+
+	// reload applies the reloadable settings of the configuration
+	// without restarting (and losing any warm caches). The
+	// response cache and the rate limiter are created with the
+	// router, so they are only reloaded once they exist.
+	var reloadMutex sync.Mutex
+	var cachedRouter *services.ResponseCache
+	var rateLimitedRouter *services.RateLimitHandler
+	reload := func() error {
+		reloadMutex.Lock()
+		defer reloadMutex.Unlock()
+
+		if err := cfg.Reload(); err != nil {
+			return fmt.Errorf("%w: unable to load configuration", err)
+		}
+
+		redactor, err := redact.New(cfg.LogRedactionConfig())
+		if err != nil {
+			return fmt.Errorf("%w: unable to initialize log redaction", err)
+		}
+		redact.Install(redactor)
+
+		if cachedRouter != nil {
+			cachedRouter.Reload(cfg)
+		}
+		if rateLimitedRouter != nil {
+			rateLimitedRouter.Reload(cfg)
+		}
+
+		return nil
+	}
+
 	// The asserter automatically rejects incorrectly formatted
-	// requests.
+	// requests (including requests for call methods that are
+	// not served).
 	asserter, err := asserter.NewServer(
 		ethereum.OperationTypes,
 		ethereum.HistoricalBalanceSupported,
 		[]*types.NetworkIdentifier{cfg.Network},
-		ethereum.CallMethods,
+		cfg.CallMethods(),
 		ethereum.IncludeMempoolCoins,
 		"",
 	)
@@ -80,9 +132,12 @@ func runRunCmd(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 	ctx, cancel := context.WithCancel(ctx)
 	go handleSignals([]context.CancelFunc{cancel})
+	handleReload(ctx, reload)
 
 	g, ctx := errgroup.WithContext(ctx)
 
+	quarantine := &services.Quarantine{}
+
 	var client *ethereum.Client
 	if cfg.Mode == configuration.Online {
 		if !cfg.RemoteGeth {
@@ -92,38 +147,283 @@ func runRunCmd(cmd *cobra.Command, args []string) error {
 		}
 
 		var err error
-		client, err = ethereum.NewClient(cfg.GethURL, cfg.Params, cfg.SkipGethAdmin)
+		client, err = newClient(cfg)
 		if err != nil {
 			return fmt.Errorf("%w: cannot initialize ethereum client", err)
 		}
 		defer client.Close()
+
+		// The index is found once, before anything is served, so
+		// that /network/options and STRICT validation agree on it.
+		if err := findTimestampStartIndex(ctx, cfg, client); err != nil {
+			return err
+		}
+
+		g.Go(func() error {
+			return client.ProbeCapabilities(ctx)
+		})
+
+		g.Go(func() error {
+			return client.MonitorLag(ctx)
+		})
+
+		g.Go(func() error {
+			return client.MonitorArchives(ctx)
+		})
+
+		g.Go(func() error {
+			return client.MonitorHardforks(ctx, cfg.Hardforks)
+		})
+
+		g.Go(func() error {
+			return client.MonitorMempool(ctx, cfg.MempoolMonitor)
+		})
+
+		g.Go(func() error {
+			return client.ResolveSelectors(ctx)
+		})
+
+		// Requests are rejected until the node is
+		// known to be on the configured chain.
+		quarantine.Unverified()
+		g.Go(func() error {
+			return verifyChain(ctx, cfg, client, quarantine)
+		})
 	}
 
-	router := services.NewBlockchainRouter(cfg, client, asserter)
+	var watermarkStore *watermark.Store
+	var watermarks services.Watermarks
+	if cfg.Mode == configuration.Online && len(cfg.WatermarkPath) > 0 {
+		var err error
+		watermarkStore, err = watermark.Open(cfg.WatermarkPath)
+		if err != nil {
+			return fmt.Errorf("%w: cannot initialize watermarks", err)
+		}
 
-	loggedRouter := server.LoggerMiddleware(router)
+		g.Go(func() error {
+			return watermarkStore.Run(ctx)
+		})
+		watermarks = watermarkStore
+	}
+
+	var headEvents *services.HeadEvents
+	if cfg.Mode == configuration.Online && cfg.EnableHeadEvents {
+		headEvents = services.NewHeadEvents(cfg.BlockEventsHistory)
+	}
+
+	// The head of the node is followed to publish head
+	// events and to record the depth of reorgs.
+	if headEvents != nil || watermarkStore != nil {
+		g.Go(func() error {
+			return client.MonitorHeads(ctx, func(event *types.BlockEvent) {
+				if headEvents != nil {
+					headEvents.Publish(event)
+				}
+				if watermarkStore != nil {
+					watermarkStore.Observe(event)
+				}
+			})
+		})
+	}
+
+	var nonceTracker services.NonceTracker
+	if cfg.Mode == configuration.Online && len(cfg.NonceTrackerPath) > 0 {
+		tracker, err := nonce.OpenTracker(cfg.NonceTrackerPath, nonce.DefaultLease)
+		if err != nil {
+			return fmt.Errorf("%w: cannot initialize nonce tracker", err)
+		}
+		defer tracker.Close()
+
+		nonceTracker = tracker
+	}
+
+	var index services.AccountIndex
+	if cfg.Mode == configuration.Online && len(cfg.IndexPath) > 0 {
+		i, err := indexer.Open(cfg.IndexPath, client, cfg.IndexRetention, cfg.IndexTokenHolders)
+		if err != nil {
+			return fmt.Errorf("%w: cannot initialize index", err)
+		}
+		defer i.Close()
+
+		g.Go(func() error {
+			return i.Run(ctx)
+		})
+		index = i
+	}
+
+	var auditLog services.AuditLog
+	if cfg.Mode == configuration.Online && len(cfg.AuditLogPath) > 0 {
+		l, err := audit.Open(cfg.AuditLogPath, []byte(cfg.AuditLogKey))
+		if err != nil {
+			return fmt.Errorf("%w: cannot initialize audit log", err)
+		}
+		defer l.Close()
+
+		auditLog = l
+	}
+
+	var submitQueue services.SubmitQueue
+	if cfg.Mode == configuration.Online && len(cfg.SubmitQueuePath) > 0 {
+		q, err := submit.OpenQueue(cfg.SubmitQueuePath, client, submit.DefaultExpiry)
+		if err != nil {
+			return fmt.Errorf("%w: cannot initialize submit queue", err)
+		}
+		defer q.Close()
+
+		g.Go(func() error {
+			return q.Run(ctx)
+		})
+		submitQueue = q
+	}
+
+	var blockClient services.Client = client
+	if cfg.Mode == configuration.Online && len(cfg.BlockArchiveURL) > 0 {
+		store, err := archive.OpenStore(cfg.BlockArchiveURL)
+		if err != nil {
+			return fmt.Errorf("%w: cannot initialize block archive", err)
+		}
+
+		blockArchive := archive.New(store, client, cfg.Network)
+		if _, err := blockArchive.Manifest(ctx); err != nil {
+			return fmt.Errorf("%w: cannot read block archive", err)
+		}
+
+		if cfg.BlockArchiveEnabled(configuration.ExportBlockArchive) {
+			g.Go(func() error {
+				return blockArchive.Run(ctx)
+			})
+		}
+
+		if cfg.BlockArchiveEnabled(configuration.ReplayBlockArchive) {
+			blockClient = services.ArchiveClient(client, blockArchive)
+		}
+	}
+
+	router := services.NewBlockchainRouter(cfg, blockClient, nonceTracker, index, auditLog, headEvents, submitQueue, asserter)
+
+	validatedRouter, err := services.ValidationMiddleware(cfg, router)
+	if err != nil {
+		return fmt.Errorf("%w: cannot initialize validation middleware", err)
+	}
+
+	// Only blocks that passed validation advance the
+	// served block watermark.
+	watermarkRouter := services.WatermarkMiddleware(watermarks, validatedRouter)
+
+	// Responses are canonicalized after they are validated so
+	// that the canonical encoding is what gets cached.
+	canonicalRouter := services.CanonicalJSONMiddleware(cfg, watermarkRouter)
+
+	// Responses are cached after they are validated so
+	// that cache hits do not need to be validated again.
+	// When head events are published, responses for blocks
+	// that are reorged out are evicted.
+	reloadMutex.Lock()
+	cachedRouter = services.CacheMiddleware(cfg, client, headEvents, canonicalRouter)
+	reloadMutex.Unlock()
+
+	// Cache hits are counted as requests that did
+	// not make any upstream calls.
+	upstreamTracker := services.NewUpstreamTracker()
+	upstreamRouter := services.UpstreamMiddleware(upstreamTracker, cachedRouter)
+
+	// Head events are served by the same middleware as the
+	// Rosetta API, except for the middleware above, which
+	// only applies to Rosetta responses.
+	eventsRouter := upstreamRouter
+	if headEvents != nil {
+		mux := http.NewServeMux()
+		mux.Handle(services.HeadEventsPath, services.HeadEventsHandler(headEvents))
+		mux.Handle("/", upstreamRouter)
+		eventsRouter = mux
+	}
+
+	quarantinedRouter := services.QuarantineMiddleware(quarantine, eventsRouter)
+
+	// Requests rejected during maintenance are not
+	// counted as in flight, so draining can be observed.
+	maintenance := services.NewMaintenance(cfg.MaintenanceWindows)
+	maintainedRouter := services.MaintenanceMiddleware(maintenance, quarantinedRouter)
+
+	// Malformed and oversized requests are rejected
+	// before any other middleware reads them.
+	hardenedRouter := services.RequestMiddleware(cfg, maintainedRouter)
+
+	// In public mode, node addresses are stripped from every
+	// error, including the errors of the middleware above.
+	publicRouter := services.PublicMiddleware(cfg, hardenedRouter)
+	reloadMutex.Lock()
+	rateLimitedRouter = services.RateLimitMiddleware(cfg, publicRouter)
+	reloadMutex.Unlock()
+
+	// Clients that are not allowed to construct transactions
+	// are rejected before they count against the rate limit.
+	constructionRouter := services.ConstructionAccessMiddleware(cfg, rateLimitedRouter)
+
+	loggedRouter := server.LoggerMiddleware(constructionRouter)
 	corsRouter := server.CorsMiddleware(loggedRouter)
-	server := &http.Server{
-		Addr:         fmt.Sprintf(":%d", cfg.Port),
-		Handler:      corsRouter,
-		ReadTimeout:  readTimeout,
-		WriteTimeout: writeTimeout,
-		IdleTimeout:  idleTimeout,
+
+	// Admin endpoints are served with the Rosetta API
+	// unless they have their own listener.
+	adminMux := http.NewServeMux()
+	adminEnabled := cfg.EnableMetrics || cfg.EnableAdminReload || cfg.EnableUpstreamReport ||
+		cfg.EnableAdminMaintenance || cfg.EnableAdminDebugUI
+	if cfg.EnableMetrics {
+		adminMux.Handle("/metrics", metrics.Handler())
+	}
+	if cfg.EnableAdminReload {
+		adminMux.Handle("/admin/reload", services.ReloadHandler(reload))
+	}
+	if cfg.EnableUpstreamReport {
+		adminMux.Handle("/admin/upstream", services.UpstreamReportHandler(upstreamTracker))
 	}
+	if cfg.EnableAdminMaintenance {
+		adminMux.Handle("/admin/maintenance", services.MaintenanceHandler(maintenance))
+	}
+	if cfg.EnableAdminDebugUI {
+		adminMux.Handle("/admin/debug/", services.DebugUIHandler(cfg, blockClient, upstreamTracker, hardenedRouter))
+	}
+	separateAdmin := len(cfg.HTTPServer.AdminListenAddress) > 0
 
-	g.Go(func() error {
-		log.Printf("server listening on port %d", cfg.Port)
-		return server.ListenAndServe()
-	})
+	// In public mode, admin endpoints are never
+	// served with the Rosetta API.
+	if adminEnabled && !separateAdmin && cfg.PublicMode {
+		log.Printf(
+			"admin endpoints are disabled in public mode unless %s is set",
+			configuration.AdminListenAddressEnv,
+		)
+		adminEnabled = false
+	}
 
-	g.Go(func() error {
-		// If we don't shutdown server in errgroup, it will
-		// never stop because server.ListenAndServe doesn't
-		// take any context.
-		<-ctx.Done()
+	handler := corsRouter
+	if adminEnabled && !separateAdmin {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", adminMux)
+		mux.Handle("/admin/", adminMux)
+		mux.Handle("/", corsRouter)
+		handler = mux
+	}
 
-		return server.Shutdown(ctx)
-	})
+	addresses := cfg.HTTPServer.ListenAddresses
+	if len(addresses) == 0 {
+		addresses = []string{fmt.Sprintf(":%d", cfg.Port)}
+	}
+	if err := serve(ctx, g, &cfg.HTTPServer, "server", addresses, handler); err != nil {
+		return err
+	}
+
+	if separateAdmin {
+		if err := serve(
+			ctx,
+			g,
+			&cfg.HTTPServer,
+			"admin server",
+			[]string{cfg.HTTPServer.AdminListenAddress},
+			adminMux,
+		); err != nil {
+			return err
+		}
+	}
 
 	err = g.Wait()
 	if SignalReceived {
@@ -132,3 +432,114 @@ func runRunCmd(cmd *cobra.Command, args []string) error {
 
 	return err
 }
+
+// findTimestampStartIndex sets cfg.TimestampStartIndex to the
+// index found by client (see ethereum.Client.TimestampStartIndex)
+// when it is not configured, retrying until the node responds.
+func findTimestampStartIndex(
+	ctx context.Context,
+	cfg *configuration.Configuration,
+	client *ethereum.Client,
+) error {
+	if cfg.TimestampStartIndex != nil {
+		return nil
+	}
+
+	for {
+		index, err := client.TimestampStartIndex(ctx)
+		if err == nil {
+			log.Printf("timestamp start index: %d", index)
+			cfg.TimestampStartIndex = &index
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Printf("unable to find timestamp start index: %s", err.Error())
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(timestampStartIndexRetryInterval):
+		}
+	}
+}
+
+// verifyChain ensures the node is on the configured network. On
+// mismatch, it returns an error (stopping rosetta-core) or, if
+// cfg.ChainMismatchAction is QUARANTINE, enters quarantine.
+func verifyChain(
+	ctx context.Context,
+	cfg *configuration.Configuration,
+	client *ethereum.Client,
+	quarantine *services.Quarantine,
+) error {
+	err := client.VerifyChain(ctx, cfg.Params.ChainID, cfg.GenesisBlockIdentifier)
+	if err == nil {
+		quarantine.Verified()
+		return nil
+	}
+
+	err = withNetworkHint(err)
+	if cfg.ChainMismatchAction == configuration.QuarantineOnChainMismatch {
+		log.Printf("%s: rejecting all requests", err.Error())
+		quarantine.Enter(err)
+		return nil
+	}
+
+	return err
+}
+
+// withNetworkHint adds the network preset matching the chain
+// of the node to err, if err is a *ethereum.ChainMismatchError.
+func withNetworkHint(err error) error {
+	var mismatch *ethereum.ChainMismatchError
+	if !errors.As(err, &mismatch) {
+		return err
+	}
+
+	presets, presetsErr := configuration.LoadNetworkPresets(os.Getenv(configuration.NetworkPresetsEnv))
+	if presetsErr != nil {
+		return err
+	}
+
+	name := configuration.MatchNetworkPreset(presets, mismatch.NodeChainID, mismatch.NodeGenesis)
+	if len(name) == 0 {
+		return err
+	}
+
+	return fmt.Errorf(
+		"%w (the node is on %s, set %s=%s)",
+		err,
+		name,
+		configuration.NetworkEnv,
+		name,
+	)
+}
+
+// newClient creates the ethereum client configured by cfg.
+func newClient(cfg *configuration.Configuration) (*ethereum.Client, error) {
+	return ethereum.NewClient(cfg.GethURL, cfg.Params, &ethereum.ClientOptions{
+		SkipAdminCalls:      cfg.SkipGethAdmin,
+		EmitApprovals:       cfg.EnableApprovalOperations,
+		EntryPoints:         cfg.UserOperationEntryPoints,
+		WatchedAddresses:    cfg.WatchedAddresses,
+		Lag:                 cfg.NodeLag,
+		CheckInvariants:     cfg.EnableInvariantChecks,
+		CollapseOperations:  cfg.CollapseOperations,
+		ZeroValueOperations: cfg.ZeroValueOperations,
+		DustThreshold:       cfg.DustThreshold,
+		CustomTracer:        cfg.CustomTracer,
+		Labels:              cfg.Labels,
+		ABIRegistry:         cfg.ABIRegistry,
+		Transformers:        cfg.BlockTransformers,
+		BalanceCacheSize:    cfg.BalanceCacheSize,
+		GraphQLBatchSize:    cfg.GraphQLBatchSize,
+		RewardRecipient:     cfg.RewardRecipient,
+		TraceStartIndex:     cfg.TraceStartIndex,
+		ArchiveURLs:         cfg.ArchiveURLs,
+		HedgeDelay:          cfg.HedgeDelay,
+		Proxy:               cfg.UpstreamProxy,
+	})
+}
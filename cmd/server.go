@@ -0,0 +1,153 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// readTimeout is the maximum duration for reading the entire
+	// request, including the body.
+	readTimeout = 5 * time.Second
+
+	// writeTimeout is the maximum duration before timing out
+	// writes of the response. It is reset whenever a new
+	// request's header is read.
+	writeTimeout = 120 * time.Second
+
+	// idleTimeout is the maximum amount of time to wait for the
+	// next request when keep-alives are enabled.
+	idleTimeout = 30 * time.Second
+)
+
+// newServer creates an *http.Server serving handler with
+// the timeouts and limits of cfg. Timeouts that are not
+// configured use the defaults above.
+func newServer(cfg *configuration.HTTPServerConfig, handler http.Handler) *http.Server {
+	server := &http.Server{
+		Handler:        handler,
+		ReadTimeout:    readTimeout,
+		WriteTimeout:   writeTimeout,
+		IdleTimeout:    idleTimeout,
+		MaxHeaderBytes: cfg.MaxHeaderBytes,
+	}
+	if cfg.ReadTimeout > 0 {
+		server.ReadTimeout = cfg.ReadTimeout
+	}
+	if cfg.WriteTimeout > 0 {
+		server.WriteTimeout = cfg.WriteTimeout
+	}
+	if cfg.IdleTimeout > 0 {
+		server.IdleTimeout = cfg.IdleTimeout
+	}
+
+	return server
+}
+
+// newTLSConfig returns the *tls.Config HTTPS is served with,
+// or nil if cfg does not configure HTTPS. If client CAs are
+// configured, client certificates are requested and verified
+// but not required, so that only the endpoints that need them
+// reject clients without one (see
+// services.ConstructionAccessMiddleware).
+func newTLSConfig(cfg *configuration.HTTPServerConfig) (*tls.Config, error) {
+	if len(cfg.TLSCertFile) == 0 {
+		return nil, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to load TLS certificate %s", err, cfg.TLSCertFile)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if len(cfg.ConstructionClientCAFile) > 0 {
+		pem, err := ioutil.ReadFile(cfg.ConstructionClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to read client CAs %s", err, cfg.ConstructionClientCAFile)
+		}
+
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.ConstructionClientCAFile)
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return tlsConfig, nil
+}
+
+// serve listens on every address and serves handler in g
+// until ctx is done. All addresses are bound before serve
+// returns, so a taken port fails startup. If cfg configures
+// HTTPS, it is served on every address.
+func serve(
+	ctx context.Context,
+	g *errgroup.Group,
+	cfg *configuration.HTTPServerConfig,
+	name string,
+	addresses []string,
+	handler http.Handler,
+) error {
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	listenConfig := &net.ListenConfig{KeepAlive: cfg.TCPKeepAlive}
+	for _, address := range addresses {
+		listener, err := listenConfig.Listen(ctx, "tcp", address)
+		if err != nil {
+			return fmt.Errorf("%w: unable to listen on %s", err, address)
+		}
+		if tlsConfig != nil {
+			listener = tls.NewListener(listener, tlsConfig)
+		}
+
+		server := newServer(cfg, handler)
+		g.Go(func() error {
+			log.Printf("%s listening on %s", name, listener.Addr().String())
+			return server.Serve(listener)
+		})
+
+		g.Go(func() error {
+			// If we don't shutdown server in errgroup, it will
+			// never stop because server.Serve doesn't take
+			// any context.
+			<-ctx.Done()
+
+			return server.Shutdown(ctx)
+		})
+	}
+
+	return nil
+}
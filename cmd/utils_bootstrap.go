@@ -15,7 +15,14 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
 	"github.com/coinbase/rosetta-ethereum/ethereum"
+	"github.com/coinbase/rosetta-ethereum/ethereum/networks"
 
 	"github.com/spf13/cobra"
 )
@@ -26,17 +33,77 @@ var (
 		Short: "Generate a bootstrap balance configuration file",
 		Long: `For rosetta-cli testing, it can be useful to generate
 a bootstrap balances file for balances that were created
-at genesis. This command creates such a file given the
-path of an Ethereum genesis file.
+at genesis. This command creates such a file from the
+genesis allocation of the network, read from one of:
+- a genesis file, given as the first argument
+- the genesis file embedded for --network (CORE, BUFFALO,
+  or DEVNET)
+- the genesis state of the node at --geth-url, dumped with
+  debug_dumpBlock (the node must keep the genesis state and
+  the preimages of the genesis accounts)
 
-When calling this command, you must provide 2 arguments:
-[1] the location of the genesis file
+When calling this command, you must provide the location of
+where to write the bootstrap balances file as the last argument:
+[1] the location of the genesis file (without --network or --geth-url)
 [2] the location of where to write bootstrap balances file`,
 		RunE: runUtilsBootstrapCmd,
-		Args: cobra.ExactArgs(2), //nolint:gomnd
+		Args: cobra.RangeArgs(1, 2), //nolint:gomnd
 	}
+
+	utilsBootstrapNetwork string
+	utilsBootstrapGethURL string
 )
 
+// bootstrapNetworks are the networks with an embedded
+// genesis file, keyed by their NETWORK value.
+var bootstrapNetworks = map[string]string{
+	configuration.Core:    networks.CoreNetwork,
+	configuration.Buffalo: networks.BuffaloNetwork,
+	configuration.Devnet:  networks.DevNetwork,
+}
+
+func init() {
+	utilsBootstrapCmd.Flags().StringVar(
+		&utilsBootstrapNetwork,
+		"network",
+		"",
+		"network whose embedded genesis file is used (CORE, BUFFALO, or DEVNET)",
+	)
+	utilsBootstrapCmd.Flags().StringVar(
+		&utilsBootstrapGethURL,
+		"geth-url",
+		"",
+		"url of a node to dump the genesis state from",
+	)
+}
+
 func runUtilsBootstrapCmd(cmd *cobra.Command, args []string) error {
-	return ethereum.GenerateBootstrapFile(args[0], args[1])
+	outputFile := args[len(args)-1]
+	sources := 0
+	for _, source := range []bool{
+		len(args) == 2, //nolint:gomnd
+		len(utilsBootstrapNetwork) > 0,
+		len(utilsBootstrapGethURL) > 0,
+	} {
+		if source {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return errors.New("provide exactly one of a genesis file, --network, or --geth-url")
+	}
+
+	switch {
+	case len(utilsBootstrapNetwork) > 0:
+		network, ok := bootstrapNetworks[strings.ToUpper(utilsBootstrapNetwork)]
+		if !ok {
+			return fmt.Errorf("%s does not have an embedded genesis file", utilsBootstrapNetwork)
+		}
+
+		return ethereum.GenerateNetworkBootstrapFile(network, outputFile)
+	case len(utilsBootstrapGethURL) > 0:
+		return ethereum.GenerateNodeBootstrapFile(context.Background(), utilsBootstrapGethURL, outputFile)
+	default:
+		return ethereum.GenerateBootstrapFile(args[0], outputFile)
+	}
 }
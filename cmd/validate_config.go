@@ -0,0 +1,334 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/archive"
+	"github.com/coinbase/rosetta-ethereum/audit"
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	"github.com/coinbase/rosetta-ethereum/indexer"
+	"github.com/coinbase/rosetta-ethereum/redact"
+	"github.com/coinbase/rosetta-ethereum/submit"
+	"github.com/coinbase/rosetta-ethereum/watermark"
+
+	"github.com/coinbase/rosetta-sdk-go/asserter"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	validateConfigCmd = &cobra.Command{
+		Use:   "validate-config",
+		Short: "Validate the configuration without starting the server",
+		Long: `Performs a dry run of the startup of rosetta-core: loads the
+configuration from the environment and initializes everything
+that does not require the node, including the TLS certificate,
+key, and client CAs. In online mode, the configured stores
+(watermarks, index, audit log, submit queue, and block archive)
+are opened read-only and checked. With GETH_URL set, it then
+connects to the node to verify that it is on the configured
+chain (chain ID and genesis block), that it serves the methods
+rosetta-core requires, and that it is synced.
+
+Every check is printed with what to fix when it fails. The
+command fails if any check fails, so that it can gate production
+rollouts. The server, the node, and the stores are not started,
+and nothing is written.`,
+		RunE:         runValidateConfigCmd,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+	}
+
+	validateConfigTimeout time.Duration
+)
+
+// errInvalidConfiguration is returned when
+// a check of validate-config fails.
+var errInvalidConfiguration = errors.New("configuration is invalid")
+
+func init() {
+	validateConfigCmd.Flags().DurationVar(
+		&validateConfigTimeout,
+		"timeout",
+		30*time.Second, // nolint:gomnd
+		"maximum duration of the node and block archive checks",
+	)
+}
+
+// validationReport prints the outcome of the
+// checks of validate-config as they complete.
+type validationReport struct {
+	failed int
+}
+
+func (r *validationReport) pass(check string, details ...string) {
+	color.Green("PASS %s", check)
+	for _, detail := range details {
+		fmt.Printf("     %s\n", detail)
+	}
+}
+
+func (r *validationReport) warn(check string, reason string) {
+	color.Yellow("WARN %s: %s", check, reason)
+}
+
+func (r *validationReport) skip(check string, reason string) {
+	fmt.Printf("SKIP %s: %s\n", check, reason)
+}
+
+func (r *validationReport) fail(check string, err error) {
+	r.failed++
+	color.Red("FAIL %s: %s", check, err.Error())
+}
+
+// result returns errInvalidConfiguration if any check failed.
+func (r *validationReport) result() error {
+	if r.failed > 0 {
+		return fmt.Errorf("%w: %d checks failed", errInvalidConfiguration, r.failed)
+	}
+
+	fmt.Println("configuration is valid")
+	return nil
+}
+
+func runValidateConfigCmd(cmd *cobra.Command, args []string) error {
+	report := &validationReport{}
+
+	cfg, err := configuration.LoadConfiguration()
+	if err != nil {
+		report.fail("configuration", err)
+		return report.result()
+	}
+	report.pass(
+		"configuration",
+		fmt.Sprintf("mode: %s", cfg.Mode),
+		fmt.Sprintf("network: %s", types.PrintStruct(cfg.Network)),
+		fmt.Sprintf("chain ID: %s", cfg.Params.ChainID.String()),
+		fmt.Sprintf("genesis block: %s", cfg.GenesisBlockIdentifier.Hash),
+	)
+
+	if err := ethereum.SetSystemContracts(cfg.SystemContracts); err != nil {
+		report.fail("system contracts", err)
+	} else {
+		report.pass("system contracts")
+	}
+
+	// Node addresses are printed the way they are logged.
+	redactor, err := redact.New(cfg.LogRedaction)
+	if err != nil {
+		report.fail("log redaction", err)
+	} else {
+		redact.Install(redactor)
+		report.pass("log redaction")
+	}
+
+	if _, err := asserter.NewServer(
+		ethereum.OperationTypes,
+		ethereum.HistoricalBalanceSupported,
+		[]*types.NetworkIdentifier{cfg.Network},
+		cfg.CallMethods(),
+		ethereum.IncludeMempoolCoins,
+		"",
+	); err != nil {
+		report.fail("server asserter", err)
+	} else {
+		report.pass("server asserter", fmt.Sprintf("call methods: %d", len(cfg.CallMethods())))
+	}
+
+	tlsConfig, err := newTLSConfig(&cfg.HTTPServer)
+	switch {
+	case err != nil:
+		report.fail("tls", err)
+	case tlsConfig == nil:
+		report.skip("tls", fmt.Sprintf("HTTP is served (set %s to serve HTTPS)", configuration.TLSCertFileEnv))
+	case tlsConfig.ClientCAs != nil:
+		report.pass("tls", "client certificates are verified by /construction")
+	default:
+		report.pass("tls")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), validateConfigTimeout)
+	defer cancel()
+
+	if cfg.Mode != configuration.Online {
+		report.skip("stores", "stores are not used in offline mode")
+		report.skip("node", "the node is not used in offline mode")
+		return report.result()
+	}
+
+	checkStores(ctx, cfg, report)
+
+	switch {
+	case !cfg.RemoteGeth:
+		report.skip(
+			"node",
+			fmt.Sprintf("the node is started by rosetta-core run (set %s to check a remote node)", configuration.GethEnv),
+		)
+		return report.result()
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		report.fail("node client", err)
+		return report.result()
+	}
+	defer client.Close()
+
+	nodeURL := redact.String(cfg.GethURL)
+	if err := client.CheckChain(ctx, cfg.Params.ChainID, cfg.GenesisBlockIdentifier); err != nil {
+		var mismatch *ethereum.ChainMismatchError
+		if errors.As(err, &mismatch) {
+			report.fail("chain", withNetworkHint(err))
+			return report.result()
+		}
+
+		report.fail("chain", fmt.Errorf("%w (is the node at %s running and reachable?)", err, nodeURL))
+		report.skip("capabilities", "the node is not reachable")
+		report.skip("sync", "the node is not reachable")
+		return report.result()
+	}
+	report.pass("chain", fmt.Sprintf("node at %s is on the configured chain", nodeURL))
+
+	decisions, err := client.CheckCapabilities(ctx)
+	if err != nil {
+		report.fail("capabilities", err)
+	} else {
+		report.pass("capabilities", decisions...)
+	}
+
+	head, _, syncStatus, _, err := client.Status(ctx)
+	switch {
+	case err != nil:
+		report.fail("sync", err)
+	case syncStatus != nil && syncStatus.Synced != nil && !*syncStatus.Synced:
+		target := "unknown"
+		if syncStatus.TargetIndex != nil {
+			target = fmt.Sprintf("%d", *syncStatus.TargetIndex)
+		}
+		report.warn(
+			"sync",
+			fmt.Sprintf("the node is syncing (head block %d, target block %s)", head.Index, target),
+		)
+	default:
+		report.pass("sync", fmt.Sprintf("head block: %d (%s)", head.Index, head.Hash))
+	}
+
+	return report.result()
+}
+
+// checkStores opens every configured store the way run does,
+// but read-only, so that unreadable, corrupt, or incompatible
+// stores are caught before the server starts. Stores that do
+// not exist yet are created by run.
+func checkStores(ctx context.Context, cfg *configuration.Configuration, report *validationReport) {
+	if len(cfg.WatermarkPath) > 0 {
+		if store, err := watermark.Open(cfg.WatermarkPath); err != nil {
+			report.fail("watermarks", err)
+		} else if served := store.Watermarks().ServedBlock; served != nil {
+			report.pass("watermarks", fmt.Sprintf("served block: %d", served.Index))
+		} else {
+			report.pass("watermarks")
+		}
+	}
+
+	if checkStorePath(report, "index", cfg.IndexPath) {
+		checkIndex(report, cfg.IndexPath)
+	}
+
+	if checkStorePath(report, "audit log", cfg.AuditLogPath) {
+		if err := audit.Verify(cfg.AuditLogPath, []byte(cfg.AuditLogKey)); err != nil {
+			report.fail("audit log", err)
+		} else {
+			report.pass("audit log", "every record is correctly chained")
+		}
+	}
+
+	if checkStorePath(report, "submit queue", cfg.SubmitQueuePath) {
+		if q, err := submit.OpenQueueReadOnly(cfg.SubmitQueuePath); err != nil {
+			report.fail("submit queue", err)
+		} else {
+			q.Close()
+			report.pass("submit queue")
+		}
+	}
+
+	if len(cfg.BlockArchiveURL) > 0 {
+		store, err := archive.OpenStore(cfg.BlockArchiveURL)
+		if err != nil {
+			report.fail("block archive", err)
+			return
+		}
+
+		// Reading the manifest does not use the node.
+		manifest, err := archive.New(store, nil, cfg.Network).Manifest(ctx)
+		if err != nil {
+			report.fail("block archive", err)
+			return
+		}
+		if manifest.Head == nil {
+			report.pass("block archive", "no blocks are archived yet")
+		} else {
+			report.pass("block archive", fmt.Sprintf("head block: %d", manifest.Head.Index))
+		}
+	}
+}
+
+// checkIndex opens the index at path read-only
+// and checks that its schema is supported.
+func checkIndex(report *validationReport, path string) {
+	i, err := indexer.OpenReadOnly(path)
+	if err != nil {
+		report.fail("index", err)
+		return
+	}
+	defer i.Close()
+
+	version, err := i.SchemaVersion()
+	if err != nil {
+		report.fail("index", err)
+		return
+	}
+
+	report.pass("index", fmt.Sprintf("schema version: %d", version))
+}
+
+// checkStorePath returns true if the store at path should be
+// opened: it is configured and exists. A store that does not
+// exist yet passes, since it is created by run.
+func checkStorePath(report *validationReport, check string, path string) bool {
+	if len(path) == 0 {
+		return false
+	}
+
+	_, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		report.pass(check, fmt.Sprintf("%s does not exist yet and will be created", path))
+		return false
+	case err != nil:
+		report.fail(check, err)
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/coinbase/rosetta-ethereum/audit"
+	"github.com/coinbase/rosetta-ethereum/configuration"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyAuditLogCmd = &cobra.Command{
+		Use:   "verify-audit-log [path]",
+		Short: "Verify the hash chain of an audit log",
+		Long: `Every record of the audit log contains the hash of the
+previous record. This command recomputes the chain and
+reports the first record that was modified, removed, or
+reordered.
+
+If the log was written with AUDIT_LOG_KEY, the same key
+must be set when running this command.`,
+		RunE: runVerifyAuditLogCmd,
+		Args: cobra.ExactArgs(1),
+	}
+)
+
+func runVerifyAuditLogCmd(cmd *cobra.Command, args []string) error {
+	key := []byte(os.Getenv(configuration.AuditLogKeyEnv))
+	if err := audit.Verify(args[0], key); err != nil {
+		return fmt.Errorf("%w: audit log is invalid", err)
+	}
+
+	fmt.Printf("audit log %s is valid\n", args[0])
+	return nil
+}
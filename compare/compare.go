@@ -0,0 +1,258 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package compare diffs the blocks served by two
+// rosetta-core deployments, so changes to the conversion
+// logic can be validated before they are rolled out.
+package compare
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+// The fields reported in a Discrepancy.
+const (
+	BlockHashField         = "block_identifier.hash"
+	ParentHashField        = "parent_block_identifier.hash"
+	TimestampField         = "timestamp"
+	BlockMetadataField     = "block.metadata"
+	TransactionField       = "transaction"
+	TransactionMetaField   = "transaction.metadata"
+	OperationCountField    = "operations"
+	OperationTypeField     = "operation.type"
+	OperationStatusField   = "operation.status"
+	OperationAccountField  = "operation.account"
+	OperationAmountField   = "operation.amount"
+	RelatedOperationsField = "operation.related_operations"
+	OperationMetadataField = "operation.metadata"
+)
+
+// Discrepancy is a difference between a block served by
+// a deployment (Value) and by the other deployment
+// (OtherValue).
+type Discrepancy struct {
+	BlockIdentifier *types.BlockIdentifier `json:"block_identifier"`
+	TransactionHash string                 `json:"transaction_hash,omitempty"`
+	OperationIndex  *int64                 `json:"operation_index,omitempty"`
+	Field           string                 `json:"field"`
+	Value           string                 `json:"value"`
+	OtherValue      string                 `json:"other_value"`
+}
+
+// Report is the result of comparing a range of blocks.
+type Report struct {
+	Start          int64          `json:"start_index"`
+	End            int64          `json:"end_index"`
+	BlocksCompared int64          `json:"blocks_compared"`
+	Discrepancies  []*Discrepancy `json:"discrepancies"`
+
+	// Fields is the number of discrepancies per field.
+	Fields map[string]int `json:"discrepancies_by_field"`
+}
+
+// NewReport creates an empty *Report for
+// the blocks from start to end.
+func NewReport(start int64, end int64) *Report {
+	return &Report{
+		Start:         start,
+		End:           end,
+		Discrepancies: []*Discrepancy{},
+		Fields:        map[string]int{},
+	}
+}
+
+// Add compares block with other and adds
+// their discrepancies to the report.
+func (r *Report) Add(block *types.Block, other *types.Block, includeMetadata bool) {
+	r.BlocksCompared++
+	for _, discrepancy := range Blocks(block, other, includeMetadata) {
+		r.Discrepancies = append(r.Discrepancies, discrepancy)
+		r.Fields[discrepancy.Field]++
+	}
+}
+
+// Blocks returns the discrepancies between block and other.
+// Transactions are matched by hash and operations by index.
+// Metadata is only compared if includeMetadata is true.
+func Blocks(block *types.Block, other *types.Block, includeMetadata bool) []*Discrepancy {
+	c := &comparison{blockIdentifier: block.BlockIdentifier}
+
+	c.field("", nil, BlockHashField, block.BlockIdentifier.Hash, other.BlockIdentifier.Hash)
+	c.field(
+		"",
+		nil,
+		ParentHashField,
+		block.ParentBlockIdentifier.Hash,
+		other.ParentBlockIdentifier.Hash,
+	)
+	c.field(
+		"",
+		nil,
+		TimestampField,
+		strconv.FormatInt(block.Timestamp, 10),
+		strconv.FormatInt(other.Timestamp, 10),
+	)
+	if includeMetadata {
+		c.field("", nil, BlockMetadataField, encode(block.Metadata), encode(other.Metadata))
+	}
+
+	transactions := map[string]*types.Transaction{}
+	for _, tx := range block.Transactions {
+		transactions[tx.TransactionIdentifier.Hash] = tx
+	}
+	otherTransactions := map[string]*types.Transaction{}
+	for _, tx := range other.Transactions {
+		otherTransactions[tx.TransactionIdentifier.Hash] = tx
+	}
+
+	hashes := make([]string, 0, len(transactions)+len(otherTransactions))
+	for hash := range transactions {
+		hashes = append(hashes, hash)
+	}
+	for hash := range otherTransactions {
+		if _, ok := transactions[hash]; !ok {
+			hashes = append(hashes, hash)
+		}
+	}
+	sort.Strings(hashes)
+
+	for _, hash := range hashes {
+		tx, ok := transactions[hash]
+		otherTx, otherOk := otherTransactions[hash]
+		if !ok || !otherOk {
+			c.field(hash, nil, TransactionField, strconv.FormatBool(ok), strconv.FormatBool(otherOk))
+			continue
+		}
+
+		c.transaction(tx, otherTx, includeMetadata)
+	}
+
+	return c.discrepancies
+}
+
+// comparison collects the discrepancies of a block.
+type comparison struct {
+	blockIdentifier *types.BlockIdentifier
+	discrepancies   []*Discrepancy
+}
+
+func (c *comparison) transaction(tx *types.Transaction, other *types.Transaction, includeMetadata bool) {
+	hash := tx.TransactionIdentifier.Hash
+	if includeMetadata {
+		c.field(hash, nil, TransactionMetaField, encode(tx.Metadata), encode(other.Metadata))
+	}
+
+	c.field(
+		hash,
+		nil,
+		OperationCountField,
+		strconv.Itoa(len(tx.Operations)),
+		strconv.Itoa(len(other.Operations)),
+	)
+
+	for i := 0; i < len(tx.Operations) && i < len(other.Operations); i++ {
+		op := tx.Operations[i]
+		otherOp := other.Operations[i]
+		index := op.OperationIdentifier.Index
+
+		c.field(hash, &index, OperationTypeField, op.Type, otherOp.Type)
+		c.field(hash, &index, OperationStatusField, stringValue(op.Status), stringValue(otherOp.Status))
+		c.field(hash, &index, OperationAccountField, encode(op.Account), encode(otherOp.Account))
+		c.field(hash, &index, OperationAmountField, amount(op.Amount), amount(otherOp.Amount))
+		c.field(
+			hash,
+			&index,
+			RelatedOperationsField,
+			encode(op.RelatedOperations),
+			encode(otherOp.RelatedOperations),
+		)
+		if includeMetadata {
+			c.field(hash, &index, OperationMetadataField, encode(op.Metadata), encode(otherOp.Metadata))
+		}
+	}
+}
+
+// field records a discrepancy if value and otherValue differ.
+func (c *comparison) field(
+	hash string,
+	index *int64,
+	field string,
+	value string,
+	otherValue string,
+) {
+	if value == otherValue {
+		return
+	}
+
+	c.discrepancies = append(c.discrepancies, &Discrepancy{
+		BlockIdentifier: c.blockIdentifier,
+		TransactionHash: hash,
+		OperationIndex:  index,
+		Field:           field,
+		Value:           value,
+		OtherValue:      otherValue,
+	})
+}
+
+func amount(amount *types.Amount) string {
+	if amount == nil {
+		return ""
+	}
+
+	if amount.Currency == nil {
+		return amount.Value
+	}
+
+	// Tokens sharing a symbol are distinguished
+	// by the contract address in their metadata.
+	if metadata := encode(amount.Currency.Metadata); len(metadata) > 0 {
+		return fmt.Sprintf("%s %s %s", amount.Value, amount.Currency.Symbol, metadata)
+	}
+
+	return fmt.Sprintf("%s %s", amount.Value, amount.Currency.Symbol)
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
+
+// encode returns the JSON encoding of value (with
+// sorted map keys) or an empty string if value is
+// empty.
+func encode(value interface{}) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return ""
+		}
+	case []*types.OperationIdentifier:
+		if len(v) == 0 {
+			return ""
+		}
+	case *types.AccountIdentifier:
+		if v == nil {
+			return ""
+		}
+	}
+
+	return types.PrintStruct(value)
+}
@@ -0,0 +1,159 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compare
+
+import (
+	"testing"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var currency = &types.Currency{Symbol: "CORE", Decimals: 18}
+
+func testBlock() *types.Block {
+	return &types.Block{
+		BlockIdentifier:       &types.BlockIdentifier{Index: 2, Hash: "block 2"},
+		ParentBlockIdentifier: &types.BlockIdentifier{Index: 1, Hash: "block 1"},
+		Timestamp:             2000,
+		Transactions: []*types.Transaction{
+			{
+				TransactionIdentifier: &types.TransactionIdentifier{Hash: "tx 1"},
+				Operations: []*types.Operation{
+					{
+						OperationIdentifier: &types.OperationIdentifier{Index: 0},
+						Type:                "CALL",
+						Status:              types.String("SUCCESS"),
+						Account:             &types.AccountIdentifier{Address: "0x1"},
+						Amount:              &types.Amount{Value: "-100", Currency: currency},
+					},
+					{
+						OperationIdentifier: &types.OperationIdentifier{Index: 1},
+						RelatedOperations:   []*types.OperationIdentifier{{Index: 0}},
+						Type:                "CALL",
+						Status:              types.String("SUCCESS"),
+						Account:             &types.AccountIdentifier{Address: "0x2"},
+						Amount:              &types.Amount{Value: "100", Currency: currency},
+						Metadata:            map[string]interface{}{"label": "foundation"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestBlocks_Equal(t *testing.T) {
+	assert.Empty(t, Blocks(testBlock(), testBlock(), true))
+}
+
+func TestBlocks(t *testing.T) {
+	block := testBlock()
+	other := testBlock()
+	other.ParentBlockIdentifier.Hash = "orphan"
+	other.Transactions[0].Operations[1].Type = "FEE"
+	other.Transactions[0].Operations[1].Amount.Value = "99"
+	other.Transactions[0].Operations[1].Metadata = nil
+	other.Transactions = append(other.Transactions, &types.Transaction{
+		TransactionIdentifier: &types.TransactionIdentifier{Hash: "tx 2"},
+	})
+
+	index := int64(1)
+	blockIdentifier := block.BlockIdentifier
+	assert.Equal(t, []*Discrepancy{
+		{
+			BlockIdentifier: blockIdentifier,
+			Field:           ParentHashField,
+			Value:           "block 1",
+			OtherValue:      "orphan",
+		},
+		{
+			BlockIdentifier: blockIdentifier,
+			TransactionHash: "tx 1",
+			OperationIndex:  &index,
+			Field:           OperationTypeField,
+			Value:           "CALL",
+			OtherValue:      "FEE",
+		},
+		{
+			BlockIdentifier: blockIdentifier,
+			TransactionHash: "tx 1",
+			OperationIndex:  &index,
+			Field:           OperationAmountField,
+			Value:           "100 CORE",
+			OtherValue:      "99 CORE",
+		},
+		{
+			BlockIdentifier: blockIdentifier,
+			TransactionHash: "tx 2",
+			Field:           TransactionField,
+			Value:           "false",
+			OtherValue:      "true",
+		},
+	}, Blocks(block, other, false))
+
+	// Metadata is only compared when requested.
+	discrepancies := Blocks(block, other, true)
+	assert.Len(t, discrepancies, 5)
+	assert.Equal(t, OperationMetadataField, discrepancies[3].Field)
+	assert.Equal(t, `{"label":"foundation"}`, discrepancies[3].Value)
+	assert.Empty(t, discrepancies[3].OtherValue)
+}
+
+func TestBlocks_TokenCollision(t *testing.T) {
+	usdt := &types.Currency{
+		Symbol:   "USDT",
+		Decimals: 6,
+		Metadata: map[string]interface{}{"token_address": "0x900101d06A7426441Ae63e9AB3B9b0F63Be145F1"},
+	}
+	fakeUSDT := &types.Currency{
+		Symbol:   "USDT",
+		Decimals: 6,
+		Metadata: map[string]interface{}{"token_address": "0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d"},
+	}
+
+	block := testBlock()
+	block.Transactions[0].Operations[0].Amount.Currency = usdt
+	other := testBlock()
+	other.Transactions[0].Operations[0].Amount.Currency = fakeUSDT
+	assert.Equal(t, []*Discrepancy{
+		{
+			BlockIdentifier: block.BlockIdentifier,
+			TransactionHash: "tx 1",
+			OperationIndex:  new(int64),
+			Field:           OperationAmountField,
+			Value:           `-100 USDT {"token_address":"0x900101d06A7426441Ae63e9AB3B9b0F63Be145F1"}`,
+			OtherValue:      `-100 USDT {"token_address":"0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d"}`,
+		},
+	}, Blocks(block, other, false))
+
+	// The same token is not a discrepancy.
+	other.Transactions[0].Operations[0].Amount.Currency = usdt
+	assert.Empty(t, Blocks(block, other, false))
+}
+
+func TestReport(t *testing.T) {
+	report := NewReport(2, 3)
+	report.Add(testBlock(), testBlock(), false)
+
+	other := testBlock()
+	other.Transactions[0].Operations = other.Transactions[0].Operations[:1]
+	report.Add(testBlock(), other, false)
+
+	assert.Equal(t, int64(2), report.BlocksCompared)
+	assert.Len(t, report.Discrepancies, 1)
+	assert.Equal(t, map[string]int{OperationCountField: 1}, report.Fields)
+	assert.Equal(t, "2", report.Discrepancies[0].Value)
+	assert.Equal(t, "1", report.Discrepancies[0].OtherValue)
+}
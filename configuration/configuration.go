@@ -17,13 +17,24 @@ package configuration
 import (
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
+	"net"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/coinbase/rosetta-ethereum/ethereum"
+	"github.com/coinbase/rosetta-ethereum/ethereum/networks"
+	"github.com/coinbase/rosetta-ethereum/indexer"
+	"github.com/coinbase/rosetta-ethereum/redact"
 
 	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/coinbase/rosetta-sdk-go/utils"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -73,6 +84,12 @@ const (
 	// read to determine network.
 	NetworkEnv = "NETWORK"
 
+	// NetworkPresetsEnv is an optional environment variable
+	// containing the path of a directory of network preset
+	// files (see NetworkPreset). Presets in the directory are
+	// added to (or replace) the embedded Corechain presets.
+	NetworkPresetsEnv = "NETWORK_PRESETS_PATH"
+
 	// ChainIDEnv is the environment variable read
 	// to determine chainID
 	ChainIDEnv = "CHAINID"
@@ -92,33 +109,1040 @@ const (
 	// when GethEnv is not populated.
 	DefaultGethURL = "http://localhost:8579"
 
-	// BuffaloGethURL is the URL of the running
-	// test network `Buffalo` geth node. This is used
-	// when GethEnv is not populated and NetworkEnv is `BUFFALO`
-	BuffaloGethURL = "http://localhost:8575"
-
 	// SkipGethAdminEnv is an optional environment variable
 	// to skip geth `admin` calls which are typically not supported
 	// by hosted node services. When not set, defaults to false.
 	SkipGethAdminEnv = "SKIP_GETH_ADMIN"
 
+	// ValidationModeEnv is an optional environment variable
+	// used to determine if responses are validated against
+	// the Rosetta specification before being served. When not
+	// set, defaults to PERMISSIVE.
+	ValidationModeEnv = "VALIDATION_MODE"
+
+	// MetricsEnv is an optional environment variable
+	// used to expose metrics in the Prometheus format at
+	// /metrics. When not set, defaults to false.
+	MetricsEnv = "ENABLE_METRICS"
+
+	// LogRedactionEnv is an optional environment variable
+	// pointing to a JSON file that lists additional fields,
+	// headers, and patterns to redact from logs and error
+	// messages. Private keys, signed payloads, and auth
+	// headers are always redacted.
+	LogRedactionEnv = "LOG_REDACTION_CONFIG"
+
+	// NonceTrackerEnv is an optional environment variable
+	// pointing to a directory used to persist nonces allocated
+	// in /construction/metadata. When set, nonces are allocated
+	// server-side so that concurrent construction flows for the
+	// same sender never receive the same nonce.
+	NonceTrackerEnv = "NONCE_TRACKER_PATH"
+
+	// IndexEnv is an optional environment variable pointing
+	// to a directory used to persist the local index. When
+	// set, rosetta-core indexes confirmed blocks in the
+	// background.
+	IndexEnv = "INDEX_PATH"
+
+	// AccountSummaryEnv is an optional environment variable
+	// used to serve the non-standard /account/summary endpoint.
+	// It requires IndexEnv to be set. When not set, defaults
+	// to false.
+	AccountSummaryEnv = "ENABLE_ACCOUNT_SUMMARY"
+
+	// BlockInlineTransactionsEnv is an optional environment
+	// variable used to limit the number of transactions
+	// returned inline by /block. Any additional transactions
+	// are returned in other_transactions and must be fetched
+	// with /block/transaction. When not set (or set to 0),
+	// all transactions are returned inline.
+	BlockInlineTransactionsEnv = "BLOCK_INLINE_TRANSACTIONS"
+
+	// ApprovalOperationsEnv is an optional environment variable
+	// used to surface ERC-20 Approval events as zero-amount
+	// APPROVAL operations. When not set, defaults to false.
+	ApprovalOperationsEnv = "ENABLE_APPROVAL_OPERATIONS"
+
+	// RuntimeConfigEnv is an optional environment variable
+	// pointing to a JSON file of settings that can be changed
+	// while rosetta-core is running (see RuntimeConfig). Settings
+	// in the file override their environment variables. The file
+	// is re-read by Configuration.Reload.
+	RuntimeConfigEnv = "RUNTIME_CONFIG"
+
+	// AdminReloadEnv is an optional environment variable
+	// used to serve POST /admin/reload, which reloads the
+	// configuration like SIGHUP does. When not set, defaults
+	// to false.
+	AdminReloadEnv = "ENABLE_ADMIN_RELOAD"
+
+	// WatchedAddressesEnv is an optional environment variable
+	// pointing to a JSON file containing a list of watched
+	// addresses. When set, rosetta-core serves blocks in filtered
+	// block mode: transactions that cannot touch a watched address
+	// (based on their sender, recipient, and logs bloom) are
+	// returned with only their fee operations.
+	WatchedAddressesEnv = "WATCHED_ADDRESSES"
+
+	// ReferenceURLsEnv is an optional environment variable
+	// containing a comma-separated list of JSON-RPC endpoints
+	// (other nodes or public endpoints) the node is compared
+	// against. When set, /network/status reports the node as
+	// lagging if it falls more than MaxNodeLagEnv blocks behind.
+	ReferenceURLsEnv = "REFERENCE_URLS"
+
+	// MaxNodeLagEnv is an optional environment variable used
+	// to set the number of blocks the node can fall behind the
+	// reference nodes before it is degraded. When not set,
+	// defaults to ethereum.DefaultMaxLag.
+	MaxNodeLagEnv = "MAX_NODE_LAG"
+
+	// RejectStaleReadsEnv is an optional environment variable
+	// used to reject /account/balance requests without a block
+	// identifier while the node is degraded. When not set,
+	// defaults to false.
+	RejectStaleReadsEnv = "REJECT_STALE_READS"
+
+	// IndexRetentionBlocksEnv is an optional environment variable
+	// used to prune addresses from the local index once they have
+	// had no activity for this many blocks. It requires IndexEnv
+	// to be set. When not set (or set to 0), addresses are never
+	// pruned.
+	IndexRetentionBlocksEnv = "INDEX_RETENTION_BLOCKS"
+
+	// IndexAddressPrefixesEnv is an optional environment variable
+	// containing a comma-separated list of hex address prefixes.
+	// When set, only addresses matching one of the prefixes are
+	// indexed. It requires IndexEnv to be set.
+	IndexAddressPrefixesEnv = "INDEX_ADDRESS_PREFIXES"
+
+	// InvariantChecksEnv is an optional environment variable
+	// used to verify that the operations of every converted
+	// block balance (the sum of all amounts equals the block
+	// issuance minus burns). Blocks that do not balance are
+	// rejected. When not set, defaults to false.
+	InvariantChecksEnv = "ENABLE_INVARIANT_CHECKS"
+
+	// UpstreamProxyEnv is an optional environment variable
+	// containing the URL of an HTTP(S) or SOCKS5 proxy used
+	// for all connections to the node and the reference nodes.
+	// When not set, the standard HTTP_PROXY, HTTPS_PROXY, and
+	// NO_PROXY environment variables are honored.
+	UpstreamProxyEnv = "UPSTREAM_PROXY"
+
+	// ResponseCacheSizeEnv is an optional environment variable
+	// used to cache up to this many /block, /block/transaction,
+	// and /network/options responses in memory. When not set
+	// (or set to 0), responses are not cached.
+	ResponseCacheSizeEnv = "RESPONSE_CACHE_SIZE"
+
+	// ResponseCacheTTLEnv is an optional environment variable
+	// used to set how long responses for blocks near the tip
+	// are cached (responses for finalized blocks never expire).
+	// It is parsed with time.ParseDuration. When not set,
+	// defaults to DefaultResponseCacheTTL.
+	ResponseCacheTTLEnv = "RESPONSE_CACHE_TTL"
+
+	// DefaultResponseCacheTTL is the default
+	// value of ResponseCacheTTLEnv.
+	DefaultResponseCacheTTL = 3 * time.Second
+
+	// AuditLogEnv is an optional environment variable pointing
+	// to a file used to record every /construction/submit
+	// request in a hash-chained audit log. When not set,
+	// submissions are not audited.
+	AuditLogEnv = "AUDIT_LOG_PATH"
+
+	// AuditLogKeyEnv is an optional environment variable
+	// containing the key used to chain the records of the
+	// audit log with HMAC-SHA256. When not set, records are
+	// chained with SHA256.
+	AuditLogKeyEnv = "AUDIT_LOG_KEY"
+
+	// DisabledModulesEnv is an optional environment variable
+	// containing a comma-separated list of modules (see Module)
+	// to disable. Endpoints of disabled modules return
+	// an "endpoint disabled" error. When not set, all modules
+	// are enabled.
+	DisabledModulesEnv = "DISABLED_MODULES"
+
+	// TimestampStartIndexEnv is an optional environment variable
+	// used to set the index of the first block with a valid
+	// timestamp. When not set, the index is computed from the
+	// node in online mode (see ethereum.Client.TimestampStartIndex)
+	// and is the block after genesis in offline mode.
+	TimestampStartIndexEnv = "TIMESTAMP_START_INDEX"
+
+	// CollapseOperationsEnv is an optional environment variable
+	// used to collapse the trace operations of every transaction
+	// into a single operation per account holding its net balance
+	// change. When not set, defaults to false.
+	CollapseOperationsEnv = "COLLAPSE_OPERATIONS"
+
+	// StakedBalancesEnv is an optional environment variable
+	// used to include the CORE delegated through PledgeAgent
+	// and the unclaimed delegation rewards of an account in
+	// the /account/balance metadata. When not set, defaults
+	// to false.
+	StakedBalancesEnv = "ENABLE_STAKED_BALANCES"
+
+	// GasLimitMultiplierEnv is an optional environment variable
+	// containing the multiplier applied to the gas limits
+	// estimated (with eth_estimateGas) by /construction/metadata,
+	// to absorb estimation variance. It must be at least 1. When
+	// not set, defaults to 1.
+	GasLimitMultiplierEnv = "GAS_LIMIT_MULTIPLIER"
+
+	// GasLimitCapEnv is an optional environment variable used
+	// to cap the gas limits estimated by /construction/metadata
+	// (after GasLimitMultiplierEnv is applied). When not set
+	// (or set to 0), estimates are not capped.
+	GasLimitCapEnv = "GAS_LIMIT_CAP"
+
+	// GasLimitDefaultsEnv is an optional environment variable
+	// containing a comma-separated list of <type>=<gas limit>
+	// pairs (see GasLimitType) overriding the gas limits used
+	// by /construction/metadata when gas is not estimated.
+	GasLimitDefaultsEnv = "GAS_LIMIT_DEFAULTS"
+
+	// UpstreamReportEnv is an optional environment variable
+	// used to serve GET /admin/upstream, which reports the
+	// number and class of upstream RPC calls made to serve
+	// each endpoint. When not set, defaults to false.
+	UpstreamReportEnv = "ENABLE_UPSTREAM_REPORT"
+
+	// ChainMismatchEnv is an optional environment variable used
+	// to determine what happens when the chain ID or genesis block
+	// of the node differ from the configured network (see
+	// ChainMismatchAction). When not set, defaults to HALT.
+	ChainMismatchEnv = "CHAIN_MISMATCH_ACTION"
+
+	// MaxRequestBodySizeEnv is an optional environment variable
+	// used to set the maximum size (in bytes) of request bodies.
+	// Larger requests are rejected before they are decoded. When
+	// not set, defaults to DefaultMaxRequestBodySize.
+	MaxRequestBodySizeEnv = "MAX_REQUEST_BODY_SIZE"
+
+	// DefaultMaxRequestBodySize is the default
+	// value of MaxRequestBodySizeEnv.
+	DefaultMaxRequestBodySize = int64(1 << 20) // nolint:gomnd
+
+	// RequestBodySizeLimitsEnv is an optional environment variable
+	// containing a comma-separated list of <path>=<bytes> pairs
+	// overriding MaxRequestBodySizeEnv for specific endpoints
+	// (i.e. "/construction/combine=4194304").
+	RequestBodySizeLimitsEnv = "REQUEST_BODY_SIZE_LIMITS"
+
+	// StrictJSONDecodingEnv is an optional environment variable
+	// used to reject requests with fields that are not part of
+	// the Rosetta specification of their endpoint. When not set,
+	// defaults to false.
+	StrictJSONDecodingEnv = "STRICT_JSON_DECODING"
+
+	// ArchiveURLsEnv is an optional environment variable
+	// containing a comma-separated list of archive node
+	// endpoints. When set, reads of historical blocks, traces,
+	// and balances are balanced across the node and the archive
+	// nodes that are on the same chain as the node.
+	ArchiveURLsEnv = "ARCHIVE_URLS"
+
+	// NonceMonitorAddressesEnv is an optional environment
+	// variable containing a comma-separated list of addresses
+	// (i.e. hot wallets) whose nonces are monitored. When set,
+	// gaps and stuck nonces in the mempool are logged and
+	// reported as metrics.
+	NonceMonitorAddressesEnv = "NONCE_MONITOR_ADDRESSES"
+
+	// NonceStuckAfterEnv is an optional environment variable
+	// used to change how long the nonce of a monitored address
+	// can stay the same while it has transactions in the
+	// mempool before it is considered stuck. It is parsed with
+	// time.ParseDuration and defaults to
+	// ethereum.DefaultNonceStuckAfter.
+	NonceStuckAfterEnv = "NONCE_STUCK_AFTER"
+
+	// CustomTracerEnv is an optional environment variable
+	// containing the path of a JS tracer file or the name of
+	// a native tracer run on every transaction in addition to
+	// the call tracer. Its output is surfaced in the metadata
+	// of every transaction.
+	CustomTracerEnv = "CUSTOM_TRACER"
+
+	// CustomTracerProcessorEnv is an optional environment
+	// variable containing the name of the registered
+	// ethereum.TraceProcessor that converts the output of
+	// CustomTracerEnv into operations.
+	CustomTracerProcessorEnv = "CUSTOM_TRACER_PROCESSOR"
+
+	// LabelsPathEnv is an optional environment variable
+	// containing the path of a JSON file of address labels.
+	// Labeled accounts are labeled in the metadata of their
+	// account identifiers.
+	LabelsPathEnv = "LABELS_PATH"
+
+	// MulticallContractEnv is an optional environment variable
+	// containing the address of the Multicall3 contract used to
+	// execute batches of calls in the "eth_call" /call method.
+	// It defaults to ethereum.MulticallContract.
+	MulticallContractEnv = "MULTICALL_CONTRACT"
+
+	// BlockTransformersEnv is an optional environment variable
+	// containing a comma-separated list of the names of the
+	// registered ethereum.BlockTransformers applied to every
+	// converted block, in order.
+	BlockTransformersEnv = "BLOCK_TRANSFORMERS"
+
+	// BalanceCacheSizeEnv is an optional environment variable
+	// used to cache up to this many historical balances in
+	// memory, keyed by address and block hash. When not set
+	// (or set to 0), balances are not cached.
+	BalanceCacheSizeEnv = "BALANCE_CACHE_SIZE"
+
+	// BlockArchiveURLEnv is an optional environment variable
+	// containing the location of the block archive (see
+	// archive.OpenStore), i.e. s3://bucket/prefix.
+	BlockArchiveURLEnv = "BLOCK_ARCHIVE_URL"
+
+	// BlockArchiveModeEnv is an optional environment variable
+	// containing a comma-separated list of the ways the block
+	// archive is used (see BlockArchiveMode). When not set,
+	// blocks are only exported.
+	BlockArchiveModeEnv = "BLOCK_ARCHIVE_MODE"
+
+	// HeadEventsEnv is an optional environment variable used
+	// to serve GET /events/heads, a Server-Sent Events stream
+	// of the blocks added to and removed from the canonical
+	// chain, and POST /events/blocks. When not set, defaults
+	// to false.
+	HeadEventsEnv = "ENABLE_HEAD_EVENTS"
+
+	// BlockEventsHistoryEnv is an optional environment variable
+	// containing the number of recent block events kept for
+	// clients of /events/blocks and /events/heads that fell
+	// behind. When not set (or set to 0), 1024 events are kept.
+	BlockEventsHistoryEnv = "BLOCK_EVENTS_HISTORY"
+
+	// GraphQLBatchSizeEnv is an optional environment variable
+	// used to fetch transaction receipts over GraphQL for this
+	// many consecutive blocks at once, instead of fetching the
+	// receipts of every block with JSON-RPC. When not set (or
+	// set to 0), receipts are fetched with JSON-RPC.
+	GraphQLBatchSizeEnv = "GRAPHQL_BATCH_SIZE"
+
+	// AdminMaintenanceEnv is an optional environment variable
+	// used to serve /admin/maintenance, which puts rosetta-core
+	// in (and takes it out of) maintenance mode. When not set,
+	// defaults to false.
+	AdminMaintenanceEnv = "ENABLE_ADMIN_MAINTENANCE"
+
+	// AdminDebugUIEnv is an optional environment variable used
+	// to serve a debug UI under /admin/debug/ showing the recent
+	// blocks as they are converted, the upstream calls, and the
+	// metrics. When not set, defaults to false.
+	AdminDebugUIEnv = "ENABLE_ADMIN_DEBUG_UI"
+
+	// MaintenanceWindowsEnv is an optional environment variable
+	// containing a comma-separated list of scheduled maintenance
+	// windows, each a <start>/<end> pair of RFC 3339 timestamps.
+	// rosetta-core is in maintenance mode during every window.
+	MaintenanceWindowsEnv = "MAINTENANCE_WINDOWS"
+
+	// RewardRecipientEnv is an optional environment variable
+	// determining the account MINER_REWARD operations are
+	// attributed to (see ethereum.RewardRecipient). When not
+	// set, they are attributed to the coinbase.
+	RewardRecipientEnv = "REWARD_RECIPIENT"
+
+	// SubmitQueueEnv is an optional environment variable
+	// pointing to a directory used to persist transactions
+	// accepted by /construction/submit. When set, accepted
+	// transactions are rebroadcast until they are mined.
+	SubmitQueueEnv = "SUBMIT_QUEUE_PATH"
+
+	// TxPoolMetricsEnv is an optional environment variable
+	// used to report the size of the mempool of the node as
+	// metrics, from the samples also used to monitor the
+	// addresses in NonceMonitorAddressesEnv. Transactions of the addresses
+	// in NonceMonitorAddressesEnv that stay pending for longer
+	// than NonceStuckAfterEnv are logged. When not set, defaults
+	// to false.
+	TxPoolMetricsEnv = "ENABLE_TXPOOL_METRICS"
+
+	// ZeroValueOperationsEnv is an optional environment variable
+	// determining how trace operations that do not move CORE are
+	// emitted (see ethereum.ZeroValueOperations). When not set,
+	// zero-value calls are skipped.
+	ZeroValueOperationsEnv = "ZERO_VALUE_OPERATIONS"
+
+	// DustThresholdEnv is an optional environment variable
+	// containing the minimum value (in wei) of trace operations.
+	// Trace operations moving less are replaced by a DUST
+	// operation per account with their net amount. When not set,
+	// no trace operation is dropped.
+	DustThresholdEnv = "DUST_THRESHOLD"
+
+	// HTTPReadTimeoutEnv, HTTPWriteTimeoutEnv, and
+	// HTTPIdleTimeoutEnv are optional environment variables
+	// used to change the read, write, and idle timeouts of the
+	// HTTP servers (see HTTPServerConfig). They are parsed with
+	// time.ParseDuration.
+	HTTPReadTimeoutEnv  = "HTTP_READ_TIMEOUT"
+	HTTPWriteTimeoutEnv = "HTTP_WRITE_TIMEOUT"
+	HTTPIdleTimeoutEnv  = "HTTP_IDLE_TIMEOUT"
+
+	// HTTPMaxHeaderBytesEnv is an optional environment variable
+	// used to set the maximum size (in bytes) of request headers.
+	HTTPMaxHeaderBytesEnv = "HTTP_MAX_HEADER_BYTES"
+
+	// HTTPKeepAliveEnv is an optional environment variable used
+	// to set the TCP keep-alive period of accepted connections.
+	// It is parsed with time.ParseDuration, and a negative
+	// period disables keep-alives.
+	HTTPKeepAliveEnv = "HTTP_TCP_KEEP_ALIVE"
+
+	// ListenAddressesEnv is an optional environment variable
+	// containing a comma-separated list of <host>:<port>
+	// addresses the Rosetta API is served on. When set, PORT
+	// is not used.
+	ListenAddressesEnv = "LISTEN_ADDRESSES"
+
+	// AdminListenAddressEnv is an optional environment variable
+	// containing the <host>:<port> address /metrics and the
+	// /admin endpoints are served on (i.e. 127.0.0.1:9090). When
+	// set, they are no longer served with the Rosetta API.
+	AdminListenAddressEnv = "ADMIN_LISTEN_ADDRESS"
+
+	// TLSCertFileEnv and TLSKeyFileEnv are optional environment
+	// variables pointing to the PEM encoded certificate (chain)
+	// and private key used to serve HTTPS. They must be set
+	// together. When not set, HTTP is served.
+	TLSCertFileEnv = "TLS_CERT_FILE"
+	TLSKeyFileEnv  = "TLS_KEY_FILE"
+
+	// ConstructionClientCAFileEnv is an optional environment
+	// variable pointing to the PEM encoded certificates of the
+	// CAs of the clients allowed to call the /construction
+	// endpoints. When set, /construction requests without a
+	// client certificate signed by one of them are rejected,
+	// while the other endpoints do not require one. It requires
+	// TLSCertFileEnv and TLSKeyFileEnv.
+	ConstructionClientCAFileEnv = "CONSTRUCTION_CLIENT_CA_FILE"
+
+	// ConstructionAllowedIPsEnv is an optional environment
+	// variable containing a comma-separated list of the IP
+	// addresses and CIDR ranges (i.e. "10.0.0.0/8") allowed
+	// to call the /construction endpoints. When not set,
+	// they can be called from any address.
+	ConstructionAllowedIPsEnv = "CONSTRUCTION_ALLOWED_IPS"
+
+	// ABIRegistryEnv is an optional environment variable used
+	// to decode the methods called by transactions in their
+	// metadata with the ABIs of the system contracts and of
+	// common tokens. When not set, defaults to false.
+	ABIRegistryEnv = "ENABLE_ABI_REGISTRY"
+
+	// ABIPathEnv is an optional environment variable pointing
+	// to a JSON contract ABI, or to a directory of them, used
+	// to decode methods in addition to the built-in ABIs. When
+	// set, ABIRegistryEnv defaults to true.
+	ABIPathEnv = "ABI_PATH"
+
+	// FourByteURLEnv is an optional environment variable
+	// containing the endpoint of a 4byte directory (i.e.
+	// ethereum.DefaultFourByteURL) used to resolve unknown
+	// selectors. When set, ABIRegistryEnv defaults to true.
+	FourByteURLEnv = "FOUR_BYTE_URL"
+
+	// UserOperationsEnv is an optional environment variable used
+	// to surface the user operations of ERC-4337 bundles as
+	// zero-amount USER_OPERATION operations attributed to their
+	// fee payer. When not set, defaults to false.
+	UserOperationsEnv = "ENABLE_USER_OPERATIONS"
+
+	// EntryPointContractsEnv is an optional environment variable
+	// containing a comma-separated list of the ERC-4337 EntryPoint
+	// contracts whose user operations are surfaced. When not set,
+	// the canonical deployments (ethereum.EntryPointContracts)
+	// are used.
+	EntryPointContractsEnv = "ENTRY_POINT_CONTRACTS"
+
+	// CanonicalJSONEnv is an optional environment variable used
+	// to serialize JSON responses canonically (sorted keys, no
+	// whitespace, and a single representation of every number),
+	// so that digests of responses are stable across versions
+	// and platforms. When not set, defaults to false.
+	CanonicalJSONEnv = "CANONICAL_JSON"
+
+	// MetadataCacheTTLEnv is an optional environment variable
+	// used to set how long the gas price and gas limit returned
+	// by /construction/metadata are reused for identical
+	// preprocess options (nonces are never cached). It is parsed
+	// with time.ParseDuration. When not set, nothing is cached.
+	MetadataCacheTTLEnv = "CONSTRUCTION_METADATA_CACHE_TTL"
+
+	// RawRLPMetadataEnv is an optional environment variable used
+	// to add the raw encodings of headers and transactions to the
+	// metadata of blocks and transactions (see
+	// ethereum.RawRLPTransformer). When not set, defaults to false.
+	RawRLPMetadataEnv = "RAW_RLP_METADATA"
+
+	// PublicModeEnv is an optional environment variable used to
+	// harden rosetta-core so that its read endpoints can be
+	// exposed publicly: the /call methods passed through to the
+	// node and /construction/submit are disabled, admin endpoints
+	// are only served on AdminListenAddressEnv, requests are rate
+	// limited and smaller by default, and node addresses are
+	// stripped from error messages. When not set, defaults to false.
+	PublicModeEnv = "PUBLIC_MODE"
+
+	// RateLimitEnv is an optional environment variable used to
+	// limit the requests per second served to each client
+	// address. When not set, requests are only rate limited in
+	// public mode (see DefaultPublicRateLimit).
+	RateLimitEnv = "RATE_LIMIT"
+
+	// DefaultPublicRateLimit is the default value
+	// of RateLimitEnv in public mode.
+	DefaultPublicRateLimit = 10
+
+	// DefaultPublicMaxRequestBodySize is the default value
+	// of MaxRequestBodySizeEnv in public mode.
+	DefaultPublicMaxRequestBodySize = int64(64 << 10) // nolint:gomnd
+
+	// WatermarkEnv is an optional environment variable pointing
+	// to a file used to persist the highest block served by
+	// /block and the depth of the last observed reorg across
+	// restarts. They are reported in the metadata of
+	// /network/status.
+	WatermarkEnv = "WATERMARK_PATH"
+
+	// IndexTokenHoldersEnv is an optional environment variable
+	// used to index the holders of ERC-20 tokens, so that the
+	// "token_inventory" /call method can be served. It requires
+	// IndexEnv to be set. When not set, defaults to false.
+	IndexTokenHoldersEnv = "INDEX_TOKEN_HOLDERS"
+
+	// TraceStartIndexEnv is an optional environment variable
+	// used to set the index of the first block the node can
+	// trace (i.e. the first block with state, on nodes that
+	// prune it). Older blocks are served in degraded mode with
+	// only their fee and external call operations. When not
+	// set, all blocks are traced.
+	TraceStartIndexEnv = "TRACE_START_INDEX"
+
+	// HedgeDelayEnv is an optional environment variable used to
+	// hedge latency-critical reads relative to the tip (the tip
+	// block header and balances at the tip): if the node has not
+	// responded after this duration (i.e. 100ms), the read is also
+	// sent to an archive node and the first response is used. It
+	// requires ArchiveURLsEnv to be set. When not set, reads are
+	// not hedged.
+	HedgeDelayEnv = "HEDGE_DELAY"
+
+	// ConsensusMetadataEnv is an optional environment variable
+	// used to add the consensus fields of headers (the sealing
+	// validator recovered from the extraData signature and
+	// whether it sealed in turn) to the metadata of blocks (see
+	// ethereum.NewConsensusTransformer). When not set, defaults
+	// to false.
+	ConsensusMetadataEnv = "CONSENSUS_METADATA"
+
+	// HDDerivationEnv is an optional environment variable used
+	// to allow /construction/derive to derive the non-hardened
+	// children of an extended public key (xpub) provided in the
+	// request metadata, so that deposit addresses can be derived
+	// server-side. Extended keys are sensitive, so it is disabled
+	// unless this is set to true.
+	HDDerivationEnv = "HD_DERIVATION"
+
 	// MiddlewareVersion is the version of rosetta-core.
 	MiddlewareVersion = "0.0.4"
 )
 
+// ValidationMode is the setting that determines if
+// responses are validated before being served.
+type ValidationMode string
+
+const (
+	// StrictValidation validates every response against
+	// the Rosetta specification and refuses to serve
+	// responses that are invalid.
+	StrictValidation ValidationMode = "STRICT"
+
+	// PermissiveValidation serves responses without
+	// validating them.
+	PermissiveValidation ValidationMode = "PERMISSIVE"
+)
+
+// ChainMismatchAction is the setting that determines what
+// happens when the node is on a different chain than the
+// configured network.
+type ChainMismatchAction string
+
+const (
+	// HaltOnChainMismatch stops rosetta-core.
+	HaltOnChainMismatch ChainMismatchAction = "HALT"
+
+	// QuarantineOnChainMismatch keeps rosetta-core running
+	// (so the mismatch can be inspected through the logs,
+	// metrics, and admin endpoints) but rejects all Rosetta
+	// API requests.
+	QuarantineOnChainMismatch ChainMismatchAction = "QUARANTINE"
+)
+
+// Module is a functional area of the
+// Rosetta API that can be disabled.
+type Module string
+
+const (
+	// ConstructionModule is the /construction/* endpoints.
+	ConstructionModule Module = "construction"
+
+	// CallModule is the /call endpoint.
+	CallModule Module = "call"
+
+	// MempoolModule is the /mempool/* endpoints.
+	MempoolModule Module = "mempool"
+
+	// SearchModule is the /search/* endpoints. rosetta-core
+	// does not serve them, so disabling it has no effect,
+	// but it is accepted so that the same DisabledModulesEnv
+	// can be shared with other Rosetta implementations.
+	SearchModule Module = "search"
+)
+
+// BlockArchiveMode is a way the block archive is used.
+type BlockArchiveMode string
+
+const (
+	// ExportBlockArchive continuously exports
+	// confirmed blocks to the block archive.
+	ExportBlockArchive BlockArchiveMode = "export"
+
+	// ReplayBlockArchive serves /block from the
+	// block archive for archived blocks.
+	ReplayBlockArchive BlockArchiveMode = "replay"
+)
+
+// Modules are all modules that can be disabled.
+var Modules = []Module{
+	ConstructionModule,
+	CallModule,
+	MempoolModule,
+	SearchModule,
+}
+
+// GasLimitType is the kind of transaction
+// a default gas limit applies to.
+type GasLimitType string
+
+const (
+	// TransferGasLimit is the type of native CORE transfers.
+	TransferGasLimit GasLimitType = "transfer"
+
+	// DelegateGasLimit is the type of PledgeAgent
+	// delegations.
+	DelegateGasLimit GasLimitType = "delegate"
+)
+
+// GasLimitTypes are all types that can
+// be set in GasLimitDefaultsEnv.
+var GasLimitTypes = []GasLimitType{
+	TransferGasLimit,
+	DelegateGasLimit,
+}
+
+// GasLimits determines the gas limits
+// returned by /construction/metadata.
+type GasLimits struct {
+	// Multiplier is applied to gas estimates.
+	Multiplier float64
+
+	// Cap is the maximum gas limit of an estimate
+	// (0 if estimates are not capped).
+	Cap uint64
+
+	// Defaults are the gas limits used when gas is
+	// not estimated. Types that are not populated use
+	// the gas limits in the ethereum package.
+	Defaults map[GasLimitType]uint64
+}
+
+// Default returns the gas limit of transactions of
+// gasLimitType that are not estimated. It is safe
+// to call on a nil GasLimits.
+func (g *GasLimits) Default(gasLimitType GasLimitType) uint64 {
+	if g != nil {
+		if limit, ok := g.Defaults[gasLimitType]; ok {
+			return limit
+		}
+	}
+
+	if gasLimitType == DelegateGasLimit {
+		return uint64(ethereum.DelegateGasLimit)
+	}
+
+	return uint64(ethereum.TransferGasLimit)
+}
+
+// ErrGasLimitCapExceeded is returned by Estimate when
+// the multiplied estimate is above Cap.
+var ErrGasLimitCapExceeded = errors.New("gas limit exceeds GAS_LIMIT_CAP")
+
+// Estimate applies Multiplier to an estimated gas limit and
+// returns ErrGasLimitCapExceeded if the result is above Cap
+// (a capped limit would not cover the estimated execution).
+// It is safe to call on a nil GasLimits.
+func (g *GasLimits) Estimate(estimate uint64) (uint64, error) {
+	if g == nil {
+		return estimate, nil
+	}
+
+	limit := uint64(math.Ceil(float64(estimate) * g.Multiplier))
+	if g.Cap > 0 && limit > g.Cap {
+		return 0, fmt.Errorf("%w: %d > %d", ErrGasLimitCapExceeded, limit, g.Cap)
+	}
+
+	return limit, nil
+}
+
+// MaintenanceWindow is a scheduled period of
+// maintenance, from Start until (excluding) End.
+type MaintenanceWindow struct {
+	Start time.Time
+	End   time.Time
+}
+
+// HTTPServerConfig configures the HTTP servers of rosetta-core.
+// Zero values use the defaults of the run command.
+type HTTPServerConfig struct {
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration
+	IdleTimeout    time.Duration
+	MaxHeaderBytes int
+	TCPKeepAlive   time.Duration
+
+	// ListenAddresses are the addresses the Rosetta API is
+	// served on. If empty, it is served on all interfaces at
+	// the port of the Configuration.
+	ListenAddresses []string
+
+	// AdminListenAddress is the address /metrics and the
+	// /admin endpoints are served on. If empty, they are
+	// served with the Rosetta API.
+	AdminListenAddress string
+
+	// TLSCertFile and TLSKeyFile are the certificate and
+	// key HTTPS is served with. If empty, HTTP is served.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// ConstructionClientCAFile contains the CAs of the client
+	// certificates required by the /construction endpoints.
+	// If empty, client certificates are not requested.
+	ConstructionClientCAFile string
+
+	// ConstructionAllowedNetworks are the networks allowed to
+	// call the /construction endpoints. If empty, they can be
+	// called from any address.
+	ConstructionAllowedNetworks []*net.IPNet
+}
+
+// RuntimeConfig is the content of the RuntimeConfigEnv file.
+// Settings that are not populated keep the value of their
+// environment variable. ResponseCacheTTL is parsed with
+// time.ParseDuration.
+type RuntimeConfig struct {
+	BlockInlineTransactions *int    `json:"block_inline_transactions,omitempty"`
+	RateLimit               *int    `json:"rate_limit,omitempty"`
+	ResponseCacheSize       *int    `json:"response_cache_size,omitempty"`
+	ResponseCacheTTL        *string `json:"response_cache_ttl,omitempty"`
+}
+
+// runtimeSettings are the settings of the
+// RuntimeConfigEnv file, once applied.
+type runtimeSettings struct {
+	blockInlineTransactions int
+	rateLimit               int
+	responseCacheSize       int
+	responseCacheTTL        time.Duration
+}
+
+// override returns s overridden by the
+// RuntimeConfigEnv file at path.
+func (s runtimeSettings) override(path string) (runtimeSettings, error) {
+	var runtimeConfig RuntimeConfig
+	if err := utils.LoadAndParse(path, &runtimeConfig); err != nil {
+		return s, fmt.Errorf("%w: unable to load %s %s", err, RuntimeConfigEnv, path)
+	}
+
+	if runtimeConfig.BlockInlineTransactions != nil {
+		if *runtimeConfig.BlockInlineTransactions < 0 {
+			return s, fmt.Errorf("block_inline_transactions in %s must not be negative", path)
+		}
+		s.blockInlineTransactions = *runtimeConfig.BlockInlineTransactions
+	}
+
+	if runtimeConfig.RateLimit != nil {
+		if *runtimeConfig.RateLimit <= 0 {
+			return s, fmt.Errorf("rate_limit in %s must be positive", path)
+		}
+		s.rateLimit = *runtimeConfig.RateLimit
+	}
+
+	if runtimeConfig.ResponseCacheSize != nil {
+		if *runtimeConfig.ResponseCacheSize < 0 {
+			return s, fmt.Errorf("response_cache_size in %s must not be negative", path)
+		}
+		s.responseCacheSize = *runtimeConfig.ResponseCacheSize
+	}
+
+	if runtimeConfig.ResponseCacheTTL != nil {
+		val, err := time.ParseDuration(*runtimeConfig.ResponseCacheTTL)
+		if err != nil {
+			return s, fmt.Errorf("%w: unable to parse response_cache_ttl in %s", err, path)
+		}
+		if val <= 0 {
+			return s, fmt.Errorf("response_cache_ttl in %s must be positive", path)
+		}
+		s.responseCacheTTL = val
+	}
+
+	if s.responseCacheSize > 0 && s.responseCacheTTL == 0 {
+		s.responseCacheTTL = DefaultResponseCacheTTL
+	}
+
+	return s, nil
+}
+
 // Configuration determines how
 type Configuration struct {
-	Mode                   Mode
-	Network                *types.NetworkIdentifier
-	GenesisBlockIdentifier *types.BlockIdentifier
-	GethURL                string
-	RemoteGeth             bool
-	Port                   int
-	GethArguments          string
-	SkipGethAdmin          bool
+	Mode                     Mode
+	Network                  *types.NetworkIdentifier
+	GenesisBlockIdentifier   *types.BlockIdentifier
+	GethURL                  string
+	SystemContracts          map[string]common.Address
+	Tokens                   []*networks.Token
+	RemoteGeth               bool
+	Port                     int
+	GethArguments            string
+	SkipGethAdmin            bool
+	ValidationMode           ValidationMode
+	EnableMetrics            bool
+	LogRedaction             *redact.Config
+	LogRedactionPath         string
+	NonceTrackerPath         string
+	IndexPath                string
+	IndexRetention           *indexer.Retention
+	EnableAccountSummary     bool
+	BlockInlineTransactions  int
+	EnableApprovalOperations bool
+	RuntimeConfigPath        string
+	EnableAdminReload        bool
+	WatchedAddresses         []common.Address
+	NodeLag                  *ethereum.LagConfig
+	EnableInvariantChecks    bool
+	UpstreamProxy            *url.URL
+	ResponseCacheSize        int
+	ResponseCacheTTL         time.Duration
+	AuditLogPath             string
+	AuditLogKey              string
+	DisabledModules          []Module
+	TimestampStartIndex      *int64
+	CollapseOperations       bool
+	EnableStakedBalances     bool
+	GasLimits                *GasLimits
+	EnableUpstreamReport     bool
+	ChainMismatchAction      ChainMismatchAction
+	MaxRequestBodySize       int64
+	RequestBodySizeLimits    map[string]int64
+	StrictJSONDecoding       bool
+	ArchiveURLs              []string
+	Hardforks                map[string]uint64
+	MempoolMonitor           *ethereum.MempoolMonitorConfig
+	CustomTracer             *ethereum.CustomTracer
+	Labels                   map[common.Address]*ethereum.Label
+	MulticallContract        *common.Address
+	BlockTransformers        []ethereum.BlockTransformer
+	BalanceCacheSize         int
+	BlockArchiveURL          string
+	BlockArchiveModes        []BlockArchiveMode
+	EnableHeadEvents         bool
+	GraphQLBatchSize         int
+	EnableAdminMaintenance   bool
+	EnableAdminDebugUI       bool
+	MaintenanceWindows       []*MaintenanceWindow
+	BlockEventsHistory       int
+	RewardRecipient          ethereum.RewardRecipient
+	SubmitQueuePath          string
+	HTTPServer               HTTPServerConfig
+	ZeroValueOperations      ethereum.ZeroValueOperations
+	DustThreshold            *big.Int
+	ABIRegistry              *ethereum.ABIRegistryConfig
+	UserOperationEntryPoints []common.Address
+	CanonicalJSON            bool
+	MetadataCacheTTL         time.Duration
+	RawRLPMetadata           bool
+	PublicMode               bool
+	RateLimit                int
+	WatermarkPath            string
+	IndexTokenHolders        bool
+	TraceStartIndex          int64
+	HedgeDelay               time.Duration
+	ConsensusMetadata        bool
+	HDDerivation             bool
 
 	// Block Reward Data
 	Params *params.ChainConfig
+
+	// environment holds the settings of the RuntimeConfigEnv
+	// file as set by their environment variables, so that
+	// settings removed from the file are restored by Reload.
+	// It is only populated if RuntimeConfigPath is.
+	environment *runtimeSettings
+
+	// reloadMutex guards the settings
+	// replaced by Reload.
+	reloadMutex sync.RWMutex
+}
+
+// Reload re-reads the LogRedactionEnv and RuntimeConfigEnv files
+// and applies the settings that can be changed while rosetta-core
+// is running (LogRedaction, BlockInlineTransactions, RateLimit,
+// ResponseCacheSize, and ResponseCacheTTL). The environment of a
+// running process does not change, so it is not re-read: settings
+// that are not in the files keep the value of their environment
+// variable. All other settings are left unchanged.
+func (c *Configuration) Reload() error {
+	var logRedaction *redact.Config
+	if len(c.LogRedactionPath) > 0 {
+		logRedaction = &redact.Config{}
+		if err := utils.LoadAndParse(c.LogRedactionPath, logRedaction); err != nil {
+			return fmt.Errorf("%w: unable to load %s %s", err, LogRedactionEnv, c.LogRedactionPath)
+		}
+	}
+
+	var settings runtimeSettings
+	if c.environment != nil {
+		var err error
+		settings, err = c.environment.override(c.RuntimeConfigPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.reloadMutex.Lock()
+	defer c.reloadMutex.Unlock()
+
+	c.LogRedaction = logRedaction
+	if c.environment != nil {
+		c.BlockInlineTransactions = settings.blockInlineTransactions
+		c.RateLimit = settings.rateLimit
+		c.ResponseCacheSize = settings.responseCacheSize
+		c.ResponseCacheTTL = settings.responseCacheTTL
+	}
+
+	return nil
+}
+
+// LogRedactionConfig returns LogRedaction. It is
+// safe to call while the configuration is reloaded.
+func (c *Configuration) LogRedactionConfig() *redact.Config {
+	c.reloadMutex.RLock()
+	defer c.reloadMutex.RUnlock()
+
+	return c.LogRedaction
+}
+
+// InlineTransactions returns BlockInlineTransactions. It
+// is safe to call while the configuration is reloaded.
+func (c *Configuration) InlineTransactions() int {
+	c.reloadMutex.RLock()
+	defer c.reloadMutex.RUnlock()
+
+	return c.BlockInlineTransactions
+}
+
+// ClientRateLimit returns RateLimit. It is safe
+// to call while the configuration is reloaded.
+func (c *Configuration) ClientRateLimit() int {
+	c.reloadMutex.RLock()
+	defer c.reloadMutex.RUnlock()
+
+	return c.RateLimit
+}
+
+// ResponseCacheConfig returns ResponseCacheSize and
+// ResponseCacheTTL. It is safe to call while the
+// configuration is reloaded.
+func (c *Configuration) ResponseCacheConfig() (int, time.Duration) {
+	c.reloadMutex.RLock()
+	defer c.reloadMutex.RUnlock()
+
+	return c.ResponseCacheSize, c.ResponseCacheTTL
+}
+
+// RequestBodySizeLimit returns the maximum size (in bytes) of
+// the body of requests to path. If MaxRequestBodySize is not
+// populated, DefaultMaxRequestBodySize is used.
+func (c *Configuration) RequestBodySizeLimit(path string) int64 {
+	if limit, ok := c.RequestBodySizeLimits[path]; ok {
+		return limit
+	}
+
+	if c.MaxRequestBodySize > 0 {
+		return c.MaxRequestBodySize
+	}
+
+	return DefaultMaxRequestBodySize
+}
+
+// ModuleEnabled returns false if module
+// is in DisabledModules.
+func (c *Configuration) ModuleEnabled(module Module) bool {
+	for _, disabled := range c.DisabledModules {
+		if disabled == module {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CallMethods returns the /call methods that are served.
+// In public mode, the methods passed through to the node
+// (ethereum.PassthroughCallMethods) are not.
+func (c *Configuration) CallMethods() []string {
+	if !c.PublicMode {
+		return ethereum.CallMethods
+	}
+
+	passthrough := map[string]struct{}{}
+	for _, method := range ethereum.PassthroughCallMethods {
+		passthrough[method] = struct{}{}
+	}
+
+	methods := []string{}
+	for _, method := range ethereum.CallMethods {
+		if _, ok := passthrough[method]; !ok {
+			methods = append(methods, method)
+		}
+	}
+
+	return methods
+}
+
+// BlockArchiveEnabled returns true if the block
+// archive is used in mode.
+func (c *Configuration) BlockArchiveEnabled(mode BlockArchiveMode) bool {
+	for _, enabled := range c.BlockArchiveModes {
+		if enabled == mode {
+			return true
+		}
+	}
+
+	return false
 }
 
 // LoadConfiguration attempts to create a new Configuration
@@ -181,40 +1205,36 @@ func LoadConfiguration() (*Configuration, error) {
 		config.GenesisBlockIdentifier = ethereum.GoerliGenesisBlockIdentifier
 		config.Params = params.GoerliChainConfig
 		config.GethArguments = ethereum.GoerliGethArguments
-	case Devnet:
-		config.Network = &types.NetworkIdentifier{
-			Blockchain: ethereum.Blockchain,
-			Network:    ethereum.DevNetwork,
+	case "":
+		return nil, errors.New("NETWORK must be populated")
+	default:
+		presets, err := LoadNetworkPresets(os.Getenv(NetworkPresetsEnv))
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to load network presets", err)
 		}
-		config.GenesisBlockIdentifier = ethereum.DevGenesisBlockIdentifier
-		config.Params = ethereum.DevChainConfig
 
-		if chainID.Cmp(big.NewInt(0)) == 1 {
-			config.Params.ChainID = chainID
+		preset, ok := presets[networkValue]
+		if !ok {
+			return nil, fmt.Errorf("%s is not a valid network", networkValue)
 		}
 
-		config.GethArguments = ethereum.DevGethArguments
-	case Core:
 		config.Network = &types.NetworkIdentifier{
 			Blockchain: ethereum.Blockchain,
-			Network:    ethereum.CoreNetwork,
+			Network:    preset.Network,
 		}
-		config.GenesisBlockIdentifier = ethereum.CoreGenesisBlockIdentifier
-		config.Params = ethereum.CoreChainConfig
-		config.GethArguments = ethereum.CoreGethArguments
-	case Buffalo:
-		config.Network = &types.NetworkIdentifier{
-			Blockchain: ethereum.Blockchain,
-			Network:    ethereum.BuffaloNetwork,
+		config.GenesisBlockIdentifier = preset.GenesisBlockIdentifier
+		config.Params = preset.ChainConfig
+		config.Hardforks = preset.Hardforks
+		config.GethArguments = preset.GethArguments
+		config.SystemContracts = preset.SystemContracts
+		config.Tokens = preset.Tokens
+		if len(preset.GethURL) > 0 {
+			config.GethURL = preset.GethURL
+		}
+
+		if networkValue == Devnet && chainID.Cmp(big.NewInt(0)) == 1 {
+			config.Params.ChainID = chainID
 		}
-		config.GenesisBlockIdentifier = ethereum.BuffaloGenesisBlockIdentifier
-		config.Params = ethereum.BuffaloChainConfig
-		config.GethArguments = ethereum.BuffaloGethArguments
-		config.GethURL = BuffaloGethURL
-	case "":
-		return nil, errors.New("NETWORK must be populated")
-	default:
-		return nil, fmt.Errorf("%s is not a valid network", networkValue)
 	}
 	envGethURL := os.Getenv(GethEnv)
 	if len(envGethURL) > 0 {
@@ -232,16 +1252,1250 @@ func LoadConfiguration() (*Configuration, error) {
 		config.SkipGethAdmin = val
 	}
 
-	portValue := os.Getenv(PortEnv)
-	if len(portValue) == 0 {
-		return nil, errors.New("PORT must be populated")
+	config.ValidationMode = PermissiveValidation
+	validationModeValue := ValidationMode(os.Getenv(ValidationModeEnv))
+	switch validationModeValue {
+	case StrictValidation, PermissiveValidation:
+		config.ValidationMode = validationModeValue
+	case "":
+	default:
+		return nil, fmt.Errorf("%s is not a valid validation mode", validationModeValue)
 	}
 
-	port, err := strconv.Atoi(portValue)
-	if err != nil || len(portValue) == 0 || port <= 0 {
-		return nil, fmt.Errorf("%w: unable to parse port %s", err, portValue)
+	config.ChainMismatchAction = HaltOnChainMismatch
+	chainMismatchValue := ChainMismatchAction(os.Getenv(ChainMismatchEnv))
+	switch chainMismatchValue {
+	case HaltOnChainMismatch, QuarantineOnChainMismatch:
+		config.ChainMismatchAction = chainMismatchValue
+	case "":
+	default:
+		return nil, fmt.Errorf("%s is not a valid chain mismatch action", chainMismatchValue)
 	}
-	config.Port = port
 
-	return config, nil
+	envMetrics := os.Getenv(MetricsEnv)
+	if len(envMetrics) > 0 {
+		val, err := strconv.ParseBool(envMetrics)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, MetricsEnv, envMetrics)
+		}
+		config.EnableMetrics = val
+	}
+
+	config.LogRedactionPath = os.Getenv(LogRedactionEnv)
+	if len(config.LogRedactionPath) > 0 {
+		config.LogRedaction = &redact.Config{}
+		if err := utils.LoadAndParse(config.LogRedactionPath, config.LogRedaction); err != nil {
+			return nil, fmt.Errorf(
+				"%w: unable to load %s %s",
+				err,
+				LogRedactionEnv,
+				config.LogRedactionPath,
+			)
+		}
+	}
+
+	config.NonceTrackerPath = os.Getenv(NonceTrackerEnv)
+	config.IndexPath = os.Getenv(IndexEnv)
+	config.AuditLogPath = os.Getenv(AuditLogEnv)
+	config.AuditLogKey = os.Getenv(AuditLogKeyEnv)
+
+	envTimestampStartIndex := os.Getenv(TimestampStartIndexEnv)
+	if len(envTimestampStartIndex) > 0 {
+		val, err := strconv.ParseInt(envTimestampStartIndex, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%w: unable to parse %s %s",
+				err,
+				TimestampStartIndexEnv,
+				envTimestampStartIndex,
+			)
+		}
+		if val < 0 {
+			return nil, fmt.Errorf(
+				"unable to parse %s %s: must not be negative",
+				TimestampStartIndexEnv,
+				envTimestampStartIndex,
+			)
+		}
+		config.TimestampStartIndex = &val
+	}
+
+	envDisabledModules := os.Getenv(DisabledModulesEnv)
+	if len(envDisabledModules) > 0 {
+		for _, name := range strings.Split(envDisabledModules, ",") {
+			module := Module(strings.ToLower(strings.TrimSpace(name)))
+			if len(module) == 0 {
+				continue
+			}
+
+			if !validModule(module) {
+				return nil, fmt.Errorf("%s is not a valid module", module)
+			}
+			config.DisabledModules = append(config.DisabledModules, module)
+		}
+	}
+
+	envAccountSummary := os.Getenv(AccountSummaryEnv)
+	if len(envAccountSummary) > 0 {
+		val, err := strconv.ParseBool(envAccountSummary)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, AccountSummaryEnv, envAccountSummary)
+		}
+		config.EnableAccountSummary = val
+	}
+
+	if config.EnableAccountSummary && len(config.IndexPath) == 0 {
+		return nil, fmt.Errorf("%s requires %s to be populated", AccountSummaryEnv, IndexEnv)
+	}
+
+	envIndexTokenHolders := os.Getenv(IndexTokenHoldersEnv)
+	if len(envIndexTokenHolders) > 0 {
+		val, err := strconv.ParseBool(envIndexTokenHolders)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, IndexTokenHoldersEnv, envIndexTokenHolders)
+		}
+		config.IndexTokenHolders = val
+	}
+
+	if config.IndexTokenHolders && len(config.IndexPath) == 0 {
+		return nil, fmt.Errorf("%s requires %s to be populated", IndexTokenHoldersEnv, IndexEnv)
+	}
+
+	envTraceStartIndex := os.Getenv(TraceStartIndexEnv)
+	if len(envTraceStartIndex) > 0 {
+		val, err := strconv.ParseInt(envTraceStartIndex, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, TraceStartIndexEnv, envTraceStartIndex)
+		}
+		if val < 0 {
+			return nil, fmt.Errorf("%s must not be negative", TraceStartIndexEnv)
+		}
+		config.TraceStartIndex = val
+	}
+
+	retention := &indexer.Retention{}
+	envIndexRetentionBlocks := os.Getenv(IndexRetentionBlocksEnv)
+	if len(envIndexRetentionBlocks) > 0 {
+		val, err := strconv.ParseInt(envIndexRetentionBlocks, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%w: unable to parse %s %s",
+				err,
+				IndexRetentionBlocksEnv,
+				envIndexRetentionBlocks,
+			)
+		}
+		if val < 0 {
+			return nil, fmt.Errorf(
+				"unable to parse %s %s: must not be negative",
+				IndexRetentionBlocksEnv,
+				envIndexRetentionBlocks,
+			)
+		}
+		retention.Blocks = val
+	}
+
+	envIndexAddressPrefixes := os.Getenv(IndexAddressPrefixesEnv)
+	if len(envIndexAddressPrefixes) > 0 {
+		prefixes, err := indexer.ParseAddressPrefixes(envIndexAddressPrefixes)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%w: unable to parse %s %s",
+				err,
+				IndexAddressPrefixesEnv,
+				envIndexAddressPrefixes,
+			)
+		}
+		retention.AddressPrefixes = prefixes
+	}
+
+	if retention.Blocks > 0 || len(retention.AddressPrefixes) > 0 {
+		if len(config.IndexPath) == 0 {
+			return nil, fmt.Errorf(
+				"%s and %s require %s to be populated",
+				IndexRetentionBlocksEnv,
+				IndexAddressPrefixesEnv,
+				IndexEnv,
+			)
+		}
+		config.IndexRetention = retention
+	}
+
+	envBlockInlineTransactions := os.Getenv(BlockInlineTransactionsEnv)
+	if len(envBlockInlineTransactions) > 0 {
+		val, err := strconv.Atoi(envBlockInlineTransactions)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%w: unable to parse %s %s",
+				err,
+				BlockInlineTransactionsEnv,
+				envBlockInlineTransactions,
+			)
+		}
+		if val < 0 {
+			return nil, fmt.Errorf(
+				"unable to parse %s %s: must not be negative",
+				BlockInlineTransactionsEnv,
+				envBlockInlineTransactions,
+			)
+		}
+		config.BlockInlineTransactions = val
+	}
+
+	envApprovalOperations := os.Getenv(ApprovalOperationsEnv)
+	if len(envApprovalOperations) > 0 {
+		val, err := strconv.ParseBool(envApprovalOperations)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%w: unable to parse %s %s",
+				err,
+				ApprovalOperationsEnv,
+				envApprovalOperations,
+			)
+		}
+		config.EnableApprovalOperations = val
+	}
+
+	envAdminReload := os.Getenv(AdminReloadEnv)
+	if len(envAdminReload) > 0 {
+		val, err := strconv.ParseBool(envAdminReload)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, AdminReloadEnv, envAdminReload)
+		}
+		config.EnableAdminReload = val
+	}
+
+	envInvariantChecks := os.Getenv(InvariantChecksEnv)
+	if len(envInvariantChecks) > 0 {
+		val, err := strconv.ParseBool(envInvariantChecks)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, InvariantChecksEnv, envInvariantChecks)
+		}
+		config.EnableInvariantChecks = val
+	}
+
+	envCollapseOperations := os.Getenv(CollapseOperationsEnv)
+	if len(envCollapseOperations) > 0 {
+		val, err := strconv.ParseBool(envCollapseOperations)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, CollapseOperationsEnv, envCollapseOperations)
+		}
+		config.CollapseOperations = val
+	}
+
+	envStakedBalances := os.Getenv(StakedBalancesEnv)
+	if len(envStakedBalances) > 0 {
+		val, err := strconv.ParseBool(envStakedBalances)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, StakedBalancesEnv, envStakedBalances)
+		}
+		config.EnableStakedBalances = val
+	}
+
+	envUpstreamReport := os.Getenv(UpstreamReportEnv)
+	if len(envUpstreamReport) > 0 {
+		val, err := strconv.ParseBool(envUpstreamReport)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, UpstreamReportEnv, envUpstreamReport)
+		}
+		config.EnableUpstreamReport = val
+	}
+
+	envUpstreamProxy := os.Getenv(UpstreamProxyEnv)
+	if len(envUpstreamProxy) > 0 {
+		proxy, err := url.Parse(envUpstreamProxy)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, UpstreamProxyEnv, envUpstreamProxy)
+		}
+
+		switch proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("%s is not a valid %s scheme", proxy.Scheme, UpstreamProxyEnv)
+		}
+		config.UpstreamProxy = proxy
+	}
+
+	envResponseCacheSize := os.Getenv(ResponseCacheSizeEnv)
+	if len(envResponseCacheSize) > 0 {
+		val, err := strconv.Atoi(envResponseCacheSize)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%w: unable to parse %s %s",
+				err,
+				ResponseCacheSizeEnv,
+				envResponseCacheSize,
+			)
+		}
+		if val < 0 {
+			return nil, fmt.Errorf(
+				"unable to parse %s %s: must not be negative",
+				ResponseCacheSizeEnv,
+				envResponseCacheSize,
+			)
+		}
+		config.ResponseCacheSize = val
+	}
+
+	envResponseCacheTTL := os.Getenv(ResponseCacheTTLEnv)
+	if len(envResponseCacheTTL) > 0 {
+		val, err := time.ParseDuration(envResponseCacheTTL)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%w: unable to parse %s %s",
+				err,
+				ResponseCacheTTLEnv,
+				envResponseCacheTTL,
+			)
+		}
+		if val <= 0 {
+			return nil, fmt.Errorf(
+				"unable to parse %s %s: must be positive",
+				ResponseCacheTTLEnv,
+				envResponseCacheTTL,
+			)
+		}
+		config.ResponseCacheTTL = val
+	}
+
+	if config.ResponseCacheSize > 0 && config.ResponseCacheTTL == 0 {
+		config.ResponseCacheTTL = DefaultResponseCacheTTL
+	}
+
+	envWatchedAddresses := os.Getenv(WatchedAddressesEnv)
+	if len(envWatchedAddresses) > 0 {
+		var addresses []string
+		if err := utils.LoadAndParse(envWatchedAddresses, &addresses); err != nil {
+			return nil, fmt.Errorf(
+				"%w: unable to load %s %s",
+				err,
+				WatchedAddressesEnv,
+				envWatchedAddresses,
+			)
+		}
+
+		for _, address := range addresses {
+			if !common.IsHexAddress(address) {
+				return nil, fmt.Errorf("%s in %s is not a valid address", address, envWatchedAddresses)
+			}
+
+			config.WatchedAddresses = append(config.WatchedAddresses, common.HexToAddress(address))
+		}
+	}
+
+	envReferenceURLs := os.Getenv(ReferenceURLsEnv)
+	if len(envReferenceURLs) > 0 {
+		config.NodeLag = &ethereum.LagConfig{
+			MaxLag: ethereum.DefaultMaxLag,
+		}
+		for _, url := range strings.Split(envReferenceURLs, ",") {
+			if url = strings.TrimSpace(url); len(url) > 0 {
+				config.NodeLag.References = append(config.NodeLag.References, url)
+			}
+		}
+
+		envMaxNodeLag := os.Getenv(MaxNodeLagEnv)
+		if len(envMaxNodeLag) > 0 {
+			val, err := strconv.ParseInt(envMaxNodeLag, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: unable to parse %s %s", err, MaxNodeLagEnv, envMaxNodeLag)
+			}
+			if val < 0 {
+				return nil, fmt.Errorf("unable to parse %s %s: must not be negative", MaxNodeLagEnv, envMaxNodeLag)
+			}
+			config.NodeLag.MaxLag = val
+		}
+
+		envRejectStaleReads := os.Getenv(RejectStaleReadsEnv)
+		if len(envRejectStaleReads) > 0 {
+			val, err := strconv.ParseBool(envRejectStaleReads)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"%w: unable to parse %s %s",
+					err,
+					RejectStaleReadsEnv,
+					envRejectStaleReads,
+				)
+			}
+			config.NodeLag.RejectStaleReads = val
+		}
+	}
+
+	envMaxRequestBodySize := os.Getenv(MaxRequestBodySizeEnv)
+	if len(envMaxRequestBodySize) > 0 {
+		val, err := strconv.ParseInt(envMaxRequestBodySize, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%w: unable to parse %s %s",
+				err,
+				MaxRequestBodySizeEnv,
+				envMaxRequestBodySize,
+			)
+		}
+		if val <= 0 {
+			return nil, fmt.Errorf(
+				"unable to parse %s %s: must be positive",
+				MaxRequestBodySizeEnv,
+				envMaxRequestBodySize,
+			)
+		}
+		config.MaxRequestBodySize = val
+	}
+
+	requestBodySizeLimits, err := loadRequestBodySizeLimits()
+	if err != nil {
+		return nil, err
+	}
+	config.RequestBodySizeLimits = requestBodySizeLimits
+
+	envStrictJSONDecoding := os.Getenv(StrictJSONDecodingEnv)
+	if len(envStrictJSONDecoding) > 0 {
+		val, err := strconv.ParseBool(envStrictJSONDecoding)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%w: unable to parse %s %s",
+				err,
+				StrictJSONDecodingEnv,
+				envStrictJSONDecoding,
+			)
+		}
+		config.StrictJSONDecoding = val
+	}
+
+	envNonceMonitorAddresses := os.Getenv(NonceMonitorAddressesEnv)
+	if len(envNonceMonitorAddresses) > 0 {
+		config.MempoolMonitor = &ethereum.MempoolMonitorConfig{
+			StuckAfter: ethereum.DefaultNonceStuckAfter,
+		}
+		for _, address := range strings.Split(envNonceMonitorAddresses, ",") {
+			address = strings.TrimSpace(address)
+			if !common.IsHexAddress(address) {
+				return nil, fmt.Errorf(
+					"%s in %s is not a valid address",
+					address,
+					NonceMonitorAddressesEnv,
+				)
+			}
+			config.MempoolMonitor.Addresses = append(
+				config.MempoolMonitor.Addresses,
+				common.HexToAddress(address),
+			)
+		}
+
+		envNonceStuckAfter := os.Getenv(NonceStuckAfterEnv)
+		if len(envNonceStuckAfter) > 0 {
+			val, err := time.ParseDuration(envNonceStuckAfter)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"%w: unable to parse %s %s",
+					err,
+					NonceStuckAfterEnv,
+					envNonceStuckAfter,
+				)
+			}
+			if val <= 0 {
+				return nil, fmt.Errorf(
+					"unable to parse %s %s: must be positive",
+					NonceStuckAfterEnv,
+					envNonceStuckAfter,
+				)
+			}
+			config.MempoolMonitor.StuckAfter = val
+		}
+	}
+
+	customTracer, err := loadCustomTracer()
+	if err != nil {
+		return nil, err
+	}
+	config.CustomTracer = customTracer
+
+	envLabelsPath := os.Getenv(LabelsPathEnv)
+	if len(envLabelsPath) > 0 {
+		labels, err := ethereum.LoadLabels(envLabelsPath)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, LabelsPathEnv, envLabelsPath)
+		}
+		config.Labels = labels
+	}
+
+	envMulticallContract := os.Getenv(MulticallContractEnv)
+	if len(envMulticallContract) > 0 {
+		if !common.IsHexAddress(envMulticallContract) {
+			return nil, fmt.Errorf(
+				"%s in %s is not a valid address",
+				envMulticallContract,
+				MulticallContractEnv,
+			)
+		}
+		address := common.HexToAddress(envMulticallContract)
+		config.MulticallContract = &address
+	}
+
+	envBlockTransformers := os.Getenv(BlockTransformersEnv)
+	for _, name := range strings.Split(envBlockTransformers, ",") {
+		if name = strings.TrimSpace(name); len(name) == 0 {
+			continue
+		}
+
+		transformer, ok := ethereum.LookupBlockTransformer(name)
+		if !ok {
+			return nil, fmt.Errorf(
+				"%s in %s is not a registered block transformer (registered: %s)",
+				name,
+				BlockTransformersEnv,
+				strings.Join(ethereum.BlockTransformers(), ", "),
+			)
+		}
+		config.BlockTransformers = append(config.BlockTransformers, transformer)
+	}
+
+	envRawRLPMetadata := os.Getenv(RawRLPMetadataEnv)
+	if len(envRawRLPMetadata) > 0 {
+		val, err := strconv.ParseBool(envRawRLPMetadata)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, RawRLPMetadataEnv, envRawRLPMetadata)
+		}
+		config.RawRLPMetadata = val
+	}
+	if config.RawRLPMetadata {
+		config.BlockTransformers = append(config.BlockTransformers, ethereum.RawRLPTransformer)
+	}
+
+	envConsensusMetadata := os.Getenv(ConsensusMetadataEnv)
+	if len(envConsensusMetadata) > 0 {
+		val, err := strconv.ParseBool(envConsensusMetadata)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, ConsensusMetadataEnv, envConsensusMetadata)
+		}
+		config.ConsensusMetadata = val
+	}
+	if config.ConsensusMetadata {
+		config.BlockTransformers = append(
+			config.BlockTransformers,
+			ethereum.NewConsensusTransformer(config.Params.ChainID),
+		)
+	}
+
+	envHDDerivation := os.Getenv(HDDerivationEnv)
+	if len(envHDDerivation) > 0 {
+		val, err := strconv.ParseBool(envHDDerivation)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, HDDerivationEnv, envHDDerivation)
+		}
+		config.HDDerivation = val
+	}
+
+	envBalanceCacheSize := os.Getenv(BalanceCacheSizeEnv)
+	if len(envBalanceCacheSize) > 0 {
+		val, err := strconv.Atoi(envBalanceCacheSize)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%w: unable to parse %s %s",
+				err,
+				BalanceCacheSizeEnv,
+				envBalanceCacheSize,
+			)
+		}
+		if val < 0 {
+			return nil, fmt.Errorf(
+				"unable to parse %s %s: must not be negative",
+				BalanceCacheSizeEnv,
+				envBalanceCacheSize,
+			)
+		}
+		config.BalanceCacheSize = val
+	}
+
+	config.BlockArchiveURL = os.Getenv(BlockArchiveURLEnv)
+	envBlockArchiveMode := os.Getenv(BlockArchiveModeEnv)
+	for _, name := range strings.Split(envBlockArchiveMode, ",") {
+		mode := BlockArchiveMode(strings.ToLower(strings.TrimSpace(name)))
+		switch mode {
+		case "":
+			continue
+		case ExportBlockArchive, ReplayBlockArchive:
+			config.BlockArchiveModes = append(config.BlockArchiveModes, mode)
+		default:
+			return nil, fmt.Errorf("%s in %s is not a valid block archive mode", mode, BlockArchiveModeEnv)
+		}
+	}
+
+	if len(config.BlockArchiveModes) > 0 && len(config.BlockArchiveURL) == 0 {
+		return nil, fmt.Errorf("%s requires %s to be populated", BlockArchiveModeEnv, BlockArchiveURLEnv)
+	}
+
+	if len(config.BlockArchiveURL) > 0 && len(config.BlockArchiveModes) == 0 {
+		config.BlockArchiveModes = []BlockArchiveMode{ExportBlockArchive}
+	}
+
+	envHeadEvents := os.Getenv(HeadEventsEnv)
+	if len(envHeadEvents) > 0 {
+		val, err := strconv.ParseBool(envHeadEvents)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, HeadEventsEnv, envHeadEvents)
+		}
+		config.EnableHeadEvents = val
+	}
+
+	envGraphQLBatchSize := os.Getenv(GraphQLBatchSizeEnv)
+	if len(envGraphQLBatchSize) > 0 {
+		val, err := strconv.Atoi(envGraphQLBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%w: unable to parse %s %s",
+				err,
+				GraphQLBatchSizeEnv,
+				envGraphQLBatchSize,
+			)
+		}
+		if val < 0 {
+			return nil, fmt.Errorf(
+				"unable to parse %s %s: must not be negative",
+				GraphQLBatchSizeEnv,
+				envGraphQLBatchSize,
+			)
+		}
+		config.GraphQLBatchSize = val
+	}
+
+	envAdminMaintenance := os.Getenv(AdminMaintenanceEnv)
+	if len(envAdminMaintenance) > 0 {
+		val, err := strconv.ParseBool(envAdminMaintenance)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, AdminMaintenanceEnv, envAdminMaintenance)
+		}
+		config.EnableAdminMaintenance = val
+	}
+
+	envAdminDebugUI := os.Getenv(AdminDebugUIEnv)
+	if len(envAdminDebugUI) > 0 {
+		val, err := strconv.ParseBool(envAdminDebugUI)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, AdminDebugUIEnv, envAdminDebugUI)
+		}
+		config.EnableAdminDebugUI = val
+	}
+
+	maintenanceWindows, err := loadMaintenanceWindows()
+	if err != nil {
+		return nil, err
+	}
+	config.MaintenanceWindows = maintenanceWindows
+
+	envBlockEventsHistory := os.Getenv(BlockEventsHistoryEnv)
+	if len(envBlockEventsHistory) > 0 {
+		val, err := strconv.Atoi(envBlockEventsHistory)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%w: unable to parse %s %s",
+				err,
+				BlockEventsHistoryEnv,
+				envBlockEventsHistory,
+			)
+		}
+		if val < 0 {
+			return nil, fmt.Errorf(
+				"unable to parse %s %s: must not be negative",
+				BlockEventsHistoryEnv,
+				envBlockEventsHistory,
+			)
+		}
+		config.BlockEventsHistory = val
+	}
+
+	rewardRecipientValue := ethereum.RewardRecipient(os.Getenv(RewardRecipientEnv))
+	switch rewardRecipientValue {
+	case ethereum.CoinbaseRewardRecipient, ethereum.FeeAddressRewardRecipient:
+		config.RewardRecipient = rewardRecipientValue
+	case "":
+	default:
+		return nil, fmt.Errorf("%s is not a valid reward recipient", rewardRecipientValue)
+	}
+
+	config.SubmitQueuePath = os.Getenv(SubmitQueueEnv)
+
+	zeroValueOperationsValue := ethereum.ZeroValueOperations(os.Getenv(ZeroValueOperationsEnv))
+	switch zeroValueOperationsValue {
+	case ethereum.SkipZeroValueOperations,
+		ethereum.SuppressZeroValueOperations,
+		ethereum.LabelZeroValueOperations:
+		config.ZeroValueOperations = zeroValueOperationsValue
+	case "":
+	default:
+		return nil, fmt.Errorf("%s is not a valid zero value operations setting", zeroValueOperationsValue)
+	}
+
+	envDustThreshold := os.Getenv(DustThresholdEnv)
+	if len(envDustThreshold) > 0 {
+		threshold, ok := new(big.Int).SetString(envDustThreshold, 10)
+		if !ok || threshold.Sign() <= 0 {
+			return nil, fmt.Errorf("%s is not a valid %s", envDustThreshold, DustThresholdEnv)
+		}
+		config.DustThreshold = threshold
+	}
+
+	abiRegistry, err := loadABIRegistryConfig()
+	if err != nil {
+		return nil, err
+	}
+	config.ABIRegistry = abiRegistry
+
+	entryPoints, err := loadUserOperationEntryPoints()
+	if err != nil {
+		return nil, err
+	}
+	config.UserOperationEntryPoints = entryPoints
+
+	envCanonicalJSON := os.Getenv(CanonicalJSONEnv)
+	if len(envCanonicalJSON) > 0 {
+		val, err := strconv.ParseBool(envCanonicalJSON)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, CanonicalJSONEnv, envCanonicalJSON)
+		}
+		config.CanonicalJSON = val
+	}
+
+	envMetadataCacheTTL := os.Getenv(MetadataCacheTTLEnv)
+	if len(envMetadataCacheTTL) > 0 {
+		val, err := time.ParseDuration(envMetadataCacheTTL)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%w: unable to parse %s %s",
+				err,
+				MetadataCacheTTLEnv,
+				envMetadataCacheTTL,
+			)
+		}
+		if val <= 0 {
+			return nil, fmt.Errorf(
+				"unable to parse %s %s: must be positive",
+				MetadataCacheTTLEnv,
+				envMetadataCacheTTL,
+			)
+		}
+		config.MetadataCacheTTL = val
+	}
+
+	envTxPoolMetrics := os.Getenv(TxPoolMetricsEnv)
+	if len(envTxPoolMetrics) > 0 {
+		val, err := strconv.ParseBool(envTxPoolMetrics)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, TxPoolMetricsEnv, envTxPoolMetrics)
+		}
+
+		if val {
+			if config.MempoolMonitor == nil {
+				config.MempoolMonitor = &ethereum.MempoolMonitorConfig{
+					StuckAfter: ethereum.DefaultNonceStuckAfter,
+				}
+			}
+			config.MempoolMonitor.Metrics = true
+		}
+	}
+
+	envPublicMode := os.Getenv(PublicModeEnv)
+	if len(envPublicMode) > 0 {
+		val, err := strconv.ParseBool(envPublicMode)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, PublicModeEnv, envPublicMode)
+		}
+		config.PublicMode = val
+	}
+
+	envRateLimit := os.Getenv(RateLimitEnv)
+	if len(envRateLimit) > 0 {
+		val, err := strconv.Atoi(envRateLimit)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, RateLimitEnv, envRateLimit)
+		}
+		if val <= 0 {
+			return nil, fmt.Errorf("unable to parse %s %s: must be positive", RateLimitEnv, envRateLimit)
+		}
+		config.RateLimit = val
+	}
+
+	if config.PublicMode {
+		if config.RateLimit == 0 {
+			config.RateLimit = DefaultPublicRateLimit
+		}
+		if config.MaxRequestBodySize == 0 {
+			config.MaxRequestBodySize = DefaultPublicMaxRequestBodySize
+		}
+	}
+
+	config.WatermarkPath = os.Getenv(WatermarkEnv)
+
+	envArchiveURLs := os.Getenv(ArchiveURLsEnv)
+	for _, url := range strings.Split(envArchiveURLs, ",") {
+		if url = strings.TrimSpace(url); len(url) > 0 {
+			config.ArchiveURLs = append(config.ArchiveURLs, url)
+		}
+	}
+
+	envHedgeDelay := os.Getenv(HedgeDelayEnv)
+	if len(envHedgeDelay) > 0 {
+		val, err := time.ParseDuration(envHedgeDelay)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%w: unable to parse %s %s",
+				err,
+				HedgeDelayEnv,
+				envHedgeDelay,
+			)
+		}
+		if val <= 0 {
+			return nil, fmt.Errorf(
+				"unable to parse %s %s: must be positive",
+				HedgeDelayEnv,
+				envHedgeDelay,
+			)
+		}
+		config.HedgeDelay = val
+	}
+
+	if config.HedgeDelay > 0 && len(config.ArchiveURLs) == 0 {
+		return nil, fmt.Errorf("%s requires %s to be populated", HedgeDelayEnv, ArchiveURLsEnv)
+	}
+
+	gasLimits, err := loadGasLimits()
+	if err != nil {
+		return nil, err
+	}
+	config.GasLimits = gasLimits
+
+	config.RuntimeConfigPath = os.Getenv(RuntimeConfigEnv)
+	if len(config.RuntimeConfigPath) > 0 {
+		config.environment = &runtimeSettings{
+			blockInlineTransactions: config.BlockInlineTransactions,
+			rateLimit:               config.RateLimit,
+			responseCacheSize:       config.ResponseCacheSize,
+			responseCacheTTL:        config.ResponseCacheTTL,
+		}
+
+		settings, err := config.environment.override(config.RuntimeConfigPath)
+		if err != nil {
+			return nil, err
+		}
+		config.BlockInlineTransactions = settings.blockInlineTransactions
+		config.RateLimit = settings.rateLimit
+		config.ResponseCacheSize = settings.responseCacheSize
+		config.ResponseCacheTTL = settings.responseCacheTTL
+	}
+
+	httpServer, err := loadHTTPServerConfig()
+	if err != nil {
+		return nil, err
+	}
+	config.HTTPServer = *httpServer
+
+	portValue := os.Getenv(PortEnv)
+	if len(portValue) == 0 && len(config.HTTPServer.ListenAddresses) > 0 {
+		return config, nil
+	}
+	if len(portValue) == 0 {
+		return nil, errors.New("PORT must be populated")
+	}
+
+	port, err := strconv.Atoi(portValue)
+	if err != nil || len(portValue) == 0 || port <= 0 {
+		return nil, fmt.Errorf("%w: unable to parse port %s", err, portValue)
+	}
+	config.Port = port
+
+	return config, nil
+}
+
+// loadGasLimits parses GasLimitMultiplierEnv, GasLimitCapEnv,
+// and GasLimitDefaultsEnv. It returns nil if none are set.
+func loadGasLimits() (*GasLimits, error) {
+	envMultiplier := os.Getenv(GasLimitMultiplierEnv)
+	envCap := os.Getenv(GasLimitCapEnv)
+	envDefaults := os.Getenv(GasLimitDefaultsEnv)
+	if len(envMultiplier) == 0 && len(envCap) == 0 && len(envDefaults) == 0 {
+		return nil, nil
+	}
+
+	gasLimits := &GasLimits{
+		Multiplier: 1,
+		Defaults:   map[GasLimitType]uint64{},
+	}
+
+	if len(envMultiplier) > 0 {
+		val, err := strconv.ParseFloat(envMultiplier, 64)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%w: unable to parse %s %s",
+				err,
+				GasLimitMultiplierEnv,
+				envMultiplier,
+			)
+		}
+		if val < 1 || math.IsInf(val, 0) {
+			return nil, fmt.Errorf(
+				"unable to parse %s %s: must be a finite number of at least 1",
+				GasLimitMultiplierEnv,
+				envMultiplier,
+			)
+		}
+		gasLimits.Multiplier = val
+	}
+
+	if len(envCap) > 0 {
+		val, err := strconv.ParseUint(envCap, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, GasLimitCapEnv, envCap)
+		}
+		gasLimits.Cap = val
+	}
+
+	for _, pair := range strings.Split(envDefaults, ",") {
+		if pair = strings.TrimSpace(pair); len(pair) == 0 {
+			continue
+		}
+
+		separator := strings.Index(pair, "=")
+		if separator < 0 {
+			return nil, fmt.Errorf("%s in %s is not a <type>=<gas limit> pair", pair, GasLimitDefaultsEnv)
+		}
+
+		gasLimitType := GasLimitType(strings.ToLower(strings.TrimSpace(pair[:separator])))
+		if !validGasLimitType(gasLimitType) {
+			return nil, fmt.Errorf("%s is not a valid gas limit type", gasLimitType)
+		}
+
+		val, err := strconv.ParseUint(strings.TrimSpace(pair[separator+1:]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, GasLimitDefaultsEnv, envDefaults)
+		}
+		if val == 0 {
+			return nil, fmt.Errorf("unable to parse %s %s: must be positive", GasLimitDefaultsEnv, envDefaults)
+		}
+		gasLimits.Defaults[gasLimitType] = val
+	}
+
+	return gasLimits, nil
+}
+
+// loadRequestBodySizeLimits parses RequestBodySizeLimitsEnv.
+// It returns nil if it is not set.
+func loadRequestBodySizeLimits() (map[string]int64, error) {
+	envLimits := os.Getenv(RequestBodySizeLimitsEnv)
+	if len(envLimits) == 0 {
+		return nil, nil
+	}
+
+	limits := map[string]int64{}
+	for _, pair := range strings.Split(envLimits, ",") {
+		if pair = strings.TrimSpace(pair); len(pair) == 0 {
+			continue
+		}
+
+		separator := strings.LastIndex(pair, "=")
+		if separator < 0 || !strings.HasPrefix(strings.TrimSpace(pair), "/") {
+			return nil, fmt.Errorf("%s in %s is not a <path>=<bytes> pair", pair, RequestBodySizeLimitsEnv)
+		}
+		path := strings.TrimSpace(pair[:separator])
+
+		val, err := strconv.ParseInt(strings.TrimSpace(pair[separator+1:]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, RequestBodySizeLimitsEnv, envLimits)
+		}
+		if val <= 0 {
+			return nil, fmt.Errorf("unable to parse %s %s: must be positive", RequestBodySizeLimitsEnv, envLimits)
+		}
+		limits[path] = val
+	}
+
+	return limits, nil
+}
+
+// loadHTTPServerConfig parses the environment
+// variables of the HTTPServerConfig.
+// loadABIRegistryConfig returns the configuration of the ABI
+// registry. If it is not enabled, nil is returned.
+func loadABIRegistryConfig() (*ethereum.ABIRegistryConfig, error) {
+	envABIPath := os.Getenv(ABIPathEnv)
+	envFourByteURL := os.Getenv(FourByteURLEnv)
+	enabled := len(envABIPath) > 0 || len(envFourByteURL) > 0
+
+	envABIRegistry := os.Getenv(ABIRegistryEnv)
+	if len(envABIRegistry) > 0 {
+		val, err := strconv.ParseBool(envABIRegistry)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, ABIRegistryEnv, envABIRegistry)
+		}
+		enabled = val
+	}
+
+	if !enabled {
+		return nil, nil
+	}
+
+	config := &ethereum.ABIRegistryConfig{}
+	if len(envABIPath) > 0 {
+		abis, err := ethereum.LoadABIs(envABIPath)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, ABIPathEnv, envABIPath)
+		}
+		config.ABIs = abis
+	}
+
+	if len(envFourByteURL) > 0 {
+		u, err := url.Parse(envFourByteURL)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, FourByteURLEnv, envFourByteURL)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("unable to parse %s %s: must be an http or https URL", FourByteURLEnv, envFourByteURL)
+		}
+		config.LookupURL = envFourByteURL
+	}
+
+	return config, nil
+}
+
+// loadUserOperationEntryPoints returns the EntryPoint contracts
+// whose user operations are surfaced. If user operations are
+// not enabled, nil is returned.
+func loadUserOperationEntryPoints() ([]common.Address, error) {
+	envUserOperations := os.Getenv(UserOperationsEnv)
+	if len(envUserOperations) == 0 {
+		return nil, nil
+	}
+
+	val, err := strconv.ParseBool(envUserOperations)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to parse %s %s", err, UserOperationsEnv, envUserOperations)
+	}
+	if !val {
+		return nil, nil
+	}
+
+	envEntryPoints := os.Getenv(EntryPointContractsEnv)
+	if len(envEntryPoints) == 0 {
+		return ethereum.EntryPointContracts, nil
+	}
+
+	var entryPoints []common.Address
+	for _, address := range strings.Split(envEntryPoints, ",") {
+		address = strings.TrimSpace(address)
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("%s in %s is not a valid address", address, EntryPointContractsEnv)
+		}
+
+		entryPoints = append(entryPoints, common.HexToAddress(address))
+	}
+
+	return entryPoints, nil
+}
+
+func loadHTTPServerConfig() (*HTTPServerConfig, error) {
+	config := &HTTPServerConfig{}
+	for env, timeout := range map[string]*time.Duration{
+		HTTPReadTimeoutEnv:  &config.ReadTimeout,
+		HTTPWriteTimeoutEnv: &config.WriteTimeout,
+		HTTPIdleTimeoutEnv:  &config.IdleTimeout,
+		HTTPKeepAliveEnv:    &config.TCPKeepAlive,
+	} {
+		envTimeout := os.Getenv(env)
+		if len(envTimeout) == 0 {
+			continue
+		}
+
+		val, err := time.ParseDuration(envTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, env, envTimeout)
+		}
+		if val <= 0 && env != HTTPKeepAliveEnv {
+			return nil, fmt.Errorf("unable to parse %s %s: must be positive", env, envTimeout)
+		}
+		*timeout = val
+	}
+
+	envMaxHeaderBytes := os.Getenv(HTTPMaxHeaderBytesEnv)
+	if len(envMaxHeaderBytes) > 0 {
+		val, err := strconv.Atoi(envMaxHeaderBytes)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%w: unable to parse %s %s",
+				err,
+				HTTPMaxHeaderBytesEnv,
+				envMaxHeaderBytes,
+			)
+		}
+		if val <= 0 {
+			return nil, fmt.Errorf(
+				"unable to parse %s %s: must be positive",
+				HTTPMaxHeaderBytesEnv,
+				envMaxHeaderBytes,
+			)
+		}
+		config.MaxHeaderBytes = val
+	}
+
+	envListenAddresses := os.Getenv(ListenAddressesEnv)
+	for _, address := range strings.Split(envListenAddresses, ",") {
+		if address = strings.TrimSpace(address); len(address) == 0 {
+			continue
+		}
+
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, ListenAddressesEnv, envListenAddresses)
+		}
+		config.ListenAddresses = append(config.ListenAddresses, address)
+	}
+
+	config.AdminListenAddress = strings.TrimSpace(os.Getenv(AdminListenAddressEnv))
+	if len(config.AdminListenAddress) > 0 {
+		if _, _, err := net.SplitHostPort(config.AdminListenAddress); err != nil {
+			return nil, fmt.Errorf(
+				"%w: unable to parse %s %s",
+				err,
+				AdminListenAddressEnv,
+				config.AdminListenAddress,
+			)
+		}
+	}
+
+	config.TLSCertFile = os.Getenv(TLSCertFileEnv)
+	config.TLSKeyFile = os.Getenv(TLSKeyFileEnv)
+	if (len(config.TLSCertFile) > 0) != (len(config.TLSKeyFile) > 0) {
+		return nil, fmt.Errorf("%s and %s must be set together", TLSCertFileEnv, TLSKeyFileEnv)
+	}
+
+	config.ConstructionClientCAFile = os.Getenv(ConstructionClientCAFileEnv)
+	if len(config.ConstructionClientCAFile) > 0 && len(config.TLSCertFile) == 0 {
+		return nil, fmt.Errorf("%s requires %s to be populated", ConstructionClientCAFileEnv, TLSCertFileEnv)
+	}
+
+	envAllowedIPs := os.Getenv(ConstructionAllowedIPsEnv)
+	for _, entry := range strings.Split(envAllowedIPs, ",") {
+		if entry = strings.TrimSpace(entry); len(entry) == 0 {
+			continue
+		}
+
+		network, err := parseNetwork(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, ConstructionAllowedIPsEnv, envAllowedIPs)
+		}
+		config.ConstructionAllowedNetworks = append(config.ConstructionAllowedNetworks, network)
+	}
+
+	return config, nil
+}
+
+// parseNetwork parses a CIDR range or
+// an IP address (as a single address range).
+func parseNetwork(entry string) (*net.IPNet, error) {
+	if strings.Contains(entry, "/") {
+		_, network, err := net.ParseCIDR(entry)
+		return network, err
+	}
+
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return nil, fmt.Errorf("%s is not an IP address", entry)
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil // nolint:gomnd
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil // nolint:gomnd
+}
+
+// loadMaintenanceWindows parses MaintenanceWindowsEnv.
+// It returns nil if it is not set.
+func loadMaintenanceWindows() ([]*MaintenanceWindow, error) {
+	envWindows := os.Getenv(MaintenanceWindowsEnv)
+	if len(envWindows) == 0 {
+		return nil, nil
+	}
+
+	windows := []*MaintenanceWindow{}
+	for _, pair := range strings.Split(envWindows, ",") {
+		if pair = strings.TrimSpace(pair); len(pair) == 0 {
+			continue
+		}
+
+		bounds := strings.Split(pair, "/")
+		if len(bounds) != 2 { // nolint:gomnd
+			return nil, fmt.Errorf("%s in %s is not a <start>/<end> pair", pair, MaintenanceWindowsEnv)
+		}
+
+		start, err := time.Parse(time.RFC3339, strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, MaintenanceWindowsEnv, envWindows)
+		}
+		end, err := time.Parse(time.RFC3339, strings.TrimSpace(bounds[1]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse %s %s", err, MaintenanceWindowsEnv, envWindows)
+		}
+		if !end.After(start) {
+			return nil, fmt.Errorf("%s in %s does not end after it starts", pair, MaintenanceWindowsEnv)
+		}
+
+		windows = append(windows, &MaintenanceWindow{Start: start, End: end})
+	}
+
+	return windows, nil
+}
+
+// loadCustomTracer returns the *ethereum.CustomTracer configured
+// by CustomTracerEnv and CustomTracerProcessorEnv, or nil if no
+// custom tracer is configured.
+func loadCustomTracer() (*ethereum.CustomTracer, error) {
+	envCustomTracer := os.Getenv(CustomTracerEnv)
+	envProcessor := os.Getenv(CustomTracerProcessorEnv)
+	if len(envCustomTracer) == 0 {
+		if len(envProcessor) > 0 {
+			return nil, fmt.Errorf("%s requires %s", CustomTracerProcessorEnv, CustomTracerEnv)
+		}
+
+		return nil, nil
+	}
+
+	var processor ethereum.TraceProcessor
+	if len(envProcessor) > 0 {
+		var ok bool
+		processor, ok = ethereum.LookupTraceProcessor(envProcessor)
+		if !ok {
+			return nil, fmt.Errorf(
+				"%s is not a registered trace processor (registered: %s)",
+				envProcessor,
+				strings.Join(ethereum.TraceProcessors(), ", "),
+			)
+		}
+	}
+
+	tracer, err := ethereum.LoadCustomTracer(envCustomTracer, processor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to parse %s %s", err, CustomTracerEnv, envCustomTracer)
+	}
+
+	return tracer, nil
+}
+
+// validGasLimitType returns true if
+// gasLimitType is in GasLimitTypes.
+func validGasLimitType(gasLimitType GasLimitType) bool {
+	for _, t := range GasLimitTypes {
+		if t == gasLimitType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validModule returns true if module is in Modules.
+func validModule(module Module) bool {
+	for _, m := range Modules {
+		if m == module {
+			return true
+		}
+	}
+
+	return false
 }
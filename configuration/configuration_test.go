@@ -16,23 +16,116 @@ package configuration
 
 import (
 	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/coinbase/rosetta-ethereum/ethereum"
+	"github.com/coinbase/rosetta-ethereum/indexer"
+	"github.com/coinbase/rosetta-ethereum/redact"
 
 	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestLoadConfiguration(t *testing.T) {
+	multicallContract := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
 	tests := map[string]struct {
-		Mode          string
-		Network       string
-		Port          string
-		Geth          string
-		SkipGethAdmin string
+		Mode           string
+		Network        string
+		Port           string
+		Geth           string
+		SkipGethAdmin  string
+		ValidationMode string
+		Metrics        string
+		LogRedaction   string
+		NonceTracker   string
+		Index          string
+		AccountSummary string
+		InlineTxs      string
+		Approvals      string
+		RuntimeConfig  string
+		AdminReload    string
+		Watched        string
+		ReferenceURLs  string
+		MaxNodeLag     string
+		RejectStale    string
+		Retention      string
+		Prefixes       string
+		Invariants     string
+		Proxy          string
+		CacheSize      string
+		CacheTTL       string
+		AuditLog       string
+		AuditLogKey    string
+		Disabled       string
+		TimestampStart string
+		Collapse       string
+		StakedBalances string
+		GasMultiplier  string
+		GasCap         string
+		GasDefaults    string
+		UpstreamReport string
+		ChainMismatch  string
+		MaxBodySize    string
+		BodySizeLimits string
+		StrictJSON     string
+		ArchiveURLs    string
+		NonceAddresses string
+		NonceStuck     string
+		CustomTracer   string
+		TraceProcessor string
+		Labels         string
+		Multicall      string
+		Transformers   string
+		BalanceCache   string
+		ArchiveURL     string
+		ArchiveMode    string
+		HeadEvents     string
+		GraphQLBatch   string
+		Maintenance    string
+		Windows        string
+		EventsHistory  string
+		RewardTo       string
+		SubmitQueue    string
+		TxPoolMetrics  string
+		ReadTimeout    string
+		WriteTimeout   string
+		IdleTimeout    string
+		MaxHeaders     string
+		KeepAlive      string
+		Listen         string
+		AdminListen    string
+		ZeroValueOps   string
+		ABIRegistry    string
+		ABIPath        string
+		FourByteURL    string
+		UserOps        string
+		EntryPoints    string
+		DebugUI        string
+		CanonicalJSON  string
+		DustThreshold  string
+		MetadataTTL    string
+		RawRLP         string
+		PublicMode     string
+		RateLimit      string
+		TLSCert        string
+		TLSKey         string
+		ClientCA       string
+		AllowedIPs     string
+		Watermark      string
+		TokenHolders   string
+		TraceStart     string
+		HedgeDelay     string
+		Consensus      string
+		HDDerivation   string
 
 		cfg *Configuration
 		err error
@@ -66,6 +159,8 @@ func TestLoadConfiguration(t *testing.T) {
 				GethURL:                DefaultGethURL,
 				GethArguments:          ethereum.MainnetGethArguments,
 				SkipGethAdmin:          false,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
 			},
 		},
 		"all set (mainnet) + geth": {
@@ -87,6 +182,8 @@ func TestLoadConfiguration(t *testing.T) {
 				RemoteGeth:             true,
 				GethArguments:          ethereum.MainnetGethArguments,
 				SkipGethAdmin:          true,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
 			},
 		},
 		"all set (ropsten)": {
@@ -104,6 +201,8 @@ func TestLoadConfiguration(t *testing.T) {
 				Port:                   1000,
 				GethURL:                DefaultGethURL,
 				GethArguments:          ethereum.RopstenGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
 			},
 		},
 		"all set (rinkeby)": {
@@ -121,6 +220,8 @@ func TestLoadConfiguration(t *testing.T) {
 				Port:                   1000,
 				GethURL:                DefaultGethURL,
 				GethArguments:          ethereum.RinkebyGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
 			},
 		},
 		"all set (goerli)": {
@@ -138,11 +239,13 @@ func TestLoadConfiguration(t *testing.T) {
 				Port:                   1000,
 				GethURL:                DefaultGethURL,
 				GethArguments:          ethereum.GoerliGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
 			},
 		},
-		"all set (testnet)": {
+		"all set (devnet)": {
 			Mode:          string(Online),
-			Network:       Testnet,
+			Network:       Devnet,
 			Port:          "1000",
 			SkipGethAdmin: "TRUE",
 			cfg: &Configuration{
@@ -151,14 +254,2013 @@ func TestLoadConfiguration(t *testing.T) {
 					Network:    ethereum.DevNetwork,
 					Blockchain: ethereum.Blockchain,
 				},
-				Params:                 params.AllCliqueProtocolChanges,
-				GenesisBlockIdentifier: nil,
+				Params:                 ethereum.DevChainConfig,
+				GenesisBlockIdentifier: ethereum.DevGenesisBlockIdentifier,
 				Port:                   1000,
 				GethURL:                DefaultGethURL,
 				GethArguments:          ethereum.DevGethArguments,
+				SystemContracts:        ethereum.SystemContracts(),
 				SkipGethAdmin:          true,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
 			},
 		},
+		"all set (mainnet) + strict validation + metrics": {
+			Mode:           string(Online),
+			Network:        Mainnet,
+			Port:           "1000",
+			ValidationMode: string(StrictValidation),
+			Metrics:        "true",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         StrictValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				EnableMetrics:          true,
+			},
+		},
+		"all set (mainnet) + log redaction": {
+			Mode:         string(Online),
+			Network:      Mainnet,
+			Port:         "1000",
+			LogRedaction: "testdata/log_redaction.json",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				LogRedaction: &redact.Config{
+					Fields:   []string{"mnemonic"},
+					Headers:  []string{"X-Node-Token"},
+					Patterns: []string{"sk_[a-z0-9]+"},
+				},
+				LogRedactionPath: "testdata/log_redaction.json",
+			},
+		},
+		"all set (mainnet) + nonce tracker": {
+			Mode:         string(Online),
+			Network:      Mainnet,
+			Port:         "1000",
+			NonceTracker: "/data/nonces",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				NonceTrackerPath:       "/data/nonces",
+			},
+		},
+		"all set (mainnet) + audit log": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			AuditLog:    "/data/audit.log",
+			AuditLogKey: "secret",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				AuditLogPath:           "/data/audit.log",
+				AuditLogKey:            "secret",
+			},
+		},
+		"all set (mainnet) + disabled modules": {
+			Mode:     string(Online),
+			Network:  Mainnet,
+			Port:     "1000",
+			Disabled: "construction, Mempool",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				DisabledModules:        []Module{ConstructionModule, MempoolModule},
+			},
+		},
+		"all set (mainnet) + timestamp start index": {
+			Mode:           string(Online),
+			Network:        Mainnet,
+			Port:           "1000",
+			TimestampStart: "42",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				TimestampStartIndex:    types.Int64(42),
+			},
+		},
+		"all set (mainnet) + collapse operations": {
+			Mode:     string(Online),
+			Network:  Mainnet,
+			Port:     "1000",
+			Collapse: "true",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				CollapseOperations:     true,
+			},
+		},
+		"all set (mainnet) + staked balances": {
+			Mode:           string(Online),
+			Network:        Mainnet,
+			Port:           "1000",
+			StakedBalances: "true",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				EnableStakedBalances:   true,
+			},
+		},
+		"all set (mainnet) + gas limits": {
+			Mode:          string(Online),
+			Network:       Mainnet,
+			Port:          "1000",
+			GasMultiplier: "1.2",
+			GasCap:        "500000",
+			GasDefaults:   "transfer=25000, DELEGATE=350000",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				GasLimits: &GasLimits{
+					Multiplier: 1.2,
+					Cap:        500000,
+					Defaults: map[GasLimitType]uint64{
+						TransferGasLimit: 25000,
+						DelegateGasLimit: 350000,
+					},
+				},
+			},
+		},
+		"all set (mainnet) + upstream report": {
+			Mode:           string(Online),
+			Network:        Mainnet,
+			Port:           "1000",
+			UpstreamReport: "true",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				EnableUpstreamReport:   true,
+			},
+		},
+		"all set (mainnet) + quarantine on chain mismatch": {
+			Mode:          string(Online),
+			Network:       Mainnet,
+			Port:          "1000",
+			ChainMismatch: string(QuarantineOnChainMismatch),
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    QuarantineOnChainMismatch,
+			},
+		},
+		"all set (mainnet) + request limits": {
+			Mode:           string(Online),
+			Network:        Mainnet,
+			Port:           "1000",
+			MaxBodySize:    "65536",
+			BodySizeLimits: "/construction/combine=1048576, /call=4096",
+			StrictJSON:     "true",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				MaxRequestBodySize:     65536,
+				RequestBodySizeLimits: map[string]int64{
+					"/construction/combine": 1048576,
+					"/call":                 4096,
+				},
+				StrictJSONDecoding: true,
+			},
+		},
+		"all set (mainnet) + archive urls": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			ArchiveURLs: "http://archive-1:8545, ws://archive-2:8546,",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				ArchiveURLs:            []string{"http://archive-1:8545", "ws://archive-2:8546"},
+			},
+		},
+		"all set (mainnet) + nonce monitor": {
+			Mode:           string(Online),
+			Network:        Mainnet,
+			Port:           "1000",
+			NonceAddresses: "0x1111111111111111111111111111111111111111, 0x2222222222222222222222222222222222222222",
+			NonceStuck:     "2m",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				MempoolMonitor: &ethereum.MempoolMonitorConfig{
+					Addresses: []common.Address{
+						common.HexToAddress("0x1111111111111111111111111111111111111111"),
+						common.HexToAddress("0x2222222222222222222222222222222222222222"),
+					},
+					StuckAfter: 2 * time.Minute,
+				},
+			},
+		},
+		"invalid nonce monitor address": {
+			Mode:           string(Online),
+			Network:        Mainnet,
+			Port:           "1000",
+			NonceAddresses: "0x1111111111111111111111111111111111111111,0x12",
+			err:            errors.New("0x12 in NONCE_MONITOR_ADDRESSES is not a valid address"),
+		},
+		"invalid nonce stuck after": {
+			Mode:           string(Online),
+			Network:        Mainnet,
+			Port:           "1000",
+			NonceAddresses: "0x1111111111111111111111111111111111111111",
+			NonceStuck:     "-1m",
+			err:            errors.New("unable to parse NONCE_STUCK_AFTER -1m: must be positive"),
+		},
+		"all set (mainnet) + custom tracer": {
+			Mode:         string(Online),
+			Network:      Mainnet,
+			Port:         "1000",
+			CustomTracer: "prestateTracer",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				CustomTracer: &ethereum.CustomTracer{
+					Tracer: "prestateTracer",
+				},
+			},
+		},
+		"invalid custom tracer": {
+			Mode:         string(Online),
+			Network:      Mainnet,
+			Port:         "1000",
+			CustomTracer: "./missing.js",
+			err:          errors.New("unable to parse CUSTOM_TRACER ./missing.js"),
+		},
+		"unregistered trace processor": {
+			Mode:           string(Online),
+			Network:        Mainnet,
+			Port:           "1000",
+			CustomTracer:   "prestateTracer",
+			TraceProcessor: "tokens",
+			err:            errors.New("tokens is not a registered trace processor"),
+		},
+		"trace processor without custom tracer": {
+			Mode:           string(Online),
+			Network:        Mainnet,
+			Port:           "1000",
+			TraceProcessor: "tokens",
+			err:            errors.New("CUSTOM_TRACER_PROCESSOR requires CUSTOM_TRACER"),
+		},
+		"all set (mainnet) + labels": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Port:    "1000",
+			Labels:  "testdata/labels.json",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				Labels: map[common.Address]*ethereum.Label{
+					common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"): {
+						Name:     "Exchange A",
+						Category: "exchange",
+					},
+				},
+			},
+		},
+		"invalid labels": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Port:    "1000",
+			Labels:  "testdata/labels_invalid.json",
+			err:     errors.New("0x1234 is not a valid address: unable to parse LABELS_PATH"),
+		},
+		"missing labels": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Port:    "1000",
+			Labels:  "testdata/missing.json",
+			err:     errors.New("unable to parse LABELS_PATH testdata/missing.json"),
+		},
+		"all set (mainnet) + multicall contract": {
+			Mode:      string(Online),
+			Network:   Mainnet,
+			Port:      "1000",
+			Multicall: "0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				MulticallContract:      &multicallContract,
+			},
+		},
+		"invalid multicall contract": {
+			Mode:      string(Online),
+			Network:   Mainnet,
+			Port:      "1000",
+			Multicall: "0x1234",
+			err:       errors.New("0x1234 in MULTICALL_CONTRACT is not a valid address"),
+		},
+		"unregistered block transformer": {
+			Mode:         string(Online),
+			Network:      Mainnet,
+			Port:         "1000",
+			Transformers: "swaps",
+			err:          errors.New("swaps in BLOCK_TRANSFORMERS is not a registered block transformer"),
+		},
+		"all set (mainnet) + balance cache": {
+			Mode:         string(Online),
+			Network:      Mainnet,
+			Port:         "1000",
+			BalanceCache: "10000",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				BalanceCacheSize:       10000,
+			},
+		},
+		"invalid balance cache size": {
+			Mode:         string(Online),
+			Network:      Mainnet,
+			Port:         "1000",
+			BalanceCache: "-1",
+			err:          errors.New("unable to parse BALANCE_CACHE_SIZE -1: must not be negative"),
+		},
+		"all set (mainnet) + block archive": {
+			Mode:       string(Online),
+			Network:    Mainnet,
+			Port:       "1000",
+			ArchiveURL: "s3://archive/mainnet",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				BlockArchiveURL:        "s3://archive/mainnet",
+				BlockArchiveModes:      []BlockArchiveMode{ExportBlockArchive},
+			},
+		},
+		"all set (mainnet) + block archive replay": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			ArchiveURL:  "gs://archive",
+			ArchiveMode: "Replay, export",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				BlockArchiveURL:        "gs://archive",
+				BlockArchiveModes:      []BlockArchiveMode{ReplayBlockArchive, ExportBlockArchive},
+			},
+		},
+		"invalid block archive mode": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			ArchiveURL:  "gs://archive",
+			ArchiveMode: "import",
+			err:         errors.New("import in BLOCK_ARCHIVE_MODE is not a valid block archive mode"),
+		},
+		"block archive mode without url": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			ArchiveMode: "replay",
+			err:         errors.New("BLOCK_ARCHIVE_MODE requires BLOCK_ARCHIVE_URL to be populated"),
+		},
+		"all set (mainnet) + head events": {
+			Mode:       string(Online),
+			Network:    Mainnet,
+			Port:       "1000",
+			HeadEvents: "true",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				EnableHeadEvents:       true,
+			},
+		},
+		"all set (mainnet) + block events history": {
+			Mode:          string(Online),
+			Network:       Mainnet,
+			Port:          "1000",
+			HeadEvents:    "true",
+			EventsHistory: "100000",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				EnableHeadEvents:       true,
+				BlockEventsHistory:     100000,
+			},
+		},
+		"invalid block events history": {
+			Mode:          string(Online),
+			Network:       Mainnet,
+			Port:          "1000",
+			EventsHistory: "-1",
+			err:           errors.New("unable to parse BLOCK_EVENTS_HISTORY -1: must not be negative"),
+		},
+		"all set (mainnet) + reward recipient": {
+			Mode:     string(Online),
+			Network:  Mainnet,
+			Port:     "1000",
+			RewardTo: "fee_address",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				RewardRecipient:        ethereum.FeeAddressRewardRecipient,
+			},
+		},
+		"invalid reward recipient": {
+			Mode:     string(Online),
+			Network:  Mainnet,
+			Port:     "1000",
+			RewardTo: "validator",
+			err:      errors.New("validator is not a valid reward recipient"),
+		},
+		"all set (mainnet) + submit queue": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			SubmitQueue: "/data/submit",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				SubmitQueuePath:        "/data/submit",
+			},
+		},
+		"all set (mainnet) + txpool metrics": {
+			Mode:           string(Online),
+			Network:        Mainnet,
+			Port:           "1000",
+			TxPoolMetrics:  "true",
+			NonceAddresses: "0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309",
+			NonceStuck:     "10m",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				MempoolMonitor: &ethereum.MempoolMonitorConfig{
+					Addresses: []common.Address{
+						common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"),
+					},
+					StuckAfter: 10 * time.Minute,
+					Metrics:    true,
+				},
+			},
+		},
+		"all set (mainnet) + txpool metrics without addresses": {
+			Mode:          string(Online),
+			Network:       Mainnet,
+			Port:          "1000",
+			TxPoolMetrics: "true",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				MempoolMonitor: &ethereum.MempoolMonitorConfig{
+					StuckAfter: ethereum.DefaultNonceStuckAfter,
+					Metrics:    true,
+				},
+			},
+		},
+		"invalid txpool metrics": {
+			Mode:          string(Online),
+			Network:       Mainnet,
+			Port:          "1000",
+			TxPoolMetrics: "sometimes",
+			err:           errors.New("unable to parse ENABLE_TXPOOL_METRICS sometimes"),
+		},
+		"all set (mainnet) + http server": {
+			Mode:         string(Online),
+			Network:      Mainnet,
+			Port:         "1000",
+			ReadTimeout:  "10s",
+			WriteTimeout: "5m",
+			IdleTimeout:  "1m",
+			MaxHeaders:   "65536",
+			KeepAlive:    "-1s",
+			AdminListen:  "127.0.0.1:9090",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				HTTPServer: HTTPServerConfig{
+					ReadTimeout:        10 * time.Second,
+					WriteTimeout:       5 * time.Minute,
+					IdleTimeout:        time.Minute,
+					MaxHeaderBytes:     65536,
+					TCPKeepAlive:       -time.Second,
+					AdminListenAddress: "127.0.0.1:9090",
+				},
+			},
+		},
+		"all set (mainnet) + listen addresses without port": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Listen:  "127.0.0.1:8080, [::1]:8080",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				HTTPServer: HTTPServerConfig{
+					ListenAddresses: []string{"127.0.0.1:8080", "[::1]:8080"},
+				},
+			},
+		},
+		"invalid http read timeout": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			ReadTimeout: "0s",
+			err:         errors.New("unable to parse HTTP_READ_TIMEOUT 0s: must be positive"),
+		},
+		"invalid http max header bytes": {
+			Mode:       string(Online),
+			Network:    Mainnet,
+			Port:       "1000",
+			MaxHeaders: "lots",
+			err:        errors.New("unable to parse HTTP_MAX_HEADER_BYTES lots"),
+		},
+		"invalid listen addresses": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Listen:  "127.0.0.1",
+			err:     errors.New("unable to parse LISTEN_ADDRESSES 127.0.0.1"),
+		},
+		"invalid admin listen address": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			AdminListen: "localhost",
+			err:         errors.New("unable to parse ADMIN_LISTEN_ADDRESS localhost"),
+		},
+		"all set (mainnet) + zero value operations": {
+			Mode:         string(Online),
+			Network:      Mainnet,
+			Port:         "1000",
+			ZeroValueOps: "label",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				ZeroValueOperations:    ethereum.LabelZeroValueOperations,
+			},
+		},
+		"invalid zero value operations": {
+			Mode:         string(Online),
+			Network:      Mainnet,
+			Port:         "1000",
+			ZeroValueOps: "drop",
+			err:          errors.New("drop is not a valid zero value operations setting"),
+		},
+		"all set (mainnet) + abi registry": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			ABIRegistry: "true",
+			FourByteURL: ethereum.DefaultFourByteURL,
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				ABIRegistry: &ethereum.ABIRegistryConfig{
+					LookupURL: ethereum.DefaultFourByteURL,
+				},
+			},
+		},
+		"all set (mainnet) + abi registry disabled": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			ABIRegistry: "false",
+			FourByteURL: ethereum.DefaultFourByteURL,
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+			},
+		},
+		"invalid abi registry": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			ABIRegistry: "maybe",
+			err:         errors.New("unable to parse ENABLE_ABI_REGISTRY maybe"),
+		},
+		"missing abi path": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Port:    "1000",
+			ABIPath: "/not/a/path",
+			err:     errors.New("unable to parse ABI_PATH /not/a/path"),
+		},
+		"invalid four byte url": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			FourByteURL: "4byte.directory",
+			err:         errors.New("unable to parse FOUR_BYTE_URL 4byte.directory"),
+		},
+		"all set (mainnet) + user operations": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Port:    "1000",
+			UserOps: "true",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                   params.MainnetChainConfig,
+				GenesisBlockIdentifier:   ethereum.MainnetGenesisBlockIdentifier,
+				Port:                     1000,
+				GethURL:                  DefaultGethURL,
+				GethArguments:            ethereum.MainnetGethArguments,
+				ValidationMode:           PermissiveValidation,
+				ChainMismatchAction:      HaltOnChainMismatch,
+				UserOperationEntryPoints: ethereum.EntryPointContracts,
+			},
+		},
+		"all set (mainnet) + user operations + entry points": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			UserOps:     "true",
+			EntryPoints: "0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789, 0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				UserOperationEntryPoints: []common.Address{
+					common.HexToAddress("0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"),
+					common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d"),
+				},
+			},
+		},
+		"invalid user operations": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Port:    "1000",
+			UserOps: "maybe",
+			err:     errors.New("unable to parse ENABLE_USER_OPERATIONS maybe"),
+		},
+		"invalid entry points": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			UserOps:     "true",
+			EntryPoints: "0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789,",
+			err:         errors.New(" in ENTRY_POINT_CONTRACTS is not a valid address"),
+		},
+		"all set (mainnet) + debug ui": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Port:    "1000",
+			DebugUI: "true",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				EnableAdminDebugUI:     true,
+			},
+		},
+		"invalid debug ui": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Port:    "1000",
+			DebugUI: "yes please",
+			err:     errors.New("unable to parse ENABLE_ADMIN_DEBUG_UI yes please"),
+		},
+		"all set (mainnet) + canonical json": {
+			Mode:          string(Online),
+			Network:       Mainnet,
+			Port:          "1000",
+			CanonicalJSON: "true",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				CanonicalJSON:          true,
+			},
+		},
+		"invalid canonical json": {
+			Mode:          string(Online),
+			Network:       Mainnet,
+			Port:          "1000",
+			CanonicalJSON: "sorted",
+			err:           errors.New("unable to parse CANONICAL_JSON sorted"),
+		},
+		"all set (mainnet) + dust threshold": {
+			Mode:          string(Online),
+			Network:       Mainnet,
+			Port:          "1000",
+			DustThreshold: "1000000000",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				DustThreshold:          big.NewInt(1000000000),
+			},
+		},
+		"invalid dust threshold": {
+			Mode:          string(Online),
+			Network:       Mainnet,
+			Port:          "1000",
+			DustThreshold: "0.5",
+			err:           errors.New("0.5 is not a valid DUST_THRESHOLD"),
+		},
+		"non-positive dust threshold": {
+			Mode:          string(Online),
+			Network:       Mainnet,
+			Port:          "1000",
+			DustThreshold: "0",
+			err:           errors.New("0 is not a valid DUST_THRESHOLD"),
+		},
+		"all set (mainnet) + metadata cache": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			MetadataTTL: "2s",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				MetadataCacheTTL:       2 * time.Second,
+			},
+		},
+		"invalid metadata cache ttl": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			MetadataTTL: "soon",
+			err:         errors.New("unable to parse CONSTRUCTION_METADATA_CACHE_TTL soon"),
+		},
+		"non-positive metadata cache ttl": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			MetadataTTL: "0s",
+			err:         errors.New("unable to parse CONSTRUCTION_METADATA_CACHE_TTL 0s"),
+		},
+		"all set (mainnet) + raw rlp metadata": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Port:    "1000",
+			RawRLP:  "true",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				RawRLPMetadata:         true,
+				BlockTransformers:      []ethereum.BlockTransformer{ethereum.RawRLPTransformer},
+			},
+		},
+		"invalid raw rlp metadata": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Port:    "1000",
+			RawRLP:  "hex",
+			err:     errors.New("unable to parse RAW_RLP_METADATA hex"),
+		},
+		"all set (mainnet) + consensus metadata": {
+			Mode:      string(Online),
+			Network:   Mainnet,
+			Port:      "1000",
+			RawRLP:    "true",
+			Consensus: "true",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				RawRLPMetadata:         true,
+				ConsensusMetadata:      true,
+				BlockTransformers: []ethereum.BlockTransformer{
+					ethereum.RawRLPTransformer,
+					ethereum.NewConsensusTransformer(params.MainnetChainConfig.ChainID),
+				},
+			},
+		},
+		"invalid consensus metadata": {
+			Mode:      string(Online),
+			Network:   Mainnet,
+			Port:      "1000",
+			Consensus: "yes please",
+			err:       errors.New("unable to parse CONSENSUS_METADATA yes please"),
+		},
+		"all set (mainnet) + hd derivation": {
+			Mode:         string(Online),
+			Network:      Mainnet,
+			Port:         "1000",
+			HDDerivation: "true",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				HDDerivation:           true,
+			},
+		},
+		"invalid hd derivation": {
+			Mode:         string(Online),
+			Network:      Mainnet,
+			Port:         "1000",
+			HDDerivation: "xpub",
+			err:          errors.New("unable to parse HD_DERIVATION xpub"),
+		},
+		"all set (mainnet) + public mode": {
+			Mode:       string(Online),
+			Network:    Mainnet,
+			Port:       "1000",
+			PublicMode: "true",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				PublicMode:             true,
+				RateLimit:              DefaultPublicRateLimit,
+				MaxRequestBodySize:     DefaultPublicMaxRequestBodySize,
+			},
+		},
+		"all set (mainnet) + public mode with limits": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			PublicMode:  "true",
+			RateLimit:   "50",
+			MaxBodySize: "4096",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				PublicMode:             true,
+				RateLimit:              50,
+				MaxRequestBodySize:     4096,
+			},
+		},
+		"all set (mainnet) + rate limit": {
+			Mode:      string(Online),
+			Network:   Mainnet,
+			Port:      "1000",
+			RateLimit: "50",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				RateLimit:              50,
+			},
+		},
+		"invalid public mode": {
+			Mode:       string(Online),
+			Network:    Mainnet,
+			Port:       "1000",
+			PublicMode: "public",
+			err:        errors.New("unable to parse PUBLIC_MODE public"),
+		},
+		"invalid rate limit": {
+			Mode:      string(Online),
+			Network:   Mainnet,
+			Port:      "1000",
+			RateLimit: "0",
+			err:       errors.New("unable to parse RATE_LIMIT 0"),
+		},
+		"all set (mainnet) + construction access": {
+			Mode:       string(Online),
+			Network:    Mainnet,
+			Port:       "1000",
+			TLSCert:    "cert.pem",
+			TLSKey:     "key.pem",
+			ClientCA:   "ca.pem",
+			AllowedIPs: "10.0.0.0/8, 192.0.2.1,::1",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				HTTPServer: HTTPServerConfig{
+					TLSCertFile:              "cert.pem",
+					TLSKeyFile:               "key.pem",
+					ConstructionClientCAFile: "ca.pem",
+					ConstructionAllowedNetworks: []*net.IPNet{
+						{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)},
+						{IP: net.IP{192, 0, 2, 1}, Mask: net.CIDRMask(32, 32)},
+						{IP: net.ParseIP("::1"), Mask: net.CIDRMask(128, 128)},
+					},
+				},
+			},
+		},
+		"tls cert without key": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Port:    "1000",
+			TLSCert: "cert.pem",
+			err:     errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together"),
+		},
+		"client ca without tls": {
+			Mode:     string(Online),
+			Network:  Mainnet,
+			Port:     "1000",
+			ClientCA: "ca.pem",
+			err:      errors.New("CONSTRUCTION_CLIENT_CA_FILE requires TLS_CERT_FILE to be populated"),
+		},
+		"invalid construction allowed ips": {
+			Mode:       string(Online),
+			Network:    Mainnet,
+			Port:       "1000",
+			AllowedIPs: "10.0.0.0/8,10.0.0",
+			err:        errors.New("10.0.0 is not an IP address: unable to parse CONSTRUCTION_ALLOWED_IPS"),
+		},
+		"all set (mainnet) + watermark": {
+			Mode:      string(Online),
+			Network:   Mainnet,
+			Port:      "1000",
+			Watermark: "/data/watermarks.json",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				WatermarkPath:          "/data/watermarks.json",
+			},
+		},
+		"all set (mainnet) + token holders": {
+			Mode:         string(Online),
+			Network:      Mainnet,
+			Port:         "1000",
+			Index:        "/data/index",
+			TokenHolders: "true",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				IndexPath:              "/data/index",
+				IndexTokenHolders:      true,
+			},
+		},
+		"invalid token holders": {
+			Mode:         string(Online),
+			Network:      Mainnet,
+			Port:         "1000",
+			Index:        "/data/index",
+			TokenHolders: "all",
+			err:          errors.New("unable to parse INDEX_TOKEN_HOLDERS all"),
+		},
+		"token holders without index": {
+			Mode:         string(Online),
+			Network:      Mainnet,
+			Port:         "1000",
+			TokenHolders: "true",
+			err:          errors.New("INDEX_TOKEN_HOLDERS requires INDEX_PATH to be populated"),
+		},
+		"all set (mainnet) + trace start index": {
+			Mode:       string(Online),
+			Network:    Mainnet,
+			Port:       "1000",
+			TraceStart: "5000000",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				TraceStartIndex:        5000000,
+			},
+		},
+		"invalid trace start index": {
+			Mode:       string(Online),
+			Network:    Mainnet,
+			Port:       "1000",
+			TraceStart: "latest",
+			err:        errors.New("unable to parse TRACE_START_INDEX latest"),
+		},
+		"negative trace start index": {
+			Mode:       string(Online),
+			Network:    Mainnet,
+			Port:       "1000",
+			TraceStart: "-1",
+			err:        errors.New("TRACE_START_INDEX must not be negative"),
+		},
+		"all set (mainnet) + hedge delay": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			ArchiveURLs: "http://archive-1:8545",
+			HedgeDelay:  "100ms",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				ArchiveURLs:            []string{"http://archive-1:8545"},
+				HedgeDelay:             100 * time.Millisecond,
+			},
+		},
+		"invalid hedge delay": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			ArchiveURLs: "http://archive-1:8545",
+			HedgeDelay:  "0s",
+			err:         errors.New("unable to parse HEDGE_DELAY 0s"),
+		},
+		"hedge delay without archive nodes": {
+			Mode:       string(Online),
+			Network:    Mainnet,
+			Port:       "1000",
+			HedgeDelay: "100ms",
+			err:        errors.New("HEDGE_DELAY requires ARCHIVE_URLS to be populated"),
+		},
+		"invalid head events": {
+			Mode:       string(Online),
+			Network:    Mainnet,
+			Port:       "1000",
+			HeadEvents: "sometimes",
+			err:        errors.New("unable to parse ENABLE_HEAD_EVENTS sometimes"),
+		},
+		"all set (mainnet) + graphql batch size": {
+			Mode:         string(Online),
+			Network:      Mainnet,
+			Port:         "1000",
+			GraphQLBatch: "100",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				GraphQLBatchSize:       100,
+			},
+		},
+		"invalid graphql batch size": {
+			Mode:         string(Online),
+			Network:      Mainnet,
+			Port:         "1000",
+			GraphQLBatch: "-1",
+			err:          errors.New("unable to parse GRAPHQL_BATCH_SIZE -1"),
+		},
+		"all set (mainnet) + maintenance": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			Maintenance: "true",
+			Windows:     "2024-01-01T00:00:00Z/2024-01-01T01:00:00Z, 2024-02-01T12:00:00+08:00/2024-02-01T13:00:00+08:00",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				EnableAdminMaintenance: true,
+				MaintenanceWindows: []*MaintenanceWindow{
+					{
+						Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+						End:   time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+					},
+					{
+						Start: time.Date(2024, 2, 1, 12, 0, 0, 0, time.FixedZone("", 8*60*60)),
+						End:   time.Date(2024, 2, 1, 13, 0, 0, 0, time.FixedZone("", 8*60*60)),
+					},
+				},
+			},
+		},
+		"invalid admin maintenance": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			Maintenance: "sometimes",
+			err:         errors.New("unable to parse ENABLE_ADMIN_MAINTENANCE sometimes"),
+		},
+		"invalid maintenance window": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Port:    "1000",
+			Windows: "2024-01-01T00:00:00Z",
+			err:     errors.New("2024-01-01T00:00:00Z in MAINTENANCE_WINDOWS is not a <start>/<end> pair"),
+		},
+		"invalid maintenance window timestamp": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Port:    "1000",
+			Windows: "2024-01-01/2024-01-02",
+			err:     errors.New("unable to parse MAINTENANCE_WINDOWS 2024-01-01/2024-01-02"),
+		},
+		"maintenance window ending before it starts": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Port:    "1000",
+			Windows: "2024-01-01T01:00:00Z/2024-01-01T00:00:00Z",
+			err:     errors.New("2024-01-01T01:00:00Z/2024-01-01T00:00:00Z in MAINTENANCE_WINDOWS does not end after it starts"),
+		},
+		"invalid max request body size": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			MaxBodySize: "0",
+			err:         errors.New("unable to parse MAX_REQUEST_BODY_SIZE 0"),
+		},
+		"invalid request body size limit": {
+			Mode:           string(Online),
+			Network:        Mainnet,
+			Port:           "1000",
+			BodySizeLimits: "call=4096",
+			err:            errors.New("call=4096 in REQUEST_BODY_SIZE_LIMITS is not a <path>=<bytes> pair"),
+		},
+		"invalid strict json decoding": {
+			Mode:       string(Online),
+			Network:    Mainnet,
+			Port:       "1000",
+			StrictJSON: "strict",
+			err:        errors.New("unable to parse STRICT_JSON_DECODING strict"),
+		},
+		"invalid chain mismatch action": {
+			Mode:          string(Online),
+			Network:       Mainnet,
+			Port:          "1000",
+			ChainMismatch: "IGNORE",
+			err:           errors.New("IGNORE is not a valid chain mismatch action"),
+		},
+		"invalid upstream report": {
+			Mode:           string(Online),
+			Network:        Mainnet,
+			Port:           "1000",
+			UpstreamReport: "yes please",
+			err:            errors.New("unable to parse ENABLE_UPSTREAM_REPORT yes please"),
+		},
+		"invalid gas limit multiplier": {
+			Mode:          string(Online),
+			Network:       Mainnet,
+			Port:          "1000",
+			GasMultiplier: "0.9",
+			err:           errors.New("unable to parse GAS_LIMIT_MULTIPLIER 0.9"),
+		},
+		"invalid gas limit cap": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Port:    "1000",
+			GasCap:  "-1",
+			err:     errors.New("unable to parse GAS_LIMIT_CAP -1"),
+		},
+		"invalid gas limit type": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			GasDefaults: "swap=100000",
+			err:         errors.New("swap is not a valid gas limit type"),
+		},
+		"invalid gas limit default": {
+			Mode:        string(Online),
+			Network:     Mainnet,
+			Port:        "1000",
+			GasDefaults: "transfer",
+			err:         errors.New("transfer in GAS_LIMIT_DEFAULTS is not a <type>=<gas limit> pair"),
+		},
+		"invalid collapse operations": {
+			Mode:     string(Online),
+			Network:  Mainnet,
+			Port:     "1000",
+			Collapse: "sometimes",
+			err:      errors.New("unable to parse COLLAPSE_OPERATIONS sometimes"),
+		},
+		"invalid timestamp start index": {
+			Mode:           string(Online),
+			Network:        Mainnet,
+			Port:           "1000",
+			TimestampStart: "-1",
+			err:            errors.New("unable to parse TIMESTAMP_START_INDEX -1"),
+		},
+		"invalid disabled module": {
+			Mode:     string(Online),
+			Network:  Mainnet,
+			Port:     "1000",
+			Disabled: "account",
+			err:      errors.New("account is not a valid module"),
+		},
+		"all set (mainnet) + account summary": {
+			Mode:           string(Online),
+			Network:        Mainnet,
+			Port:           "1000",
+			Index:          "/data/index",
+			AccountSummary: "true",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				IndexPath:              "/data/index",
+				EnableAccountSummary:   true,
+			},
+		},
+		"account summary without index": {
+			Mode:           string(Online),
+			Network:        Mainnet,
+			Port:           "1000",
+			AccountSummary: "true",
+			err:            errors.New("ENABLE_ACCOUNT_SUMMARY requires INDEX_PATH to be populated"),
+		},
+		"all set (mainnet) + inline transaction limit": {
+			Mode:      string(Online),
+			Network:   Mainnet,
+			Port:      "1000",
+			InlineTxs: "500",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                  params.MainnetChainConfig,
+				GenesisBlockIdentifier:  ethereum.MainnetGenesisBlockIdentifier,
+				Port:                    1000,
+				GethURL:                 DefaultGethURL,
+				GethArguments:           ethereum.MainnetGethArguments,
+				ValidationMode:          PermissiveValidation,
+				ChainMismatchAction:     HaltOnChainMismatch,
+				BlockInlineTransactions: 500,
+			},
+		},
+		"all set (mainnet) + approval operations": {
+			Mode:      string(Online),
+			Network:   Mainnet,
+			Port:      "1000",
+			Approvals: "true",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                   params.MainnetChainConfig,
+				GenesisBlockIdentifier:   ethereum.MainnetGenesisBlockIdentifier,
+				Port:                     1000,
+				GethURL:                  DefaultGethURL,
+				GethArguments:            ethereum.MainnetGethArguments,
+				ValidationMode:           PermissiveValidation,
+				ChainMismatchAction:      HaltOnChainMismatch,
+				EnableApprovalOperations: true,
+			},
+		},
+		"all set (mainnet) + runtime config + admin reload": {
+			Mode:          string(Online),
+			Network:       Mainnet,
+			Port:          "1000",
+			InlineTxs:     "10",
+			RuntimeConfig: "testdata/runtime_config.json",
+			AdminReload:   "true",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                  params.MainnetChainConfig,
+				GenesisBlockIdentifier:  ethereum.MainnetGenesisBlockIdentifier,
+				Port:                    1000,
+				GethURL:                 DefaultGethURL,
+				GethArguments:           ethereum.MainnetGethArguments,
+				ValidationMode:          PermissiveValidation,
+				ChainMismatchAction:     HaltOnChainMismatch,
+				BlockInlineTransactions: 50,
+				RuntimeConfigPath:       "testdata/runtime_config.json",
+				EnableAdminReload:       true,
+				environment: &runtimeSettings{
+					blockInlineTransactions: 10,
+				},
+			},
+		},
+		"all set (mainnet) + watched addresses": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Port:    "1000",
+			Watched: "testdata/watched_addresses.json",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				WatchedAddresses: []common.Address{
+					common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"),
+					common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d"),
+				},
+			},
+		},
+		"all set (mainnet) + lag detection": {
+			Mode:          string(Online),
+			Network:       Mainnet,
+			Port:          "1000",
+			ReferenceURLs: "http://node-1:8545, https://rpc.coredao.org",
+			MaxNodeLag:    "20",
+			RejectStale:   "true",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				NodeLag: &ethereum.LagConfig{
+					References:       []string{"http://node-1:8545", "https://rpc.coredao.org"},
+					MaxLag:           20,
+					RejectStaleReads: true,
+				},
+			},
+		},
+		"all set (mainnet) + lag detection defaults": {
+			Mode:          string(Online),
+			Network:       Mainnet,
+			Port:          "1000",
+			ReferenceURLs: "http://node-1:8545",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				NodeLag: &ethereum.LagConfig{
+					References: []string{"http://node-1:8545"},
+					MaxLag:     ethereum.DefaultMaxLag,
+				},
+			},
+		},
+		"all set (mainnet) + index retention": {
+			Mode:      string(Online),
+			Network:   Mainnet,
+			Port:      "1000",
+			Index:     "/data/index",
+			Retention: "1000000",
+			Prefixes:  "0xE3a5, 57b",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				IndexPath:              "/data/index",
+				IndexRetention: &indexer.Retention{
+					Blocks:          1000000,
+					AddressPrefixes: []string{"e3a5", "57b"},
+				},
+			},
+		},
+		"all set (mainnet) + invariant checks": {
+			Mode:       string(Online),
+			Network:    Mainnet,
+			Port:       "1000",
+			Invariants: "true",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				EnableInvariantChecks:  true,
+			},
+		},
+		"all set (mainnet) + upstream proxy": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Port:    "1000",
+			Geth:    "wss://node.internal:8546",
+			Proxy:   "socks5://proxy.internal:1080",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                "wss://node.internal:8546",
+				RemoteGeth:             true,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				UpstreamProxy: &url.URL{
+					Scheme: "socks5",
+					Host:   "proxy.internal:1080",
+				},
+			},
+		},
+		"response cache": {
+			Mode:      string(Online),
+			Network:   Mainnet,
+			Port:      "1000",
+			CacheSize: "1000",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				ResponseCacheSize:      1000,
+				ResponseCacheTTL:       DefaultResponseCacheTTL,
+			},
+		},
+		"response cache with ttl": {
+			Mode:      string(Online),
+			Network:   Mainnet,
+			Port:      "1000",
+			CacheSize: "1000",
+			CacheTTL:  "500ms",
+			cfg: &Configuration{
+				Mode: Online,
+				Network: &types.NetworkIdentifier{
+					Network:    ethereum.MainnetNetwork,
+					Blockchain: ethereum.Blockchain,
+				},
+				Params:                 params.MainnetChainConfig,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				GethURL:                DefaultGethURL,
+				GethArguments:          ethereum.MainnetGethArguments,
+				ValidationMode:         PermissiveValidation,
+				ChainMismatchAction:    HaltOnChainMismatch,
+				ResponseCacheSize:      1000,
+				ResponseCacheTTL:       500 * time.Millisecond,
+			},
+		},
+		"invalid response cache size": {
+			Mode:      string(Online),
+			Network:   Mainnet,
+			Port:      "1000",
+			CacheSize: "-1",
+			err:       errors.New("unable to parse RESPONSE_CACHE_SIZE -1"),
+		},
+		"invalid response cache ttl": {
+			Mode:      string(Online),
+			Network:   Mainnet,
+			Port:      "1000",
+			CacheSize: "1000",
+			CacheTTL:  "soon",
+			err:       errors.New("unable to parse RESPONSE_CACHE_TTL soon"),
+		},
+		"invalid upstream proxy": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Port:    "1000",
+			Proxy:   "ftp://proxy.internal",
+			err:     errors.New("ftp is not a valid UPSTREAM_PROXY scheme"),
+		},
+		"invalid invariant checks": {
+			Mode:       string(Online),
+			Network:    Mainnet,
+			Port:       "1000",
+			Invariants: "sometimes",
+			err:        errors.New("unable to parse ENABLE_INVARIANT_CHECKS sometimes"),
+		},
+		"index retention without index": {
+			Mode:      string(Online),
+			Network:   Mainnet,
+			Port:      "1000",
+			Retention: "1000000",
+			err:       errors.New("INDEX_RETENTION_BLOCKS and INDEX_ADDRESS_PREFIXES require INDEX_PATH to be populated"),
+		},
+		"invalid index address prefix": {
+			Mode:     string(Online),
+			Network:  Mainnet,
+			Port:     "1000",
+			Index:    "/data/index",
+			Prefixes: "0xzz",
+			err:      errors.New("unable to parse INDEX_ADDRESS_PREFIXES 0xzz"),
+		},
+		"invalid max node lag": {
+			Mode:          string(Online),
+			Network:       Mainnet,
+			Port:          "1000",
+			ReferenceURLs: "http://node-1:8545",
+			MaxNodeLag:    "-1",
+			err:           errors.New("unable to parse MAX_NODE_LAG -1"),
+		},
+		"invalid watched address": {
+			Mode:    string(Online),
+			Network: Mainnet,
+			Port:    "1000",
+			Watched: "testdata/watched_addresses_invalid.json",
+			err:     errors.New("0x1234 in testdata/watched_addresses_invalid.json is not a valid address"),
+		},
+		"missing runtime config": {
+			Mode:          string(Online),
+			Network:       Mainnet,
+			Port:          "1000",
+			RuntimeConfig: "testdata/missing.json",
+			err:           errors.New("unable to load RUNTIME_CONFIG testdata/missing.json"),
+		},
+		"negative runtime inline transaction limit": {
+			Mode:          string(Online),
+			Network:       Mainnet,
+			Port:          "1000",
+			RuntimeConfig: "testdata/runtime_config_negative.json",
+			err:           errors.New("block_inline_transactions in testdata/runtime_config_negative.json must not be negative"),
+		},
+		"invalid inline transaction limit": {
+			Mode:      string(Online),
+			Network:   Mainnet,
+			Port:      "1000",
+			InlineTxs: "-1",
+			err:       errors.New("unable to parse BLOCK_INLINE_TRANSACTIONS -1"),
+		},
+		"missing log redaction config": {
+			Mode:         string(Online),
+			Network:      Mainnet,
+			Port:         "1000",
+			LogRedaction: "testdata/missing.json",
+			err:          errors.New("unable to load LOG_REDACTION_CONFIG testdata/missing.json"),
+		},
+		"invalid validation mode": {
+			Mode:           string(Online),
+			Network:        Mainnet,
+			Port:           "1000",
+			ValidationMode: "bad mode",
+			err:            errors.New("bad mode is not a valid validation mode"),
+		},
 		"invalid mode": {
 			Mode:    "bad mode",
 			Network: Ropsten,
@@ -186,11 +2288,95 @@ func TestLoadConfiguration(t *testing.T) {
 			os.Setenv(PortEnv, test.Port)
 			os.Setenv(GethEnv, test.Geth)
 			os.Setenv(SkipGethAdminEnv, test.SkipGethAdmin)
+			os.Setenv(ValidationModeEnv, test.ValidationMode)
+			os.Setenv(MetricsEnv, test.Metrics)
+			os.Setenv(LogRedactionEnv, test.LogRedaction)
+			os.Setenv(NonceTrackerEnv, test.NonceTracker)
+			os.Setenv(IndexEnv, test.Index)
+			os.Setenv(AccountSummaryEnv, test.AccountSummary)
+			os.Setenv(BlockInlineTransactionsEnv, test.InlineTxs)
+			os.Setenv(ApprovalOperationsEnv, test.Approvals)
+			os.Setenv(RuntimeConfigEnv, test.RuntimeConfig)
+			os.Setenv(AdminReloadEnv, test.AdminReload)
+			os.Setenv(WatchedAddressesEnv, test.Watched)
+			os.Setenv(ReferenceURLsEnv, test.ReferenceURLs)
+			os.Setenv(MaxNodeLagEnv, test.MaxNodeLag)
+			os.Setenv(RejectStaleReadsEnv, test.RejectStale)
+			os.Setenv(IndexRetentionBlocksEnv, test.Retention)
+			os.Setenv(IndexAddressPrefixesEnv, test.Prefixes)
+			os.Setenv(InvariantChecksEnv, test.Invariants)
+			os.Setenv(UpstreamProxyEnv, test.Proxy)
+			os.Setenv(ResponseCacheSizeEnv, test.CacheSize)
+			os.Setenv(ResponseCacheTTLEnv, test.CacheTTL)
+			os.Setenv(AuditLogEnv, test.AuditLog)
+			os.Setenv(AuditLogKeyEnv, test.AuditLogKey)
+			os.Setenv(DisabledModulesEnv, test.Disabled)
+			os.Setenv(TimestampStartIndexEnv, test.TimestampStart)
+			os.Setenv(CollapseOperationsEnv, test.Collapse)
+			os.Setenv(StakedBalancesEnv, test.StakedBalances)
+			os.Setenv(GasLimitMultiplierEnv, test.GasMultiplier)
+			os.Setenv(GasLimitCapEnv, test.GasCap)
+			os.Setenv(GasLimitDefaultsEnv, test.GasDefaults)
+			os.Setenv(UpstreamReportEnv, test.UpstreamReport)
+			os.Setenv(ChainMismatchEnv, test.ChainMismatch)
+			os.Setenv(MaxRequestBodySizeEnv, test.MaxBodySize)
+			os.Setenv(RequestBodySizeLimitsEnv, test.BodySizeLimits)
+			os.Setenv(StrictJSONDecodingEnv, test.StrictJSON)
+			os.Setenv(ArchiveURLsEnv, test.ArchiveURLs)
+			os.Setenv(NonceMonitorAddressesEnv, test.NonceAddresses)
+			os.Setenv(NonceStuckAfterEnv, test.NonceStuck)
+			os.Setenv(CustomTracerEnv, test.CustomTracer)
+			os.Setenv(CustomTracerProcessorEnv, test.TraceProcessor)
+			os.Setenv(LabelsPathEnv, test.Labels)
+			os.Setenv(MulticallContractEnv, test.Multicall)
+			os.Setenv(BlockTransformersEnv, test.Transformers)
+			os.Setenv(BalanceCacheSizeEnv, test.BalanceCache)
+			os.Setenv(BlockArchiveURLEnv, test.ArchiveURL)
+			os.Setenv(BlockArchiveModeEnv, test.ArchiveMode)
+			os.Setenv(HeadEventsEnv, test.HeadEvents)
+			os.Setenv(GraphQLBatchSizeEnv, test.GraphQLBatch)
+			os.Setenv(AdminMaintenanceEnv, test.Maintenance)
+			os.Setenv(MaintenanceWindowsEnv, test.Windows)
+			os.Setenv(BlockEventsHistoryEnv, test.EventsHistory)
+			os.Setenv(RewardRecipientEnv, test.RewardTo)
+			os.Setenv(SubmitQueueEnv, test.SubmitQueue)
+			os.Setenv(TxPoolMetricsEnv, test.TxPoolMetrics)
+			os.Setenv(HTTPReadTimeoutEnv, test.ReadTimeout)
+			os.Setenv(HTTPWriteTimeoutEnv, test.WriteTimeout)
+			os.Setenv(HTTPIdleTimeoutEnv, test.IdleTimeout)
+			os.Setenv(HTTPMaxHeaderBytesEnv, test.MaxHeaders)
+			os.Setenv(HTTPKeepAliveEnv, test.KeepAlive)
+			os.Setenv(ListenAddressesEnv, test.Listen)
+			os.Setenv(AdminListenAddressEnv, test.AdminListen)
+			os.Setenv(ZeroValueOperationsEnv, test.ZeroValueOps)
+			os.Setenv(ABIRegistryEnv, test.ABIRegistry)
+			os.Setenv(ABIPathEnv, test.ABIPath)
+			os.Setenv(FourByteURLEnv, test.FourByteURL)
+			os.Setenv(UserOperationsEnv, test.UserOps)
+			os.Setenv(EntryPointContractsEnv, test.EntryPoints)
+			os.Setenv(AdminDebugUIEnv, test.DebugUI)
+			os.Setenv(CanonicalJSONEnv, test.CanonicalJSON)
+			os.Setenv(DustThresholdEnv, test.DustThreshold)
+			os.Setenv(MetadataCacheTTLEnv, test.MetadataTTL)
+			os.Setenv(RawRLPMetadataEnv, test.RawRLP)
+			os.Setenv(PublicModeEnv, test.PublicMode)
+			os.Setenv(RateLimitEnv, test.RateLimit)
+			os.Setenv(TLSCertFileEnv, test.TLSCert)
+			os.Setenv(TLSKeyFileEnv, test.TLSKey)
+			os.Setenv(ConstructionClientCAFileEnv, test.ClientCA)
+			os.Setenv(ConstructionAllowedIPsEnv, test.AllowedIPs)
+			os.Setenv(WatermarkEnv, test.Watermark)
+			os.Setenv(IndexTokenHoldersEnv, test.TokenHolders)
+			os.Setenv(TraceStartIndexEnv, test.TraceStart)
+			os.Setenv(HedgeDelayEnv, test.HedgeDelay)
+			os.Setenv(ConsensusMetadataEnv, test.Consensus)
+			os.Setenv(HDDerivationEnv, test.HDDerivation)
 
 			cfg, err := LoadConfiguration()
 			if test.err != nil {
 				assert.Nil(t, cfg)
 				assert.Contains(t, err.Error(), test.err.Error())
+				assert.NotContains(t, err.Error(), "%!w")
 			} else {
 				assert.Equal(t, test.cfg, cfg)
 				assert.NoError(t, err)
@@ -198,3 +2384,130 @@ func TestLoadConfiguration(t *testing.T) {
 		})
 	}
 }
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configuration")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	runtimeConfig := filepath.Join(dir, "runtime_config.json")
+	assert.NoError(t, ioutil.WriteFile(runtimeConfig, []byte(`{"block_inline_transactions": 10}`), 0600))
+	logRedaction := filepath.Join(dir, "log_redaction.json")
+	assert.NoError(t, ioutil.WriteFile(logRedaction, []byte(`{}`), 0600))
+
+	os.Clearenv()
+	os.Setenv(ModeEnv, string(Online))
+	os.Setenv(NetworkEnv, Mainnet)
+	os.Setenv(PortEnv, "1000")
+	os.Setenv(RateLimitEnv, "5")
+	os.Setenv(RuntimeConfigEnv, runtimeConfig)
+	os.Setenv(LogRedactionEnv, logRedaction)
+
+	cfg, err := LoadConfiguration()
+	assert.NoError(t, err)
+	assert.Equal(t, 10, cfg.InlineTransactions())
+	assert.Equal(t, 5, cfg.ClientRateLimit())
+	assert.Empty(t, cfg.LogRedactionConfig().Fields)
+
+	// Reloadable settings are applied from the files
+	assert.NoError(t, ioutil.WriteFile(runtimeConfig, []byte(`{
+		"block_inline_transactions": 20,
+		"rate_limit": 50,
+		"response_cache_size": 100
+	}`), 0600))
+	assert.NoError(t, ioutil.WriteFile(logRedaction, []byte(`{"fields": ["mnemonic"]}`), 0600))
+	assert.NoError(t, cfg.Reload())
+	assert.Equal(t, 20, cfg.InlineTransactions())
+	assert.Equal(t, 50, cfg.ClientRateLimit())
+	size, ttl := cfg.ResponseCacheConfig()
+	assert.Equal(t, 100, size)
+	assert.Equal(t, DefaultResponseCacheTTL, ttl)
+	assert.Equal(t, []string{"mnemonic"}, cfg.LogRedactionConfig().Fields)
+
+	// Settings removed from the file are restored to their
+	// environment variable, which is not re-read
+	assert.NoError(t, ioutil.WriteFile(runtimeConfig, []byte(`{"response_cache_ttl": "1m"}`), 0600))
+	os.Setenv(RateLimitEnv, "7")
+	os.Setenv(PortEnv, "2000")
+	assert.NoError(t, cfg.Reload())
+	assert.Equal(t, 0, cfg.InlineTransactions())
+	assert.Equal(t, 5, cfg.ClientRateLimit())
+	size, ttl = cfg.ResponseCacheConfig()
+	assert.Equal(t, 0, size)
+	assert.Equal(t, time.Minute, ttl)
+
+	// Other settings are not reloaded
+	assert.Equal(t, 1000, cfg.Port)
+
+	// Invalid configurations are not applied
+	assert.NoError(t, ioutil.WriteFile(runtimeConfig, []byte(`{"block_inline_transactions": 20}`), 0600))
+	assert.NoError(t, cfg.Reload())
+	for _, invalid := range []string{
+		`{"block_inline_transactions": -1}`,
+		`{"rate_limit": 0}`,
+		`{"response_cache_size": -1}`,
+		`{"response_cache_ttl": "soon"}`,
+	} {
+		assert.NoError(t, ioutil.WriteFile(runtimeConfig, []byte(invalid), 0600))
+		assert.Error(t, cfg.Reload())
+		assert.Equal(t, 20, cfg.InlineTransactions())
+		assert.Equal(t, 5, cfg.ClientRateLimit())
+	}
+}
+
+func TestGasLimits(t *testing.T) {
+	var unset *GasLimits
+	assert.Equal(t, uint64(ethereum.TransferGasLimit), unset.Default(TransferGasLimit))
+	assert.Equal(t, uint64(ethereum.DelegateGasLimit), unset.Default(DelegateGasLimit))
+	limit, err := unset.Estimate(50000)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(50000), limit)
+
+	gasLimits := &GasLimits{
+		Multiplier: 1.25,
+		Cap:        100000,
+		Defaults: map[GasLimitType]uint64{
+			DelegateGasLimit: 350000,
+		},
+	}
+	assert.Equal(t, uint64(ethereum.TransferGasLimit), gasLimits.Default(TransferGasLimit))
+	assert.Equal(t, uint64(350000), gasLimits.Default(DelegateGasLimit))
+	limit, err = gasLimits.Estimate(50000)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(62500), limit)
+	limit, err = gasLimits.Estimate(21001)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(26252), limit)
+	limit, err = gasLimits.Estimate(80000)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(100000), limit)
+	_, err = gasLimits.Estimate(90000)
+	assert.True(t, errors.Is(err, ErrGasLimitCapExceeded))
+}
+
+func TestRequestBodySizeLimit(t *testing.T) {
+	cfg := &Configuration{}
+	assert.Equal(t, DefaultMaxRequestBodySize, cfg.RequestBodySizeLimit("/block"))
+
+	cfg = &Configuration{
+		MaxRequestBodySize: 4096,
+		RequestBodySizeLimits: map[string]int64{
+			"/construction/combine": 65536,
+		},
+	}
+	assert.Equal(t, int64(4096), cfg.RequestBodySizeLimit("/block"))
+	assert.Equal(t, int64(65536), cfg.RequestBodySizeLimit("/construction/combine"))
+}
+
+func TestCallMethods(t *testing.T) {
+	cfg := &Configuration{}
+	assert.Equal(t, ethereum.CallMethods, cfg.CallMethods())
+
+	cfg = &Configuration{PublicMode: true}
+	methods := cfg.CallMethods()
+	assert.Len(t, methods, len(ethereum.CallMethods)-len(ethereum.PassthroughCallMethods))
+	assert.Contains(t, methods, ethereum.ValidatorSetMethod)
+	for _, method := range ethereum.PassthroughCallMethods {
+		assert.NotContains(t, methods, method)
+	}
+}
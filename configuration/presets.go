@@ -0,0 +1,227 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configuration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	"github.com/coinbase/rosetta-ethereum/ethereum/networks"
+
+	"github.com/coinbase/rosetta-sdk-go/asserter"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// builtinPreset is what a preset of a built-in network
+// adds to the parameters of the network (see networks).
+type builtinPreset struct {
+	name          string
+	gethArguments string
+	gethURL       string
+}
+
+// builtinPresets are the presets of the built-in
+// networks, keyed by networks.Network.Name.
+var builtinPresets = map[string]*builtinPreset{
+	networks.CoreNetwork: {
+		name:          Core,
+		gethArguments: ethereum.CoreGethArguments,
+	},
+	networks.BuffaloNetwork: {
+		name:          Buffalo,
+		gethArguments: ethereum.BuffaloGethArguments,
+		gethURL:       "http://localhost:8575",
+	},
+	networks.DevNetwork: {
+		name:          Devnet,
+		gethArguments: ethereum.DevGethArguments,
+	},
+}
+
+// NetworkPreset describes a network that rosetta-core can be run
+// against without code changes. The presets of the Corechain
+// networks are derived from the networks package and additional
+// presets can be loaded from NetworkPresetsEnv.
+type NetworkPreset struct {
+	// Name is the value of NetworkEnv that selects the preset.
+	Name string `json:"name"`
+
+	// Network is the network of the *types.NetworkIdentifier.
+	Network string `json:"network"`
+
+	GenesisBlockIdentifier *types.BlockIdentifier `json:"genesis_block_identifier"`
+	ChainConfig            *params.ChainConfig    `json:"chain_config"`
+
+	// Hardforks are the activation heights of the hardforks of
+	// the network, keyed by the name of the hardfork in the chain
+	// config of the node without the "Block" suffix (i.e.
+	// "hashPower"). They are reported in /network/options and
+	// checked against the hardforks this binary supports.
+	Hardforks map[string]uint64 `json:"hardforks,omitempty"`
+
+	GethArguments string `json:"geth_arguments"`
+
+	// GethURL is the URL of the node used when GethEnv is not
+	// populated. When empty, DefaultGethURL is used.
+	GethURL string `json:"geth_url,omitempty"`
+
+	// SystemContracts are the system contract addresses of the
+	// network, keyed by contract name (i.e. "PledgeAgent").
+	SystemContracts map[string]common.Address `json:"system_contracts,omitempty"`
+
+	// Tokens are well-known ERC-20 tokens of the network.
+	Tokens []*networks.Token `json:"tokens,omitempty"`
+}
+
+// networkPreset returns the preset of a built-in network.
+func networkPreset(network *networks.Network, builtin *builtinPreset) *NetworkPreset {
+	hardforks := make(map[string]uint64, len(network.Hardforks))
+	for name, height := range network.Hardforks {
+		hardforks[name] = height
+	}
+
+	systemContracts := make(map[string]common.Address, len(network.SystemContracts))
+	for name, address := range network.SystemContracts {
+		systemContracts[name] = address
+	}
+
+	preset := &NetworkPreset{
+		Name:    builtin.name,
+		Network: network.Name,
+		GenesisBlockIdentifier: &types.BlockIdentifier{
+			Index: 0,
+			Hash:  network.GenesisHash.Hex(),
+		},
+		ChainConfig: &params.ChainConfig{
+			ChainID: new(big.Int).Set(network.ChainID),
+		},
+		GethArguments:   builtin.gethArguments,
+		GethURL:         builtin.gethURL,
+		SystemContracts: systemContracts,
+		Tokens:          network.Tokens,
+	}
+	if len(hardforks) > 0 {
+		preset.Hardforks = hardforks
+	}
+
+	return preset
+}
+
+// validate ensures the preset is complete.
+func (p *NetworkPreset) validate() error {
+	if len(p.Name) == 0 {
+		return errors.New("name must be populated")
+	}
+
+	if len(p.Network) == 0 {
+		return errors.New("network must be populated")
+	}
+
+	if err := asserter.BlockIdentifier(p.GenesisBlockIdentifier); err != nil {
+		return fmt.Errorf("%w: invalid genesis_block_identifier", err)
+	}
+
+	if p.ChainConfig == nil || p.ChainConfig.ChainID == nil {
+		return errors.New("chain_config.chainId must be populated")
+	}
+
+	return nil
+}
+
+// LoadNetworkPresets returns the presets of the built-in networks,
+// keyed by name. If dir is not empty, every .json file in dir is
+// loaded as a preset, replacing any built-in preset with the same
+// name.
+func LoadNetworkPresets(dir string) (map[string]*NetworkPreset, error) {
+	presets := map[string]*NetworkPreset{}
+	for _, network := range networks.All() {
+		builtin, ok := builtinPresets[network.Name]
+		if !ok {
+			return nil, fmt.Errorf("network %s has no preset", network.Name)
+		}
+
+		presets[builtin.name] = networkPreset(network, builtin)
+	}
+
+	if len(dir) == 0 {
+		return presets, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		content, err := ioutil.ReadFile(filepath.Clean(file))
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to read network preset %s", err, file)
+		}
+
+		if err := addPreset(presets, file, content); err != nil {
+			return nil, err
+		}
+	}
+
+	return presets, nil
+}
+
+// MatchNetworkPreset returns the name of the preset with the
+// provided chain ID and genesis block hash, or an empty string
+// if there is none. It is used to suggest the NetworkEnv of a
+// node that is on a different chain than configured.
+func MatchNetworkPreset(
+	presets map[string]*NetworkPreset,
+	chainID *big.Int,
+	genesisHash string,
+) string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		preset := presets[name]
+		if preset.ChainConfig.ChainID.Cmp(chainID) == 0 &&
+			strings.EqualFold(preset.GenesisBlockIdentifier.Hash, genesisHash) {
+			return name
+		}
+	}
+
+	return ""
+}
+
+func addPreset(presets map[string]*NetworkPreset, file string, content []byte) error {
+	var preset NetworkPreset
+	if err := json.Unmarshal(content, &preset); err != nil {
+		return fmt.Errorf("%w: unable to parse network preset %s", err, file)
+	}
+
+	if err := preset.validate(); err != nil {
+		return fmt.Errorf("%w: invalid network preset %s", err, file)
+	}
+
+	presets[preset.Name] = &preset
+	return nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configuration
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	"github.com/coinbase/rosetta-ethereum/ethereum/networks"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadNetworkPresets(t *testing.T) {
+	presets, err := LoadNetworkPresets("")
+	assert.NoError(t, err)
+	assert.Len(t, presets, 3)
+
+	// The presets of the built-in networks match
+	// their parameters
+	for name, expected := range map[string]*NetworkPreset{
+		Core: {
+			Name:                   Core,
+			Network:                ethereum.CoreNetwork,
+			GenesisBlockIdentifier: ethereum.CoreGenesisBlockIdentifier,
+			ChainConfig:            ethereum.CoreChainConfig,
+			Hardforks:              ethereum.CoreHardforks,
+			GethArguments:          ethereum.CoreGethArguments,
+			SystemContracts:        ethereum.SystemContracts(),
+			Tokens:                 networks.Core.Tokens,
+		},
+		Buffalo: {
+			Name:                   Buffalo,
+			Network:                ethereum.BuffaloNetwork,
+			GenesisBlockIdentifier: ethereum.BuffaloGenesisBlockIdentifier,
+			ChainConfig:            ethereum.BuffaloChainConfig,
+			Hardforks:              ethereum.BuffaloHardforks,
+			GethArguments:          ethereum.BuffaloGethArguments,
+			GethURL:                "http://localhost:8575",
+			SystemContracts:        ethereum.SystemContracts(),
+		},
+		Devnet: {
+			Name:                   Devnet,
+			Network:                ethereum.DevNetwork,
+			GenesisBlockIdentifier: ethereum.DevGenesisBlockIdentifier,
+			ChainConfig:            ethereum.DevChainConfig,
+			GethArguments:          ethereum.DevGethArguments,
+			SystemContracts:        ethereum.SystemContracts(),
+		},
+	} {
+		assert.Equal(t, expected, presets[name], name)
+	}
+
+	dir, err := ioutil.TempDir("", "presets")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Presets are copies, so changing a loaded
+	// preset does not change the network
+	presets[Core].Hardforks["hashPower"] = 1
+	presets[Core].ChainConfig.ChainID.SetInt64(1)
+	assert.Equal(t, uint64(0), networks.Core.Hardforks["hashPower"])
+	assert.Equal(t, big.NewInt(1116), networks.Core.ChainID)
+
+	// External presets add networks and replace built-in ones
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "pigeon.json"), []byte(`{
+		"name": "PIGEON",
+		"network": "Pigeon",
+		"genesis_block_identifier": {"index": 0, "hash": "0x01"},
+		"chain_config": {"chainId": 1114},
+		"geth_arguments": "--graphql",
+		"tokens": [
+			{"symbol": "WCORE", "decimals": 18, "address": "0x1111111111111111111111111111111111111111"}
+		]
+	}`), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "buffalo.json"), []byte(`{
+		"name": "BUFFALO",
+		"network": "Buffalo",
+		"genesis_block_identifier": {"index": 0, "hash": "0x02"},
+		"chain_config": {"chainId": 1115}
+	}`), 0600))
+
+	presets, err = LoadNetworkPresets(dir)
+	assert.NoError(t, err)
+	assert.Len(t, presets, 4)
+	assert.Equal(t, big.NewInt(1114), presets["PIGEON"].ChainConfig.ChainID)
+	assert.Equal(t, "0x02", presets[Buffalo].GenesisBlockIdentifier.Hash)
+
+	os.Clearenv()
+	os.Setenv(ModeEnv, string(Online))
+	os.Setenv(NetworkEnv, "PIGEON")
+	os.Setenv(PortEnv, "1000")
+	os.Setenv(NetworkPresetsEnv, dir)
+	cfg, err := LoadConfiguration()
+	assert.NoError(t, err)
+	assert.Equal(t, &types.NetworkIdentifier{
+		Blockchain: ethereum.Blockchain,
+		Network:    "Pigeon",
+	}, cfg.Network)
+	assert.Equal(t, "--graphql", cfg.GethArguments)
+	assert.Equal(t, DefaultGethURL, cfg.GethURL)
+	assert.Equal(t, []*networks.Token{
+		{
+			Symbol:   "WCORE",
+			Decimals: 18,
+			Address:  common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		},
+	}, cfg.Tokens)
+	os.Clearenv()
+
+	// Invalid presets are rejected
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "invalid.json"), []byte(`{
+		"name": "INVALID",
+		"network": "Invalid",
+		"genesis_block_identifier": {"index": 0, "hash": "0x03"}
+	}`), 0600))
+	presets, err = LoadNetworkPresets(dir)
+	assert.Nil(t, presets)
+	assert.Contains(t, err.Error(), "chain_config.chainId must be populated")
+}
+
+func TestMatchNetworkPreset(t *testing.T) {
+	presets, err := LoadNetworkPresets("")
+	assert.NoError(t, err)
+
+	assert.Equal(t, Buffalo, MatchNetworkPreset(
+		presets,
+		ethereum.BuffaloChainConfig.ChainID,
+		ethereum.BuffaloGenesisBlockIdentifier.Hash,
+	))
+	assert.Equal(t, Core, MatchNetworkPreset(
+		presets,
+		ethereum.CoreChainConfig.ChainID,
+		ethereum.CoreGenesisBlockIdentifier.Hash,
+	))
+
+	// Both the chain ID and the genesis block must match
+	assert.Equal(t, "", MatchNetworkPreset(
+		presets,
+		ethereum.CoreChainConfig.ChainID,
+		ethereum.BuffaloGenesisBlockIdentifier.Hash,
+	))
+	assert.Equal(t, "", MatchNetworkPreset(presets, big.NewInt(1), ethereum.CoreGenesisBlockIdentifier.Hash))
+}
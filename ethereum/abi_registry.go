@@ -0,0 +1,526 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	// DefaultFourByteURL is the signature lookup endpoint
+	// of the Ethereum Signature Database.
+	DefaultFourByteURL = "https://www.4byte.directory/api/v1/signatures/"
+
+	// MethodMetadataKey is the transaction and operation metadata
+	// key populated with the decoded method of a call.
+	MethodMetadataKey = "method"
+
+	// RegistryMethodSource and FourByteMethodSource are
+	// the sources of decoded methods.
+	RegistryMethodSource = "registry"
+	FourByteMethodSource = "4byte"
+
+	// selectorLength is the length of a method selector.
+	selectorLength = 4
+
+	// fourByteCacheSize is the maximum number of selectors
+	// resolved with the lookup endpoint that are cached.
+	fourByteCacheSize = 10000
+
+	// fourByteQueueSize is the maximum number of selectors
+	// waiting to be resolved with the lookup endpoint. Any
+	// selector not found while the queue is full is
+	// retried the next time it is seen.
+	fourByteQueueSize = 1000
+
+	// fourByteTimeout is the timeout of a request
+	// to the lookup endpoint.
+	fourByteTimeout = 10 * time.Second
+
+	// fourByteRetryInterval is how long a selector is not looked
+	// up again after the lookup endpoint could not be reached.
+	fourByteRetryInterval = time.Minute
+)
+
+// commonABI contains the methods of commonly used contracts
+// (ERC-20 tokens, WCORE, and the system contracts) that
+// seed the ABI registry.
+const commonABI = `[
+	{"type":"function","name":"transfer","stateMutability":"nonpayable","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"transferFrom","stateMutability":"nonpayable","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"approve","stateMutability":"nonpayable","inputs":[{"name":"spender","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"deposit","stateMutability":"payable","inputs":[],"outputs":[]},
+	{"type":"function","name":"withdraw","stateMutability":"nonpayable","inputs":[{"name":"wad","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"undelegateCoin","stateMutability":"nonpayable","inputs":[{"name":"agent","type":"address"}],"outputs":[]},
+	{"type":"function","name":"undelegateCoin","stateMutability":"nonpayable","inputs":[{"name":"agent","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"transferCoin","stateMutability":"nonpayable","inputs":[{"name":"sourceAgent","type":"address"},{"name":"targetAgent","type":"address"}],"outputs":[]},
+	{"type":"function","name":"transferCoin","stateMutability":"nonpayable","inputs":[{"name":"sourceAgent","type":"address"},{"name":"targetAgent","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"claimReward","stateMutability":"nonpayable","inputs":[{"name":"agentList","type":"address[]"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"deposit","stateMutability":"payable","inputs":[{"name":"valAddr","type":"address"}],"outputs":[]},
+	{"type":"function","name":"distributeReward","stateMutability":"nonpayable","inputs":[],"outputs":[]},
+	{"type":"function","name":"slash","stateMutability":"nonpayable","inputs":[{"name":"validator","type":"address"}],"outputs":[]}
+]`
+
+// seedABIs are the ABIs every ABI registry is seeded with.
+var seedABIs = []abi.ABI{
+	mustParseABI(commonABI),
+	systemABI,
+	pledgeAgent,
+	stakingContracts,
+	safeABI,
+	multicallABI,
+}
+
+// ABIRegistryConfig configures the decoding of the
+// methods called by transactions (see ABIRegistry).
+type ABIRegistryConfig struct {
+	// ABIs are registered in addition to the
+	// system contracts and common token methods.
+	ABIs []abi.ABI
+
+	// LookupURL is the endpoint of a 4byte directory used
+	// to resolve selectors that are not registered. If it
+	// is empty, selectors are not looked up.
+	LookupURL string
+}
+
+// LoadABIs loads the contract ABIs in the JSON file at path.
+// If path is a directory, the ABIs in all of its .json
+// files are loaded.
+func LoadABIs(path string) ([]abi.ABI, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not load abi %s", err, path)
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: could not list abi directory %s", err, path)
+		}
+	}
+
+	abis := make([]abi.ABI, len(files))
+	for i, file := range files {
+		contents, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%w: could not load abi %s", err, file)
+		}
+
+		parsed, err := abi.JSON(strings.NewReader(string(contents)))
+		if err != nil {
+			return nil, fmt.Errorf("%w: could not parse abi %s", err, file)
+		}
+		abis[i] = parsed
+	}
+
+	return abis, nil
+}
+
+// DecodedMethod is a call decoded with the ABI registry.
+type DecodedMethod struct {
+	Selector  string
+	Name      string
+	Signature string
+	Source    string
+	Arguments []*DecodedArgument
+}
+
+// DecodedArgument is an argument of a DecodedMethod.
+// Its value is JSON-friendly: addresses, integers, and
+// byte arrays are encoded as strings.
+type DecodedArgument struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+func (m *DecodedMethod) metadata() map[string]interface{} {
+	metadata := map[string]interface{}{
+		"selector":  m.Selector,
+		"name":      m.Name,
+		"signature": m.Signature,
+		"source":    m.Source,
+	}
+	if m.Arguments != nil {
+		metadata["arguments"] = m.Arguments
+	}
+
+	return metadata
+}
+
+// abiRegistry decodes calldata with the registered methods
+// and, if configured, with the signatures of a 4byte directory.
+//
+// Blocks are only decoded with the registered methods, so
+// they are converted the same way whenever they are fetched
+// (and their digests, caches, and archives stay stable).
+// Signatures are only used by the decode_transaction call
+// method: selectors are looked up in the background (see
+// ResolveSelectors) so a call never waits on the lookup
+// endpoint, and a selector that is not resolved yet is
+// not decoded.
+type abiRegistry struct {
+	methods map[[selectorLength]byte]*abi.Method
+
+	lookupURL string
+	client    *http.Client
+	queue     chan [selectorLength]byte
+
+	mutex    sync.Mutex
+	resolved map[[selectorLength]byte]*abi.Method // nil if not found
+	queued   map[[selectorLength]byte]time.Time
+}
+
+// newABIRegistry returns an abiRegistry seeded with seedABIs and
+// the ABIs in config. If config is nil, nil is returned (methods
+// are not decoded).
+func newABIRegistry(config *ABIRegistryConfig, proxy *url.URL) *abiRegistry {
+	if config == nil {
+		return nil
+	}
+
+	r := &abiRegistry{
+		methods:   map[[selectorLength]byte]*abi.Method{},
+		lookupURL: config.LookupURL,
+		client: &http.Client{
+			Timeout:   fourByteTimeout,
+			Transport: newTransport(proxy),
+		},
+		queue:    make(chan [selectorLength]byte, fourByteQueueSize),
+		resolved: map[[selectorLength]byte]*abi.Method{},
+		queued:   map[[selectorLength]byte]time.Time{},
+	}
+	for _, contract := range append(seedABIs, config.ABIs...) {
+		for _, method := range contract.Methods {
+			method := method
+
+			var selector [selectorLength]byte
+			copy(selector[:], method.ID)
+			r.methods[selector] = &method
+		}
+	}
+
+	return r
+}
+
+// method returns the method with selector and its source. If
+// the selector is not registered and lookup is true, it is
+// resolved with the 4byte directory (or queued to be).
+func (r *abiRegistry) method(selector [selectorLength]byte, lookup bool) (*abi.Method, string, bool) {
+	if method, ok := r.methods[selector]; ok {
+		return method, RegistryMethodSource, true
+	}
+
+	if !lookup || len(r.lookupURL) == 0 {
+		return nil, "", false
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if method, ok := r.resolved[selector]; ok {
+		return method, FourByteMethodSource, method != nil
+	}
+
+	if _, ok := r.queued[selector]; ok {
+		return nil, "", false
+	}
+
+	select {
+	case r.queue <- selector:
+		r.queued[selector] = time.Time{}
+	default:
+	}
+
+	return nil, "", false
+}
+
+// decode decodes data with the method of its selector (see
+// method). Arguments that cannot be unpacked are omitted.
+func (r *abiRegistry) decode(data []byte, lookup bool) (*DecodedMethod, bool) {
+	if r == nil || len(data) < selectorLength {
+		return nil, false
+	}
+
+	var selector [selectorLength]byte
+	copy(selector[:], data)
+	method, source, ok := r.method(selector, lookup)
+	if !ok {
+		return nil, false
+	}
+
+	decoded := &DecodedMethod{
+		Selector:  hexutil.Encode(selector[:]),
+		Name:      method.RawName,
+		Signature: method.Sig,
+		Source:    source,
+	}
+
+	values, err := method.Inputs.Unpack(data[selectorLength:])
+	if err != nil {
+		return decoded, true
+	}
+
+	decoded.Arguments = make([]*DecodedArgument, len(values))
+	for i, value := range values {
+		decoded.Arguments[i] = &DecodedArgument{
+			Name:  method.Inputs[i].Name,
+			Type:  method.Inputs[i].Type.String(),
+			Value: argumentValue(value),
+		}
+	}
+
+	return decoded, true
+}
+
+// decodeCalls walks a trace and annotates every call
+// with the registered method it executes.
+func (r *abiRegistry) decodeCalls(call *Call) {
+	if r == nil || call == nil {
+		return
+	}
+
+	if call.Type == CallOpType {
+		if method, ok := r.decode(call.Input, false); ok {
+			call.method = method
+		}
+	}
+
+	for _, child := range call.Calls {
+		r.decodeCalls(child)
+	}
+}
+
+// argumentValue returns the JSON-friendly
+// representation of an unpacked argument.
+func argumentValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case common.Address:
+		return MustChecksum(v.Hex())
+	case *big.Int:
+		return v.String()
+	case []byte:
+		return hexutil.Encode(v)
+	case bool, string:
+		return v
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() { // nolint:exhaustive
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(rv.Int()).String()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(rv.Uint()).String()
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return hexutil.Encode(b)
+		}
+		fallthrough
+	case reflect.Slice:
+		values := make([]interface{}, rv.Len())
+		for i := range values {
+			values[i] = argumentValue(rv.Index(i).Interface())
+		}
+		return values
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// fourByteResponse is the response of
+// the signature lookup endpoint.
+type fourByteResponse struct {
+	Results []struct {
+		ID            int64  `json:"id"`
+		TextSignature string `json:"text_signature"`
+	} `json:"results"`
+}
+
+// lookup returns the method with selector in the 4byte
+// directory. If several signatures share the selector,
+// the oldest one is used as newer signatures are
+// usually crafted collisions. If the selector is
+// unknown, nil is returned.
+func (r *abiRegistry) lookup(
+	ctx context.Context,
+	selector [selectorLength]byte,
+) (*abi.Method, error) {
+	endpoint, err := url.Parse(r.lookupURL)
+	if err != nil {
+		return nil, err
+	}
+	query := endpoint.Query()
+	query.Set("hex_signature", hexutil.Encode(selector[:]))
+	endpoint.RawQuery = query.Encode()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := r.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("lookup returned status %d", response.StatusCode)
+	}
+
+	var body fourByteResponse
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	var oldest *abi.Method
+	var oldestID int64
+	for _, result := range body.Results {
+		method, err := parseSignature(result.TextSignature)
+		if err != nil || !equalSelector(method.ID, selector) {
+			continue
+		}
+
+		if oldest == nil || result.ID < oldestID {
+			oldest, oldestID = method, result.ID
+		}
+	}
+
+	return oldest, nil
+}
+
+// parseSignature returns the method of a text signature
+// (i.e. transfer(address,uint256)). The arguments of the
+// method are not named.
+func parseSignature(signature string) (*abi.Method, error) {
+	open := strings.Index(signature, "(")
+	if open <= 0 || !strings.HasSuffix(signature, ")") {
+		return nil, fmt.Errorf("%s is not a valid signature", signature)
+	}
+
+	name := signature[:open]
+	var inputs abi.Arguments
+	if params := signature[open+1 : len(signature)-1]; len(params) > 0 {
+		// Tuples are not supported.
+		if strings.ContainsAny(params, "()") {
+			return nil, fmt.Errorf("%s has unsupported arguments", signature)
+		}
+
+		for _, param := range strings.Split(params, ",") {
+			typ, err := abi.NewType(param, "", nil)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s is not a valid signature", err, signature)
+			}
+			inputs = append(inputs, abi.Argument{Type: typ})
+		}
+	}
+
+	method := abi.NewMethod(name, name, abi.Function, "", false, false, inputs, nil)
+	return &method, nil
+}
+
+func equalSelector(id []byte, selector [selectorLength]byte) bool {
+	return len(id) == selectorLength && string(id) == string(selector[:])
+}
+
+// resolve looks up selector and caches the result. If the
+// lookup endpoint cannot be reached, the selector is looked
+// up again after fourByteRetryInterval.
+func (r *abiRegistry) resolve(ctx context.Context, selector [selectorLength]byte) {
+	method, err := r.lookup(ctx, selector)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf(
+				"%s: unable to look up selector %s",
+				err.Error(),
+				hexutil.Encode(selector[:]),
+			)
+		}
+		r.queued[selector] = time.Now().Add(fourByteRetryInterval)
+		return
+	}
+
+	delete(r.queued, selector)
+	if len(r.resolved) >= fourByteCacheSize {
+		for evicted := range r.resolved {
+			delete(r.resolved, evicted)
+			break
+		}
+	}
+	r.resolved[selector] = method
+}
+
+// expireFailures allows the selectors whose lookup
+// failed before now to be looked up again.
+func (r *abiRegistry) expireFailures(now time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for selector, retryAt := range r.queued {
+		if !retryAt.IsZero() && retryAt.Before(now) {
+			delete(r.queued, selector)
+		}
+	}
+}
+
+// ResolveSelectors looks up the selectors of decoded transactions
+// that are not registered with the configured 4byte directory
+// until ctx is done. Transactions are decoded with the resolved
+// methods once they are available.
+func (ec *Client) ResolveSelectors(ctx context.Context) error {
+	r := ec.abiRegistry
+	if r == nil || len(r.lookupURL) == 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(fourByteRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case selector := <-r.queue:
+			r.resolve(ctx, selector)
+		case now := <-ticker.C:
+			r.expireFailures(now)
+		}
+	}
+}
@@ -0,0 +1,504 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/metrics"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// archivePollInterval is how often the archive nodes
+	// are checked against the node.
+	archivePollInterval = 15 * time.Second
+
+	// archiveScoreDecay is the weight of the previous health
+	// score of an archive node when a call completes. With a
+	// decay of 0.8, 4 consecutive failures make a healthy
+	// archive node unhealthy.
+	archiveScoreDecay = 0.8
+
+	// minArchiveScore is the health score below which
+	// an archive node stops receiving reads.
+	minArchiveScore = 0.5
+
+	archiveHealthyMetric  = "archive/healthy"
+	archiveFallbackMetric = "archive/fallbacks"
+)
+
+var (
+	// graphQLBlockNumber and graphQLBlockHash match the
+	// block selection of the GraphQL queries made by Balance.
+	graphQLBlockNumber = regexp.MustCompile(`block\(\s*number:\s*(\d+)\s*\)`)
+	graphQLBlockHash   = regexp.MustCompile(`block\(\s*hash:\s*"0x[0-9a-fA-F]{64}"\s*\)`)
+
+	// errArchiveMiss is returned when an archive node
+	// does not have the data requested.
+	errArchiveMiss = errors.New("archive node returned no data")
+)
+
+// hashReads are the read methods selecting data by hash,
+// which can be served by any archive node that has it.
+var hashReads = map[string]bool{
+	"eth_getBlockByHash":              true,
+	"eth_getUncleByBlockHashAndIndex": true,
+	"eth_getTransactionReceipt":       true,
+	"debug_traceBlockByHash":          true,
+	"debug_traceTransaction":          true,
+}
+
+// heightReads are the read methods selecting data by height,
+// keyed by the index of their block number argument.
+var heightReads = map[string]int{
+	"eth_getBlockByNumber": 0,
+	"eth_getBalance":       1,
+	"eth_call":             1,
+}
+
+// archive is an archive node that shares reads with the node.
+type archive struct {
+	index int
+	c     JSONRPC
+	g     GraphQL
+
+	// score is the health score of the archive node,
+	// between 0 and 1.
+	score float64
+
+	// checkpoint is the highest height at which the archive
+	// node was found to have the same block hash as the node
+	// (-1 if it was never consistent). Blocks up to checkpoint
+	// are on the same chain in both nodes.
+	checkpoint int64
+}
+
+// archivePool balances reads of historical data (blocks,
+// receipts, traces, and balances) across the node and a set
+// of archive nodes, in a round-robin fashion. All other calls
+// (i.e. relative to the tip or the mempool) are made to the
+// node. Reads are only sent to archive nodes that are healthy
+// and were checked to be on the same chain as the node (see
+// check), and fall back to the node if the archive node fails
+// or does not have the data. If hedgeDelay is not 0, reads
+// relative to the tip are hedged (see hedge).
+type archivePool struct {
+	node        JSONRPC
+	nodeGraphQL GraphQL
+	hedgeDelay  time.Duration
+
+	next uint64
+
+	mutex    sync.RWMutex
+	archives []*archive
+
+	// head is the head of the node
+	// at the last check.
+	head int64
+
+	// servedTip is the number of the highest
+	// tip header served by a hedged read.
+	servedTip int64
+}
+
+func newArchivePool(
+	node JSONRPC,
+	nodeGraphQL GraphQL,
+	urls []string,
+	hedgeDelay time.Duration,
+	proxy *url.URL,
+) (*archivePool, error) {
+	archives := make([]*archive, len(urls))
+	for i, endpoint := range urls {
+		c, err := dialRPC(endpoint, proxy)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to dial archive node %d", err, i)
+		}
+
+		g, err := newGraphQLClient(endpoint, proxy)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to create GraphQL client of archive node %d", err, i)
+		}
+
+		archives[i] = &archive{
+			index:      i,
+			c:          &instrumentedRPC{JSONRPC: c},
+			g:          &instrumentedGraphQL{GraphQL: g},
+			score:      1,
+			checkpoint: -1,
+		}
+	}
+
+	return &archivePool{
+		node:        node,
+		nodeGraphQL: nodeGraphQL,
+		hedgeDelay:  hedgeDelay,
+		archives:    archives,
+	}, nil
+}
+
+// readHeight returns whether a call is a read that can be
+// balanced and, if the read selects data by height, the
+// height (-1 otherwise).
+func readHeight(method string, args []interface{}) (bool, int64) {
+	if hashReads[method] {
+		return true, -1
+	}
+
+	position, ok := heightReads[method]
+	if !ok || len(args) <= position {
+		return false, 0
+	}
+
+	// Tags (i.e. "latest") are relative to the
+	// tip, which is only known to the node.
+	number, ok := args[position].(string)
+	if !ok {
+		return false, 0
+	}
+	height, err := hexutil.DecodeUint64(number)
+	if err != nil {
+		return false, 0
+	}
+
+	return true, int64(height)
+}
+
+// pick returns the archive node that should serve a read at
+// height (-1 if the read selects data by hash), or nil if it
+// should be served by the node. The node takes part in the
+// rotation.
+func (p *archivePool) pick(height int64) *archive {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	eligible := []*archive{nil}
+	for _, a := range p.archives {
+		if a.score < minArchiveScore || a.checkpoint < 0 || a.checkpoint < height {
+			continue
+		}
+
+		eligible = append(eligible, a)
+	}
+
+	return eligible[atomic.AddUint64(&p.next, 1)%uint64(len(eligible))]
+}
+
+// record updates the health score of a after a call. Errors
+// returned by the archive node itself (i.e. execution reverted)
+// do not affect its score.
+func (p *archivePool) record(a *archive, err error) {
+	var rpcErr rpc.Error
+	if err != nil && errors.As(err, &rpcErr) {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	a.score *= archiveScoreDecay
+	if err == nil {
+		a.score += 1 - archiveScoreDecay
+	}
+}
+
+// fallback logs a read that an archive node
+// could not serve before it is made to the node.
+func fallback(a *archive, method string, err error) {
+	metrics.Counter(archiveFallbackMetric).Inc(1)
+	if !errors.Is(err, errArchiveMiss) {
+		log.Printf("archive node %d failed %s, using node: %s", a.index, method, err.Error())
+	}
+}
+
+// CallContext implements JSONRPC.
+func (p *archivePool) CallContext(
+	ctx context.Context,
+	result interface{},
+	method string,
+	args ...interface{},
+) error {
+	if p.hedgeDelay > 0 && result != nil && tipRead(method, args) {
+		return p.hedgeCall(ctx, result, method, args...)
+	}
+
+	read, height := readHeight(method, args)
+	if !read || result == nil {
+		return p.node.CallContext(ctx, result, method, args...)
+	}
+
+	a := p.pick(height)
+	if a == nil {
+		return p.node.CallContext(ctx, result, method, args...)
+	}
+
+	var raw json.RawMessage
+	err := a.c.CallContext(ctx, &raw, method, args...)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	p.record(a, err)
+	if err == nil && isNull(raw) {
+		err = errArchiveMiss
+	}
+	if err == nil {
+		err = json.Unmarshal(raw, result)
+	}
+	if err != nil {
+		fallback(a, method, err)
+		return p.node.CallContext(ctx, result, method, args...)
+	}
+
+	return nil
+}
+
+// BatchCallContext implements JSONRPC. A batch is balanced
+// if all of its calls are reads.
+func (p *archivePool) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	height := int64(-1)
+	for _, elem := range b {
+		read, elemHeight := readHeight(elem.Method, elem.Args)
+		if !read || elem.Result == nil {
+			return p.node.BatchCallContext(ctx, b)
+		}
+		if elemHeight > height {
+			height = elemHeight
+		}
+	}
+
+	a := p.pick(height)
+	if a == nil {
+		return p.node.BatchCallContext(ctx, b)
+	}
+
+	raws := make([]json.RawMessage, len(b))
+	batch := make([]rpc.BatchElem, len(b))
+	for i, elem := range b {
+		batch[i] = rpc.BatchElem{Method: elem.Method, Args: elem.Args, Result: &raws[i]}
+	}
+
+	err := a.c.BatchCallContext(ctx, batch)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	p.record(a, err)
+	for i := 0; err == nil && i < len(batch); i++ {
+		switch {
+		case batch[i].Error != nil:
+			err = batch[i].Error
+		case isNull(raws[i]):
+			err = errArchiveMiss
+		default:
+			err = json.Unmarshal(raws[i], b[i].Result)
+		}
+	}
+	if err != nil {
+		fallback(a, "batch", err)
+		return p.node.BatchCallContext(ctx, b)
+	}
+
+	for i := range b {
+		b[i].Error = nil
+	}
+
+	return nil
+}
+
+// Query implements GraphQL. Only queries of a block
+// selected by number or hash are balanced.
+func (p *archivePool) Query(ctx context.Context, input string) (string, error) {
+	if p.hedgeDelay > 0 && graphQLTipBlock.MatchString(input) {
+		return p.hedgeQuery(ctx, input)
+	}
+
+	height := int64(-1)
+	if match := graphQLBlockNumber.FindStringSubmatch(input); match != nil {
+		number, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return p.nodeGraphQL.Query(ctx, input)
+		}
+		height = number
+	} else if !graphQLBlockHash.MatchString(input) {
+		return p.nodeGraphQL.Query(ctx, input)
+	}
+
+	a := p.pick(height)
+	if a == nil {
+		return p.nodeGraphQL.Query(ctx, input)
+	}
+
+	result, err := a.g.Query(ctx, input)
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	p.record(a, err)
+	if err == nil {
+		err = checkGraphQLBlock(result)
+	}
+	if err != nil {
+		fallback(a, "graphql", err)
+		return p.nodeGraphQL.Query(ctx, input)
+	}
+
+	return result, nil
+}
+
+// checkGraphQLBlock returns errArchiveMiss if the result
+// of a block query has errors or no block.
+func checkGraphQLBlock(result string) error {
+	var response struct {
+		Data struct {
+			Block json.RawMessage `json:"block"`
+		} `json:"data"`
+		Errors []interface{} `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(result), &response); err != nil {
+		return err
+	}
+	if len(response.Errors) > 0 || isNull(response.Data.Block) {
+		return errArchiveMiss
+	}
+
+	return nil
+}
+
+// Close implements JSONRPC.
+func (p *archivePool) Close() {
+	p.node.Close()
+	for _, a := range p.archives {
+		a.c.Close()
+	}
+}
+
+// check compares every archive node with the node at the
+// lowest of their heads and updates its checkpoint and
+// health score.
+func (p *archivePool) check(ctx context.Context) {
+	var head hexutil.Uint64
+	if err := p.node.CallContext(ctx, &head, "eth_blockNumber"); err != nil {
+		if ctx.Err() == nil {
+			log.Printf("unable to get head of node: %s", err.Error())
+		}
+		return
+	}
+
+	p.mutex.Lock()
+	p.head = int64(head)
+	p.mutex.Unlock()
+
+	healthy := int64(0)
+	for _, a := range p.archives {
+		checkpoint, err := p.checkpoint(ctx, a, int64(head))
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			// Archive URLs may contain credentials,
+			// so only the index is logged.
+			log.Printf("unable to check archive node %d: %s", a.index, err.Error())
+		}
+
+		p.record(a, err)
+
+		p.mutex.Lock()
+		a.checkpoint = checkpoint
+		if a.score >= minArchiveScore && a.checkpoint >= 0 {
+			healthy++
+		}
+		p.mutex.Unlock()
+	}
+
+	metrics.Gauge(archiveHealthyMetric).Update(healthy)
+}
+
+// checkpoint returns the height up to which a is on the same
+// chain as the node (-1 if its block hash differs).
+func (p *archivePool) checkpoint(ctx context.Context, a *archive, head int64) (int64, error) {
+	var archiveHead hexutil.Uint64
+	if err := a.c.CallContext(ctx, &archiveHead, "eth_blockNumber"); err != nil {
+		return -1, err
+	}
+
+	height := head
+	if int64(archiveHead) < height {
+		height = int64(archiveHead)
+	}
+
+	var nodeHeader, archiveHeader *types.Header
+	number := toBlockNumArg(big.NewInt(height))
+	if err := p.node.CallContext(ctx, &nodeHeader, "eth_getBlockByNumber", number, false); err != nil {
+		return -1, err
+	}
+	if err := a.c.CallContext(ctx, &archiveHeader, "eth_getBlockByNumber", number, false); err != nil {
+		return -1, err
+	}
+	if nodeHeader == nil || archiveHeader == nil {
+		return -1, fmt.Errorf("block %d not found", height)
+	}
+
+	if nodeHeader.Hash() != archiveHeader.Hash() {
+		log.Printf(
+			"archive node %d has block %s at height %d but the node has %s",
+			a.index,
+			archiveHeader.Hash().Hex(),
+			height,
+			nodeHeader.Hash().Hex(),
+		)
+		return -1, nil
+	}
+
+	return height, nil
+}
+
+// isNull returns true if raw is empty or the JSON null.
+func isNull(raw json.RawMessage) bool {
+	return len(bytes.TrimSpace(raw)) == 0 || bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// MonitorArchives checks the archive nodes against the node
+// until ctx is done, so that reads are only balanced across
+// archive nodes on the same chain. If no archive nodes are
+// configured, it returns immediately.
+func (ec *Client) MonitorArchives(ctx context.Context) error {
+	if ec.archives == nil {
+		return nil
+	}
+
+	for {
+		ec.archives.check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(archivePollInterval):
+		}
+	}
+}
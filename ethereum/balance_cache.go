@@ -0,0 +1,193 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/coinbase/rosetta-ethereum/metrics"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+)
+
+const (
+	balanceCacheHitsMetric   = "balance_cache/hits"
+	balanceCacheMissesMetric = "balance_cache/misses"
+)
+
+// balanceKey identifies the balance of an
+// account at a block.
+type balanceKey struct {
+	address   string
+	blockHash string
+}
+
+// balanceEntry is a cached balance. The index of the block
+// is kept so the entry can be evicted if the block is
+// reorged out.
+type balanceEntry struct {
+	key      balanceKey
+	index    int64
+	response *RosettaTypes.AccountBalanceResponse
+}
+
+// balanceCache caches historical balances keyed by address and
+// block hash, evicting the least recently used entries once it is
+// full. The balance of an account at a block hash never changes, so
+// entries do not expire. Instead, the entries of blocks that are
+// reorged out are evicted when the block that replaced them is
+// fetched (see observe).
+//
+// Balances requested only by index are not served from the cache
+// (the block at an index can change until it is final), but they
+// are cached under the hash they were fetched at.
+type balanceCache struct {
+	size int
+
+	mutex   sync.Mutex
+	entries map[balanceKey]*list.Element
+	order   *list.List
+
+	// hashes are the hashes of the blocks
+	// with cached entries, keyed by index.
+	hashes map[int64]map[string]int
+}
+
+// newBalanceCache returns a balanceCache holding up
+// to size balances. If size is 0, nil is returned.
+func newBalanceCache(size int) *balanceCache {
+	if size == 0 {
+		return nil
+	}
+
+	return &balanceCache{
+		size:    size,
+		entries: map[balanceKey]*list.Element{},
+		order:   list.New(),
+		hashes:  map[int64]map[string]int{},
+	}
+}
+
+// get returns the balance of address cached at
+// blockHash. If there is no entry, nil is returned.
+func (c *balanceCache) get(address string, blockHash string) *RosettaTypes.AccountBalanceResponse {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[balanceKey{address: address, blockHash: blockHash}]
+	if !ok {
+		metrics.Counter(balanceCacheMissesMetric).Inc(1)
+		return nil
+	}
+
+	metrics.Counter(balanceCacheHitsMetric).Inc(1)
+	c.order.MoveToFront(element)
+	return copyBalanceResponse(element.Value.(*balanceEntry).response)
+}
+
+// put caches the balance of address in response, evicting
+// the least recently used entry if the cache is full.
+func (c *balanceCache) put(address string, response *RosettaTypes.AccountBalanceResponse) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry := &balanceEntry{
+		key: balanceKey{
+			address:   address,
+			blockHash: response.BlockIdentifier.Hash,
+		},
+		index:    response.BlockIdentifier.Index,
+		response: copyBalanceResponse(response),
+	}
+	if element, ok := c.entries[entry.key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[entry.key] = c.order.PushFront(entry)
+	if c.hashes[entry.index] == nil {
+		c.hashes[entry.index] = map[string]int{}
+	}
+	c.hashes[entry.index][entry.key.blockHash]++
+
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// observe records that the canonical block at index is
+// blockIdentifier and evicts the entries of any other
+// block at that index, which must have been reorged out.
+func (c *balanceCache) observe(blockIdentifier *RosettaTypes.BlockIdentifier) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for hash := range c.hashes[blockIdentifier.Index] {
+		if hash == blockIdentifier.Hash {
+			continue
+		}
+
+		for element := c.order.Front(); element != nil; {
+			next := element.Next()
+			if element.Value.(*balanceEntry).key.blockHash == hash {
+				c.remove(element)
+			}
+			element = next
+		}
+	}
+}
+
+// remove evicts the entry in element. The
+// caller must hold the mutex.
+func (c *balanceCache) remove(element *list.Element) {
+	entry := element.Value.(*balanceEntry)
+	c.order.Remove(element)
+	delete(c.entries, entry.key)
+
+	c.hashes[entry.index][entry.key.blockHash]--
+	if c.hashes[entry.index][entry.key.blockHash] == 0 {
+		delete(c.hashes[entry.index], entry.key.blockHash)
+	}
+	if len(c.hashes[entry.index]) == 0 {
+		delete(c.hashes, entry.index)
+	}
+}
+
+// copyBalanceResponse returns a copy of response that
+// can be modified without modifying the cached entry.
+func copyBalanceResponse(response *RosettaTypes.AccountBalanceResponse) *RosettaTypes.AccountBalanceResponse {
+	balances := make([]*RosettaTypes.Amount, len(response.Balances))
+	for i, balance := range response.Balances {
+		amount := *balance
+		balances[i] = &amount
+	}
+
+	var metadata map[string]interface{}
+	if response.Metadata != nil {
+		metadata = make(map[string]interface{}, len(response.Metadata))
+		for key, value := range response.Metadata {
+			metadata[key] = value
+		}
+	}
+
+	blockIdentifier := *response.BlockIdentifier
+	return &RosettaTypes.AccountBalanceResponse{
+		BlockIdentifier: &blockIdentifier,
+		Balances:        balances,
+		Metadata:        metadata,
+	}
+}
@@ -15,16 +15,38 @@
 package ethereum
 
 import (
+	"context"
+	"embed"
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"sort"
 	"strings"
 
+	"github.com/coinbase/rosetta-ethereum/amount"
+	"github.com/coinbase/rosetta-ethereum/ethereum/networks"
+
 	"github.com/coinbase/rosetta-sdk-go/storage/modules"
 	"github.com/coinbase/rosetta-sdk-go/types"
 	"github.com/coinbase/rosetta-sdk-go/utils"
+	"github.com/ethereum/go-ethereum/common"
 )
 
+// maxDumpedAccounts is the maximum number of
+// accounts returned by debug_dumpBlock.
+const maxDumpedAccounts = 256
+
+//go:embed genesis_files/*.json
+var genesisFiles embed.FS
+
+// genesisFileNames are the names of the
+// embedded genesis files, keyed by network.
+var genesisFileNames = map[string]string{
+	networks.CoreNetwork:    "mainnet.json",
+	networks.BuffaloNetwork: "testnet.json",
+	networks.DevNetwork:     "devnet.json",
+}
+
 type genesis struct {
 	Alloc map[string]genesisAllocation `json:"alloc"`
 }
@@ -41,33 +63,99 @@ func GenerateBootstrapFile(genesisFile string, outputFile string) error {
 		return fmt.Errorf("%w: could not load genesis file", err)
 	}
 
+	return writeBootstrapFile(genesisAllocations.Alloc, outputFile)
+}
+
+// GenerateNetworkBootstrapFile creates the bootstrap balances
+// file for network (i.e. networks.CoreNetwork) from the genesis
+// file embedded in rosetta-core.
+func GenerateNetworkBootstrapFile(network string, outputFile string) error {
+	name, ok := genesisFileNames[network]
+	if !ok {
+		return fmt.Errorf("no genesis file is embedded for %s", network)
+	}
+
+	content, err := genesisFiles.ReadFile("genesis_files/" + name)
+	if err != nil {
+		return fmt.Errorf("%w: could not load genesis file of %s", err, network)
+	}
+
+	var genesisAllocations genesis
+	if err := json.Unmarshal(content, &genesisAllocations); err != nil {
+		return fmt.Errorf("%w: could not parse genesis file of %s", err, network)
+	}
+
+	return writeBootstrapFile(genesisAllocations.Alloc, outputFile)
+}
+
+// GenerateNodeBootstrapFile creates the bootstrap balances file
+// from the state of the genesis block of the node at url, which
+// is dumped with debug_dumpBlock. The node must still have the
+// genesis state and the preimages of the genesis accounts (i.e.
+// an archive node), as accounts without a preimage are omitted
+// from the dump.
+func GenerateNodeBootstrapFile(ctx context.Context, url string, outputFile string) error {
+	client, err := dialRPC(url, nil)
+	if err != nil {
+		return fmt.Errorf("%w: unable to dial node", err)
+	}
+	defer client.Close()
+
+	var dump struct {
+		Accounts map[common.Address]genesisAllocation `json:"accounts"`
+	}
+	genesisBlock := toBlockNumArg(big.NewInt(GenesisBlockIndex))
+	if err := client.CallContext(ctx, &dump, "debug_dumpBlock", genesisBlock); err != nil {
+		return fmt.Errorf("%w: unable to dump genesis state", err)
+	}
+
+	// The dump is truncated once it reaches the
+	// limit, so it may not contain every account.
+	if len(dump.Accounts) >= maxDumpedAccounts {
+		return fmt.Errorf(
+			"genesis state has at least %d accounts and cannot be dumped: use a genesis file instead",
+			maxDumpedAccounts,
+		)
+	}
+
+	alloc := make(map[string]genesisAllocation, len(dump.Accounts))
+	for address, account := range dump.Accounts {
+		alloc[address.Hex()] = account
+	}
+
+	return writeBootstrapFile(alloc, outputFile)
+}
+
+// writeBootstrapFile writes the non-zero balances
+// in alloc to outputFile, sorted by address.
+func writeBootstrapFile(alloc map[string]genesisAllocation, outputFile string) error {
 	// Sort keys for deterministic genesis creation
 	keys := make([]string, 0)
 	formattedAllocations := map[string]string{}
-	for k := range genesisAllocations.Alloc {
+	for k := range alloc {
 		checkAddr, ok := ChecksumAddress(k)
 		if !ok {
 			return fmt.Errorf("invalid address 0x%s", k)
 		}
 		keys = append(keys, checkAddr)
-		formattedAllocations[checkAddr] = genesisAllocations.Alloc[k].Balance
+		formattedAllocations[checkAddr] = alloc[k].Balance
 	}
 	sort.Strings(keys)
 
 	var bal *big.Int
-	var ok bool
+	var err error
 	// Write to file
 	balances := []*modules.BootstrapBalance{}
 	for _, k := range keys {
 		v := formattedAllocations[k]
 		if strings.HasPrefix(v, "0x") {
-			bal, ok = new(big.Int).SetString(v[2:], 16)
+			bal, err = amount.ParseHex(v)
 		} else {
-			bal, ok = new(big.Int).SetString(v, 10)
+			bal, err = amount.ParseUnsigned(v)
 		}
 
-		if !ok {
-			return fmt.Errorf("cannot parse %s for integer", v)
+		if err != nil {
+			return fmt.Errorf("%w: cannot parse balance of %s", err, k)
 		}
 
 		if bal.Sign() == 0 {
@@ -78,7 +166,7 @@ func GenerateBootstrapFile(genesisFile string, outputFile string) error {
 			Account: &types.AccountIdentifier{
 				Address: k,
 			},
-			Value:    bal.String(),
+			Value:    amount.Format(bal),
 			Currency: Currency,
 		})
 	}
@@ -0,0 +1,96 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	// NonceMetadataKey is the CANCEL operation metadata
+	// key holding the nonce of the cancelled transaction.
+	NonceMetadataKey = "nonce"
+
+	// ReplacementPriceBump is the minimum gas price increase (in
+	// percent) for the txpool to replace a pending transaction.
+	ReplacementPriceBump = 10
+)
+
+// CancelTarget describes the transaction
+// replaced by a cancellation.
+type CancelTarget struct {
+	// Nonce is the nonce of the account at the head. The
+	// transactions of the account with a lower nonce are mined.
+	Nonce uint64
+
+	// GasPrice is the gas price of the transaction in the mempool
+	// with the cancelled nonce, or nil if there is none.
+	GasPrice *big.Int
+}
+
+// CancelTarget returns the state of the transaction of
+// account with nonce, which a cancellation replaces.
+func (ec *Client) CancelTarget(
+	ctx context.Context,
+	account common.Address,
+	nonce uint64,
+) (*CancelTarget, error) {
+	var head hexutil.Uint64
+	if err := ec.c.CallContext(ctx, &head, "eth_getTransactionCount", account, "latest"); err != nil {
+		return nil, err
+	}
+
+	target := &CancelTarget{Nonce: uint64(head)}
+	if nonce < target.Nonce {
+		return target, nil
+	}
+
+	// Only the transactions of account are fetched,
+	// not the whole mempool.
+	var content struct {
+		Pending map[string]*rpcMempoolTransaction `json:"pending"`
+		Queued  map[string]*rpcMempoolTransaction `json:"queued"`
+	}
+	if err := ec.c.CallContext(ctx, &content, "txpool_contentFrom", account); err != nil {
+		return nil, err
+	}
+
+	key := strconv.FormatUint(nonce, 10) // nolint:gomnd
+	for _, txs := range []map[string]*rpcMempoolTransaction{content.Pending, content.Queued} {
+		if tx, ok := txs[key]; ok && tx != nil && tx.GasPrice != nil {
+			target.GasPrice = (*big.Int)(tx.GasPrice)
+		}
+	}
+
+	return target, nil
+}
+
+// rpcMempoolTransaction is the subset of a
+// txpool_contentFrom transaction used by CancelTarget.
+type rpcMempoolTransaction struct {
+	GasPrice *hexutil.Big `json:"gasPrice"`
+}
+
+// ReplacementGasPrice returns the lowest gas price accepted
+// to replace a pending transaction with gasPrice.
+func ReplacementGasPrice(gasPrice *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(gasPrice, big.NewInt(100+ReplacementPriceBump)) // nolint:gomnd
+	return bumped.Div(bumped, big.NewInt(100))                                 // nolint:gomnd
+}
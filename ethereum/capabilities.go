@@ -0,0 +1,382 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/metrics"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// capabilityProbeInterval is how often the capabilities
+	// of the node are probed until it responds.
+	capabilityProbeInterval = 5 * time.Second
+
+	blockReceiptsFallbackMetric = "block_receipts/fallbacks"
+)
+
+// Capability is a namespace or method of the node that
+// is used when the node serves it.
+type Capability string
+
+const (
+	// DebugCapability is the debug namespace, used to trace
+	// transactions. It is the only required capability.
+	DebugCapability Capability = "debug"
+
+	// TxPoolCapability is the txpool namespace, used to serve
+	// /mempool and to monitor the mempool and nonce gaps.
+	TxPoolCapability Capability = "txpool"
+
+	// AdminCapability is the admin namespace, used to
+	// report peers and the hardforks of the node.
+	AdminCapability Capability = "admin"
+
+	// BlockReceiptsCapability is the eth_getBlockReceipts
+	// method, used to fetch the receipts of a block at once.
+	BlockReceiptsCapability Capability = "eth_getBlockReceipts"
+
+	// GraphQLCapability is the GraphQL endpoint,
+	// used to prefetch receipts.
+	GraphQLCapability Capability = "graphql"
+)
+
+// Capabilities records which capabilities the node serves.
+type Capabilities map[Capability]bool
+
+// capabilityProbe checks whether the node serves a capability.
+type capabilityProbe struct {
+	capability Capability
+	probe      func(context.Context, *Client) error
+}
+
+// capabilityProbes are run in order by ProbeCapabilities. Probes
+// use arguments the node rejects (or cheaply serves) so that only
+// the presence of the method is tested.
+var capabilityProbes = []*capabilityProbe{
+	{
+		capability: DebugCapability,
+		probe: func(ctx context.Context, ec *Client) error {
+			var raw interface{}
+			return ec.c.CallContext(ctx, &raw, "debug_traceTransaction", common.Hash{}, ec.tc)
+		},
+	},
+	{
+		capability: TxPoolCapability,
+		probe: func(ctx context.Context, ec *Client) error {
+			var raw interface{}
+			return ec.c.CallContext(ctx, &raw, "txpool_status")
+		},
+	},
+	{
+		capability: AdminCapability,
+		probe: func(ctx context.Context, ec *Client) error {
+			var raw interface{}
+			return ec.c.CallContext(ctx, &raw, "admin_nodeInfo")
+		},
+	},
+	{
+		capability: BlockReceiptsCapability,
+		probe: func(ctx context.Context, ec *Client) error {
+			var raw interface{}
+			return ec.c.CallContext(ctx, &raw, "eth_getBlockReceipts", "earliest")
+		},
+	},
+	{
+		capability: GraphQLCapability,
+		probe: func(ctx context.Context, ec *Client) error {
+			result, err := ec.g.Query(ctx, "{ block(number: 0) { hash } }")
+			if err != nil {
+				return err
+			}
+
+			// Nodes without GraphQL serve a 404 page.
+			var block struct {
+				Block *graphQLBlock `json:"block"`
+			}
+			if err := ec.graphQLQueryResult(result, &block); err != nil || block.Block == nil {
+				return errCapabilityUnavailable
+			}
+
+			return nil
+		},
+	},
+}
+
+// errCapabilityUnavailable is returned by probes of
+// capabilities the node does not serve.
+var errCapabilityUnavailable = errors.New("capability unavailable")
+
+// capabilityAvailable returns whether err, the result of a probe,
+// shows that the capability is served. Errors that say nothing
+// about the capability (i.e. the node is not reachable yet) are
+// returned.
+func capabilityAvailable(err error) (bool, error) {
+	if err == nil {
+		return true, nil
+	}
+
+	if errors.Is(err, errCapabilityUnavailable) {
+		return false, nil
+	}
+
+	// The method is served if it only rejected the probe,
+	// unless the node (or its provider) reports that it
+	// is not available.
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		if rpcErr.ErrorCode() == methodNotFoundCode {
+			return false, nil
+		}
+
+		message := strings.ToLower(rpcErr.Error())
+		for _, unavailable := range []string{"not available", "not supported", "not allowed", "does not exist"} {
+			if strings.Contains(message, unavailable) {
+				return false, nil
+			}
+		}
+
+		return true, nil
+	}
+
+	// Providers block methods with 4xx responses.
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode >= http.StatusBadRequest &&
+		httpErr.StatusCode < http.StatusInternalServerError {
+		return false, nil
+	}
+
+	return false, err
+}
+
+// probeCapabilities probes every capability once. Capabilities
+// skipped by configuration (SKIP_GETH_ADMIN) are not probed.
+func (ec *Client) probeCapabilities(ctx context.Context) (Capabilities, error) {
+	capabilities := Capabilities{}
+	for _, probe := range capabilityProbes {
+		if probe.capability == AdminCapability && ec.skipAdminCalls {
+			capabilities[AdminCapability] = false
+			continue
+		}
+
+		available, err := capabilityAvailable(probe.probe(ctx, ec))
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to probe %s", err, probe.capability)
+		}
+		capabilities[probe.capability] = available
+	}
+
+	return capabilities, nil
+}
+
+// configureCapabilities selects the fetch strategy best suited
+// to capabilities and returns the decisions made, one per
+// capability. If a required capability is missing, an error is
+// returned.
+func (ec *Client) configureCapabilities(capabilities Capabilities) ([]string, error) {
+	if !capabilities[DebugCapability] {
+		return nil, errors.New(
+			"the debug namespace is not available, transactions cannot be traced (enable it with --http.api)",
+		)
+	}
+
+	decision := func(capability Capability, strategy string) string {
+		status := "unavailable"
+		if capabilities[capability] {
+			status = "available"
+		}
+
+		return fmt.Sprintf("%-20s %-11s %s", capability, status, strategy)
+	}
+
+	decisions := []string{
+		decision(DebugCapability, "blocks are traced with debug_traceBlockByHash"),
+	}
+
+	switch {
+	case capabilities[TxPoolCapability]:
+		decisions = append(decisions, decision(TxPoolCapability, "/mempool and mempool monitoring are served"))
+	default:
+		decisions = append(decisions, decision(
+			TxPoolCapability,
+			"/mempool, mempool monitoring, and cancellations are unavailable",
+		))
+	}
+
+	switch {
+	case capabilities[AdminCapability]:
+		decisions = append(decisions, decision(AdminCapability, "peers and node hardforks are reported"))
+	default:
+		atomic.StoreInt32(&ec.adminPeersUnavailable, 1)
+		decisions = append(decisions, decision(AdminCapability, "peers and node hardforks are not reported"))
+	}
+
+	graphQL := ec.receipts != nil && capabilities[GraphQLCapability]
+	if ec.receipts != nil && !graphQL {
+		atomic.StoreInt32(&ec.graphQLUnavailable, 1)
+	}
+	if capabilities[BlockReceiptsCapability] {
+		atomic.StoreInt32(&ec.blockReceiptsAvailable, 1)
+	}
+
+	switch {
+	case graphQL:
+		decisions = append(decisions, decision(GraphQLCapability, "receipts are prefetched over GraphQL"))
+	case capabilities[GraphQLCapability]:
+		decisions = append(decisions, decision(
+			GraphQLCapability,
+			"receipts are not prefetched (set GRAPHQL_BATCH_SIZE to prefetch them)",
+		))
+	default:
+		decisions = append(decisions, decision(GraphQLCapability, "receipts are not prefetched"))
+	}
+
+	switch {
+	case capabilities[BlockReceiptsCapability] && graphQL:
+		decisions = append(decisions, decision(BlockReceiptsCapability, "receipts fall back to eth_getBlockReceipts"))
+	case capabilities[BlockReceiptsCapability]:
+		decisions = append(decisions, decision(BlockReceiptsCapability, "receipts are fetched with eth_getBlockReceipts"))
+	default:
+		decisions = append(decisions, decision(
+			BlockReceiptsCapability,
+			"receipts are fetched with batched eth_getTransactionReceipt",
+		))
+	}
+
+	return decisions, nil
+}
+
+// CheckCapabilities probes the capabilities of the node once and
+// returns the decisions ProbeCapabilities would make. If the node
+// cannot be reached or does not serve a required capability, an
+// error is returned.
+func (ec *Client) CheckCapabilities(ctx context.Context) ([]string, error) {
+	capabilities, err := ec.probeCapabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return ec.configureCapabilities(capabilities)
+}
+
+// ProbeCapabilities probes the capabilities of the node, retrying
+// until the node responds or ctx is done, then selects the fetch
+// strategy best suited to them and logs the decisions. Until then,
+// every capability is assumed to be available except
+// eth_getBlockReceipts. If the node does not serve a required
+// capability, an error is returned so that the node is fixed
+// before blocks are synced rather than failing mid-sync.
+func (ec *Client) ProbeCapabilities(ctx context.Context) error {
+	for {
+		capabilities, err := ec.probeCapabilities(ctx)
+		if err == nil {
+			decisions, err := ec.configureCapabilities(capabilities)
+			if err != nil {
+				return err
+			}
+
+			log.Printf("node capabilities:")
+			for _, decision := range decisions {
+				log.Printf("  %s", decision)
+			}
+
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+		log.Printf("unable to probe node capabilities: %s", err.Error())
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(capabilityProbeInterval):
+		}
+	}
+}
+
+// blockReceipts returns the receipts of txs in the block with
+// blockHash, fetched with eth_getBlockReceipts.
+func (ec *Client) blockReceipts(
+	ctx context.Context,
+	blockHash common.Hash,
+	txs []rpcTransaction,
+) ([]*types.Receipt, error) {
+	var receipts []*types.Receipt
+	if err := ec.c.CallContext(ctx, &receipts, "eth_getBlockReceipts", blockHash.Hex()); err != nil {
+		return nil, err
+	}
+	if len(receipts) < len(txs) {
+		return nil, fmt.Errorf("expected %d receipts but got %d", len(txs), len(receipts))
+	}
+
+	receipts = receipts[:len(txs)]
+	for i, receipt := range receipts {
+		if receipt == nil || receipt.TxHash != txs[i].tx.Hash() {
+			return nil, fmt.Errorf("missing receipt for %s", txs[i].tx.Hash().Hex())
+		}
+
+		if receipt.BlockHash != blockHash {
+			return nil, fmt.Errorf(
+				"%w: expected block hash %s for transaction but got %s",
+				ErrBlockOrphaned,
+				blockHash.Hex(),
+				receipt.BlockHash.Hex(),
+			)
+		}
+	}
+
+	return receipts, nil
+}
+
+// blockReceiptsWithFallback returns the receipts of txs fetched
+// with eth_getBlockReceipts when the node serves it, or false if
+// they must be fetched individually.
+func (ec *Client) blockReceiptsWithFallback(
+	ctx context.Context,
+	blockHash common.Hash,
+	txs []rpcTransaction,
+) ([]*types.Receipt, bool, error) {
+	if atomic.LoadInt32(&ec.blockReceiptsAvailable) == 0 {
+		return nil, false, nil
+	}
+
+	receipts, err := ec.blockReceipts(ctx, blockHash, txs)
+	if err == nil || errors.Is(err, ErrBlockOrphaned) {
+		return receipts, true, err
+	}
+
+	metrics.Counter(blockReceiptsFallbackMetric).Inc(1)
+	log.Printf(
+		"%s: unable to get receipts of block %s with eth_getBlockReceipts, falling back to eth_getTransactionReceipt",
+		err.Error(),
+		blockHash.Hex(),
+	)
+
+	return nil, false, nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// chainCheckInterval is the time between attempts
+// to reach the node in VerifyChain.
+const chainCheckInterval = 5 * time.Second
+
+// ChainMismatchError is returned when the chain ID or genesis
+// block of the node differ from the configured network.
+type ChainMismatchError struct {
+	ConfiguredChainID *big.Int
+	NodeChainID       *big.Int
+	ConfiguredGenesis string
+	NodeGenesis       string
+}
+
+// Error implements error.
+func (e *ChainMismatchError) Error() string {
+	return fmt.Sprintf(
+		"%s: node has chain ID %s and genesis block %s, but chain ID %s and genesis block %s are configured",
+		ErrChainMismatch.Error(),
+		e.NodeChainID.String(),
+		e.NodeGenesis,
+		e.ConfiguredChainID.String(),
+		e.ConfiguredGenesis,
+	)
+}
+
+// Unwrap returns ErrChainMismatch.
+func (e *ChainMismatchError) Unwrap() error {
+	return ErrChainMismatch
+}
+
+// CheckChain returns a *ChainMismatchError if the chain ID or
+// the genesis block of the node differ from chainID and genesis.
+func (ec *Client) CheckChain(
+	ctx context.Context,
+	chainID *big.Int,
+	genesis *RosettaTypes.BlockIdentifier,
+) error {
+	var nodeChainID hexutil.Big
+	if err := ec.c.CallContext(ctx, &nodeChainID, "eth_chainId"); err != nil {
+		return fmt.Errorf("%w: unable to get chain ID", err)
+	}
+
+	header, err := ec.blockHeaderByNumber(ctx, big.NewInt(GenesisBlockIndex))
+	if err != nil {
+		return fmt.Errorf("%w: unable to get genesis block", err)
+	}
+
+	nodeGenesis := header.Hash().Hex()
+	if nodeChainID.ToInt().Cmp(chainID) != 0 || !strings.EqualFold(nodeGenesis, genesis.Hash) {
+		return &ChainMismatchError{
+			ConfiguredChainID: chainID,
+			NodeChainID:       nodeChainID.ToInt(),
+			ConfiguredGenesis: genesis.Hash,
+			NodeGenesis:       nodeGenesis,
+		}
+	}
+
+	return nil
+}
+
+// VerifyChain calls CheckChain until the node can be reached
+// (which may take a while when the node is started alongside
+// rosetta-core). It returns nil if the node is on the configured
+// chain (or ctx is canceled) and a *ChainMismatchError otherwise.
+func (ec *Client) VerifyChain(
+	ctx context.Context,
+	chainID *big.Int,
+	genesis *RosettaTypes.BlockIdentifier,
+) error {
+	for {
+		err := ec.CheckChain(ctx, chainID, genesis)
+		if err == nil {
+			return nil
+		}
+
+		var mismatch *ChainMismatchError
+		if errors.As(err, &mismatch) {
+			return err
+		}
+
+		if ctx.Err() == nil {
+			log.Printf("unable to verify chain of node: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(chainCheckInterval):
+		}
+	}
+}
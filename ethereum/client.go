@@ -21,10 +21,16 @@ import (
 	"fmt"
 	"log"
 	"math/big"
-	"net/http"
-	"strconv"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/coinbase/rosetta-ethereum/amount"
+	"github.com/coinbase/rosetta-ethereum/fees"
+	"github.com/coinbase/rosetta-ethereum/metrics"
+
 	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
@@ -45,9 +51,9 @@ const (
 	maxTraceConcurrency  = int64(16) // nolint:gomnd
 	semaphoreTraceWeight = int64(1)  // nolint:gomnd
 
-	// eip1559TxType is the EthTypes.Transaction.Type() value that indicates this transaction
-	// follows EIP-1559.
-	eip1559TxType = 2
+	// methodNotFoundCode is the JSON-RPC error code
+	// of calls to methods the node does not expose.
+	methodNotFoundCode = -32601
 )
 
 // Client allows for querying a set of specific Ethereum endpoints in an
@@ -64,14 +70,185 @@ type Client struct {
 
 	traceSemaphore *semaphore.Weighted
 
-	skipAdminCalls bool
+	skipAdminCalls     bool
+	emitApprovals      bool
+	checkInvariants    bool
+	collapseOperations bool
+
+	zeroValueOperations ZeroValueOperations
+	dustThreshold       *big.Int
+
+	// watchlist is nil unless filtered block mode is enabled.
+	watchlist *watchlist
+
+	// lag is nil unless lag detection is enabled.
+	lag *lagMonitor
+
+	// customTracer is nil unless a custom tracer is configured.
+	customTracer *CustomTracer
+
+	// labels is nil unless address labels are configured.
+	labels map[common.Address]*Label
+
+	// entryPoints is nil unless user operations are enabled.
+	entryPoints map[common.Address]struct{}
+
+	// abiRegistry is nil unless method decoding is enabled.
+	abiRegistry *abiRegistry
+
+	// transformers are applied to every converted
+	// block and transaction (see BlockTransformer).
+	transformers []BlockTransformer
+
+	// balances is nil unless the balance cache is enabled.
+	balances *balanceCache
+
+	// receipts is nil unless receipts are
+	// fetched over GraphQL.
+	receipts *receiptPrefetcher
+
+	// archives is nil unless archive nodes are configured,
+	// in which case c and g balance reads across them.
+	archives *archivePool
+
+	// timestampStartIndex is populated once
+	// TimestampStartIndex finds the index.
+	timestampMutex      sync.Mutex
+	timestampStartIndex *int64
+
+	// nodeForks are the hardforks in the chain config
+	// of the node (see refreshHardforks).
+	hardforksMutex sync.RWMutex
+	nodeForks      map[string]uint64
+
+	// adminPeersUnavailable is set to 1 once the node
+	// rejects admin_peers (see peers) or is found not
+	// to serve the admin namespace (see ProbeCapabilities).
+	adminPeersUnavailable int32
+
+	// blockReceiptsAvailable and graphQLUnavailable are set
+	// to 1 by ProbeCapabilities when the node serves
+	// eth_getBlockReceipts and does not serve GraphQL.
+	blockReceiptsAvailable int32
+	graphQLUnavailable     int32
+
+	// rewards is nil unless rewards are attributed
+	// to fee addresses (see attributeRewards).
+	rewards *rewardResolver
+
+	// traceStartIndex is the index of the first block
+	// the node can trace (see degradedBlock).
+	traceStartIndex int64
+}
+
+// ClientOptions configures the optional
+// features of a Client (see NewClient).
+type ClientOptions struct {
+	// SkipAdminCalls disables the calls to
+	// the admin namespace of the node.
+	SkipAdminCalls bool
+
+	// EmitApprovals surfaces ERC-20 Approval
+	// events as APPROVAL operations.
+	EmitApprovals bool
+
+	// EntryPoints are the ERC-4337 EntryPoints whose bundles
+	// have their user operations surfaced as USER_OPERATION
+	// operations (see userOperationOps).
+	EntryPoints []common.Address
+
+	// WatchedAddresses, if not empty, runs the Client in
+	// filtered block mode: transactions that cannot touch a
+	// watched address are returned with only their fee
+	// operations.
+	WatchedAddresses []common.Address
+
+	// Lag, if not nil, reports when the node falls behind
+	// the reference nodes (see MonitorLag).
+	Lag *LagConfig
+
+	// CheckInvariants rejects blocks whose
+	// operations do not balance (see checkInvariant).
+	CheckInvariants bool
+
+	// CollapseOperations collapses the trace operations of
+	// every transaction into a single operation per account
+	// (see collapseOps).
+	CollapseOperations bool
+
+	// ZeroValueOperations determines which zero value
+	// trace operations are emitted.
+	ZeroValueOperations ZeroValueOperations
+
+	// DustThreshold, if not nil, aggregates trace operations
+	// moving less per account (see filterDust).
+	DustThreshold *big.Int
+
+	// CustomTracer, if not nil, is run on every transaction
+	// in addition to the call tracer (see CustomTracer).
+	CustomTracer *CustomTracer
+
+	// Labels, if not nil, label the accounts of operations
+	// along with the names of the system contracts.
+	Labels map[common.Address]*Label
+
+	// ABIRegistry, if not nil, decodes the methods called
+	// by transactions in their metadata (see abiRegistry).
+	ABIRegistry *ABIRegistryConfig
+
+	// Transformers are applied to every converted block
+	// and transaction (see BlockTransformer).
+	Transformers []BlockTransformer
+
+	// BalanceCacheSize, if not 0, caches up to that many
+	// historical balances (see balanceCache).
+	BalanceCacheSize int
+
+	// GraphQLBatchSize, if not 0, fetches receipts over
+	// GraphQL for that many consecutive blocks at once
+	// (see receiptPrefetcher).
+	GraphQLBatchSize int
+
+	// RewardRecipient, if FeeAddressRewardRecipient,
+	// attributes the rewards of every block to the fee
+	// address of its validator (see attributeRewards).
+	RewardRecipient RewardRecipient
+
+	// TraceStartIndex is the index of the first block traced.
+	// Blocks below it are served in degraded mode (see
+	// degradedBlock).
+	TraceStartIndex int64
+
+	// ArchiveURLs, if not empty, balance historical reads
+	// across the node and those archive nodes (see
+	// archivePool).
+	ArchiveURLs []string
+
+	// HedgeDelay, if not 0, hedges reads relative to the
+	// tip across the archive nodes (see hedge).
+	HedgeDelay time.Duration
+
+	// Proxy, if not nil, is used for all connections.
+	// Otherwise, the standard proxy environment
+	// variables are honored.
+	Proxy *neturl.URL
 }
 
-// NewClient creates a Client that from the provided url and params.
-func NewClient(url string, params *params.ChainConfig, skipAdminCalls bool) (*Client, error) {
-	c, err := rpc.DialHTTPWithClient(url, &http.Client{
-		Timeout: gethHTTPTimeout,
-	})
+// NewClient creates a Client for the node at url with params,
+// configured by opts (which may be nil). The node and the
+// reference nodes can be reached over HTTP(S) or WebSocket
+// (ws:// or wss://).
+func NewClient(
+	url string,
+	params *params.ChainConfig,
+	opts *ClientOptions,
+) (*Client, error) {
+	if opts == nil {
+		opts = &ClientOptions{}
+	}
+	proxy := opts.Proxy
+
+	c, err := dialRPC(url, proxy)
 	if err != nil {
 		return nil, fmt.Errorf("%w: unable to dial node", err)
 	}
@@ -81,17 +258,65 @@ func NewClient(url string, params *params.ChainConfig, skipAdminCalls bool) (*Cl
 		return nil, fmt.Errorf("%w: unable to load trace config", err)
 	}
 
-	g, err := newGraphQLClient(url)
+	g, err := newGraphQLClient(url, proxy)
 	if err != nil {
 		return nil, fmt.Errorf("%w: unable to create GraphQL client", err)
 	}
 
-	return &Client{params, tc, c, g, semaphore.NewWeighted(maxTraceConcurrency), skipAdminCalls}, nil
+	var lag *lagMonitor
+	if opts.Lag != nil {
+		lag, err = newLagMonitor(opts.Lag, proxy)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to initialize lag detection", err)
+		}
+	}
+
+	var (
+		rpcClient     JSONRPC = &instrumentedRPC{JSONRPC: c}
+		graphQLClient GraphQL = &instrumentedGraphQL{GraphQL: g}
+		archives      *archivePool
+	)
+	if len(opts.ArchiveURLs) > 0 {
+		archives, err = newArchivePool(rpcClient, graphQLClient, opts.ArchiveURLs, opts.HedgeDelay, proxy)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to initialize archive nodes", err)
+		}
+		rpcClient, graphQLClient = archives, archives
+	}
+
+	return &Client{
+		p:                   params,
+		tc:                  tc,
+		c:                   rpcClient,
+		g:                   graphQLClient,
+		traceSemaphore:      semaphore.NewWeighted(maxTraceConcurrency),
+		skipAdminCalls:      opts.SkipAdminCalls,
+		emitApprovals:       opts.EmitApprovals,
+		checkInvariants:     opts.CheckInvariants,
+		collapseOperations:  opts.CollapseOperations,
+		zeroValueOperations: opts.ZeroValueOperations,
+		dustThreshold:       opts.DustThreshold,
+		watchlist:           newWatchlist(opts.WatchedAddresses),
+		lag:                 lag,
+		customTracer:        opts.CustomTracer,
+		labels:              newLabels(opts.Labels),
+		entryPoints:         newEntryPoints(opts.EntryPoints),
+		abiRegistry:         newABIRegistry(opts.ABIRegistry, proxy),
+		transformers:        opts.Transformers,
+		balances:            newBalanceCache(opts.BalanceCacheSize),
+		receipts:            newReceiptPrefetcher(opts.GraphQLBatchSize),
+		archives:            archives,
+		rewards:             newRewardResolver(opts.RewardRecipient),
+		traceStartIndex:     opts.TraceStartIndex,
+	}, nil
 }
 
 // Close shuts down the RPC client connection.
 func (ec *Client) Close() {
 	ec.c.Close()
+	if ec.lag != nil {
+		ec.lag.close()
+	}
 }
 
 // Status returns geth status information
@@ -124,6 +349,8 @@ func (ec *Client) Status(ctx context.Context) (
 		}
 	}
 
+	syncStatus = ec.lagSyncStatus(header.Number.Int64(), syncStatus)
+
 	peers, err := ec.peers(ctx)
 	if err != nil {
 		return nil, -1, nil, nil, err
@@ -147,6 +374,16 @@ func (ec *Client) PendingNonceAt(ctx context.Context, account common.Address) (u
 	return uint64(result), err
 }
 
+// PendingBalanceAt returns the balance of account
+// at the pending state of the node.
+func (ec *Client) PendingBalanceAt(ctx context.Context, account common.Address) (*big.Int, error) {
+	var result hexutil.Big
+	if err := ec.c.CallContext(ctx, &result, "eth_getBalance", account, "pending"); err != nil {
+		return nil, err
+	}
+	return (*big.Int)(&result), nil
+}
+
 // SuggestGasPrice retrieves the currently suggested gas price to allow a timely
 // execution of a transaction.
 func (ec *Client) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
@@ -157,16 +394,36 @@ func (ec *Client) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
 	return (*big.Int)(&hex), nil
 }
 
-// Peers retrieves all peers of the node.
+// peers retrieves all peers of the node. Nodes (and hosted
+// providers) that do not expose the admin namespace are
+// detected the first time admin_peers is rejected, after
+// which no peers are reported. Other failures are logged
+// and reported as no peers, so they do not fail Status.
 func (ec *Client) peers(ctx context.Context) ([]*RosettaTypes.Peer, error) {
 	var info []*p2p.PeerInfo
 
-	if ec.skipAdminCalls {
+	if ec.skipAdminCalls || atomic.LoadInt32(&ec.adminPeersUnavailable) == 1 {
 		return []*RosettaTypes.Peer{}, nil
 	}
 
 	if err := ec.c.CallContext(ctx, &info, "admin_peers"); err != nil {
-		return nil, err
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == methodNotFoundCode {
+			if atomic.CompareAndSwapInt32(&ec.adminPeersUnavailable, 0, 1) {
+				log.Printf(
+					"%s: admin_peers is not available, peers will not be reported (set SKIP_GETH_ADMIN to skip admin calls)",
+					err.Error(),
+				)
+			}
+		} else {
+			log.Printf("%s: unable to get peers", err.Error())
+		}
+
+		return []*RosettaTypes.Peer{}, nil
 	}
 
 	peers := make([]*RosettaTypes.Peer, len(info))
@@ -174,11 +431,16 @@ func (ec *Client) peers(ctx context.Context) ([]*RosettaTypes.Peer, error) {
 		peers[i] = &RosettaTypes.Peer{
 			PeerID: peerInfo.ID,
 			Metadata: map[string]interface{}{
-				"name":      peerInfo.Name,
-				"enode":     peerInfo.Enode,
-				"caps":      peerInfo.Caps,
-				"enr":       peerInfo.ENR,
-				"protocols": peerInfo.Protocols,
+				"name":           peerInfo.Name,
+				"enode":          peerInfo.Enode,
+				"caps":           peerInfo.Caps,
+				"enr":            peerInfo.ENR,
+				"protocols":      peerInfo.Protocols,
+				"local_address":  peerInfo.Network.LocalAddress,
+				"remote_address": peerInfo.Network.RemoteAddress,
+				"inbound":        peerInfo.Network.Inbound,
+				"trusted":        peerInfo.Network.Trusted,
+				"static":         peerInfo.Network.Static,
 			},
 		}
 	}
@@ -272,7 +534,7 @@ func (ec *Client) Transaction(
 
 	loadedTx := body.LoadedTransaction()
 	loadedTx.Transaction = body.tx
-	feeAmount, feeBurned, err := calculateGas(body.tx, receipt, *header)
+	feeAmount, feeBurned, err := calculateGas(ec.p, body.tx, receipt, *header)
 	if err != nil {
 		return nil, err
 	}
@@ -286,7 +548,7 @@ func (ec *Client) Transaction(
 		loadedTx.RawTrace = rawTraces
 	}
 
-	tx, err := ec.populateTransaction(loadedTx)
+	tx, err := ec.convertTransaction(loadedTx)
 	if err != nil {
 		return nil, fmt.Errorf("%w: cannot parse %s", err, loadedTx.Transaction.Hash().Hex())
 	}
@@ -300,14 +562,30 @@ func (ec *Client) Block(
 	ctx context.Context,
 	blockIdentifier *RosettaTypes.PartialBlockIdentifier,
 ) (*RosettaTypes.Block, error) {
+	block, _, err := ec.BlockPage(ctx, blockIdentifier, 0)
+	return block, err
+}
+
+// BlockPage returns a block at the *RosettaTypes.PartialBlockIdentifier
+// with at most limit transactions (including the block reward transaction)
+// populated. The identifiers of the remaining transactions are returned
+// so they can be fetched with Transaction. Only the populated transactions
+// are traced, so large blocks can be served without tracing the entire
+// block. If limit is 0, all transactions are populated.
+func (ec *Client) BlockPage(
+	ctx context.Context,
+	blockIdentifier *RosettaTypes.PartialBlockIdentifier,
+	limit int,
+) (*RosettaTypes.Block, []*RosettaTypes.TransactionIdentifier, error) {
 	if blockIdentifier != nil {
 		if blockIdentifier.Hash != nil {
-			return ec.getParsedBlock(ctx, "eth_getBlockByHash", *blockIdentifier.Hash, true)
+			return ec.getParsedBlock(ctx, limit, "eth_getBlockByHash", *blockIdentifier.Hash, true)
 		}
 
 		if blockIdentifier.Index != nil {
 			return ec.getParsedBlock(
 				ctx,
+				limit,
 				"eth_getBlockByNumber",
 				toBlockNumArg(big.NewInt(*blockIdentifier.Index)),
 				true,
@@ -315,7 +593,7 @@ func (ec *Client) Block(
 		}
 	}
 
-	return ec.getParsedBlock(ctx, "eth_getBlockByNumber", toBlockNumArg(nil), true)
+	return ec.getParsedBlock(ctx, limit, "eth_getBlockByNumber", toBlockNumArg(nil), true)
 }
 
 // Header returns a block header from the current canonical chain. If number is
@@ -411,6 +689,7 @@ func (ec *Client) getUncles(
 
 func (ec *Client) getBlock(
 	ctx context.Context,
+	limit int,
 	blockMethod string,
 	args ...interface{},
 ) (
@@ -430,10 +709,10 @@ func (ec *Client) getBlock(
 	var head types.Header
 	var body rpcBlock
 	if err := json.Unmarshal(raw, &head); err != nil {
-		return nil, nil, err
+		return nil, nil, unconvertible(err)
 	}
 	if err := json.Unmarshal(raw, &body); err != nil {
-		return nil, nil, err
+		return nil, nil, unconvertible(err)
 	}
 
 	uncles, err := ec.getUncles(ctx, &head, &body)
@@ -441,8 +720,15 @@ func (ec *Client) getBlock(
 		return nil, nil, fmt.Errorf("%w: unable to get uncles", err)
 	}
 
+	// Only load the transactions that will be populated. The
+	// block reward transaction counts against limit.
+	loaded := body.Transactions
+	if limit > 0 && len(loaded) > limit-1 {
+		loaded = loaded[:limit-1]
+	}
+
 	// Get all transaction receipts
-	receipts, err := ec.getBlockReceipts(ctx, body.Hash, body.Transactions)
+	receipts, err := ec.getBlockReceipts(ctx, head.Number.Int64(), body.Hash, loaded)
 	if err != nil {
 		return nil, nil, fmt.Errorf("%w: could not get receipts for %x", err, body.Hash[:])
 	}
@@ -454,20 +740,45 @@ func (ec *Client) getBlock(
 	// concurrent traces that are computed to 16 to avoid overwhelming geth).
 	var traces []*rpcCall
 	var rawTraces []*rpcRawCall
+	var customTraces []json.RawMessage
 	var addTraces bool
-	if head.Number.Int64() != GenesisBlockIndex { // not possible to get traces at genesis
+	degraded := ec.degradedBlock(head.Number.Int64())
+	if head.Number.Int64() != GenesisBlockIndex && !degraded { // not possible to get traces at genesis
 		addTraces = true
-		traces, rawTraces, err = ec.getBlockTraces(ctx, body.Hash)
+		var rng hexutil.Bytes
+		if len(loaded) < len(body.Transactions) {
+			rng, err = rangeBlock(&head, loaded)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: could not encode transactions of %x", err, body.Hash[:])
+			}
+		}
+
+		traces, rawTraces, err = ec.traceBlock(ctx, body.Hash, rng, loaded)
 		if err != nil {
 			return nil, nil, fmt.Errorf("%w: could not get traces for %x", err, body.Hash[:])
 		}
+
+		if ec.customTracer != nil {
+			customTraces, err = ec.customTraces(ctx, body.Hash, rng, loaded)
+			var rpcErr rpc.Error
+			if errors.As(err, &rpcErr) {
+				// The node could not run the tracer on the block.
+				err = unconvertible(err)
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: could not get custom traces for %x", err, body.Hash[:])
+			}
+		}
 	}
 
 	// Convert all txs to loaded txs
 	txs := make([]*types.Transaction, len(body.Transactions))
-	loadedTxs := make([]*loadedTransaction, len(body.Transactions))
 	for i, tx := range body.Transactions {
 		txs[i] = tx.tx
+	}
+
+	loadedTxs := make([]*loadedTransaction, len(loaded))
+	for i, tx := range loaded {
 		receipt := receipts[i]
 		if err != nil {
 			return nil, nil, fmt.Errorf("%w: failure getting effective gas price", err)
@@ -475,14 +786,15 @@ func (ec *Client) getBlock(
 		loadedTxs[i] = tx.LoadedTransaction()
 		loadedTxs[i].Transaction = txs[i]
 
-		feeAmount, feeBurned, err := calculateGas(txs[i], receipt, head)
+		feeAmount, feeBurned, err := calculateGas(ec.p, txs[i], receipt, head)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, unconvertible(err)
 		}
 		loadedTxs[i].FeeAmount = feeAmount
 		loadedTxs[i].FeeBurned = feeBurned
 		loadedTxs[i].Miner = MustChecksum(head.Coinbase.Hex())
 		loadedTxs[i].Receipt = receipt
+		loadedTxs[i].Degraded = degraded
 
 		// Continue if calls does not exist (occurs at genesis)
 		if !addTraces {
@@ -491,45 +803,37 @@ func (ec *Client) getBlock(
 
 		loadedTxs[i].Trace = traces[i].Result
 		loadedTxs[i].RawTrace = rawTraces[i].Result
+		if customTraces != nil {
+			loadedTxs[i].CustomTrace = customTraces[i]
+		}
 	}
 
 	return types.NewBlockWithHeader(&head).WithBody(txs, uncles), loadedTxs, nil
 }
 
+// calculateGas returns the fee paid by tx and the portion of it that
+// was burned. It verifies that the gas limit of tx is at least its
+// intrinsic gas under the hardforks active at head. The gas used in
+// the receipt is not checked, as refunds can bring it below the
+// intrinsic gas.
 func calculateGas(
+	config *params.ChainConfig,
 	tx *types.Transaction,
 	txReceipt *types.Receipt,
 	head types.Header,
 ) (
 	*big.Int, *big.Int, error,
 ) {
-	gasUsed := new(big.Int).SetUint64(txReceipt.GasUsed)
-	gasPrice, err := effectiveGasPrice(tx, head.BaseFee)
-	if err != nil {
-		return nil, nil, fmt.Errorf("%w: failure getting effective gas price", err)
-	}
-	feeAmount := new(big.Int).Mul(gasUsed, gasPrice)
-	var feeBurned *big.Int
-	if head.BaseFee != nil { // EIP-1559
-		feeBurned = new(big.Int).Mul(gasUsed, head.BaseFee)
+	if err := fees.CheckGas(config, head.Number, tx, tx.Gas()); err != nil {
+		return nil, nil, err
 	}
 
-	return feeAmount, feeBurned, nil
-}
-
-// effectiveGasPrice returns the price of gas charged to this transaction to be included in the
-// block.
-func effectiveGasPrice(tx *EthTypes.Transaction, baseFee *big.Int) (*big.Int, error) {
-	if tx.Type() != eip1559TxType {
-		return tx.GasPrice(), nil
-	}
-	// For EIP-1559 the gas price is determined by the base fee & miner tip instead
-	// of the tx-specified gas price.
-	tip, err := tx.EffectiveGasTip(baseFee)
+	fee, err := fees.Calculate(tx, txReceipt.GasUsed, head.BaseFee)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return new(big.Int).Add(tip, baseFee), nil
+
+	return fee.Amount, fee.Burned, nil
 }
 
 func (ec *Client) getTransactionTraces(
@@ -559,6 +863,7 @@ func (ec *Client) getTransactionTraces(
 func (ec *Client) getBlockTraces(
 	ctx context.Context,
 	blockHash common.Hash,
+	rng hexutil.Bytes,
 ) ([]*rpcCall, []*rpcRawCall, error) {
 	if err := ec.traceSemaphore.Acquire(ctx, semaphoreTraceWeight); err != nil {
 		return nil, nil, err
@@ -568,7 +873,12 @@ func (ec *Client) getBlockTraces(
 	var calls []*rpcCall
 	var rawCalls []*rpcRawCall
 	var raw json.RawMessage
-	err := ec.c.CallContext(ctx, &raw, "debug_traceBlockByHash", blockHash, ec.tc)
+	var err error
+	if rng != nil {
+		err = ec.c.CallContext(ctx, &raw, "debug_traceBlock", rng, ec.tc)
+	} else {
+		err = ec.c.CallContext(ctx, &raw, "debug_traceBlockByHash", blockHash, ec.tc)
+	}
 	if err != nil {
 		return nil, nil, err
 	}
@@ -588,6 +898,7 @@ func (ec *Client) getBlockTraces(
 
 func (ec *Client) getBlockReceipts(
 	ctx context.Context,
+	blockNumber int64,
 	blockHash common.Hash,
 	txs []rpcTransaction,
 ) ([]*types.Receipt, error) {
@@ -596,6 +907,24 @@ func (ec *Client) getBlockReceipts(
 		return receipts, nil
 	}
 
+	if ec.receipts != nil && atomic.LoadInt32(&ec.graphQLUnavailable) == 0 {
+		receipts, err := ec.graphQLBlockReceipts(ctx, blockNumber, blockHash, txs)
+		if err == nil {
+			return receipts, nil
+		}
+
+		metrics.Counter(graphQLReceiptsFallbackMetric).Inc(1)
+		log.Printf(
+			"%s: unable to get receipts of block %s over GraphQL, falling back to JSON-RPC",
+			err.Error(),
+			blockHash.Hex(),
+		)
+	}
+
+	if receipts, ok, err := ec.blockReceiptsWithFallback(ctx, blockHash, txs); ok {
+		return receipts, err
+	}
+
 	reqs := make([]rpc.BatchElem, len(txs))
 	for i := range reqs {
 		reqs[i] = rpc.BatchElem{
@@ -645,7 +974,21 @@ type Call struct {
 	GasUsed      *big.Int       `json:"gasUsed"`
 	Revert       bool
 	ErrorMessage string  `json:"error"`
+	Input        []byte  `json:"input"`
+	Output       []byte  `json:"output"`
 	Calls        []*Call `json:"calls"`
+
+	// safe is populated if the call executes
+	// a Gnosis Safe transaction.
+	safe *SafeTransaction
+
+	// method is populated if the method executed by
+	// the call is known (see abiRegistry).
+	method *DecodedMethod
+
+	// callerReverted is true if the call succeeded
+	// but one of its callers reverted (see flattenTraces).
+	callerReverted bool
 }
 
 type flatCall struct {
@@ -656,17 +999,24 @@ type flatCall struct {
 	GasUsed      *big.Int       `json:"gasUsed"`
 	Revert       bool
 	ErrorMessage string `json:"error"`
+	Safe         *SafeTransaction
+	Method       *DecodedMethod
+
+	CallerReverted bool
 }
 
 func (t *Call) flatten() *flatCall {
 	return &flatCall{
-		Type:         t.Type,
-		From:         t.From,
-		To:           t.To,
-		Value:        t.Value,
-		GasUsed:      t.GasUsed,
-		Revert:       t.Revert,
-		ErrorMessage: t.ErrorMessage,
+		Type:           t.Type,
+		From:           t.From,
+		To:             t.To,
+		Value:          t.Value,
+		GasUsed:        t.GasUsed,
+		Revert:         t.Revert,
+		ErrorMessage:   t.ErrorMessage,
+		Safe:           t.safe,
+		Method:         t.method,
+		CallerReverted: t.callerReverted,
 	}
 }
 
@@ -679,8 +1029,10 @@ func (t *Call) UnmarshalJSON(input []byte) error {
 		Value        *hexutil.Big   `json:"value"`
 		GasUsed      *hexutil.Big   `json:"gasUsed"`
 		Revert       bool
-		ErrorMessage string  `json:"error"`
-		Calls        []*Call `json:"calls"`
+		ErrorMessage string        `json:"error"`
+		Input        hexutil.Bytes `json:"input"`
+		Output       hexutil.Bytes `json:"output"`
+		Calls        []*Call       `json:"calls"`
 	}
 	var dec CustomTrace
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -706,6 +1058,8 @@ func (t *Call) UnmarshalJSON(input []byte) error {
 		t.Revert = true
 	}
 	t.ErrorMessage = dec.ErrorMessage
+	t.Input = dec.Input
+	t.Output = dec.Output
 	t.Calls = dec.Calls
 	return nil
 }
@@ -719,8 +1073,13 @@ func flattenTraces(data *Call, flattened []*flatCall) []*flatCall {
 	results := append(flattened, data.flatten())
 	for _, child := range data.Calls {
 		// Ensure all children of a reverted call
-		// are also reverted!
+		// are also reverted! Children that did not
+		// fail themselves are marked, so their
+		// failure is attributed to their caller.
 		if data.Revert {
+			if !child.Revert {
+				child.callerReverted = true
+			}
 			child.Revert = true
 
 			// Copy error message from parent
@@ -737,8 +1096,13 @@ func flattenTraces(data *Call, flattened []*flatCall) []*flatCall {
 }
 
 // traceOps returns all *RosettaTypes.Operation for a given
-// array of flattened traces.
-func traceOps(calls []*flatCall, startIndex int) []*RosettaTypes.Operation { // nolint: gocognit
+// array of flattened traces. Zero value traces are emitted
+// as determined by zeroValueOps.
+func traceOps( // nolint: gocognit
+	calls []*flatCall,
+	startIndex int,
+	zeroValueOps ZeroValueOperations,
+) []*RosettaTypes.Operation {
 	var ops []*RosettaTypes.Operation
 	if len(calls) == 0 {
 		return ops
@@ -752,6 +1116,9 @@ func traceOps(calls []*flatCall, startIndex int) []*RosettaTypes.Operation { //
 		if trace.Revert {
 			opStatus = FailureStatus
 			metadata["error"] = trace.ErrorMessage
+			if trace.CallerReverted {
+				metadata[CallerRevertedMetadataKey] = true
+			}
 		}
 
 		var zeroValue bool
@@ -759,13 +1126,22 @@ func traceOps(calls []*flatCall, startIndex int) []*RosettaTypes.Operation { //
 			zeroValue = true
 		}
 
-		// Skip all 0 value CallType operations (TODO: make optional to include)
+		// Zero value operations are only included as configured
+		// by zeroValueOps. By default, the inner transaction of a
+		// Safe execution is always included so that the Safe is
+		// surfaced as the effective sender.
 		//
 		// We can't continue here because we may need to adjust our destroyed
 		// accounts map if a CallTYpe operation resurrects an account.
-		shouldAdd := true
-		if zeroValue && CallType(trace.Type) {
-			shouldAdd = false
+		shouldAdd := !zeroValue || zeroValueOps.includeZeroValue(trace.Type, trace.Safe != nil)
+		if trace.Safe != nil {
+			metadata[safeMetadataKey] = trace.Safe.metadata()
+		}
+		if trace.Method != nil {
+			metadata[MethodMetadataKey] = trace.Method.metadata()
+		}
+		if zeroValue && zeroValueOps == LabelZeroValueOperations {
+			metadata[subtypeMetadataKey] = ZeroValueSubtype
 		}
 
 		// Checksum addresses
@@ -782,10 +1158,7 @@ func traceOps(calls []*flatCall, startIndex int) []*RosettaTypes.Operation { //
 				Account: &RosettaTypes.AccountIdentifier{
 					Address: from,
 				},
-				Amount: &RosettaTypes.Amount{
-					Value:    new(big.Int).Neg(trace.Value).String(),
-					Currency: Currency,
-				},
+				Amount:   amount.NewNeg(trace.Value, Currency),
 				Metadata: metadata,
 			}
 			if zeroValue {
@@ -801,9 +1174,12 @@ func traceOps(calls []*flatCall, startIndex int) []*RosettaTypes.Operation { //
 		}
 
 		// Add to destroyed accounts if SELFDESTRUCT
-		// and overwrite existing balance.
+		// and overwrite existing balance. A reverted
+		// SELFDESTRUCT does not destroy the account.
 		if trace.Type == SelfDestructOpType {
-			destroyedAccounts[from] = new(big.Int)
+			if opStatus == SuccessStatus {
+				destroyedAccounts[from] = new(big.Int)
+			}
 
 			// If destination of of SELFDESTRUCT is self,
 			// we should skip. In the EVM, the balance is reset
@@ -814,16 +1190,17 @@ func traceOps(calls []*flatCall, startIndex int) []*RosettaTypes.Operation { //
 			}
 		}
 
-		// Skip empty to addresses (this may not
-		// actually occur but leaving it as a
-		// sanity check)
-		if len(trace.To.String()) == 0 {
+		// Skip empty to addresses. The tracer omits the
+		// to address of a failed CREATE or CREATE2, so
+		// there is no created account to credit.
+		if trace.To == (common.Address{}) && CreateType(trace.Type) {
 			continue
 		}
 
 		// If the account is resurrected, we remove it from
-		// the destroyed accounts map.
-		if CreateType(trace.Type) {
+		// the destroyed accounts map. A failed CREATE does
+		// not resurrect the account.
+		if CreateType(trace.Type) && opStatus == SuccessStatus {
 			delete(destroyedAccounts, to)
 		}
 
@@ -843,10 +1220,7 @@ func traceOps(calls []*flatCall, startIndex int) []*RosettaTypes.Operation { //
 				Account: &RosettaTypes.AccountIdentifier{
 					Address: to,
 				},
-				Amount: &RosettaTypes.Amount{
-					Value:    trace.Value.String(),
-					Currency: Currency,
-				},
+				Amount:   amount.New(trace.Value, Currency),
 				Metadata: metadata,
 			}
 			if zeroValue {
@@ -882,10 +1256,7 @@ func traceOps(calls []*flatCall, startIndex int) []*RosettaTypes.Operation { //
 			Account: &RosettaTypes.AccountIdentifier{
 				Address: acct,
 			},
-			Amount: &RosettaTypes.Amount{
-				Value:    new(big.Int).Neg(val).String(),
-				Currency: Currency,
-			},
+			Amount: amount.NewNeg(val, Currency),
 		})
 	}
 
@@ -933,9 +1304,19 @@ type loadedTransaction struct {
 	Trace    *Call
 	RawTrace json.RawMessage
 	Receipt  *types.Receipt
+
+	// CustomTrace is the output of the custom
+	// tracer, if one is configured.
+	CustomTrace json.RawMessage
+
+	// Degraded is set when the transaction is in a block
+	// that is too old to be traced (see degradedBlock).
+	Degraded bool
 }
 
 func feeOps(tx *loadedTransaction) []*RosettaTypes.Operation {
+	payer := transactionFeePayer(tx)
+
 	var minerEarnedAmount *big.Int
 	if tx.FeeBurned == nil {
 		minerEarnedAmount = tx.FeeAmount
@@ -950,12 +1331,9 @@ func feeOps(tx *loadedTransaction) []*RosettaTypes.Operation {
 			Type:   FeeOpType,
 			Status: RosettaTypes.String(SuccessStatus),
 			Account: &RosettaTypes.AccountIdentifier{
-				Address: MustChecksum(tx.From.String()),
-			},
-			Amount: &RosettaTypes.Amount{
-				Value:    new(big.Int).Neg(minerEarnedAmount).String(),
-				Currency: Currency,
+				Address: payer.Address,
 			},
+			Amount: amount.NewNeg(minerEarnedAmount, Currency),
 		},
 
 		{
@@ -972,10 +1350,7 @@ func feeOps(tx *loadedTransaction) []*RosettaTypes.Operation {
 			Account: &RosettaTypes.AccountIdentifier{
 				Address: MustChecksum(tx.Miner),
 			},
-			Amount: &RosettaTypes.Amount{
-				Value:    minerEarnedAmount.String(),
-				Currency: Currency,
-			},
+			Amount: amount.New(minerEarnedAmount, Currency),
 		},
 	}
 	if tx.FeeBurned == nil {
@@ -988,12 +1363,9 @@ func feeOps(tx *loadedTransaction) []*RosettaTypes.Operation {
 		Type:   FeeOpType,
 		Status: RosettaTypes.String(SuccessStatus),
 		Account: &RosettaTypes.AccountIdentifier{
-			Address: MustChecksum(tx.From.String()),
-		},
-		Amount: &RosettaTypes.Amount{
-			Value:    new(big.Int).Neg(tx.FeeBurned).String(),
-			Currency: Currency,
+			Address: payer.Address,
 		},
+		Amount: amount.NewNeg(tx.FeeBurned, Currency),
 	}
 	return append(ops, burntOp)
 }
@@ -1043,21 +1415,18 @@ func (ec *Client) contractCall(
 	ctx context.Context,
 	params map[string]interface{},
 ) (map[string]interface{}, error) {
+	if isMulticall(params) {
+		return ec.multicall(ctx, params)
+	}
+
 	// validate call input
 	input, err := validateCallInput(params)
 	if err != nil {
 		return nil, err
 	}
 
-	// default query
-	blockQuery := "latest"
-
-	// if block number or hash, override blockQuery
-	if input.BlockIndex > int64(0) {
-		blockQuery = toBlockNumArg(big.NewInt(input.BlockIndex))
-	} else if len(input.BlockHash) > 0 {
-		blockQuery = input.BlockHash
-	}
+	// if block number or hash, override the default "latest" query
+	blockQuery := callBlockQuery(input.BlockIndex, input.BlockHash)
 
 	// ensure valid contract address
 	_, ok := ChecksumAddress(input.To)
@@ -1071,8 +1440,13 @@ func (ec *Client) contractCall(
 		"data": input.Data,
 	}
 
+	args := []interface{}{callParams, blockQuery}
+	if len(input.StateOverrides) > 0 {
+		args = append(args, input.StateOverrides)
+	}
+
 	var resp string
-	if err := ec.c.CallContext(ctx, &resp, "eth_call", callParams, blockQuery); err != nil {
+	if err := ec.c.CallContext(ctx, &resp, "eth_call", args...); err != nil {
 		return nil, err
 	}
 
@@ -1111,8 +1485,15 @@ func (ec *Client) estimateGas(
 		"data": input.Data,
 	}
 
+	// State overrides are only supported by nodes that accept
+	// a block and an override set after the call parameters.
+	args := []interface{}{estimateGasParams}
+	if len(input.StateOverrides) > 0 {
+		args = append(args, toBlockNumArg(nil), input.StateOverrides)
+	}
+
 	var resp string
-	if err := ec.c.CallContext(ctx, &resp, "eth_estimateGas", estimateGasParams); err != nil {
+	if err := ec.c.CallContext(ctx, &resp, "eth_estimateGas", args...); err != nil {
 		return nil, err
 	}
 
@@ -1123,9 +1504,11 @@ func (ec *Client) estimateGas(
 
 func validateCallInput(params map[string]interface{}) (*GetCallInput, error) {
 	var input GetCallInput
-	if err := RosettaTypes.UnmarshalMap(params, &input); err != nil {
-		return nil, fmt.Errorf("%w: %s", ErrCallParametersInvalid, err.Error())
+	overrides, err := decodeCallParams(params, &input)
+	if err != nil {
+		return nil, err
 	}
+	input.StateOverrides = overrides
 
 	// to address is required for call requests
 	if len(input.To) == 0 {
@@ -1138,17 +1521,53 @@ func validateCallInput(params map[string]interface{}) (*GetCallInput, error) {
 	return &input, nil
 }
 
+// decodeCallParams decodes params into input and returns
+// the state overrides in params. State overrides are keyed
+// by address and hex-encoded, which RosettaTypes.UnmarshalMap
+// cannot decode, so they are decoded separately as JSON.
+func decodeCallParams(params map[string]interface{}, input interface{}) (StateOverride, error) {
+	callParams := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		if k != stateOverridesKey {
+			callParams[k] = v
+		}
+	}
+
+	if err := RosettaTypes.UnmarshalMap(callParams, input); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCallParametersInvalid, err.Error())
+	}
+
+	overrides, ok := params[stateOverridesKey]
+	if !ok {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(overrides)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCallParametersInvalid, err.Error())
+	}
+
+	var stateOverrides StateOverride
+	if err := json.Unmarshal(raw, &stateOverrides); err != nil {
+		return nil, fmt.Errorf("%w: invalid state overrides: %s", ErrCallParametersInvalid, err.Error())
+	}
+
+	return stateOverrides, nil
+}
+
 func (ec *Client) getParsedBlock(
 	ctx context.Context,
+	limit int,
 	blockMethod string,
 	args ...interface{},
 ) (
 	*RosettaTypes.Block,
+	[]*RosettaTypes.TransactionIdentifier,
 	error,
 ) {
-	block, loadedTransactions, err := ec.getBlock(ctx, blockMethod, args...)
+	block, loadedTransactions, err := ec.getBlock(ctx, limit, blockMethod, args...)
 	if err != nil {
-		return nil, fmt.Errorf("%w: could not get block", err)
+		return nil, nil, fmt.Errorf("%w: could not get block", err)
 	}
 
 	blockIdentifier := &RosettaTypes.BlockIdentifier{
@@ -1166,15 +1585,63 @@ func (ec *Client) getParsedBlock(
 
 	txs, err := ec.populateTransactions(blockIdentifier, block, loadedTransactions)
 	if err != nil {
-		return nil, err
+		return nil, nil, unconvertible(err)
+	}
+
+	if err := ec.attributeRewards(ctx, block, txs); err != nil {
+		return nil, nil, fmt.Errorf("%w: unable to attribute rewards", err)
+	}
+
+	var otherTransactions []*RosettaTypes.TransactionIdentifier
+	for _, tx := range block.Transactions()[len(loadedTransactions):] {
+		otherTransactions = append(otherTransactions, &RosettaTypes.TransactionIdentifier{
+			Hash: tx.Hash().Hex(),
+		})
+	}
+
+	// A partial block cannot balance.
+	if ec.checkInvariants && len(otherTransactions) == 0 {
+		if err := checkInvariant(blockIdentifier, txs, loadedTransactions); err != nil {
+			return nil, nil, unconvertible(err)
+		}
 	}
 
-	return &RosettaTypes.Block{
+	metadata, err := ec.roundMetadata(ctx, block)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: unable to get round metadata", err)
+	}
+	if ec.degradedBlock(blockIdentifier.Index) {
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+		metadata[DegradedMetadataKey] = true
+	}
+
+	rosettaBlock := &RosettaTypes.Block{
 		BlockIdentifier:       blockIdentifier,
 		ParentBlockIdentifier: parentBlockIdentifier,
 		Timestamp:             convertTime(block.Time()),
 		Transactions:          txs,
-	}, nil
+		Metadata:              metadata,
+	}
+
+	if err := ec.transformBlock(rosettaBlock, block); err != nil {
+		return nil, nil, err
+	}
+
+	if ec.balances != nil {
+		ec.balances.observe(blockIdentifier)
+	}
+
+	// The digest must cover every transaction, so
+	// partial blocks do not have one.
+	if len(otherTransactions) == 0 {
+		if err := addBlockDigest(rosettaBlock); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return rosettaBlock, otherTransactions, nil
 }
 
 func convertTime(time uint64) int64 {
@@ -1188,7 +1655,7 @@ func (ec *Client) populateTransactions(
 ) ([]*RosettaTypes.Transaction, error) {
 	transactions := make(
 		[]*RosettaTypes.Transaction,
-		len(block.Transactions())+1, // include reward tx
+		len(loadedTransactions)+1, // include reward tx
 	)
 
 	// Compute reward transaction (block + uncle reward)
@@ -1199,9 +1666,7 @@ func (ec *Client) populateTransactions(
 	)
 
 	for i, tx := range loadedTransactions {
-		transaction, err := ec.populateTransaction(
-			tx,
-		)
+		transaction, err := ec.convertTransaction(tx)
 		if err != nil {
 			return nil, fmt.Errorf("%w: cannot parse %s", err, tx.Transaction.Hash().Hex())
 		}
@@ -1221,11 +1686,50 @@ func (ec *Client) populateTransaction(
 	feeOps := feeOps(tx)
 	ops = append(ops, feeOps...)
 
-	// Compute trace operations
-	traces := flattenTraces(tx.Trace, []*flatCall{})
+	// In filtered block mode, skip decoding the trace of
+	// transactions that cannot touch a watched address.
+	filtered := ec.watchlist != nil && !ec.watchlist.mayTouch(tx)
+	traced := tx.Trace != nil
+	if !filtered && traced {
+		// Compute trace operations
+		markSafeExecutions(tx.Trace)
+		ec.abiRegistry.decodeCalls(tx.Trace)
+		traces := flattenTraces(tx.Trace, []*flatCall{})
+
+		traceOps := traceOps(traces, len(ops), ec.zeroValueOperations)
+		if ec.collapseOperations {
+			traceOps = collapseOps(traceOps, len(ops))
+		}
+		traceOps = filterDust(traceOps, len(ops), ec.dustThreshold)
+		ops = append(ops, traceOps...)
+
+		// Compute approval operations
+		if ec.emitApprovals {
+			ops = append(ops, approvalOps(tx.Receipt, len(ops))...)
+		}
+	} else if !filtered && tx.Degraded {
+		// Only the external call of a degraded
+		// transaction is known.
+		traces := flattenTraces(externalCall(tx), []*flatCall{})
+		traceOps := traceOps(traces, len(ops), ec.zeroValueOperations)
+		ops = append(ops, filterDust(traceOps, len(ops), ec.dustThreshold)...)
+	}
 
-	traceOps := traceOps(traces, len(ops))
-	ops = append(ops, traceOps...)
+	// Compute user operations of ERC-4337 bundles
+	var userOps []*RosettaTypes.Operation
+	if !filtered {
+		userOps = ec.userOperationOps(tx, len(ops))
+		ops = append(ops, userOps...)
+
+		customOps, err := ec.customTraceOps(tx, len(ops))
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, customOps...)
+	}
+
+	// Label Foundation and treasury flows
+	labelOperations(ops)
 
 	// Marshal receipt and trace data
 	// TODO: replace with marshalJSONMap (used in `services`)
@@ -1239,11 +1743,6 @@ func (ec *Client) populateTransaction(
 		return nil, err
 	}
 
-	var traceMap map[string]interface{}
-	if err := json.Unmarshal(tx.RawTrace, &traceMap); err != nil {
-		return nil, err
-	}
-
 	populatedTransaction := &RosettaTypes.Transaction{
 		TransactionIdentifier: &RosettaTypes.TransactionIdentifier{
 			Hash: tx.Transaction.Hash().Hex(),
@@ -1253,10 +1752,57 @@ func (ec *Client) populateTransaction(
 			"gas_limit": hexutil.EncodeUint64(tx.Transaction.Gas()),
 			"gas_price": hexutil.EncodeBig(tx.Transaction.GasPrice()),
 			"receipt":   receiptMap,
-			"trace":     traceMap,
 		},
 	}
 
+	// The gas of a bundle is paid by the bundler
+	// and refunded by the EntryPoint.
+	if len(userOps) > 0 {
+		payer := transactionFeePayer(tx)
+		payer.Type = BundlerFeePayer
+		populatedTransaction.Metadata[FeePayerMetadataKey] = payer
+	}
+
+	if tx.Transaction.To() != nil {
+		if method, ok := ec.abiRegistry.decode(tx.Transaction.Data(), false); ok {
+			populatedTransaction.Metadata[MethodMetadataKey] = method.metadata()
+		}
+	}
+
+	// The trace is fetched even if it is not decoded,
+	// so failures can always be explained.
+	reasonMap, err := failureReasonMetadata(tx)
+	if err != nil {
+		return nil, err
+	}
+	if reasonMap != nil {
+		populatedTransaction.Metadata[FailureReasonMetadataKey] = reasonMap
+	}
+
+	if filtered {
+		populatedTransaction.Metadata["filtered"] = true
+		return populatedTransaction, nil
+	}
+
+	if tx.CustomTrace != nil {
+		var customTrace interface{}
+		if err := json.Unmarshal(tx.CustomTrace, &customTrace); err != nil {
+			return nil, err
+		}
+		populatedTransaction.Metadata[CustomTraceMetadataKey] = customTrace
+	}
+
+	if !traced {
+		populatedTransaction.Metadata[TraceUnavailableMetadataKey] = true
+		return populatedTransaction, nil
+	}
+
+	var traceMap map[string]interface{}
+	if err := json.Unmarshal(tx.RawTrace, &traceMap); err != nil {
+		return nil, err
+	}
+	populatedTransaction.Metadata["trace"] = traceMap
+
 	return populatedTransaction, nil
 }
 
@@ -1280,14 +1826,12 @@ func (ec *Client) blockRewardTransaction(
 	miningReward := ec.miningReward(big.NewInt(blockIdentifier.Index))
 
 	// Calculate miner rewards
-	minerReward := miningReward
-	numUncles := len(uncles)
+	minerReward := big.NewInt(miningReward)
 	if len(uncles) > 0 {
-		reward := new(big.Float)
-		uncleReward := float64(numUncles) / UnclesRewardMultiplier
-		rewardFloat := reward.Mul(big.NewFloat(uncleReward), big.NewFloat(float64(miningReward)))
-		rewardInt, _ := rewardFloat.Int64()
-		minerReward += rewardInt
+		// The miner earns 1/32 of the mining reward
+		// for every uncle it includes.
+		uncleReward := new(big.Int).Mul(big.NewInt(miningReward), big.NewInt(int64(len(uncles))))
+		minerReward.Add(minerReward, uncleReward.Div(uncleReward, big.NewInt(UnclesRewardMultiplier)))
 	}
 
 	miningRewardOp := &RosettaTypes.Operation{
@@ -1299,10 +1843,7 @@ func (ec *Client) blockRewardTransaction(
 		Account: &RosettaTypes.AccountIdentifier{
 			Address: MustChecksum(miner),
 		},
-		Amount: &RosettaTypes.Amount{
-			Value:    strconv.FormatInt(minerReward, 10),
-			Currency: Currency,
-		},
+		Amount: amount.New(minerReward, Currency),
 	}
 	ops = append(ops, miningRewardOp)
 
@@ -1326,10 +1867,7 @@ func (ec *Client) blockRewardTransaction(
 			Account: &RosettaTypes.AccountIdentifier{
 				Address: MustChecksum(uncleMiner),
 			},
-			Amount: &RosettaTypes.Amount{
-				Value:    uncleRewardBlock.String(),
-				Currency: Currency,
-			},
+			Amount: amount.New(uncleRewardBlock, Currency),
 		}
 		ops = append(ops, uncleRewardOp)
 	}
@@ -1407,6 +1945,19 @@ func (ec *Client) Balance(
 	account *RosettaTypes.AccountIdentifier,
 	block *RosettaTypes.PartialBlockIdentifier,
 ) (*RosettaTypes.AccountBalanceResponse, error) {
+	if block == nil || (block.Hash == nil && block.Index == nil) {
+		if err := ec.checkStaleRead(); err != nil {
+			return nil, err
+		}
+	}
+
+	address := strings.ToLower(account.Address)
+	if ec.balances != nil && block != nil && block.Hash != nil {
+		if cached := ec.balances.get(address, *block.Hash); cached != nil {
+			return cached, nil
+		}
+	}
+
 	blockQuery := ""
 	if block != nil {
 		if block.Hash != nil {
@@ -1441,37 +1992,47 @@ func (ec *Client) Balance(
 		return nil, errors.New(RosettaTypes.PrintStruct(bal.Errors))
 	}
 
-	balance, ok := new(big.Int).SetString(bal.Data.Block.Account.Balance[2:], 16)
-	if !ok {
+	balance, err := amount.ParseHex(bal.Data.Block.Account.Balance)
+	if err != nil {
 		return nil, fmt.Errorf(
-			"could not extract account balance from %s",
+			"%w: could not extract account balance from %s",
+			err,
 			bal.Data.Block.Account.Balance,
 		)
 	}
-	nonce, ok := new(big.Int).SetString(bal.Data.Block.Account.Nonce[2:], 16)
-	if !ok {
+	nonce, err := hexutil.DecodeUint64(bal.Data.Block.Account.Nonce)
+	if err != nil {
 		return nil, fmt.Errorf(
-			"could not extract account nonce from %s",
+			"%w: could not extract account nonce from %s",
+			err,
 			bal.Data.Block.Account.Nonce,
 		)
 	}
 
-	return &RosettaTypes.AccountBalanceResponse{
+	response := &RosettaTypes.AccountBalanceResponse{
 		Balances: []*RosettaTypes.Amount{
-			{
-				Value:    balance.String(),
-				Currency: Currency,
-			},
+			amount.New(balance, Currency),
 		},
 		BlockIdentifier: &RosettaTypes.BlockIdentifier{
 			Hash:  bal.Data.Block.Hash,
 			Index: bal.Data.Block.Number,
 		},
 		Metadata: map[string]interface{}{
-			"nonce": nonce.Int64(),
+			"nonce": int64(nonce),
 			"code":  bal.Data.Block.Account.Code,
 		},
-	}, nil
+	}
+
+	// Precompiles have no code, so they are
+	// labeled to tell them apart from EOAs.
+	if name, ok := Precompile(common.HexToAddress(account.Address)); ok {
+		response.Metadata["precompile"] = name
+	}
+	if ec.balances != nil {
+		ec.balances.put(address, response)
+	}
+
+	return response, nil
 }
 
 // GetBlockByNumberInput is the input to the call
@@ -1498,6 +2059,28 @@ type GetCallInput struct {
 	GasPrice   int64  `json:"gas_price"`
 	Value      int64  `json:"value"`
 	Data       string `json:"data"`
+
+	// StateOverrides are applied to the state before
+	// executing "eth_call" and "eth_estimateGas".
+	StateOverrides StateOverride `json:"state_overrides,omitempty"`
+}
+
+// stateOverridesKey is the call parameter
+// holding the state overrides.
+const stateOverridesKey = "state_overrides"
+
+// StateOverride is the set of account overrides accepted by
+// "eth_call" and "eth_estimateGas" (i.e. to fund an account
+// that will only be funded right before it transacts).
+type StateOverride map[common.Address]OverrideAccount
+
+// OverrideAccount overrides the state of a single account.
+type OverrideAccount struct {
+	Nonce     *hexutil.Uint64             `json:"nonce,omitempty"`
+	Code      *hexutil.Bytes              `json:"code,omitempty"`
+	Balance   *hexutil.Big                `json:"balance,omitempty"`
+	State     map[common.Hash]common.Hash `json:"state,omitempty"`
+	StateDiff map[common.Hash]common.Hash `json:"stateDiff,omitempty"`
 }
 
 // Call handles calls to the /call endpoint.
@@ -1566,6 +2149,78 @@ func (ec *Client) Call(
 			return nil, err
 		}
 
+		return &RosettaTypes.CallResponse{
+			Result: resp,
+		}, nil
+	case RewardScheduleMethod:
+		resp, err := ec.rewardSchedule(ctx, request.Parameters)
+		if err != nil {
+			return nil, err
+		}
+
+		return &RosettaTypes.CallResponse{
+			Result: resp,
+		}, nil
+	case DecodeTransactionMethod:
+		resp, err := ec.decodeTransaction(request.Parameters)
+		if err != nil {
+			return nil, err
+		}
+
+		return &RosettaTypes.CallResponse{
+			Result: resp,
+		}, nil
+	case ValidatorSetMethod:
+		resp, err := ec.validatorSetCall(ctx, request.Parameters)
+		if err != nil {
+			return nil, err
+		}
+
+		return &RosettaTypes.CallResponse{
+			Result: resp,
+		}, nil
+	case ValidatorStakeMethod:
+		resp, err := ec.validatorStakeCall(ctx, request.Parameters)
+		if err != nil {
+			return nil, err
+		}
+
+		return &RosettaTypes.CallResponse{
+			Result: resp,
+		}, nil
+	case ValidatorAPRInputsMethod:
+		resp, err := ec.validatorAPRInputsCall(ctx, request.Parameters)
+		if err != nil {
+			return nil, err
+		}
+
+		return &RosettaTypes.CallResponse{
+			Result: resp,
+		}, nil
+	case DelegatorRewardsMethod:
+		resp, err := ec.delegatorRewardsCall(ctx, request.Parameters)
+		if err != nil {
+			return nil, err
+		}
+
+		return &RosettaTypes.CallResponse{
+			Result: resp,
+		}, nil
+	case TransactionStatusMethod:
+		resp, err := ec.transactionStatus(ctx, request.Parameters)
+		if err != nil {
+			return nil, err
+		}
+
+		return &RosettaTypes.CallResponse{
+			Result: resp,
+		}, nil
+	case InclusionProofMethod:
+		resp, err := ec.inclusionProof(ctx, request.Parameters)
+		if err != nil {
+			return nil, err
+		}
+
 		return &RosettaTypes.CallResponse{
 			Result: resp,
 		}, nil
@@ -15,27 +15,46 @@
 package ethereum
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/coinbase/rosetta-ethereum/metrics"
 	mocks "github.com/coinbase/rosetta-ethereum/mocks/ethereum"
 
 	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
 	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"golang.org/x/sync/semaphore"
@@ -155,9 +174,14 @@ func TestStatus_NotSyncing(t *testing.T) {
 					"eth/64",
 					"eth/65",
 				},
-				"enode": "enode://5654cc39fd278c994c451434dfa7b1a44977c52018a87e911368b54daf795955d5a2dc2ece98be5a7e8d0eb245c8ef573c92e04e8b15363f9c713a8127fe7c7b@35.183.116.112:57510", // nolint
-				"enr":   "",
-				"name":  "Geth/v1.9.22-stable-c71a7e26/linux-amd64/go1.15",
+				"enode":          "enode://5654cc39fd278c994c451434dfa7b1a44977c52018a87e911368b54daf795955d5a2dc2ece98be5a7e8d0eb245c8ef573c92e04e8b15363f9c713a8127fe7c7b@35.183.116.112:57510", // nolint
+				"enr":            "",
+				"name":           "Geth/v1.9.22-stable-c71a7e26/linux-amd64/go1.15",
+				"local_address":  "172.31.2.163:30303",
+				"remote_address": "35.183.116.112:57510",
+				"inbound":        true,
+				"trusted":        false,
+				"static":         false,
 				"protocols": map[string]interface{}{
 					"eth": map[string]interface{}{
 						"difficulty": float64(31779242235308530),
@@ -175,9 +199,14 @@ func TestStatus_NotSyncing(t *testing.T) {
 					"eth/64",
 					"eth/65",
 				},
-				"enode": "enode://bead1278155bfabdd51f04a6e896356da2f5687aa1f550bebc540828579522b87e22c67edf90efa651582e40c8c8037eb0f998208cab4a69b52c5e3387671b59@174.129.122.13:30303",                                                    // nolint
-				"enr":   "enr:-Je4QICGSLfIHa7vX3bdWnKqWIS7YwmLUP6JVqU5nBhxPpH_X_Uz1pZwVS8a48uESHay1nvz9FtxLYFftpMr3wvFZJ4Qg2V0aMfGhGcn75CAgmlkgnY0gmlwhK6Beg2Jc2VjcDI1NmsxoQO-rRJ4FVv6vdUfBKboljVtovVoeqH1UL68VAgoV5UiuIN0Y3CCdl-DdWRwgnZf", // nolint
-				"name":  "Geth/v1.9.15-omnibus-75eb5240/linux-amd64/go1.14.4",
+				"enode":          "enode://bead1278155bfabdd51f04a6e896356da2f5687aa1f550bebc540828579522b87e22c67edf90efa651582e40c8c8037eb0f998208cab4a69b52c5e3387671b59@174.129.122.13:30303",                                                    // nolint
+				"enr":            "enr:-Je4QICGSLfIHa7vX3bdWnKqWIS7YwmLUP6JVqU5nBhxPpH_X_Uz1pZwVS8a48uESHay1nvz9FtxLYFftpMr3wvFZJ4Qg2V0aMfGhGcn75CAgmlkgnY0gmlwhK6Beg2Jc2VjcDI1NmsxoQO-rRJ4FVv6vdUfBKboljVtovVoeqH1UL68VAgoV5UiuIN0Y3CCdl-DdWRwgnZf", // nolint
+				"name":           "Geth/v1.9.15-omnibus-75eb5240/linux-amd64/go1.14.4",
+				"local_address":  "172.31.2.163:37908",
+				"remote_address": "174.129.122.13:30303",
+				"inbound":        false,
+				"trusted":        false,
+				"static":         false,
 				"protocols": map[string]interface{}{
 					"eth": map[string]interface{}{
 						"difficulty": float64(31779248439556308),
@@ -271,6 +300,51 @@ func TestStatus_NotSyncing_SkipAdminCalls(t *testing.T) {
 	mockGraphQL.AssertExpectations(t)
 }
 
+// jsonRPCError is an rpc.Error returned by the node.
+type jsonRPCError struct {
+	code    int
+	message string
+}
+
+func (e *jsonRPCError) Error() string  { return e.message }
+func (e *jsonRPCError) ErrorCode() int { return e.code }
+
+func TestPeers_AdminUnavailable(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	c := &Client{c: mockJSONRPC}
+	ctx := context.Background()
+
+	// Transient failures are retried
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"admin_peers",
+	).Return(
+		errors.New("connection reset"),
+	).Once()
+	peers, err := c.peers(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []*RosettaTypes.Peer{}, peers)
+
+	// Nodes without the admin namespace are only called once
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"admin_peers",
+	).Return(
+		&jsonRPCError{code: -32601, message: "the method admin_peers does not exist/is not available"},
+	).Once()
+	for i := 0; i < 2; i++ {
+		peers, err = c.peers(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []*RosettaTypes.Peer{}, peers)
+	}
+
+	mockJSONRPC.AssertExpectations(t)
+}
+
 func TestStatus_Syncing(t *testing.T) {
 	mockJSONRPC := &mocks.JSONRPC{}
 	mockGraphQL := &mocks.GraphQL{}
@@ -357,9 +431,14 @@ func TestStatus_Syncing(t *testing.T) {
 					"eth/64",
 					"eth/65",
 				},
-				"enode": "enode://5654cc39fd278c994c451434dfa7b1a44977c52018a87e911368b54daf795955d5a2dc2ece98be5a7e8d0eb245c8ef573c92e04e8b15363f9c713a8127fe7c7b@35.183.116.112:57510", // nolint
-				"enr":   "",
-				"name":  "Geth/v1.9.22-stable-c71a7e26/linux-amd64/go1.15",
+				"enode":          "enode://5654cc39fd278c994c451434dfa7b1a44977c52018a87e911368b54daf795955d5a2dc2ece98be5a7e8d0eb245c8ef573c92e04e8b15363f9c713a8127fe7c7b@35.183.116.112:57510", // nolint
+				"enr":            "",
+				"name":           "Geth/v1.9.22-stable-c71a7e26/linux-amd64/go1.15",
+				"local_address":  "172.31.2.163:30303",
+				"remote_address": "35.183.116.112:57510",
+				"inbound":        true,
+				"trusted":        false,
+				"static":         false,
 				"protocols": map[string]interface{}{
 					"eth": map[string]interface{}{
 						"difficulty": float64(31779242235308530),
@@ -377,9 +456,14 @@ func TestStatus_Syncing(t *testing.T) {
 					"eth/64",
 					"eth/65",
 				},
-				"enode": "enode://bead1278155bfabdd51f04a6e896356da2f5687aa1f550bebc540828579522b87e22c67edf90efa651582e40c8c8037eb0f998208cab4a69b52c5e3387671b59@174.129.122.13:30303",                                                    // nolint
-				"enr":   "enr:-Je4QICGSLfIHa7vX3bdWnKqWIS7YwmLUP6JVqU5nBhxPpH_X_Uz1pZwVS8a48uESHay1nvz9FtxLYFftpMr3wvFZJ4Qg2V0aMfGhGcn75CAgmlkgnY0gmlwhK6Beg2Jc2VjcDI1NmsxoQO-rRJ4FVv6vdUfBKboljVtovVoeqH1UL68VAgoV5UiuIN0Y3CCdl-DdWRwgnZf", // nolint
-				"name":  "Geth/v1.9.15-omnibus-75eb5240/linux-amd64/go1.14.4",
+				"enode":          "enode://bead1278155bfabdd51f04a6e896356da2f5687aa1f550bebc540828579522b87e22c67edf90efa651582e40c8c8037eb0f998208cab4a69b52c5e3387671b59@174.129.122.13:30303",                                                    // nolint
+				"enr":            "enr:-Je4QICGSLfIHa7vX3bdWnKqWIS7YwmLUP6JVqU5nBhxPpH_X_Uz1pZwVS8a48uESHay1nvz9FtxLYFftpMr3wvFZJ4Qg2V0aMfGhGcn75CAgmlkgnY0gmlwhK6Beg2Jc2VjcDI1NmsxoQO-rRJ4FVv6vdUfBKboljVtovVoeqH1UL68VAgoV5UiuIN0Y3CCdl-DdWRwgnZf", // nolint
+				"name":           "Geth/v1.9.15-omnibus-75eb5240/linux-amd64/go1.14.4",
+				"local_address":  "172.31.2.163:37908",
+				"remote_address": "174.129.122.13:30303",
+				"inbound":        false,
+				"trusted":        false,
+				"static":         false,
 				"protocols": map[string]interface{}{
 					"eth": map[string]interface{}{
 						"difficulty": float64(31779248439556308),
@@ -674,6 +758,109 @@ func TestBalance_Historical_Index(t *testing.T) {
 	mockGraphQL.AssertExpectations(t)
 }
 
+func TestBalance_Cache(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	mockGraphQL := &mocks.GraphQL{}
+
+	c := &Client{
+		c:              mockJSONRPC,
+		g:              mockGraphQL,
+		traceSemaphore: semaphore.NewWeighted(100),
+		balances:       newBalanceCache(10),
+	}
+
+	ctx := context.Background()
+	result, err := ioutil.ReadFile(
+		"testdata/account_balance_0x4cfc400fed52f9681b42454c2db4b18ab98f8de1.json",
+	)
+	assert.NoError(t, err)
+	mockGraphQL.On(
+		"Query",
+		ctx,
+		`{
+			block(hash: "0x9999286598edf07606228ba0233736e544a086a8822c61f9db3706887fc25dda"){
+				hash
+				number
+				account(address:"0x2f93B2f047E05cdf602820Ac4B3178efc2b43D55"){
+					balance
+					transactionCount
+					code
+				}
+			}
+		}`,
+	).Return(
+		string(result),
+		nil,
+	).Twice()
+
+	account := &RosettaTypes.AccountIdentifier{
+		Address: "0x2f93B2f047E05cdf602820Ac4B3178efc2b43D55",
+	}
+	block := &RosettaTypes.PartialBlockIdentifier{
+		Hash: RosettaTypes.String(
+			"0x9999286598edf07606228ba0233736e544a086a8822c61f9db3706887fc25dda",
+		),
+	}
+	resp, err := c.Balance(ctx, account, block)
+	assert.NoError(t, err)
+	resp.Metadata["staked_balance"] = "1"
+
+	// The second request is served from the cache and is
+	// not affected by changes to the first response.
+	cached, err := c.Balance(ctx, &RosettaTypes.AccountIdentifier{
+		Address: "0x2f93b2f047e05cdf602820ac4b3178efc2b43d55",
+	}, block)
+	assert.NoError(t, err)
+	assert.Equal(t, "10372550232136640000000", cached.Balances[0].Value)
+	assert.Equal(t, map[string]interface{}{
+		"code":  "0x",
+		"nonce": int64(0),
+	}, cached.Metadata)
+
+	// Fetching the canonical block at the same index
+	// does not evict the entry.
+	c.balances.observe(&RosettaTypes.BlockIdentifier{
+		Hash:  "0x9999286598edf07606228ba0233736e544a086a8822c61f9db3706887fc25dda",
+		Index: 8165,
+	})
+	_, err = c.Balance(ctx, account, block)
+	assert.NoError(t, err)
+
+	// Once another block is fetched at the same index, the
+	// block was reorged out and its entries are evicted.
+	c.balances.observe(&RosettaTypes.BlockIdentifier{
+		Hash:  "0x1111111111111111111111111111111111111111111111111111111111111111",
+		Index: 8165,
+	})
+	assert.Empty(t, c.balances.entries)
+	assert.Empty(t, c.balances.hashes)
+	_, err = c.Balance(ctx, account, block)
+	assert.NoError(t, err)
+
+	mockJSONRPC.AssertExpectations(t)
+	mockGraphQL.AssertExpectations(t)
+}
+
+func TestBalanceCache_Evict(t *testing.T) {
+	c := newBalanceCache(2)
+	for i := int64(0); i < 3; i++ {
+		c.put("0x1", &RosettaTypes.AccountBalanceResponse{
+			BlockIdentifier: &RosettaTypes.BlockIdentifier{
+				Hash:  fmt.Sprintf("0x%d", i),
+				Index: i,
+			},
+		})
+	}
+
+	// The least recently used entry is evicted.
+	assert.Nil(t, c.get("0x1", "0x0"))
+	assert.NotNil(t, c.get("0x1", "0x1"))
+	assert.NotNil(t, c.get("0x1", "0x2"))
+	assert.Len(t, c.hashes, 2)
+
+	assert.Nil(t, newBalanceCache(0))
+}
+
 func TestBalance_InvalidAddress(t *testing.T) {
 	mockJSONRPC := &mocks.JSONRPC{}
 	mockGraphQL := &mocks.GraphQL{}
@@ -1040,123 +1227,6016 @@ func TestCall_Call_InvalidArgs(t *testing.T) {
 	mockGraphQL.AssertExpectations(t)
 }
 
-func TestCall_EstimateGas(t *testing.T) {
+func TestCall_EstimateGas(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	mockGraphQL := &mocks.GraphQL{}
+
+	c := &Client{
+		c:              mockJSONRPC,
+		g:              mockGraphQL,
+		traceSemaphore: semaphore.NewWeighted(100),
+	}
+
+	ctx := context.Background()
+
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_estimateGas",
+		map[string]string{
+			"from": "0xE550f300E477C60CE7e7172d12e5a27e9379D2e3",
+			"to":   "0xaD6D458402F60fD3Bd25163575031ACDce07538D",
+			"data": "0xa9059cbb000000000000000000000000ae7e48ee0f758cd706b76cf7e2175d982800879a" +
+				"00000000000000000000000000000000000000000000000000521c5f98b8ea00",
+		},
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).(*string)
+
+			var expected map[string]interface{}
+			file, err := ioutil.ReadFile("testdata/estimate_gas_0xaD6D458402F60fD3Bd25163575031ACDce07538D.json")
+			assert.NoError(t, err)
+
+			err = json.Unmarshal(file, &expected)
+			assert.NoError(t, err)
+
+			*r = expected["data"].(string)
+		},
+	).Once()
+
+	correctRaw, err := ioutil.ReadFile("testdata/estimate_gas_0xaD6D458402F60fD3Bd25163575031ACDce07538D.json")
+	assert.NoError(t, err)
+	var correct map[string]interface{}
+	assert.NoError(t, json.Unmarshal(correctRaw, &correct))
+
+	resp, err := c.Call(
+		ctx,
+		&RosettaTypes.CallRequest{
+			Method: "eth_estimateGas",
+			Parameters: map[string]interface{}{
+				"from": "0xE550f300E477C60CE7e7172d12e5a27e9379D2e3",
+				"to":   "0xaD6D458402F60fD3Bd25163575031ACDce07538D",
+				"data": "0xa9059cbb000000000000000000000000ae7e48ee0f758cd706b76cf7e2175d982800879a" +
+					"00000000000000000000000000000000000000000000000000521c5f98b8ea00",
+			},
+		},
+	)
+	assert.Equal(t, &RosettaTypes.CallResponse{
+		Result:     correct,
+		Idempotent: false,
+	}, resp)
+	assert.NoError(t, err)
+
+	mockJSONRPC.AssertExpectations(t)
+	mockGraphQL.AssertExpectations(t)
+}
+
+func TestCall_EstimateGas_StateOverrides(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	mockGraphQL := &mocks.GraphQL{}
+
+	c := &Client{
+		c:              mockJSONRPC,
+		g:              mockGraphQL,
+		traceSemaphore: semaphore.NewWeighted(100),
+	}
+
+	ctx := context.Background()
+	from := common.HexToAddress("0xE550f300E477C60CE7e7172d12e5a27e9379D2e3")
+	balance := (*hexutil.Big)(big.NewInt(1000000000000000000))
+
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_estimateGas",
+		map[string]string{
+			"from": from.Hex(),
+			"to":   "0xaD6D458402F60fD3Bd25163575031ACDce07538D",
+			"data": "0x",
+		},
+		"latest",
+		StateOverride{
+			from: OverrideAccount{Balance: balance},
+		},
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).(*string)
+			*r = "0x5208"
+		},
+	).Once()
+
+	resp, err := c.Call(
+		ctx,
+		&RosettaTypes.CallRequest{
+			Method: "eth_estimateGas",
+			Parameters: map[string]interface{}{
+				"from": from.Hex(),
+				"to":   "0xaD6D458402F60fD3Bd25163575031ACDce07538D",
+				"data": "0x",
+				"state_overrides": map[string]interface{}{
+					from.Hex(): map[string]interface{}{
+						"balance": balance.String(),
+					},
+				},
+			},
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, &RosettaTypes.CallResponse{
+		Result: map[string]interface{}{
+			"data": "0x5208",
+		},
+		Idempotent: false,
+	}, resp)
+
+	mockJSONRPC.AssertExpectations(t)
+	mockGraphQL.AssertExpectations(t)
+}
+
+func TestCall_EstimateGas_InvalidArgs(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	mockGraphQL := &mocks.GraphQL{}
+
+	c := &Client{
+		c:              mockJSONRPC,
+		g:              mockGraphQL,
+		traceSemaphore: semaphore.NewWeighted(100),
+	}
+
+	ctx := context.Background()
+	resp, err := c.Call(
+		ctx,
+		&RosettaTypes.CallRequest{
+			Method: "eth_estimateGas",
+			Parameters: map[string]interface{}{
+				"From": "0xE550f300E477C60CE7e7172d12e5a27e9379D2e3",
+				"to":   "0xaD6D458402F60fD3Bd25163575031ACDce07538D",
+			},
+		},
+	)
+	assert.Nil(t, resp)
+	assert.True(t, errors.Is(err, ErrCallParametersInvalid))
+
+	mockJSONRPC.AssertExpectations(t)
+	mockGraphQL.AssertExpectations(t)
+}
+
+func TestCall_InvalidMethod(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	mockGraphQL := &mocks.GraphQL{}
+
+	c := &Client{
+		c:              mockJSONRPC,
+		g:              mockGraphQL,
+		traceSemaphore: semaphore.NewWeighted(100),
+	}
+
+	ctx := context.Background()
+	resp, err := c.Call(
+		ctx,
+		&RosettaTypes.CallRequest{
+			Method: "blah",
+		},
+	)
+	assert.Nil(t, resp)
+	assert.True(t, errors.Is(err, ErrCallMethodInvalid))
+
+	mockJSONRPC.AssertExpectations(t)
+	mockGraphQL.AssertExpectations(t)
+}
+
+func TestCall_RewardSchedule(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	mockGraphQL := &mocks.GraphQL{}
+
+	c := &Client{
+		c:              mockJSONRPC,
+		g:              mockGraphQL,
+		traceSemaphore: semaphore.NewWeighted(100),
+	}
+
+	ctx := context.Background()
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_getBlockByNumber",
+		"latest",
+		false,
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			header := args.Get(1).(**types.Header)
+			file, err := ioutil.ReadFile("testdata/basic_header.json")
+			assert.NoError(t, err)
+
+			*header = new(types.Header)
+
+			assert.NoError(t, (*header).UnmarshalJSON(file))
+		},
+	).Once()
+
+	contractCalls := []struct {
+		to     common.Address
+		data   string
+		result string
+	}{
+		{
+			to:     ValidatorSetContract,
+			data:   "0x0ac168a1",
+			result: "0x00000000000000000000000000000000000000000000000029a2241af62c0000",
+		},
+		{
+			to:     ValidatorSetContract,
+			data:   "0x983443df",
+			result: "0x0000000000000000000000000000000000000000000000000000000000000005",
+		},
+		{
+			to:     SystemRewardContract,
+			data:   "0x5192c82c",
+			result: "0x00000000000000000000000000000000000000000000000000000000000003e8",
+		},
+	}
+	for _, call := range contractCalls {
+		result := call.result
+		mockJSONRPC.On(
+			"CallContext",
+			ctx,
+			mock.Anything,
+			"eth_call",
+			map[string]string{
+				"to":   call.to.Hex(),
+				"data": call.data,
+			},
+			"0x880eb0",
+		).Return(
+			nil,
+		).Run(
+			func(args mock.Arguments) {
+				r := args.Get(1).(*string)
+				*r = result
+			},
+		).Once()
+	}
+
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_getLogs",
+		map[string]interface{}{
+			"fromBlock": "0x0",
+			"toBlock":   "0x880eb0",
+			"address":   rewardScheduleContracts,
+			"topics":    [][]common.Hash{{paramChangeTopic}},
+		},
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).(*[]types.Log)
+
+			file, err := ioutil.ReadFile("testdata/reward_schedule_logs.json")
+			assert.NoError(t, err)
+
+			assert.NoError(t, json.Unmarshal(file, r))
+		},
+	).Once()
+
+	correctRaw, err := ioutil.ReadFile("testdata/reward_schedule_response.json")
+	assert.NoError(t, err)
+	var correct map[string]interface{}
+	assert.NoError(t, json.Unmarshal(correctRaw, &correct))
+
+	resp, err := c.Call(
+		ctx,
+		&RosettaTypes.CallRequest{
+			Method:     RewardScheduleMethod,
+			Parameters: map[string]interface{}{},
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, &RosettaTypes.CallResponse{
+		Result:     correct,
+		Idempotent: false,
+	}, resp)
+
+	mockJSONRPC.AssertExpectations(t)
+	mockGraphQL.AssertExpectations(t)
+}
+
+func TestCall_RewardSchedule_InvalidArgs(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	mockGraphQL := &mocks.GraphQL{}
+
+	c := &Client{
+		c:              mockJSONRPC,
+		g:              mockGraphQL,
+		traceSemaphore: semaphore.NewWeighted(100),
+	}
+
+	ctx := context.Background()
+	resp, err := c.Call(
+		ctx,
+		&RosettaTypes.CallRequest{
+			Method: RewardScheduleMethod,
+			Parameters: map[string]interface{}{
+				"index": "not a number",
+			},
+		},
+	)
+	assert.Nil(t, resp)
+	assert.True(t, errors.Is(err, ErrCallParametersInvalid))
+
+	mockJSONRPC.AssertExpectations(t)
+	mockGraphQL.AssertExpectations(t)
+}
+
+func TestTraceOps_RevertedSelfDestruct(t *testing.T) {
+	sender := "0x1111111111111111111111111111111111111111"
+	contract := "0x2222222222222222222222222222222222222222"
+	beneficiary := "0x3333333333333333333333333333333333333333"
+
+	// The SELFDESTRUCT of contract is reverted, so the
+	// value it receives afterwards is not destroyed.
+	rawTrace := fmt.Sprintf(`{
+		"type": "CALL",
+		"from": "%s",
+		"to": "%s",
+		"value": "0x0",
+		"calls": [{
+			"type": "CALL",
+			"from": "%s",
+			"to": "%s",
+			"value": "0x0",
+			"error": "execution reverted",
+			"calls": [{
+				"type": "SELFDESTRUCT",
+				"from": "%s",
+				"to": "%s",
+				"value": "0x5"
+			}]
+		}, {
+			"type": "CALL",
+			"from": "%s",
+			"to": "%s",
+			"value": "0x2"
+		}]
+	}`, sender, contract, contract, contract, contract, beneficiary, contract, contract)
+
+	var trace Call
+	assert.NoError(t, json.Unmarshal([]byte(rawTrace), &trace))
+
+	ops := traceOps(flattenTraces(&trace, []*flatCall{}), 0, SkipZeroValueOperations)
+	assert.Len(t, ops, 4)
+	for _, op := range ops {
+		assert.NotEqual(t, DestructOpType, op.Type)
+	}
+	assert.Equal(t, SelfDestructOpType, ops[0].Type)
+	assert.Equal(t, FailureStatus, *ops[0].Status)
+}
+
+func TestTraceOps_FailedCreate(t *testing.T) {
+	sender := common.HexToAddress("0x1111111111111111111111111111111111111111")
+
+	// The tracer omits the to address of a failed CREATE.
+	rawTrace := fmt.Sprintf(`{
+		"type": "CALL",
+		"from": "%s",
+		"to": "0x2222222222222222222222222222222222222222",
+		"value": "0x0",
+		"calls": [{
+			"type": "CREATE",
+			"from": "0x2222222222222222222222222222222222222222",
+			"value": "0x1",
+			"error": "contract creation code storage out of gas"
+		}]
+	}`, sender.Hex())
+
+	var trace Call
+	assert.NoError(t, json.Unmarshal([]byte(rawTrace), &trace))
+
+	ops := traceOps(flattenTraces(&trace, []*flatCall{}), 0, SkipZeroValueOperations)
+	assert.Equal(t, []*RosettaTypes.Operation{
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 0},
+			Type:                CreateOpType,
+			Status:              RosettaTypes.String(FailureStatus),
+			Account: &RosettaTypes.AccountIdentifier{
+				Address: "0x2222222222222222222222222222222222222222",
+			},
+			Amount: &RosettaTypes.Amount{
+				Value:    "-1",
+				Currency: Currency,
+			},
+			Metadata: map[string]interface{}{
+				"error": "contract creation code storage out of gas",
+			},
+		},
+	}, ops)
+}
+
+func TestTraceOps_ZeroValue(t *testing.T) {
+	rawTrace := `{
+		"type": "CALL",
+		"from": "0x1111111111111111111111111111111111111111",
+		"to": "0x2222222222222222222222222222222222222222",
+		"value": "0x0",
+		"calls": [{
+			"type": "CREATE",
+			"from": "0x2222222222222222222222222222222222222222",
+			"to": "0x3333333333333333333333333333333333333333",
+			"value": "0x0"
+		}, {
+			"type": "CALL",
+			"from": "0x2222222222222222222222222222222222222222",
+			"to": "0x4444444444444444444444444444444444444444",
+			"value": "0x5"
+		}]
+	}`
+
+	var trace Call
+	assert.NoError(t, json.Unmarshal([]byte(rawTrace), &trace))
+
+	summary := func(ops []*RosettaTypes.Operation) []string {
+		summaries := make([]string, len(ops))
+		for i, op := range ops {
+			value := "none"
+			if op.Amount != nil {
+				value = op.Amount.Value
+			}
+			summaries[i] = fmt.Sprintf("%s %s %s %v", op.Type, op.Account.Address[:6], value, op.Metadata[subtypeMetadataKey])
+		}
+		return summaries
+	}
+
+	tests := map[ZeroValueOperations][]string{
+		"": {
+			"CREATE 0x2222 none <nil>",
+			"CREATE 0x3333 none <nil>",
+			"CALL 0x2222 -5 <nil>",
+			"CALL 0x4444 5 <nil>",
+		},
+		SkipZeroValueOperations: {
+			"CREATE 0x2222 none <nil>",
+			"CREATE 0x3333 none <nil>",
+			"CALL 0x2222 -5 <nil>",
+			"CALL 0x4444 5 <nil>",
+		},
+		SuppressZeroValueOperations: {
+			"CALL 0x2222 -5 <nil>",
+			"CALL 0x4444 5 <nil>",
+		},
+		LabelZeroValueOperations: {
+			"CALL 0x1111 none ZERO_VALUE",
+			"CALL 0x2222 none ZERO_VALUE",
+			"CREATE 0x2222 none ZERO_VALUE",
+			"CREATE 0x3333 none ZERO_VALUE",
+			"CALL 0x2222 -5 <nil>",
+			"CALL 0x4444 5 <nil>",
+		},
+	}
+
+	for zeroValueOps, expected := range tests {
+		t.Run(string(zeroValueOps), func(t *testing.T) {
+			ops := traceOps(flattenTraces(&trace, []*flatCall{}), 2, zeroValueOps)
+			assert.Equal(t, expected, summary(ops))
+			for i, op := range ops {
+				assert.Equal(t, int64(i+2), op.OperationIdentifier.Index)
+				if i%2 == 1 {
+					assert.Equal(t, []*RosettaTypes.OperationIdentifier{{Index: int64(i + 1)}}, op.RelatedOperations)
+				}
+			}
+		})
+	}
+}
+
+func TestTraceOps_Precompile(t *testing.T) {
+	sender := "0x1111111111111111111111111111111111111111"
+	sha256 := common.HexToAddress("0x2")
+
+	name, ok := Precompile(sha256)
+	assert.True(t, ok)
+	assert.Equal(t, "sha256", name)
+	_, ok = Precompile(common.HexToAddress(sender))
+	assert.False(t, ok)
+
+	// Value sent to a precompile is credited to it.
+	rawTrace := fmt.Sprintf(`{
+		"type": "CALL",
+		"from": "%s",
+		"to": "%s",
+		"value": "0x1"
+	}`, sender, sha256.Hex())
+
+	var trace Call
+	assert.NoError(t, json.Unmarshal([]byte(rawTrace), &trace))
+
+	ops := traceOps(flattenTraces(&trace, []*flatCall{}), 0, SkipZeroValueOperations)
+	assert.Len(t, ops, 2)
+	assert.Equal(t, sha256.Hex(), ops[1].Account.Address)
+	assert.Equal(t, "1", ops[1].Amount.Value)
+}
+
+func TestTraceOps_PartialFailure(t *testing.T) {
+	// A synthetic router call modeled on a swap that catches
+	// the revert of its pair: the transfers made below the
+	// reverted pair fail with it, while the sibling calls of
+	// the router still succeed. The addresses are placeholders,
+	// see TestTraceOps_PartialFailureMainnet for a real trace.
+	file, err := ioutil.ReadFile("testdata/trace_partial_failure_synthetic.json")
+	assert.NoError(t, err)
+
+	var trace Call
+	assert.NoError(t, json.Unmarshal(file, &trace))
+
+	ops := traceOps(flattenTraces(&trace, []*flatCall{}), 0, SkipZeroValueOperations)
+
+	type attribution struct {
+		address        string
+		value          string
+		status         string
+		callerReverted bool
+	}
+	expected := []attribution{
+		{"0x1111111111111111111111111111111111111111", "-1000000000000000000", SuccessStatus, false},
+		{"0x2222222222222222222222222222222222222222", "1000000000000000000", SuccessStatus, false},
+		{"0x2222222222222222222222222222222222222222", "-1000000000000000000", SuccessStatus, false},
+		{"0x3333333333333333333333333333333333333333", "1000000000000000000", SuccessStatus, false},
+		{"0x2222222222222222222222222222222222222222", "-7", FailureStatus, false},
+		{"0x4444444444444444444444444444444444444444", "7", FailureStatus, false},
+		{"0x4444444444444444444444444444444444444444", "-5", FailureStatus, true},
+		{"0x5555555555555555555555555555555555555555", "5", FailureStatus, true},
+		{"0x5555555555555555555555555555555555555555", "-3", FailureStatus, true},
+		{"0x6666666666666666666666666666666666666666", "3", FailureStatus, true},
+		{"0x2222222222222222222222222222222222222222", "-1", SuccessStatus, false},
+		{"0x1111111111111111111111111111111111111111", "1", SuccessStatus, false},
+	}
+
+	attributions := make([]attribution, len(ops))
+	for i, op := range ops {
+		attributions[i] = attribution{
+			address:        op.Account.Address,
+			value:          op.Amount.Value,
+			status:         *op.Status,
+			callerReverted: op.Metadata[CallerRevertedMetadataKey] == true,
+		}
+
+		if *op.Status == FailureStatus {
+			assert.Equal(t, "execution reverted", op.Metadata["error"])
+		}
+	}
+	assert.Equal(t, expected, attributions)
+
+	// Failed operations do not change any balance.
+	sum, destroyed := operationsSum(ops)
+	assert.Equal(t, "0", sum.String())
+	assert.Equal(t, "0", destroyed.String())
+
+	collapsed := collapseOps(ops, 0)
+	assert.Len(t, collapsed, 3)
+	assert.Equal(t, "-999999999999999999", collapsed[0].Amount.Value)
+	assert.Equal(t, "-1", collapsed[1].Amount.Value)
+	assert.Equal(t, "1000000000000000000", collapsed[2].Amount.Value)
+
+	// Flattening the trace again attributes the failures the same way.
+	assert.Equal(t, ops, traceOps(flattenTraces(&trace, []*flatCall{}), 0, SkipZeroValueOperations))
+}
+
+func TestTraceOps_PartialFailureMainnet(t *testing.T) {
+	// Transaction 0x05613760334d347e771fad61b1815c8c817b8dd5f0fcbba57c3f2df67dec33d6
+	// (Ethereum mainnet block 239782) succeeds, but the wallet call it
+	// makes fails with "invalid jump destination" after its DELEGATECALL
+	// ran out of gas. The 1.05 ETH transfer made below the DELEGATECALL
+	// did not fail itself: its failure is attributed to its caller.
+	file, err := ioutil.ReadFile(
+		"testdata/block_trace_0xc4487850a40d85b79cf5e5b69db38284fbd39efcf902ca8a6d9f2ba89c538ea3.json",
+	) // nolint
+	assert.NoError(t, err)
+
+	var traces []*rpcCall
+	assert.NoError(t, json.Unmarshal(file, &traces))
+	assert.Len(t, traces, 1)
+
+	calls := flattenTraces(traces[0].Result, []*flatCall{})
+	assert.Len(t, calls, 7)
+	for _, call := range calls[:4] {
+		assert.False(t, call.Revert)
+	}
+	assert.Equal(t, "invalid jump destination", calls[4].ErrorMessage)
+	assert.False(t, calls[4].CallerReverted)
+	assert.Equal(t, "out of gas", calls[5].ErrorMessage)
+	assert.False(t, calls[5].CallerReverted)
+
+	ops := traceOps(calls, 0, SkipZeroValueOperations)
+	assert.Len(t, ops, 2)
+	for _, op := range ops {
+		assert.Equal(t, FailureStatus, *op.Status)
+		assert.Equal(t, "out of gas", op.Metadata["error"])
+		assert.Equal(t, true, op.Metadata[CallerRevertedMetadataKey])
+	}
+	assert.Equal(t, "0xc2662c7aca9Fd8bD659108FB943eA9188c370501", ops[0].Account.Address)
+	assert.Equal(t, "-1050000000000000000", ops[0].Amount.Value)
+	assert.Equal(t, "0x8c30393085C8C3fb4C1fB16165d9fBac5D86E1D9", ops[1].Account.Address)
+	assert.Equal(t, "1050000000000000000", ops[1].Amount.Value)
+
+	// The transfer does not change any balance.
+	sum, destroyed := operationsSum(ops)
+	assert.Equal(t, "0", sum.String())
+	assert.Equal(t, "0", destroyed.String())
+}
+
+func TestTraceOps_SafeExecution(t *testing.T) {
+	owner := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	safe := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	singleton := common.HexToAddress("0x3333333333333333333333333333333333333333")
+	token := common.HexToAddress("0x4444444444444444444444444444444444444444")
+	innerData := hexutil.MustDecode("0xa9059cbb")
+
+	input, err := safeABI.Pack(
+		"execTransaction",
+		token,
+		big.NewInt(0),
+		innerData,
+		safeOperationCall,
+		big.NewInt(0),
+		big.NewInt(0),
+		big.NewInt(0),
+		common.Address{},
+		common.Address{},
+		[]byte{},
+	)
+	assert.NoError(t, err)
+
+	rawTrace := fmt.Sprintf(`{
+		"type": "CALL",
+		"from": "%s",
+		"to": "%s",
+		"value": "0x0",
+		"input": "%s",
+		"calls": [{
+			"type": "DELEGATECALL",
+			"from": "%s",
+			"to": "%s",
+			"input": "%s",
+			"calls": [{
+				"type": "CALL",
+				"from": "%s",
+				"to": "%s",
+				"value": "0x0",
+				"input": "%s"
+			}]
+		}]
+	}`,
+		owner.Hex(), safe.Hex(), hexutil.Encode(input),
+		safe.Hex(), singleton.Hex(), hexutil.Encode(input),
+		safe.Hex(), token.Hex(), hexutil.Encode(innerData),
+	)
+
+	var trace Call
+	assert.NoError(t, json.Unmarshal([]byte(rawTrace), &trace))
+
+	markSafeExecutions(&trace)
+	ops := traceOps(flattenTraces(&trace, []*flatCall{}), 0, SkipZeroValueOperations)
+
+	safeMetadata := map[string]interface{}{
+		"safe":      MustChecksum(safe.Hex()),
+		"to":        MustChecksum(token.Hex()),
+		"value":     "0",
+		"data":      "0xa9059cbb",
+		"operation": CallOpType,
+	}
+	assert.Equal(t, []*RosettaTypes.Operation{
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 0},
+			Type:                CallOpType,
+			Status:              RosettaTypes.String(SuccessStatus),
+			Account: &RosettaTypes.AccountIdentifier{
+				Address: MustChecksum(safe.Hex()),
+			},
+			Metadata: map[string]interface{}{safeMetadataKey: safeMetadata},
+		},
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 1},
+			RelatedOperations: []*RosettaTypes.OperationIdentifier{
+				{Index: 0},
+			},
+			Type:   CallOpType,
+			Status: RosettaTypes.String(SuccessStatus),
+			Account: &RosettaTypes.AccountIdentifier{
+				Address: MustChecksum(token.Hex()),
+			},
+			Metadata: map[string]interface{}{safeMetadataKey: safeMetadata},
+		},
+	}, ops)
+}
+
+func TestApprovalOps(t *testing.T) {
+	token := common.HexToAddress("0x6B175474E89094C44Da98b954EedeAC495271d0F")
+	owner := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	spender := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	unlimited := common.LeftPadBytes(abi.MaxUint256.Bytes(), 32)
+	limited := common.LeftPadBytes(big.NewInt(1000).Bytes(), 32)
+
+	receipt := &types.Receipt{
+		Logs: []*types.Log{
+			{
+				Address: token,
+				Topics:  []common.Hash{approvalTopic, owner.Hash(), spender.Hash()},
+				Data:    unlimited,
+				Index:   4,
+			},
+			{
+				// ERC-721 approvals index the token ID
+				Address: token,
+				Topics: []common.Hash{
+					approvalTopic,
+					owner.Hash(),
+					spender.Hash(),
+					common.BigToHash(big.NewInt(1)),
+				},
+				Index: 5,
+			},
+			{
+				Address: token,
+				Topics:  []common.Hash{common.HexToHash("0x01"), owner.Hash(), spender.Hash()},
+				Data:    limited,
+				Index:   6,
+			},
+			{
+				Address: token,
+				Topics:  []common.Hash{approvalTopic, owner.Hash(), spender.Hash()},
+				Data:    limited,
+				Index:   7,
+			},
+		},
+	}
+
+	ops := approvalOps(receipt, 2)
+	assert.Equal(t, []*RosettaTypes.Operation{
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 2},
+			Type:                ApprovalOpType,
+			Status:              RosettaTypes.String(SuccessStatus),
+			Account:             &RosettaTypes.AccountIdentifier{Address: owner.Hex()},
+			Metadata: map[string]interface{}{
+				"token":     token.Hex(),
+				"spender":   spender.Hex(),
+				"allowance": abi.MaxUint256.String(),
+				"unlimited": true,
+				"log_index": uint(4),
+			},
+		},
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 3},
+			Type:                ApprovalOpType,
+			Status:              RosettaTypes.String(SuccessStatus),
+			Account:             &RosettaTypes.AccountIdentifier{Address: owner.Hex()},
+			Metadata: map[string]interface{}{
+				"token":     token.Hex(),
+				"spender":   spender.Hex(),
+				"allowance": "1000",
+				"unlimited": false,
+				"log_index": uint(7),
+			},
+		},
+	}, ops)
+
+	assert.Len(t, approvalOps(nil, 0), 0)
+}
+
+func TestWatchlist_MayTouch(t *testing.T) {
+	watched := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	other := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	token := common.HexToAddress("0x6B175474E89094C44Da98b954EedeAC495271d0F")
+	w := newWatchlist([]common.Address{watched})
+
+	receipt := func(logs ...*types.Log) *types.Receipt {
+		r := &types.Receipt{Logs: logs}
+		r.Bloom = types.CreateBloom(types.Receipts{r})
+		return r
+	}
+	addressLog := func(from common.Address, to common.Address) *types.Log {
+		return &types.Log{
+			Address: token,
+			Topics:  []common.Hash{approvalTopic, from.Hash(), to.Hash()},
+		}
+	}
+	loadedTx := func(from common.Address, to common.Address, r *types.Receipt) *loadedTransaction {
+		return &loadedTransaction{
+			Transaction: types.NewTransaction(0, to, big.NewInt(0), 21000, big.NewInt(1), nil),
+			From:        &from,
+			Receipt:     r,
+		}
+	}
+
+	assert.Nil(t, newWatchlist(nil))
+	assert.True(t, w.mayTouch(loadedTx(watched, other, receipt())))
+	assert.True(t, w.mayTouch(loadedTx(other, watched, receipt())))
+	assert.True(t, w.mayTouch(loadedTx(other, token, receipt(addressLog(other, watched)))))
+	assert.True(t, w.mayTouch(loadedTx(other, other, nil)))
+	assert.False(t, w.mayTouch(loadedTx(other, other, receipt())))
+	assert.False(t, w.mayTouch(loadedTx(other, token, receipt(addressLog(other, token)))))
+}
+
+func TestPopulateTransaction_Filtered(t *testing.T) {
+	watched := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	other := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	c := &Client{watchlist: newWatchlist([]common.Address{watched})}
+
+	tx := &loadedTransaction{
+		Transaction: types.NewTransaction(0, other, big.NewInt(1), 21000, big.NewInt(1), nil),
+		From:        &other,
+		FeeAmount:   big.NewInt(21000),
+		Miner:       watched.Hex(),
+		Receipt:     &types.Receipt{},
+		Trace: &Call{
+			Type:  "CALL",
+			From:  other,
+			To:    other,
+			Value: big.NewInt(1),
+		},
+	}
+
+	populated, err := c.populateTransaction(tx)
+	assert.NoError(t, err)
+	assert.Len(t, populated.Operations, 2)
+	for _, op := range populated.Operations {
+		assert.Equal(t, FeeOpType, op.Type)
+	}
+	assert.Equal(t, true, populated.Metadata["filtered"])
+	assert.NotContains(t, populated.Metadata, "trace")
+}
+
+func TestLabelOperations(t *testing.T) {
+	other := "0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d"
+	op := func(index int64, address string, related ...int64) *RosettaTypes.Operation {
+		o := &RosettaTypes.Operation{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: index},
+			Type:                CallOpType,
+			Account:             &RosettaTypes.AccountIdentifier{Address: address},
+		}
+		for _, r := range related {
+			o.RelatedOperations = append(
+				o.RelatedOperations,
+				&RosettaTypes.OperationIdentifier{Index: r},
+			)
+		}
+
+		return o
+	}
+
+	ops := []*RosettaTypes.Operation{
+		op(0, FoundationContract.Hex()),
+		op(1, other, 0),
+		op(2, other),
+		op(3, SystemRewardContract.Hex(), 2),
+		op(4, other),
+		op(5, other, 4),
+	}
+	labelOperations(ops)
+
+	assert.Equal(t, map[string]interface{}{
+		"subtype":    FoundationSubtype,
+		"foundation": true,
+	}, ops[0].Metadata)
+	assert.Equal(t, map[string]interface{}{"foundation": true}, ops[1].Metadata)
+	assert.Equal(t, map[string]interface{}{"treasury": true}, ops[2].Metadata)
+	assert.Equal(t, map[string]interface{}{
+		"subtype":  TreasurySubtype,
+		"treasury": true,
+	}, ops[3].Metadata)
+	assert.Nil(t, ops[4].Metadata)
+	assert.Nil(t, ops[5].Metadata)
+}
+
+func TestLabelOperations_TraceOps(t *testing.T) {
+	rawTrace := fmt.Sprintf(`{
+		"type": "CALL",
+		"from": "0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d",
+		"to": "%s",
+		"value": "0x1"
+	}`, FoundationContract.Hex())
+
+	var trace Call
+	assert.NoError(t, json.Unmarshal([]byte(rawTrace), &trace))
+
+	// traceOps shares the metadata of both sides of a transfer,
+	// so only the Foundation side may have the subtype.
+	ops := traceOps(flattenTraces(&trace, []*flatCall{}), 0, SkipZeroValueOperations)
+	assert.Len(t, ops, 2)
+	labelOperations(ops)
+
+	assert.Equal(t, map[string]interface{}{"foundation": true}, ops[0].Metadata)
+	assert.Equal(t, map[string]interface{}{
+		"subtype":    FoundationSubtype,
+		"foundation": true,
+	}, ops[1].Metadata)
+}
+
+func TestLagMonitor(t *testing.T) {
+	ctx := context.Background()
+	mockJSONRPC := &mocks.JSONRPC{}
+	mockReference := &mocks.JSONRPC{}
+	mockUnreachable := &mocks.JSONRPC{}
+	c := &Client{
+		c: mockJSONRPC,
+		lag: &lagMonitor{
+			config: &LagConfig{
+				MaxLag:           10,
+				RejectStaleReads: true,
+			},
+			references: []JSONRPC{mockReference, mockUnreachable},
+		},
+	}
+
+	reference := func(head uint64) {
+		mockReference.On(
+			"CallContext",
+			ctx,
+			mock.Anything,
+			"eth_blockNumber",
+		).Return(
+			nil,
+		).Run(
+			func(args mock.Arguments) {
+				r := args.Get(1).(*hexutil.Uint64)
+				*r = hexutil.Uint64(head)
+			},
+		).Once()
+		mockUnreachable.On(
+			"CallContext",
+			ctx,
+			mock.Anything,
+			"eth_blockNumber",
+		).Return(
+			errors.New("connection refused"),
+		).Once()
+	}
+
+	// Within the allowed lag
+	reference(110)
+	c.lag.update(ctx, 100)
+	syncStatus := &RosettaTypes.SyncStatus{Synced: RosettaTypes.Bool(true)}
+	assert.Equal(t, syncStatus, c.lagSyncStatus(100, syncStatus))
+	assert.NoError(t, c.checkStaleRead())
+
+	// Degraded
+	reference(111)
+	c.lag.update(ctx, 100)
+	assert.Equal(t, &RosettaTypes.SyncStatus{
+		CurrentIndex: RosettaTypes.Int64(100),
+		TargetIndex:  RosettaTypes.Int64(111),
+		Stage:        RosettaTypes.String(laggingStage),
+		Synced:       RosettaTypes.Bool(false),
+	}, c.lagSyncStatus(100, syncStatus))
+	assert.True(t, errors.Is(c.checkStaleRead(), ErrNodeLagging))
+
+	// Tip-relative balance queries are rejected, historical
+	// queries are not
+	_, err := c.Balance(ctx, &RosettaTypes.AccountIdentifier{Address: "0x01"}, nil)
+	assert.True(t, errors.Is(err, ErrNodeLagging))
+
+	// Stale reads are allowed if not configured
+	c.lag.config.RejectStaleReads = false
+	assert.NoError(t, c.checkStaleRead())
+	c.lag.config.RejectStaleReads = true
+
+	// Unreachable references do not degrade the node
+	mockReference.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_blockNumber",
+	).Return(
+		errors.New("connection refused"),
+	).Once()
+	mockUnreachable.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_blockNumber",
+	).Return(
+		errors.New("connection refused"),
+	).Once()
+	c.lag.update(ctx, 100)
+	assert.NoError(t, c.checkStaleRead())
+
+	mockJSONRPC.AssertExpectations(t)
+	mockReference.AssertExpectations(t)
+	mockUnreachable.AssertExpectations(t)
+}
+
+func TestTransaction_Refund(t *testing.T) {
+	from := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	to := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	miner := common.HexToAddress("0x0000000000000000000000000000000000001000")
+	blockHash := common.HexToHash("0xb2b2")
+	gasPrice := big.NewInt(5000000000)
+
+	tests := map[string]struct {
+		baseFee      *big.Int
+		traceGasUsed int64
+		gasUsed      uint64
+		fees         []string
+	}{
+		// 60000 gas executed with a 24000 refund capped at
+		// half of the gas used (pre-London): 30000 gas is
+		// charged.
+		"pre-london selfdestruct refund": {
+			traceGasUsed: 60000,
+			gasUsed:      30000,
+			fees: []string{
+				from.Hex() + " -150000000000000",
+				miner.Hex() + " 150000000000000",
+			},
+		},
+		// 30000 gas executed with a 15000 refund (pre-London):
+		// the 15000 gas charged is below the intrinsic gas of
+		// the transaction, which must not fail the block.
+		"pre-london refund below intrinsic gas": {
+			traceGasUsed: 30000,
+			gasUsed:      15000,
+			fees: []string{
+				from.Hex() + " -75000000000000",
+				miner.Hex() + " 75000000000000",
+			},
+		},
+		// 60000 gas executed with a 19800 SSTORE clear refund
+		// capped at a fifth of the gas used (London): 48000
+		// gas is charged, and its base fee is burned by the
+		// sender.
+		"london sstore clear refund": {
+			baseFee:      big.NewInt(1000000000),
+			traceGasUsed: 60000,
+			gasUsed:      48000,
+			fees: []string{
+				from.Hex() + " -192000000000000",
+				miner.Hex() + " 192000000000000",
+				from.Hex() + " -48000000000000",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockJSONRPC := &mocks.JSONRPC{}
+			c := &Client{
+				c:              mockJSONRPC,
+				p:              params.MainnetChainConfig,
+				traceSemaphore: semaphore.NewWeighted(100),
+			}
+			ctx := context.Background()
+
+			tx := types.NewTransaction(0, to, big.NewInt(0), 100000, gasPrice, []byte{0x01})
+			encoded, err := tx.MarshalJSON()
+			assert.NoError(t, err)
+			var body map[string]interface{}
+			assert.NoError(t, json.Unmarshal(encoded, &body))
+			body["from"] = from.Hex()
+			body["blockHash"] = blockHash.Hex()
+			body["blockNumber"] = "0x3e8"
+			encoded, err = json.Marshal(body)
+			assert.NoError(t, err)
+
+			mockJSONRPC.On(
+				"CallContext", ctx, mock.Anything, "eth_getTransactionByHash", tx.Hash().Hex(),
+			).Return(nil).Run(func(args mock.Arguments) {
+				*args.Get(1).(*json.RawMessage) = encoded
+			}).Once()
+			mockJSONRPC.On(
+				"CallContext", ctx, mock.Anything, "eth_getBlockByHash", blockHash.Hex(), false,
+			).Return(nil).Run(func(args mock.Arguments) {
+				*args.Get(1).(**types.Header) = &types.Header{
+					Number:   big.NewInt(1000),
+					Coinbase: miner,
+					BaseFee:  test.baseFee,
+				}
+			}).Once()
+			mockJSONRPC.On(
+				"CallContext", ctx, mock.Anything, "eth_getTransactionReceipt", tx.Hash(),
+			).Return(nil).Run(func(args mock.Arguments) {
+				*args.Get(1).(**types.Receipt) = &types.Receipt{
+					Status:    types.ReceiptStatusSuccessful,
+					GasUsed:   test.gasUsed,
+					BlockHash: blockHash,
+				}
+			}).Once()
+
+			// The trace reports the gas used before the
+			// refund, which is not what the sender paid.
+			mockJSONRPC.On(
+				"CallContext", ctx, mock.Anything, "debug_traceTransaction", tx.Hash(), mock.Anything,
+			).Return(nil).Run(func(args mock.Arguments) {
+				*args.Get(1).(*json.RawMessage) = json.RawMessage(fmt.Sprintf(
+					`{"type":"CALL","from":"%s","to":"%s","value":"0x0","gasUsed":"0x%x","input":"0x01"}`,
+					from.Hex(),
+					to.Hex(),
+					test.traceGasUsed,
+				))
+			}).Once()
+
+			transaction, err := c.Transaction(
+				ctx,
+				&RosettaTypes.BlockIdentifier{Hash: blockHash.Hex(), Index: 1000},
+				&RosettaTypes.TransactionIdentifier{Hash: tx.Hash().Hex()},
+			)
+			assert.NoError(t, err)
+
+			var fees []string
+			for _, op := range transaction.Operations {
+				if op.Type == FeeOpType {
+					fees = append(fees, op.Account.Address+" "+op.Amount.Value)
+				}
+			}
+			assert.Equal(t, test.fees, fees)
+			mockJSONRPC.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCall_DecodeTransaction(t *testing.T) {
+	c := &Client{p: CoreChainConfig}
+	ctx := context.Background()
+
+	key, err := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	assert.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	recipient := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	unsigned := types.NewTransaction(3, recipient, big.NewInt(1000), 21000, big.NewInt(1), nil)
+
+	decode := func(tx *types.Transaction) map[string]interface{} {
+		raw, err := tx.MarshalBinary()
+		assert.NoError(t, err)
+
+		resp, err := c.Call(ctx, &RosettaTypes.CallRequest{
+			Method: DecodeTransactionMethod,
+			Parameters: map[string]interface{}{
+				"transaction": hexutil.Encode(raw),
+			},
+		})
+		assert.NoError(t, err)
+
+		return resp.Result
+	}
+
+	// Signed for Core
+	signed, err := types.SignTx(unsigned, types.NewEIP155Signer(CoreChainConfig.ChainID), key)
+	assert.NoError(t, err)
+	result := decode(signed)
+	assert.Equal(t, signed.Hash().Hex(), result["hash"])
+	assert.Equal(t, sender.Hex(), result["from"])
+	assert.Equal(t, true, result["signed"])
+	assert.Equal(t, true, result["chain_id_matches"])
+	assert.Equal(t, "0x3", result["transaction"].(map[string]interface{})["nonce"])
+
+	var decoded DecodedTransaction
+	assert.NoError(t, RosettaTypes.UnmarshalMap(result, &decoded))
+	assert.Equal(t, []*RosettaTypes.Operation{
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 0},
+			Type:                CallOpType,
+			Account:             &RosettaTypes.AccountIdentifier{Address: sender.Hex()},
+			Amount:              &RosettaTypes.Amount{Value: "-1000", Currency: Currency},
+		},
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 1},
+			RelatedOperations:   []*RosettaTypes.OperationIdentifier{{Index: 0}},
+			Type:                CallOpType,
+			Account:             &RosettaTypes.AccountIdentifier{Address: recipient.Hex()},
+			Amount:              &RosettaTypes.Amount{Value: "1000", Currency: Currency},
+		},
+	}, decoded.Operations)
+
+	// Contract creation, to the address derived
+	// from the sender and the nonce
+	create := types.NewContractCreation(7, big.NewInt(1000), 100000, big.NewInt(1), []byte{0x60, 0x00})
+	signed, err = types.SignTx(create, types.NewEIP155Signer(CoreChainConfig.ChainID), key)
+	assert.NoError(t, err)
+	decoded = DecodedTransaction{}
+	assert.NoError(t, RosettaTypes.UnmarshalMap(decode(signed), &decoded))
+	assert.Equal(t, []*RosettaTypes.Operation{
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 0},
+			Type:                CreateOpType,
+			Account:             &RosettaTypes.AccountIdentifier{Address: sender.Hex()},
+			Amount:              &RosettaTypes.Amount{Value: "-1000", Currency: Currency},
+		},
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 1},
+			RelatedOperations:   []*RosettaTypes.OperationIdentifier{{Index: 0}},
+			Type:                CreateOpType,
+			Account: &RosettaTypes.AccountIdentifier{
+				Address: crypto.CreateAddress(sender, 7).Hex(),
+			},
+			Amount: &RosettaTypes.Amount{Value: "1000", Currency: Currency},
+		},
+	}, decoded.Operations)
+
+	// Signed for another chain
+	signed, err = types.SignTx(unsigned, types.NewEIP155Signer(big.NewInt(1)), key)
+	assert.NoError(t, err)
+	result = decode(signed)
+	assert.Equal(t, sender.Hex(), result["from"])
+	assert.Equal(t, false, result["chain_id_matches"])
+
+	// Unsigned
+	result = decode(unsigned)
+	assert.Equal(t, false, result["signed"])
+	assert.NotContains(t, result, "from")
+	assert.Equal(t, []interface{}{}, result["operations"])
+	assert.NotContains(t, result, "method")
+
+	// Registered method
+	c.abiRegistry = newABIRegistry(&ABIRegistryConfig{}, nil)
+	data := append(hexutil.MustDecode("0xa9059cbb"), common.LeftPadBytes(recipient.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(big.NewInt(5).Bytes(), 32)...)
+	result = decode(types.NewTransaction(4, recipient, big.NewInt(0), 60000, big.NewInt(1), data))
+	method := result["method"].(map[string]interface{})
+	assert.Equal(t, "transfer", method["name"])
+	assert.Equal(t, RegistryMethodSource, method["source"])
+
+	// Invalid
+	resp, err := c.Call(ctx, &RosettaTypes.CallRequest{
+		Method: DecodeTransactionMethod,
+		Parameters: map[string]interface{}{
+			"transaction": "0x1234",
+		},
+	})
+	assert.Nil(t, resp)
+	assert.True(t, errors.Is(err, ErrCallParametersInvalid))
+}
+
+func TestCall_InclusionProof(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	mockGraphQL := &mocks.GraphQL{}
+
+	c := &Client{
+		c:              mockJSONRPC,
+		g:              mockGraphQL,
+		p:              params.RopstenChainConfig,
+		traceSemaphore: semaphore.NewWeighted(100),
+	}
+
+	ctx := context.Background()
+	txHash := "0xb240b922161bb0aeaa5ebe67e6cf77311092bd945b9582b8deba61e2ebdde74f"
+	blockHash := common.HexToHash("0x68985b6b06bb5c6012393145729babb983fc16c50ec5207972ddda02de02f7e2")
+	txHashes := []string{
+		"0xf121c8c07ed51b6ac2d11fe3f0892bff2221ec9168280d12581ea8ff45e71421",
+		"0xef0748860f1c1ba28a5ae3ae9d2d1133940f7c8090fc862acf48de42b00ae2b5",
+		txHash,
+		"0xfac8149f95c20f62264991fe15dc74ca77c92ad6e4329496548277fb4d520509",
+		"0x0a4cd36d72c2ed4767c1d228a7aa0638c3e46397f48b6b09f35ed455c851bb04",
+		"0x9ee03d5922b2a901e3fc05d8a6351165b9f211162363c790c98746ef229e395c",
+		"0x0d4a4f924858a5b19f6b931a914701d4258e73fa738da3d38eb3be1d1e862a7a",
+	}
+	loadReceipt := func(hash string) *types.Receipt {
+		file, err := ioutil.ReadFile("testdata/tx_receipt_" + hash + ".json")
+		assert.NoError(t, err)
+
+		receipt := new(types.Receipt)
+		assert.NoError(t, receipt.UnmarshalJSON(file))
+		return receipt
+	}
+
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_getTransactionReceipt",
+		common.HexToHash(txHash),
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			*(args.Get(1).(**types.Receipt)) = loadReceipt(txHash)
+		},
+	).Once()
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_getBlockByHash",
+		blockHash,
+		true,
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			file, err := ioutil.ReadFile("testdata/block_13998626.json")
+			assert.NoError(t, err)
+			*(args.Get(1).(*json.RawMessage)) = json.RawMessage(file)
+		},
+	).Once()
+	mockJSONRPC.On(
+		"BatchCallContext",
+		ctx,
+		mock.Anything,
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).([]rpc.BatchElem)
+			assert.Len(t, r, len(txHashes))
+			for i, hash := range txHashes {
+				assert.Equal(t, hash, r[i].Args[0])
+				*(r[i].Result.(**types.Receipt)) = loadReceipt(hash)
+			}
+		},
+	).Once()
+
+	resp, err := c.Call(ctx, &RosettaTypes.CallRequest{
+		Method:     InclusionProofMethod,
+		Parameters: map[string]interface{}{"tx_hash": txHash},
+	})
+	assert.NoError(t, err)
+
+	var proof InclusionProof
+	assert.NoError(t, RosettaTypes.UnmarshalMap(resp.Result, &proof))
+	assert.Equal(t, txHash, proof.TransactionHash)
+	assert.Equal(t, &RosettaTypes.BlockIdentifier{
+		Index: 13998626,
+		Hash:  blockHash.Hex(),
+	}, proof.BlockIdentifier)
+	assert.Equal(t, uint(2), proof.TransactionIndex)
+	assert.Equal(t, "0x02", proof.Key)
+
+	// The header hashes to the block hash and
+	// commits to the roots of the proofs.
+	var header types.Header
+	assert.NoError(t, rlp.DecodeBytes(hexutil.MustDecode(proof.Header), &header))
+	assert.Equal(t, blockHash, header.Hash())
+	assert.Equal(t, header.TxHash.Hex(), proof.TransactionsRoot)
+	assert.Equal(t, header.ReceiptHash.Hex(), proof.ReceiptsRoot)
+
+	// verify returns the value proven at the key of the proof.
+	verify := func(root string, nodes []string) []byte {
+		db := memorydb.New()
+		for _, node := range nodes {
+			encoded := hexutil.MustDecode(node)
+			assert.NoError(t, db.Put(crypto.Keccak256(encoded), encoded))
+		}
+
+		value, err := trie.VerifyProof(common.HexToHash(root), hexutil.MustDecode(proof.Key), db)
+		assert.NoError(t, err)
+		return value
+	}
+
+	txValue := verify(proof.TransactionsRoot, proof.TransactionProof)
+	assert.Equal(t, proof.Transaction, hexutil.Encode(txValue))
+	var tx types.Transaction
+	assert.NoError(t, tx.UnmarshalBinary(txValue))
+	assert.Equal(t, txHash, tx.Hash().Hex())
+
+	receiptValue := verify(proof.ReceiptsRoot, proof.ReceiptProof)
+	assert.Equal(t, proof.Receipt, hexutil.Encode(receiptValue))
+	expectedReceipt, err := loadReceipt(txHash).MarshalBinary()
+	assert.NoError(t, err)
+	assert.Equal(t, expectedReceipt, receiptValue)
+
+	// Transactions that are not mined cannot be proven.
+	pendingHash := "0x0000000000000000000000000000000000000000000000000000000000000001"
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_getTransactionReceipt",
+		common.HexToHash(pendingHash),
+	).Return(
+		nil,
+	).Once()
+	resp, err = c.Call(ctx, &RosettaTypes.CallRequest{
+		Method:     InclusionProofMethod,
+		Parameters: map[string]interface{}{"tx_hash": pendingHash},
+	})
+	assert.Nil(t, resp)
+	assert.True(t, errors.Is(err, ErrCallParametersInvalid))
+
+	resp, err = c.Call(ctx, &RosettaTypes.CallRequest{
+		Method:     InclusionProofMethod,
+		Parameters: map[string]interface{}{"tx_hash": "0x1234"},
+	})
+	assert.Nil(t, resp)
+	assert.True(t, errors.Is(err, ErrCallParametersInvalid))
+
+	mockJSONRPC.AssertExpectations(t)
+	mockGraphQL.AssertExpectations(t)
+}
+
+// signerVector is a transaction signed with a fixed key
+// (see testdata/signer_vectors.json). The vectors were
+// generated once, so changes in how go-ethereum encodes,
+// hashes, or recovers the sender of transactions fail
+// TestSignerVectors when the dependency is upgraded.
+type signerVector struct {
+	Name    string `json:"name"`
+	ChainID int64  `json:"chain_id"`
+	Type    uint8  `json:"type"`
+	Raw     string `json:"raw"`
+	Hash    string `json:"hash"`
+	Sender  string `json:"sender"`
+}
+
+func TestSignerVectors(t *testing.T) {
+	file, err := ioutil.ReadFile("testdata/signer_vectors.json")
+	assert.NoError(t, err)
+
+	var vectors []*signerVector
+	assert.NoError(t, json.Unmarshal(file, &vectors))
+	assert.NotEmpty(t, vectors)
+
+	chainConfigs := []*params.ChainConfig{
+		CoreChainConfig,
+		BuffaloChainConfig,
+		DevChainConfig,
+	}
+
+	for _, vector := range vectors {
+		t.Run(vector.Name, func(t *testing.T) {
+			raw, err := hexutil.Decode(vector.Raw)
+			assert.NoError(t, err)
+
+			tx := new(types.Transaction)
+			assert.NoError(t, tx.UnmarshalBinary(raw))
+			assert.Equal(t, vector.Type, tx.Type())
+			assert.Equal(t, vector.Hash, tx.Hash().Hex())
+
+			encoded, err := tx.MarshalBinary()
+			assert.NoError(t, err)
+			assert.Equal(t, raw, encoded)
+
+			if vector.ChainID == 0 {
+				assert.False(t, tx.Protected())
+			} else {
+				assert.True(t, tx.Protected())
+				assert.Equal(t, vector.ChainID, tx.ChainId().Int64())
+			}
+
+			// The signers of every hardfork that supports the
+			// transaction type recover the same sender.
+			chainID := big.NewInt(vector.ChainID)
+			if vector.ChainID == 0 {
+				chainID = CoreChainConfig.ChainID
+			}
+			signers := []types.Signer{types.NewLondonSigner(chainID)}
+			if tx.Type() <= types.AccessListTxType {
+				signers = append(signers, types.NewEIP2930Signer(chainID))
+			}
+			if tx.Type() == types.LegacyTxType {
+				signers = append(signers, types.NewEIP155Signer(chainID))
+			}
+			if !tx.Protected() {
+				signers = append(signers, types.HomesteadSigner{})
+			}
+			for _, signer := range signers {
+				sender, err := types.Sender(signer, tx)
+				assert.NoError(t, err)
+				assert.Equal(t, vector.Sender, sender.Hex())
+			}
+
+			// The sender is only recovered on the chain the
+			// transaction was signed for, unless it is not
+			// replay protected.
+			for _, config := range chainConfigs {
+				sender, err := types.Sender(types.LatestSignerForChainID(config.ChainID), tx)
+				matches := !tx.Protected() || config.ChainID.Int64() == vector.ChainID
+				if matches {
+					assert.NoError(t, err)
+					assert.Equal(t, vector.Sender, sender.Hex())
+				} else {
+					assert.True(t, errors.Is(err, types.ErrInvalidChainId))
+				}
+
+				c := &Client{p: config}
+				result, err := c.decodeTransaction(map[string]interface{}{
+					"transaction": vector.Raw,
+				})
+				assert.NoError(t, err)
+				assert.Equal(t, vector.Hash, result["hash"])
+				assert.Equal(t, vector.Sender, result["from"])
+				assert.Equal(t, true, result["signed"])
+				assert.Equal(t, matches, result["chain_id_matches"])
+			}
+		})
+	}
+}
+
+func TestRoundMetadata(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+
+	c := &Client{
+		c:              mockJSONRPC,
+		traceSemaphore: semaphore.NewWeighted(100),
+	}
+
+	ctx := context.Background()
+	header := &types.Header{Number: big.NewInt(100)}
+	transfer := types.NewTransaction(0, common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d"), big.NewInt(1), 21000, big.NewInt(1), nil)
+
+	// Blocks without a turnRound call have no metadata
+	metadata, err := c.roundMetadata(ctx, types.NewBlockWithHeader(header).WithBody(
+		[]*types.Transaction{transfer},
+		nil,
+	))
+	assert.NoError(t, err)
+	assert.Nil(t, metadata)
+
+	turnRound := types.NewTransaction(1, CandidateHubContract, big.NewInt(0), 1000000, big.NewInt(0), turnRoundSelector)
+	block := types.NewBlockWithHeader(header).WithBody(
+		[]*types.Transaction{turnRound, transfer},
+		nil,
+	)
+
+	roundTag := hexutil.Encode(systemABI.Methods["roundTag"].ID)
+	contractCalls := []struct {
+		to     common.Address
+		data   string
+		block  string
+		result string
+	}{
+		{
+			to:     CandidateHubContract,
+			data:   roundTag,
+			block:  "0x64",
+			result: "0x0000000000000000000000000000000000000000000000000000000000004a39",
+		},
+		{
+			to:     CandidateHubContract,
+			data:   roundTag,
+			block:  "0x63",
+			result: "0x0000000000000000000000000000000000000000000000000000000000004a38",
+		},
+		{
+			to:    ValidatorSetContract,
+			data:  hexutil.Encode(systemABI.Methods["getValidators"].ID),
+			block: "0x64",
+			result: "0x0000000000000000000000000000000000000000000000000000000000000020" +
+				"0000000000000000000000000000000000000000000000000000000000000002" +
+				"000000000000000000000000e3a5b4d7f79d64088c8d4ef153a7dde2b2d47309" +
+				"00000000000000000000000057b414a0332b5cab885a451c2a28a07d1e9b8a8d",
+		},
+	}
+	for _, call := range contractCalls {
+		result := call.result
+		mockJSONRPC.On(
+			"CallContext",
+			ctx,
+			mock.Anything,
+			"eth_call",
+			map[string]string{
+				"to":   call.to.Hex(),
+				"data": call.data,
+			},
+			call.block,
+		).Return(
+			nil,
+		).Run(
+			func(args mock.Arguments) {
+				r := args.Get(1).(*string)
+				*r = result
+			},
+		).Once()
+	}
+
+	metadata, err = c.roundMetadata(ctx, block)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		RoundMetadataKey: map[string]interface{}{
+			"number":      float64(19001),
+			"start_index": float64(100),
+			"validators": []interface{}{
+				"0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309",
+				"0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d",
+			},
+			"previous_round": map[string]interface{}{
+				"number":    float64(19000),
+				"end_index": float64(99),
+			},
+		},
+	}, metadata)
+
+	mockJSONRPC.AssertExpectations(t)
+}
+
+func TestAttributeRewardOps(t *testing.T) {
+	coinbase := "0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"
+	feeAddress := "0x9cD4A6f3F1a5e1B05c0Ec5c6A8F8E5b0Ff1b2F10"
+	otherFeeAddress := "0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d"
+	sender := "0x4Ed7c70F96B99c776995fB64377f0d4aB3B0e1C1"
+	validators := map[common.Address]*Validator{
+		common.HexToAddress(coinbase): {
+			Operator:   "0x1Ca3E4C9B5d2b9B2B1E9b6e9A2A2cEeaE5F6B3A1",
+			Consensus:  coinbase,
+			FeeAddress: feeAddress,
+		},
+		common.HexToAddress("0x0000000000000000000000000000000000000001"): {
+			Consensus:  "0x0000000000000000000000000000000000000001",
+			FeeAddress: otherFeeAddress,
+		},
+	}
+	op := func(index int64, opType string, address string, value string, related ...int64) *RosettaTypes.Operation {
+		operation := &RosettaTypes.Operation{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: index},
+			Type:                opType,
+			Status:              RosettaTypes.String(SuccessStatus),
+			Account:             &RosettaTypes.AccountIdentifier{Address: address},
+			Amount:              &RosettaTypes.Amount{Value: value, Currency: Currency},
+		}
+		for _, r := range related {
+			operation.RelatedOperations = append(operation.RelatedOperations, &RosettaTypes.OperationIdentifier{Index: r})
+		}
+
+		return operation
+	}
+
+	validatorSet := ValidatorSetContract.Hex()
+	txs := []*RosettaTypes.Transaction{
+		{Operations: []*RosettaTypes.Operation{op(0, MinerRewardOpType, coinbase, "0")}},
+		{Operations: []*RosettaTypes.Operation{
+			op(0, FeeOpType, sender, "-21000"),
+			op(1, FeeOpType, coinbase, "21000", 0),
+			op(2, CallOpType, sender, "-5"),
+			op(3, CallOpType, feeAddress, "5", 2),
+		}},
+		{Operations: []*RosettaTypes.Operation{
+			op(0, FeeOpType, coinbase, "0"),
+			op(1, FeeOpType, coinbase, "0", 0),
+			op(2, CallOpType, coinbase, "-21000"),
+			op(3, CallOpType, validatorSet, "21000", 2),
+		}},
+		{Operations: []*RosettaTypes.Operation{
+			op(0, CallOpType, validatorSet, "-100"),
+			op(1, CallOpType, otherFeeAddress, "100", 0),
+		}},
+	}
+
+	attributeRewardOps(common.HexToAddress(coinbase), validators, txs)
+
+	assert.Equal(t, feeAddress, txs[0].Operations[0].Account.Address)
+	assert.Equal(t, map[string]interface{}{
+		consensusAddressMetadataKey: coinbase,
+		validatorMetadataKey:        "0x1Ca3E4C9B5d2b9B2B1E9b6e9A2A2cEeaE5F6B3A1",
+		RewardRecipientMetadataKey:  feeAddress,
+	}, txs[0].Operations[0].Metadata)
+
+	// Fees paid to the coinbase
+	assert.Nil(t, txs[1].Operations[0].Metadata)
+	assert.Equal(t, map[string]interface{}{
+		RewardRecipientMetadataKey: feeAddress,
+		RewardHopMetadataKey:       FeeRewardHop,
+	}, txs[1].Operations[1].Metadata)
+
+	// Transfers to a fee address are not rewards
+	assert.Nil(t, txs[1].Operations[3].Metadata)
+
+	// Deposit of the fees into ValidatorSet
+	assert.Nil(t, txs[2].Operations[1].Metadata)
+	for _, operation := range txs[2].Operations[2:] {
+		assert.Equal(t, map[string]interface{}{
+			RewardRecipientMetadataKey: feeAddress,
+			RewardHopMetadataKey:       DepositRewardHop,
+		}, operation.Metadata)
+	}
+
+	// Distribution of rewards to every fee address
+	for _, operation := range txs[3].Operations {
+		assert.Equal(t, map[string]interface{}{
+			RewardRecipientMetadataKey: otherFeeAddress,
+			RewardHopMetadataKey:       DistributionRewardHop,
+		}, operation.Metadata)
+	}
+
+	// Blocks of unknown validators are unchanged
+	txs = []*RosettaTypes.Transaction{
+		{Operations: []*RosettaTypes.Operation{op(0, MinerRewardOpType, sender, "0")}},
+	}
+	attributeRewardOps(common.HexToAddress(sender), validators, txs)
+	assert.Equal(t, sender, txs[0].Operations[0].Account.Address)
+	assert.Nil(t, txs[0].Operations[0].Metadata)
+}
+
+func TestAttributeRewards(t *testing.T) {
+	operator := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	consensus := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	feeAddress := common.HexToAddress("0x9cD4A6f3f1a5E1b05C0eC5C6a8f8E5b0ff1B2f10")
+
+	mockJSONRPC := &mocks.JSONRPC{}
+	c := &Client{
+		c:              mockJSONRPC,
+		traceSemaphore: semaphore.NewWeighted(100),
+		rewards:        newRewardResolver(FeeAddressRewardRecipient),
+	}
+	assert.Nil(t, newRewardResolver(CoinbaseRewardRecipient))
+	assert.Nil(t, newRewardResolver(""))
+
+	// The validators of the parent block (0x880eb0) are used.
+	block := types.NewBlockWithHeader(&types.Header{
+		Number:   big.NewInt(8916657),
+		Coinbase: consensus,
+	})
+	mockSystemCall(t, mockJSONRPC, systemABI, CandidateHubContract, "roundTag", nil, big.NewInt(19000))
+	mockSystemCall(t, mockJSONRPC, systemABI, ValidatorSetContract, "getValidators", nil, []common.Address{consensus})
+	mockSystemCall(
+		t,
+		mockJSONRPC,
+		stakingContracts,
+		ValidatorSetContract,
+		"currentValidatorSet",
+		[]interface{}{big.NewInt(0)},
+		operator,
+		consensus,
+		feeAddress,
+		big.NewInt(100),
+		big.NewInt(0),
+	)
+
+	newTxs := func() []*RosettaTypes.Transaction {
+		return []*RosettaTypes.Transaction{c.blockRewardTransaction(
+			&RosettaTypes.BlockIdentifier{Index: 8916657, Hash: "block"},
+			consensus.Hex(),
+			nil,
+		)}
+	}
+
+	txs := newTxs()
+	assert.NoError(t, c.attributeRewards(context.Background(), block, txs))
+	assert.Equal(t, feeAddress.Hex(), txs[0].Operations[0].Account.Address)
+	assert.Equal(t, "0", txs[0].Operations[0].Amount.Value)
+
+	// The validators of a round are cached.
+	mockSystemCall(t, mockJSONRPC, systemABI, CandidateHubContract, "roundTag", nil, big.NewInt(19000))
+	txs = newTxs()
+	assert.NoError(t, c.attributeRewards(context.Background(), block, txs))
+	assert.Equal(t, feeAddress.Hex(), txs[0].Operations[0].Account.Address)
+
+	mockJSONRPC.AssertExpectations(t)
+}
+
+func TestCheckInvariant(t *testing.T) {
+	blockIdentifier := &RosettaTypes.BlockIdentifier{Index: 100, Hash: "block 100"}
+	sender := "0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"
+	recipient := "0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d"
+	miner := "0x0000000000000000000000000000000000001000"
+
+	op := func(opType string, address string, value string, status string) *RosettaTypes.Operation {
+		return &RosettaTypes.Operation{
+			Type:    opType,
+			Status:  RosettaTypes.String(status),
+			Account: &RosettaTypes.AccountIdentifier{Address: address},
+			Amount:  &RosettaTypes.Amount{Value: value, Currency: Currency},
+		}
+	}
+
+	reward := &RosettaTypes.Transaction{
+		TransactionIdentifier: &RosettaTypes.TransactionIdentifier{Hash: blockIdentifier.Hash},
+		Operations: []*RosettaTypes.Operation{
+			op(MinerRewardOpType, miner, "3", SuccessStatus),
+		},
+	}
+	loadedTxs := []*loadedTransaction{
+		{FeeAmount: big.NewInt(30), FeeBurned: big.NewInt(10)},
+	}
+	tx := &RosettaTypes.Transaction{
+		TransactionIdentifier: &RosettaTypes.TransactionIdentifier{Hash: "0x1"},
+		Operations: []*RosettaTypes.Operation{
+			op(FeeOpType, sender, "-20", SuccessStatus),
+			op(FeeOpType, miner, "20", SuccessStatus),
+			op(FeeOpType, sender, "-10", SuccessStatus),
+			op(CallOpType, sender, "-100", SuccessStatus),
+			op(CallOpType, recipient, "100", SuccessStatus),
+			op(CallOpType, sender, "-50", FailureStatus),
+			op(SelfDestructOpType, recipient, "-100", SuccessStatus),
+			op(SelfDestructOpType, sender, "100", SuccessStatus),
+			op(CallOpType, recipient, "-7", SuccessStatus),
+			op(CallOpType, sender, "7", SuccessStatus),
+			op(DestructOpType, sender, "-7", SuccessStatus),
+			{
+				Type:    ApprovalOpType,
+				Status:  RosettaTypes.String(SuccessStatus),
+				Account: &RosettaTypes.AccountIdentifier{Address: sender},
+			},
+		},
+	}
+
+	assert.NoError(t, checkInvariant(
+		blockIdentifier,
+		[]*RosettaTypes.Transaction{reward, tx},
+		loadedTxs,
+	))
+
+	// A missing credit is reported with the transaction
+	tx.Operations = tx.Operations[1:]
+	err := checkInvariant(
+		blockIdentifier,
+		[]*RosettaTypes.Transaction{reward, tx},
+		loadedTxs,
+	)
+	assert.True(t, errors.Is(err, ErrInvariantViolated))
+	assert.Contains(t, err.Error(), "operations sum to 6 but expected -14")
+	assert.Contains(t, err.Error(), "unbalanced transactions: [0x1: 20]")
+}
+
+func TestDialRPC(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := dialRPC("ftp://localhost", nil)
+	assert.EqualError(t, err, "unsupported scheme \"ftp\"")
+
+	// WebSocket endpoints
+	server := rpc.NewServer()
+	defer server.Stop()
+	wsServer := httptest.NewServer(server.WebsocketHandler([]string{"*"}))
+	defer wsServer.Close()
+
+	c, err := dialRPC("ws"+strings.TrimPrefix(wsServer.URL, "http"), nil)
+	assert.NoError(t, err)
+	var modules map[string]string
+	assert.NoError(t, c.CallContext(ctx, &modules, "rpc_modules"))
+	assert.Contains(t, modules, "rpc")
+	c.Close()
+
+	// HTTP endpoints through a proxy
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+
+		var request struct {
+			ID json.RawMessage `json:"id"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x1"}`, request.ID)
+	}))
+	defer proxy.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	assert.NoError(t, err)
+	c, err = dialRPC("http://node.internal:8545", proxyURL)
+	assert.NoError(t, err)
+	var result string
+	assert.NoError(t, c.CallContext(ctx, &result, "eth_blockNumber"))
+	assert.Equal(t, "0x1", result)
+	assert.Equal(t, "http://node.internal:8545/", proxied)
+	c.Close()
+}
+
+func TestHTTPURL(t *testing.T) {
+	for endpoint, expected := range map[string]string{
+		"http://localhost:8545": "http://localhost:8545",
+		"ws://localhost:8546":   "http://localhost:8546",
+		"wss://node.internal":   "https://node.internal",
+	} {
+		u, err := httpURL(endpoint)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, u.String())
+	}
+}
+
+func TestTraceBlock_Fallback(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	tc, err := testTraceConfig()
+	assert.NoError(t, err)
+	c := &Client{
+		c:              mockJSONRPC,
+		tc:             tc,
+		traceSemaphore: semaphore.NewWeighted(100),
+	}
+
+	ctx := context.Background()
+	blockHash := common.HexToHash("0x01")
+	recipient := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	txs := []rpcTransaction{
+		{tx: types.NewTransaction(0, recipient, big.NewInt(1), 21000, big.NewInt(1), nil)},
+		{tx: types.NewTransaction(1, recipient, big.NewInt(1), 21000, big.NewInt(1), nil)},
+	}
+	rawTrace := json.RawMessage(`{"type":"CALL","from":"0xe3a5b4d7f79d64088c8d4ef153a7dde2b2d47309","to":"0x57b414a0332b5cab885a451c2a28a07d1e9b8a8d","value":"0x1","gasUsed":"0x0"}`) // nolint
+
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"debug_traceBlockByHash",
+		blockHash,
+		tc,
+	).Return(
+		errors.New("execution timeout"),
+	).Once()
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"debug_traceTransaction",
+		txs[0].tx.Hash(),
+		tc,
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).(*json.RawMessage)
+			*r = rawTrace
+		},
+	).Once()
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"debug_traceTransaction",
+		txs[1].tx.Hash(),
+		tc,
+	).Return(
+		errors.New("out of memory"),
+	).Once()
+
+	traces, rawTraces, err := c.traceBlock(ctx, blockHash, nil, txs)
+	assert.NoError(t, err)
+	assert.Len(t, traces, 2)
+	assert.Equal(t, recipient, traces[0].Result.To)
+	assert.Equal(t, rawTrace, rawTraces[0].Result)
+	assert.Nil(t, traces[1].Result)
+	assert.Nil(t, rawTraces[1].Result)
+
+	// Canceled requests are not degraded
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	mockJSONRPC.On(
+		"CallContext",
+		canceled,
+		mock.Anything,
+		"debug_traceBlockByHash",
+		blockHash,
+		tc,
+	).Return(
+		context.Canceled,
+	).Maybe()
+	_, _, err = c.traceBlock(canceled, blockHash, nil, txs)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	mockJSONRPC.AssertExpectations(t)
+}
+
+func TestBlockPage(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	tc, err := testTraceConfig()
+	assert.NoError(t, err)
+	c := &Client{
+		c:              mockJSONRPC,
+		tc:             tc,
+		p:              params.RopstenChainConfig,
+		traceSemaphore: semaphore.NewWeighted(100),
+	}
+
+	ctx := context.Background()
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_getBlockByNumber",
+		"0x58b97",
+		true,
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).(*json.RawMessage)
+
+			file, err := ioutil.ReadFile("testdata/block_363415.json")
+			assert.NoError(t, err)
+
+			*r = json.RawMessage(file)
+		},
+	).Once()
+
+	// Only the receipt and trace of the
+	// inlined transaction are fetched, tracing
+	// the block truncated to it
+	inlined := "0x9e0f7c64a5bf1fc9f3d7b7963cf23f74e3d2c0b2b3f35f26df031954e5581179"
+	mockJSONRPC.On(
+		"BatchCallContext",
+		ctx,
+		mock.Anything,
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).([]rpc.BatchElem)
+
+			assert.Len(t, r, 1)
+			assert.Equal(t, inlined, r[0].Args[0])
+
+			file, err := ioutil.ReadFile("testdata/tx_receipt_" + inlined + ".json")
+			assert.NoError(t, err)
+
+			receipt := new(types.Receipt)
+			assert.NoError(t, receipt.UnmarshalJSON(file))
+			*(r[0].Result.(**types.Receipt)) = receipt
+		},
+	).Once()
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"debug_traceBlock",
+		mock.Anything,
+		tc,
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			var block types.Block
+			assert.NoError(t, rlp.DecodeBytes(args.Get(3).(hexutil.Bytes), &block))
+			assert.Equal(t, int64(363415), block.Number().Int64())
+			assert.Len(t, block.Transactions(), 1)
+			assert.Equal(t, inlined, block.Transactions()[0].Hash().Hex())
+
+			r := args.Get(1).(*json.RawMessage)
+
+			file, err := ioutil.ReadFile(
+				"testdata/block_trace_0xf0445269b02ba461af662d8c6aac50d9557a0cc9dbe580d3e180efd7879cc79e.json",
+			) // nolint
+			assert.NoError(t, err)
+
+			var traces []*rpcRawCall
+			assert.NoError(t, json.Unmarshal(file, &traces))
+			raw, err := json.Marshal(traces[:1])
+			assert.NoError(t, err)
+			*r = raw
+		},
+	).Once()
+
+	block, otherTransactions, err := c.BlockPage(
+		ctx,
+		&RosettaTypes.PartialBlockIdentifier{
+			Index: RosettaTypes.Int64(363415),
+		},
+		2,
+	)
+	assert.NoError(t, err)
+	assert.Len(t, block.Transactions, 2)
+	assert.Equal(t, inlined, block.Transactions[1].TransactionIdentifier.Hash)
+	assert.Contains(t, block.Transactions[1].Metadata, "trace")
+	assert.Equal(t, []*RosettaTypes.TransactionIdentifier{
+		{Hash: "0x0046a7c3ca126864a3e851235ca6bf030300f9138f035f5f190e59ff9a4b22ff"},
+	}, otherTransactions)
+
+	mockJSONRPC.AssertExpectations(t)
+}
+
+func TestPopulateTransaction_TraceUnavailable(t *testing.T) {
+	sender := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	recipient := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	c := &Client{}
+
+	tx := &loadedTransaction{
+		Transaction: types.NewTransaction(0, recipient, big.NewInt(1), 21000, big.NewInt(1), nil),
+		From:        &sender,
+		FeeAmount:   big.NewInt(21000),
+		Miner:       recipient.Hex(),
+		Receipt:     &types.Receipt{},
+	}
+
+	populated, err := c.populateTransaction(tx)
+	assert.NoError(t, err)
+	assert.Len(t, populated.Operations, 2)
+	for _, op := range populated.Operations {
+		assert.Equal(t, FeeOpType, op.Type)
+	}
+	assert.Equal(t, true, populated.Metadata[TraceUnavailableMetadataKey])
+	assert.NotContains(t, populated.Metadata, "trace")
+}
+
+func TestPopulateTransaction_Degraded(t *testing.T) {
+	sender := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	recipient := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	c := &Client{}
+
+	tx := &loadedTransaction{
+		Transaction: types.NewTransaction(0, recipient, big.NewInt(5), 21000, big.NewInt(1), nil),
+		From:        &sender,
+		FeeAmount:   big.NewInt(21000),
+		Miner:       recipient.Hex(),
+		Receipt:     &types.Receipt{Status: types.ReceiptStatusSuccessful, GasUsed: 21000},
+		Degraded:    true,
+	}
+
+	populated, err := c.populateTransaction(tx)
+	assert.NoError(t, err)
+	assert.Len(t, populated.Operations, 4)
+	assert.Equal(t, CallOpType, populated.Operations[2].Type)
+	assert.Equal(t, sender.Hex(), populated.Operations[2].Account.Address)
+	assert.Equal(t, "-5", populated.Operations[2].Amount.Value)
+	assert.Equal(t, SuccessStatus, *populated.Operations[2].Status)
+	assert.Equal(t, recipient.Hex(), populated.Operations[3].Account.Address)
+	assert.Equal(t, "5", populated.Operations[3].Amount.Value)
+	assert.Equal(t, true, populated.Metadata[TraceUnavailableMetadataKey])
+	assert.NotContains(t, populated.Metadata, "trace")
+
+	// A failed external call moves no value.
+	tx.Receipt.Status = types.ReceiptStatusFailed
+	populated, err = c.populateTransaction(tx)
+	assert.NoError(t, err)
+	assert.Len(t, populated.Operations, 4)
+	assert.Equal(t, FailureStatus, *populated.Operations[2].Status)
+	assert.NotContains(t, populated.Metadata, FailureReasonMetadataKey)
+}
+
+func TestFailureReason(t *testing.T) {
+	tests := map[string]struct {
+		trace    string
+		expected *FailureReason
+	}{
+		"success": {
+			trace: `{"type":"CALL","output":"0x"}`,
+		},
+		"revert reason": {
+			trace: `{"type":"CALL","error":"execution reverted","output":"0x08c379a0000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000000127472616e7366657220746f6f206c617267650000000000000000000000000000"}`,
+			expected: &FailureReason{
+				Error:  "execution reverted",
+				Reason: "transfer too large",
+			},
+		},
+		"panic": {
+			trace: `{"type":"CALL","error":"execution reverted","output":"0x4e487b710000000000000000000000000000000000000000000000000000000000000011"}`,
+			expected: &FailureReason{
+				Error:       "execution reverted",
+				PanicCode:   "0x11",
+				PanicReason: "arithmetic overflow or underflow",
+			},
+		},
+		"custom error": {
+			trace: `{"type":"CALL","error":"execution reverted","output":"0x12345678"}`,
+			expected: &FailureReason{
+				Error:  "execution reverted",
+				Output: "0x12345678",
+			},
+		},
+		"out of gas": {
+			trace: `{"type":"CALL","error":"out of gas"}`,
+			expected: &FailureReason{
+				Error: "out of gas",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var trace Call
+			assert.NoError(t, json.Unmarshal([]byte(test.trace), &trace))
+			assert.Equal(t, test.expected, failureReason(&trace))
+		})
+	}
+
+	// The failure reason is attached to failed transactions
+	sender := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	recipient := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	rawTrace := json.RawMessage(`{"type":"CALL","error":"out of gas"}`)
+	var trace Call
+	assert.NoError(t, json.Unmarshal(rawTrace, &trace))
+
+	c := &Client{}
+	populated, err := c.populateTransaction(&loadedTransaction{
+		Transaction: types.NewTransaction(0, recipient, big.NewInt(1), 21000, big.NewInt(1), nil),
+		From:        &sender,
+		FeeAmount:   big.NewInt(21000),
+		Miner:       recipient.Hex(),
+		Receipt:     &types.Receipt{Status: types.ReceiptStatusFailed},
+		Trace:       &trace,
+		RawTrace:    rawTrace,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"error": "out of gas",
+	}, populated.Metadata[FailureReasonMetadataKey])
+}
+
+func TestSetSystemContracts(t *testing.T) {
+	defaults := SystemContracts()
+	defer func() {
+		assert.NoError(t, SetSystemContracts(defaults))
+	}()
+
+	pledgeAgent := common.HexToAddress("0x0000000000000000000000000000000000002007")
+	assert.NoError(t, SetSystemContracts(map[string]common.Address{
+		"PledgeAgent": pledgeAgent,
+	}))
+	assert.Equal(t, pledgeAgent, PledgeAgentContract)
+	assert.Equal(t, defaults["ValidatorSet"], ValidatorSetContract)
+
+	// Unknown contracts are rejected without applying any change
+	err := SetSystemContracts(map[string]common.Address{
+		"ValidatorSet": pledgeAgent,
+		"Unknown":      pledgeAgent,
+	})
+	assert.EqualError(t, err, "Unknown is not a system contract")
+	assert.Equal(t, defaults["ValidatorSet"], ValidatorSetContract)
+}
+
+// mockSystemCall mocks an eth_call of method on to at block 0x880eb0
+// (the block of testdata/basic_header.json) returning outputs.
+func mockSystemCall(
+	t *testing.T,
+	mockJSONRPC *mocks.JSONRPC,
+	contractABI abi.ABI,
+	to common.Address,
+	method string,
+	args []interface{},
+	outputs ...interface{},
+) {
+	data, err := contractABI.Pack(method, args...)
+	assert.NoError(t, err)
+	result, err := contractABI.Methods[method].Outputs.Pack(outputs...)
+	assert.NoError(t, err)
+
+	mockJSONRPC.On(
+		"CallContext",
+		mock.Anything,
+		mock.Anything,
+		"eth_call",
+		map[string]string{
+			"to":   to.Hex(),
+			"data": hexutil.Encode(data),
+		},
+		"0x880eb0",
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).(*string)
+			*r = hexutil.Encode(result)
+		},
+	).Once()
+}
+
+func mockLatestHeader(t *testing.T, mockJSONRPC *mocks.JSONRPC) {
+	mockJSONRPC.On(
+		"CallContext",
+		mock.Anything,
+		mock.Anything,
+		"eth_getBlockByNumber",
+		"latest",
+		false,
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			header := args.Get(1).(**types.Header)
+			file, err := ioutil.ReadFile("testdata/basic_header.json")
+			assert.NoError(t, err)
+
+			*header = new(types.Header)
+
+			assert.NoError(t, (*header).UnmarshalJSON(file))
+		},
+	).Once()
+}
+
+func TestCall_Validators(t *testing.T) {
+	operator := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	consensus := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	feeAddress := common.HexToAddress("0x9cD4A6f3f1a5E1b05C0eC5C6a8f8E5b0ff1B2f10")
+	deposit, _ := new(big.Int).SetString("5000000000000000000000", 10)
+	income, _ := new(big.Int).SetString("12000000000000000000", 10)
+	blockIdentifier := map[string]interface{}{
+		"hash":  "0x48269a339ce1489cff6bab70eff432289c4f490b81dbd00ff1f81c68de06b842",
+		"index": float64(8916656),
+	}
+
+	mockValidatorSet := func(mockJSONRPC *mocks.JSONRPC) {
+		mockSystemCall(
+			t,
+			mockJSONRPC,
+			systemABI,
+			ValidatorSetContract,
+			"getValidators",
+			nil,
+			[]common.Address{consensus},
+		)
+		mockSystemCall(
+			t,
+			mockJSONRPC,
+			stakingContracts,
+			ValidatorSetContract,
+			"currentValidatorSet",
+			[]interface{}{big.NewInt(0)},
+			operator,
+			consensus,
+			feeAddress,
+			big.NewInt(100),
+			income,
+		)
+	}
+	validator := map[string]interface{}{
+		"operator":               operator.Hex(),
+		"consensus":              consensus.Hex(),
+		"fee_address":            feeAddress.Hex(),
+		"commission_thousandths": float64(100),
+		"income":                 income.String(),
+	}
+
+	tests := map[string]struct {
+		method string
+		params map[string]interface{}
+		mock   func(*mocks.JSONRPC)
+		result map[string]interface{}
+	}{
+		"validator_set": {
+			method: ValidatorSetMethod,
+			params: map[string]interface{}{},
+			mock: func(mockJSONRPC *mocks.JSONRPC) {
+				mockSystemCall(t, mockJSONRPC, systemABI, CandidateHubContract, "roundTag", nil, big.NewInt(19000))
+				mockValidatorSet(mockJSONRPC)
+			},
+			result: map[string]interface{}{
+				"block_identifier": blockIdentifier,
+				"round":            float64(19000),
+				"validators":       []interface{}{validator},
+			},
+		},
+		"validator_stake": {
+			method: ValidatorStakeMethod,
+			params: map[string]interface{}{
+				"validator": operator.Hex(),
+			},
+			mock: func(mockJSONRPC *mocks.JSONRPC) {
+				mockSystemCall(
+					t,
+					mockJSONRPC,
+					stakingContracts,
+					PledgeAgentContract,
+					"agentsMap",
+					[]interface{}{operator},
+					deposit,
+					big.NewInt(0),
+					deposit,
+				)
+			},
+			result: map[string]interface{}{
+				"block_identifier": blockIdentifier,
+				"validator":        operator.Hex(),
+				"total_deposit":    deposit.String(),
+				"power":            "0",
+				"coin":             deposit.String(),
+			},
+		},
+		"delegator_rewards": {
+			method: DelegatorRewardsMethod,
+			params: map[string]interface{}{
+				"delegator": feeAddress.Hex(),
+			},
+			mock: func(mockJSONRPC *mocks.JSONRPC) {
+				mockSystemCall(t, mockJSONRPC, systemABI, CandidateHubContract, "roundTag", nil, big.NewInt(19000))
+				mockSystemCall(
+					t,
+					mockJSONRPC,
+					stakingContracts,
+					CandidateHubContract,
+					"getCandidates",
+					nil,
+					[]common.Address{operator, consensus},
+				)
+				mockSystemCall(
+					t,
+					mockJSONRPC,
+					pledgeAgent,
+					PledgeAgentContract,
+					"getDelegator",
+					[]interface{}{operator, feeAddress},
+					deposit,
+					deposit,
+					big.NewInt(18990),
+					big.NewInt(2),
+				)
+				mockSystemCall(
+					t,
+					mockJSONRPC,
+					pledgeAgent,
+					PledgeAgentContract,
+					"getDelegator",
+					[]interface{}{consensus, feeAddress},
+					big.NewInt(0),
+					big.NewInt(0),
+					big.NewInt(0),
+					big.NewInt(0),
+				)
+				mockSystemCall(
+					t,
+					mockJSONRPC,
+					pledgeAgent,
+					PledgeAgentContract,
+					"rewardMap",
+					[]interface{}{feeAddress},
+					big.NewInt(12),
+				)
+
+				// Claims are simulated from the delegator and
+				// include the rewards already settled.
+				data, err := pledgeAgent.Pack("claimReward", []common.Address{operator})
+				assert.NoError(t, err)
+				output, err := pledgeAgent.Methods["claimReward"].Outputs.Pack(big.NewInt(112), true)
+				assert.NoError(t, err)
+				mockJSONRPC.On(
+					"CallContext",
+					mock.Anything,
+					mock.Anything,
+					"eth_call",
+					map[string]string{
+						"from": feeAddress.Hex(),
+						"to":   PledgeAgentContract.Hex(),
+						"data": hexutil.Encode(data),
+					},
+					"0x880eb0",
+				).Return(nil).Run(func(args mock.Arguments) {
+					*(args.Get(1).(*string)) = hexutil.Encode(output)
+				}).Once()
+			},
+			result: map[string]interface{}{
+				"block_identifier": blockIdentifier,
+				"delegator":        feeAddress.Hex(),
+				"round":            float64(19000),
+				"settled":          "12",
+				"accrued":          "112",
+				"complete":         true,
+				"validators": []interface{}{
+					map[string]interface{}{
+						"validator": operator.Hex(),
+						"deposit":   deposit.String(),
+						"accrued":   "100",
+					},
+				},
+			},
+		},
+		"validator_apr_inputs": {
+			method: ValidatorAPRInputsMethod,
+			params: map[string]interface{}{},
+			mock: func(mockJSONRPC *mocks.JSONRPC) {
+				mockSystemCall(t, mockJSONRPC, systemABI, CandidateHubContract, "roundTag", nil, big.NewInt(19000))
+				mockSystemCall(t, mockJSONRPC, systemABI, ValidatorSetContract, "blockReward", nil, big.NewInt(3000))
+				mockSystemCall(
+					t,
+					mockJSONRPC,
+					systemABI,
+					ValidatorSetContract,
+					"blockRewardIncentivePercent",
+					nil,
+					big.NewInt(5),
+				)
+				mockValidatorSet(mockJSONRPC)
+				mockSystemCall(
+					t,
+					mockJSONRPC,
+					stakingContracts,
+					PledgeAgentContract,
+					"agentsMap",
+					[]interface{}{operator},
+					deposit,
+					big.NewInt(0),
+					deposit,
+				)
+			},
+			result: map[string]interface{}{
+				"block_identifier":               blockIdentifier,
+				"round":                          float64(19000),
+				"block_reward":                   "3000",
+				"block_reward_incentive_percent": float64(5),
+				"validators": []interface{}{
+					map[string]interface{}{
+						"operator":               operator.Hex(),
+						"consensus":              consensus.Hex(),
+						"fee_address":            feeAddress.Hex(),
+						"commission_thousandths": float64(100),
+						"income":                 income.String(),
+						"total_deposit":          deposit.String(),
+					},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockJSONRPC := &mocks.JSONRPC{}
+			mockGraphQL := &mocks.GraphQL{}
+
+			c := &Client{
+				c:              mockJSONRPC,
+				g:              mockGraphQL,
+				traceSemaphore: semaphore.NewWeighted(100),
+			}
+
+			mockLatestHeader(t, mockJSONRPC)
+			test.mock(mockJSONRPC)
+
+			resp, err := c.Call(
+				context.Background(),
+				&RosettaTypes.CallRequest{
+					Method:     test.method,
+					Parameters: test.params,
+				},
+			)
+			assert.NoError(t, err)
+			assert.Equal(t, test.result, resp.Result)
+
+			mockJSONRPC.AssertExpectations(t)
+			mockGraphQL.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCall_Multicall(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	c := &Client{
+		c:              mockJSONRPC,
+		traceSemaphore: semaphore.NewWeighted(100),
+	}
+	ctx := context.Background()
+
+	token := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	data, err := multicallABI.Pack("tryAggregate", false, []multicallCall{
+		{Target: token, CallData: []byte{0x18, 0x16, 0x0d, 0xdd}},
+		{Target: ValidatorSetContract, CallData: []byte{}},
+	})
+	assert.NoError(t, err)
+	output, err := multicallABI.Methods["tryAggregate"].Outputs.Pack([]multicallResult{
+		{Success: true, ReturnData: common.LeftPadBytes([]byte{0x64}, 32)},
+		{Success: false, ReturnData: []byte{}},
+	})
+	assert.NoError(t, err)
+
+	mockLatestHeader(t, mockJSONRPC)
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_call",
+		map[string]string{
+			"to":   MulticallContract.Hex(),
+			"data": hexutil.Encode(data),
+		},
+		"0x48269a339ce1489cff6bab70eff432289c4f490b81dbd00ff1f81c68de06b842",
+	).Return(nil).Run(func(args mock.Arguments) {
+		*(args.Get(1).(*string)) = hexutil.Encode(output)
+	}).Once()
+
+	resp, err := c.Call(ctx, &RosettaTypes.CallRequest{
+		Method: "eth_call",
+		Parameters: map[string]interface{}{
+			"calls": []interface{}{
+				map[string]interface{}{"to": token.Hex(), "data": "0x18160ddd"},
+				map[string]interface{}{"to": ValidatorSetContract.Hex(), "data": "0x"},
+			},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"block_identifier": map[string]interface{}{
+			"hash":  "0x48269a339ce1489cff6bab70eff432289c4f490b81dbd00ff1f81c68de06b842",
+			"index": float64(8916656),
+		},
+		"results": []interface{}{
+			map[string]interface{}{
+				"success": true,
+				"data":    "0x0000000000000000000000000000000000000000000000000000000000000064",
+			},
+			map[string]interface{}{
+				"success": false,
+				"data":    "0x",
+			},
+		},
+	}, resp.Result)
+
+	mockJSONRPC.AssertExpectations(t)
+}
+
+func TestCall_Multicall_InvalidArgs(t *testing.T) {
+	c := &Client{c: &mocks.JSONRPC{}}
+	tooMany := make([]interface{}, MaxMulticallCalls+1)
+	for i := range tooMany {
+		tooMany[i] = map[string]interface{}{"to": ValidatorSetContract.Hex(), "data": "0x"}
+	}
+
+	tests := map[string][]interface{}{
+		"empty":           {},
+		"invalid to":      {map[string]interface{}{"to": "0x1234", "data": "0x"}},
+		"invalid data":    {map[string]interface{}{"to": ValidatorSetContract.Hex(), "data": "1234"}},
+		"too many calls":  tooMany,
+		"missing address": {map[string]interface{}{"data": "0x"}},
+	}
+
+	for name, calls := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, err := c.Call(context.Background(), &RosettaTypes.CallRequest{
+				Method:     "eth_call",
+				Parameters: map[string]interface{}{"calls": calls},
+			})
+			assert.Nil(t, resp)
+			assert.True(t, errors.Is(err, ErrCallParametersInvalid))
+		})
+	}
+}
+
+func TestCall_ValidatorStake_InvalidArgs(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	mockGraphQL := &mocks.GraphQL{}
+
+	c := &Client{
+		c:              mockJSONRPC,
+		g:              mockGraphQL,
+		traceSemaphore: semaphore.NewWeighted(100),
+	}
+
+	resp, err := c.Call(
+		context.Background(),
+		&RosettaTypes.CallRequest{
+			Method: ValidatorStakeMethod,
+			Parameters: map[string]interface{}{
+				"validator": "not an address",
+			},
+		},
+	)
+	assert.Nil(t, resp)
+	assert.True(t, errors.Is(err, ErrCallParametersInvalid))
+
+	mockJSONRPC.AssertExpectations(t)
+	mockGraphQL.AssertExpectations(t)
+}
+
+func TestBlockDigest(t *testing.T) {
+	block := &RosettaTypes.Block{
+		BlockIdentifier: &RosettaTypes.BlockIdentifier{
+			Hash:  "0x48269a339ce1489cff6bab70eff432289c4f490b81dbd00ff1f81c68de06b842",
+			Index: 8916656,
+		},
+		ParentBlockIdentifier: &RosettaTypes.BlockIdentifier{
+			Hash:  "0x6b9f8f5388ea4ff227d1d9b79594694a554dc3c75cd26f73ccc548ebe884b0b1",
+			Index: 8916655,
+		},
+		Timestamp: 1603225195000,
+		Transactions: []*RosettaTypes.Transaction{
+			{
+				TransactionIdentifier: &RosettaTypes.TransactionIdentifier{
+					Hash: "0x48269a339ce1489cff6bab70eff432289c4f490b81dbd00ff1f81c68de06b842",
+				},
+				Operations: []*RosettaTypes.Operation{
+					{
+						OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 0},
+						Type:                MinerRewardOpType,
+						Status:              RosettaTypes.String(SuccessStatus),
+						Account: &RosettaTypes.AccountIdentifier{
+							Address: "0x52351e33b3c693Cc05F21831647EBdAb8A68eb95",
+						},
+						Amount: &RosettaTypes.Amount{
+							Value:    "3000000000000000000",
+							Currency: Currency,
+						},
+					},
+				},
+			},
+		},
+		Metadata: map[string]interface{}{
+			RoundMetadataKey: map[string]interface{}{
+				"number": 19000,
+			},
+		},
+	}
+
+	digest, err := BlockDigest(block)
+	assert.NoError(t, err)
+	assert.NoError(t, addBlockDigest(block))
+	assert.Equal(t, digest, block.Metadata[DigestMetadataKey])
+
+	// The digest is not part of the digest, so it can be
+	// recomputed from a served (JSON encoded) block.
+	encoded, err := json.Marshal(block)
+	assert.NoError(t, err)
+	var served RosettaTypes.Block
+	assert.NoError(t, json.Unmarshal(encoded, &served))
+	servedDigest, err := BlockDigest(&served)
+	assert.NoError(t, err)
+	assert.Equal(t, digest, servedDigest)
+
+	// Any change to the conversion changes the digest
+	served.Transactions[0].Operations[0].Amount.Value = "2000000000000000000"
+	changedDigest, err := BlockDigest(&served)
+	assert.NoError(t, err)
+	assert.NotEqual(t, digest, changedDigest)
+}
+
+func TestTimestampStartIndex(t *testing.T) {
+	validTime := uint64(1600000000)
+	tests := map[string]struct {
+		genesisTime uint64
+		firstValid  int64
+		head        int64
+		index       int64
+		cached      bool
+	}{
+		"valid genesis": {
+			genesisTime: validTime,
+			head:        100,
+			index:       0,
+			cached:      true,
+		},
+		"first block valid": {
+			firstValid: 1,
+			head:       100,
+			index:      1,
+			cached:     true,
+		},
+		"later block valid": {
+			firstValid: 37,
+			head:       100,
+			index:      37,
+			cached:     true,
+		},
+		"head is first valid block": {
+			firstValid: 100,
+			head:       100,
+			index:      100,
+			cached:     true,
+		},
+		"no valid block": {
+			firstValid: 200,
+			head:       100,
+			index:      101,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockJSONRPC := &mocks.JSONRPC{}
+			c := &Client{c: mockJSONRPC}
+
+			headerAt := func(index int64) *types.Header {
+				header := &types.Header{
+					Number:     big.NewInt(index),
+					Difficulty: big.NewInt(0),
+				}
+				switch {
+				case index == GenesisBlockIndex:
+					header.Time = test.genesisTime
+				case index >= test.firstValid:
+					header.Time = validTime
+				}
+
+				return header
+			}
+
+			mockJSONRPC.On(
+				"CallContext",
+				mock.Anything,
+				mock.Anything,
+				"eth_getBlockByNumber",
+				mock.Anything,
+				false,
+			).Return(
+				nil,
+			).Run(
+				func(args mock.Arguments) {
+					index := test.head
+					if arg := args.Get(3).(string); arg != "latest" {
+						index = hexutil.MustDecodeBig(arg).Int64()
+					}
+
+					header := args.Get(1).(**types.Header)
+					*header = headerAt(index)
+				},
+			)
+
+			index, err := c.TimestampStartIndex(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, test.index, index)
+
+			calls := len(mockJSONRPC.Calls)
+			index, err = c.TimestampStartIndex(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, test.index, index)
+			if test.cached {
+				assert.Len(t, mockJSONRPC.Calls, calls)
+			} else {
+				assert.Greater(t, len(mockJSONRPC.Calls), calls)
+			}
+		})
+	}
+}
+
+func TestCollapseOps(t *testing.T) {
+	op := func(opType string, status string, address string, value string) *RosettaTypes.Operation {
+		o := &RosettaTypes.Operation{
+			Type:    opType,
+			Status:  RosettaTypes.String(status),
+			Account: &RosettaTypes.AccountIdentifier{Address: address},
+		}
+		if len(value) > 0 {
+			o.Amount = &RosettaTypes.Amount{Value: value, Currency: Currency}
+		}
+
+		return o
+	}
+
+	a := "0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"
+	b := "0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d"
+	c := "0x52351e33b3c693Cc05F21831647EBdAb8A68eb95"
+	d := "0x0000000000000000000000000000000000001007"
+	ops := []*RosettaTypes.Operation{
+		op(CallOpType, SuccessStatus, a, "-10"),
+		op(CallOpType, SuccessStatus, b, "10"),
+		op(CallOpType, SuccessStatus, b, "-4"),
+		op(CallOpType, SuccessStatus, c, "4"),
+		op(CallOpType, FailureStatus, c, "-1"),
+		op(CallOpType, FailureStatus, d, "1"),
+		op(DelegateCallOpType, SuccessStatus, b, ""),
+		op(CallOpType, SuccessStatus, d, "-2"),
+		op(CallOpType, SuccessStatus, d, "2"),
+		op(DestructOpType, SuccessStatus, c, "-4"),
+	}
+
+	assert.Equal(t, []*RosettaTypes.Operation{
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 1},
+			Type:                CallOpType,
+			Status:              RosettaTypes.String(SuccessStatus),
+			Account:             &RosettaTypes.AccountIdentifier{Address: a},
+			Amount:              &RosettaTypes.Amount{Value: "-10", Currency: Currency},
+		},
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 2},
+			Type:                CallOpType,
+			Status:              RosettaTypes.String(SuccessStatus),
+			Account:             &RosettaTypes.AccountIdentifier{Address: b},
+			Amount:              &RosettaTypes.Amount{Value: "6", Currency: Currency},
+		},
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 3},
+			Type:                CallOpType,
+			Status:              RosettaTypes.String(SuccessStatus),
+			Account:             &RosettaTypes.AccountIdentifier{Address: c},
+			Amount:              &RosettaTypes.Amount{Value: "4", Currency: Currency},
+		},
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 4},
+			Type:                DestructOpType,
+			Status:              RosettaTypes.String(SuccessStatus),
+			Account:             &RosettaTypes.AccountIdentifier{Address: c},
+			Amount:              &RosettaTypes.Amount{Value: "-4", Currency: Currency},
+		},
+	}, collapseOps(ops, 1))
+}
+
+func TestFilterDust(t *testing.T) {
+	op := func(index int64, opType string, status string, address string, value string) *RosettaTypes.Operation {
+		o := &RosettaTypes.Operation{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: index},
+			Type:                opType,
+			Status:              RosettaTypes.String(status),
+			Account:             &RosettaTypes.AccountIdentifier{Address: address},
+		}
+		if len(value) > 0 {
+			o.Amount = &RosettaTypes.Amount{Value: value, Currency: Currency}
+		}
+		if index%2 == 0 {
+			o.RelatedOperations = []*RosettaTypes.OperationIdentifier{{Index: index - 1}}
+		}
+
+		return o
+	}
+
+	a := "0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"
+	b := "0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d"
+	c := "0x52351e33b3c693Cc05F21831647EBdAb8A68eb95"
+	ops := func() []*RosettaTypes.Operation {
+		return []*RosettaTypes.Operation{
+			op(1, CallOpType, SuccessStatus, a, "-5"),
+			op(2, CallOpType, SuccessStatus, b, "5"),
+			op(3, CallOpType, SuccessStatus, a, "-100"),
+			op(4, CallOpType, SuccessStatus, c, "100"),
+			op(5, CallOpType, SuccessStatus, b, "-3"),
+			op(6, CallOpType, SuccessStatus, c, "3"),
+			op(7, CallOpType, FailureStatus, a, "-1"),
+			op(8, CallOpType, FailureStatus, c, "1"),
+			op(9, DelegateCallOpType, SuccessStatus, a, ""),
+			op(10, CallOpType, SuccessStatus, c, "-2"),
+			op(11, CallOpType, SuccessStatus, c, "2"),
+			op(12, DestructOpType, SuccessStatus, c, "-1"),
+		}
+	}
+
+	// Without a threshold, nothing is dropped.
+	assert.Equal(t, ops(), filterDust(ops(), 1, nil))
+
+	filtered := filterDust(ops(), 1, big.NewInt(10))
+	assert.Equal(t, []*RosettaTypes.Operation{
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 1},
+			Type:                CallOpType,
+			Status:              RosettaTypes.String(SuccessStatus),
+			Account:             &RosettaTypes.AccountIdentifier{Address: a},
+			Amount:              &RosettaTypes.Amount{Value: "-100", Currency: Currency},
+		},
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 2},
+			RelatedOperations:   []*RosettaTypes.OperationIdentifier{{Index: 1}},
+			Type:                CallOpType,
+			Status:              RosettaTypes.String(SuccessStatus),
+			Account:             &RosettaTypes.AccountIdentifier{Address: c},
+			Amount:              &RosettaTypes.Amount{Value: "100", Currency: Currency},
+		},
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 3},
+			Type:                DelegateCallOpType,
+			Status:              RosettaTypes.String(SuccessStatus),
+			Account:             &RosettaTypes.AccountIdentifier{Address: a},
+		},
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 4},
+			Type:                DestructOpType,
+			Status:              RosettaTypes.String(SuccessStatus),
+			Account:             &RosettaTypes.AccountIdentifier{Address: c},
+			Amount:              &RosettaTypes.Amount{Value: "-1", Currency: Currency},
+		},
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 5},
+			Type:                DustOpType,
+			Status:              RosettaTypes.String(SuccessStatus),
+			Account:             &RosettaTypes.AccountIdentifier{Address: a},
+			Amount:              &RosettaTypes.Amount{Value: "-5", Currency: Currency},
+			Metadata:            map[string]interface{}{DustOperationsMetadataKey: int64(1)},
+		},
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 6},
+			Type:                DustOpType,
+			Status:              RosettaTypes.String(SuccessStatus),
+			Account:             &RosettaTypes.AccountIdentifier{Address: b},
+			Amount:              &RosettaTypes.Amount{Value: "2", Currency: Currency},
+			Metadata:            map[string]interface{}{DustOperationsMetadataKey: int64(2)},
+		},
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 7},
+			Type:                DustOpType,
+			Status:              RosettaTypes.String(SuccessStatus),
+			Account:             &RosettaTypes.AccountIdentifier{Address: c},
+			Amount:              &RosettaTypes.Amount{Value: "3", Currency: Currency},
+			Metadata:            map[string]interface{}{DustOperationsMetadataKey: int64(3)},
+		},
+	}, filtered)
+
+	// Balances still net to zero.
+	total := new(big.Int)
+	for _, op := range filtered {
+		if op.Amount != nil && op.Type != DestructOpType {
+			value, ok := new(big.Int).SetString(op.Amount.Value, 10)
+			assert.True(t, ok)
+			total.Add(total, value)
+		}
+	}
+	assert.Equal(t, int64(0), total.Int64())
+}
+
+func TestStakedBalance(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	c := &Client{c: mockJSONRPC}
+
+	account := common.HexToAddress("0x52351e33b3c693Cc05F21831647EBdAb8A68eb95")
+	validatorA := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	validatorB := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	mockSystemCall(
+		t,
+		mockJSONRPC,
+		stakingContracts,
+		CandidateHubContract,
+		"getCandidates",
+		nil,
+		[]common.Address{validatorA, validatorB},
+	)
+	mockSystemCall(
+		t,
+		mockJSONRPC,
+		pledgeAgent,
+		PledgeAgentContract,
+		"getDelegator",
+		[]interface{}{validatorA, account},
+		big.NewInt(100),
+		big.NewInt(150),
+		big.NewInt(19000),
+		big.NewInt(3),
+	)
+	mockSystemCall(
+		t,
+		mockJSONRPC,
+		pledgeAgent,
+		PledgeAgentContract,
+		"getDelegator",
+		[]interface{}{validatorB, account},
+		big.NewInt(0),
+		big.NewInt(0),
+		big.NewInt(0),
+		big.NewInt(0),
+	)
+	mockSystemCall(
+		t,
+		mockJSONRPC,
+		pledgeAgent,
+		PledgeAgentContract,
+		"rewardMap",
+		[]interface{}{account},
+		big.NewInt(12),
+	)
+
+	staked, err := c.StakedBalance(context.Background(), account, &RosettaTypes.BlockIdentifier{
+		Index: 8916656,
+		Hash:  "0x48269a339ce1489cff6bab70eff432289c4f490b81dbd00ff1f81c68de06b842",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &StakedBalance{
+		Staked:           big.NewInt(150),
+		UnclaimedRewards: big.NewInt(12),
+	}, staked)
+
+	mockJSONRPC.AssertExpectations(t)
+}
+
+func TestUpstreamCalls(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	mockGraphQL := &mocks.GraphQL{}
+	c := &Client{
+		c: &instrumentedRPC{JSONRPC: mockJSONRPC},
+		g: &instrumentedGraphQL{GraphQL: mockGraphQL},
+	}
+
+	ctx, calls := WithUpstreamCalls(context.Background())
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_getBlockByNumber",
+		"latest",
+		false,
+	).Return(nil).Once()
+	mockJSONRPC.On(
+		"BatchCallContext",
+		ctx,
+		mock.Anything,
+	).Return(nil).Once()
+	mockGraphQL.On("Query", ctx, "{ block { number } }").Return("{}", nil).Once()
+
+	var header *types.Header
+	assert.NoError(t, c.c.CallContext(ctx, &header, "eth_getBlockByNumber", "latest", false))
+	assert.NoError(t, c.c.BatchCallContext(ctx, []rpc.BatchElem{
+		{Method: "debug_traceTransaction"},
+		{Method: "debug_traceTransaction"},
+	}))
+	_, err := c.g.Query(ctx, "{ block { number } }")
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(4), calls.Total())
+	assert.Equal(t, map[string]int64{
+		"eth":            1,
+		"debug":          2,
+		GraphQLCallClass: 1,
+	}, calls.Classes())
+
+	// Calls without *UpstreamCalls are only counted in metrics
+	mockJSONRPC.On(
+		"CallContext",
+		context.Background(),
+		mock.Anything,
+		"eth_chainId",
+	).Return(nil).Once()
+	var chainID hexutil.Big
+	assert.NoError(t, c.c.CallContext(context.Background(), &chainID, "eth_chainId"))
+	assert.Equal(t, int64(4), calls.Total())
+
+	mockJSONRPC.AssertExpectations(t)
+	mockGraphQL.AssertExpectations(t)
+}
+
+func TestUpstreamCallClass(t *testing.T) {
+	assert.Equal(t, "eth", UpstreamCallClass("eth_getBlockByNumber"))
+	assert.Equal(t, "debug", UpstreamCallClass("debug_traceBlockByHash"))
+	assert.Equal(t, "txpool", UpstreamCallClass("txpool_content"))
+	assert.Equal(t, "unknown", UpstreamCallClass("unknown"))
+}
+
+func TestCheckChain(t *testing.T) {
+	genesis := &types.Header{
+		Number:     big.NewInt(0),
+		Difficulty: big.NewInt(1),
+		Time:       1600000000,
+	}
+	configured := &RosettaTypes.BlockIdentifier{
+		Index: 0,
+		Hash:  genesis.Hash().Hex(),
+	}
+
+	tests := map[string]struct {
+		chainID *big.Int
+		genesis *types.Header
+		err     bool
+	}{
+		"same chain": {
+			chainID: big.NewInt(1116),
+			genesis: genesis,
+		},
+		"different chain ID": {
+			chainID: big.NewInt(1115),
+			genesis: genesis,
+			err:     true,
+		},
+		"different genesis": {
+			chainID: big.NewInt(1116),
+			genesis: &types.Header{
+				Number:     big.NewInt(0),
+				Difficulty: big.NewInt(1),
+				Time:       1600000001,
+			},
+			err: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockJSONRPC := &mocks.JSONRPC{}
+			c := &Client{c: mockJSONRPC}
+			ctx := context.Background()
+
+			mockJSONRPC.On(
+				"CallContext",
+				ctx,
+				mock.Anything,
+				"eth_chainId",
+			).Return(nil).Run(
+				func(args mock.Arguments) {
+					r := args.Get(1).(*hexutil.Big)
+					*r = hexutil.Big(*test.chainID)
+				},
+			).Once()
+			mockJSONRPC.On(
+				"CallContext",
+				ctx,
+				mock.Anything,
+				"eth_getBlockByNumber",
+				"0x0",
+				false,
+			).Return(nil).Run(
+				func(args mock.Arguments) {
+					r := args.Get(1).(**types.Header)
+					*r = test.genesis
+				},
+			).Once()
+
+			err := c.VerifyChain(ctx, big.NewInt(1116), configured)
+			if test.err {
+				assert.True(t, errors.Is(err, ErrChainMismatch))
+				var mismatch *ChainMismatchError
+				assert.True(t, errors.As(err, &mismatch))
+				assert.Equal(t, test.chainID, mismatch.NodeChainID)
+				assert.Equal(t, test.genesis.Hash().Hex(), mismatch.NodeGenesis)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			mockJSONRPC.AssertExpectations(t)
+		})
+	}
+
+	// VerifyChain stops retrying when ctx is canceled
+	mockJSONRPC := &mocks.JSONRPC{}
+	c := &Client{c: mockJSONRPC}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_chainId",
+	).Return(errors.New("connection refused")).Once()
+	assert.NoError(t, c.VerifyChain(ctx, big.NewInt(1116), configured))
+	mockJSONRPC.AssertExpectations(t)
+}
+
+func newTestArchivePool(checkpoint int64) (*archivePool, *mocks.JSONRPC, *mocks.JSONRPC, *mocks.GraphQL, *mocks.GraphQL) {
+	node := &mocks.JSONRPC{}
+	archiveNode := &mocks.JSONRPC{}
+	nodeGraphQL := &mocks.GraphQL{}
+	archiveGraphQL := &mocks.GraphQL{}
+
+	return &archivePool{
+		node:        node,
+		nodeGraphQL: nodeGraphQL,
+		archives: []*archive{
+			{
+				c:          archiveNode,
+				g:          archiveGraphQL,
+				score:      1,
+				checkpoint: checkpoint,
+			},
+		},
+	}, node, archiveNode, nodeGraphQL, archiveGraphQL
+}
+
+func TestArchivePool_Routing(t *testing.T) {
+	ctx := context.Background()
+	pool, node, archiveNode, nodeGraphQL, archiveGraphQL := newTestArchivePool(100)
+	setBalance := func(balance string) func(mock.Arguments) {
+		return func(args mock.Arguments) {
+			r := args.Get(1).(*json.RawMessage)
+			*r = json.RawMessage(`"` + balance + `"`)
+		}
+	}
+
+	// Reads below the checkpoint rotate across the archive node and the node.
+	archiveNode.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_getBalance",
+		"0x1",
+		"0x64",
+	).Return(nil).Run(setBalance("0x2")).Once()
+	node.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_getBalance",
+		"0x1",
+		"0x64",
+	).Return(nil).Run(setBalance("0x2")).Once()
+	for i := 0; i < 2; i++ {
+		var balance json.RawMessage
+		assert.NoError(t, pool.CallContext(ctx, &balance, "eth_getBalance", "0x1", "0x64"))
+		assert.Equal(t, json.RawMessage(`"0x2"`), balance)
+	}
+
+	// Reads above the checkpoint and reads relative to the tip go to the node.
+	node.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_getBalance",
+		"0x1",
+		"0x65",
+	).Return(nil).Run(setBalance("0x3")).Once()
+	node.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_getBalance",
+		"0x1",
+		"latest",
+	).Return(nil).Run(setBalance("0x4")).Once()
+	node.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_blockNumber",
+	).Return(nil).Once()
+	for _, number := range []string{"0x65", "latest"} {
+		var balance json.RawMessage
+		assert.NoError(t, pool.CallContext(ctx, &balance, "eth_getBalance", "0x1", number))
+	}
+	var head hexutil.Uint64
+	assert.NoError(t, pool.CallContext(ctx, &head, "eth_blockNumber"))
+
+	// Reads by hash go to the archive node, in turn.
+	pool.next = 0
+	archiveNode.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_getTransactionReceipt",
+		"0xabc",
+	).Return(nil).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).(*json.RawMessage)
+			*r = json.RawMessage(`{"status":"0x1"}`)
+		},
+	).Once()
+	var receipt struct {
+		Status string `json:"status"`
+	}
+	assert.NoError(t, pool.CallContext(ctx, &receipt, "eth_getTransactionReceipt", "0xabc"))
+	assert.Equal(t, "0x1", receipt.Status)
+
+	// GraphQL queries of a block are balanced too.
+	query := `{ block(number: 10) { account(address: "0x1") { balance } } }`
+	archiveGraphQL.On("Query", ctx, query).Return(
+		`{"data":{"block":{"account":{"balance":"0x5"}}}}`,
+		nil,
+	).Once()
+	nodeGraphQL.On("Query", ctx, query).Return(
+		`{"data":{"block":{"account":{"balance":"0x5"}}}}`,
+		nil,
+	).Once()
+	for i := 0; i < 2; i++ {
+		result, err := pool.Query(ctx, query)
+		assert.NoError(t, err)
+		assert.Contains(t, result, "0x5")
+	}
+
+	node.AssertExpectations(t)
+	archiveNode.AssertExpectations(t)
+	nodeGraphQL.AssertExpectations(t)
+	archiveGraphQL.AssertExpectations(t)
+}
+
+func TestArchivePool_Fallback(t *testing.T) {
+	ctx := context.Background()
+	pool, node, archiveNode, _, _ := newTestArchivePool(100)
+
+	// The archive node does not have the block.
+	archiveNode.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_getBlockByNumber",
+		"0xa",
+		true,
+	).Return(nil).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).(*json.RawMessage)
+			*r = json.RawMessage("null")
+		},
+	).Once()
+	node.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_getBlockByNumber",
+		"0xa",
+		true,
+	).Return(nil).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).(*json.RawMessage)
+			*r = json.RawMessage(`{"number":"0xa"}`)
+		},
+	).Once()
+	var block json.RawMessage
+	assert.NoError(t, pool.CallContext(ctx, &block, "eth_getBlockByNumber", "0xa", true))
+	assert.Equal(t, json.RawMessage(`{"number":"0xa"}`), block)
+	assert.Equal(t, 1.0, pool.archives[0].score)
+
+	// Failures of the archive node lower its score until
+	// it stops receiving reads.
+	for i := 0; i < 4; i++ {
+		pool.next = 0
+		archiveNode.On(
+			"CallContext",
+			ctx,
+			mock.Anything,
+			"eth_getBlockByNumber",
+			"0xa",
+			true,
+		).Return(errors.New("connection refused")).Once()
+		node.On(
+			"CallContext",
+			ctx,
+			mock.Anything,
+			"eth_getBlockByNumber",
+			"0xa",
+			true,
+		).Return(nil).Once()
+		assert.NoError(t, pool.CallContext(ctx, &block, "eth_getBlockByNumber", "0xa", true))
+	}
+	assert.Less(t, pool.archives[0].score, minArchiveScore)
+	assert.Nil(t, pool.pick(10))
+	assert.Nil(t, pool.pick(10))
+
+	node.AssertExpectations(t)
+	archiveNode.AssertExpectations(t)
+}
+
+func TestArchivePool_Batch(t *testing.T) {
+	ctx := context.Background()
+	pool, node, archiveNode, _, _ := newTestArchivePool(100)
+
+	// A batch with a missing receipt is made to the node.
+	archiveNode.On(
+		"BatchCallContext",
+		ctx,
+		mock.Anything,
+	).Return(nil).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).([]rpc.BatchElem)
+			*(r[0].Result.(*json.RawMessage)) = json.RawMessage(`{"status":"0x1"}`)
+			*(r[1].Result.(*json.RawMessage)) = json.RawMessage("null")
+		},
+	).Once()
+	node.On(
+		"BatchCallContext",
+		ctx,
+		mock.Anything,
+	).Return(nil).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).([]rpc.BatchElem)
+			for i := range r {
+				*(r[i].Result.(*json.RawMessage)) = json.RawMessage(`{"status":"0x0"}`)
+			}
+		},
+	).Once()
+
+	receipts := make([]json.RawMessage, 2)
+	batch := []rpc.BatchElem{
+		{Method: "eth_getTransactionReceipt", Args: []interface{}{"0xa"}, Result: &receipts[0]},
+		{Method: "eth_getTransactionReceipt", Args: []interface{}{"0xb"}, Result: &receipts[1]},
+	}
+	assert.NoError(t, pool.BatchCallContext(ctx, batch))
+	assert.Equal(t, json.RawMessage(`{"status":"0x0"}`), receipts[1])
+
+	// A batch that is not only reads is made to the node.
+	node.On("BatchCallContext", ctx, mock.Anything).Return(nil).Once()
+	batch = []rpc.BatchElem{
+		{Method: "eth_getTransactionReceipt", Args: []interface{}{"0xa"}, Result: &receipts[0]},
+		{Method: "eth_getTransactionCount", Args: []interface{}{"0x1", "pending"}, Result: &receipts[1]},
+	}
+	assert.NoError(t, pool.BatchCallContext(ctx, batch))
+
+	node.AssertExpectations(t)
+	archiveNode.AssertExpectations(t)
+}
+
+func TestArchivePool_Check(t *testing.T) {
+	header := &types.Header{
+		Number:     big.NewInt(90),
+		Difficulty: big.NewInt(1),
+		Time:       1600000000,
+	}
+	fork := &types.Header{
+		Number:     big.NewInt(90),
+		Difficulty: big.NewInt(2),
+		Time:       1600000000,
+	}
+
+	tests := map[string]struct {
+		archiveHeader *types.Header
+		checkpoint    int64
+	}{
+		"same chain": {
+			archiveHeader: header,
+			checkpoint:    90,
+		},
+		"different chain": {
+			archiveHeader: fork,
+			checkpoint:    -1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			pool, node, archiveNode, _, _ := newTestArchivePool(-1)
+			setHead := func(head uint64) func(mock.Arguments) {
+				return func(args mock.Arguments) {
+					r := args.Get(1).(*hexutil.Uint64)
+					*r = hexutil.Uint64(head)
+				}
+			}
+			setHeader := func(header *types.Header) func(mock.Arguments) {
+				return func(args mock.Arguments) {
+					r := args.Get(1).(**types.Header)
+					*r = header
+				}
+			}
+
+			// The archive node is behind the node, so
+			// both are compared at the archive head.
+			node.On(
+				"CallContext",
+				ctx,
+				mock.Anything,
+				"eth_blockNumber",
+			).Return(nil).Run(setHead(100)).Once()
+			archiveNode.On(
+				"CallContext",
+				ctx,
+				mock.Anything,
+				"eth_blockNumber",
+			).Return(nil).Run(setHead(90)).Once()
+			node.On(
+				"CallContext",
+				ctx,
+				mock.Anything,
+				"eth_getBlockByNumber",
+				"0x5a",
+				false,
+			).Return(nil).Run(setHeader(header)).Once()
+			archiveNode.On(
+				"CallContext",
+				ctx,
+				mock.Anything,
+				"eth_getBlockByNumber",
+				"0x5a",
+				false,
+			).Return(nil).Run(setHeader(test.archiveHeader)).Once()
+
+			pool.check(ctx)
+			assert.Equal(t, test.checkpoint, pool.archives[0].checkpoint)
+			if test.checkpoint < 0 {
+				assert.Nil(t, pool.pick(-1))
+				assert.Nil(t, pool.pick(-1))
+			}
+
+			node.AssertExpectations(t)
+			archiveNode.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArchivePool_Hedge(t *testing.T) {
+	ctx := context.Background()
+	pool, node, archiveNode, nodeGraphQL, archiveGraphQL := newTestArchivePool(100)
+	pool.hedgeDelay = 5 * time.Millisecond
+	pool.head = 102
+	setHeader := func(number string) func(mock.Arguments) {
+		return func(args mock.Arguments) {
+			r := args.Get(1).(*json.RawMessage)
+			*r = json.RawMessage(`{"number":"` + number + `"}`)
+		}
+	}
+
+	// A slow node is hedged by the archive node.
+	node.On(
+		"CallContext",
+		mock.Anything,
+		mock.Anything,
+		"eth_getBlockByNumber",
+		"latest",
+		false,
+	).Return(nil).Run(setHeader("0x66")).After(100 * time.Millisecond).Once()
+	archiveNode.On(
+		"CallContext",
+		mock.Anything,
+		mock.Anything,
+		"eth_getBlockByNumber",
+		"latest",
+		false,
+	).Return(nil).Run(setHeader("0x65")).Once()
+	var header json.RawMessage
+	assert.NoError(t, pool.CallContext(ctx, &header, "eth_getBlockByNumber", "latest", false))
+	assert.Equal(t, json.RawMessage(`{"number":"0x65"}`), header)
+
+	// A hedged tip below the last one served is
+	// rejected, and the node is waited for.
+	node.On(
+		"CallContext",
+		mock.Anything,
+		mock.Anything,
+		"eth_getBlockByNumber",
+		"latest",
+		false,
+	).Return(nil).Run(setHeader("0x66")).After(50 * time.Millisecond).Once()
+	archiveNode.On(
+		"CallContext",
+		mock.Anything,
+		mock.Anything,
+		"eth_getBlockByNumber",
+		"latest",
+		false,
+	).Return(nil).Run(setHeader("0x64")).Once()
+	assert.NoError(t, pool.CallContext(ctx, &header, "eth_getBlockByNumber", "latest", false))
+	assert.Equal(t, json.RawMessage(`{"number":"0x66"}`), header)
+	assert.Equal(t, int64(0x66), pool.servedTip)
+
+	// A fast node is not hedged, and its errors
+	// are returned.
+	node.On(
+		"CallContext",
+		mock.Anything,
+		mock.Anything,
+		"eth_getBalance",
+		"0x1",
+		"latest",
+	).Return(nil).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).(*json.RawMessage)
+			*r = json.RawMessage(`"0x2"`)
+		},
+	).Once()
+	var balance json.RawMessage
+	assert.NoError(t, pool.CallContext(ctx, &balance, "eth_getBalance", "0x1", "latest"))
+	assert.Equal(t, json.RawMessage(`"0x2"`), balance)
+
+	node.On(
+		"CallContext",
+		mock.Anything,
+		mock.Anything,
+		"eth_getBalance",
+		"0x2",
+		"latest",
+	).Return(errors.New("header not found")).Once()
+	assert.EqualError(
+		t,
+		pool.CallContext(ctx, &balance, "eth_getBalance", "0x2", "latest"),
+		"header not found",
+	)
+
+	// Balances at the tip are queried over GraphQL.
+	query := `{ block() { account(address: "0x1") { balance } } }`
+	nodeGraphQL.On("Query", mock.Anything, query).Return(
+		`{"data":{"block":{"account":{"balance":"0x5"}}}}`,
+		nil,
+	).After(100 * time.Millisecond).Once()
+	archiveGraphQL.On("Query", mock.Anything, query).Return(
+		`{"data":{"block":{"account":{"balance":"0x6"}}}}`,
+		nil,
+	).Once()
+	result, err := pool.Query(ctx, query)
+	assert.NoError(t, err)
+	assert.Contains(t, result, "0x6")
+
+	// An archive node that is behind the node is not used.
+	pool.head = 200
+	node.On(
+		"CallContext",
+		mock.Anything,
+		mock.Anything,
+		"eth_getBlockByNumber",
+		"latest",
+		false,
+	).Return(nil).Run(setHeader("0xc8")).After(20 * time.Millisecond).Once()
+	assert.NoError(t, pool.CallContext(ctx, &header, "eth_getBlockByNumber", "latest", false))
+	assert.Equal(t, json.RawMessage(`{"number":"0xc8"}`), header)
+
+	node.AssertExpectations(t)
+	archiveNode.AssertExpectations(t)
+	nodeGraphQL.AssertExpectations(t)
+	archiveGraphQL.AssertExpectations(t)
+}
+
+func TestHardforks(t *testing.T) {
+	ctx := context.Background()
+	configured := map[string]uint64{
+		"istanbul":  0,
+		"hashPower": 0,
+		"future":    100,
+	}
+	mockNodeInfo := func(mockJSONRPC *mocks.JSONRPC) {
+		mockJSONRPC.On(
+			"CallContext",
+			ctx,
+			mock.Anything,
+			"admin_nodeInfo",
+		).Return(nil).Run(
+			func(args mock.Arguments) {
+				assert.NoError(t, json.Unmarshal([]byte(`{
+					"protocols": {
+						"eth": {
+							"config": {
+								"chainId": 1116,
+								"istanbulBlock": 0,
+								"eip150Hash": "0x0000000000000000000000000000000000000000000000000000000000000000",
+								"hashPowerBlock": 0,
+								"futureBlock": 100000,
+								"berlinBlock": null
+							}
+						}
+					}
+				}`), args.Get(1)))
+			},
+		).Once()
+	}
+
+	t.Run("node hardforks", func(t *testing.T) {
+		mockJSONRPC := &mocks.JSONRPC{}
+		c := &Client{c: mockJSONRPC}
+
+		// Until the node is read, the configured
+		// hardforks are returned.
+		assert.Equal(t, []*Hardfork{
+			{Name: "hashPower", Block: 0, Supported: true},
+			{Name: "istanbul", Block: 0, Supported: true},
+			{Name: "future", Block: 100, Supported: false},
+		}, c.Hardforks(configured))
+
+		// The node schedules future at 100000, not 100,
+		// and is only read once.
+		mockNodeInfo(mockJSONRPC)
+		assert.NoError(t, c.refreshHardforks(ctx))
+		for i := 0; i < 2; i++ {
+			assert.Equal(t, []*Hardfork{
+				{Name: "hashPower", Block: 0, Supported: true},
+				{Name: "istanbul", Block: 0, Supported: true},
+				{Name: "future", Block: 100000, Supported: false},
+			}, c.Hardforks(configured))
+		}
+
+		// Failing to read the node again keeps
+		// the hardforks read before.
+		mockJSONRPC.On(
+			"CallContext",
+			ctx,
+			mock.Anything,
+			"admin_nodeInfo",
+		).Return(errors.New("node down")).Once()
+		assert.Error(t, c.refreshHardforks(ctx))
+		assert.Equal(t, uint64(100000), c.Hardforks(configured)[2].Block)
+
+		mockJSONRPC.AssertExpectations(t)
+	})
+
+	t.Run("skip admin calls", func(t *testing.T) {
+		mockJSONRPC := &mocks.JSONRPC{}
+		c := &Client{c: mockJSONRPC, skipAdminCalls: true}
+
+		assert.NoError(t, c.refreshHardforks(ctx))
+		assert.Equal(t, []*Hardfork{
+			{Name: "hashPower", Block: 0, Supported: true},
+			{Name: "istanbul", Block: 0, Supported: true},
+			{Name: "future", Block: 100, Supported: false},
+		}, c.Hardforks(configured))
+
+		mockJSONRPC.AssertExpectations(t)
+	})
+
+	t.Run("check hardforks", func(t *testing.T) {
+		mockJSONRPC := &mocks.JSONRPC{}
+		c := &Client{c: mockJSONRPC}
+		warned := map[string]bool{}
+		activated := map[string]bool{}
+		for _, head := range []uint64{100000 - HardforkWarningBlocks - 1, 100000 - HardforkWarningBlocks, 100000} {
+			h := head
+			mockNodeInfo(mockJSONRPC)
+			mockJSONRPC.On(
+				"CallContext",
+				ctx,
+				mock.Anything,
+				"eth_blockNumber",
+			).Return(nil).Run(
+				func(args mock.Arguments) {
+					r := args.Get(1).(*hexutil.Uint64)
+					*r = hexutil.Uint64(h)
+				},
+			).Once()
+
+			assert.NoError(t, c.checkHardforks(ctx, nil, warned, activated))
+			assert.Equal(t, head >= 100000-HardforkWarningBlocks, warned["future"])
+			assert.Equal(t, head >= 100000, activated["future"])
+			assert.False(t, warned["hashPower"] || activated["hashPower"])
+		}
+
+		mockJSONRPC.AssertExpectations(t)
+	})
+}
+
+func TestMissingNonces(t *testing.T) {
+	tests := map[string]struct {
+		nonce   uint64
+		pending []uint64
+		queued  []uint64
+		missing uint64
+	}{
+		"empty mempool": {
+			nonce: 5,
+		},
+		"only pending": {
+			nonce:   5,
+			pending: []uint64{6, 5},
+		},
+		"queued after pending": {
+			nonce:   5,
+			pending: []uint64{5, 6},
+			queued:  []uint64{10, 9},
+			missing: 2,
+		},
+		"queued without pending": {
+			nonce:   5,
+			queued:  []uint64{6},
+			missing: 1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.missing, missingNonces(test.nonce, test.pending, test.queued))
+		})
+	}
+}
+
+func TestCheckMempool_Nonces(t *testing.T) {
+	ctx := context.Background()
+	address := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	config := &MempoolMonitorConfig{
+		Addresses:  []common.Address{address},
+		StuckAfter: time.Minute,
+	}
+	mockJSONRPC := &mocks.JSONRPC{}
+	c := &Client{c: mockJSONRPC}
+	state := newMempoolState()
+	mockCheck := func(content string, nonce uint64) {
+		mockJSONRPC.On(
+			"CallContext",
+			ctx,
+			mock.Anything,
+			"txpool_content",
+		).Return(nil).Run(
+			func(args mock.Arguments) {
+				assert.NoError(t, json.Unmarshal([]byte(content), args.Get(1)))
+			},
+		).Once()
+		mockJSONRPC.On(
+			"BatchCallContext",
+			ctx,
+			mock.Anything,
+		).Return(nil).Run(
+			func(args mock.Arguments) {
+				r := args.Get(1).([]rpc.BatchElem)
+				assert.Len(t, r, 1)
+				assert.Equal(t, "eth_getTransactionCount", r[0].Method)
+				assert.Equal(t, []interface{}{address, "latest"}, r[0].Args)
+				*(r[0].Result.(*hexutil.Uint64)) = hexutil.Uint64(nonce)
+			},
+		).Once()
+	}
+	start := time.Unix(1600000000, 0)
+
+	// Nonce 6 is missing before the queued transaction.
+	mockCheck(`{
+		"pending": {"0x1111111111111111111111111111111111111111": {"5": {}}},
+		"queued": {"0x1111111111111111111111111111111111111111": {"7": {}}}
+	}`, 5)
+	assert.NoError(t, c.checkMempool(ctx, config, state, start))
+	assert.Equal(t, &nonceState{nonce: 5, since: start, gap: true}, state.nonces[address])
+
+	// The nonce does not progress.
+	mockCheck(`{
+		"pending": {"0x1111111111111111111111111111111111111111": {"5": {}, "6": {}, "7": {}}},
+		"queued": {}
+	}`, 5)
+	assert.NoError(t, c.checkMempool(ctx, config, state, start.Add(2*time.Minute)))
+	assert.Equal(t, &nonceState{nonce: 5, since: start, stuck: true}, state.nonces[address])
+
+	// The transactions are included.
+	later := start.Add(3 * time.Minute)
+	mockCheck(`{"pending": {}, "queued": {}}`, 8)
+	assert.NoError(t, c.checkMempool(ctx, config, state, later))
+	assert.Equal(t, &nonceState{nonce: 8, since: later}, state.nonces[address])
+
+	// Without metrics, the pool is not measured.
+	assert.Empty(t, state.seen)
+
+	mockJSONRPC.AssertExpectations(t)
+}
+
+func TestCheckMempool_Metrics(t *testing.T) {
+	// The pool is only measured with metrics enabled.
+	metrics.Enable()
+
+	ctx := context.Background()
+	address := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	other := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	config := &MempoolMonitorConfig{
+		Addresses:  []common.Address{address},
+		StuckAfter: time.Minute,
+		Metrics:    true,
+	}
+	mockJSONRPC := &mocks.JSONRPC{}
+	c := &Client{c: mockJSONRPC}
+	state := newMempoolState()
+	mockSample := func(content string) {
+		mockJSONRPC.On(
+			"CallContext",
+			ctx,
+			mock.Anything,
+			"txpool_content",
+		).Return(nil).Run(
+			func(args mock.Arguments) {
+				assert.NoError(t, json.Unmarshal([]byte(content), args.Get(1)))
+			},
+		).Once()
+		mockJSONRPC.On(
+			"BatchCallContext",
+			ctx,
+			mock.Anything,
+		).Return(nil).Run(
+			func(args mock.Arguments) {
+				r := args.Get(1).([]rpc.BatchElem)
+				*(r[0].Result.(*hexutil.Uint64)) = hexutil.Uint64(5)
+			},
+		).Once()
+	}
+	start := time.Unix(1600000000, 0)
+	transfer := common.HexToHash("0x3333333333333333333333333333333333333333333333333333333333333333")
+	replacement := common.HexToHash("0x4444444444444444444444444444444444444444444444444444444444444444")
+
+	mockSample(`{
+		"pending": {
+			"0x1111111111111111111111111111111111111111": {"5": {"hash": "` + transfer.Hex() + `"}},
+			"0x2222222222222222222222222222222222222222": {"0": {"hash": "` + transfer.Hex() + `"}}
+		},
+		"queued": {"0x1111111111111111111111111111111111111111": {"7": {}}}
+	}`)
+	assert.NoError(t, c.checkMempool(ctx, config, state, start))
+	assert.Equal(t, int64(2), metrics.Gauge(txPoolPendingMetric).Value())
+	assert.Equal(t, int64(1), metrics.Gauge(txPoolQueuedMetric).Value())
+	assert.Equal(t, int64(0), metrics.Gauge(txPoolOldestPendingMetric).Value())
+	assert.Equal(t, int64(0), metrics.Gauge(txPoolStuckMetric+address.Hex()).Value())
+
+	// Both transactions are still pending, but only the
+	// transaction of the watched address is stuck.
+	alerts := metrics.Counter(txPoolAlertsMetric).Count()
+	mockSample(`{
+		"pending": {
+			"0x1111111111111111111111111111111111111111": {"5": {"hash": "` + transfer.Hex() + `"}},
+			"0x2222222222222222222222222222222222222222": {"0": {"hash": "` + transfer.Hex() + `"}}
+		},
+		"queued": {}
+	}`)
+	assert.NoError(t, c.checkMempool(ctx, config, state, start.Add(2*time.Minute)))
+	assert.Equal(t, int64(120), metrics.Gauge(txPoolOldestPendingMetric).Value())
+	assert.Equal(t, int64(1), metrics.Gauge(txPoolStuckMetric+address.Hex()).Value())
+	assert.Equal(t, alerts+1, metrics.Counter(txPoolAlertsMetric).Count())
+	assert.Equal(t, map[mempoolEntry]bool{
+		{address: address, nonce: 5, hash: transfer}: true,
+	}, state.stuck)
+
+	// A stuck transaction is only alerted on once.
+	mockSample(`{
+		"pending": {
+			"0x1111111111111111111111111111111111111111": {"5": {"hash": "` + transfer.Hex() + `"}},
+			"0x2222222222222222222222222222222222222222": {"0": {"hash": "` + transfer.Hex() + `"}}
+		},
+		"queued": {}
+	}`)
+	assert.NoError(t, c.checkMempool(ctx, config, state, start.Add(3*time.Minute)))
+	assert.Equal(t, alerts+1, metrics.Counter(txPoolAlertsMetric).Count())
+
+	// The stuck transaction is replaced, which resets its age.
+	mockSample(`{
+		"pending": {"0x1111111111111111111111111111111111111111": {"5": {"hash": "` + replacement.Hex() + `"}}},
+		"queued": {}
+	}`)
+	assert.NoError(t, c.checkMempool(ctx, config, state, start.Add(4*time.Minute)))
+	assert.Equal(t, int64(0), metrics.Gauge(txPoolOldestPendingMetric).Value())
+	assert.Equal(t, int64(0), metrics.Gauge(txPoolStuckMetric+address.Hex()).Value())
+	assert.Empty(t, state.stuck)
+	assert.Equal(t, map[mempoolEntry]time.Time{
+		{address: address, nonce: 5, hash: replacement}: start.Add(4 * time.Minute),
+	}, state.seen)
+	assert.NotContains(t, state.seen, mempoolEntry{address: other, nonce: 0, hash: transfer})
+
+	mockJSONRPC.AssertExpectations(t)
+}
+
+func TestMonitorMempool_Unavailable(t *testing.T) {
+	ctx := context.Background()
+	mockJSONRPC := &mocks.JSONRPC{}
+	c := &Client{c: mockJSONRPC}
+
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"txpool_content",
+	).Return(&jsonRPCError{code: -32601, message: "the method txpool_content does not exist/is not available"}).Once()
+	assert.NoError(t, c.MonitorMempool(ctx, &MempoolMonitorConfig{Metrics: true}))
+
+	mockJSONRPC.AssertExpectations(t)
+}
+
+// tokenTraceProcessor surfaces the token transfers
+// reported by a custom tracer as operations.
+type tokenTraceProcessor struct{}
+
+func (tokenTraceProcessor) Operations(
+	tx *types.Transaction,
+	receipt *types.Receipt,
+	trace json.RawMessage,
+	startIndex int,
+) ([]*RosettaTypes.Operation, error) {
+	var transfers []struct {
+		Account string `json:"account"`
+		Value   string `json:"value"`
+	}
+	if err := json.Unmarshal(trace, &transfers); err != nil {
+		return nil, err
+	}
+
+	ops := make([]*RosettaTypes.Operation, len(transfers))
+	for i, transfer := range transfers {
+		ops[i] = &RosettaTypes.Operation{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{
+				Index: int64(startIndex + i),
+			},
+			Type:    "TOKEN_TRANSFER",
+			Status:  RosettaTypes.String(SuccessStatus),
+			Account: &RosettaTypes.AccountIdentifier{Address: transfer.Account},
+			Amount: &RosettaTypes.Amount{
+				Value:    transfer.Value,
+				Currency: &RosettaTypes.Currency{Symbol: "TKN", Decimals: 18},
+			},
+		}
+	}
+
+	return ops, nil
+}
+
+func TestRegisterTraceProcessor(t *testing.T) {
+	RegisterTraceProcessor("test tokens", tokenTraceProcessor{})
+	defer func() {
+		traceProcessorsMutex.Lock()
+		delete(traceProcessors, "test tokens")
+		traceProcessorsMutex.Unlock()
+	}()
+
+	processor, ok := LookupTraceProcessor("test tokens")
+	assert.True(t, ok)
+	assert.Equal(t, tokenTraceProcessor{}, processor)
+	assert.Contains(t, TraceProcessors(), "test tokens")
+
+	assert.Panics(t, func() {
+		RegisterTraceProcessor("test tokens", tokenTraceProcessor{})
+	})
+
+	_, ok = LookupTraceProcessor("missing")
+	assert.False(t, ok)
+}
+
+func TestLoadCustomTracer(t *testing.T) {
+	tracer, err := LoadCustomTracer("prestateTracer", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, &CustomTracer{Tracer: "prestateTracer"}, tracer)
+
+	file, err := ioutil.TempFile("", "tracer*.js")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString("{result: function() { return []; }, fault: function() {}}")
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+
+	tracer, err = LoadCustomTracer(file.Name(), tokenTraceProcessor{})
+	assert.NoError(t, err)
+	assert.Equal(t, &CustomTracer{
+		Tracer:    "{result: function() { return []; }, fault: function() {}}",
+		Processor: tokenTraceProcessor{},
+	}, tracer)
+
+	_, err = LoadCustomTracer("./missing.js", nil)
+	assert.Error(t, err)
+}
+
+func TestCustomTraces(t *testing.T) {
+	ctx := context.Background()
+	blockHash := common.HexToHash("0x01")
+	txs := []rpcTransaction{
+		{tx: types.NewTransaction(0, common.HexToAddress("0x02"), big.NewInt(1), 21000, big.NewInt(1), nil)},
+		{tx: types.NewTransaction(1, common.HexToAddress("0x02"), big.NewInt(1), 21000, big.NewInt(1), nil)},
+	}
+	customTracer := &CustomTracer{Tracer: "tokenTracer"}
+
+	t.Run("block", func(t *testing.T) {
+		mockJSONRPC := &mocks.JSONRPC{}
+		c := &Client{
+			c:              mockJSONRPC,
+			customTracer:   customTracer,
+			traceSemaphore: semaphore.NewWeighted(maxTraceConcurrency),
+		}
+		mockJSONRPC.On(
+			"CallContext",
+			ctx,
+			mock.Anything,
+			"debug_traceBlockByHash",
+			blockHash,
+			customTracer.traceConfig(),
+		).Return(nil).Run(
+			func(args mock.Arguments) {
+				r := args.Get(1).(*[]*rpcRawCall)
+				*r = []*rpcRawCall{
+					{Result: json.RawMessage(`[]`)},
+					{Result: json.RawMessage(`[{"account":"0x02","value":"5"}]`)},
+				}
+			},
+		).Once()
+
+		traces, err := c.customTraces(ctx, blockHash, nil, txs)
+		assert.NoError(t, err)
+		assert.Equal(t, []json.RawMessage{
+			json.RawMessage(`[]`),
+			json.RawMessage(`[{"account":"0x02","value":"5"}]`),
+		}, traces)
+
+		mockJSONRPC.AssertExpectations(t)
+	})
+
+	t.Run("fallback to transactions", func(t *testing.T) {
+		mockJSONRPC := &mocks.JSONRPC{}
+		c := &Client{
+			c:              mockJSONRPC,
+			customTracer:   customTracer,
+			traceSemaphore: semaphore.NewWeighted(maxTraceConcurrency),
+		}
+		mockJSONRPC.On(
+			"CallContext",
+			ctx,
+			mock.Anything,
+			"debug_traceBlockByHash",
+			blockHash,
+			customTracer.traceConfig(),
+		).Return(errors.New("execution timeout")).Once()
+		for _, tx := range txs {
+			mockJSONRPC.On(
+				"CallContext",
+				ctx,
+				mock.Anything,
+				"debug_traceTransaction",
+				tx.tx.Hash(),
+				customTracer.traceConfig(),
+			).Return(nil).Run(
+				func(args mock.Arguments) {
+					r := args.Get(1).(*json.RawMessage)
+					*r = json.RawMessage(`[]`)
+				},
+			).Once()
+		}
+
+		traces, err := c.customTraces(ctx, blockHash, nil, txs)
+		assert.NoError(t, err)
+		assert.Equal(t, []json.RawMessage{json.RawMessage(`[]`), json.RawMessage(`[]`)}, traces)
+
+		mockJSONRPC.AssertExpectations(t)
+	})
+
+	t.Run("transaction failure", func(t *testing.T) {
+		mockJSONRPC := &mocks.JSONRPC{}
+		c := &Client{
+			c:              mockJSONRPC,
+			customTracer:   customTracer,
+			traceSemaphore: semaphore.NewWeighted(maxTraceConcurrency),
+		}
+		rng := hexutil.Bytes{0x01}
+		mockJSONRPC.On(
+			"CallContext",
+			ctx,
+			mock.Anything,
+			"debug_traceBlock",
+			rng,
+			customTracer.traceConfig(),
+		).Return(errors.New("execution timeout")).Once()
+		mockJSONRPC.On(
+			"CallContext",
+			ctx,
+			mock.Anything,
+			"debug_traceTransaction",
+			txs[0].tx.Hash(),
+			customTracer.traceConfig(),
+		).Return(errors.New("execution timeout")).Once()
+
+		traces, err := c.customTraces(ctx, blockHash, rng, txs)
+		assert.Nil(t, traces)
+		assert.Error(t, err)
+
+		mockJSONRPC.AssertExpectations(t)
+	})
+}
+
+func TestPopulateTransaction_CustomTrace(t *testing.T) {
+	sender := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	recipient := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	rawTrace := json.RawMessage(`{"type":"CALL","from":"0xe3a5b4d7f79d64088c8d4ef153a7dde2b2d47309","to":"0x57b414a0332b5cab885a451c2a28a07d1e9b8a8d","value":"0x0"}`)
+	var trace Call
+	assert.NoError(t, json.Unmarshal(rawTrace, &trace))
+	tx := &loadedTransaction{
+		Transaction: types.NewTransaction(0, recipient, big.NewInt(0), 60000, big.NewInt(1), nil),
+		From:        &sender,
+		FeeAmount:   big.NewInt(60000),
+		Miner:       recipient.Hex(),
+		Receipt:     &types.Receipt{Status: types.ReceiptStatusSuccessful},
+		Trace:       &trace,
+		RawTrace:    rawTrace,
+		CustomTrace: json.RawMessage(`[{"account":"0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d","value":"5"}]`),
+	}
+
+	// Without a processor, the output is only surfaced in the metadata.
+	c := &Client{customTracer: &CustomTracer{Tracer: "tokenTracer"}}
+	populated, err := c.populateTransaction(tx)
+	assert.NoError(t, err)
+	for _, op := range populated.Operations {
+		assert.NotEqual(t, "TOKEN_TRANSFER", op.Type)
+	}
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"account": "0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d",
+			"value":   "5",
+		},
+	}, populated.Metadata[CustomTraceMetadataKey])
+
+	c = &Client{customTracer: &CustomTracer{Tracer: "tokenTracer", Processor: tokenTraceProcessor{}}}
+	populated, err = c.populateTransaction(tx)
+	assert.NoError(t, err)
+	last := populated.Operations[len(populated.Operations)-1]
+	assert.Equal(t, "TOKEN_TRANSFER", last.Type)
+	assert.Equal(t, int64(len(populated.Operations)-1), last.OperationIdentifier.Index)
+	assert.Equal(t, "5", last.Amount.Value)
+	assert.Contains(t, populated.Metadata, CustomTraceMetadataKey)
+}
+
+// noteTransformer notes the hash of every transaction in its
+// metadata and appends an operation on the Burn contract.
+type noteTransformer struct{}
+
+func (noteTransformer) TransformTransaction(
+	tx *RosettaTypes.Transaction,
+	raw *types.Transaction,
+	receipt *types.Receipt,
+) error {
+	tx.Metadata["note"] = raw.Hash().Hex()
+	tx.Operations = append(tx.Operations, &RosettaTypes.Operation{
+		OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: int64(len(tx.Operations))},
+		Type:                "NOTE",
+		Account:             &RosettaTypes.AccountIdentifier{Address: BurnContract.Hex()},
+	})
+
+	return nil
+}
+
+func (noteTransformer) TransformBlock(block *RosettaTypes.Block, raw *types.Block) error {
+	block.Metadata = map[string]interface{}{"notes": len(block.Transactions)}
+	return nil
+}
+
+func TestRegisterBlockTransformer(t *testing.T) {
+	RegisterBlockTransformer("test notes", noteTransformer{})
+	defer func() {
+		blockTransformersMutex.Lock()
+		delete(blockTransformers, "test notes")
+		blockTransformersMutex.Unlock()
+	}()
+
+	transformer, ok := LookupBlockTransformer("test notes")
+	assert.True(t, ok)
+	assert.Equal(t, noteTransformer{}, transformer)
+	assert.Contains(t, BlockTransformers(), "test notes")
+
+	assert.Panics(t, func() {
+		RegisterBlockTransformer("test notes", noteTransformer{})
+	})
+
+	_, ok = LookupBlockTransformer("missing")
+	assert.False(t, ok)
+}
+
+func TestConvertTransaction_Transformers(t *testing.T) {
+	sender := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	recipient := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	tx := &loadedTransaction{
+		Transaction: types.NewTransaction(0, recipient, big.NewInt(0), 60000, big.NewInt(1), nil),
+		From:        &sender,
+		FeeAmount:   big.NewInt(60000),
+		Miner:       recipient.Hex(),
+		Receipt:     &types.Receipt{Status: types.ReceiptStatusSuccessful},
+	}
+
+	c := &Client{
+		transformers: []BlockTransformer{noteTransformer{}},
+		labels:       newLabels(map[common.Address]*Label{}),
+	}
+	converted, err := c.convertTransaction(tx)
+	assert.NoError(t, err)
+	assert.Equal(t, tx.Transaction.Hash().Hex(), converted.Metadata["note"])
+
+	// Operations appended by transformers are labeled.
+	last := converted.Operations[len(converted.Operations)-1]
+	assert.Equal(t, "NOTE", last.Type)
+	assert.Equal(t, &Label{Name: "Burn", Category: SystemContractCategory}, last.Account.Metadata[LabelMetadataKey])
+
+	block := &RosettaTypes.Block{Transactions: []*RosettaTypes.Transaction{converted}}
+	assert.NoError(t, c.transformBlock(block, types.NewBlockWithHeader(&types.Header{})))
+	assert.Equal(t, map[string]interface{}{"notes": 1}, block.Metadata)
+
+	// Without transformers, transactions are only populated.
+	c = &Client{}
+	converted, err = c.convertTransaction(tx)
+	assert.NoError(t, err)
+	assert.NotContains(t, converted.Metadata, "note")
+}
+
+func TestRawRLPTransformer(t *testing.T) {
+	recipient := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	legacy := types.NewTransaction(0, recipient, big.NewInt(1), 21000, big.NewInt(1), nil)
+	typed := types.NewTx(&types.AccessListTx{
+		ChainID:  big.NewInt(1116),
+		Nonce:    1,
+		GasPrice: big.NewInt(1),
+		Gas:      21000,
+		To:       &recipient,
+		Value:    big.NewInt(1),
+	})
+
+	// The hash of every encoding is the hash of what it encodes.
+	for _, raw := range []*types.Transaction{legacy, typed} {
+		tx := &RosettaTypes.Transaction{}
+		assert.NoError(t, RawRLPTransformer.TransformTransaction(tx, raw, &types.Receipt{}))
+		encoded, err := hexutil.Decode(tx.Metadata[RawTransactionMetadataKey].(string))
+		assert.NoError(t, err)
+		assert.Equal(t, raw.Hash(), crypto.Keccak256Hash(encoded))
+	}
+
+	raw := types.NewBlockWithHeader(&types.Header{
+		Number:     big.NewInt(100),
+		ParentHash: common.HexToHash("0x01"),
+		GasLimit:   30000000,
+		Extra:      []byte("extra"),
+	})
+	block := &RosettaTypes.Block{Metadata: map[string]interface{}{"round": 1}}
+	assert.NoError(t, RawRLPTransformer.TransformBlock(block, raw))
+	assert.Equal(t, 1, block.Metadata["round"])
+	encoded, err := hexutil.Decode(block.Metadata[RawHeaderMetadataKey].(string))
+	assert.NoError(t, err)
+	assert.Equal(t, raw.Hash(), crypto.Keccak256Hash(encoded))
+
+	// Blocks without metadata get some.
+	block = &RosettaTypes.Block{}
+	assert.NoError(t, RawRLPTransformer.TransformBlock(block, raw))
+	assert.Contains(t, block.Metadata, RawHeaderMetadataKey)
+}
+
+func TestConsensusTransformer(t *testing.T) {
+	key, err := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	assert.NoError(t, err)
+	validator := crypto.PubkeyToAddress(key.PublicKey)
+	next := []common.Address{
+		common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d"),
+		common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"),
+	}
+	chainID := CoreChainConfig.ChainID
+	transformer := NewConsensusTransformer(chainID)
+
+	// seal signs header with key and returns the sealed block.
+	vanity := bytes.Repeat([]byte{0xaa}, extraVanity)
+	seal := func(header *types.Header, validators []common.Address) *types.Block {
+		header.Extra = append([]byte{}, vanity...)
+		for _, v := range validators {
+			header.Extra = append(header.Extra, v.Bytes()...)
+		}
+		header.Extra = append(header.Extra, make([]byte, extraSeal)...)
+
+		hash, err := sealHash(chainID, header)
+		assert.NoError(t, err)
+		signature, err := crypto.Sign(hash.Bytes(), key)
+		assert.NoError(t, err)
+		copy(header.Extra[len(header.Extra)-extraSeal:], signature)
+
+		return types.NewBlockWithHeader(header)
+	}
+
+	// In turn
+	raw := seal(&types.Header{
+		Number:     big.NewInt(101),
+		Coinbase:   validator,
+		Difficulty: big.NewInt(2),
+		GasLimit:   30000000,
+	}, nil)
+	block := &RosettaTypes.Block{Metadata: map[string]interface{}{"round": 1}}
+	assert.NoError(t, transformer.TransformBlock(block, raw))
+	assert.Equal(t, 1, block.Metadata["round"])
+	var metadata ConsensusMetadata
+	assert.NoError(t, RosettaTypes.UnmarshalMap(
+		block.Metadata[ConsensusMetadataKey].(map[string]interface{}),
+		&metadata,
+	))
+	assert.Equal(t, "2", metadata.Difficulty)
+	assert.True(t, metadata.InTurn)
+	assert.Equal(t, hexutil.Encode(vanity), metadata.Vanity)
+	assert.Equal(t, hexutil.Encode(raw.Extra()[extraVanity:]), metadata.Signature)
+	assert.Equal(t, validator.Hex(), metadata.Sealer)
+	assert.Empty(t, metadata.Validators)
+
+	// Out of turn, on an epoch block
+	raw = seal(&types.Header{
+		Number:     big.NewInt(200),
+		Coinbase:   validator,
+		Difficulty: big.NewInt(1),
+		GasLimit:   30000000,
+	}, next)
+	metadata = *consensusMetadata(chainID, raw.Header())
+	assert.Equal(t, "1", metadata.Difficulty)
+	assert.False(t, metadata.InTurn)
+	assert.Equal(t, validator.Hex(), metadata.Sealer)
+	assert.Equal(t, []string{next[0].Hex(), next[1].Hex()}, metadata.Validators)
+
+	// The signature covers the chain ID.
+	metadata = *consensusMetadata(BuffaloChainConfig.ChainID, raw.Header())
+	assert.Empty(t, metadata.Sealer)
+
+	// Signatures not made by the coinbase have no sealer.
+	raw = seal(&types.Header{
+		Number:     big.NewInt(102),
+		Coinbase:   next[0],
+		Difficulty: big.NewInt(2),
+		GasLimit:   30000000,
+	}, nil)
+	metadata = *consensusMetadata(chainID, raw.Header())
+	assert.NotEmpty(t, metadata.Signature)
+	assert.Empty(t, metadata.Sealer)
+
+	// The Core mainnet genesis block lists the first
+	// validators but is not signed.
+	file, err := ioutil.ReadFile("testdata/core_mainnet_header_0.json")
+	assert.NoError(t, err)
+	var genesis types.Header
+	assert.NoError(t, json.Unmarshal(file, &genesis))
+	assert.Equal(t, CoreGenesisHash, genesis.Hash())
+	metadata = *consensusMetadata(chainID, &genesis)
+	assert.Equal(t, "1", metadata.Difficulty)
+	assert.False(t, metadata.InTurn)
+	assert.Equal(t, hexutil.Encode(make([]byte, extraVanity)), metadata.Vanity)
+	assert.Equal(t, hexutil.Encode(make([]byte, extraSeal)), metadata.Signature)
+	assert.Empty(t, metadata.Sealer)
+	assert.Len(t, metadata.Validators, 15)
+	assert.Equal(t, "0x4121F067B0F5135D77C29b2B329e8Cb1bd96C960", metadata.Validators[0])
+
+	// Short extraData is not decoded.
+	metadata = *consensusMetadata(chainID, &types.Header{
+		Number:     big.NewInt(1),
+		Difficulty: big.NewInt(2),
+		Extra:      []byte("extra"),
+	})
+	assert.Equal(t, ConsensusMetadata{Difficulty: "2", InTurn: true}, metadata)
+
+	// Transactions are not modified.
+	tx := &RosettaTypes.Transaction{}
+	assert.NoError(t, transformer.TransformTransaction(tx, nil, nil))
+	assert.Nil(t, tx.Metadata)
+}
+
+// headerChain extends base with headers up to length-1. The
+// new headers have fork as extra data, so chains extended
+// with different forks diverge after base.
+func headerChain(base []*types.Header, length int64, fork string) []*types.Header {
+	chain := append([]*types.Header{}, base...)
+	for index := int64(len(base)); index < length; index++ {
+		header := &types.Header{Number: big.NewInt(index), Extra: []byte(fork)}
+		if index > 0 {
+			header.ParentHash = chain[index-1].Hash()
+		}
+		chain = append(chain, header)
+	}
+
+	return chain
+}
+
+func headerEvents(headers []*types.Header, eventType RosettaTypes.BlockEventType) []*RosettaTypes.BlockEvent {
+	events := []*RosettaTypes.BlockEvent{}
+	for _, header := range headers {
+		events = append(events, &RosettaTypes.BlockEvent{
+			BlockIdentifier: &RosettaTypes.BlockIdentifier{
+				Index: header.Number.Int64(),
+				Hash:  header.Hash().Hex(),
+			},
+			Type: eventType,
+		})
+	}
+
+	return events
+}
+
+func TestHeadTracker(t *testing.T) {
+	ctx := context.Background()
+	main := headerChain(nil, 10, "")
+	fork := headerChain(main[:7], 11, "fork")
+	headers := map[string]*types.Header{}
+	for _, header := range append(main, fork...) {
+		headers[header.Hash().Hex()] = header
+	}
+	header := func(ctx context.Context, hash string) (*types.Header, error) {
+		header, ok := headers[hash]
+		if !ok {
+			return nil, ethereum.NotFound
+		}
+
+		return header, nil
+	}
+
+	tracker := &headTracker{}
+
+	// Tracking starts at the first head.
+	events, err := tracker.update(ctx, main[5], header)
+	assert.NoError(t, err)
+	assert.Equal(t, headerEvents(main[5:6], RosettaTypes.ADDED), events)
+
+	// An unchanged head has no events.
+	events, err = tracker.update(ctx, main[5], header)
+	assert.NoError(t, err)
+	assert.Nil(t, events)
+
+	// Skipped blocks are added.
+	events, err = tracker.update(ctx, main[9], header)
+	assert.NoError(t, err)
+	assert.Equal(t, headerEvents(main[6:10], RosettaTypes.ADDED), events)
+
+	// Reorged blocks are removed (from the highest) before
+	// the blocks of the new branch are added.
+	events, err = tracker.update(ctx, fork[10], header)
+	assert.NoError(t, err)
+	expected := []*RosettaTypes.BlockEvent{}
+	for i := 9; i >= 7; i-- {
+		expected = append(expected, headerEvents(main[i:i+1], RosettaTypes.REMOVED)...)
+	}
+	expected = append(expected, headerEvents(fork[7:11], RosettaTypes.ADDED)...)
+	assert.Equal(t, expected, events)
+
+	// Headers that cannot be fetched leave the tracker unchanged.
+	unknown := &types.Header{Number: big.NewInt(12), ParentHash: common.HexToHash("0x1")}
+	_, err = tracker.update(ctx, unknown, header)
+	assert.Error(t, err)
+	assert.Equal(t, fork[10].Hash().Hex(), tracker.chain[len(tracker.chain)-1].Hash)
+
+	// A head that does not connect to the tracked
+	// chain restarts tracking at the head.
+	tracker.chain = tracker.chain[len(tracker.chain)-1:]
+	events, err = tracker.update(ctx, main[9], header)
+	assert.NoError(t, err)
+	assert.Equal(t, headerEvents(main[9:10], RosettaTypes.ADDED), events)
+	assert.Len(t, tracker.chain, 1)
+}
+
+func TestHeadTracker_Reorgs(t *testing.T) {
+	ctx := context.Background()
+	tests := map[string]struct {
+		depth  int64
+		extra  int64
+		length int64
+	}{
+		"depth 1":                    {depth: 1},
+		"depth 1 with longer branch": {depth: 1, extra: 2},
+		"depth 12":                   {depth: 12},
+		"shorter branch":             {depth: 12, extra: -4},
+		"depth of window":            {depth: HeadWindow - 1},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			length := int64(2 * HeadWindow)
+			main := headerChain(nil, length, "")
+			fork := headerChain(main[:length-test.depth], length+test.extra, "fork")
+			headers := map[string]*types.Header{}
+			for _, header := range append(main, fork...) {
+				headers[header.Hash().Hex()] = header
+			}
+			header := func(ctx context.Context, hash string) (*types.Header, error) {
+				return headers[hash], nil
+			}
+
+			tracker := &headTracker{}
+			_, err := tracker.update(ctx, main[0], header)
+			assert.NoError(t, err)
+			for _, head := range main[1:] {
+				_, err = tracker.update(ctx, head, header)
+				assert.NoError(t, err)
+			}
+
+			events, err := tracker.update(ctx, fork[len(fork)-1], header)
+			assert.NoError(t, err)
+
+			expected := []*RosettaTypes.BlockEvent{}
+			for i := length - 1; i >= length-test.depth; i-- {
+				expected = append(expected, headerEvents(main[i:i+1], RosettaTypes.REMOVED)...)
+			}
+			expected = append(expected, headerEvents(fork[length-test.depth:], RosettaTypes.ADDED)...)
+			assert.Equal(t, expected, events)
+
+			// The tracked chain is the window of the new branch,
+			// less the blocks a shorter branch dropped.
+			tracked := int64(HeadWindow)
+			if test.extra < 0 {
+				tracked += test.extra
+			}
+			assert.Equal(t, tracked, int64(len(tracker.chain)))
+			for _, tracked := range tracker.chain {
+				assert.Equal(t, fork[tracked.Index].Hash().Hex(), tracked.Hash)
+			}
+		})
+	}
+}
+
+// mockTransactionByHash mocks eth_getTransactionByHash for the
+// transaction in testdata with its blockHash and blockNumber
+// removed unless mined is true.
+func mockTransactionByHash(t *testing.T, mockJSONRPC *mocks.JSONRPC, mined bool) {
+	mockJSONRPC.On(
+		"CallContext",
+		mock.Anything,
+		mock.Anything,
+		"eth_getTransactionByHash",
+		"0x9cc8e6a09ae9cbdb7da77515110a8e343a945df4269c53842dd26969d32c6cc4",
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			tx := args.Get(1).(**rpcTransaction)
+			file, err := ioutil.ReadFile(
+				"testdata/transaction_0x9cc8e6a09ae9cbdb7da77515110a8e343a945df4269c53842dd26969d32c6cc4.json",
+			)
+			assert.NoError(t, err)
+
+			*tx = new(rpcTransaction)
+			assert.NoError(t, json.Unmarshal(file, *tx))
+			if !mined {
+				(*tx).BlockHash = nil
+				(*tx).BlockNumber = nil
+			}
+		},
+	).Once()
+}
+
+func mockTransactionCount(mockJSONRPC *mocks.JSONRPC, count uint64) {
+	mockJSONRPC.On(
+		"CallContext",
+		mock.Anything,
+		mock.Anything,
+		"eth_getTransactionCount",
+		common.HexToAddress("0x687422eea2cb73b5d3e242ba5456b782919afc85"),
+		"latest",
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			*(args.Get(1).(*hexutil.Uint64)) = hexutil.Uint64(count)
+		},
+	).Once()
+}
+
+func TestCall_TransactionStatus(t *testing.T) {
+	txHash := "0x9cc8e6a09ae9cbdb7da77515110a8e343a945df4269c53842dd26969d32c6cc4"
+	from := "0x687422eEA2cB73B5d3e242bA5456b782919AFc85"
+	nonce := uint64(0x9c6)
+	successful := true
+
+	mockReceipt := func(mockJSONRPC *mocks.JSONRPC, found bool) {
+		mockJSONRPC.On(
+			"CallContext",
+			mock.Anything,
+			mock.Anything,
+			"eth_getTransactionReceipt",
+			common.HexToHash(txHash),
+		).Return(
+			nil,
+		).Run(
+			func(args mock.Arguments) {
+				if !found {
+					return
+				}
+
+				r := args.Get(1).(**types.Receipt)
+				file, err := ioutil.ReadFile(
+					"testdata/tx_receipt_0x9cc8e6a09ae9cbdb7da77515110a8e343a945df4269c53842dd26969d32c6cc4.json",
+				)
+				assert.NoError(t, err)
+
+				*r = new(types.Receipt)
+				assert.NoError(t, (*r).UnmarshalJSON(file))
+				(*r).Status = types.ReceiptStatusSuccessful
+			},
+		).Once()
+	}
+
+	tests := map[string]struct {
+		params map[string]interface{}
+		mock   func(*mocks.JSONRPC)
+
+		expected    *TransactionStatus
+		expectedErr error
+	}{
+		"mined": {
+			params: map[string]interface{}{"tx_hash": txHash},
+			mock: func(mockJSONRPC *mocks.JSONRPC) {
+				mockTransactionByHash(t, mockJSONRPC, true)
+				mockReceipt(mockJSONRPC, true)
+				mockLatestHeader(t, mockJSONRPC)
+			},
+			expected: &TransactionStatus{
+				TransactionHash: txHash,
+				Status:          MinedTransactionStatus,
+				BlockIdentifier: &RosettaTypes.BlockIdentifier{
+					Hash:  "0xc10a51a3898a85c7165a9d883acc9a68f139934d0cb91dfad4c7d3a7c1a1960d",
+					Index: 0xafc8,
+				},
+				Confirmations: 8916656 - 0xafc8 + 1,
+				Successful:    &successful,
+				From:          from,
+				Nonce:         &nonce,
+			},
+		},
+		"pending": {
+			params: map[string]interface{}{"tx_hash": txHash},
+			mock: func(mockJSONRPC *mocks.JSONRPC) {
+				mockTransactionByHash(t, mockJSONRPC, false)
+				mockTransactionCount(mockJSONRPC, nonce)
+			},
+			expected: &TransactionStatus{
+				TransactionHash: txHash,
+				Status:          PendingTransactionStatus,
+				From:            from,
+				Nonce:           &nonce,
+			},
+		},
+		"replaced while pending": {
+			params: map[string]interface{}{"tx_hash": txHash},
+			mock: func(mockJSONRPC *mocks.JSONRPC) {
+				mockTransactionByHash(t, mockJSONRPC, false)
+				mockTransactionCount(mockJSONRPC, nonce+1)
+				mockReceipt(mockJSONRPC, false)
+			},
+			expected: &TransactionStatus{
+				TransactionHash: txHash,
+				Status:          DroppedTransactionStatus,
+				Replaced:        true,
+				From:            from,
+				Nonce:           &nonce,
+			},
+		},
+		"mined while pending": {
+			params: map[string]interface{}{"tx_hash": txHash},
+			mock: func(mockJSONRPC *mocks.JSONRPC) {
+				mockTransactionByHash(t, mockJSONRPC, false)
+				mockTransactionCount(mockJSONRPC, nonce+1)
+				mockReceipt(mockJSONRPC, true)
+				mockLatestHeader(t, mockJSONRPC)
+			},
+			expected: &TransactionStatus{
+				TransactionHash: txHash,
+				Status:          MinedTransactionStatus,
+				BlockIdentifier: &RosettaTypes.BlockIdentifier{
+					Hash:  "0xc10a51a3898a85c7165a9d883acc9a68f139934d0cb91dfad4c7d3a7c1a1960d",
+					Index: 0xafc8,
+				},
+				Confirmations: 8916656 - 0xafc8 + 1,
+				Successful:    &successful,
+				From:          from,
+				Nonce:         &nonce,
+			},
+		},
+		"dropped": {
+			params: map[string]interface{}{"tx_hash": txHash},
+			mock: func(mockJSONRPC *mocks.JSONRPC) {
+				mockJSONRPC.On(
+					"CallContext",
+					mock.Anything,
+					mock.Anything,
+					"eth_getTransactionByHash",
+					txHash,
+				).Return(nil).Once()
+			},
+			expected: &TransactionStatus{
+				TransactionHash: txHash,
+				Status:          DroppedTransactionStatus,
+			},
+		},
+		"replaced": {
+			params: map[string]interface{}{
+				"tx_hash": txHash,
+				"from":    from,
+				"nonce":   nonce,
+			},
+			mock: func(mockJSONRPC *mocks.JSONRPC) {
+				mockJSONRPC.On(
+					"CallContext",
+					mock.Anything,
+					mock.Anything,
+					"eth_getTransactionByHash",
+					txHash,
+				).Return(nil).Once()
+				mockTransactionCount(mockJSONRPC, nonce+1)
+				mockReceipt(mockJSONRPC, false)
+			},
+			expected: &TransactionStatus{
+				TransactionHash: txHash,
+				Status:          DroppedTransactionStatus,
+				Replaced:        true,
+				From:            from,
+				Nonce:           &nonce,
+			},
+		},
+		"invalid hash": {
+			params:      map[string]interface{}{"tx_hash": "0x1234"},
+			expectedErr: ErrCallParametersInvalid,
+		},
+		"from without nonce": {
+			params: map[string]interface{}{
+				"tx_hash": txHash,
+				"from":    from,
+			},
+			expectedErr: ErrCallParametersInvalid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockJSONRPC := &mocks.JSONRPC{}
+			mockGraphQL := &mocks.GraphQL{}
+			if test.mock != nil {
+				test.mock(mockJSONRPC)
+			}
+
+			c := &Client{
+				c:              mockJSONRPC,
+				g:              mockGraphQL,
+				traceSemaphore: semaphore.NewWeighted(100),
+			}
+
+			resp, err := c.Call(
+				context.Background(),
+				&RosettaTypes.CallRequest{
+					Method:     TransactionStatusMethod,
+					Parameters: test.params,
+				},
+			)
+			if test.expectedErr != nil {
+				assert.Nil(t, resp)
+				assert.True(t, errors.Is(err, test.expectedErr))
+			} else {
+				assert.NoError(t, err)
+
+				var status TransactionStatus
+				assert.NoError(t, RosettaTypes.UnmarshalMap(resp.Result, &status))
+				assert.Equal(t, test.expected, &status)
+			}
+
+			mockJSONRPC.AssertExpectations(t)
+			mockGraphQL.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGraphQLReceipt(t *testing.T) {
+	file, err := ioutil.ReadFile(
+		"testdata/tx_receipt_0x9ee03d5922b2a901e3fc05d8a6351165b9f211162363c790c98746ef229e395c.json",
+	)
+	assert.NoError(t, err)
+
+	var expected types.Receipt
+	assert.NoError(t, expected.UnmarshalJSON(file))
+
+	// The receipt is rebuilt from the
+	// fields served over GraphQL.
+	logs := make([]map[string]interface{}, len(expected.Logs))
+	for i, log := range expected.Logs {
+		logs[i] = map[string]interface{}{
+			"index":   log.Index,
+			"account": map[string]interface{}{"address": log.Address.Hex()},
+			"topics":  log.Topics,
+			"data":    hexutil.Encode(log.Data),
+		}
+	}
+	served, err := json.Marshal(map[string]interface{}{
+		"hash":              expected.TxHash.Hex(),
+		"index":             expected.TransactionIndex,
+		"type":              expected.Type,
+		"status":            expected.Status,
+		"gasUsed":           expected.GasUsed,
+		"cumulativeGasUsed": hexutil.EncodeUint64(expected.CumulativeGasUsed),
+		"createdContract":   nil,
+		"logs":              logs,
+	})
+	assert.NoError(t, err)
+
+	var receipt graphQLReceipt
+	assert.NoError(t, json.Unmarshal(served, &receipt))
+	rebuilt, err := receipt.receipt(expected.BlockHash, expected.BlockNumber.Uint64())
+	assert.NoError(t, err)
+
+	expectedJSON, err := expected.MarshalJSON()
+	assert.NoError(t, err)
+	rebuiltJSON, err := rebuilt.MarshalJSON()
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(expectedJSON), string(rebuiltJSON))
+
+	receipt.Status = nil
+	_, err = receipt.receipt(expected.BlockHash, expected.BlockNumber.Uint64())
+	assert.Error(t, err)
+}
+
+// fakeGraphQL serves the GraphQL queries used to fetch receipts
+// for a chain whose block at every number has counts[number]
+// transactions (blocks above the tip do not exist).
+type fakeGraphQL struct {
+	counts  map[int64]int
+	tip     int64
+	queries int
+}
+
+var (
+	fakeBlockByNumber = regexp.MustCompile(`(b\d+): block\(number: (\d+)\)`)
+	fakeBlockByHash   = regexp.MustCompile(`(b\d+): block\(hash: "(0x[0-9a-fA-F]+)"\)`)
+	fakeTransactionAt = regexp.MustCompile(`(t\d+): transactionAt\(index: (\d+)\)`)
+)
+
+func fakeBlockHash(number int64) common.Hash {
+	return common.BigToHash(big.NewInt(number + 1))
+}
+
+func fakeTransactions(number int64, count int) []rpcTransaction {
+	txs := make([]rpcTransaction, count)
+	for i := range txs {
+		txs[i].tx = types.NewTransaction(
+			uint64(i),
+			common.Address{},
+			big.NewInt(number),
+			21000,
+			big.NewInt(1),
+			nil,
+		)
+	}
+
+	return txs
+}
+
+func (f *fakeGraphQL) Query(ctx context.Context, input string) (string, error) {
+	f.queries++
+	data := map[string]interface{}{}
+
+	for _, match := range fakeBlockByNumber.FindAllStringSubmatch(input, -1) {
+		number, _ := strconv.ParseInt(match[2], 10, 64)
+		if number > f.tip {
+			data[match[1]] = nil
+			continue
+		}
+
+		data[match[1]] = map[string]interface{}{
+			"hash":             fakeBlockHash(number).Hex(),
+			"number":           number,
+			"transactionCount": f.counts[number],
+		}
+	}
+
+	blocks := fakeBlockByHash.FindAllStringSubmatchIndex(input, -1)
+	for i, block := range blocks {
+		end := len(input)
+		if i+1 < len(blocks) {
+			end = blocks[i+1][0]
+		}
+
+		number := common.HexToHash(input[block[4]:block[5]]).Big().Int64() - 1
+		txs := fakeTransactions(number, f.counts[number])
+		transactions := map[string]interface{}{}
+		for _, match := range fakeTransactionAt.FindAllStringSubmatch(input[block[1]:end], -1) {
+			index, _ := strconv.Atoi(match[2])
+			transactions[match[1]] = map[string]interface{}{
+				"hash":              txs[index].tx.Hash().Hex(),
+				"index":             index,
+				"type":              0,
+				"status":            1,
+				"gasUsed":           21000,
+				"cumulativeGasUsed": 21000 * (index + 1),
+				"logs":              []interface{}{},
+			}
+		}
+		data[input[block[2]:block[3]]] = transactions
+	}
+
+	response, err := json.Marshal(map[string]interface{}{"data": data})
+	return string(response), err
+}
+
+func TestGraphQLReceipts_Paging(t *testing.T) {
+	g := &fakeGraphQL{counts: map[int64]int{1: 300, 2: 2}, tip: 3}
+	c := &Client{g: g}
+
+	receipts, err := c.graphQLReceipts(context.Background(), []*receiptsRequest{
+		{hash: fakeBlockHash(1), number: 1, count: 300},
+		{hash: fakeBlockHash(2), number: 2, count: 2},
+		{hash: fakeBlockHash(3), number: 3, count: 0},
+	})
+	assert.NoError(t, err)
+
+	// The first document is filled by block 1, and the second
+	// resumes at transaction 250 of block 1 before block 2.
+	assert.Equal(t, 2, g.queries)
+	assert.Len(t, receipts, 3)
+	assert.Empty(t, receipts[fakeBlockHash(3)])
+	for number, count := range g.counts {
+		txs := fakeTransactions(number, count)
+		assert.Len(t, receipts[fakeBlockHash(number)], count)
+		for i, receipt := range receipts[fakeBlockHash(number)] {
+			assert.Equal(t, txs[i].tx.Hash(), receipt.TxHash)
+			assert.Equal(t, uint(i), receipt.TransactionIndex)
+			assert.Equal(t, uint64(number), receipt.BlockNumber.Uint64())
+		}
+	}
+}
+
+func TestGraphQLBlockReceipts(t *testing.T) {
+	g := &fakeGraphQL{counts: map[int64]int{10: 3, 11: 1, 12: 2, 13: 1}, tip: 12}
+	c := &Client{g: g, receipts: newReceiptPrefetcher(10)}
+	ctx := context.Background()
+
+	// The receipts of blocks 10 to 12 are fetched
+	// with the first block of the window.
+	txs := fakeTransactions(11, 1)
+	receipts, err := c.graphQLBlockReceipts(ctx, 11, fakeBlockHash(11), txs)
+	assert.NoError(t, err)
+	assert.Len(t, receipts, 1)
+	assert.Equal(t, txs[0].tx.Hash(), receipts[0].TxHash)
+	assert.Equal(t, 2, g.queries)
+
+	// Only the populated transactions are returned.
+	txs = fakeTransactions(10, 3)[:2]
+	receipts, err = c.graphQLBlockReceipts(ctx, 10, fakeBlockHash(10), txs)
+	assert.NoError(t, err)
+	assert.Len(t, receipts, 2)
+	assert.Equal(t, 2, g.queries)
+
+	// Block 13 did not exist when the window was
+	// fetched, so its receipts are fetched on their own.
+	g.tip = 13
+	txs = fakeTransactions(13, 1)
+	receipts, err = c.graphQLBlockReceipts(ctx, 13, fakeBlockHash(13), txs)
+	assert.NoError(t, err)
+	assert.Len(t, receipts, 1)
+	assert.Equal(t, 3, g.queries)
+
+	// Receipts of other transactions are rejected.
+	_, err = c.graphQLBlockReceipts(ctx, 12, fakeBlockHash(12), fakeTransactions(13, 1))
+	assert.Error(t, err)
+}
+
+func TestReceiptPrefetcher_Canceled(t *testing.T) {
+	p := newReceiptPrefetcher(10)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fetches := 0
+	fetch := func(ctx context.Context, start int64, size int64) (map[common.Hash][]*types.Receipt, error) {
+		fetches++
+		close(started)
+		<-release
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		return map[common.Hash][]*types.Receipt{fakeBlockHash(start): {}}, nil
+	}
+
+	// The request that starts the fetch of
+	// the window goes away before it completes.
+	first, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error)
+	go func() {
+		_, err := p.window(first, 11, fetch)
+		canceled <- err
+	}()
+	<-started
+
+	shared := make(chan map[common.Hash][]*types.Receipt)
+	go func() {
+		receipts, err := p.window(context.Background(), 12, fetch)
+		assert.NoError(t, err)
+		shared <- receipts
+	}()
+
+	cancel()
+	assert.True(t, errors.Is(<-canceled, context.Canceled))
+
+	// The fetch is not canceled with it, so the
+	// requests sharing the window still get it.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	assert.Contains(t, <-shared, fakeBlockHash(10))
+
+	receipts, err := p.window(context.Background(), 13, fetch)
+	assert.NoError(t, err)
+	assert.Contains(t, receipts, fakeBlockHash(10))
+	assert.Equal(t, 1, fetches)
+}
+
+func TestGenerateNetworkBootstrapFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bootstrap")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fromFile := filepath.Join(dir, "file.json")
+	assert.NoError(t, GenerateBootstrapFile("genesis_files/mainnet.json", fromFile))
+	embedded := filepath.Join(dir, "embedded.json")
+	assert.NoError(t, GenerateNetworkBootstrapFile("Core", embedded))
+
+	expected, err := ioutil.ReadFile(fromFile)
+	assert.NoError(t, err)
+	actual, err := ioutil.ReadFile(embedded)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	assert.EqualError(
+		t,
+		GenerateNetworkBootstrapFile("Unknown", embedded),
+		"no genesis file is embedded for Unknown",
+	)
+}
+
+func TestGenerateNodeBootstrapFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bootstrap")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	accounts := `{
+		"0x0000000000000000000000000000000000001002": {"balance": "100"},
+		"0x0000000000000000000000000000000000001000": {"balance": "0x10"},
+		"0x0000000000000000000000000000000000001001": {"balance": "0"}
+	}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []string        `json:"params"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "debug_dumpBlock", request.Method)
+		assert.Equal(t, []string{"0x0"}, request.Params)
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(
+			w,
+			`{"jsonrpc":"2.0","id":%s,"result":{"root":"0x00","accounts":%s}}`,
+			request.ID,
+			accounts,
+		)
+	}))
+	defer server.Close()
+
+	output := filepath.Join(dir, "bootstrap.json")
+	assert.NoError(t, GenerateNodeBootstrapFile(context.Background(), server.URL, output))
+
+	var balances []map[string]interface{}
+	raw, err := ioutil.ReadFile(output)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(raw, &balances))
+	assert.Len(t, balances, 2)
+	assert.Equal(
+		t,
+		map[string]interface{}{"address": "0x0000000000000000000000000000000000001000"},
+		balances[0]["account_identifier"],
+	)
+	assert.Equal(t, "16", balances[0]["value"])
+	assert.Equal(t, "100", balances[1]["value"])
+
+	// A truncated dump is rejected
+	entries := make([]string, maxDumpedAccounts)
+	for i := range entries {
+		entries[i] = fmt.Sprintf(`"%s": {"balance": "1"}`, common.BigToAddress(big.NewInt(int64(i+1))).Hex())
+	}
+	accounts = "{" + strings.Join(entries, ",") + "}"
+	assert.EqualError(
+		t,
+		GenerateNodeBootstrapFile(context.Background(), server.URL, output),
+		"genesis state has at least 256 accounts and cannot be dumped: use a genesis file instead",
+	)
+}
+
+func TestTokenBalances(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	c := &Client{c: mockJSONRPC}
+	ctx := context.Background()
+
+	account := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	block := &RosettaTypes.BlockIdentifier{
+		Index: 1000,
+		Hash:  "0x9cbd8e0e2bd5ae4d0dbf0a1ae6f1d1b1b3f2f0bcf0e7e1f0b2f8d6f0a1c1c1c1",
+	}
+
+	// The balance of every token is its index.
+	tokens := 45
+	currencies := make([]*RosettaTypes.Currency, tokens)
+	tokenIndexes := map[string]int{}
+	for i := range currencies {
+		token := common.BigToAddress(big.NewInt(int64(i + 1))).Hex()
+		currencies[i] = &RosettaTypes.Currency{
+			Symbol:   fmt.Sprintf("T%d", i),
+			Decimals: 18,
+			Metadata: map[string]interface{}{TokenAddressMetadataKey: token},
+		}
+		tokenIndexes[token] = i
+	}
+
+	data := "0x70a08231000000000000000000000000" + strings.ToLower(account.Hex()[2:])
+	var batches []int
+	var mutex sync.Mutex
+	mockJSONRPC.On(
+		"BatchCallContext",
+		mock.Anything,
+		mock.Anything,
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).([]rpc.BatchElem)
+			mutex.Lock()
+			batches = append(batches, len(r))
+			mutex.Unlock()
+
+			for _, elem := range r {
+				assert.Equal(t, "eth_call", elem.Method)
+				call := elem.Args[0].(map[string]string)
+				assert.Equal(t, data, call["data"])
+				assert.Equal(t, block.Hash, elem.Args[1])
+
+				*(elem.Result.(*string)) = hexutil.Encode(
+					common.BigToHash(big.NewInt(int64(tokenIndexes[call["to"]]))).Bytes(),
+				)
+			}
+		},
+	).Times(3)
+
+	amounts, err := c.TokenBalances(ctx, account, block, currencies)
+	assert.NoError(t, err)
+	assert.Len(t, amounts, tokens)
+	for i, amount := range amounts {
+		assert.Equal(t, &RosettaTypes.Amount{
+			Value:    strconv.Itoa(i),
+			Currency: currencies[i],
+		}, amount)
+	}
+	assert.ElementsMatch(t, []int{20, 20, 5}, batches)
+
+	mockJSONRPC.AssertExpectations(t)
+}
+
+func TestTokenBalances_Unsupported(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	c := &Client{c: mockJSONRPC}
+	ctx := context.Background()
+
+	account := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	block := &RosettaTypes.BlockIdentifier{Index: 1000, Hash: "0xabc"}
+
+	// Currencies without a token address
+	_, err := c.TokenBalances(ctx, account, block, []*RosettaTypes.Currency{
+		{Symbol: "BTC", Decimals: 8},
+	})
+	assert.True(t, errors.Is(err, ErrCurrencyUnsupported))
+
+	// Tokens without code
+	mockJSONRPC.On(
+		"BatchCallContext",
+		mock.Anything,
+		mock.Anything,
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).([]rpc.BatchElem)
+			*(r[0].Result.(*string)) = "0x"
+		},
+	).Once()
+
+	_, err = c.TokenBalances(ctx, account, block, []*RosettaTypes.Currency{
+		{
+			Symbol:   "EOA",
+			Decimals: 18,
+			Metadata: map[string]interface{}{
+				TokenAddressMetadataKey: "0x1234567890123456789012345678901234567890",
+			},
+		},
+	})
+	assert.True(t, errors.Is(err, ErrCurrencyUnsupported))
+
+	// Errors of single calls
+	mockJSONRPC.On(
+		"BatchCallContext",
+		mock.Anything,
+		mock.Anything,
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).([]rpc.BatchElem)
+			r[0].Error = errors.New("execution reverted")
+		},
+	).Once()
+
+	_, err = c.TokenBalances(ctx, account, block, []*RosettaTypes.Currency{
+		{
+			Symbol:   "T",
+			Decimals: 18,
+			Metadata: map[string]interface{}{
+				TokenAddressMetadataKey: "0x0000000000000000000000000000000000000001",
+			},
+		},
+	})
+	assert.EqualError(t, err, "execution reverted: unable to get balance of T")
+
+	mockJSONRPC.AssertExpectations(t)
+}
+
+func TestTokenTransfers(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	c := &Client{c: mockJSONRPC}
+	ctx := context.Background()
+
+	token := common.HexToAddress("0x6B175474E89094C44Da98b954EedeAC495271d0F")
+	from := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	to := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	blockHash := common.HexToHash("0x9cbd8e0e2bd5ae4d0dbf0a1ae6f1d1b1b3f2f0bcf0e7e1f0b2f8d6f0a1c1c1c1")
+	value := common.LeftPadBytes(big.NewInt(1000).Bytes(), 32)
+
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_getLogs",
+		map[string]interface{}{
+			"fromBlock": "0x3e8",
+			"toBlock":   "0x7cf",
+			"topics":    [][]common.Hash{{transferTopic}},
+		},
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).(*[]types.Log)
+			*r = []types.Log{
+				{
+					Address:     token,
+					Topics:      []common.Hash{transferTopic, from.Hash(), to.Hash()},
+					Data:        value,
+					BlockNumber: 1500,
+					BlockHash:   blockHash,
+				},
+				{
+					// ERC-721 transfers index the token ID
+					Address: token,
+					Topics: []common.Hash{
+						transferTopic,
+						from.Hash(),
+						to.Hash(),
+						common.BigToHash(big.NewInt(1)),
+					},
+					BlockNumber: 1500,
+					BlockHash:   blockHash,
+				},
+			}
+		},
+	).Once()
+
+	transfers, err := c.TokenTransfers(ctx, 1000, 1999)
+	assert.NoError(t, err)
+	assert.Equal(t, []*TokenTransfer{
+		{
+			Block: &RosettaTypes.BlockIdentifier{Index: 1500, Hash: blockHash.Hex()},
+			Token: token,
+			From:  from,
+			To:    to,
+		},
+	}, transfers)
+
+	mockJSONRPC.AssertExpectations(t)
+}
+
+func TestCanonicalCurrency(t *testing.T) {
+	address := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	token := TokenCurrency("usdT", 6, address)
+	assert.Equal(t, &RosettaTypes.Currency{
+		Symbol:   "usdT",
+		Decimals: 6,
+		Metadata: map[string]interface{}{
+			TokenAddressMetadataKey: "0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d",
+		},
+	}, token)
+
+	requested := &RosettaTypes.Currency{
+		Symbol:   "usdT",
+		Decimals: 6,
+		Metadata: map[string]interface{}{
+			TokenAddressMetadataKey: "0x57b414a0332b5cab885a451c2a28a07d1e9b8a8d",
+			"issuer":                "example",
+		},
+	}
+	canonical := CanonicalCurrency(requested)
+	assert.Equal(t, "0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d", canonical.Metadata[TokenAddressMetadataKey])
+	assert.Equal(t, "example", canonical.Metadata["issuer"])
+	assert.Equal(t, "usdT", canonical.Symbol)
+	assert.Equal(t, "0x57b414a0332b5cab885a451c2a28a07d1e9b8a8d", requested.Metadata[TokenAddressMetadataKey])
+
+	// Tokens sharing a symbol remain distinct.
+	other := TokenCurrency("usdT", 6, common.HexToAddress("0x900101d06A7426441Ae63e9AB3B9b0F63Be145F1"))
+	assert.NotEqual(t, RosettaTypes.Hash(token), RosettaTypes.Hash(other))
+	assert.Equal(t, RosettaTypes.Hash(token), RosettaTypes.Hash(CanonicalCurrency(token)))
+
+	// Other currencies are unchanged.
+	assert.Equal(t, Currency, CanonicalCurrency(Currency))
+	assert.Nil(t, CanonicalCurrency(nil))
+}
+
+func TestLoadLabels(t *testing.T) {
+	dir, err := ioutil.TempDir("", "labels")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "labels.json")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{
+		"0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309": {"name": "Exchange A", "category": "exchange"},
+		"0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d": {"name": "Bridge B"}
+	}`), 0600))
+	labels, err := LoadLabels(path)
+	assert.NoError(t, err)
+	assert.Equal(t, map[common.Address]*Label{
+		common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"): {
+			Name:     "Exchange A",
+			Category: "exchange",
+		},
+		common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d"): {
+			Name: "Bridge B",
+		},
+	}, labels)
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{
+		"0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309": {"category": "exchange"}
+	}`), 0600))
+	labels, err = LoadLabels(path)
+	assert.Nil(t, labels)
+	assert.EqualError(t, err, "label of 0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309 has no name")
+
+	labels, err = LoadLabels(filepath.Join(dir, "missing.json"))
+	assert.Nil(t, labels)
+	assert.Error(t, err)
+}
+
+func TestLabelAccounts(t *testing.T) {
+	exchange := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	foundation := &Label{Name: "Core Foundation", Category: "foundation"}
+	c := &Client{
+		labels: newLabels(map[common.Address]*Label{
+			exchange:           {Name: "Exchange A", Category: "exchange"},
+			FoundationContract: foundation,
+		}),
+	}
+
+	ops := []*RosettaTypes.Operation{
+		{Account: &RosettaTypes.AccountIdentifier{Address: exchange.Hex()}},
+		{Account: &RosettaTypes.AccountIdentifier{Address: BurnContract.Hex()}},
+		{Account: &RosettaTypes.AccountIdentifier{Address: FoundationContract.Hex()}},
+		{Account: &RosettaTypes.AccountIdentifier{Address: "0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d"}},
+	}
+	c.labelAccounts(ops)
+
+	assert.Equal(t, map[string]interface{}{
+		LabelMetadataKey: &Label{Name: "Exchange A", Category: "exchange"},
+	}, ops[0].Account.Metadata)
+	assert.Equal(t, map[string]interface{}{
+		LabelMetadataKey: &Label{Name: "Burn", Category: SystemContractCategory},
+	}, ops[1].Account.Metadata)
+	assert.Equal(t, map[string]interface{}{
+		LabelMetadataKey: foundation,
+	}, ops[2].Account.Metadata)
+	assert.Nil(t, ops[3].Account.Metadata)
+
+	// Labeling is disabled unless labels are configured.
+	c = &Client{labels: newLabels(nil)}
+	ops = []*RosettaTypes.Operation{
+		{Account: &RosettaTypes.AccountIdentifier{Address: BurnContract.Hex()}},
+	}
+	c.labelAccounts(ops)
+	assert.Nil(t, ops[0].Account.Metadata)
+}
+
+func TestLoadABIs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "abis")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "vault.json"), []byte(`[
+		{"type":"function","name":"stake","stateMutability":"payable","inputs":[{"name":"duration","type":"uint64"}],"outputs":[]}
+	]`), 0600))
+	abis, err := LoadABIs(dir)
+	assert.NoError(t, err)
+	assert.Len(t, abis, 1)
+	assert.Equal(t, "stake(uint64)", abis[0].Methods["stake"].Sig)
+
+	abis, err = LoadABIs(filepath.Join(dir, "vault.json"))
+	assert.NoError(t, err)
+	assert.Len(t, abis, 1)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{`), 0600))
+	abis, err = LoadABIs(dir)
+	assert.Nil(t, abis)
+	assert.Error(t, err)
+
+	abis, err = LoadABIs(filepath.Join(dir, "missing.json"))
+	assert.Nil(t, abis)
+	assert.Error(t, err)
+}
+
+func TestABIRegistry_Decode(t *testing.T) {
+	vault := mustParseABI(`[
+		{"type":"function","name":"stake","stateMutability":"payable","inputs":[{"name":"duration","type":"uint64"},{"name":"ids","type":"bytes32[]"}],"outputs":[]}
+	]`)
+	r := newABIRegistry(&ABIRegistryConfig{ABIs: []abi.ABI{vault}}, nil)
+
+	validator := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	data, err := DelegateCoinData(validator)
+	assert.NoError(t, err)
+	method, ok := r.decode(data, false)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]interface{}{
+		"selector":  hexutil.Encode(data[:4]),
+		"name":      "delegateCoin",
+		"signature": "delegateCoin(address)",
+		"source":    RegistryMethodSource,
+		"arguments": []*DecodedArgument{
+			{Name: "agent", Type: "address", Value: validator.Hex()},
+		},
+	}, method.metadata())
+
+	// Overloaded methods are reported with their raw name.
+	data, err = mustParseABI(commonABI).Pack("undelegateCoin0", validator, big.NewInt(100))
+	assert.NoError(t, err)
+	method, ok = r.decode(data, false)
+	assert.True(t, ok)
+	assert.Equal(t, "undelegateCoin", method.Name)
+	assert.Equal(t, "undelegateCoin(address,uint256)", method.Signature)
+	assert.Equal(t, "100", method.Arguments[1].Value)
+
+	data, err = vault.Pack("stake", uint64(30), [][32]byte{{1}})
+	assert.NoError(t, err)
+	method, ok = r.decode(data, false)
+	assert.True(t, ok)
+	assert.Equal(t, []*DecodedArgument{
+		{Name: "duration", Type: "uint64", Value: "30"},
+		{
+			Name:  "ids",
+			Type:  "bytes32[]",
+			Value: []interface{}{hexutil.Encode(common.Hash{1}.Bytes())},
+		},
+	}, method.Arguments)
+
+	// Calldata that cannot be unpacked is decoded without arguments.
+	method, ok = r.decode(data[:10], false)
+	assert.True(t, ok)
+	assert.Equal(t, "stake", method.Name)
+	assert.Nil(t, method.Arguments)
+
+	// Unknown selectors are not decoded nor looked up
+	// unless a lookup endpoint is configured.
+	_, ok = r.decode([]byte{0xde, 0xad, 0xbe, 0xef}, false)
+	assert.False(t, ok)
+	assert.Len(t, r.queue, 0)
+	_, ok = r.decode([]byte{0xde, 0xad}, false)
+	assert.False(t, ok)
+
+	// Decoding is disabled unless the registry is configured.
+	r = newABIRegistry(nil, nil)
+	_, ok = r.decode(data, false)
+	assert.False(t, ok)
+}
+
+func TestABIRegistry_DecodeCalls(t *testing.T) {
+	validator := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	data, err := DelegateCoinData(validator)
+	assert.NoError(t, err)
+
+	trace := &Call{
+		Type:  CallOpType,
+		From:  common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"),
+		To:    common.HexToAddress("0x0000000000000000000000000000000000001007"),
+		Value: big.NewInt(100),
+		Input: data,
+		Calls: []*Call{
+			{
+				Type:  DelegateCallOpType,
+				From:  common.HexToAddress("0x0000000000000000000000000000000000001007"),
+				To:    common.HexToAddress("0x0000000000000000000000000000000000001008"),
+				Value: big.NewInt(0),
+				Input: data,
+			},
+		},
+	}
+	newABIRegistry(&ABIRegistryConfig{}, nil).decodeCalls(trace)
+	assert.Equal(t, "delegateCoin", trace.method.Name)
+	assert.Nil(t, trace.Calls[0].method)
+
+	ops := traceOps(flattenTraces(trace, []*flatCall{}), 0, SkipZeroValueOperations)
+	assert.Len(t, ops, 2)
+	for _, op := range ops {
+		assert.Equal(t, trace.method.metadata(), op.Metadata[MethodMetadataKey])
+	}
+}
+
+func TestABIRegistry_Lookup(t *testing.T) {
+	var lookups int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&lookups, 1)
+		switch r.URL.Query().Get("hex_signature") {
+		case "0x6a627842":
+			fmt.Fprint(w, `{"results": [
+				{"id": 20, "text_signature": "mint(address)"},
+				{"id": 10, "text_signature": "mint(address)"},
+				{"id": 5, "text_signature": "collision(uint256)"}
+			]}`)
+		case "0xdeadbeef":
+			fmt.Fprint(w, `{"results": []}`)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	r := newABIRegistry(&ABIRegistryConfig{LookupURL: server.URL}, nil)
+	c := &Client{abiRegistry: r}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- c.ResolveSelectors(ctx)
+	}()
+
+	account := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	data := append(hexutil.MustDecode("0x6a627842"), common.LeftPadBytes(account.Bytes(), 32)...)
+
+	// Selectors are decoded once they are resolved.
+	_, ok := r.decode(data, true)
+	assert.False(t, ok)
+	_, ok = r.decode([]byte{0xde, 0xad, 0xbe, 0xef}, true)
+	assert.False(t, ok)
+	_, ok = r.decode([]byte{0x01, 0x02, 0x03, 0x04}, true)
+	assert.False(t, ok)
+	assert.Eventually(t, func() bool {
+		_, ok := r.decode(data, true)
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	method, ok := r.decode(data, true)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]interface{}{
+		"selector":  "0x6a627842",
+		"name":      "mint",
+		"signature": "mint(address)",
+		"source":    FourByteMethodSource,
+		"arguments": []*DecodedArgument{
+			{Name: "", Type: "address", Value: account.Hex()},
+		},
+	}, method.metadata())
+
+	// Blocks are only decoded with the registered methods.
+	_, ok = r.decode(data, false)
+	assert.False(t, ok)
+
+	// Unknown selectors are cached and failed lookups
+	// are not retried until fourByteRetryInterval.
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&lookups) == 3
+	}, time.Second, 10*time.Millisecond)
+	_, ok = r.decode([]byte{0xde, 0xad, 0xbe, 0xef}, true)
+	assert.False(t, ok)
+	_, ok = r.decode([]byte{0x01, 0x02, 0x03, 0x04}, true)
+	assert.False(t, ok)
+	assert.Len(t, r.queue, 0)
+
+	r.expireFailures(time.Now().Add(fourByteRetryInterval + time.Second))
+	_, ok = r.decode([]byte{0x01, 0x02, 0x03, 0x04}, true)
+	assert.False(t, ok)
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&lookups) == 4
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestParseSignature(t *testing.T) {
+	method, err := parseSignature("transfer(address,uint256)")
+	assert.NoError(t, err)
+	assert.Equal(t, "transfer", method.RawName)
+	assert.Equal(t, "0xa9059cbb", hexutil.Encode(method.ID))
+
+	method, err = parseSignature("claim()")
+	assert.NoError(t, err)
+	assert.Equal(t, "claim()", method.Sig)
+
+	for _, signature := range []string{
+		"transfer",
+		"(address)",
+		"transfer(address",
+		"swap((address,uint256))",
+		"transfer(addr,uint256)",
+	} {
+		_, err := parseSignature(signature)
+		assert.Error(t, err, signature)
+	}
+}
+
+func TestUserOperationOps(t *testing.T) {
+	bundler := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	account := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	paymaster := common.HexToAddress("0x2170Ed0880ac9A755fd29B2688956BD959F933F8")
+	entryPoint := EntryPointContracts[0]
+
+	userOperationLog := func(address common.Address, hash common.Hash, payer common.Address, success bool) *types.Log {
+		var data []byte
+		data = append(data, common.LeftPadBytes(big.NewInt(7).Bytes(), 32)...)
+		if success {
+			data = append(data, common.LeftPadBytes([]byte{1}, 32)...)
+		} else {
+			data = append(data, make([]byte, 32)...)
+		}
+		data = append(data, common.LeftPadBytes(big.NewInt(50000).Bytes(), 32)...)
+		data = append(data, common.LeftPadBytes(big.NewInt(40000).Bytes(), 32)...)
+
+		return &types.Log{
+			Address: address,
+			Topics: []common.Hash{
+				userOperationTopic,
+				hash,
+				common.BytesToHash(account.Bytes()),
+				common.BytesToHash(payer.Bytes()),
+			},
+			Data:  data,
+			Index: 3,
+		}
+	}
+
+	tx := &loadedTransaction{
+		Transaction: types.NewTransaction(0, entryPoint, big.NewInt(0), 21000, big.NewInt(1), nil),
+		From:        &bundler,
+		FeeAmount:   big.NewInt(21000),
+		Miner:       account.Hex(),
+		Receipt: &types.Receipt{
+			Status: types.ReceiptStatusSuccessful,
+			Logs: []*types.Log{
+				userOperationLog(entryPoint, common.Hash{1}, paymaster, true),
+				userOperationLog(entryPoint, common.Hash{2}, common.Address{}, false),
+				// Events of unknown EntryPoints are ignored.
+				userOperationLog(paymaster, common.Hash{3}, paymaster, true),
+			},
+		},
+	}
+
+	c := &Client{entryPoints: newEntryPoints(EntryPointContracts)}
+	populated, err := c.populateTransaction(tx)
+	assert.NoError(t, err)
+	assert.Equal(t, &FeePayer{
+		Address: bundler.Hex(),
+		Type:    BundlerFeePayer,
+	}, populated.Metadata[FeePayerMetadataKey])
+
+	// The fee is paid by the bundler.
+	assert.Len(t, populated.Operations, 4)
+	assert.Equal(t, bundler.Hex(), populated.Operations[0].Account.Address)
+	assert.Equal(t, &RosettaTypes.Operation{
+		OperationIdentifier: &RosettaTypes.OperationIdentifier{Index: 2},
+		Type:                UserOperationOpType,
+		Status:              RosettaTypes.String(SuccessStatus),
+		Account:             &RosettaTypes.AccountIdentifier{Address: paymaster.Hex()},
+		Metadata: map[string]interface{}{
+			"user_op_hash":      common.Hash{1}.Hex(),
+			"entry_point":       entryPoint.Hex(),
+			"sender":            account.Hex(),
+			"paymaster":         paymaster.Hex(),
+			"nonce":             "7",
+			"actual_gas_cost":   "50000",
+			"actual_gas_used":   "40000",
+			"bundler":           bundler.Hex(),
+			"log_index":         uint(3),
+			FeePayerMetadataKey: PaymasterFeePayer,
+		},
+	}, populated.Operations[2])
+	assert.Equal(t, FailureStatus, *populated.Operations[3].Status)
+	assert.Equal(t, account.Hex(), populated.Operations[3].Account.Address)
+	assert.Equal(t, AccountFeePayer, populated.Operations[3].Metadata[FeePayerMetadataKey])
+	assert.NotContains(t, populated.Operations[3].Metadata, "paymaster")
+
+	// User operations are not decoded unless enabled.
+	c = &Client{entryPoints: newEntryPoints(nil)}
+	populated, err = c.populateTransaction(tx)
+	assert.NoError(t, err)
+	assert.Len(t, populated.Operations, 2)
+	assert.NotContains(t, populated.Metadata, FeePayerMetadataKey)
+}
+
+func TestCapabilityAvailable(t *testing.T) {
+	tests := map[string]struct {
+		err       error
+		available bool
+		retry     bool
+	}{
+		"served":            {available: true},
+		"probe rejected":    {err: &jsonRPCError{code: -32000, message: "transaction not found"}, available: true},
+		"method not found":  {err: &jsonRPCError{code: -32601, message: "the method does not exist"}},
+		"provider disabled": {err: &jsonRPCError{code: -32000, message: "Method not allowed on this plan"}},
+		"blocked":           {err: rpc.HTTPError{StatusCode: http.StatusForbidden}},
+		"unavailable":       {err: errCapabilityUnavailable},
+		"node error":        {err: rpc.HTTPError{StatusCode: http.StatusBadGateway}, retry: true},
+		"unreachable":       {err: errors.New("connection refused"), retry: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			available, err := capabilityAvailable(test.err)
+			assert.Equal(t, test.available, available)
+			assert.Equal(t, test.retry, err != nil)
+		})
+	}
+}
+
+func TestProbeCapabilities(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	mockGraphQL := &mocks.GraphQL{}
+	c := &Client{
+		c:        mockJSONRPC,
+		g:        mockGraphQL,
+		receipts: newReceiptPrefetcher(10),
+	}
+	ctx := context.Background()
+
+	mockJSONRPC.On(
+		"CallContext", ctx, mock.Anything, "debug_traceTransaction", common.Hash{}, mock.Anything,
+	).Return(
+		&jsonRPCError{code: -32000, message: "transaction 0x0 not found"},
+	).Once()
+	mockJSONRPC.On(
+		"CallContext", ctx, mock.Anything, "txpool_status",
+	).Return(
+		&jsonRPCError{code: -32601, message: "the method txpool_status does not exist/is not available"},
+	).Once()
+	mockJSONRPC.On("CallContext", ctx, mock.Anything, "admin_nodeInfo").Return(nil).Once()
+	mockJSONRPC.On("CallContext", ctx, mock.Anything, "eth_getBlockReceipts", "earliest").Return(nil).Once()
+	mockGraphQL.On("Query", ctx, "{ block(number: 0) { hash } }").Return("404 page not found", nil).Once()
+
+	assert.NoError(t, c.ProbeCapabilities(ctx))
+	assert.Equal(t, int32(1), c.blockReceiptsAvailable)
+	assert.Equal(t, int32(1), c.graphQLUnavailable)
+	assert.Equal(t, int32(0), c.adminPeersUnavailable)
+	mockJSONRPC.AssertExpectations(t)
+	mockGraphQL.AssertExpectations(t)
+
+	// Admin calls that are skipped are not probed.
+	c = &Client{skipAdminCalls: true}
+	capabilities := Capabilities{DebugCapability: true, GraphQLCapability: true}
+	decisions, err := c.configureCapabilities(capabilities)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"debug                available   blocks are traced with debug_traceBlockByHash",
+		"txpool               unavailable /mempool, mempool monitoring, and cancellations are unavailable",
+		"admin                unavailable peers and node hardforks are not reported",
+		"graphql              available   receipts are not prefetched (set GRAPHQL_BATCH_SIZE to prefetch them)",
+		"eth_getBlockReceipts unavailable receipts are fetched with batched eth_getTransactionReceipt",
+	}, decisions)
+	assert.Equal(t, int32(1), c.adminPeersUnavailable)
+	assert.Equal(t, int32(0), c.blockReceiptsAvailable)
+
+	// Tracing is required.
+	_, err = c.configureCapabilities(Capabilities{TxPoolCapability: true})
+	assert.Error(t, err)
+}
+
+func TestCheckCapabilities(t *testing.T) {
 	mockJSONRPC := &mocks.JSONRPC{}
 	mockGraphQL := &mocks.GraphQL{}
-
-	c := &Client{
-		c:              mockJSONRPC,
-		g:              mockGraphQL,
-		traceSemaphore: semaphore.NewWeighted(100),
-	}
-
+	c := &Client{c: mockJSONRPC, g: mockGraphQL, skipAdminCalls: true}
 	ctx := context.Background()
 
+	// Unreachable nodes are not retried.
 	mockJSONRPC.On(
-		"CallContext",
-		ctx,
-		mock.Anything,
-		"eth_estimateGas",
-		map[string]string{
-			"from": "0xE550f300E477C60CE7e7172d12e5a27e9379D2e3",
-			"to":   "0xaD6D458402F60fD3Bd25163575031ACDce07538D",
-			"data": "0xa9059cbb000000000000000000000000ae7e48ee0f758cd706b76cf7e2175d982800879a" +
-				"00000000000000000000000000000000000000000000000000521c5f98b8ea00",
-		},
+		"CallContext", ctx, mock.Anything, "debug_traceTransaction", common.Hash{}, mock.Anything,
 	).Return(
-		nil,
-	).Run(
-		func(args mock.Arguments) {
-			r := args.Get(1).(*string)
+		errors.New("connection refused"),
+	).Once()
+	_, err := c.CheckCapabilities(ctx)
+	assert.Error(t, err)
 
-			var expected map[string]interface{}
-			file, err := ioutil.ReadFile("testdata/estimate_gas_0xaD6D458402F60fD3Bd25163575031ACDce07538D.json")
-			assert.NoError(t, err)
+	// Nodes without the debug namespace are rejected.
+	mockJSONRPC.On(
+		"CallContext", ctx, mock.Anything, "debug_traceTransaction", common.Hash{}, mock.Anything,
+	).Return(
+		&jsonRPCError{code: -32601, message: "the method debug_traceTransaction does not exist/is not available"},
+	).Once()
+	mockJSONRPC.On("CallContext", ctx, mock.Anything, "txpool_status").Return(nil).Once()
+	mockJSONRPC.On(
+		"CallContext", ctx, mock.Anything, "eth_getBlockReceipts", "earliest",
+	).Return(nil).Once()
+	mockGraphQL.On("Query", ctx, "{ block(number: 0) { hash } }").Return("404 page not found", nil).Once()
+	_, err = c.CheckCapabilities(ctx)
+	assert.Error(t, err)
 
-			err = json.Unmarshal(file, &expected)
-			assert.NoError(t, err)
+	mockJSONRPC.AssertExpectations(t)
+	mockGraphQL.AssertExpectations(t)
+}
 
-			*r = expected["data"].(string)
-		},
-	).Once()
+func TestGetBlockReceipts_BlockReceipts(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	c := &Client{c: mockJSONRPC, blockReceiptsAvailable: 1}
+	ctx := context.Background()
 
-	correctRaw, err := ioutil.ReadFile("testdata/estimate_gas_0xaD6D458402F60fD3Bd25163575031ACDce07538D.json")
+	blockHash := common.HexToHash("0xb2b2")
+	txs := []rpcTransaction{
+		{tx: types.NewTransaction(0, common.Address{}, big.NewInt(1), 21000, big.NewInt(1), nil)},
+		{tx: types.NewTransaction(1, common.Address{}, big.NewInt(1), 21000, big.NewInt(1), nil)},
+	}
+	receipts := func(hash common.Hash) []*types.Receipt {
+		var receipts []*types.Receipt
+		for _, tx := range txs {
+			receipts = append(receipts, &types.Receipt{TxHash: tx.tx.Hash(), BlockHash: hash})
+		}
+
+		return receipts
+	}
+
+	mockJSONRPC.On(
+		"CallContext", ctx, mock.Anything, "eth_getBlockReceipts", blockHash.Hex(),
+	).Return(nil).Run(func(args mock.Arguments) {
+		*(args.Get(1).(*[]*types.Receipt)) = receipts(blockHash)
+	}).Once()
+	fetched, err := c.getBlockReceipts(ctx, 1, blockHash, txs[:1])
 	assert.NoError(t, err)
-	var correct map[string]interface{}
-	assert.NoError(t, json.Unmarshal(correctRaw, &correct))
+	assert.Equal(t, receipts(blockHash)[:1], fetched)
 
-	resp, err := c.Call(
-		ctx,
-		&RosettaTypes.CallRequest{
-			Method: "eth_estimateGas",
-			Parameters: map[string]interface{}{
-				"from": "0xE550f300E477C60CE7e7172d12e5a27e9379D2e3",
-				"to":   "0xaD6D458402F60fD3Bd25163575031ACDce07538D",
-				"data": "0xa9059cbb000000000000000000000000ae7e48ee0f758cd706b76cf7e2175d982800879a" +
-					"00000000000000000000000000000000000000000000000000521c5f98b8ea00",
-			},
-		},
-	)
-	assert.Equal(t, &RosettaTypes.CallResponse{
-		Result:     correct,
-		Idempotent: false,
-	}, resp)
+	// Receipts of another block are orphaned.
+	mockJSONRPC.On(
+		"CallContext", ctx, mock.Anything, "eth_getBlockReceipts", blockHash.Hex(),
+	).Return(nil).Run(func(args mock.Arguments) {
+		*(args.Get(1).(*[]*types.Receipt)) = receipts(common.HexToHash("0xc3c3"))
+	}).Once()
+	_, err = c.getBlockReceipts(ctx, 1, blockHash, txs)
+	assert.True(t, errors.Is(err, ErrBlockOrphaned))
+
+	// Other failures fall back to eth_getTransactionReceipt.
+	mockJSONRPC.On(
+		"CallContext", ctx, mock.Anything, "eth_getBlockReceipts", blockHash.Hex(),
+	).Return(errors.New("timeout")).Once()
+	mockJSONRPC.On("BatchCallContext", ctx, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		for i, elem := range args.Get(1).([]rpc.BatchElem) {
+			assert.Equal(t, "eth_getTransactionReceipt", elem.Method)
+			*(elem.Result.(**types.Receipt)) = receipts(blockHash)[i]
+		}
+	}).Once()
+	fetched, err = c.getBlockReceipts(ctx, 1, blockHash, txs)
 	assert.NoError(t, err)
+	assert.Equal(t, receipts(blockHash), fetched)
 
 	mockJSONRPC.AssertExpectations(t)
-	mockGraphQL.AssertExpectations(t)
 }
 
-func TestCall_EstimateGas_InvalidArgs(t *testing.T) {
+func TestCancelTarget(t *testing.T) {
 	mockJSONRPC := &mocks.JSONRPC{}
-	mockGraphQL := &mocks.GraphQL{}
-
-	c := &Client{
-		c:              mockJSONRPC,
-		g:              mockGraphQL,
-		traceSemaphore: semaphore.NewWeighted(100),
+	c := &Client{c: mockJSONRPC}
+	ctx := context.Background()
+	account := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+
+	headNonce := func(nonce uint64) {
+		mockJSONRPC.On(
+			"CallContext", ctx, mock.Anything, "eth_getTransactionCount", account, "latest",
+		).Return(nil).Run(func(args mock.Arguments) {
+			*(args.Get(1).(*hexutil.Uint64)) = hexutil.Uint64(nonce)
+		}).Once()
 	}
 
-	ctx := context.Background()
-	resp, err := c.Call(
-		ctx,
-		&RosettaTypes.CallRequest{
-			Method: "eth_estimateGas",
-			Parameters: map[string]interface{}{
-				"From": "0xE550f300E477C60CE7e7172d12e5a27e9379D2e3",
-				"to":   "0xaD6D458402F60fD3Bd25163575031ACDce07538D",
-			},
+	// Mined transactions are not looked up in the mempool
+	headNonce(6)
+	target, err := c.CancelTarget(ctx, account, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, &CancelTarget{Nonce: 6}, target)
+
+	headNonce(5)
+	mockJSONRPC.On("CallContext", ctx, mock.Anything, "txpool_contentFrom", account).Return(nil).Run(
+		func(args mock.Arguments) {
+			assert.NoError(t, json.Unmarshal([]byte(`{
+				"pending": {
+					"5": {"gasPrice": "0x3b9aca00"},
+					"6": {"gasPrice": "0x1"}
+				},
+				"queued": {}
+			}`), args.Get(1)))
 		},
-	)
-	assert.Nil(t, resp)
-	assert.True(t, errors.Is(err, ErrCallParametersInvalid))
+	).Once()
+	target, err = c.CancelTarget(ctx, account, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, &CancelTarget{Nonce: 5, GasPrice: big.NewInt(1000000000)}, target)
+
+	// Transactions missing from the mempool have no gas price
+	headNonce(5)
+	mockJSONRPC.On("CallContext", ctx, mock.Anything, "txpool_contentFrom", account).Return(nil).Once()
+	target, err = c.CancelTarget(ctx, account, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, &CancelTarget{Nonce: 5}, target)
+
+	assert.Equal(t, big.NewInt(1100000000), ReplacementGasPrice(big.NewInt(1000000000)))
+	assert.Equal(t, big.NewInt(12), ReplacementGasPrice(big.NewInt(11)))
 
 	mockJSONRPC.AssertExpectations(t)
-	mockGraphQL.AssertExpectations(t)
 }
 
-func TestCall_InvalidMethod(t *testing.T) {
-	mockJSONRPC := &mocks.JSONRPC{}
-	mockGraphQL := &mocks.GraphQL{}
+func TestDelegateCoinData(t *testing.T) {
+	validator := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	data, err := DelegateCoinData(validator)
+	assert.NoError(t, err)
+	assert.Len(t, data, 36)
 
-	c := &Client{
-		c:              mockJSONRPC,
-		g:              mockGraphQL,
-		traceSemaphore: semaphore.NewWeighted(100),
-	}
+	parsed, ok := ParseDelegateCoinData(data)
+	assert.True(t, ok)
+	assert.Equal(t, validator, parsed)
 
-	ctx := context.Background()
-	resp, err := c.Call(
-		ctx,
-		&RosettaTypes.CallRequest{
-			Method: "blah",
-		},
-	)
-	assert.Nil(t, resp)
-	assert.True(t, errors.Is(err, ErrCallMethodInvalid))
+	// Other calldata is not a delegation
+	_, ok = ParseDelegateCoinData([]byte{})
+	assert.False(t, ok)
+	_, ok = ParseDelegateCoinData(RequiredCoinDepositData())
+	assert.False(t, ok)
+	_, ok = ParseDelegateCoinData(data[:20])
+	assert.False(t, ok)
 
-	mockJSONRPC.AssertExpectations(t)
-	mockGraphQL.AssertExpectations(t)
+	minimum, err := ParseRequiredCoinDeposit(math.U256Bytes(big.NewInt(1000)))
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(1000), minimum)
+
+	_, err = ParseRequiredCoinDeposit([]byte{0x01})
+	assert.Error(t, err)
 }
 
 func testTraceConfig() (*tracers.TraceConfig, error) {
@@ -1394,6 +7474,57 @@ func TestBlock_Index(t *testing.T) {
 	mockGraphQL.AssertExpectations(t)
 }
 
+func TestBlock_Unconvertible(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	c := &Client{
+		c:              mockJSONRPC,
+		p:              params.RopstenChainConfig,
+		traceSemaphore: semaphore.NewWeighted(100),
+	}
+
+	ctx := context.Background()
+	blockIdentifier := &RosettaTypes.PartialBlockIdentifier{
+		Index: RosettaTypes.Int64(10992),
+	}
+
+	// Blocks that cannot be fetched are not unconvertible
+	fetchErr := errors.New("connection refused")
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_getBlockByNumber",
+		"0x2af0",
+		true,
+	).Return(
+		fetchErr,
+	).Once()
+	_, err := c.Block(ctx, blockIdentifier)
+	assert.True(t, errors.Is(err, fetchErr))
+	assert.False(t, errors.Is(err, ErrBlockUnconvertible))
+
+	// Blocks that cannot be decoded are
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_getBlockByNumber",
+		"0x2af0",
+		true,
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).(*json.RawMessage)
+			*r = json.RawMessage(`{"number": "not a number"}`)
+		},
+	).Once()
+	_, err = c.Block(ctx, blockIdentifier)
+	assert.True(t, errors.Is(err, ErrBlockUnconvertible))
+
+	mockJSONRPC.AssertExpectations(t)
+}
+
 func TestBlock_FirstBlock(t *testing.T) {
 	mockJSONRPC := &mocks.JSONRPC{}
 	mockGraphQL := &mocks.GraphQL{}
@@ -1714,6 +7845,99 @@ func TestBlock_10994(t *testing.T) {
 	mockGraphQL.AssertExpectations(t)
 }
 
+// Block below the trace start index
+func TestBlock_Degraded(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	mockGraphQL := &mocks.GraphQL{}
+
+	tc, err := testTraceConfig()
+	assert.NoError(t, err)
+	c := &Client{
+		c:               mockJSONRPC,
+		g:               mockGraphQL,
+		tc:              tc,
+		p:               params.RopstenChainConfig,
+		traceSemaphore:  semaphore.NewWeighted(100),
+		traceStartIndex: 10995,
+	}
+
+	ctx := context.Background()
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_getBlockByNumber",
+		"0x2af2",
+		true,
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).(*json.RawMessage)
+
+			file, err := ioutil.ReadFile("testdata/block_10994.json")
+			assert.NoError(t, err)
+
+			*r = json.RawMessage(file)
+		},
+	).Once()
+	mockJSONRPC.On(
+		"BatchCallContext",
+		ctx,
+		mock.Anything,
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).([]rpc.BatchElem)
+
+			assert.Len(t, r, 1)
+			file, err := ioutil.ReadFile(
+				"testdata/tx_receipt_0xd83b1dcf7d47c4115d78ce0361587604e8157591b118bd64ada02e86c9d5ca7e.json",
+			) // nolint
+			assert.NoError(t, err)
+
+			receipt := new(types.Receipt)
+			assert.NoError(t, receipt.UnmarshalJSON(file))
+			*(r[0].Result.(**types.Receipt)) = receipt
+		},
+	).Once()
+
+	correctRaw, err := ioutil.ReadFile("testdata/block_response_10994.json")
+	assert.NoError(t, err)
+	var correctResp *RosettaTypes.BlockResponse
+	assert.NoError(t, json.Unmarshal(correctRaw, &correctResp))
+
+	resp, err := c.Block(
+		ctx,
+		&RosettaTypes.PartialBlockIdentifier{
+			Index: RosettaTypes.Int64(10994),
+		},
+	)
+	assert.NoError(t, err)
+
+	// The block is not traced, but its external
+	// call moves no value, so no operation is lost.
+	jsonResp, err := jsonifyBlock(resp)
+	assert.NoError(t, err)
+	assert.Equal(t, true, jsonResp.Metadata[DegradedMetadataKey])
+	assert.Len(t, jsonResp.Transactions, len(correctResp.Block.Transactions))
+	for i, tx := range jsonResp.Transactions {
+		expected := correctResp.Block.Transactions[i].Operations
+		assert.Len(t, tx.Operations, len(expected))
+		for j, op := range tx.Operations {
+			assert.Equal(t, expected[j].Type, op.Type)
+			assert.Equal(t, expected[j].Account, op.Account)
+			assert.Equal(t, expected[j].Amount.Value, op.Amount.Value)
+		}
+	}
+	assert.Equal(t, true, jsonResp.Transactions[1].Metadata[TraceUnavailableMetadataKey])
+	assert.NotContains(t, jsonResp.Transactions[1].Metadata, "trace")
+
+	mockJSONRPC.AssertExpectations(t)
+	mockGraphQL.AssertExpectations(t)
+}
+
 // Block with uncle
 func TestBlock_10991(t *testing.T) {
 	mockJSONRPC := &mocks.JSONRPC{}
@@ -2642,6 +8866,37 @@ func TestPendingNonceAt(t *testing.T) {
 	mockGraphQL.AssertExpectations(t)
 }
 
+func TestPendingBalanceAt(t *testing.T) {
+	mockJSONRPC := &mocks.JSONRPC{}
+	c := &Client{c: mockJSONRPC}
+
+	ctx := context.Background()
+	mockJSONRPC.On(
+		"CallContext",
+		ctx,
+		mock.Anything,
+		"eth_getBalance",
+		common.HexToAddress("0xfFC614eE978630D7fB0C06758DeB580c152154d3"),
+		"pending",
+	).Return(
+		nil,
+	).Run(
+		func(args mock.Arguments) {
+			r := args.Get(1).(*hexutil.Big)
+
+			*r = hexutil.Big(*big.NewInt(1000))
+		},
+	).Once()
+	resp, err := c.PendingBalanceAt(
+		ctx,
+		common.HexToAddress("0xfFC614eE978630D7fB0C06758DeB580c152154d3"),
+	)
+	assert.Equal(t, big.NewInt(1000), resp)
+	assert.NoError(t, err)
+
+	mockJSONRPC.AssertExpectations(t)
+}
+
 func TestSuggestGasPrice(t *testing.T) {
 	mockJSONRPC := &mocks.JSONRPC{}
 	mockGraphQL := &mocks.GraphQL{}
@@ -0,0 +1,82 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"math/big"
+
+	"github.com/coinbase/rosetta-ethereum/amount"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+)
+
+// collapseOps collapses the trace operations of a transaction
+// into a single CALL operation per account holding its net
+// balance change, in the order accounts first appear in ops.
+// Failed and zero-amount operations do not change balances,
+// so they are dropped. DESTRUCT operations are kept as is
+// because they remove currency from circulation.
+func collapseOps(ops []*RosettaTypes.Operation, startIndex int) []*RosettaTypes.Operation {
+	var accounts []string
+	net := map[string]*big.Int{}
+	var destructOps []*RosettaTypes.Operation
+	for _, op := range ops {
+		if op.Type == DestructOpType {
+			destructOps = append(destructOps, op)
+			continue
+		}
+
+		if op.Amount == nil || op.Status == nil || *op.Status != SuccessStatus {
+			continue
+		}
+
+		value, err := amount.Parse(op.Amount.Value)
+		if err != nil {
+			continue
+		}
+
+		address := op.Account.Address
+		if _, ok := net[address]; !ok {
+			accounts = append(accounts, address)
+			net[address] = new(big.Int)
+		}
+		net[address].Add(net[address], value)
+	}
+
+	var collapsed []*RosettaTypes.Operation
+	for _, address := range accounts {
+		if net[address].Sign() == 0 {
+			continue
+		}
+
+		collapsed = append(collapsed, &RosettaTypes.Operation{
+			Type:   CallOpType,
+			Status: RosettaTypes.String(SuccessStatus),
+			Account: &RosettaTypes.AccountIdentifier{
+				Address: address,
+			},
+			Amount: amount.New(net[address], Currency),
+		})
+	}
+	collapsed = append(collapsed, destructOps...)
+
+	for i, op := range collapsed {
+		op.OperationIdentifier = &RosettaTypes.OperationIdentifier{
+			Index: int64(startIndex + i),
+		}
+	}
+
+	return collapsed
+}
@@ -0,0 +1,195 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"fmt"
+	"math/big"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	EthTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// ConsensusMetadataKey is the block metadata key holding
+	// the consensus fields of the block header, decoded (see
+	// ConsensusMetadata).
+	ConsensusMetadataKey = "consensus"
+
+	// extraVanity and extraSeal are the lengths of the vanity
+	// prefix and of the validator signature suffix of the
+	// extraData of a header. On epoch blocks, the addresses
+	// of the next validators are in between.
+	extraVanity = 32
+	extraSeal   = crypto.SignatureLength
+
+	// inTurnDifficulty is the difficulty of blocks sealed by
+	// the validator whose turn it was. Other validators seal
+	// blocks with a difficulty of 1.
+	inTurnDifficulty = 2
+)
+
+// ConsensusMetadata are the consensus fields of a block header.
+type ConsensusMetadata struct {
+	// Difficulty is 2 if the block was sealed in turn,
+	// and 1 otherwise.
+	Difficulty string `json:"difficulty"`
+	InTurn     bool   `json:"in_turn"`
+
+	// Vanity and Signature are the hex-encoded prefix
+	// and suffix of the extraData of the header.
+	Vanity    string `json:"vanity,omitempty"`
+	Signature string `json:"signature,omitempty"`
+
+	// Sealer is the validator recovered from Signature. It
+	// is only set if it is the coinbase of the block, so it
+	// is empty if the signature cannot be recovered (i.e. at
+	// genesis) or was not made by the coinbase.
+	Sealer string `json:"sealer,omitempty"`
+
+	// Validators are the validators of the next epoch,
+	// only found in the extraData of epoch blocks.
+	Validators []string `json:"validators,omitempty"`
+}
+
+// consensusTransformer is a BlockTransformer adding the
+// consensus fields of headers to the metadata of blocks (see
+// ConsensusMetadataKey). It is enabled with the
+// CONSENSUS_METADATA setting rather than registered by name.
+type consensusTransformer struct {
+	chainID *big.Int
+}
+
+// NewConsensusTransformer returns a BlockTransformer adding the
+// consensus fields of the headers of the chain with chainID to
+// the metadata of blocks.
+func NewConsensusTransformer(chainID *big.Int) BlockTransformer {
+	return &consensusTransformer{chainID: chainID}
+}
+
+// TransformTransaction does not modify tx.
+func (t *consensusTransformer) TransformTransaction(
+	tx *RosettaTypes.Transaction,
+	raw *EthTypes.Transaction,
+	receipt *EthTypes.Receipt,
+) error {
+	return nil
+}
+
+// TransformBlock adds the consensus fields of the header of raw to block.
+func (t *consensusTransformer) TransformBlock(block *RosettaTypes.Block, raw *EthTypes.Block) error {
+	metadata, err := marshalJSONMap(consensusMetadata(t.chainID, raw.Header()))
+	if err != nil {
+		return fmt.Errorf("%w: unable to marshal consensus metadata of block %s", err, raw.Hash().Hex())
+	}
+
+	if block.Metadata == nil {
+		block.Metadata = map[string]interface{}{}
+	}
+	block.Metadata[ConsensusMetadataKey] = metadata
+
+	return nil
+}
+
+// consensusMetadata decodes the consensus fields of header.
+// The extraData of headers that are too short to hold a
+// signature is not decoded.
+func consensusMetadata(chainID *big.Int, header *EthTypes.Header) *ConsensusMetadata {
+	metadata := &ConsensusMetadata{
+		Difficulty: header.Difficulty.String(),
+		InTurn:     header.Difficulty.Cmp(big.NewInt(inTurnDifficulty)) == 0,
+	}
+
+	extra := header.Extra
+	if len(extra) < extraVanity+extraSeal {
+		return metadata
+	}
+
+	signature := extra[len(extra)-extraSeal:]
+	metadata.Vanity = hexutil.Encode(extra[:extraVanity])
+	metadata.Signature = hexutil.Encode(signature)
+
+	validators := extra[extraVanity : len(extra)-extraSeal]
+	if len(validators)%common.AddressLength == 0 {
+		for i := 0; i < len(validators); i += common.AddressLength {
+			validator := common.BytesToAddress(validators[i : i+common.AddressLength])
+			metadata.Validators = append(metadata.Validators, validator.Hex())
+		}
+	}
+
+	sealer, err := recoverSealer(chainID, header, signature)
+	if err == nil && sealer == header.Coinbase {
+		metadata.Sealer = sealer.Hex()
+	}
+
+	return metadata
+}
+
+// sealHash returns the hash signed by the validator sealing
+// header: the hash of the RLP encoding of the chain ID and the
+// header fields, without the signature in its extraData.
+func sealHash(chainID *big.Int, header *EthTypes.Header) (common.Hash, error) {
+	fields := []interface{}{
+		chainID,
+		header.ParentHash,
+		header.UncleHash,
+		header.Coinbase,
+		header.Root,
+		header.TxHash,
+		header.ReceiptHash,
+		header.Bloom,
+		header.Difficulty,
+		header.Number,
+		header.GasLimit,
+		header.GasUsed,
+		header.Time,
+		header.Extra[:len(header.Extra)-extraSeal],
+		header.MixDigest,
+		header.Nonce,
+	}
+	if header.BaseFee != nil {
+		fields = append(fields, header.BaseFee)
+	}
+
+	encoded, err := rlp.EncodeToBytes(fields)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// recoverSealer returns the address that
+// signed header with signature.
+func recoverSealer(chainID *big.Int, header *EthTypes.Header, signature []byte) (common.Address, error) {
+	hash, err := sealHash(chainID, header)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	pubkey, err := crypto.Ecrecover(hash.Bytes(), signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	var sealer common.Address
+	copy(sealer[:], crypto.Keccak256(pubkey[1:])[12:])
+
+	return sealer, nil
+}
@@ -0,0 +1,223 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	EthTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+// CustomTraceMetadataKey is the transaction metadata
+// key of the output of the custom tracer.
+const CustomTraceMetadataKey = "custom_trace"
+
+// nativeTracerName matches the names of the tracers
+// built into geth (i.e. "prestateTracer").
+var nativeTracerName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// TraceProcessor converts the output of a custom tracer for a
+// transaction into operations. Operations must be indexed from
+// startIndex and, like all operations, are subject to the
+// invariant checks (see checkInvariant). Processors are registered by name with
+// RegisterTraceProcessor, usually in the init function of the
+// package that implements them.
+type TraceProcessor interface {
+	Operations(
+		tx *EthTypes.Transaction,
+		receipt *EthTypes.Receipt,
+		trace json.RawMessage,
+		startIndex int,
+	) ([]*RosettaTypes.Operation, error)
+}
+
+var (
+	traceProcessorsMutex sync.RWMutex
+	traceProcessors      = map[string]TraceProcessor{}
+)
+
+// RegisterTraceProcessor makes processor available under name.
+// It panics if a processor is already registered under name.
+func RegisterTraceProcessor(name string, processor TraceProcessor) {
+	traceProcessorsMutex.Lock()
+	defer traceProcessorsMutex.Unlock()
+
+	if _, ok := traceProcessors[name]; ok {
+		panic(fmt.Sprintf("trace processor %s is already registered", name))
+	}
+	traceProcessors[name] = processor
+}
+
+// LookupTraceProcessor returns the
+// processor registered under name.
+func LookupTraceProcessor(name string) (TraceProcessor, bool) {
+	traceProcessorsMutex.RLock()
+	defer traceProcessorsMutex.RUnlock()
+
+	processor, ok := traceProcessors[name]
+	return processor, ok
+}
+
+// TraceProcessors returns the names of
+// the registered processors.
+func TraceProcessors() []string {
+	traceProcessorsMutex.RLock()
+	defer traceProcessorsMutex.RUnlock()
+
+	names := make([]string, 0, len(traceProcessors))
+	for name := range traceProcessors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// CustomTracer is a tracer run on every transaction in addition
+// to the call tracer used to compute operations.
+type CustomTracer struct {
+	// Tracer is the source of a JS tracer or
+	// the name of a native tracer.
+	Tracer string
+
+	// Processor converts the output of Tracer into operations.
+	// If nil, the output is only surfaced in the metadata of
+	// the transaction.
+	Processor TraceProcessor
+}
+
+// LoadCustomTracer returns the *CustomTracer of tracer, which is
+// either the path of a JS tracer file or the name of a native
+// tracer.
+func LoadCustomTracer(tracer string, processor TraceProcessor) (*CustomTracer, error) {
+	if _, err := os.Stat(tracer); err == nil {
+		source, err := ioutil.ReadFile(tracer)
+		if err != nil {
+			return nil, fmt.Errorf("%w: could not load tracer file %s", err, tracer)
+		}
+
+		return &CustomTracer{Tracer: string(source), Processor: processor}, nil
+	}
+
+	if !nativeTracerName.MatchString(tracer) {
+		return nil, fmt.Errorf("%s is neither a tracer file nor the name of a native tracer", tracer)
+	}
+
+	return &CustomTracer{Tracer: tracer, Processor: processor}, nil
+}
+
+func (t *CustomTracer) traceConfig() *tracers.TraceConfig {
+	return &tracers.TraceConfig{
+		Timeout: &tracerTimeout,
+		Tracer:  &t.Tracer,
+	}
+}
+
+// customTraces returns the output of the custom tracer for txs,
+// tracing them individually if the block (or rng, see traceBlock)
+// cannot be traced. Unlike the call tracer, a transaction that
+// cannot be traced fails the block, as its operations would
+// silently be missing.
+func (ec *Client) customTraces(
+	ctx context.Context,
+	blockHash common.Hash,
+	rng hexutil.Bytes,
+	txs []rpcTransaction,
+) ([]json.RawMessage, error) {
+	traces, err := ec.customBlockTraces(ctx, blockHash, rng)
+	if err == nil && len(traces) == len(txs) {
+		return traces, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	traces = make([]json.RawMessage, len(txs))
+	for i, tx := range txs {
+		trace, err := ec.customTransactionTrace(ctx, tx.tx.Hash())
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to trace %s with custom tracer", err, tx.tx.Hash().Hex())
+		}
+		traces[i] = trace
+	}
+
+	return traces, nil
+}
+
+func (ec *Client) customBlockTraces(
+	ctx context.Context,
+	blockHash common.Hash,
+	rng hexutil.Bytes,
+) ([]json.RawMessage, error) {
+	if err := ec.traceSemaphore.Acquire(ctx, semaphoreTraceWeight); err != nil {
+		return nil, err
+	}
+	defer ec.traceSemaphore.Release(semaphoreTraceWeight)
+
+	var traces []*rpcRawCall
+	var err error
+	if rng != nil {
+		err = ec.c.CallContext(ctx, &traces, "debug_traceBlock", rng, ec.customTracer.traceConfig())
+	} else {
+		err = ec.c.CallContext(ctx, &traces, "debug_traceBlockByHash", blockHash, ec.customTracer.traceConfig())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]json.RawMessage, len(traces))
+	for i, trace := range traces {
+		results[i] = trace.Result
+	}
+
+	return results, nil
+}
+
+func (ec *Client) customTransactionTrace(ctx context.Context, hash common.Hash) (json.RawMessage, error) {
+	if err := ec.traceSemaphore.Acquire(ctx, semaphoreTraceWeight); err != nil {
+		return nil, err
+	}
+	defer ec.traceSemaphore.Release(semaphoreTraceWeight)
+
+	var trace json.RawMessage
+	err := ec.c.CallContext(ctx, &trace, "debug_traceTransaction", hash, ec.customTracer.traceConfig())
+	return trace, err
+}
+
+// customTraceOps returns the operations of the output
+// of the custom tracer for tx, if any.
+func (ec *Client) customTraceOps(tx *loadedTransaction, startIndex int) ([]*RosettaTypes.Operation, error) {
+	if ec.customTracer == nil || ec.customTracer.Processor == nil || tx.CustomTrace == nil {
+		return nil, nil
+	}
+
+	ops, err := ec.customTracer.Processor.Operations(tx.Transaction, tx.Receipt, tx.CustomTrace, startIndex)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to process custom trace", err)
+	}
+
+	return ops, nil
+}
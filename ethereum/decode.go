@@ -0,0 +1,166 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/coinbase/rosetta-ethereum/amount"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// DecodeTransactionMethod is the /call method used to decode
+// a raw transaction without broadcasting it.
+const DecodeTransactionMethod = "decode_transaction"
+
+// DecodeTransactionInput is the input to the call
+// method "decode_transaction".
+type DecodeTransactionInput struct {
+	// Transaction is the hex-encoded binary (RLP or typed
+	// envelope) representation of a transaction.
+	Transaction string `json:"transaction"`
+}
+
+// DecodedTransaction is the output of the call
+// method "decode_transaction".
+type DecodedTransaction struct {
+	Hash        string                 `json:"hash"`
+	Transaction map[string]interface{} `json:"transaction"`
+
+	// From is only populated if the transaction is
+	// signed and the sender can be recovered.
+	From   string `json:"from,omitempty"`
+	Signed bool   `json:"signed"`
+
+	// ChainIDMatches is false if the transaction is replay
+	// protected for a different chain than the node's.
+	ChainIDMatches bool `json:"chain_id_matches"`
+
+	// Operations are the operations the transaction is
+	// expected to produce if it succeeds (excluding fees,
+	// which depend on the gas used).
+	Operations []*RosettaTypes.Operation `json:"operations"`
+
+	// Method is the method called by the transaction, if it is
+	// registered or resolved with the 4byte directory (see
+	// abiRegistry).
+	Method map[string]interface{} `json:"method,omitempty"`
+}
+
+// decodeTransaction decodes a raw transaction and derives
+// the Rosetta operations of the value it transfers.
+func (ec *Client) decodeTransaction(
+	params map[string]interface{},
+) (map[string]interface{}, error) {
+	var input DecodeTransactionInput
+	if err := RosettaTypes.UnmarshalMap(params, &input); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCallParametersInvalid, err.Error())
+	}
+
+	raw, err := hexutil.Decode(input.Transaction)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to decode transaction hex: %s", ErrCallParametersInvalid, err.Error())
+	}
+
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("%w: unable to decode transaction: %s", ErrCallParametersInvalid, err.Error())
+	}
+
+	txJSON, err := tx.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCallOutputMarshal, err.Error())
+	}
+
+	var txMap map[string]interface{}
+	if err := json.Unmarshal(txJSON, &txMap); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCallOutputMarshal, err.Error())
+	}
+
+	decoded := &DecodedTransaction{
+		Hash:           tx.Hash().Hex(),
+		Transaction:    txMap,
+		ChainIDMatches: !tx.Protected() || tx.ChainId().Cmp(ec.p.ChainID) == 0,
+		Operations:     []*RosettaTypes.Operation{},
+	}
+
+	v, r, s := tx.RawSignatureValues()
+	if v.Sign() != 0 || r.Sign() != 0 || s.Sign() != 0 {
+		signer := types.LatestSignerForChainID(tx.ChainId())
+		if !tx.Protected() {
+			signer = types.HomesteadSigner{}
+		}
+
+		if from, err := types.Sender(signer, tx); err == nil {
+			decoded.From = MustChecksum(from.Hex())
+			decoded.Signed = true
+			decoded.Operations = transferOps(from, tx)
+		}
+	}
+
+	if tx.To() != nil {
+		if method, ok := ec.abiRegistry.decode(tx.Data(), true); ok {
+			decoded.Method = method.metadata()
+		}
+	}
+
+	return marshalJSONMap(decoded)
+}
+
+// transferOps returns the operations transferring the value
+// of tx from sender to its recipient: the called account or,
+// for a contract creation, the address of the created contract
+// (which only depends on sender and the nonce of tx).
+func transferOps(sender common.Address, tx *types.Transaction) []*RosettaTypes.Operation {
+	opType := CallOpType
+	var recipient common.Address
+	if to := tx.To(); to != nil {
+		recipient = *to
+	} else {
+		opType = CreateOpType
+		recipient = crypto.CreateAddress(sender, tx.Nonce())
+	}
+
+	ops := []*RosettaTypes.Operation{
+		{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{
+				Index: 0,
+			},
+			Type:    opType,
+			Account: &RosettaTypes.AccountIdentifier{Address: MustChecksum(sender.Hex())},
+			Amount:  amount.NewNeg(tx.Value(), Currency),
+		},
+	}
+
+	return append(ops, &RosettaTypes.Operation{
+		OperationIdentifier: &RosettaTypes.OperationIdentifier{
+			Index: 1,
+		},
+		RelatedOperations: []*RosettaTypes.OperationIdentifier{
+			{
+				Index: 0,
+			},
+		},
+		Type:    opType,
+		Account: &RosettaTypes.AccountIdentifier{Address: MustChecksum(recipient.Hex())},
+		Amount:  amount.New(tx.Value(), Currency),
+	})
+}
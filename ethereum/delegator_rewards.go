@@ -0,0 +1,193 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// DelegatorRewardsMethod is the /call method used to fetch the
+// rewards a delegator has accrued but not claimed at a given block.
+const DelegatorRewardsMethod = "delegator_rewards"
+
+// DelegatorRewardsInput is the input to the
+// call method "delegator_rewards".
+type DelegatorRewardsInput struct {
+	BlockIndex int64  `json:"index,omitempty"`
+	BlockHash  string `json:"hash,omitempty"`
+	Delegator  string `json:"delegator"`
+}
+
+// DelegatorReward is the reward a delegator has accrued
+// from its delegation to a validator candidate.
+type DelegatorReward struct {
+	Validator string `json:"validator"`
+	Deposit   string `json:"deposit"`
+	Accrued   string `json:"accrued"`
+}
+
+// DelegatorRewards is the result of the call method
+// "delegator_rewards". Settled are the rewards PledgeAgent has
+// already credited to the delegator (see
+// UnclaimedRewardsMetadataKey), and Accrued are all the rewards
+// the delegator would receive by claiming at the block (Settled
+// and the rewards of every delegation of past rounds). If
+// Complete is false, PledgeAgent would only settle some of the
+// rounds in a single claim and Accrued is a lower bound.
+type DelegatorRewards struct {
+	BlockIdentifier *RosettaTypes.BlockIdentifier `json:"block_identifier"`
+	Delegator       string                        `json:"delegator"`
+	Round           int64                         `json:"round"`
+	Settled         string                        `json:"settled"`
+	Accrued         string                        `json:"accrued"`
+	Complete        bool                          `json:"complete"`
+	Validators      []*DelegatorReward            `json:"validators"`
+}
+
+// delegatorRewardsCall returns the rewards the requested
+// delegator has not claimed at the requested block.
+func (ec *Client) delegatorRewardsCall(
+	ctx context.Context,
+	params map[string]interface{},
+) (map[string]interface{}, error) {
+	var input DelegatorRewardsInput
+	if err := RosettaTypes.UnmarshalMap(params, &input); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCallParametersInvalid, err.Error())
+	}
+
+	if !common.IsHexAddress(input.Delegator) {
+		return nil, fmt.Errorf("%w: %s is not a valid delegator address", ErrCallParametersInvalid, input.Delegator)
+	}
+
+	header, err := ec.callHeader(ctx, input.BlockIndex, input.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	rewards, err := ec.delegatorRewards(ctx, toBlockNumArg(header.Number), common.HexToAddress(input.Delegator))
+	if err != nil {
+		return nil, err
+	}
+	rewards.BlockIdentifier = headerIdentifier(header)
+
+	return marshalJSONMap(rewards)
+}
+
+// delegatorRewards returns the rewards delegator has not
+// claimed at blockQuery. The rewards accrued from every
+// delegation are computed by simulating a claim of them,
+// so they are computed exactly as PledgeAgent would.
+func (ec *Client) delegatorRewards(
+	ctx context.Context,
+	blockQuery string,
+	delegator common.Address,
+) (*DelegatorRewards, error) {
+	round, err := ec.callContractBig(ctx, systemABI, CandidateHubContract, blockQuery, "roundTag")
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get round", err)
+	}
+
+	delegations, err := ec.delegations(ctx, blockQuery, delegator)
+	if err != nil {
+		return nil, err
+	}
+
+	settled, err := ec.callContractBig(ctx, pledgeAgent, PledgeAgentContract, blockQuery, "rewardMap", delegator)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get unclaimed rewards", err)
+	}
+
+	rewards := &DelegatorRewards{
+		Delegator:  MustChecksum(delegator.Hex()),
+		Round:      round.Int64(),
+		Settled:    settled.String(),
+		Complete:   true,
+		Validators: []*DelegatorReward{},
+	}
+	accrued := new(big.Int).Set(settled)
+	for _, delegation := range delegations {
+		claimed, complete, err := ec.simulateClaim(ctx, blockQuery, delegator, delegation.validator)
+		if err != nil {
+			return nil, err
+		}
+
+		// Every claim also pays the settled rewards.
+		validatorAccrued := new(big.Int).Sub(claimed, settled)
+		accrued.Add(accrued, validatorAccrued)
+		rewards.Complete = rewards.Complete && complete
+		rewards.Validators = append(rewards.Validators, &DelegatorReward{
+			Validator: MustChecksum(delegation.validator.Hex()),
+			Deposit:   delegation.deposit.String(),
+			Accrued:   validatorAccrued.String(),
+		})
+	}
+	rewards.Accrued = accrued.String()
+
+	return rewards, nil
+}
+
+// simulateClaim returns the rewards delegator would receive by
+// claiming the rewards of its delegation to validator at
+// blockQuery, and whether all of them would be claimed.
+func (ec *Client) simulateClaim(
+	ctx context.Context,
+	blockQuery string,
+	delegator common.Address,
+	validator common.Address,
+) (*big.Int, bool, error) {
+	data, err := pledgeAgent.Pack("claimReward", []common.Address{validator})
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: unable to pack claimReward", err)
+	}
+
+	callParams := map[string]string{
+		"from": delegator.Hex(),
+		"to":   PledgeAgentContract.Hex(),
+		"data": hexutil.Encode(data),
+	}
+
+	var resp string
+	if err := ec.c.CallContext(ctx, &resp, "eth_call", callParams, blockQuery); err != nil {
+		return nil, false, fmt.Errorf("%w: unable to simulate claiming rewards from %s", err, validator.Hex())
+	}
+
+	output, err := hexutil.Decode(resp)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: unable to decode claimReward", err)
+	}
+
+	values, err := pledgeAgent.Unpack("claimReward", output)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: unable to unpack claimReward", err)
+	}
+
+	reward, ok := values[0].(*big.Int)
+	if !ok {
+		return nil, false, fmt.Errorf("unexpected output type %T from claimReward", values[0])
+	}
+
+	complete, ok := values[1].(bool)
+	if !ok {
+		return nil, false, fmt.Errorf("unexpected output type %T from claimReward", values[1])
+	}
+
+	return reward, complete, nil
+}
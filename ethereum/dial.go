@@ -0,0 +1,94 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
+)
+
+// proxyFunc returns the proxy selection used for all upstream
+// connections. If proxy is nil, the standard HTTP_PROXY,
+// HTTPS_PROXY, and NO_PROXY environment variables are honored.
+// Both HTTP(S) and SOCKS5 proxies are supported.
+func proxyFunc(proxy *url.URL) func(*http.Request) (*url.URL, error) {
+	if proxy == nil {
+		return http.ProxyFromEnvironment
+	}
+
+	return http.ProxyURL(proxy)
+}
+
+// newTransport returns a copy of http.DefaultTransport
+// using the provided proxy.
+func newTransport(proxy *url.URL) *http.Transport {
+	// See this conversation around why `.Clone()` is used here:
+	// https://github.com/golang/go/issues/26013
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFunc(proxy)
+
+	return transport
+}
+
+// dialRPC connects to a JSON-RPC endpoint over HTTP(S)
+// or WebSocket (ws:// or wss://), through proxy if it
+// is not nil.
+func dialRPC(endpoint string, proxy *url.URL) (*rpc.Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return rpc.DialHTTPWithClient(endpoint, &http.Client{
+			Timeout:   gethHTTPTimeout,
+			Transport: newTransport(proxy),
+		})
+	case "ws", "wss":
+		ctx, cancel := context.WithTimeout(context.Background(), gethHTTPTimeout)
+		defer cancel()
+
+		dialer := *websocket.DefaultDialer
+		dialer.Proxy = proxyFunc(proxy)
+		return rpc.DialWebsocketWithDialer(ctx, endpoint, "", dialer)
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+}
+
+// httpURL returns the HTTP(S) URL served by the same
+// host as endpoint (i.e. to reach GraphQL when connected
+// over WebSocket).
+func httpURL(endpoint string) (*url.URL, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	}
+
+	return u, nil
+}
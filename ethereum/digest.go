@@ -0,0 +1,78 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/coinbase/rosetta-ethereum/canonical"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	// DigestMetadataKey is the block metadata key holding
+	// the digest of the converted block (see BlockDigest).
+	DigestMetadataKey = "digest"
+)
+
+// BlockDigest returns the SHA256 hash of the canonical JSON
+// encoding (see canonical.Marshal) of block: its identifiers,
+// timestamp, transactions, operations, and metadata, ignoring
+// any digest already in its metadata.
+// Two deployments that convert a block identically compute the
+// same digest, regardless of version or platform, so comparing
+// digests is enough to detect conversions that drifted apart.
+func BlockDigest(block *RosettaTypes.Block) (string, error) {
+	digestable := *block
+	if _, ok := block.Metadata[DigestMetadataKey]; ok {
+		digestable.Metadata = make(map[string]interface{}, len(block.Metadata)-1)
+		for key, value := range block.Metadata {
+			if key != DigestMetadataKey {
+				digestable.Metadata[key] = value
+			}
+		}
+	}
+
+	if len(digestable.Metadata) == 0 {
+		digestable.Metadata = nil
+	}
+
+	encoded, err := canonical.Marshal(&digestable)
+	if err != nil {
+		return "", fmt.Errorf("%w: unable to encode block", err)
+	}
+
+	digest := sha256.Sum256(encoded)
+	return hexutil.Encode(digest[:]), nil
+}
+
+// addBlockDigest populates the DigestMetadataKey
+// metadata of block.
+func addBlockDigest(block *RosettaTypes.Block) error {
+	digest, err := BlockDigest(block)
+	if err != nil {
+		return err
+	}
+
+	if block.Metadata == nil {
+		block.Metadata = map[string]interface{}{}
+	}
+	block.Metadata[DigestMetadataKey] = digest
+
+	return nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"math/big"
+
+	"github.com/coinbase/rosetta-ethereum/amount"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+)
+
+const (
+	// DustOperationsMetadataKey is the metadata key of DUST
+	// operations holding the number of operations they replace.
+	DustOperationsMetadataKey = "dust_operations"
+)
+
+// filterDust drops the successful trace operations moving less
+// than threshold (in either direction) and appends a DUST
+// operation per account holding the net amount of the operations
+// dropped for it, in the order accounts first appear in ops, so
+// balances still reconcile. Dropped operations that net to zero
+// for an account do not have a DUST operation. Failed operations
+// do not change balances, so failed dust is dropped, while
+// operations without an amount and DESTRUCT operations are always
+// kept. The related operations of kept operations are remapped
+// to their new indexes. If threshold is nil, ops are returned
+// unchanged.
+func filterDust(
+	ops []*RosettaTypes.Operation,
+	startIndex int,
+	threshold *big.Int,
+) []*RosettaTypes.Operation {
+	if threshold == nil {
+		return ops
+	}
+
+	var kept []*RosettaTypes.Operation
+	var accounts []string
+	net := map[string]*big.Int{}
+	count := map[string]int64{}
+	for _, op := range ops {
+		if op.Amount == nil || op.Type == DestructOpType {
+			kept = append(kept, op)
+			continue
+		}
+
+		value, err := amount.Parse(op.Amount.Value)
+		if err != nil || new(big.Int).Abs(value).Cmp(threshold) >= 0 {
+			kept = append(kept, op)
+			continue
+		}
+
+		if op.Status == nil || *op.Status != SuccessStatus {
+			continue
+		}
+
+		address := op.Account.Address
+		if _, ok := net[address]; !ok {
+			accounts = append(accounts, address)
+			net[address] = new(big.Int)
+		}
+		net[address].Add(net[address], value)
+		count[address]++
+	}
+
+	for _, address := range accounts {
+		if net[address].Sign() == 0 {
+			continue
+		}
+
+		kept = append(kept, &RosettaTypes.Operation{
+			Type:   DustOpType,
+			Status: RosettaTypes.String(SuccessStatus),
+			Account: &RosettaTypes.AccountIdentifier{
+				Address: address,
+			},
+			Amount: amount.New(net[address], Currency),
+			Metadata: map[string]interface{}{
+				DustOperationsMetadataKey: count[address],
+			},
+		})
+	}
+
+	// Operations are renumbered, so related operations
+	// are remapped (or dropped along with their operation).
+	indexes := map[int64]int64{}
+	for i, op := range kept {
+		if op.OperationIdentifier != nil {
+			indexes[op.OperationIdentifier.Index] = int64(startIndex + i)
+		}
+	}
+	for i, op := range kept {
+		op.OperationIdentifier = &RosettaTypes.OperationIdentifier{
+			Index: int64(startIndex + i),
+		}
+
+		var related []*RosettaTypes.OperationIdentifier
+		for _, identifier := range op.RelatedOperations {
+			if index, ok := indexes[identifier.Index]; ok {
+				related = append(related, &RosettaTypes.OperationIdentifier{Index: index})
+			}
+		}
+		op.RelatedOperations = related
+	}
+
+	return kept
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"math/big"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// approvalTopicCount is the number of topics in an ERC-20
+	// Approval event. ERC-721 Approval events share the same
+	// signature but index the token ID as a fourth topic.
+	approvalTopicCount = 3
+
+	// approvalDataLength is the length of the
+	// (unindexed) value of an ERC-20 Approval event.
+	approvalDataLength = 32
+)
+
+var (
+	// approvalTopic is the topic of the ERC-20 Approval event.
+	approvalTopic = crypto.Keccak256Hash([]byte("Approval(address,address,uint256)"))
+
+	// unlimitedAllowance is the allowance at and above which an
+	// approval is considered unlimited. Wallets usually approve
+	// 2^256-1, but some tokens decrement the allowance on every
+	// transfer so anything in the top half of the range is
+	// treated as unlimited.
+	unlimitedAllowance = new(big.Int).Lsh(big.NewInt(1), 255) // nolint:gomnd
+)
+
+// approvalOps returns an APPROVAL operation for every ERC-20
+// Approval event in receipt. Approvals do not move funds, so
+// the operations do not have an amount. The token, spender, and
+// allowance are populated in the operation metadata.
+func approvalOps(receipt *types.Receipt, startIndex int) []*RosettaTypes.Operation {
+	var ops []*RosettaTypes.Operation
+	if receipt == nil {
+		return ops
+	}
+
+	for _, log := range receipt.Logs {
+		if len(log.Topics) != approvalTopicCount ||
+			log.Topics[0] != approvalTopic ||
+			len(log.Data) != approvalDataLength {
+			continue
+		}
+
+		owner := common.BytesToAddress(log.Topics[1].Bytes())
+		spender := common.BytesToAddress(log.Topics[2].Bytes())
+		allowance := new(big.Int).SetBytes(log.Data)
+
+		ops = append(ops, &RosettaTypes.Operation{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{
+				Index: int64(len(ops) + startIndex),
+			},
+			Type:   ApprovalOpType,
+			Status: RosettaTypes.String(SuccessStatus),
+			Account: &RosettaTypes.AccountIdentifier{
+				Address: MustChecksum(owner.Hex()),
+			},
+			Metadata: map[string]interface{}{
+				"token":     MustChecksum(log.Address.Hex()),
+				"spender":   MustChecksum(spender.Hex()),
+				"allowance": allowance.String(),
+				"unlimited": allowance.Cmp(unlimitedAllowance) >= 0,
+				"log_index": log.Index,
+			},
+		})
+	}
+
+	return ops
+}
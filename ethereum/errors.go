@@ -22,4 +22,36 @@ var (
 	ErrCallParametersInvalid = errors.New("call parameters invalid")
 	ErrCallOutputMarshal     = errors.New("call output marshal")
 	ErrCallMethodInvalid     = errors.New("call method invalid")
+	ErrNodeLagging           = errors.New("node lagging behind reference nodes")
+	ErrInvariantViolated     = errors.New("block operations violate double-entry invariant")
+	ErrChainMismatch         = errors.New("node is on a different chain than configured")
+	ErrCurrencyUnsupported   = errors.New("currency unsupported")
+	ErrCurrencyDuplicated    = errors.New("currency duplicated")
+
+	// ErrBlockUnconvertible is matched by the errors of blocks
+	// that were fetched but could not be traced or converted,
+	// as opposed to blocks that could not be fetched.
+	ErrBlockUnconvertible = errors.New("block unconvertible")
 )
+
+// unconvertibleError marks an error with ErrBlockUnconvertible
+// while preserving the errors it wraps.
+type unconvertibleError struct {
+	err error
+}
+
+func unconvertible(err error) error {
+	return &unconvertibleError{err: err}
+}
+
+func (e *unconvertibleError) Error() string {
+	return e.err.Error()
+}
+
+func (e *unconvertibleError) Unwrap() error {
+	return e.err
+}
+
+func (e *unconvertibleError) Is(target error) bool {
+	return target == ErrBlockUnconvertible
+}
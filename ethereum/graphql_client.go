@@ -74,9 +74,10 @@ func (g *GraphQLClient) Query(ctx context.Context, input string) (string, error)
 	return string(data), nil
 }
 
-func newGraphQLClient(baseURL string) (*GraphQLClient, error) {
-	// Compute GraphQL Endpoint
-	u, err := url.Parse(baseURL)
+func newGraphQLClient(baseURL string, proxy *url.URL) (*GraphQLClient, error) {
+	// Compute GraphQL Endpoint (GraphQL is always
+	// served over HTTP, even if baseURL is a WebSocket)
+	u, err := httpURL(baseURL)
 	if err != nil {
 		return nil, err
 	}
@@ -87,10 +88,7 @@ func newGraphQLClient(baseURL string) (*GraphQLClient, error) {
 		Timeout: graphQLHTTPTimeout,
 	}
 	// Override transport idle connection settings
-	//
-	// See this conversation around why `.Clone()` is used here:
-	// https://github.com/golang/go/issues/26013
-	customTransport := http.DefaultTransport.(*http.Transport).Clone()
+	customTransport := newTransport(proxy)
 	customTransport.IdleConnTimeout = graphQLIdleConnectionTimeout
 	customTransport.MaxIdleConns = graphQLMaxIdle
 	customTransport.MaxIdleConnsPerHost = graphQLMaxIdle
@@ -0,0 +1,457 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// graphQLPageSize is the maximum number of transactions
+	// whose receipts are requested in a single GraphQL document.
+	// Blocks with more transactions are paged through.
+	graphQLPageSize = 250
+
+	// receiptWindowCacheSize is the number of
+	// windows of prefetched receipts kept.
+	receiptWindowCacheSize = 4
+
+	// receiptWindowTimeout bounds the fetch of a window
+	// of receipts, which is not tied to the requests
+	// waiting for it.
+	receiptWindowTimeout = 2 * time.Minute
+
+	// graphQLReceiptFields are the fields of a
+	// transaction used to rebuild its receipt.
+	graphQLReceiptFields = "hash index type status gasUsed cumulativeGasUsed " +
+		"createdContract { address } logs { index account { address } topics data }"
+
+	graphQLReceiptsFallbackMetric = "graphql_receipts/fallbacks"
+)
+
+// graphQLLong decodes a GraphQL Long, which some
+// nodes serve as a hex string instead of a number.
+type graphQLLong uint64
+
+func (l *graphQLLong) UnmarshalJSON(input []byte) error {
+	if len(input) > 0 && input[0] == '"' {
+		var value hexutil.Uint64
+		if err := json.Unmarshal(input, &value); err != nil {
+			return err
+		}
+
+		*l = graphQLLong(value)
+		return nil
+	}
+
+	var value uint64
+	if err := json.Unmarshal(input, &value); err != nil {
+		return err
+	}
+
+	*l = graphQLLong(value)
+	return nil
+}
+
+type graphQLAccount struct {
+	Address common.Address `json:"address"`
+}
+
+type graphQLLog struct {
+	Index   uint           `json:"index"`
+	Account graphQLAccount `json:"account"`
+	Topics  []common.Hash  `json:"topics"`
+	Data    hexutil.Bytes  `json:"data"`
+}
+
+// graphQLReceipt is a transaction queried
+// with graphQLReceiptFields.
+type graphQLReceipt struct {
+	Hash              common.Hash     `json:"hash"`
+	Index             uint            `json:"index"`
+	Type              uint8           `json:"type"`
+	Status            *graphQLLong    `json:"status"`
+	GasUsed           graphQLLong     `json:"gasUsed"`
+	CumulativeGasUsed graphQLLong     `json:"cumulativeGasUsed"`
+	CreatedContract   *graphQLAccount `json:"createdContract"`
+	Logs              []*graphQLLog   `json:"logs"`
+}
+
+// receipt rebuilds the *types.Receipt returned by
+// eth_getTransactionReceipt for r, which was
+// included in block hash at number.
+func (r *graphQLReceipt) receipt(hash common.Hash, number uint64) (*types.Receipt, error) {
+	// The status is only missing from
+	// receipts created before Byzantium.
+	if r.Status == nil {
+		return nil, fmt.Errorf("receipt of %s has no status", r.Hash.Hex())
+	}
+
+	receipt := &types.Receipt{
+		Type:              r.Type,
+		Status:            uint64(*r.Status),
+		CumulativeGasUsed: uint64(r.CumulativeGasUsed),
+		Logs:              make([]*types.Log, len(r.Logs)),
+		TxHash:            r.Hash,
+		GasUsed:           uint64(r.GasUsed),
+		BlockHash:         hash,
+		BlockNumber:       new(big.Int).SetUint64(number),
+		TransactionIndex:  r.Index,
+	}
+	if r.CreatedContract != nil {
+		receipt.ContractAddress = r.CreatedContract.Address
+	}
+
+	for i, log := range r.Logs {
+		receipt.Logs[i] = &types.Log{
+			Address:     log.Account.Address,
+			Topics:      log.Topics,
+			Data:        log.Data,
+			BlockNumber: number,
+			TxHash:      r.Hash,
+			TxIndex:     r.Index,
+			BlockHash:   hash,
+			Index:       log.Index,
+		}
+	}
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+
+	return receipt, nil
+}
+
+// graphQLBlock is a block queried for its
+// hash, number, and transaction count.
+type graphQLBlock struct {
+	Hash             common.Hash `json:"hash"`
+	Number           graphQLLong `json:"number"`
+	TransactionCount graphQLLong `json:"transactionCount"`
+}
+
+// receiptsRequest is a block whose
+// receipts are fetched over GraphQL.
+type receiptsRequest struct {
+	hash   common.Hash
+	number uint64
+	count  int
+}
+
+// graphQLQuery runs query and decodes its data into v.
+func (ec *Client) graphQLQuery(ctx context.Context, query string, v interface{}) error {
+	result, err := ec.g.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	return ec.graphQLQueryResult(result, v)
+}
+
+// graphQLQueryResult decodes the data of
+// the result of a GraphQL query into v.
+func (ec *Client) graphQLQueryResult(result string, v interface{}) error {
+	var response struct {
+		Errors []struct {
+			Message string   `json:"message"`
+			Path    []string `json:"path"`
+		} `json:"errors"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(result), &response); err != nil {
+		return err
+	}
+
+	if len(response.Errors) > 0 {
+		return errors.New(RosettaTypes.PrintStruct(response.Errors))
+	}
+
+	return json.Unmarshal(response.Data, v)
+}
+
+// graphQLReceipts returns the receipts of the transactions of
+// every requested block, keyed by block hash. The blocks are
+// batched into GraphQL documents of at most graphQLPageSize
+// transactions, each block under its own alias. Blocks with
+// more transactions are paged through: every document resumes
+// at the cursor (the block and transaction index) where the
+// previous document stopped.
+func (ec *Client) graphQLReceipts(
+	ctx context.Context,
+	requests []*receiptsRequest,
+) (map[common.Hash][]*types.Receipt, error) {
+	receipts := make(map[common.Hash][]*types.Receipt, len(requests))
+	var pending []*receiptsRequest
+	for _, request := range requests {
+		receipts[request.hash] = make([]*types.Receipt, request.count)
+		if request.count > 0 {
+			pending = append(pending, request)
+		}
+	}
+
+	block, index := 0, 0
+	for block < len(pending) {
+		var query strings.Builder
+		aliases := map[string]*receiptsRequest{}
+		size := 0
+
+		query.WriteString("{")
+		for block < len(pending) && size < graphQLPageSize {
+			request := pending[block]
+			alias := fmt.Sprintf("b%d", block)
+			aliases[alias] = request
+
+			fmt.Fprintf(&query, `%s: block(hash: "%s") {`, alias, request.hash.Hex())
+			for ; index < request.count && size < graphQLPageSize; index++ {
+				fmt.Fprintf(&query, " t%d: transactionAt(index: %d) { %s }", index, index, graphQLReceiptFields)
+				size++
+			}
+			query.WriteString(" } ")
+
+			if index == request.count {
+				block++
+				index = 0
+			}
+		}
+		query.WriteString("}")
+
+		var data map[string]map[string]*graphQLReceipt
+		if err := ec.graphQLQuery(ctx, query.String(), &data); err != nil {
+			return nil, fmt.Errorf("%w: unable to query receipts", err)
+		}
+
+		for alias, request := range aliases {
+			transactions, ok := data[alias]
+			if !ok || transactions == nil {
+				return nil, fmt.Errorf("%w: block %s not found", ethereum.NotFound, request.hash.Hex())
+			}
+
+			for _, transaction := range transactions {
+				if transaction == nil || int(transaction.Index) >= request.count {
+					return nil, fmt.Errorf("missing transaction in block %s", request.hash.Hex())
+				}
+
+				receipt, err := transaction.receipt(request.hash, request.number)
+				if err != nil {
+					return nil, err
+				}
+				receipts[request.hash][transaction.Index] = receipt
+			}
+		}
+	}
+
+	return receipts, nil
+}
+
+// receiptWindow holds the receipts of a window of
+// consecutive blocks, keyed by block hash, once done
+// is closed.
+type receiptWindow struct {
+	done     chan struct{}
+	receipts map[common.Hash][]*types.Receipt
+	err      error
+}
+
+// receiptPrefetcher fetches receipts over GraphQL for windows
+// of size consecutive blocks at once, so syncing a window of
+// blocks takes a few GraphQL requests instead of a receipts
+// request per block. Concurrent requests for blocks of the
+// same window share a single fetch.
+type receiptPrefetcher struct {
+	size int64
+
+	mutex   sync.Mutex
+	windows map[int64]*receiptWindow
+	order   []int64
+}
+
+// newReceiptPrefetcher returns a receiptPrefetcher fetching
+// windows of size blocks. If size is 0, nil is returned.
+func newReceiptPrefetcher(size int) *receiptPrefetcher {
+	if size == 0 {
+		return nil
+	}
+
+	return &receiptPrefetcher{
+		size:    int64(size),
+		windows: map[int64]*receiptWindow{},
+	}
+}
+
+// window returns the receipts of the window containing
+// the block at number, fetching them with fetch if they
+// were not fetched yet.
+func (p *receiptPrefetcher) window(
+	ctx context.Context,
+	number int64,
+	fetch func(context.Context, int64, int64) (map[common.Hash][]*types.Receipt, error),
+) (map[common.Hash][]*types.Receipt, error) {
+	start := number - number%p.size
+
+	p.mutex.Lock()
+	window, ok := p.windows[start]
+	if !ok {
+		window = &receiptWindow{done: make(chan struct{})}
+		p.windows[start] = window
+		p.order = append(p.order, start)
+		if len(p.order) > receiptWindowCacheSize {
+			delete(p.windows, p.order[0])
+			p.order = p.order[1:]
+		}
+	}
+	p.mutex.Unlock()
+
+	if !ok {
+		go p.fetch(ctx, start, window, fetch)
+	}
+
+	select {
+	case <-window.done:
+		return window.receipts, window.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetch fetches window with fetch. The window is shared by
+// every request for its blocks, so it is fetched with a context
+// detached from the request that created it: canceling that
+// request does not fail the others.
+func (p *receiptPrefetcher) fetch(
+	ctx context.Context,
+	start int64,
+	window *receiptWindow,
+	fetch func(context.Context, int64, int64) (map[common.Hash][]*types.Receipt, error),
+) {
+	ctx, cancel := Detach(ctx, receiptWindowTimeout)
+	defer cancel()
+
+	window.receipts, window.err = fetch(ctx, start, p.size)
+	close(window.done)
+
+	// Failed windows are fetched again
+	// by the next request.
+	if window.err != nil {
+		p.remove(start, window)
+	}
+}
+
+func (p *receiptPrefetcher) remove(start int64, window *receiptWindow) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.windows[start] != window {
+		return
+	}
+
+	delete(p.windows, start)
+	for i, existing := range p.order {
+		if existing == start {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// receiptWindow fetches the receipts of the size blocks
+// starting at start. Blocks that do not exist yet are
+// skipped.
+func (ec *Client) receiptWindow(
+	ctx context.Context,
+	start int64,
+	size int64,
+) (map[common.Hash][]*types.Receipt, error) {
+	var query strings.Builder
+	query.WriteString("{")
+	for i := int64(0); i < size; i++ {
+		fmt.Fprintf(&query, " b%d: block(number: %d) { hash number transactionCount }", i, start+i)
+	}
+	query.WriteString(" }")
+
+	var blocks map[string]*graphQLBlock
+	if err := ec.graphQLQuery(ctx, query.String(), &blocks); err != nil {
+		return nil, fmt.Errorf("%w: unable to query blocks %d to %d", err, start, start+size-1)
+	}
+
+	requests := make([]*receiptsRequest, 0, len(blocks))
+	for i := int64(0); i < size; i++ {
+		block := blocks[fmt.Sprintf("b%d", i)]
+		if block == nil {
+			continue
+		}
+
+		requests = append(requests, &receiptsRequest{
+			hash:   block.Hash,
+			number: uint64(block.Number),
+			count:  int(block.TransactionCount),
+		})
+	}
+
+	return ec.graphQLReceipts(ctx, requests)
+}
+
+// graphQLBlockReceipts returns the receipts of txs, the first
+// transactions of the block hash at number, from the window of
+// prefetched receipts containing the block. If the block is not
+// in the window (it was reorged out or did not exist yet when
+// the window was fetched), its receipts are fetched on their own.
+func (ec *Client) graphQLBlockReceipts(
+	ctx context.Context,
+	number int64,
+	hash common.Hash,
+	txs []rpcTransaction,
+) ([]*types.Receipt, error) {
+	window, err := ec.receipts.window(ctx, number, ec.receiptWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	receipts, ok := window[hash]
+	if !ok {
+		fetched, err := ec.graphQLReceipts(ctx, []*receiptsRequest{
+			{hash: hash, number: uint64(number), count: len(txs)},
+		})
+		if err != nil {
+			return nil, err
+		}
+		receipts = fetched[hash]
+	}
+
+	if len(receipts) < len(txs) {
+		return nil, fmt.Errorf("expected %d receipts for block %s but got %d", len(txs), hash.Hex(), len(receipts))
+	}
+
+	for i := range txs {
+		if receipts[i].TxHash != txs[i].tx.Hash() {
+			return nil, fmt.Errorf(
+				"expected receipt of %s but got %s",
+				txs[i].tx.Hash().Hex(),
+				receipts[i].TxHash.Hex(),
+			)
+		}
+	}
+
+	return receipts[:len(txs)], nil
+}
@@ -0,0 +1,236 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	// hardforkPollInterval is how often upcoming
+	// hardforks are checked against the head.
+	hardforkPollInterval = time.Minute
+
+	// HardforkWarningBlocks is how many blocks before the activation
+	// of a hardfork this binary does not support a warning is logged
+	// (about a day of 3 second blocks).
+	HardforkWarningBlocks = 28800
+)
+
+// SupportedHardforks are the hardforks (named as in the chain
+// config of the node, without the "Block" suffix) this binary
+// knows the rules of.
+var SupportedHardforks = map[string]bool{
+	"homestead":      true,
+	"daoFork":        true,
+	"eip150":         true,
+	"eip155":         true,
+	"eip158":         true,
+	"byzantium":      true,
+	"constantinople": true,
+	"petersburg":     true,
+	"istanbul":       true,
+	"muirGlacier":    true,
+	"berlin":         true,
+	"london":         true,
+	"arrowGlacier":   true,
+	"grayGlacier":    true,
+	"mergeNetsplit":  true,
+	"ramanujan":      true,
+	"niels":          true,
+	"hashPower":      true,
+}
+
+// Hardfork is the activation of a hardfork.
+type Hardfork struct {
+	Name  string `json:"name"`
+	Block uint64 `json:"block"`
+
+	// Supported is false if this binary predates
+	// the hardfork and must be upgraded before it
+	// activates.
+	Supported bool `json:"supported"`
+}
+
+// ScheduleHardforks returns the hardforks of forks (activation
+// heights keyed by name) sorted by activation height.
+func ScheduleHardforks(forks map[string]uint64) []*Hardfork {
+	schedule := make([]*Hardfork, 0, len(forks))
+	for name, block := range forks {
+		schedule = append(schedule, &Hardfork{
+			Name:      name,
+			Block:     block,
+			Supported: SupportedHardforks[name],
+		})
+	}
+
+	sort.Slice(schedule, func(i, j int) bool {
+		if schedule[i].Block != schedule[j].Block {
+			return schedule[i].Block < schedule[j].Block
+		}
+
+		return schedule[i].Name < schedule[j].Name
+	})
+
+	return schedule
+}
+
+// nodeHardforks returns the activation heights of the hardforks
+// in the chain config of the node, which may include hardforks
+// this binary predates. Hardforks activated by timestamp are
+// not included.
+func (ec *Client) nodeHardforks(ctx context.Context) (map[string]uint64, error) {
+	if ec.skipAdminCalls || atomic.LoadInt32(&ec.adminPeersUnavailable) == 1 {
+		return map[string]uint64{}, nil
+	}
+
+	var info struct {
+		Protocols struct {
+			Eth struct {
+				Config map[string]json.RawMessage `json:"config"`
+			} `json:"eth"`
+		} `json:"protocols"`
+	}
+	if err := ec.c.CallContext(ctx, &info, "admin_nodeInfo"); err != nil {
+		return nil, err
+	}
+
+	forks := map[string]uint64{}
+	for key, value := range info.Protocols.Eth.Config {
+		if !strings.HasSuffix(key, "Block") {
+			continue
+		}
+
+		var block *uint64
+		if err := json.Unmarshal(value, &block); err != nil || block == nil {
+			continue
+		}
+		forks[strings.TrimSuffix(key, "Block")] = *block
+	}
+
+	return forks, nil
+}
+
+// Hardforks returns the hardforks of the network: the configured
+// hardforks and the hardforks in the chain config of the node, as
+// of the last time MonitorHardforks read it (so the node is never
+// called). When both schedule a hardfork, the activation height of
+// the node is used as that is the one the node enforces.
+func (ec *Client) Hardforks(configured map[string]uint64) []*Hardfork {
+	ec.hardforksMutex.RLock()
+	forks := make(map[string]uint64, len(ec.nodeForks)+len(configured))
+	for name, block := range ec.nodeForks {
+		forks[name] = block
+	}
+	ec.hardforksMutex.RUnlock()
+
+	for name, block := range configured {
+		if _, ok := forks[name]; !ok {
+			forks[name] = block
+		}
+	}
+
+	return ScheduleHardforks(forks)
+}
+
+// refreshHardforks reads the hardforks in the
+// chain config of the node for Hardforks.
+func (ec *Client) refreshHardforks(ctx context.Context) error {
+	forks, err := ec.nodeHardforks(ctx)
+	if err != nil {
+		return err
+	}
+
+	ec.hardforksMutex.Lock()
+	defer ec.hardforksMutex.Unlock()
+	ec.nodeForks = forks
+
+	return nil
+}
+
+// MonitorHardforks reads the hardforks in the chain config of the
+// node every hardforkPollInterval (so upgrades of the node are
+// picked up) and logs a warning when a hardfork this binary does
+// not support is within HardforkWarningBlocks of its activation
+// and when it activates, until ctx is done.
+func (ec *Client) MonitorHardforks(ctx context.Context, configured map[string]uint64) error {
+	warned := map[string]bool{}
+	activated := map[string]bool{}
+	for {
+		if err := ec.checkHardforks(ctx, configured, warned, activated); err != nil && ctx.Err() == nil {
+			log.Printf("unable to check hardforks: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(hardforkPollInterval):
+		}
+	}
+}
+
+// checkHardforks logs each unsupported hardfork once
+// as it approaches (tracked in warned) and once it
+// is active (tracked in activated).
+func (ec *Client) checkHardforks(
+	ctx context.Context,
+	configured map[string]uint64,
+	warned map[string]bool,
+	activated map[string]bool,
+) error {
+	// The hardforks last read from the node (or
+	// only the configured ones) are still checked.
+	if err := ec.refreshHardforks(ctx); err != nil && ctx.Err() == nil {
+		log.Printf("unable to read hardforks of the node: %s", err.Error())
+	}
+	schedule := ec.Hardforks(configured)
+
+	var head hexutil.Uint64
+	if err := ec.c.CallContext(ctx, &head, "eth_blockNumber"); err != nil {
+		return err
+	}
+
+	for _, fork := range schedule {
+		switch {
+		case fork.Supported || activated[fork.Name]:
+		case uint64(head) >= fork.Block:
+			activated[fork.Name] = true
+			log.Printf(
+				"hardfork %s activated at block %d but this version of rosetta-core predates it: upgrade rosetta-core",
+				fork.Name,
+				fork.Block,
+			)
+		case !warned[fork.Name] && fork.Block-uint64(head) <= HardforkWarningBlocks:
+			warned[fork.Name] = true
+			log.Printf(
+				"hardfork %s activates at block %d (in %d blocks) but this version of rosetta-core predates it: "+
+					"upgrade rosetta-core before it activates",
+				fork.Name,
+				fork.Block,
+				fork.Block-uint64(head),
+			)
+		}
+	}
+
+	return nil
+}
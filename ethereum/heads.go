@@ -0,0 +1,173 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// headPollInterval is how often the head
+	// of the node is checked for changes.
+	headPollInterval = time.Second
+
+	// HeadWindow is the number of recent canonical blocks
+	// tracked to detect reorgs. If the head of the node
+	// moves further than HeadWindow blocks from the last
+	// tracked block without connecting to it, tracking
+	// restarts at the new head.
+	HeadWindow = 128
+)
+
+// headTracker follows the canonical chain of the node. chain
+// holds the most recent canonical blocks, in ascending order.
+type headTracker struct {
+	chain []*RosettaTypes.BlockIdentifier
+}
+
+// at returns the tracked block at index, or nil
+// if no block at index is tracked.
+func (t *headTracker) at(index int64) *RosettaTypes.BlockIdentifier {
+	if len(t.chain) == 0 {
+		return nil
+	}
+
+	offset := index - t.chain[0].Index
+	if offset < 0 || offset >= int64(len(t.chain)) {
+		return nil
+	}
+
+	return t.chain[offset]
+}
+
+// update moves the tracker to head and returns the resulting
+// events: a REMOVED event for every tracked block that is no
+// longer canonical (from the highest), followed by an ADDED
+// event for every new canonical block (from the lowest). The
+// headers of blocks between the tracked chain and head are
+// fetched with header.
+func (t *headTracker) update(
+	ctx context.Context,
+	head *types.Header,
+	header func(context.Context, string) (*types.Header, error),
+) ([]*RosettaTypes.BlockEvent, error) {
+	if len(t.chain) > 0 && t.chain[len(t.chain)-1].Hash == head.Hash().Hex() {
+		return nil, nil
+	}
+
+	// Walk back from head until its parent is tracked.
+	added := []*types.Header{head}
+	connected := len(t.chain) == 0
+	for !connected {
+		oldest := added[0]
+		if oldest.Number.Sign() == 0 {
+			break
+		}
+
+		parentIndex := oldest.Number.Int64() - 1
+		if tracked := t.at(parentIndex); tracked != nil && tracked.Hash == oldest.ParentHash.Hex() {
+			connected = true
+			break
+		}
+
+		if parentIndex < t.chain[0].Index || len(added) >= HeadWindow {
+			break
+		}
+
+		parent, err := header(ctx, oldest.ParentHash.Hex())
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to get header %s", err, oldest.ParentHash.Hex())
+		}
+		added = append([]*types.Header{parent}, added...)
+	}
+
+	var events []*RosettaTypes.BlockEvent
+	if !connected {
+		log.Printf(
+			"head moved to %d without connecting to the last %d blocks, restarting at head",
+			head.Number.Int64(),
+			len(t.chain),
+		)
+		t.chain = nil
+		added = []*types.Header{head}
+	}
+
+	// Every tracked block above the parent of the
+	// oldest added block was reorged out.
+	for len(t.chain) > 0 && t.chain[len(t.chain)-1].Index >= added[0].Number.Int64() {
+		removed := t.chain[len(t.chain)-1]
+		t.chain = t.chain[:len(t.chain)-1]
+		events = append(events, &RosettaTypes.BlockEvent{
+			BlockIdentifier: removed,
+			Type:            RosettaTypes.REMOVED,
+		})
+	}
+
+	for _, header := range added {
+		blockIdentifier := &RosettaTypes.BlockIdentifier{
+			Index: header.Number.Int64(),
+			Hash:  header.Hash().Hex(),
+		}
+		t.chain = append(t.chain, blockIdentifier)
+		events = append(events, &RosettaTypes.BlockEvent{
+			BlockIdentifier: blockIdentifier,
+			Type:            RosettaTypes.ADDED,
+		})
+	}
+
+	if len(t.chain) > HeadWindow {
+		t.chain = t.chain[len(t.chain)-HeadWindow:]
+	}
+
+	return events, nil
+}
+
+// MonitorHeads follows the head of the node until ctx is
+// done, calling publish with every block added to or removed
+// from the canonical chain (see headTracker.update). Events
+// do not have a Sequence. If publish is nil, it returns
+// immediately.
+func (ec *Client) MonitorHeads(ctx context.Context, publish func(*RosettaTypes.BlockEvent)) error {
+	if publish == nil {
+		return nil
+	}
+
+	tracker := &headTracker{}
+	for {
+		head, err := ec.blockHeaderByNumber(ctx, nil)
+		if err == nil {
+			var events []*RosettaTypes.BlockEvent
+			events, err = tracker.update(ctx, head, ec.blockHeaderByHash)
+			for _, event := range events {
+				publish(event)
+			}
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Printf("unable to follow head of node: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(headPollInterval):
+		}
+	}
+}
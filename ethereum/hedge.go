@@ -0,0 +1,246 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/metrics"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	// maxHedgeLag is the number of blocks an archive node may
+	// have been behind the node at the last check to receive
+	// hedged reads, so that hedged reads are served from
+	// (nearly) the same tip.
+	maxHedgeLag = 5
+
+	archiveHedgedMetric   = "archive/hedged"
+	archiveHedgeWinMetric = "archive/hedge_wins"
+)
+
+// errStaleTip is returned for a tip header from an archive
+// node below the last tip header served.
+var errStaleTip = errors.New("archive node returned a stale tip")
+
+// graphQLTipBlock matches the GraphQL queries made by
+// Balance for the block at the tip.
+var graphQLTipBlock = regexp.MustCompile(`block\(\s*\)`)
+
+// tipRead returns true if a call is a latency-critical
+// read relative to the tip: the header of the tip block
+// or a balance at the tip.
+func tipRead(method string, args []interface{}) bool {
+	switch method {
+	case "eth_getBlockByNumber":
+		return len(args) == 2 && args[0] == "latest" && args[1] == false
+	case "eth_getBalance":
+		return len(args) == 2 && args[1] == "latest"
+	default:
+		return false
+	}
+}
+
+// tipNumber returns the number of the header in the result
+// of a call, if the call read the tip header.
+func tipNumber(method string, raw json.RawMessage) (int64, bool) {
+	if method != "eth_getBlockByNumber" {
+		return 0, false
+	}
+
+	var header struct {
+		Number hexutil.Uint64 `json:"number"`
+	}
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return 0, false
+	}
+
+	return int64(header.Number), true
+}
+
+// serveTip records that the tip header at
+// number was served, if it is the highest.
+func (p *archivePool) serveTip(number int64) {
+	for {
+		served := atomic.LoadInt64(&p.servedTip)
+		if number <= served || atomic.CompareAndSwapInt64(&p.servedTip, served, number) {
+			return
+		}
+	}
+}
+
+// hedgeResult is the result of a
+// single attempt of a hedged read.
+type hedgeResult struct {
+	archive *archive // nil for the node
+	value   interface{}
+	err     error
+}
+
+// pickHedge returns the next healthy archive node that was
+// within maxHedgeLag blocks of the node at the last check,
+// or nil if there is none.
+func (p *archivePool) pickHedge() *archive {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	var eligible []*archive
+	for _, a := range p.archives {
+		if a.score < minArchiveScore || a.checkpoint < 0 || a.checkpoint < p.head-maxHedgeLag {
+			continue
+		}
+
+		eligible = append(eligible, a)
+	}
+	if len(eligible) == 0 {
+		return nil
+	}
+
+	return eligible[atomic.AddUint64(&p.next, 1)%uint64(len(eligible))]
+}
+
+// hedge makes a read with the node (read is called with a nil
+// archive) and, if the node has not responded after hedgeDelay,
+// with an archive node as well. The first successful result is
+// returned and the other attempt is canceled. If the node fails
+// before the delay, its error is returned without hedging.
+func (p *archivePool) hedge(
+	ctx context.Context,
+	read func(context.Context, *archive) (interface{}, error),
+) (interface{}, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan *hedgeResult, 2) // nolint:gomnd
+	attempt := func(a *archive) {
+		value, err := read(ctx, a)
+		results <- &hedgeResult{archive: a, value: value, err: err}
+	}
+
+	go attempt(nil)
+	pending := 1
+
+	timer := time.NewTimer(p.hedgeDelay)
+	defer timer.Stop()
+
+	var err error
+	for {
+		select {
+		case <-timer.C:
+			a := p.pickHedge()
+			if a == nil {
+				continue
+			}
+
+			metrics.Counter(archiveHedgedMetric).Inc(1)
+			go attempt(a)
+			pending++
+		case result := <-results:
+			pending--
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			if result.archive != nil {
+				p.record(result.archive, result.err)
+			}
+			if result.err == nil {
+				if result.archive != nil {
+					metrics.Counter(archiveHedgeWinMetric).Inc(1)
+				}
+				return result.value, nil
+			}
+
+			// The error of the node is preferred.
+			if result.archive == nil || err == nil {
+				err = result.err
+			}
+			if pending == 0 {
+				return nil, err
+			}
+		}
+	}
+}
+
+// hedgeCall makes a hedged JSON-RPC read (see hedge).
+func (p *archivePool) hedgeCall(
+	ctx context.Context,
+	result interface{},
+	method string,
+	args ...interface{},
+) error {
+	value, err := p.hedge(ctx, func(ctx context.Context, a *archive) (interface{}, error) {
+		var raw json.RawMessage
+		if a == nil {
+			err := p.node.CallContext(ctx, &raw, method, args...)
+			return raw, err
+		}
+
+		if err := a.c.CallContext(ctx, &raw, method, args...); err != nil {
+			return nil, err
+		}
+		if isNull(raw) {
+			return nil, errArchiveMiss
+		}
+
+		// The tip served must never move backwards,
+		// even if the archive node is slightly behind.
+		if number, ok := tipNumber(method, raw); ok && number < atomic.LoadInt64(&p.servedTip) {
+			return nil, errStaleTip
+		}
+
+		return raw, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if number, ok := tipNumber(method, value.(json.RawMessage)); ok {
+		p.serveTip(number)
+	}
+
+	return json.Unmarshal(value.(json.RawMessage), result)
+}
+
+// hedgeQuery makes a hedged GraphQL read (see hedge).
+func (p *archivePool) hedgeQuery(ctx context.Context, input string) (string, error) {
+	value, err := p.hedge(ctx, func(ctx context.Context, a *archive) (interface{}, error) {
+		if a == nil {
+			return p.nodeGraphQL.Query(ctx, input)
+		}
+
+		result, err := a.g.Query(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkGraphQLBlock(result); err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return value.(string), nil
+}
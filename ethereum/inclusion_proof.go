@@ -0,0 +1,216 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// InclusionProofMethod is the /call method used to prove
+// that a transaction and its receipt are in a block.
+const InclusionProofMethod = "inclusion_proof"
+
+// InclusionProofInput is the input to the
+// call method "inclusion_proof".
+type InclusionProofInput struct {
+	TxHash string `json:"tx_hash"`
+}
+
+// InclusionProof is the output of the call method
+// "inclusion_proof": the Merkle proofs of a transaction
+// and its receipt against the transactionsRoot and the
+// receiptsRoot of the header of their block.
+//
+// Header is the hex-encoded RLP encoding of the header,
+// whose Keccak-256 hash is the block hash. Transaction
+// and Receipt are the hex-encoded binary encodings stored
+// in the tries at Key (the RLP encoding of the index of
+// the transaction). The proofs are the hex-encoded trie
+// nodes on the path to Key, from the root, and can be
+// verified with trie.VerifyProof.
+type InclusionProof struct {
+	TransactionHash  string                        `json:"transaction_hash"`
+	BlockIdentifier  *RosettaTypes.BlockIdentifier `json:"block_identifier"`
+	TransactionIndex uint                          `json:"transaction_index"`
+	Header           string                        `json:"header"`
+	Key              string                        `json:"key"`
+
+	TransactionsRoot string   `json:"transactions_root"`
+	Transaction      string   `json:"transaction"`
+	TransactionProof []string `json:"transaction_proof"`
+
+	ReceiptsRoot string   `json:"receipts_root"`
+	Receipt      string   `json:"receipt"`
+	ReceiptProof []string `json:"receipt_proof"`
+}
+
+// inclusionProof returns the inclusion proof of
+// the requested transaction.
+func (ec *Client) inclusionProof(
+	ctx context.Context,
+	params map[string]interface{},
+) (map[string]interface{}, error) {
+	var input InclusionProofInput
+	if err := RosettaTypes.UnmarshalMap(params, &input); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCallParametersInvalid, err.Error())
+	}
+
+	hash, err := hexutil.Decode(input.TxHash)
+	if err != nil || len(hash) != common.HashLength {
+		return nil, fmt.Errorf("%w: %s is not a transaction hash", ErrCallParametersInvalid, input.TxHash)
+	}
+	txHash := common.BytesToHash(hash)
+
+	receipt, err := ec.transactionReceipt(ctx, txHash)
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, fmt.Errorf("%w: transaction %s is not mined", ErrCallParametersInvalid, txHash.Hex())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get receipt", err)
+	}
+
+	var raw json.RawMessage
+	if err := ec.c.CallContext(ctx, &raw, "eth_getBlockByHash", receipt.BlockHash, true); err != nil {
+		return nil, fmt.Errorf("%w: block fetch failed", err)
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, fmt.Errorf("%w: block %s", ErrBlockOrphaned, receipt.BlockHash.Hex())
+	}
+
+	var head types.Header
+	var body rpcBlock
+	if err := json.Unmarshal(raw, &head); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, err
+	}
+
+	receipts, err := ec.getBlockReceipts(ctx, head.Number.Int64(), body.Hash, body.Transactions)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not get receipts for %x", err, body.Hash[:])
+	}
+
+	txs := make(types.Transactions, len(body.Transactions))
+	for i, tx := range body.Transactions {
+		txs[i] = tx.tx
+	}
+	if receipt.TransactionIndex >= uint(len(txs)) || txs[receipt.TransactionIndex].Hash() != txHash {
+		return nil, fmt.Errorf(
+			"%w: transaction %s is not at index %d of block %s",
+			ErrBlockOrphaned,
+			txHash.Hex(),
+			receipt.TransactionIndex,
+			body.Hash.Hex(),
+		)
+	}
+
+	header, err := rlp.EncodeToBytes(&head)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to encode header", err)
+	}
+
+	key, err := rlp.EncodeToBytes(receipt.TransactionIndex)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to encode key", err)
+	}
+
+	txsRoot, tx, txProof, err := proveInclusion(txs, key, head.TxHash)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to prove transaction", err)
+	}
+
+	receiptsRoot, encodedReceipt, receiptProof, err := proveInclusion(
+		types.Receipts(receipts),
+		key,
+		head.ReceiptHash,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to prove receipt", err)
+	}
+
+	return RosettaTypes.MarshalMap(&InclusionProof{
+		TransactionHash: txHash.Hex(),
+		BlockIdentifier: &RosettaTypes.BlockIdentifier{
+			Index: head.Number.Int64(),
+			Hash:  body.Hash.Hex(),
+		},
+		TransactionIndex: receipt.TransactionIndex,
+		Header:           hexutil.Encode(header),
+		Key:              hexutil.Encode(key),
+		TransactionsRoot: txsRoot.Hex(),
+		Transaction:      hexutil.Encode(tx),
+		TransactionProof: txProof,
+		ReceiptsRoot:     receiptsRoot.Hex(),
+		Receipt:          hexutil.Encode(encodedReceipt),
+		ReceiptProof:     receiptProof,
+	})
+}
+
+// proveInclusion builds the trie of list, asserts its root
+// is root, and returns the value at key and its proof.
+func proveInclusion(
+	list types.DerivableList,
+	key []byte,
+	root common.Hash,
+) (common.Hash, []byte, []string, error) {
+	tr := trie.NewEmpty(trie.NewDatabase(memorydb.New()))
+	if derived := types.DeriveSha(list, tr); derived != root {
+		return common.Hash{}, nil, nil, fmt.Errorf(
+			"derived root %s but header has %s",
+			derived.Hex(),
+			root.Hex(),
+		)
+	}
+
+	var proof proofNodes
+	if err := tr.Prove(key, 0, &proof); err != nil {
+		return common.Hash{}, nil, nil, err
+	}
+
+	value, err := tr.TryGet(key)
+	if err != nil {
+		return common.Hash{}, nil, nil, err
+	}
+
+	return root, value, proof, nil
+}
+
+// proofNodes collects the nodes of a Merkle proof
+// in the order they are written by trie.Prove.
+type proofNodes []string
+
+// Put implements ethdb.KeyValueWriter.
+func (p *proofNodes) Put(key []byte, value []byte) error {
+	*p = append(*p, hexutil.Encode(value))
+	return nil
+}
+
+// Delete implements ethdb.KeyValueWriter.
+func (p *proofNodes) Delete(key []byte) error {
+	return errors.New("proof nodes cannot be deleted")
+}
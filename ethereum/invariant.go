@@ -0,0 +1,121 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/coinbase/rosetta-ethereum/amount"
+	"github.com/coinbase/rosetta-ethereum/metrics"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+)
+
+const invariantMetric = "block/invariant_violations"
+
+// operationsSum returns the sum of the amounts of all successful
+// native currency operations in ops. The amounts of DESTRUCT
+// operations are returned separately because they remove currency
+// from circulation, like burned fees.
+func operationsSum(ops []*RosettaTypes.Operation) (*big.Int, *big.Int) {
+	sum := new(big.Int)
+	destroyed := new(big.Int)
+	for _, op := range ops {
+		if op.Amount == nil ||
+			op.Status == nil ||
+			*op.Status != SuccessStatus ||
+			RosettaTypes.Hash(op.Amount.Currency) != RosettaTypes.Hash(Currency) {
+			continue
+		}
+
+		value, err := amount.Parse(op.Amount.Value)
+		if err != nil {
+			continue
+		}
+
+		if op.Type == DestructOpType {
+			destroyed.Sub(destroyed, value)
+			continue
+		}
+
+		sum.Add(sum, value)
+	}
+
+	return sum, destroyed
+}
+
+// checkInvariant verifies that the operations of a block are
+// double-entry: the sum of all operation amounts must equal the
+// issuance of the block (its reward transaction) minus the currency
+// burned by its transactions (burned fees and destroyed balances).
+// If it does not, every transaction that does not balance is
+// included in the returned error.
+func checkInvariant(
+	blockIdentifier *RosettaTypes.BlockIdentifier,
+	txs []*RosettaTypes.Transaction,
+	loadedTxs []*loadedTransaction,
+) error {
+	issuance, _ := operationsSum(txs[0].Operations)
+	expected := new(big.Int).Set(issuance)
+	total := new(big.Int).Set(issuance)
+	burned := new(big.Int)
+
+	var unbalanced []string
+	for i, tx := range txs[1:] {
+		// Destroyed balances are only known from the DESTRUCT
+		// operations, so all other operations must balance with
+		// the burned fee.
+		sum, destroyed := operationsSum(tx.Operations)
+		feeBurned := new(big.Int)
+		if loadedTxs[i].FeeBurned != nil {
+			feeBurned.Set(loadedTxs[i].FeeBurned)
+		}
+
+		total.Add(total, sum).Sub(total, destroyed)
+		burned.Add(burned, feeBurned).Add(burned, destroyed)
+
+		if imbalance := new(big.Int).Add(sum, feeBurned); imbalance.Sign() != 0 {
+			unbalanced = append(unbalanced, fmt.Sprintf(
+				"%s: %s",
+				tx.TransactionIdentifier.Hash,
+				imbalance.String(),
+			))
+		}
+	}
+	expected.Sub(expected, burned)
+
+	if total.Cmp(expected) == 0 && len(unbalanced) == 0 {
+		return nil
+	}
+
+	metrics.Counter(invariantMetric).Inc(1)
+	err := fmt.Errorf(
+		"%w: block %d (%s) operations sum to %s but expected %s (issuance %s, burned %s); unbalanced transactions: [%s]",
+		ErrInvariantViolated,
+		blockIdentifier.Index,
+		blockIdentifier.Hash,
+		total.String(),
+		expected.String(),
+		issuance.String(),
+		burned.String(),
+		strings.Join(unbalanced, ", "),
+	)
+	log.Println(err.Error())
+
+	return err
+}
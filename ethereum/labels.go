@@ -0,0 +1,114 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// LabelMetadataKey is the account identifier metadata
+	// key populated with the label of a labeled address.
+	LabelMetadataKey = "label"
+
+	// SystemContractCategory is the category
+	// of the labels of the system contracts.
+	SystemContractCategory = "system_contract"
+)
+
+// Label describes the owner of an address
+// (i.e. an exchange or a bridge).
+type Label struct {
+	Name     string `json:"name"`
+	Category string `json:"category,omitempty"`
+}
+
+// LoadLabels loads the labels in the JSON file at path, which maps
+// addresses to labels:
+//
+//	{"0x...": {"name": "Exchange A", "category": "exchange"}}
+func LoadLabels(path string) (map[common.Address]*Label, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not load labels file %s", err, path)
+	}
+
+	var file map[string]*Label
+	if err := json.Unmarshal(contents, &file); err != nil {
+		return nil, fmt.Errorf("%w: could not parse labels file %s", err, path)
+	}
+
+	labels := make(map[common.Address]*Label, len(file))
+	for address, label := range file {
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("%s is not a valid address", address)
+		}
+		if label == nil || len(label.Name) == 0 {
+			return nil, fmt.Errorf("label of %s has no name", address)
+		}
+
+		labels[common.HexToAddress(address)] = label
+	}
+
+	return labels, nil
+}
+
+// newLabels returns the labels of the system contracts
+// merged with labels, which take precedence. If labels
+// is nil, nil is returned (labeling is disabled).
+func newLabels(labels map[common.Address]*Label) map[common.Address]*Label {
+	if labels == nil {
+		return nil
+	}
+
+	merged := map[common.Address]*Label{}
+	for name, address := range SystemContracts() {
+		merged[address] = &Label{Name: name, Category: SystemContractCategory}
+	}
+	for address, label := range labels {
+		merged[address] = label
+	}
+
+	return merged
+}
+
+// labelAccounts adds the label of every
+// labeled account in ops to its metadata.
+func (ec *Client) labelAccounts(ops []*RosettaTypes.Operation) {
+	if ec.labels == nil {
+		return
+	}
+
+	for _, op := range ops {
+		if op.Account == nil || !common.IsHexAddress(op.Account.Address) {
+			continue
+		}
+
+		label, ok := ec.labels[common.HexToAddress(op.Account.Address)]
+		if !ok {
+			continue
+		}
+
+		if op.Account.Metadata == nil {
+			op.Account.Metadata = map[string]interface{}{}
+		}
+		op.Account.Metadata[LabelMetadataKey] = label
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/metrics"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	// DefaultMaxLag is the number of blocks the node can fall
+	// behind the reference nodes before it is considered degraded.
+	DefaultMaxLag = int64(10) // nolint:gomnd
+
+	// lagPollInterval is how often the heads of the
+	// node and the reference nodes are compared.
+	lagPollInterval = 15 * time.Second
+
+	// laggingStage is the SyncStatus stage reported
+	// when the node is degraded.
+	laggingStage = "lagging"
+
+	lagMetric = "node/lag"
+)
+
+// LagConfig configures the detection of a node
+// falling behind a set of reference nodes.
+type LagConfig struct {
+	// References are the URLs of the JSON-RPC endpoints
+	// (other nodes or public endpoints) the node is
+	// compared against.
+	References []string
+
+	// MaxLag is the number of blocks the node can be
+	// behind the highest reference before it is degraded.
+	MaxLag int64
+
+	// RejectStaleReads causes balance queries relative to
+	// the tip (without a block identifier) to fail with
+	// ErrNodeLagging while the node is degraded.
+	RejectStaleReads bool
+}
+
+// lagMonitor compares the head of the node
+// with the heads of reference nodes.
+type lagMonitor struct {
+	config     *LagConfig
+	references []JSONRPC
+
+	mutex         sync.RWMutex
+	referenceHead int64
+	degraded      bool
+}
+
+func newLagMonitor(config *LagConfig, proxy *url.URL) (*lagMonitor, error) {
+	references := make([]JSONRPC, len(config.References))
+	for i, reference := range config.References {
+		c, err := dialRPC(reference, proxy)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to dial reference node %d", err, i)
+		}
+
+		references[i] = c
+	}
+
+	return &lagMonitor{
+		config:     config,
+		references: references,
+	}, nil
+}
+
+// status returns the highest reference head and whether
+// the node is degraded.
+func (m *lagMonitor) status() (int64, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.referenceHead, m.degraded
+}
+
+// update compares head with the highest head reported by the
+// references. If no reference can be reached, the node is not
+// considered degraded.
+func (m *lagMonitor) update(ctx context.Context, head int64) {
+	referenceHead := int64(-1)
+	for i, reference := range m.references {
+		var number hexutil.Uint64
+		if err := reference.CallContext(ctx, &number, "eth_blockNumber"); err != nil {
+			// Reference URLs may contain credentials,
+			// so only the index is logged.
+			log.Printf("unable to get head of reference node %d: %s", i, err.Error())
+			continue
+		}
+
+		if int64(number) > referenceHead {
+			referenceHead = int64(number)
+		}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if referenceHead < 0 {
+		m.referenceHead = 0
+		m.degraded = false
+		return
+	}
+
+	lag := referenceHead - head
+	metrics.Gauge(lagMetric).Update(lag)
+
+	m.referenceHead = referenceHead
+	m.degraded = lag > m.config.MaxLag
+}
+
+func (m *lagMonitor) close() {
+	for _, reference := range m.references {
+		reference.Close()
+	}
+}
+
+// MonitorLag compares the head of the node with the heads of the
+// reference nodes until ctx is done. If lag detection is not
+// configured, it returns immediately.
+func (ec *Client) MonitorLag(ctx context.Context) error {
+	if ec.lag == nil {
+		return nil
+	}
+
+	for {
+		var number hexutil.Uint64
+		if err := ec.c.CallContext(ctx, &number, "eth_blockNumber"); err != nil {
+			if ctx.Err() == nil {
+				log.Printf("unable to get head of node: %s", err.Error())
+			}
+		} else {
+			ec.lag.update(ctx, int64(number))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(lagPollInterval):
+		}
+	}
+}
+
+// lagSyncStatus returns the *RosettaTypes.SyncStatus to report
+// for a node at head. If the node is not degraded, syncStatus
+// is returned unchanged.
+func (ec *Client) lagSyncStatus(
+	head int64,
+	syncStatus *RosettaTypes.SyncStatus,
+) *RosettaTypes.SyncStatus {
+	if ec.lag == nil {
+		return syncStatus
+	}
+
+	referenceHead, degraded := ec.lag.status()
+	if !degraded {
+		return syncStatus
+	}
+
+	return &RosettaTypes.SyncStatus{
+		CurrentIndex: RosettaTypes.Int64(head),
+		TargetIndex:  RosettaTypes.Int64(referenceHead),
+		Stage:        RosettaTypes.String(laggingStage),
+		Synced:       RosettaTypes.Bool(false),
+	}
+}
+
+// checkStaleRead returns ErrNodeLagging if reads relative to
+// the tip are rejected and the node is degraded.
+func (ec *Client) checkStaleRead() error {
+	if ec.lag == nil || !ec.lag.config.RejectStaleReads {
+		return nil
+	}
+
+	referenceHead, degraded := ec.lag.status()
+	if degraded {
+		return fmt.Errorf("%w: reference head is %d", ErrNodeLagging, referenceHead)
+	}
+
+	return nil
+}
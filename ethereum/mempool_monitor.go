@@ -0,0 +1,379 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/metrics"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// DefaultNonceStuckAfter is how long the nonce of a watched
+	// account can stay the same while it has transactions in the
+	// mempool before it is considered stuck.
+	DefaultNonceStuckAfter = 5 * time.Minute
+
+	// mempoolPollInterval is how often the
+	// mempool of the node is sampled.
+	mempoolPollInterval = 15 * time.Second
+
+	// nonceGapMetric, nonceStuckMetric, and nonceMempoolMetric
+	// are suffixed with the address of each watched account.
+	nonceGapMetric     = "nonce/gap/"
+	nonceStuckMetric   = "nonce/stuck/"
+	nonceMempoolMetric = "nonce/mempool/"
+	nonceAlertsMetric  = "nonce/alerts"
+
+	txPoolPendingMetric       = "txpool/pending"
+	txPoolQueuedMetric        = "txpool/queued"
+	txPoolOldestPendingMetric = "txpool/oldest_pending_age"
+	txPoolAlertsMetric        = "txpool/alerts"
+
+	// txPoolStuckMetric is suffixed with
+	// the address of each watched account.
+	txPoolStuckMetric = "txpool/stuck/"
+)
+
+// MempoolMonitorConfig configures the sampling of the
+// mempool of the node (see MonitorMempool).
+type MempoolMonitorConfig struct {
+	// Addresses are the accounts (i.e. the hot wallets of a
+	// withdrawal system) whose nonce gaps and stuck nonces are
+	// detected.
+	Addresses []common.Address
+
+	// StuckAfter is how long the nonce of an account can stay
+	// the same while it has transactions in the mempool before
+	// it is considered stuck. With Metrics, it is also how long
+	// a transaction of an account can be pending before it is
+	// alerted on.
+	StuckAfter time.Duration
+
+	// Metrics reports the size of the mempool and the
+	// age of its oldest pending transaction.
+	Metrics bool
+}
+
+// mempoolContent is the pending or queued part of
+// txpool_content (keyed by account, then by decimal nonce).
+type mempoolContent map[string]map[string]struct {
+	Hash common.Hash `json:"hash"`
+}
+
+// mempoolEntry identifies a transaction in the mempool. A
+// replacement has a different hash, so it is tracked as a
+// new transaction.
+type mempoolEntry struct {
+	address common.Address
+	nonce   uint64
+	hash    common.Hash
+}
+
+// nonceState is the nonce progression of a watched account.
+type nonceState struct {
+	// nonce is the nonce of the account at the head and
+	// since is when it was first seen.
+	nonce uint64
+	since time.Time
+
+	gap   bool
+	stuck bool
+}
+
+// mempoolState is what is kept between samples. The mempool does
+// not report transaction ages, so seen is when every pending
+// transaction was first sampled.
+type mempoolState struct {
+	nonces map[common.Address]*nonceState
+	seen   map[mempoolEntry]time.Time
+	stuck  map[mempoolEntry]bool
+}
+
+func newMempoolState() *mempoolState {
+	return &mempoolState{
+		nonces: map[common.Address]*nonceState{},
+		seen:   map[mempoolEntry]time.Time{},
+		stuck:  map[mempoolEntry]bool{},
+	}
+}
+
+// missingNonces returns the number of nonces missing before
+// the queued transactions of an account with nonce at the head
+// can be included. Pending transactions are executable, so they
+// fill the nonces after nonce in order.
+func missingNonces(nonce uint64, pending []uint64, queued []uint64) uint64 {
+	next := nonce
+	sort.Slice(pending, func(i, j int) bool { return pending[i] < pending[j] })
+	for _, n := range pending {
+		if n == next {
+			next++
+		}
+	}
+
+	lowest := uint64(0)
+	found := false
+	for _, n := range queued {
+		if n >= next && (!found || n < lowest) {
+			lowest = n
+			found = true
+		}
+	}
+	if !found {
+		return 0
+	}
+
+	return lowest - next
+}
+
+// entries returns the transactions in content.
+func (content mempoolContent) entries() []mempoolEntry {
+	entries := []mempoolEntry{}
+	for account, txs := range content {
+		address := common.HexToAddress(account)
+		for nonce, tx := range txs {
+			n, err := strconv.ParseUint(nonce, 10, 64)
+			if err != nil {
+				continue
+			}
+			entries = append(entries, mempoolEntry{address: address, nonce: n, hash: tx.Hash})
+		}
+	}
+
+	return entries
+}
+
+// mempoolNonces returns the nonces of the transactions of each
+// account in entries.
+func mempoolNonces(entries []mempoolEntry) map[common.Address][]uint64 {
+	nonces := map[common.Address][]uint64{}
+	for _, entry := range entries {
+		nonces[entry.address] = append(nonces[entry.address], entry.nonce)
+	}
+
+	return nonces
+}
+
+// checkMempool samples txpool_content once and uses the sample
+// to report the mempool (if config.Metrics) and to check the
+// nonces of the watched accounts.
+func (ec *Client) checkMempool(
+	ctx context.Context,
+	config *MempoolMonitorConfig,
+	state *mempoolState,
+	now time.Time,
+) error {
+	var content struct {
+		Pending mempoolContent `json:"pending"`
+		Queued  mempoolContent `json:"queued"`
+	}
+	if err := ec.c.CallContext(ctx, &content, "txpool_content"); err != nil {
+		return err
+	}
+	pending := content.Pending.entries()
+	queued := content.Queued.entries()
+
+	if config.Metrics {
+		reportMempool(config, state, pending, len(queued), now)
+	}
+
+	if len(config.Addresses) == 0 {
+		return nil
+	}
+
+	return ec.checkNonces(ctx, config, state.nonces, mempoolNonces(pending), mempoolNonces(queued), now)
+}
+
+// reportMempool reports the size of the mempool and the age of
+// its oldest pending transaction, and logs an alert for every
+// transaction of a watched account that is pending for longer
+// than StuckAfter. Ages are measured from the first sample a
+// transaction is in.
+func reportMempool(
+	config *MempoolMonitorConfig,
+	state *mempoolState,
+	pending []mempoolEntry,
+	queued int,
+	now time.Time,
+) {
+	metrics.Gauge(txPoolPendingMetric).Update(int64(len(pending)))
+	metrics.Gauge(txPoolQueuedMetric).Update(int64(queued))
+
+	seen := make(map[mempoolEntry]time.Time, len(pending))
+	oldest := now
+	for _, entry := range pending {
+		since, ok := state.seen[entry]
+		if !ok {
+			since = now
+		}
+		seen[entry] = since
+
+		if since.Before(oldest) {
+			oldest = since
+		}
+	}
+	state.seen = seen
+	metrics.Gauge(txPoolOldestPendingMetric).Update(int64(now.Sub(oldest).Seconds()))
+
+	watched := make(map[common.Address]int64, len(config.Addresses))
+	for _, address := range config.Addresses {
+		watched[address] = 0
+	}
+
+	stuck := map[mempoolEntry]bool{}
+	for entry, since := range seen {
+		if _, ok := watched[entry.address]; !ok || now.Sub(since) < config.StuckAfter {
+			continue
+		}
+
+		watched[entry.address]++
+		stuck[entry] = true
+		if !state.stuck[entry] {
+			metrics.Counter(txPoolAlertsMetric).Inc(1)
+			log.Printf(
+				"transaction %d of %s has been pending for %s: %s",
+				entry.nonce,
+				entry.address.Hex(),
+				now.Sub(since),
+				entry.hash.Hex(),
+			)
+		}
+	}
+	state.stuck = stuck
+
+	for address, count := range watched {
+		metrics.Gauge(txPoolStuckMetric + address.Hex()).Update(count)
+	}
+}
+
+// checkNonces compares the nonces of the watched accounts at the
+// head with their pending and queued nonces, updates states, and
+// logs an alert when a gap or a stuck nonce is detected or resolved.
+func (ec *Client) checkNonces(
+	ctx context.Context,
+	config *MempoolMonitorConfig,
+	states map[common.Address]*nonceState,
+	pending map[common.Address][]uint64,
+	queued map[common.Address][]uint64,
+	now time.Time,
+) error {
+	nonces := make([]hexutil.Uint64, len(config.Addresses))
+	batch := make([]rpc.BatchElem, len(config.Addresses))
+	for i, address := range config.Addresses {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getTransactionCount",
+			Args:   []interface{}{address, "latest"},
+			Result: &nonces[i],
+		}
+	}
+	if err := ec.c.BatchCallContext(ctx, batch); err != nil {
+		return err
+	}
+
+	for i, address := range config.Addresses {
+		if batch[i].Error != nil {
+			log.Printf("unable to get nonce of %s: %s", address.Hex(), batch[i].Error.Error())
+			continue
+		}
+
+		nonce := uint64(nonces[i])
+		state, ok := states[address]
+		if !ok || state.nonce != nonce {
+			state = &nonceState{nonce: nonce, since: now}
+			states[address] = state
+		}
+
+		inMempool := len(pending[address]) + len(queued[address])
+		missing := missingNonces(nonce, pending[address], queued[address])
+		stuckFor := time.Duration(0)
+		if inMempool > 0 {
+			stuckFor = now.Sub(state.since)
+		}
+
+		metrics.Gauge(nonceMempoolMetric + address.Hex()).Update(int64(inMempool))
+		metrics.Gauge(nonceGapMetric + address.Hex()).Update(int64(missing))
+		metrics.Gauge(nonceStuckMetric + address.Hex()).Update(int64(stuckFor.Seconds()))
+
+		if gap := missing > 0; gap != state.gap {
+			state.gap = gap
+			if gap {
+				metrics.Counter(nonceAlertsMetric).Inc(1)
+				log.Printf(
+					"%s is missing %d nonces before its queued transactions (nonce %d)",
+					address.Hex(),
+					missing,
+					nonce,
+				)
+			} else {
+				log.Printf("nonce gap of %s resolved", address.Hex())
+			}
+		}
+
+		if stuck := stuckFor >= config.StuckAfter && inMempool > 0; stuck != state.stuck {
+			state.stuck = stuck
+			if stuck {
+				metrics.Counter(nonceAlertsMetric).Inc(1)
+				log.Printf(
+					"nonce %d of %s is stuck: %d transactions in the mempool but no progress for %s",
+					nonce,
+					address.Hex(),
+					inMempool,
+					stuckFor,
+				)
+			} else {
+				log.Printf("nonce of %s is no longer stuck", address.Hex())
+			}
+		}
+	}
+
+	return nil
+}
+
+// MonitorMempool samples the mempool of the node until ctx is
+// done. If config is nil, it returns immediately. If the node
+// does not serve the txpool API, it logs so and returns.
+func (ec *Client) MonitorMempool(ctx context.Context, config *MempoolMonitorConfig) error {
+	if config == nil {
+		return nil
+	}
+
+	state := newMempoolState()
+	for {
+		if err := ec.checkMempool(ctx, config, state, time.Now()); err != nil && ctx.Err() == nil {
+			var rpcErr rpc.Error
+			if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == methodNotFoundCode {
+				log.Printf("%s: the txpool API is not available, the mempool will not be sampled", err.Error())
+				return nil
+			}
+
+			log.Printf("unable to sample mempool: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(mempoolPollInterval):
+		}
+	}
+}
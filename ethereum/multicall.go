@@ -0,0 +1,206 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"fmt"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	// callsKey is the "eth_call" parameter holding the
+	// calls executed at once through MulticallContract.
+	callsKey = "calls"
+
+	// MaxMulticallCalls is the maximum number
+	// of calls in a single "eth_call" request.
+	MaxMulticallCalls = 500
+)
+
+// MulticallContract is the address of the Multicall3 contract used to
+// execute batched "eth_call" requests. It defaults to the address
+// Multicall3 is deployed at on every network that supports it.
+var MulticallContract = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+const multicallABIDefinition = `[
+	{"type":"function","name":"tryAggregate","stateMutability":"payable","inputs":[{"name":"requireSuccess","type":"bool"},{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"callData","type":"bytes"}]}],"outputs":[{"name":"returnData","type":"tuple[]","components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}]}]}
+]`
+
+var multicallABI = mustParseABI(multicallABIDefinition)
+
+// MulticallInput is the input of an "eth_call"
+// request with calls instead of to and data.
+type MulticallInput struct {
+	BlockIndex int64            `json:"index,omitempty"`
+	BlockHash  string           `json:"hash,omitempty"`
+	Calls      []*MulticallCall `json:"calls"`
+
+	// StateOverrides are applied to the
+	// state before executing the calls.
+	StateOverrides StateOverride `json:"state_overrides,omitempty"`
+}
+
+// MulticallCall is a single call of a MulticallInput.
+type MulticallCall struct {
+	To   string `json:"to"`
+	Data string `json:"data"`
+}
+
+// MulticallOutput is the output of an "eth_call" request with calls.
+// All calls are executed against the state of BlockIdentifier.
+type MulticallOutput struct {
+	BlockIdentifier *RosettaTypes.BlockIdentifier `json:"block_identifier"`
+	Results         []*MulticallResult            `json:"results"`
+}
+
+// MulticallResult is the result of a single call. A failed call
+// does not fail the request: Data is its revert data instead.
+type MulticallResult struct {
+	Success bool   `json:"success"`
+	Data    string `json:"data"`
+}
+
+// multicallCall and multicallResult mirror the
+// tuples of the Multicall3 tryAggregate method.
+type multicallCall struct {
+	Target   common.Address
+	CallData []byte
+}
+
+type multicallResult struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// isMulticall returns true if params is a
+// batch of calls instead of a single call.
+func isMulticall(params map[string]interface{}) bool {
+	_, ok := params[callsKey]
+	return ok
+}
+
+// multicall executes the calls in params in a single "eth_call"
+// through MulticallContract. The calls are pinned at the hash of
+// the requested block (or of the latest block) so that the result
+// identifies the state they were executed against.
+func (ec *Client) multicall(
+	ctx context.Context,
+	params map[string]interface{},
+) (map[string]interface{}, error) {
+	var input MulticallInput
+	overrides, err := decodeCallParams(params, &input)
+	if err != nil {
+		return nil, err
+	}
+	input.StateOverrides = overrides
+
+	if len(input.Calls) == 0 {
+		return nil, fmt.Errorf("%w: calls is empty", ErrCallParametersInvalid)
+	}
+	if len(input.Calls) > MaxMulticallCalls {
+		return nil, fmt.Errorf(
+			"%w: %d calls exceed the maximum of %d",
+			ErrCallParametersInvalid,
+			len(input.Calls),
+			MaxMulticallCalls,
+		)
+	}
+
+	calls := make([]multicallCall, len(input.Calls))
+	for i, call := range input.Calls {
+		if call == nil || !common.IsHexAddress(call.To) {
+			return nil, fmt.Errorf("%w: call %d has an invalid to address", ErrCallParametersInvalid, i)
+		}
+
+		data, err := hexutil.Decode(call.Data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: call %d has invalid data: %s", ErrCallParametersInvalid, i, err.Error())
+		}
+
+		calls[i] = multicallCall{Target: common.HexToAddress(call.To), CallData: data}
+	}
+
+	data, err := multicallABI.Pack("tryAggregate", false, calls)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCallParametersInvalid, err.Error())
+	}
+
+	header, err := ec.callHeader(ctx, input.BlockIndex, input.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{
+		map[string]string{
+			"to":   MulticallContract.Hex(),
+			"data": hexutil.Encode(data),
+		},
+		header.Hash().Hex(),
+	}
+	if len(input.StateOverrides) > 0 {
+		args = append(args, input.StateOverrides)
+	}
+
+	var resp string
+	if err := ec.c.CallContext(ctx, &resp, "eth_call", args...); err != nil {
+		return nil, err
+	}
+
+	results, err := unpackMulticall(resp)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCallOutputMarshal, err.Error())
+	}
+	if len(results) != len(calls) {
+		return nil, fmt.Errorf(
+			"%w: %d results for %d calls (is Multicall3 deployed at %s?)",
+			ErrCallOutputMarshal,
+			len(results),
+			len(calls),
+			MulticallContract.Hex(),
+		)
+	}
+
+	return marshalJSONMap(&MulticallOutput{
+		BlockIdentifier: headerIdentifier(header),
+		Results:         results,
+	})
+}
+
+// unpackMulticall decodes the output of tryAggregate.
+func unpackMulticall(output string) ([]*MulticallResult, error) {
+	raw, err := hexutil.Decode(output)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded []multicallResult
+	if err := multicallABI.UnpackIntoInterface(&decoded, "tryAggregate", raw); err != nil {
+		return nil, fmt.Errorf("%w: unable to unpack tryAggregate", err)
+	}
+
+	results := make([]*MulticallResult, len(decoded))
+	for i, result := range decoded {
+		results[i] = &MulticallResult{
+			Success: result.Success,
+			Data:    hexutil.Encode(result.ReturnData),
+		}
+	}
+
+	return results, nil
+}
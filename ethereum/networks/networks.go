@@ -0,0 +1,225 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package networks contains the parameters of the Corechain
+// networks: chain IDs, genesis hashes, hardforks, system
+// contract addresses, and precompiled contracts. It does not
+// depend on the rest of rosetta-core, so external programs can
+// import it without importing the client.
+package networks
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Version is the version of the network parameters. It is
+// incremented whenever a parameter of an existing network
+// changes (i.e. when a hardfork is scheduled), so programs
+// can detect that they were built with stale parameters.
+const Version = 3
+
+const (
+	// Blockchain is the blockchain of every network.
+	Blockchain = "Corechain"
+
+	// Symbol and Decimals describe the native currency.
+	Symbol   = "CORE"
+	Decimals = 18
+
+	// CoreNetwork, BuffaloNetwork, and DevNetwork are
+	// the names of the networks.
+	CoreNetwork    = "Core"
+	BuffaloNetwork = "Buffalo"
+	DevNetwork     = "Dev"
+)
+
+// The system contracts are deployed at the same
+// addresses in the genesis block of every network.
+var (
+	ValidatorSetContract = common.HexToAddress("0x0000000000000000000000000000000000001000")
+	SlashContract        = common.HexToAddress("0x0000000000000000000000000000000000001001")
+	SystemRewardContract = common.HexToAddress("0x0000000000000000000000000000000000001002")
+	LightClientContract  = common.HexToAddress("0x0000000000000000000000000000000000001003")
+	RelayerHubContract   = common.HexToAddress("0x0000000000000000000000000000000000001004")
+	CandidateHubContract = common.HexToAddress("0x0000000000000000000000000000000000001005")
+	GovHubContract       = common.HexToAddress("0x0000000000000000000000000000000000001006")
+	PledgeAgentContract  = common.HexToAddress("0x0000000000000000000000000000000000001007")
+	BurnContract         = common.HexToAddress("0x0000000000000000000000000000000000001008")
+	FoundationContract   = common.HexToAddress("0x0000000000000000000000000000000000001009")
+)
+
+// Network describes a Corechain network.
+type Network struct {
+	// Name is the network of the Rosetta network identifier.
+	Name string
+
+	ChainID     *big.Int
+	GenesisHash common.Hash
+
+	// Hardforks are the activation heights of the hardforks of
+	// the network, keyed by the name of the hardfork in the chain
+	// config of the node without the "Block" suffix (i.e.
+	// "hashPower").
+	Hardforks map[string]uint64
+
+	// SystemContracts are the system contract addresses,
+	// keyed by contract name (i.e. "PledgeAgent").
+	SystemContracts map[string]common.Address
+
+	// Precompiles are the addresses of the precompiled
+	// contracts, keyed by address. Precompiles have no
+	// code but can hold a balance like any other account.
+	Precompiles map[common.Address]string
+
+	// Tokens are well-known ERC-20 tokens of the network.
+	// The list is not exhaustive: any token can still be
+	// requested by its address.
+	Tokens []*Token
+}
+
+// Token is an ERC-20 token of a network.
+type Token struct {
+	Symbol   string         `json:"symbol"`
+	Decimals int32          `json:"decimals"`
+	Address  common.Address `json:"address"`
+}
+
+// The Corechain networks.
+var (
+	// Core is Corechain Mainnet.
+	Core = &Network{
+		Name:        CoreNetwork,
+		ChainID:     big.NewInt(1116), // nolint:gomnd
+		GenesisHash: common.HexToHash("0xf7fc87f11e61508a5828cd1508060ed1714c8d32a92744ae10acb43c953357ad"),
+		Hardforks: map[string]uint64{
+			"homestead":      0,
+			"eip150":         0,
+			"eip155":         0,
+			"eip158":         0,
+			"byzantium":      0,
+			"constantinople": 0,
+			"petersburg":     0,
+			"istanbul":       0,
+			"muirGlacier":    0,
+			"hashPower":      0,
+		},
+		SystemContracts: systemContracts(),
+		Precompiles:     precompiles(),
+		Tokens: []*Token{
+			{
+				Symbol:   "WCORE",
+				Decimals: 18, // nolint:gomnd
+				Address:  common.HexToAddress("0x40375C92d9FAf44d2f9db9Bd9ba41a3317a2404f"),
+			},
+			{
+				Symbol:   "USDT",
+				Decimals: 6, // nolint:gomnd
+				Address:  common.HexToAddress("0x900101d06A7426441Ae63e9AB3B9b0F63Be145F1"),
+			},
+			{
+				Symbol:   "USDC",
+				Decimals: 6, // nolint:gomnd
+				Address:  common.HexToAddress("0xa4151B2B3e269645181dCcF2D426cE75fcbDeca9"),
+			},
+		},
+	}
+
+	// Buffalo is Corechain Testnet.
+	Buffalo = &Network{
+		Name:        BuffaloNetwork,
+		ChainID:     big.NewInt(1115), // nolint:gomnd
+		GenesisHash: common.HexToHash("0xd90508c51efd64e75363cdf51114d9f2a90a79e6cd0f78f3c3038b47695c034a"),
+		Hardforks: map[string]uint64{
+			"homestead":      0,
+			"eip150":         0,
+			"eip155":         0,
+			"eip158":         0,
+			"byzantium":      0,
+			"constantinople": 0,
+			"petersburg":     0,
+			"istanbul":       0,
+			"muirGlacier":    0,
+			"ramanujan":      0,
+			"niels":          0,
+		},
+		SystemContracts: systemContracts(),
+		Precompiles:     precompiles(),
+	}
+
+	// Dev is a local development network. Its genesis
+	// hash depends on the genesis of the node, so it
+	// is left empty.
+	Dev = &Network{
+		Name:            DevNetwork,
+		ChainID:         big.NewInt(1112), // nolint:gomnd
+		Hardforks:       map[string]uint64{},
+		SystemContracts: systemContracts(),
+		Precompiles:     precompiles(),
+	}
+)
+
+func systemContracts() map[string]common.Address {
+	return map[string]common.Address{
+		"ValidatorSet": ValidatorSetContract,
+		"Slash":        SlashContract,
+		"SystemReward": SystemRewardContract,
+		"LightClient":  LightClientContract,
+		"RelayerHub":   RelayerHubContract,
+		"CandidateHub": CandidateHubContract,
+		"GovHub":       GovHubContract,
+		"PledgeAgent":  PledgeAgentContract,
+		"Burn":         BurnContract,
+		"Foundation":   FoundationContract,
+	}
+}
+
+// precompiles returns the precompiled contracts
+// of the Istanbul hardfork, which every network
+// activates at genesis.
+func precompiles() map[common.Address]string {
+	return map[common.Address]string{
+		common.BytesToAddress([]byte{1}): "ecrecover",
+		common.BytesToAddress([]byte{2}): "sha256",
+		common.BytesToAddress([]byte{3}): "ripemd160",
+		common.BytesToAddress([]byte{4}): "identity",
+		common.BytesToAddress([]byte{5}): "modexp",
+		common.BytesToAddress([]byte{6}): "bn256Add",
+		common.BytesToAddress([]byte{7}): "bn256ScalarMul",
+		common.BytesToAddress([]byte{8}): "bn256Pairing",
+		common.BytesToAddress([]byte{9}): "blake2F",
+	}
+}
+
+// All returns every network, sorted by chain ID.
+func All() []*Network {
+	all := []*Network{Core, Buffalo, Dev}
+	sort.Slice(all, func(i, j int) bool { return all[i].ChainID.Cmp(all[j].ChainID) < 0 })
+
+	return all
+}
+
+// ByChainID returns the network with chainID, or
+// nil if chainID is not a Corechain network.
+func ByChainID(chainID *big.Int) *Network {
+	for _, network := range All() {
+		if network.ChainID.Cmp(chainID) == 0 {
+			return network
+		}
+	}
+
+	return nil
+}
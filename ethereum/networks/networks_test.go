@@ -0,0 +1,72 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networks
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAll(t *testing.T) {
+	all := All()
+	assert.Equal(t, []*Network{Dev, Buffalo, Core}, all)
+
+	for _, network := range all {
+		assert.NotEmpty(t, network.Name)
+		assert.Len(t, network.SystemContracts, 10) // nolint:gomnd
+	}
+}
+
+func TestByChainID(t *testing.T) {
+	assert.Equal(t, Core, ByChainID(big.NewInt(1116)))
+	assert.Equal(t, Buffalo, ByChainID(big.NewInt(1115)))
+	assert.Equal(t, Dev, ByChainID(big.NewInt(1112)))
+	assert.Nil(t, ByChainID(big.NewInt(1)))
+}
+
+func TestSystemContracts(t *testing.T) {
+	// Each network has its own copy of the addresses.
+	Dev.SystemContracts["Burn"] = SlashContract
+	defer func() { Dev.SystemContracts["Burn"] = BurnContract }()
+
+	assert.Equal(t, BurnContract, Core.SystemContracts["Burn"])
+}
+
+func TestTokens(t *testing.T) {
+	for _, network := range All() {
+		seen := map[common.Address]bool{}
+		for _, token := range network.Tokens {
+			assert.NotEmpty(t, token.Symbol)
+			assert.NotEqual(t, common.Address{}, token.Address)
+			assert.False(t, seen[token.Address], token.Symbol)
+			seen[token.Address] = true
+		}
+	}
+}
+
+func TestPrecompiles(t *testing.T) {
+	// The registry lists the precompiled contracts
+	// the node activates at genesis.
+	var addresses []common.Address
+	for address := range Core.Precompiles {
+		addresses = append(addresses, address)
+	}
+	assert.ElementsMatch(t, vm.PrecompiledAddressesIstanbul, addresses)
+	assert.Equal(t, "sha256", Core.Precompiles[common.HexToAddress("0x2")])
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"fmt"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	EthTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// RawHeaderMetadataKey is the block metadata key holding
+	// the hex-encoded RLP encoding of the block header, whose
+	// Keccak-256 hash is the hash of the block.
+	RawHeaderMetadataKey = "raw_header"
+
+	// RawTransactionMetadataKey is the transaction metadata key
+	// holding the hex-encoded binary encoding of the transaction
+	// (the RLP encoding of legacy transactions, prefixed by the
+	// type of typed transactions), whose Keccak-256 hash is the
+	// hash of the transaction.
+	RawTransactionMetadataKey = "raw_transaction"
+)
+
+// RawRLPTransformer is a BlockTransformer adding the raw encodings
+// of headers and transactions to the metadata of blocks and
+// transactions (see RawHeaderMetadataKey and
+// RawTransactionMetadataKey), so that their hashes can be verified
+// without access to the node. It is enabled with the
+// RAW_RLP_METADATA setting rather than registered by name.
+var RawRLPTransformer BlockTransformer = rawRLPTransformer{}
+
+type rawRLPTransformer struct{}
+
+// TransformTransaction adds the binary encoding of raw to tx.
+func (rawRLPTransformer) TransformTransaction(
+	tx *RosettaTypes.Transaction,
+	raw *EthTypes.Transaction,
+	receipt *EthTypes.Receipt,
+) error {
+	encoded, err := raw.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("%w: unable to encode transaction %s", err, raw.Hash().Hex())
+	}
+
+	if tx.Metadata == nil {
+		tx.Metadata = map[string]interface{}{}
+	}
+	tx.Metadata[RawTransactionMetadataKey] = hexutil.Encode(encoded)
+
+	return nil
+}
+
+// TransformBlock adds the RLP encoding of the header of raw to block.
+func (rawRLPTransformer) TransformBlock(block *RosettaTypes.Block, raw *EthTypes.Block) error {
+	encoded, err := rlp.EncodeToBytes(raw.Header())
+	if err != nil {
+		return fmt.Errorf("%w: unable to encode header of block %s", err, raw.Hash().Hex())
+	}
+
+	if block.Metadata == nil {
+		block.Metadata = map[string]interface{}{}
+	}
+	block.Metadata[RawHeaderMetadataKey] = hexutil.Encode(encoded)
+
+	return nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	// FailureReasonMetadataKey is the transaction metadata key
+	// holding the reason a failed transaction was reverted.
+	FailureReasonMetadataKey = "failure_reason"
+
+	// CallerRevertedMetadataKey is the operation metadata flag
+	// set on the failed operations of internal calls that did
+	// not fail themselves but were reverted by a caller. The
+	// error of such operations is the error of that caller.
+	CallerRevertedMetadataKey = "caller_reverted"
+)
+
+var (
+	// panicSelector is the selector of the Panic(uint256)
+	// error raised by failed Solidity assertions.
+	panicSelector = []byte{0x4e, 0x48, 0x7b, 0x71}
+
+	// panicReasons are the descriptions of the Solidity panic codes.
+	//
+	// Source:
+	// https://docs.soliditylang.org/en/latest/control-structures.html#panic-via-assert-and-error-via-require
+	panicReasons = map[uint64]string{
+		0x00: "generic compiler panic",
+		0x01: "assertion failed",
+		0x11: "arithmetic overflow or underflow",
+		0x12: "division or modulo by zero",
+		0x21: "invalid enum value",
+		0x22: "invalid storage byte array encoding",
+		0x31: "pop on empty array",
+		0x32: "array index out of bounds",
+		0x41: "out of memory",
+		0x51: "call to zero-initialized function",
+	}
+)
+
+// FailureReason describes why a transaction failed.
+type FailureReason struct {
+	// Error is the error reported by the node
+	// (i.e. "execution reverted" or "out of gas").
+	Error string `json:"error"`
+
+	// Reason is the message passed to revert or require.
+	Reason string `json:"reason,omitempty"`
+
+	// PanicCode and PanicReason are populated if the
+	// transaction failed with a Solidity panic.
+	PanicCode   string `json:"panic_code,omitempty"`
+	PanicReason string `json:"panic_reason,omitempty"`
+
+	// Output is the raw revert data, populated when
+	// it cannot be decoded (i.e. custom errors).
+	Output string `json:"output,omitempty"`
+}
+
+// failureReason extracts the reason a transaction failed
+// from its trace. If the transaction did not fail, nil
+// is returned.
+func failureReason(trace *Call) *FailureReason {
+	if trace == nil || !trace.Revert {
+		return nil
+	}
+
+	reason := &FailureReason{
+		Error: trace.ErrorMessage,
+	}
+
+	output := trace.Output
+	switch {
+	case len(output) == 0:
+	case bytes.HasPrefix(output, panicSelector):
+		code := new(big.Int).SetBytes(output[len(panicSelector):])
+		reason.PanicCode = hexutil.EncodeBig(code)
+		if code.IsUint64() {
+			reason.PanicReason = panicReasons[code.Uint64()]
+		}
+	default:
+		message, err := abi.UnpackRevert(output)
+		if err != nil {
+			reason.Output = hexutil.Encode(output)
+		} else {
+			reason.Reason = message
+		}
+	}
+
+	return reason
+}
+
+// failureReasonMetadata returns the transaction metadata
+// describing why tx failed. If tx did not fail (or was
+// not traced), nil is returned.
+func failureReasonMetadata(tx *loadedTransaction) (map[string]interface{}, error) {
+	if tx.Receipt == nil || tx.Receipt.Status != 0 {
+		return nil, nil
+	}
+
+	reason := failureReason(tx.Trace)
+	if reason == nil {
+		return nil, nil
+	}
+
+	reasonMap, err := marshalJSONMap(reason)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to marshal failure reason", err)
+	}
+
+	return reasonMap, nil
+}
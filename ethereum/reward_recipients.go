@@ -0,0 +1,284 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/coinbase/rosetta-ethereum/amount"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// RewardRecipient determines the account MINER_REWARD
+// operations are attributed to.
+type RewardRecipient string
+
+const (
+	// CoinbaseRewardRecipient attributes MINER_REWARD
+	// operations to the coinbase of the block (the
+	// consensus address of its validator).
+	CoinbaseRewardRecipient RewardRecipient = "coinbase"
+
+	// FeeAddressRewardRecipient attributes MINER_REWARD
+	// operations to the fee address of the validator of
+	// the block, which ultimately receives its rewards.
+	FeeAddressRewardRecipient RewardRecipient = "fee_address"
+)
+
+const (
+	// RewardRecipientMetadataKey is the operation metadata key
+	// holding the fee address ultimately receiving the rewards
+	// moved by the operation.
+	RewardRecipientMetadataKey = "reward_recipient"
+
+	// RewardHopMetadataKey is the operation metadata key holding
+	// the step of the path of rewards the operation belongs to:
+	// FeeRewardHop, DepositRewardHop, or DistributionRewardHop.
+	RewardHopMetadataKey = "reward_hop"
+
+	// FeeRewardHop is the fee of a
+	// transaction paid to the coinbase.
+	FeeRewardHop = "fee"
+
+	// DepositRewardHop is the deposit of the fees of a block
+	// by its validator into the ValidatorSet contract.
+	DepositRewardHop = "deposit"
+
+	// DistributionRewardHop is the payment of the rewards of
+	// a round by the ValidatorSet contract to a fee address.
+	DistributionRewardHop = "distribution"
+
+	// consensusAddressMetadataKey and validatorMetadataKey are
+	// the MINER_REWARD operation metadata keys holding the
+	// consensus and operator addresses of the validator.
+	consensusAddressMetadataKey = "consensus_address"
+	validatorMetadataKey        = "validator"
+
+	// rewardRecipientRounds is the number of
+	// rounds whose validators are cached.
+	rewardRecipientRounds = 4
+)
+
+// rewardResolver attributes the rewards of blocks to the
+// fee addresses of the validators receiving them.
+//
+// On Core, the coinbase of a block is the consensus address of
+// its validator. No block reward is credited to it directly:
+// the fees of the block are paid to the coinbase, deposited into
+// the ValidatorSet contract by a system transaction at the end
+// of the block, and distributed to the fee address of every
+// validator (with the block rewards) when the next round starts.
+type rewardResolver struct {
+	mutex sync.Mutex
+
+	// validators are the validators of recent rounds,
+	// keyed by round and then by consensus address.
+	validators map[int64]map[common.Address]*Validator
+}
+
+// newRewardResolver returns a *rewardResolver if recipient
+// is FeeAddressRewardRecipient. Otherwise, nil is returned.
+func newRewardResolver(recipient RewardRecipient) *rewardResolver {
+	if recipient != FeeAddressRewardRecipient {
+		return nil
+	}
+
+	return &rewardResolver{
+		validators: map[int64]map[common.Address]*Validator{},
+	}
+}
+
+// roundValidators returns the validators of the round active
+// at blockNumber, keyed by consensus address.
+func (ec *Client) roundValidators(
+	ctx context.Context,
+	blockNumber *big.Int,
+) (map[common.Address]*Validator, error) {
+	r := ec.rewards
+	blockQuery := toBlockNumArg(blockNumber)
+	roundTag, err := ec.callContractBig(ctx, systemABI, CandidateHubContract, blockQuery, "roundTag")
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get round", err)
+	}
+	round := roundTag.Int64()
+
+	r.mutex.Lock()
+	validators, ok := r.validators[round]
+	r.mutex.Unlock()
+	if ok {
+		return validators, nil
+	}
+
+	list, err := ec.validators(ctx, blockQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	validators = make(map[common.Address]*Validator, len(list))
+	for _, validator := range list {
+		validators[common.HexToAddress(validator.Consensus)] = validator
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.validators[round] = validators
+	for cached := range r.validators {
+		if cached <= round-rewardRecipientRounds {
+			delete(r.validators, cached)
+		}
+	}
+
+	return validators, nil
+}
+
+// attributeRewards attributes the rewards of block in txs (the
+// converted transactions of block, starting with its reward
+// transaction) if reward recipients are resolved. The validators
+// of the parent block are used, since a block that starts a round
+// is produced by a validator of the previous round. If the coinbase
+// is not one of them, txs are left unchanged.
+func (ec *Client) attributeRewards(
+	ctx context.Context,
+	block *types.Block,
+	txs []*RosettaTypes.Transaction,
+) error {
+	if ec.rewards == nil || block.Number().Int64() == GenesisBlockIndex {
+		return nil
+	}
+
+	parentNumber := new(big.Int).Sub(block.Number(), big.NewInt(1))
+	validators, err := ec.roundValidators(ctx, parentNumber)
+	if err != nil {
+		return err
+	}
+
+	attributeRewardOps(block.Coinbase(), validators, txs)
+	return nil
+}
+
+// attributeRewardOps moves the MINER_REWARD operations of txs
+// from coinbase to the fee address of its validator and labels
+// every operation on the path of rewards to a fee address with
+// RewardRecipientMetadataKey and RewardHopMetadataKey. Core does
+// not credit block rewards to the coinbase (see miningReward), so
+// moving MINER_REWARD operations does not change any balance.
+func attributeRewardOps(
+	coinbase common.Address,
+	validators map[common.Address]*Validator,
+	txs []*RosettaTypes.Transaction,
+) {
+	validator, ok := validators[coinbase]
+	if !ok {
+		return
+	}
+
+	feeAddresses := make(map[common.Address]string, len(validators))
+	for _, v := range validators {
+		feeAddresses[common.HexToAddress(v.FeeAddress)] = v.FeeAddress
+	}
+
+	for _, tx := range txs {
+		var deposit []*RosettaTypes.Operation
+		depositCredited := false
+		for _, op := range tx.Operations {
+			account, ok := operationAccount(op)
+			if !ok {
+				continue
+			}
+
+			switch {
+			case op.Type == MinerRewardOpType && account == coinbase:
+				op.Account = &RosettaTypes.AccountIdentifier{Address: validator.FeeAddress}
+				setMetadata(op, consensusAddressMetadataKey, validator.Consensus)
+				setMetadata(op, validatorMetadataKey, validator.Operator)
+				setMetadata(op, RewardRecipientMetadataKey, validator.FeeAddress)
+			case op.Type == FeeOpType:
+				if account == coinbase && operationSign(op) > 0 {
+					labelRewardHop(op, validator.FeeAddress, FeeRewardHop)
+				}
+			case account == coinbase && operationSign(op) < 0:
+				deposit = append(deposit, op)
+			case account == ValidatorSetContract && operationSign(op) > 0:
+				deposit = append(deposit, op)
+				depositCredited = true
+			case operationSign(op) > 0:
+				recipient, ok := feeAddresses[account]
+				if !ok {
+					continue
+				}
+
+				// Only payments by the ValidatorSet contract
+				// are distributions of rewards.
+				for _, related := range op.RelatedOperations {
+					if related.Index < 0 || related.Index >= int64(len(tx.Operations)) {
+						continue
+					}
+
+					from := tx.Operations[related.Index]
+					if fromAccount, ok := operationAccount(from); ok && fromAccount == ValidatorSetContract {
+						labelRewardHop(from, recipient, DistributionRewardHop)
+						labelRewardHop(op, recipient, DistributionRewardHop)
+					}
+				}
+			}
+		}
+
+		// The fees are deposited by a transaction in which the
+		// coinbase sends CORE to the ValidatorSet contract.
+		if depositCredited && len(deposit) > 1 {
+			for _, op := range deposit {
+				labelRewardHop(op, validator.FeeAddress, DepositRewardHop)
+			}
+		}
+	}
+}
+
+// labelRewardHop labels op as the hop of the
+// path of rewards to recipient.
+func labelRewardHop(op *RosettaTypes.Operation, recipient string, hop string) {
+	setMetadata(op, RewardRecipientMetadataKey, recipient)
+	setMetadata(op, RewardHopMetadataKey, hop)
+}
+
+// operationAccount returns the address of
+// the account of op, if it has one.
+func operationAccount(op *RosettaTypes.Operation) (common.Address, bool) {
+	if op.Account == nil || !common.IsHexAddress(op.Account.Address) {
+		return common.Address{}, false
+	}
+
+	return common.HexToAddress(op.Account.Address), true
+}
+
+// operationSign returns the sign of the amount of op
+// (0 if op has no amount).
+func operationSign(op *RosettaTypes.Operation) int {
+	if op.Amount == nil {
+		return 0
+	}
+
+	value, err := amount.Parse(op.Amount.Value)
+	if err != nil {
+		return 0
+	}
+
+	return value.Sign()
+}
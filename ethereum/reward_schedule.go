@@ -0,0 +1,195 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// RewardScheduleMethod is the /call method used to fetch
+	// the block reward schedule at a given block.
+	RewardScheduleMethod = "reward_schedule"
+
+	// paramValueWordLength is the length of a GovHub parameter value
+	// that encodes a single uint256.
+	paramValueWordLength = 32
+)
+
+// RewardScheduleInput is the input to the call
+// method "reward_schedule".
+type RewardScheduleInput struct {
+	BlockIndex int64  `json:"index,omitempty"`
+	BlockHash  string `json:"hash,omitempty"`
+	FromIndex  int64  `json:"from_index,omitempty"`
+}
+
+// RewardSchedule is the block reward schedule active at
+// a block, along with every governance change applied to it.
+type RewardSchedule struct {
+	BlockIdentifier             *RosettaTypes.BlockIdentifier `json:"block_identifier"`
+	BlockReward                 string                        `json:"block_reward"`
+	BlockRewardIncentivePercent int64                         `json:"block_reward_incentive_percent"`
+	BurnRatio                   int64                         `json:"burn_ratio"`
+	Changes                     []*RewardParamChange          `json:"changes"`
+}
+
+// RewardParamChange is a reward parameter update applied
+// by GovHub.
+type RewardParamChange struct {
+	BlockIndex      int64  `json:"block_index"`
+	TransactionHash string `json:"transaction_hash"`
+	Contract        string `json:"contract"`
+	Key             string `json:"key"`
+	Value           string `json:"value"`
+}
+
+// rewardScheduleContracts are the system contracts holding
+// reward parameters.
+var rewardScheduleContracts = []common.Address{
+	ValidatorSetContract,
+	SystemRewardContract,
+}
+
+// rewardSchedule returns the reward schedule active at the requested
+// block, computed from the system contracts and their GovHub history.
+func (ec *Client) rewardSchedule(
+	ctx context.Context,
+	params map[string]interface{},
+) (map[string]interface{}, error) {
+	var input RewardScheduleInput
+	if err := RosettaTypes.UnmarshalMap(params, &input); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCallParametersInvalid, err.Error())
+	}
+
+	header, err := ec.callHeader(ctx, input.BlockIndex, input.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.FromIndex > header.Number.Int64() {
+		return nil, fmt.Errorf(
+			"%w: from_index %d is after block %d",
+			ErrCallParametersInvalid,
+			input.FromIndex,
+			header.Number.Int64(),
+		)
+	}
+
+	blockQuery := toBlockNumArg(header.Number)
+	blockReward, err := ec.callContractBig(
+		ctx,
+		systemABI,
+		ValidatorSetContract,
+		blockQuery,
+		"blockReward",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get block reward", err)
+	}
+
+	incentivePercent, err := ec.callContractBig(
+		ctx,
+		systemABI,
+		ValidatorSetContract,
+		blockQuery,
+		"blockRewardIncentivePercent",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get block reward incentive percent", err)
+	}
+
+	burnRatio, err := ec.callContractBig(
+		ctx,
+		systemABI,
+		SystemRewardContract,
+		blockQuery,
+		"burnRatio",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get burn ratio", err)
+	}
+
+	changes, err := ec.paramChanges(
+		ctx,
+		rewardScheduleContracts,
+		big.NewInt(input.FromIndex),
+		header.Number,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get reward parameter history", err)
+	}
+
+	return marshalJSONMap(&RewardSchedule{
+		BlockIdentifier:             headerIdentifier(header),
+		BlockReward:                 blockReward.String(),
+		BlockRewardIncentivePercent: incentivePercent.Int64(),
+		BurnRatio:                   burnRatio.Int64(),
+		Changes:                     changes,
+	})
+}
+
+// paramChanges returns all GovHub parameter changes applied to the
+// provided contracts in the inclusive range [from, to].
+func (ec *Client) paramChanges(
+	ctx context.Context,
+	contracts []common.Address,
+	from *big.Int,
+	to *big.Int,
+) ([]*RewardParamChange, error) {
+	filter := map[string]interface{}{
+		"fromBlock": hexutil.EncodeBig(from),
+		"toBlock":   hexutil.EncodeBig(to),
+		"address":   contracts,
+		"topics":    [][]common.Hash{{paramChangeTopic}},
+	}
+
+	var logs []types.Log
+	if err := ec.c.CallContext(ctx, &logs, "eth_getLogs", filter); err != nil {
+		return nil, err
+	}
+
+	changes := make([]*RewardParamChange, 0, len(logs))
+	for _, l := range logs {
+		values, err := systemABI.Unpack("paramChange", l.Data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to decode param change in %s", err, l.TxHash.Hex())
+		}
+
+		key, _ := values[0].(string)
+		rawValue, _ := values[1].([]byte)
+		value := hexutil.Encode(rawValue)
+		if len(rawValue) == paramValueWordLength {
+			value = new(big.Int).SetBytes(rawValue).String()
+		}
+
+		changes = append(changes, &RewardParamChange{
+			BlockIndex:      int64(l.BlockNumber),
+			TransactionHash: l.TxHash.Hex(),
+			Contract:        MustChecksum(l.Address.Hex()),
+			Key:             key,
+			Value:           value,
+		})
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// RoundMetadataKey is the block metadata key holding the
+	// Satoshi Plus round started by the block.
+	RoundMetadataKey = "round"
+)
+
+// Round is the Satoshi Plus round bookkeeping of CandidateHub
+// included in the metadata of the block that starts a round.
+// Together with the previous round boundary, it allows the
+// rewards of a round to be attributed from Rosetta data.
+type Round struct {
+	Number        int64    `json:"number"`
+	StartIndex    int64    `json:"start_index"`
+	Validators    []string `json:"validators"`
+	PreviousRound struct {
+		Number   int64 `json:"number"`
+		EndIndex int64 `json:"end_index"`
+	} `json:"previous_round"`
+}
+
+// turnRoundSelector is the selector of CandidateHub.turnRound,
+// which is called by the validator of the first block of
+// every round.
+var turnRoundSelector = systemABI.Methods["turnRound"].ID
+
+// turnsRound returns true if block contains a call
+// to CandidateHub.turnRound.
+func turnsRound(block *types.Block) bool {
+	for _, tx := range block.Transactions() {
+		if tx.To() != nil &&
+			*tx.To() == CandidateHubContract &&
+			bytes.HasPrefix(tx.Data(), turnRoundSelector) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// roundMetadata returns the block metadata of a block. Only
+// blocks that start a round have metadata, so nil is returned
+// for all other blocks.
+func (ec *Client) roundMetadata(
+	ctx context.Context,
+	block *types.Block,
+) (map[string]interface{}, error) {
+	if block.Number().Int64() == GenesisBlockIndex || !turnsRound(block) {
+		return nil, nil
+	}
+
+	blockQuery := toBlockNumArg(block.Number())
+	parentNumber := new(big.Int).Sub(block.Number(), big.NewInt(1))
+	roundTag, err := ec.callContractBig(
+		ctx,
+		systemABI,
+		CandidateHubContract,
+		blockQuery,
+		"roundTag",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get round", err)
+	}
+
+	previousRoundTag, err := ec.callContractBig(
+		ctx,
+		systemABI,
+		CandidateHubContract,
+		toBlockNumArg(parentNumber),
+		"roundTag",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get previous round", err)
+	}
+
+	// turnRound can fail, in which case the round does not change.
+	if roundTag.Cmp(previousRoundTag) == 0 {
+		return nil, nil
+	}
+
+	output, err := ec.callContract(
+		ctx,
+		systemABI,
+		ValidatorSetContract,
+		blockQuery,
+		"getValidators",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get validators", err)
+	}
+
+	var validators []common.Address
+	if err := systemABI.UnpackIntoInterface(&validators, "getValidators", output); err != nil {
+		return nil, fmt.Errorf("%w: unable to unpack getValidators", err)
+	}
+
+	round := &Round{
+		Number:     roundTag.Int64(),
+		StartIndex: block.Number().Int64(),
+		Validators: make([]string, len(validators)),
+	}
+	round.PreviousRound.Number = previousRoundTag.Int64()
+	round.PreviousRound.EndIndex = parentNumber.Int64()
+	for i, validator := range validators {
+		round.Validators[i] = MustChecksum(validator.Hex())
+	}
+
+	roundMap, err := marshalJSONMap(round)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		RoundMetadataKey: roundMap,
+	}, nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	// safeOperationCall is the Safe operation that executes
+	// the inner transaction with CALL.
+	safeOperationCall uint8 = 0
+
+	// safeOperationDelegateCall is the Safe operation that executes
+	// the inner transaction with DELEGATECALL.
+	safeOperationDelegateCall uint8 = 1
+
+	// safeMetadataKey is the operation metadata key populated
+	// with the decoded Safe transaction.
+	safeMetadataKey = "safe_transaction"
+)
+
+// gnosisSafeABI contains the subset of the Gnosis Safe
+// interface used to decode multi-signature executions.
+const gnosisSafeABI = `[
+	{"type":"function","name":"execTransaction","stateMutability":"payable","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"},{"name":"operation","type":"uint8"},{"name":"safeTxGas","type":"uint256"},{"name":"baseGas","type":"uint256"},{"name":"gasPrice","type":"uint256"},{"name":"gasToken","type":"address"},{"name":"refundReceiver","type":"address"},{"name":"signatures","type":"bytes"}],"outputs":[{"name":"success","type":"bool"}]}
+]`
+
+var (
+	safeABI = mustParseABI(gnosisSafeABI)
+
+	execTransactionSelector = safeABI.Methods["execTransaction"].ID
+)
+
+// SafeTransaction is the inner transaction of a
+// Gnosis Safe execTransaction call.
+type SafeTransaction struct {
+	Safe      common.Address
+	To        common.Address
+	Value     *big.Int
+	Data      []byte
+	Operation uint8
+}
+
+// decodeSafeTransaction decodes the input of a call to safe. If the
+// input is not a well-formed execTransaction call, false is returned.
+func decodeSafeTransaction(safe common.Address, input []byte) (*SafeTransaction, bool) {
+	if len(input) < len(execTransactionSelector) ||
+		!bytes.Equal(input[:len(execTransactionSelector)], execTransactionSelector) {
+		return nil, false
+	}
+
+	values, err := safeABI.Methods["execTransaction"].Inputs.Unpack(
+		input[len(execTransactionSelector):],
+	)
+	if err != nil {
+		return nil, false
+	}
+
+	to, ok := values[0].(common.Address)
+	if !ok {
+		return nil, false
+	}
+	value, ok := values[1].(*big.Int)
+	if !ok {
+		return nil, false
+	}
+	data, ok := values[2].([]byte)
+	if !ok {
+		return nil, false
+	}
+	operation, ok := values[3].(uint8)
+	if !ok || (operation != safeOperationCall && operation != safeOperationDelegateCall) {
+		return nil, false
+	}
+
+	return &SafeTransaction{
+		Safe:      safe,
+		To:        to,
+		Value:     value,
+		Data:      data,
+		Operation: operation,
+	}, true
+}
+
+// callType returns the trace type the Safe uses to
+// execute the inner transaction.
+func (s *SafeTransaction) callType() string {
+	if s.Operation == safeOperationDelegateCall {
+		return DelegateCallOpType
+	}
+
+	return CallOpType
+}
+
+// matches returns true if call is the execution of
+// the inner transaction by the Safe.
+func (s *SafeTransaction) matches(call *Call) bool {
+	return call.From == s.Safe &&
+		call.To == s.To &&
+		call.Type == s.callType() &&
+		(s.Operation == safeOperationDelegateCall || call.Value.Cmp(s.Value) == 0)
+}
+
+// metadata returns the operation metadata describing
+// the Safe transaction.
+func (s *SafeTransaction) metadata() map[string]interface{} {
+	return map[string]interface{}{
+		"safe":      MustChecksum(s.Safe.Hex()),
+		"to":        MustChecksum(s.To.Hex()),
+		"value":     s.Value.String(),
+		"data":      hexutil.Encode(s.Data),
+		"operation": s.callType(),
+	}
+}
+
+// markSafeExecutions walks a trace and annotates every call executed
+// on behalf of a Safe with the decoded Safe transaction.
+func markSafeExecutions(call *Call) {
+	if call == nil {
+		return
+	}
+
+	if call.Type == CallOpType {
+		if safeTx, ok := decodeSafeTransaction(call.To, call.Input); ok {
+			if inner := findSafeExecution(call.Calls, safeTx); inner != nil {
+				inner.safe = safeTx
+			}
+		}
+	}
+
+	for _, child := range call.Calls {
+		markSafeExecutions(child)
+	}
+}
+
+// findSafeExecution returns the call executing safeTx. Safes are
+// usually deployed as proxies, so the search descends through
+// DELEGATECALLs made by the Safe into its singleton.
+func findSafeExecution(calls []*Call, safeTx *SafeTransaction) *Call {
+	for _, call := range calls {
+		if safeTx.matches(call) {
+			return call
+		}
+
+		if call.Type == DelegateCallOpType && call.From == safeTx.Safe {
+			if inner := findSafeExecution(call.Calls, safeTx); inner != nil {
+				return inner
+			}
+		}
+	}
+
+	return nil
+}
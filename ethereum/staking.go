@@ -0,0 +1,206 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// ValidatorMetadataKey is the DELEGATE operation metadata
+	// key holding the operator address of the validator.
+	ValidatorMetadataKey = "validator"
+
+	// StakedBalanceMetadataKey is the /account/balance metadata
+	// key holding the CORE an account delegated to validators.
+	StakedBalanceMetadataKey = "staked_balance"
+
+	// UnclaimedRewardsMetadataKey is the /account/balance
+	// metadata key holding the delegation rewards an account
+	// has not claimed yet.
+	UnclaimedRewardsMetadataKey = "unclaimed_rewards"
+)
+
+// pledgeAgentABI contains the subset of the PledgeAgent
+// interface used to construct delegations.
+const pledgeAgentABI = `[
+	{"type":"function","name":"delegateCoin","stateMutability":"payable","inputs":[{"name":"agent","type":"address"}],"outputs":[]},
+	{"type":"function","name":"requiredCoinDeposit","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"getDelegator","stateMutability":"view","inputs":[{"name":"agent","type":"address"},{"name":"delegator","type":"address"}],"outputs":[{"name":"deposit","type":"uint256"},{"name":"newDeposit","type":"uint256"},{"name":"changeRound","type":"uint256"},{"name":"rewardIndex","type":"uint256"}]},
+	{"type":"function","name":"rewardMap","stateMutability":"view","inputs":[{"name":"","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"claimReward","stateMutability":"nonpayable","inputs":[{"name":"agentList","type":"address[]"}],"outputs":[{"name":"","type":"uint256"},{"name":"","type":"bool"}]}
+]`
+
+var pledgeAgent = mustParseABI(pledgeAgentABI)
+
+// DelegateCoinData returns the calldata of a PledgeAgent
+// delegation to validator.
+func DelegateCoinData(validator common.Address) ([]byte, error) {
+	return pledgeAgent.Pack("delegateCoin", validator)
+}
+
+// ParseDelegateCoinData returns the validator of PledgeAgent
+// delegation calldata. If data is not a delegation, false
+// is returned.
+func ParseDelegateCoinData(data []byte) (common.Address, bool) {
+	method := pledgeAgent.Methods["delegateCoin"]
+	if !bytes.HasPrefix(data, method.ID) {
+		return common.Address{}, false
+	}
+
+	values, err := method.Inputs.Unpack(data[len(method.ID):])
+	if err != nil || len(values) != 1 {
+		return common.Address{}, false
+	}
+
+	validator, ok := values[0].(common.Address)
+	return validator, ok
+}
+
+// RequiredCoinDepositData returns the calldata used to fetch
+// the minimum delegation amount from PledgeAgent.
+func RequiredCoinDepositData() []byte {
+	return pledgeAgent.Methods["requiredCoinDeposit"].ID
+}
+
+// ParseRequiredCoinDeposit decodes the minimum delegation
+// amount returned by PledgeAgent.
+func ParseRequiredCoinDeposit(output []byte) (*big.Int, error) {
+	values, err := pledgeAgent.Unpack("requiredCoinDeposit", output)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to unpack requiredCoinDeposit", err)
+	}
+
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected 1 output from requiredCoinDeposit but got %d", len(values))
+	}
+
+	value, ok := values[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("unexpected output type %T from requiredCoinDeposit", values[0])
+	}
+
+	return value, nil
+}
+
+// StakedBalance is the CORE an account has locked in
+// PledgeAgent, along with its unclaimed rewards.
+type StakedBalance struct {
+	Staked           *big.Int
+	UnclaimedRewards *big.Int
+}
+
+// StakedBalance returns the CORE address delegated to all
+// validator candidates and the rewards it has not claimed at
+// block. Rewards are only counted once PledgeAgent has settled
+// them (i.e. at the end of every round).
+func (ec *Client) StakedBalance(
+	ctx context.Context,
+	address common.Address,
+	block *RosettaTypes.BlockIdentifier,
+) (*StakedBalance, error) {
+	blockQuery := toBlockNumArg(big.NewInt(block.Index))
+	delegations, err := ec.delegations(ctx, blockQuery, address)
+	if err != nil {
+		return nil, err
+	}
+
+	staked := new(big.Int)
+	for _, delegation := range delegations {
+		staked.Add(staked, delegation.deposit)
+	}
+
+	rewards, err := ec.callContractBig(ctx, pledgeAgent, PledgeAgentContract, blockQuery, "rewardMap", address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get unclaimed rewards", err)
+	}
+
+	return &StakedBalance{
+		Staked:           staked,
+		UnclaimedRewards: rewards,
+	}, nil
+}
+
+// delegation is the CORE an account
+// delegated to a validator candidate.
+type delegation struct {
+	validator common.Address
+
+	// deposit includes delegations that are
+	// not effective until the next round.
+	deposit *big.Int
+}
+
+// delegations returns the delegations of address to every
+// validator candidate at blockQuery. Candidates address did
+// not delegate to are omitted.
+func (ec *Client) delegations(
+	ctx context.Context,
+	blockQuery string,
+	address common.Address,
+) ([]*delegation, error) {
+	output, err := ec.callContract(ctx, stakingContracts, CandidateHubContract, blockQuery, "getCandidates")
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get candidates", err)
+	}
+
+	var candidates []common.Address
+	if err := stakingContracts.UnpackIntoInterface(&candidates, "getCandidates", output); err != nil {
+		return nil, fmt.Errorf("%w: unable to unpack getCandidates", err)
+	}
+
+	delegations := []*delegation{}
+	for _, candidate := range candidates {
+		output, err := ec.callContract(
+			ctx,
+			pledgeAgent,
+			PledgeAgentContract,
+			blockQuery,
+			"getDelegator",
+			candidate,
+			address,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to get delegation to %s", err, candidate.Hex())
+		}
+
+		var delegator struct {
+			Deposit     *big.Int
+			NewDeposit  *big.Int
+			ChangeRound *big.Int
+			RewardIndex *big.Int
+		}
+		if err := pledgeAgent.UnpackIntoInterface(&delegator, "getDelegator", output); err != nil {
+			return nil, fmt.Errorf("%w: unable to unpack getDelegator", err)
+		}
+
+		if delegator.NewDeposit.Sign() == 0 {
+			continue
+		}
+
+		delegations = append(delegations, &delegation{
+			validator: candidate,
+			deposit:   delegator.NewDeposit,
+		})
+	}
+
+	return delegations, nil
+}
@@ -0,0 +1,223 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum/networks"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Corechain system contracts are deployed at fixed addresses
+// in the genesis block of every Corechain network.
+var (
+	ValidatorSetContract = networks.ValidatorSetContract
+	SlashContract        = networks.SlashContract
+	SystemRewardContract = networks.SystemRewardContract
+	LightClientContract  = networks.LightClientContract
+	RelayerHubContract   = networks.RelayerHubContract
+	CandidateHubContract = networks.CandidateHubContract
+	GovHubContract       = networks.GovHubContract
+	PledgeAgentContract  = networks.PledgeAgentContract
+	BurnContract         = networks.BurnContract
+	FoundationContract   = networks.FoundationContract
+
+	// systemContracts are the system contract addresses
+	// that can be replaced with SetSystemContracts.
+	systemContracts = map[string]*common.Address{
+		"ValidatorSet": &ValidatorSetContract,
+		"Slash":        &SlashContract,
+		"SystemReward": &SystemRewardContract,
+		"LightClient":  &LightClientContract,
+		"RelayerHub":   &RelayerHubContract,
+		"CandidateHub": &CandidateHubContract,
+		"GovHub":       &GovHubContract,
+		"PledgeAgent":  &PledgeAgentContract,
+		"Burn":         &BurnContract,
+		"Foundation":   &FoundationContract,
+	}
+
+	// precompiles are the precompiled contracts,
+	// which are the same on every network.
+	precompiles = networks.Core.Precompiles
+
+	// paramChangeTopic is the topic of the event emitted by every
+	// system contract when GovHub updates one of its parameters.
+	paramChangeTopic = crypto.Keccak256Hash([]byte("paramChange(string,bytes)"))
+)
+
+// SystemContracts returns the current system
+// contract addresses, keyed by contract name.
+func SystemContracts() map[string]common.Address {
+	contracts := make(map[string]common.Address, len(systemContracts))
+	for name, address := range systemContracts {
+		contracts[name] = *address
+	}
+
+	return contracts
+}
+
+// SetSystemContracts replaces the addresses of the system contracts
+// named in contracts (i.e. "PledgeAgent") for networks that deploy
+// them elsewhere. It must be called before any Client is created.
+func SetSystemContracts(contracts map[string]common.Address) error {
+	for name := range contracts {
+		if _, ok := systemContracts[name]; !ok {
+			return fmt.Errorf("%s is not a system contract", name)
+		}
+	}
+
+	for name, address := range contracts {
+		*systemContracts[name] = address
+	}
+
+	return nil
+}
+
+// Precompile returns the name of the precompiled contract at
+// address (i.e. "sha256"), or false if there is none.
+func Precompile(address common.Address) (string, bool) {
+	name, ok := precompiles[address]
+	return name, ok
+}
+
+// systemContractABI contains the subset of the system contract
+// interfaces used by rosetta-core.
+const systemContractABI = `[
+	{"type":"function","name":"blockReward","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"blockRewardIncentivePercent","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"burnRatio","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"roundTag","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"getValidators","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address[]"}]},
+	{"type":"function","name":"turnRound","stateMutability":"nonpayable","inputs":[],"outputs":[]},
+	{"type":"event","name":"paramChange","anonymous":false,"inputs":[{"name":"key","type":"string","indexed":false},{"name":"value","type":"bytes","indexed":false}]}
+]`
+
+var systemABI = mustParseABI(systemContractABI)
+
+func mustParseABI(definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(fmt.Sprintf("unable to parse abi: %s", err.Error()))
+	}
+
+	return parsed
+}
+
+// callBlockQuery returns the block parameter to use for
+// state queries pinned at a block index or hash. If neither
+// is populated, the latest block is used.
+func callBlockQuery(index int64, hash string) string {
+	if index > int64(0) {
+		return toBlockNumArg(big.NewInt(index))
+	}
+
+	if len(hash) > 0 {
+		return hash
+	}
+
+	return toBlockNumArg(nil)
+}
+
+// callHeader returns the header of the block a /call method
+// is pinned at. If neither index nor hash is populated, the
+// latest header is returned.
+func (ec *Client) callHeader(ctx context.Context, index int64, hash string) (*types.Header, error) {
+	var header *types.Header
+	var err error
+	if index == 0 && len(hash) > 0 {
+		header, err = ec.blockHeaderByHash(ctx, hash)
+	} else {
+		var number *big.Int
+		if index > 0 {
+			number = big.NewInt(index)
+		}
+		header, err = ec.blockHeaderByNumber(ctx, number)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get block header", err)
+	}
+
+	return header, nil
+}
+
+// callContract invokes a read-only method on a contract at the
+// provided block and returns the raw output.
+func (ec *Client) callContract(
+	ctx context.Context,
+	contractABI abi.ABI,
+	to common.Address,
+	blockQuery string,
+	method string,
+	args ...interface{},
+) ([]byte, error) {
+	data, err := contractABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to pack %s", err, method)
+	}
+
+	callParams := map[string]string{
+		"to":   to.Hex(),
+		"data": hexutil.Encode(data),
+	}
+
+	var resp string
+	if err := ec.c.CallContext(ctx, &resp, "eth_call", callParams, blockQuery); err != nil {
+		return nil, err
+	}
+
+	return hexutil.Decode(resp)
+}
+
+// callContractBig invokes a read-only method on a contract that
+// returns a single uint256.
+func (ec *Client) callContractBig(
+	ctx context.Context,
+	contractABI abi.ABI,
+	to common.Address,
+	blockQuery string,
+	method string,
+	args ...interface{},
+) (*big.Int, error) {
+	output, err := ec.callContract(ctx, contractABI, to, blockQuery, method, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := contractABI.Unpack(method, output)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to unpack %s", err, method)
+	}
+
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected 1 output from %s but got %d", method, len(values))
+	}
+
+	value, ok := values[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("unexpected output type %T from %s", values[0], method)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/coinbase/rosetta-sdk-go/asserter"
+)
+
+// validTimestamp returns true if the block time (in seconds)
+// is accepted by the Rosetta asserter.
+func validTimestamp(time uint64) bool {
+	return convertTime(time) >= asserter.MinUnixEpoch
+}
+
+// TimestampStartIndex returns the index of the first block with a
+// timestamp the Rosetta asserter accepts. Chains (and forks) whose
+// early blocks predate asserter.MinUnixEpoch (i.e. a genesis time
+// of 0) would otherwise fail validation. Block timestamps never
+// decrease, so the index is found with a binary search and cached
+// once found.
+func (ec *Client) TimestampStartIndex(ctx context.Context) (int64, error) {
+	ec.timestampMutex.Lock()
+	defer ec.timestampMutex.Unlock()
+
+	if ec.timestampStartIndex != nil {
+		return *ec.timestampStartIndex, nil
+	}
+
+	genesis, err := ec.blockHeaderByNumber(ctx, big.NewInt(GenesisBlockIndex))
+	if err != nil {
+		return 0, fmt.Errorf("%w: unable to get genesis block header", err)
+	}
+
+	if validTimestamp(genesis.Time) {
+		index := genesis.Number.Int64()
+		ec.timestampStartIndex = &index
+		return index, nil
+	}
+
+	head, err := ec.blockHeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%w: unable to get head block header", err)
+	}
+
+	// No block is valid yet, so the index
+	// cannot be cached.
+	if !validTimestamp(head.Time) {
+		return head.Number.Int64() + 1, nil
+	}
+
+	// The first valid block is in (low, high].
+	low := genesis.Number.Int64()
+	high := head.Number.Int64()
+	for high-low > 1 {
+		mid := low + (high-low)/2 // nolint:gomnd
+		header, err := ec.blockHeaderByNumber(ctx, big.NewInt(mid))
+		if err != nil {
+			return 0, fmt.Errorf("%w: unable to get block header %d", err, mid)
+		}
+
+		if validTimestamp(header.Time) {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+
+	ec.timestampStartIndex = &high
+	return high, nil
+}
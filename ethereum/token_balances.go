@@ -0,0 +1,191 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/coinbase/rosetta-ethereum/amount"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+)
+
+const (
+	// TokenAddressMetadataKey is the currency metadata holding
+	// the address of the ERC-20 contract of a token currency.
+	TokenAddressMetadataKey = "token_address"
+
+	// tokenBalanceBatchSize is the number of
+	// balanceOf calls in a single JSON-RPC batch.
+	tokenBalanceBatchSize = 20
+
+	// maxTokenBalanceConcurrency is the maximum number of
+	// batches of a single request in flight at once.
+	maxTokenBalanceConcurrency = int64(4) // nolint:gomnd
+)
+
+// balanceOfSelector is the selector of the
+// ERC-20 balanceOf(address) method.
+var balanceOfSelector = crypto.Keccak256([]byte("balanceOf(address)"))[:4]
+
+// TokenInventoryInput is the input to the
+// call method "token_inventory".
+type TokenInventoryInput struct {
+	Address string `json:"address"`
+}
+
+// TokenAddress returns the address of the ERC-20 contract
+// of currency, if it is a token currency.
+func TokenAddress(currency *RosettaTypes.Currency) (common.Address, bool) {
+	if currency == nil || currency.Metadata == nil {
+		return common.Address{}, false
+	}
+
+	address, ok := currency.Metadata[TokenAddressMetadataKey].(string)
+	if !ok || !common.IsHexAddress(address) {
+		return common.Address{}, false
+	}
+
+	return common.HexToAddress(address), true
+}
+
+// TokenCurrency returns the currency of the ERC-20 contract at
+// address. Token symbols are neither unique nor normalized, so
+// tokens are identified by the address in the currency metadata:
+// the symbol is used as-is and the address is checksummed so a
+// token always has the same currency.
+func TokenCurrency(symbol string, decimals int32, address common.Address) *RosettaTypes.Currency {
+	return &RosettaTypes.Currency{
+		Symbol:   symbol,
+		Decimals: decimals,
+		Metadata: map[string]interface{}{
+			TokenAddressMetadataKey: MustChecksum(address.Hex()),
+		},
+	}
+}
+
+// CanonicalCurrency returns a copy of currency with its token
+// address checksummed (see TokenCurrency), so currencies of the
+// same contract requested with differently cased addresses are
+// identical. Any other currency is returned unchanged.
+func CanonicalCurrency(currency *RosettaTypes.Currency) *RosettaTypes.Currency {
+	address, ok := TokenAddress(currency)
+	if !ok {
+		return currency
+	}
+
+	canonical := *currency
+	canonical.Metadata = make(map[string]interface{}, len(currency.Metadata))
+	for key, value := range currency.Metadata {
+		canonical.Metadata[key] = value
+	}
+	canonical.Metadata[TokenAddressMetadataKey] = MustChecksum(address.Hex())
+
+	return &canonical
+}
+
+// TokenBalances returns the balance of address for each token
+// currency at block, in the order of currencies. The balanceOf
+// calls are sent in batches of tokenBalanceBatchSize, with up to
+// maxTokenBalanceConcurrency batches in flight at once.
+func (ec *Client) TokenBalances(
+	ctx context.Context,
+	address common.Address,
+	block *RosettaTypes.BlockIdentifier,
+	currencies []*RosettaTypes.Currency,
+) ([]*RosettaTypes.Amount, error) {
+	data := hexutil.Encode(append(
+		append([]byte{}, balanceOfSelector...),
+		common.LeftPadBytes(address.Bytes(), common.HashLength)...,
+	))
+
+	reqs := make([]rpc.BatchElem, len(currencies))
+	results := make([]string, len(currencies))
+	for i, currency := range currencies {
+		token, ok := TokenAddress(currency)
+		if !ok {
+			return nil, fmt.Errorf(
+				"%w: %s has no valid %s",
+				ErrCurrencyUnsupported,
+				RosettaTypes.PrintStruct(currency),
+				TokenAddressMetadataKey,
+			)
+		}
+
+		// Calls are pinned at the hash of block so every balance
+		// is read from the same state, even if block is reorged.
+		reqs[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args: []interface{}{
+				map[string]string{"to": token.Hex(), "data": data},
+				block.Hash,
+			},
+			Result: &results[i],
+		}
+	}
+
+	sem := semaphore.NewWeighted(maxTokenBalanceConcurrency)
+	g, gctx := errgroup.WithContext(ctx)
+	for start := 0; start < len(reqs); start += tokenBalanceBatchSize {
+		end := start + tokenBalanceBatchSize
+		if end > len(reqs) {
+			end = len(reqs)
+		}
+
+		batch := reqs[start:end]
+		if err := sem.Acquire(gctx, 1); err != nil {
+			break
+		}
+		g.Go(func() error {
+			defer sem.Release(1)
+			return ec.c.BatchCallContext(gctx, batch)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, fmt.Errorf("%w: unable to get token balances", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	amounts := make([]*RosettaTypes.Amount, len(currencies))
+	for i, currency := range currencies {
+		if reqs[i].Error != nil {
+			return nil, fmt.Errorf("%w: unable to get balance of %s", reqs[i].Error, currency.Symbol)
+		}
+
+		// Accounts without code return no data.
+		raw, err := hexutil.Decode(results[i])
+		if err != nil || len(raw) < common.HashLength {
+			return nil, fmt.Errorf(
+				"%w: %s did not return a balance (is it an ERC-20 contract?)",
+				ErrCurrencyUnsupported,
+				currency.Symbol,
+			)
+		}
+
+		amounts[i] = amount.New(new(big.Int).SetBytes(raw[:common.HashLength]), currency)
+	}
+
+	return amounts, nil
+}
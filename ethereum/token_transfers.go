@@ -0,0 +1,90 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// transferTopicCount is the number of topics in an ERC-20
+	// Transfer event. ERC-721 Transfer events share the same
+	// signature but index the token ID as a fourth topic.
+	transferTopicCount = 3
+
+	// transferDataLength is the length of the
+	// (unindexed) value of an ERC-20 Transfer event.
+	transferDataLength = 32
+)
+
+// transferTopic is the topic of the ERC-20 Transfer event.
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// TokenTransfer is an ERC-20 Transfer event. Only the accounts
+// whose balance changed are kept, not the amount transferred.
+type TokenTransfer struct {
+	Block *RosettaTypes.BlockIdentifier
+	Token common.Address
+	From  common.Address
+	To    common.Address
+}
+
+// TokenTransfers returns the ERC-20 Transfer events of the blocks
+// in the inclusive range [start, end], in the order they were
+// emitted. Logs of reorged blocks are returned by some nodes, so
+// the range should only include confirmed blocks.
+func (ec *Client) TokenTransfers(
+	ctx context.Context,
+	start int64,
+	end int64,
+) ([]*TokenTransfer, error) {
+	filter := map[string]interface{}{
+		"fromBlock": hexutil.EncodeBig(big.NewInt(start)),
+		"toBlock":   hexutil.EncodeBig(big.NewInt(end)),
+		"topics":    [][]common.Hash{{transferTopic}},
+	}
+
+	var logs []types.Log
+	if err := ec.c.CallContext(ctx, &logs, "eth_getLogs", filter); err != nil {
+		return nil, fmt.Errorf("%w: unable to get token transfers of blocks %d to %d", err, start, end)
+	}
+
+	transfers := make([]*TokenTransfer, 0, len(logs))
+	for _, l := range logs {
+		if len(l.Topics) != transferTopicCount || len(l.Data) != transferDataLength {
+			continue
+		}
+
+		transfers = append(transfers, &TokenTransfer{
+			Block: &RosettaTypes.BlockIdentifier{
+				Index: int64(l.BlockNumber),
+				Hash:  l.BlockHash.Hex(),
+			},
+			Token: l.Address,
+			From:  common.BytesToAddress(l.Topics[1].Bytes()),
+			To:    common.BytesToAddress(l.Topics[2].Bytes()),
+		})
+	}
+
+	return transfers, nil
+}
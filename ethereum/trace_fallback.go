@@ -0,0 +1,160 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/coinbase/rosetta-ethereum/metrics"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// TraceUnavailableMetadataKey is the transaction metadata
+	// flag set when the transaction could not be traced. Such
+	// transactions only have their fee operations (and their
+	// external call in degraded blocks), so any other balance
+	// changes they made are missing.
+	TraceUnavailableMetadataKey = "trace_unavailable"
+
+	// DegradedMetadataKey is the block metadata flag set when
+	// the block is below the trace start index, so none of its
+	// transactions are traced (see degradedBlock).
+	DegradedMetadataKey = "degraded"
+
+	traceFallbackMetric    = "block/trace_fallback"
+	traceUnavailableMetric = "block/trace_unavailable"
+)
+
+// traceBlock returns the traces of txs, the transactions of a
+// block. When only the first transactions of the block are loaded,
+// rng is the block truncated to them (see rangeBlock), so they are
+// still traced at once. If the block cannot be traced at once (i.e.
+// the trace times out or runs out of memory), each transaction is
+// traced individually. Transactions that still cannot be traced get
+// a nil trace, so the block can be served in a degraded form rather
+// than not at all.
+func (ec *Client) traceBlock(
+	ctx context.Context,
+	blockHash common.Hash,
+	rng hexutil.Bytes,
+	txs []rpcTransaction,
+) ([]*rpcCall, []*rpcRawCall, error) {
+	traces, rawTraces, err := ec.getBlockTraces(ctx, blockHash, rng)
+	if err == nil && len(traces) == len(txs) && len(rawTraces) == len(txs) {
+		return traces, rawTraces, nil
+	}
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+	if err == nil {
+		err = fmt.Errorf("expected %d traces but got %d", len(txs), len(traces))
+	}
+
+	log.Printf(
+		"unable to trace block %s, tracing %d transactions individually: %s",
+		blockHash.Hex(),
+		len(txs),
+		err.Error(),
+	)
+	metrics.Counter(traceFallbackMetric).Inc(1)
+
+	return ec.traceTransactions(ctx, blockHash, txs)
+}
+
+// rangeBlock returns the RLP encoding of the block with head
+// truncated to txs, its first transactions. Tracing it with
+// debug_traceBlock executes each of txs once, where tracing them
+// individually re-executes all transactions before each of them.
+func rangeBlock(head *types.Header, txs []rpcTransaction) (hexutil.Bytes, error) {
+	loaded := make([]*types.Transaction, len(txs))
+	for i, tx := range txs {
+		loaded[i] = tx.tx
+	}
+
+	return rlp.EncodeToBytes(types.NewBlockWithHeader(head).WithBody(loaded, nil))
+}
+
+// traceTransactions traces txs individually. Transactions that
+// cannot be traced get a nil trace.
+func (ec *Client) traceTransactions(
+	ctx context.Context,
+	blockHash common.Hash,
+	txs []rpcTransaction,
+) ([]*rpcCall, []*rpcRawCall, error) {
+	traces := make([]*rpcCall, len(txs))
+	rawTraces := make([]*rpcRawCall, len(txs))
+	for i, tx := range txs {
+		call, raw, err := ec.getTransactionTraces(ctx, tx.tx.Hash())
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+
+		if err != nil {
+			log.Printf(
+				"unable to trace transaction %s in block %s: %s",
+				tx.tx.Hash().Hex(),
+				blockHash.Hex(),
+				err.Error(),
+			)
+			metrics.Counter(traceUnavailableMetric).Inc(1)
+			call, raw = nil, nil
+		}
+
+		traces[i] = &rpcCall{Result: call}
+		rawTraces[i] = &rpcRawCall{Result: raw}
+	}
+
+	return traces, rawTraces, nil
+}
+
+// degradedBlock returns true if the block at index is too old
+// to be traced, i.e. the node no longer has the state needed to
+// re-execute it. Such blocks are served from their transactions
+// and receipts only.
+func (ec *Client) degradedBlock(index int64) bool {
+	return index != GenesisBlockIndex && index < ec.traceStartIndex
+}
+
+// externalCall returns the top-level call of tx, reconstructed
+// from the transaction and its receipt. It has no internal calls.
+func externalCall(tx *loadedTransaction) *Call {
+	call := &Call{
+		Type:    CallOpType,
+		Value:   tx.Transaction.Value(),
+		GasUsed: new(big.Int).SetUint64(tx.Receipt.GasUsed),
+		Revert:  tx.Receipt.Status == 0,
+		Input:   tx.Transaction.Data(),
+	}
+	if tx.From != nil {
+		call.From = *tx.From
+	}
+
+	if to := tx.Transaction.To(); to != nil {
+		call.To = *to
+	} else {
+		call.Type = CreateOpType
+		call.To = tx.Receipt.ContractAddress
+	}
+
+	return call
+}
@@ -0,0 +1,205 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// TransactionStatusMethod is the /call method used to
+	// track the outcome of a broadcast transaction.
+	TransactionStatusMethod = "transaction_status"
+
+	// PendingTransactionStatus is the status of a
+	// transaction in the mempool of the node.
+	PendingTransactionStatus = "pending"
+
+	// MinedTransactionStatus is the status of a
+	// transaction in the canonical chain.
+	MinedTransactionStatus = "mined"
+
+	// DroppedTransactionStatus is the status of a transaction
+	// that is neither mined nor in the mempool of the node,
+	// or that can no longer be mined because another
+	// transaction with its nonce was mined.
+	DroppedTransactionStatus = "dropped"
+)
+
+// TransactionStatusInput is the input to the call method
+// "transaction_status". From and Nonce are only used once
+// the node no longer knows the transaction, to detect that
+// it was replaced.
+type TransactionStatusInput struct {
+	TxHash string  `json:"tx_hash"`
+	From   string  `json:"from,omitempty"`
+	Nonce  *uint64 `json:"nonce,omitempty"`
+}
+
+// SubmissionStatusInput is the input to the
+// call method "submission_status".
+type SubmissionStatusInput struct {
+	TxHash string `json:"tx_hash"`
+}
+
+// TransactionStatus is the outcome of a broadcast transaction.
+type TransactionStatus struct {
+	TransactionHash string `json:"transaction_hash"`
+	Status          string `json:"status"`
+
+	// BlockIdentifier, Confirmations (including the block of
+	// the transaction), and Successful (the execution status)
+	// are only populated for mined transactions.
+	BlockIdentifier *RosettaTypes.BlockIdentifier `json:"block_identifier,omitempty"`
+	Confirmations   int64                         `json:"confirmations"`
+	Successful      *bool                         `json:"successful,omitempty"`
+
+	// Replaced is true if another transaction
+	// with the nonce of the transaction was mined.
+	Replaced bool `json:"replaced"`
+
+	From  string  `json:"from,omitempty"`
+	Nonce *uint64 `json:"nonce,omitempty"`
+}
+
+// transactionStatus returns the status of the requested transaction.
+func (ec *Client) transactionStatus(
+	ctx context.Context,
+	params map[string]interface{},
+) (map[string]interface{}, error) {
+	var input TransactionStatusInput
+	if err := RosettaTypes.UnmarshalMap(params, &input); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCallParametersInvalid, err.Error())
+	}
+
+	hash, err := hexutil.Decode(input.TxHash)
+	if err != nil || len(hash) != common.HashLength {
+		return nil, fmt.Errorf("%w: %s is not a transaction hash", ErrCallParametersInvalid, input.TxHash)
+	}
+	if len(input.From) > 0 && !common.IsHexAddress(input.From) {
+		return nil, fmt.Errorf("%w: %s is not a valid address", ErrCallParametersInvalid, input.From)
+	}
+	if (len(input.From) > 0) != (input.Nonce != nil) {
+		return nil, fmt.Errorf("%w: from and nonce must be provided together", ErrCallParametersInvalid)
+	}
+
+	status := &TransactionStatus{
+		TransactionHash: common.BytesToHash(hash).Hex(),
+		From:            input.From,
+		Nonce:           input.Nonce,
+	}
+
+	var tx *rpcTransaction
+	if err := ec.c.CallContext(ctx, &tx, "eth_getTransactionByHash", status.TransactionHash); err != nil {
+		return nil, fmt.Errorf("%w: unable to get transaction", err)
+	}
+
+	if tx != nil {
+		nonce := tx.tx.Nonce()
+		status.Nonce = &nonce
+		if tx.From != nil {
+			status.From = MustChecksum(tx.From.Hex())
+		}
+
+		if tx.BlockHash != nil {
+			mined, err := ec.minedStatus(ctx, status)
+			if err != nil {
+				return nil, err
+			}
+
+			// The transaction was mined after it was fetched
+			// but its block was reorged out since.
+			if !mined {
+				status.Status = PendingTransactionStatus
+			}
+
+			return RosettaTypes.MarshalMap(status)
+		}
+	}
+
+	status.Status = PendingTransactionStatus
+	if tx == nil {
+		status.Status = DroppedTransactionStatus
+	}
+
+	// A transaction whose nonce was used by a mined
+	// transaction was replaced and can never be mined.
+	if len(status.From) > 0 && status.Nonce != nil {
+		var next hexutil.Uint64
+		if err := ec.c.CallContext(
+			ctx,
+			&next,
+			"eth_getTransactionCount",
+			common.HexToAddress(status.From),
+			"latest",
+		); err != nil {
+			return nil, fmt.Errorf("%w: unable to get nonce of %s", err, status.From)
+		}
+
+		// The transaction itself may have been mined since it
+		// was fetched, or it may be mined but no longer indexed
+		// by hash, so it is only replaced if it has no receipt.
+		if uint64(next) > *status.Nonce {
+			mined, err := ec.minedStatus(ctx, status)
+			if err != nil {
+				return nil, err
+			}
+
+			if !mined {
+				status.Status = DroppedTransactionStatus
+				status.Replaced = true
+			}
+		}
+	}
+
+	return RosettaTypes.MarshalMap(status)
+}
+
+// minedStatus populates status for a mined transaction. If
+// the transaction has no receipt, false is returned and
+// status is left unchanged.
+func (ec *Client) minedStatus(ctx context.Context, status *TransactionStatus) (bool, error) {
+	receipt, err := ec.transactionReceipt(ctx, common.HexToHash(status.TransactionHash))
+	if errors.Is(err, ethereum.NotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%w: unable to get receipt", err)
+	}
+
+	head, err := ec.blockHeaderByNumber(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("%w: unable to get head", err)
+	}
+
+	successful := receipt.Status == types.ReceiptStatusSuccessful
+	status.Status = MinedTransactionStatus
+	status.BlockIdentifier = &RosettaTypes.BlockIdentifier{
+		Hash:  receipt.BlockHash.Hex(),
+		Index: receipt.BlockNumber.Int64(),
+	}
+	status.Confirmations = head.Number.Int64() - receipt.BlockNumber.Int64() + 1
+	status.Successful = &successful
+
+	return true, nil
+}
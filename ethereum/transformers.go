@@ -0,0 +1,122 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	EthTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// BlockTransformer extends the conversion of blocks without
+// forking rosetta-core (i.e. to decode the events of a specific
+// protocol). Transformers are registered by name with
+// RegisterBlockTransformer, usually in the init function of
+// the package that implements them, and enabled with the
+// BLOCK_TRANSFORMERS setting.
+type BlockTransformer interface {
+	// TransformTransaction is called with every converted
+	// transaction (in /block and /block/transaction), along with
+	// the transaction and receipt it was converted from. It may add
+	// metadata or append operations indexed after the existing ones.
+	// Appended operations are subject to the invariant checks (see
+	// checkInvariant), so they must not move CORE.
+	TransformTransaction(
+		tx *RosettaTypes.Transaction,
+		raw *EthTypes.Transaction,
+		receipt *EthTypes.Receipt,
+	) error
+
+	// TransformBlock is called with every converted block
+	// once its transactions are transformed, along with the
+	// block it was converted from. It may add metadata.
+	TransformBlock(block *RosettaTypes.Block, raw *EthTypes.Block) error
+}
+
+var (
+	blockTransformersMutex sync.RWMutex
+	blockTransformers      = map[string]BlockTransformer{}
+)
+
+// RegisterBlockTransformer makes transformer available under name.
+// It panics if a transformer is already registered under name.
+func RegisterBlockTransformer(name string, transformer BlockTransformer) {
+	blockTransformersMutex.Lock()
+	defer blockTransformersMutex.Unlock()
+
+	if _, ok := blockTransformers[name]; ok {
+		panic(fmt.Sprintf("block transformer %s is already registered", name))
+	}
+	blockTransformers[name] = transformer
+}
+
+// LookupBlockTransformer returns the
+// transformer registered under name.
+func LookupBlockTransformer(name string) (BlockTransformer, bool) {
+	blockTransformersMutex.RLock()
+	defer blockTransformersMutex.RUnlock()
+
+	transformer, ok := blockTransformers[name]
+	return transformer, ok
+}
+
+// BlockTransformers returns the sorted names
+// of all registered transformers.
+func BlockTransformers() []string {
+	blockTransformersMutex.RLock()
+	defer blockTransformersMutex.RUnlock()
+
+	names := make([]string, 0, len(blockTransformers))
+	for name := range blockTransformers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// convertTransaction converts tx and applies the
+// block transformers to the converted transaction.
+func (ec *Client) convertTransaction(tx *loadedTransaction) (*RosettaTypes.Transaction, error) {
+	transaction, err := ec.populateTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, transformer := range ec.transformers {
+		if err := transformer.TransformTransaction(transaction, tx.Transaction, tx.Receipt); err != nil {
+			return nil, fmt.Errorf("%w: unable to transform transaction", err)
+		}
+	}
+
+	// Label the operations appended by the transformers too.
+	ec.labelAccounts(transaction.Operations)
+
+	return transaction, nil
+}
+
+// transformBlock applies the block transformers to block.
+func (ec *Client) transformBlock(block *RosettaTypes.Block, raw *EthTypes.Block) error {
+	for _, transformer := range ec.transformers {
+		if err := transformer.TransformBlock(block, raw); err != nil {
+			return fmt.Errorf("%w: unable to transform block", err)
+		}
+	}
+
+	return nil
+}
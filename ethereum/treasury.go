@@ -0,0 +1,108 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// FoundationSubtype is the subtype of operations
+	// on the account of the Foundation contract.
+	FoundationSubtype = "FOUNDATION"
+
+	// TreasurySubtype is the subtype of operations on the
+	// account of the SystemReward contract, which holds the
+	// system reward pool (the on-chain treasury).
+	TreasurySubtype = "TREASURY"
+
+	// subtypeMetadataKey is the operation metadata key
+	// populated with the subtype of an operation.
+	subtypeMetadataKey = "subtype"
+)
+
+// subtypeFlags are the operation metadata flags set on operations
+// involving an account with a subtype. The flag is also set on
+// related operations (i.e. the counterparty of a transfer) so
+// that both sides of a flow can be selected.
+var subtypeFlags = map[string]string{
+	FoundationSubtype: "foundation",
+	TreasurySubtype:   "treasury",
+}
+
+// subtypeAccounts are the system accounts
+// whose operations are labeled.
+var subtypeAccounts = map[common.Address]string{
+	FoundationContract:   FoundationSubtype,
+	SystemRewardContract: TreasurySubtype,
+}
+
+// labelOperations labels the operations in ops involving the
+// Foundation and treasury system accounts.
+func labelOperations(ops []*RosettaTypes.Operation) {
+	flags := map[int64]string{}
+	for _, op := range ops {
+		if op.Account == nil || !common.IsHexAddress(op.Account.Address) {
+			continue
+		}
+
+		subtype, ok := subtypeAccounts[common.HexToAddress(op.Account.Address)]
+		if !ok {
+			continue
+		}
+
+		setMetadata(op, subtypeMetadataKey, subtype)
+		flags[op.OperationIdentifier.Index] = subtypeFlags[subtype]
+	}
+
+	if len(flags) == 0 {
+		return
+	}
+
+	for _, op := range ops {
+		flag, labeled := flags[op.OperationIdentifier.Index]
+		for _, related := range op.RelatedOperations {
+			if related.Index < 0 || related.Index >= int64(len(ops)) {
+				continue
+			}
+
+			// Flag both sides of the relation
+			if relatedFlag, ok := flags[related.Index]; ok {
+				setMetadata(op, relatedFlag, true)
+			}
+			if labeled {
+				setMetadata(ops[related.Index], flag, true)
+			}
+		}
+
+		if labeled {
+			setMetadata(op, flag, true)
+		}
+	}
+}
+
+// setMetadata sets key in the metadata of op. The metadata is
+// copied first because operations may share their metadata map
+// (i.e. both sides of a transfer built by traceOps).
+func setMetadata(op *RosettaTypes.Operation, key string, value interface{}) {
+	metadata := make(map[string]interface{}, len(op.Metadata)+1)
+	for k, v := range op.Metadata {
+		metadata[k] = v
+	}
+	metadata[key] = value
+
+	op.Metadata = metadata
+}
@@ -17,10 +17,10 @@ package ethereum
 import (
 	"context"
 	"fmt"
-	"math/big"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum/networks"
 
 	"github.com/coinbase/rosetta-sdk-go/types"
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -30,7 +30,7 @@ const (
 	NodeVersion = "1.9.24"
 
 	// Blockchain is Ethereum.
-	Blockchain string = "Corechain"
+	Blockchain string = networks.Blockchain
 
 	// MainnetNetwork is the value of the network
 	// in MainnetNetworkIdentifier.
@@ -50,23 +50,23 @@ const (
 
 	// DevNetwork is the value of the network
 	// in DevNetworkNetworkIdentifier.
-	DevNetwork string = "Dev"
+	DevNetwork string = networks.DevNetwork
 
 	// CoreNetwork is the value of the network
 	// in CoreNetworkNetworkIdentifier.
-	CoreNetwork string = "Core"
+	CoreNetwork string = networks.CoreNetwork
 
 	// BuffaloNetwork is the value of the network
 	// in BuffaloNetworkNetworkIdentifier.
-	BuffaloNetwork string = "Buffalo"
+	BuffaloNetwork string = networks.BuffaloNetwork
 
 	// Symbol is the symbol value
 	// used in Currency.
-	Symbol = "CORE"
+	Symbol = networks.Symbol
 
 	// Decimals is the decimals value
 	// used in Currency.
-	Decimals = 18
+	Decimals = networks.Decimals
 
 	// MinerRewardOpType is used to describe
 	// a miner block reward.
@@ -105,6 +105,35 @@ const (
 	// of a transaction.
 	DestructOpType = "DESTRUCT"
 
+	// ApprovalOpType is a synthetic, zero-amount operation used to
+	// represent ERC-20 Approval events. It is only emitted when
+	// approval operations are enabled.
+	ApprovalOpType = "APPROVAL"
+
+	// UserOperationOpType is a synthetic, zero-amount operation
+	// used to represent the ERC-4337 user operations executed
+	// by a bundle. It is only emitted when user operations are
+	// enabled.
+	UserOperationOpType = "USER_OPERATION"
+
+	// DustOpType is a synthetic operation holding the net amount
+	// of the trace operations of an account that were dropped
+	// because they moved less than the dust threshold. It is
+	// only emitted when a dust threshold is configured.
+	DustOpType = "DUST"
+
+	// DelegateOpType is a construction-only operation used to
+	// express the intent to delegate CORE to a validator through
+	// PledgeAgent. It is never emitted by the Data API, where a
+	// delegation is a CALL to PledgeAgent.
+	DelegateOpType = "DELEGATE"
+
+	// CancelOpType is a construction-only operation used to
+	// express the intent to cancel a pending transaction by
+	// replacing it with a zero-value transfer to its sender.
+	// It is never emitted by the Data API.
+	CancelOpType = "CANCEL"
+
 	// SuccessStatus is the status of any
 	// Ethereum operation considered successful.
 	SuccessStatus = "SUCCESS"
@@ -133,30 +162,66 @@ const (
 	// of a transfer.
 	TransferGasLimit = int64(21000) //nolint:gomnd
 
+	// DelegateGasLimit is the gas limit of a
+	// PledgeAgent delegation. Unused gas is refunded.
+	DelegateGasLimit = int64(300000) //nolint:gomnd
+
 	// MainnetGethArguments are the arguments to start a mainnet geth instance.
 	MainnetGethArguments = `--config=/app/ethereum/geth.toml --cache=8000 --gcmode=archive --graphql`
 
 	// IncludeMempoolCoins does not apply to rosetta-core as it is not UTXO-based.
 	IncludeMempoolCoins = false
+
+	// BurnedSupplyMethod is the /call method used to fetch
+	// the CORE burned up to the last indexed block. It is
+	// served from the local index.
+	BurnedSupplyMethod = "burned_supply"
+
+	// TokenInventoryMethod is the /call method used to fetch
+	// the ERC-20 tokens held by an address. The tokens are
+	// served from the local index and their balances are
+	// read from the node.
+	TokenInventoryMethod = "token_inventory"
+
+	// FirstSeenAccountsMethod is the /call method used to fetch
+	// the addresses first seen in a range of blocks. It is
+	// served from the local index.
+	FirstSeenAccountsMethod = "first_seen_accounts"
+
+	// MaxFirstSeenAccountsRange is the maximum number of blocks
+	// requested at once with the FirstSeenAccountsMethod.
+	MaxFirstSeenAccountsRange = 10000
+
+	// SubmissionStatusMethod is the /call method used to fetch
+	// the status of a transaction in the submit queue. It is
+	// served from the submit queue.
+	SubmissionStatusMethod = "submission_status"
 )
 
 // CoreChain Genesis hashes and Network configurations to enforce below configs on.
+// The values are defined in the networks package, which external
+// programs can import without importing the client.
 var (
-	DevGenesisHash     = common.HexToHash("0x0000000000000000000000000000000000000000000000000000000000000000")
-	CoreGenesisHash    = common.HexToHash("0xf7fc87f11e61508a5828cd1508060ed1714c8d32a92744ae10acb43c953357ad")
-	BuffaloGenesisHash = common.HexToHash("0xd90508c51efd64e75363cdf51114d9f2a90a79e6cd0f78f3c3038b47695c034a")
+	DevGenesisHash     = networks.Dev.GenesisHash
+	CoreGenesisHash    = networks.Core.GenesisHash
+	BuffaloGenesisHash = networks.Buffalo.GenesisHash
 
 	CoreChainConfig = &params.ChainConfig{
-		ChainID: big.NewInt(1116),
+		ChainID: networks.Core.ChainID,
 	}
 
 	BuffaloChainConfig = &params.ChainConfig{
-		ChainID: big.NewInt(1115),
+		ChainID: networks.Buffalo.ChainID,
 	}
 
 	DevChainConfig = &params.ChainConfig{
-		ChainID: big.NewInt(1112),
+		ChainID: networks.Dev.ChainID,
 	}
+
+	// CoreHardforks and BuffaloHardforks are the activation
+	// heights of the hardforks in the genesis of the network.
+	CoreHardforks    = networks.Core.Hardforks
+	BuffaloHardforks = networks.Buffalo.Hardforks
 )
 
 var (
@@ -247,6 +312,11 @@ var (
 		DelegateCallOpType,
 		StaticCallOpType,
 		DestructOpType,
+		ApprovalOpType,
+		UserOperationOpType,
+		DustOpType,
+		DelegateOpType,
+		CancelOpType,
 	}
 
 	// OperationStatuses are all supported operation statuses.
@@ -261,15 +331,43 @@ var (
 		},
 	}
 
+	// PassthroughCallMethods are the call methods
+	// that are passed through to the node as is.
+	PassthroughCallMethods = []string{
+		"eth_getBlockByNumber",
+		"eth_getTransactionReceipt",
+		"eth_call",
+		"eth_estimateGas",
+	}
+
 	// CallMethods are all supported call methods.
 	CallMethods = []string{
 		"eth_getBlockByNumber",
 		"eth_getTransactionReceipt",
 		"eth_call",
 		"eth_estimateGas",
+		RewardScheduleMethod,
+		DecodeTransactionMethod,
+		ValidatorSetMethod,
+		ValidatorStakeMethod,
+		ValidatorAPRInputsMethod,
+		DelegatorRewardsMethod,
+		BurnedSupplyMethod,
+		TokenInventoryMethod,
+		FirstSeenAccountsMethod,
+		TransactionStatusMethod,
+		SubmissionStatusMethod,
+		InclusionProofMethod,
 	}
 )
 
+// FirstSeenAccountsInput is the input to the call method
+// "first_seen_accounts". Both indexes are inclusive.
+type FirstSeenAccountsInput struct {
+	StartIndex int64 `json:"start_index"`
+	EndIndex   int64 `json:"end_index"`
+}
+
 // JSONRPC is the interface for accessing go-ethereum's JSON RPC endpoint.
 type JSONRPC interface {
 	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
@@ -0,0 +1,181 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/metrics"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// GraphQLCallClass is the class of
+	// queries to the GraphQL endpoint.
+	GraphQLCallClass = "graphql"
+
+	upstreamCallsMetric = "upstream/calls"
+)
+
+type upstreamCallsKey struct{}
+
+// UpstreamCalls counts the upstream calls made on behalf of a
+// single request, by class (see UpstreamCallClass). It is safe
+// for concurrent use.
+type UpstreamCalls struct {
+	mutex   sync.Mutex
+	classes map[string]int64
+}
+
+// WithUpstreamCalls returns a copy of ctx that counts all
+// upstream calls made with it (or any context derived from
+// it) in the returned *UpstreamCalls.
+func WithUpstreamCalls(ctx context.Context) (context.Context, *UpstreamCalls) {
+	calls := &UpstreamCalls{classes: map[string]int64{}}
+
+	return context.WithValue(ctx, upstreamCallsKey{}, calls), calls
+}
+
+// detachedContext carries the values of
+// a context but never expires.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// Detach returns a context carrying the values of ctx (such as
+// its *UpstreamCalls) that is not canceled with ctx and expires
+// after timeout. It is used for work shared by several requests,
+// which must not fail because the request that started it went
+// away. The returned context.CancelFunc must be called once the
+// work is done.
+func Detach(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(detachedContext{ctx}, timeout)
+}
+
+// UpstreamCallsFromContext returns the *UpstreamCalls
+// of a context returned by WithUpstreamCalls.
+func UpstreamCallsFromContext(ctx context.Context) (*UpstreamCalls, bool) {
+	calls, ok := ctx.Value(upstreamCallsKey{}).(*UpstreamCalls)
+
+	return calls, ok
+}
+
+// Add counts count upstream calls of class.
+func (u *UpstreamCalls) Add(class string, count int64) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	u.classes[class] += count
+}
+
+// Classes returns the number of upstream calls by class.
+func (u *UpstreamCalls) Classes() map[string]int64 {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	classes := make(map[string]int64, len(u.classes))
+	for class, count := range u.classes {
+		classes[class] = count
+	}
+
+	return classes
+}
+
+// Total returns the number of upstream calls.
+func (u *UpstreamCalls) Total() int64 {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	var total int64
+	for _, count := range u.classes {
+		total += count
+	}
+
+	return total
+}
+
+// UpstreamCallClass returns the class of a JSON-RPC method,
+// which is its namespace (i.e. "debug" for debug_traceBlockByHash).
+// Providers usually price calls by namespace, tracing being the
+// most expensive.
+func UpstreamCallClass(method string) string {
+	if i := strings.Index(method, "_"); i > 0 {
+		return method[:i]
+	}
+
+	return method
+}
+
+// recordUpstreamCall counts a call to method in the upstream
+// metrics and, if ctx was returned by WithUpstreamCalls, in
+// the *UpstreamCalls of ctx.
+func recordUpstreamCall(ctx context.Context, method string, class string) {
+	metrics.Counter(upstreamCallsMetric).Inc(1)
+	metrics.Counter(upstreamCallsMetric + "/" + class).Inc(1)
+	metrics.Counter(upstreamCallsMetric + "/" + class + "/" + method).Inc(1)
+
+	if calls, ok := UpstreamCallsFromContext(ctx); ok {
+		calls.Add(class, 1)
+	}
+}
+
+// instrumentedRPC counts every call made through a JSONRPC.
+// Each element of a batch is counted as a call because
+// providers bill them individually.
+type instrumentedRPC struct {
+	JSONRPC
+}
+
+// CallContext implements JSONRPC.
+func (i *instrumentedRPC) CallContext(
+	ctx context.Context,
+	result interface{},
+	method string,
+	args ...interface{},
+) error {
+	recordUpstreamCall(ctx, method, UpstreamCallClass(method))
+
+	return i.JSONRPC.CallContext(ctx, result, method, args...)
+}
+
+// BatchCallContext implements JSONRPC.
+func (i *instrumentedRPC) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	for _, elem := range b {
+		recordUpstreamCall(ctx, elem.Method, UpstreamCallClass(elem.Method))
+	}
+
+	return i.JSONRPC.BatchCallContext(ctx, b)
+}
+
+// instrumentedGraphQL counts every
+// query made through a GraphQL.
+type instrumentedGraphQL struct {
+	GraphQL
+}
+
+// Query implements GraphQL.
+func (i *instrumentedGraphQL) Query(ctx context.Context, input string) (string, error) {
+	recordUpstreamCall(ctx, "query", GraphQLCallClass)
+
+	return i.GraphQL.Query(ctx, input)
+}
@@ -0,0 +1,179 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"math/big"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// FeePayerMetadataKey is the transaction metadata key
+	// populated with the fee payer of an ERC-4337 bundle
+	// and the operation metadata key populated with the
+	// type of the fee payer of a user operation.
+	FeePayerMetadataKey = "fee_payer"
+
+	// SenderFeePayer is the fee payer type of a transaction
+	// whose gas is paid by its sender.
+	SenderFeePayer = "sender"
+
+	// BundlerFeePayer is the fee payer type of an ERC-4337
+	// bundle, whose gas is paid by the bundler and refunded
+	// by the EntryPoint.
+	BundlerFeePayer = "bundler"
+
+	// PaymasterFeePayer and AccountFeePayer are the fee payer
+	// types of a user operation whose gas is paid from the
+	// EntryPoint deposit of a paymaster or of the smart
+	// account itself.
+	PaymasterFeePayer = "paymaster"
+	AccountFeePayer   = "account"
+
+	// userOperationTopicCount is the number of topics
+	// in an ERC-4337 UserOperationEvent.
+	userOperationTopicCount = 4
+
+	// userOperationDataLength is the length of the (unindexed)
+	// nonce, success, actualGasCost, and actualGasUsed of an
+	// ERC-4337 UserOperationEvent.
+	userOperationDataLength = 128
+)
+
+var (
+	// EntryPointContracts are the canonical ERC-4337
+	// EntryPoint deployments (v0.6 and v0.7).
+	EntryPointContracts = []common.Address{
+		common.HexToAddress("0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"),
+		common.HexToAddress("0x0000000071727De22E5E9d8BAf0edAc6f37da032"),
+	}
+
+	// userOperationTopic is the topic of the event emitted by
+	// the EntryPoint for every user operation of a bundle. It
+	// is the same in v0.6 and v0.7.
+	userOperationTopic = crypto.Keccak256Hash(
+		[]byte("UserOperationEvent(bytes32,address,address,uint256,bool,uint256,uint256)"),
+	)
+)
+
+// FeePayer is the account paying for the gas of
+// a transaction or of an ERC-4337 bundle.
+type FeePayer struct {
+	Address string `json:"address"`
+	Type    string `json:"type"`
+}
+
+// transactionFeePayer returns the account paying for the gas
+// of tx. Core does not support fee delegation, so the sender
+// always pays: sponsored transactions go through an ERC-4337
+// EntryPoint (see userOperationOps) and their gas is paid by
+// the bundler. If fee delegation is introduced, the sponsor
+// is resolved here.
+func transactionFeePayer(tx *loadedTransaction) *FeePayer {
+	return &FeePayer{
+		Address: MustChecksum(tx.From.Hex()),
+		Type:    SenderFeePayer,
+	}
+}
+
+func isUserOperationEvent(log *types.Log) bool {
+	return len(log.Topics) == userOperationTopicCount &&
+		log.Topics[0] == userOperationTopic &&
+		len(log.Data) == userOperationDataLength
+}
+
+// newEntryPoints returns the set of entryPoints. If
+// entryPoints is empty, nil is returned (user operations
+// are not decoded).
+func newEntryPoints(entryPoints []common.Address) map[common.Address]struct{} {
+	if len(entryPoints) == 0 {
+		return nil
+	}
+
+	set := make(map[common.Address]struct{}, len(entryPoints))
+	for _, entryPoint := range entryPoints {
+		set[entryPoint] = struct{}{}
+	}
+
+	return set
+}
+
+// userOperationOps returns a USER_OPERATION operation for every
+// ERC-4337 user operation executed by tx through a configured
+// EntryPoint. The operation is attributed to the account paying
+// for the user operation (its paymaster, if any, or else the smart
+// account) and fails if the user operation reverted. User operations
+// are paid from EntryPoint deposits, not balances, so the operations
+// do not have an amount: the actual gas cost is populated in the
+// operation metadata.
+func (ec *Client) userOperationOps(tx *loadedTransaction, startIndex int) []*RosettaTypes.Operation {
+	var ops []*RosettaTypes.Operation
+	if ec.entryPoints == nil || tx.Receipt == nil {
+		return ops
+	}
+
+	for _, log := range tx.Receipt.Logs {
+		if _, ok := ec.entryPoints[log.Address]; !ok || !isUserOperationEvent(log) {
+			continue
+		}
+
+		sender := common.BytesToAddress(log.Topics[2].Bytes())
+		paymaster := common.BytesToAddress(log.Topics[3].Bytes())
+		nonce := new(big.Int).SetBytes(log.Data[:32])
+		success := new(big.Int).SetBytes(log.Data[32:64]).Sign() != 0
+		actualGasCost := new(big.Int).SetBytes(log.Data[64:96])
+		actualGasUsed := new(big.Int).SetBytes(log.Data[96:])
+
+		status := SuccessStatus
+		if !success {
+			status = FailureStatus
+		}
+
+		payer, payerType := sender, AccountFeePayer
+		metadata := map[string]interface{}{
+			"user_op_hash":    log.Topics[1].Hex(),
+			"entry_point":     MustChecksum(log.Address.Hex()),
+			"sender":          MustChecksum(sender.Hex()),
+			"nonce":           nonce.String(),
+			"actual_gas_cost": actualGasCost.String(),
+			"actual_gas_used": actualGasUsed.String(),
+			"bundler":         MustChecksum(tx.From.Hex()),
+			"log_index":       log.Index,
+		}
+		if paymaster != (common.Address{}) {
+			payer, payerType = paymaster, PaymasterFeePayer
+			metadata["paymaster"] = MustChecksum(paymaster.Hex())
+		}
+		metadata[FeePayerMetadataKey] = payerType
+
+		ops = append(ops, &RosettaTypes.Operation{
+			OperationIdentifier: &RosettaTypes.OperationIdentifier{
+				Index: int64(len(ops) + startIndex),
+			},
+			Type:   UserOperationOpType,
+			Status: RosettaTypes.String(status),
+			Account: &RosettaTypes.AccountIdentifier{
+				Address: MustChecksum(payer.Hex()),
+			},
+			Metadata: metadata,
+		})
+	}
+
+	return ops
+}
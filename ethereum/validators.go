@@ -0,0 +1,323 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// ValidatorSetMethod is the /call method used to fetch
+	// the active validators at a given block.
+	ValidatorSetMethod = "validator_set"
+
+	// ValidatorStakeMethod is the /call method used to fetch
+	// the stake delegated to a validator at a given block.
+	ValidatorStakeMethod = "validator_stake"
+
+	// ValidatorAPRInputsMethod is the /call method used to
+	// fetch everything needed to compute validator APRs at
+	// a given block.
+	ValidatorAPRInputsMethod = "validator_apr_inputs"
+)
+
+// stakingABI contains the subset of the ValidatorSet,
+// CandidateHub, and PledgeAgent interfaces used to report
+// validator stake.
+const stakingABI = `[
+	{"type":"function","name":"currentValidatorSet","stateMutability":"view","inputs":[{"name":"","type":"uint256"}],"outputs":[{"name":"operateAddress","type":"address"},{"name":"consensusAddress","type":"address"},{"name":"feeAddress","type":"address"},{"name":"commissionThousandths","type":"uint256"},{"name":"income","type":"uint256"}]},
+	{"type":"function","name":"getCandidates","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address[]"}]},
+	{"type":"function","name":"agentsMap","stateMutability":"view","inputs":[{"name":"","type":"address"}],"outputs":[{"name":"totalDeposit","type":"uint256"},{"name":"power","type":"uint256"},{"name":"coin","type":"uint256"}]}
+]`
+
+var stakingContracts = mustParseABI(stakingABI)
+
+// ValidatorSetInput is the input to the call methods
+// "validator_set" and "validator_apr_inputs".
+type ValidatorSetInput struct {
+	BlockIndex int64  `json:"index,omitempty"`
+	BlockHash  string `json:"hash,omitempty"`
+}
+
+// ValidatorStakeInput is the input to the call
+// method "validator_stake".
+type ValidatorStakeInput struct {
+	BlockIndex int64  `json:"index,omitempty"`
+	BlockHash  string `json:"hash,omitempty"`
+	Validator  string `json:"validator"`
+}
+
+// Validator is an active validator of the ValidatorSet.
+type Validator struct {
+	Operator              string `json:"operator"`
+	Consensus             string `json:"consensus"`
+	FeeAddress            string `json:"fee_address"`
+	CommissionThousandths int64  `json:"commission_thousandths"`
+	Income                string `json:"income"`
+}
+
+// ValidatorSet is the result of the call method "validator_set".
+type ValidatorSet struct {
+	BlockIdentifier *RosettaTypes.BlockIdentifier `json:"block_identifier"`
+	Round           int64                         `json:"round"`
+	Validators      []*Validator                  `json:"validators"`
+}
+
+// ValidatorStake is the result of the call method "validator_stake".
+type ValidatorStake struct {
+	BlockIdentifier *RosettaTypes.BlockIdentifier `json:"block_identifier"`
+	Validator       string                        `json:"validator"`
+	TotalDeposit    string                        `json:"total_deposit"`
+	Power           string                        `json:"power"`
+	Coin            string                        `json:"coin"`
+}
+
+// ValidatorAPRInput is an active validator along
+// with the stake delegated to it.
+type ValidatorAPRInput struct {
+	*Validator
+	TotalDeposit string `json:"total_deposit"`
+}
+
+// ValidatorAPRInputs is the result of the call
+// method "validator_apr_inputs".
+type ValidatorAPRInputs struct {
+	BlockIdentifier             *RosettaTypes.BlockIdentifier `json:"block_identifier"`
+	Round                       int64                         `json:"round"`
+	BlockReward                 string                        `json:"block_reward"`
+	BlockRewardIncentivePercent int64                         `json:"block_reward_incentive_percent"`
+	Validators                  []*ValidatorAPRInput          `json:"validators"`
+}
+
+// validatorSetCall returns the active validators at the
+// requested block.
+func (ec *Client) validatorSetCall(
+	ctx context.Context,
+	params map[string]interface{},
+) (map[string]interface{}, error) {
+	var input ValidatorSetInput
+	if err := RosettaTypes.UnmarshalMap(params, &input); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCallParametersInvalid, err.Error())
+	}
+
+	header, err := ec.callHeader(ctx, input.BlockIndex, input.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	blockQuery := toBlockNumArg(header.Number)
+	round, err := ec.callContractBig(ctx, systemABI, CandidateHubContract, blockQuery, "roundTag")
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get round", err)
+	}
+
+	validators, err := ec.validators(ctx, blockQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	return marshalJSONMap(&ValidatorSet{
+		BlockIdentifier: headerIdentifier(header),
+		Round:           round.Int64(),
+		Validators:      validators,
+	})
+}
+
+// validatorStakeCall returns the stake delegated to
+// a validator at the requested block.
+func (ec *Client) validatorStakeCall(
+	ctx context.Context,
+	params map[string]interface{},
+) (map[string]interface{}, error) {
+	var input ValidatorStakeInput
+	if err := RosettaTypes.UnmarshalMap(params, &input); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCallParametersInvalid, err.Error())
+	}
+
+	if !common.IsHexAddress(input.Validator) {
+		return nil, fmt.Errorf("%w: %s is not a valid validator address", ErrCallParametersInvalid, input.Validator)
+	}
+
+	header, err := ec.callHeader(ctx, input.BlockIndex, input.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	stake, err := ec.validatorStake(ctx, toBlockNumArg(header.Number), common.HexToAddress(input.Validator))
+	if err != nil {
+		return nil, err
+	}
+	stake.BlockIdentifier = headerIdentifier(header)
+
+	return marshalJSONMap(stake)
+}
+
+// validatorAPRInputsCall returns the reward parameters and
+// the stake of every active validator at the requested block.
+func (ec *Client) validatorAPRInputsCall(
+	ctx context.Context,
+	params map[string]interface{},
+) (map[string]interface{}, error) {
+	var input ValidatorSetInput
+	if err := RosettaTypes.UnmarshalMap(params, &input); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCallParametersInvalid, err.Error())
+	}
+
+	header, err := ec.callHeader(ctx, input.BlockIndex, input.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	blockQuery := toBlockNumArg(header.Number)
+	round, err := ec.callContractBig(ctx, systemABI, CandidateHubContract, blockQuery, "roundTag")
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get round", err)
+	}
+
+	blockReward, err := ec.callContractBig(ctx, systemABI, ValidatorSetContract, blockQuery, "blockReward")
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get block reward", err)
+	}
+
+	incentivePercent, err := ec.callContractBig(
+		ctx,
+		systemABI,
+		ValidatorSetContract,
+		blockQuery,
+		"blockRewardIncentivePercent",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get block reward incentive percent", err)
+	}
+
+	validators, err := ec.validators(ctx, blockQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := make([]*ValidatorAPRInput, len(validators))
+	for i, validator := range validators {
+		stake, err := ec.validatorStake(ctx, blockQuery, common.HexToAddress(validator.Operator))
+		if err != nil {
+			return nil, err
+		}
+
+		inputs[i] = &ValidatorAPRInput{
+			Validator:    validator,
+			TotalDeposit: stake.TotalDeposit,
+		}
+	}
+
+	return marshalJSONMap(&ValidatorAPRInputs{
+		BlockIdentifier:             headerIdentifier(header),
+		Round:                       round.Int64(),
+		BlockReward:                 blockReward.String(),
+		BlockRewardIncentivePercent: incentivePercent.Int64(),
+		Validators:                  inputs,
+	})
+}
+
+// validators returns the current validator set of ValidatorSet.
+func (ec *Client) validators(ctx context.Context, blockQuery string) ([]*Validator, error) {
+	output, err := ec.callContract(ctx, systemABI, ValidatorSetContract, blockQuery, "getValidators")
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get validators", err)
+	}
+
+	var consensusAddresses []common.Address
+	if err := systemABI.UnpackIntoInterface(&consensusAddresses, "getValidators", output); err != nil {
+		return nil, fmt.Errorf("%w: unable to unpack getValidators", err)
+	}
+
+	validators := make([]*Validator, len(consensusAddresses))
+	for i := range consensusAddresses {
+		output, err := ec.callContract(
+			ctx,
+			stakingContracts,
+			ValidatorSetContract,
+			blockQuery,
+			"currentValidatorSet",
+			big.NewInt(int64(i)),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to get validator %d", err, i)
+		}
+
+		var validator struct {
+			OperateAddress        common.Address
+			ConsensusAddress      common.Address
+			FeeAddress            common.Address
+			CommissionThousandths *big.Int
+			Income                *big.Int
+		}
+		if err := stakingContracts.UnpackIntoInterface(&validator, "currentValidatorSet", output); err != nil {
+			return nil, fmt.Errorf("%w: unable to unpack currentValidatorSet", err)
+		}
+
+		validators[i] = &Validator{
+			Operator:              MustChecksum(validator.OperateAddress.Hex()),
+			Consensus:             MustChecksum(validator.ConsensusAddress.Hex()),
+			FeeAddress:            MustChecksum(validator.FeeAddress.Hex()),
+			CommissionThousandths: validator.CommissionThousandths.Int64(),
+			Income:                validator.Income.String(),
+		}
+	}
+
+	return validators, nil
+}
+
+// validatorStake returns the stake PledgeAgent
+// attributes to the validator operator.
+func (ec *Client) validatorStake(
+	ctx context.Context,
+	blockQuery string,
+	operator common.Address,
+) (*ValidatorStake, error) {
+	output, err := ec.callContract(ctx, stakingContracts, PledgeAgentContract, blockQuery, "agentsMap", operator)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get stake of %s", err, operator.Hex())
+	}
+
+	var agent struct {
+		TotalDeposit *big.Int
+		Power        *big.Int
+		Coin         *big.Int
+	}
+	if err := stakingContracts.UnpackIntoInterface(&agent, "agentsMap", output); err != nil {
+		return nil, fmt.Errorf("%w: unable to unpack agentsMap", err)
+	}
+
+	return &ValidatorStake{
+		Validator:    MustChecksum(operator.Hex()),
+		TotalDeposit: agent.TotalDeposit.String(),
+		Power:        agent.Power.String(),
+		Coin:         agent.Coin.String(),
+	}, nil
+}
+
+// headerIdentifier returns the *RosettaTypes.BlockIdentifier
+// of header.
+func headerIdentifier(header *types.Header) *RosettaTypes.BlockIdentifier {
+	return &RosettaTypes.BlockIdentifier{
+		Hash:  header.Hash().Hex(),
+		Index: header.Number.Int64(),
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// watchlist is the set of watched addresses used to pre-screen
+// transactions in filtered block mode.
+type watchlist struct {
+	addresses map[common.Address]struct{}
+
+	// bloom contains every watched address (both as a log
+	// address and as an indexed topic) so that most receipts
+	// can be rejected without testing each address.
+	bloom types.Bloom
+}
+
+// newWatchlist returns a *watchlist of addresses. If
+// addresses is empty, nil is returned (filtered block
+// mode is disabled).
+func newWatchlist(addresses []common.Address) *watchlist {
+	if len(addresses) == 0 {
+		return nil
+	}
+
+	w := &watchlist{
+		addresses: make(map[common.Address]struct{}, len(addresses)),
+	}
+	for _, address := range addresses {
+		w.addresses[address] = struct{}{}
+		w.bloom.Add(address.Bytes())
+		w.bloom.Add(address.Hash().Bytes())
+	}
+
+	return w
+}
+
+// watched returns true if address is watched.
+func (w *watchlist) watched(address *common.Address) bool {
+	if address == nil {
+		return false
+	}
+
+	_, ok := w.addresses[*address]
+	return ok
+}
+
+// mayTouch returns false if tx cannot touch any watched address.
+// A transaction may touch a watched address if it is sent from
+// or to the address, or if its logs bloom contains the address
+// (as the address of a log or as an indexed topic). Transfers
+// made by contracts to a watched address that are not logged
+// with the address are not detected.
+func (w *watchlist) mayTouch(tx *loadedTransaction) bool {
+	if w.watched(tx.From) || w.watched(tx.Transaction.To()) {
+		return true
+	}
+
+	if tx.Receipt == nil {
+		return true
+	}
+
+	// Receipts that do not share any bit with the
+	// watchlist bloom cannot contain a watched address.
+	bloom := tx.Receipt.Bloom
+	intersects := false
+	for i := range bloom {
+		if bloom[i]&w.bloom[i] != 0 {
+			intersects = true
+			break
+		}
+	}
+	if !intersects {
+		return false
+	}
+
+	for address := range w.addresses {
+		if bloom.Test(address.Bytes()) || bloom.Test(address.Hash().Bytes()) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,55 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+// ZeroValueOperations determines how trace
+// operations that do not move CORE are emitted.
+type ZeroValueOperations string
+
+const (
+	// SkipZeroValueOperations skips zero-value calls, except
+	// the inner transactions of Safe executions. Zero-value
+	// CREATE and SELFDESTRUCT operations are emitted without
+	// an amount. It is the default.
+	SkipZeroValueOperations ZeroValueOperations = "skip"
+
+	// SuppressZeroValueOperations skips all zero-value trace
+	// operations, so every trace operation has an amount.
+	SuppressZeroValueOperations ZeroValueOperations = "suppress"
+
+	// LabelZeroValueOperations emits all zero-value trace
+	// operations (including calls) without an amount and
+	// with the ZeroValueSubtype.
+	LabelZeroValueOperations ZeroValueOperations = "label"
+
+	// ZeroValueSubtype is the subtype of zero-value trace
+	// operations with LabelZeroValueOperations. The subtypes
+	// of system accounts (see labelOperations) take precedence.
+	ZeroValueSubtype = "ZERO_VALUE"
+)
+
+// includeZeroValue returns true if a zero-value trace of
+// type traceType is emitted. safe is true for the inner
+// transaction of a Safe execution.
+func (z ZeroValueOperations) includeZeroValue(traceType string, safe bool) bool {
+	switch z {
+	case SuppressZeroValueOperations:
+		return false
+	case LabelZeroValueOperations:
+		return true
+	default:
+		return safe || !CallType(traceType)
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fees computes the gas and fees of transactions
+// according to the hardforks active at a given block.
+package fees
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// ErrGasBelowIntrinsic is returned when a transaction
+// uses (or is allotted) less gas than its intrinsic gas.
+var ErrGasBelowIntrinsic = errors.New("gas below intrinsic gas")
+
+// Fee is the fee paid by a transaction.
+type Fee struct {
+	// Amount is the total fee paid by the sender.
+	Amount *big.Int
+
+	// Burned is the portion of Amount that is burned. It is
+	// nil if the block does not have a base fee (pre-EIP-1559).
+	Burned *big.Int
+}
+
+// rules returns the hardforks active at number. When number
+// is nil (i.e. the block a transaction will be included in is
+// not known), every hardfork scheduled in config is active.
+func rules(config *params.ChainConfig, number *big.Int) params.Rules {
+	if number == nil {
+		number = new(big.Int).SetUint64(math.MaxUint64)
+	}
+
+	return config.Rules(number, false)
+}
+
+// IntrinsicGas returns the gas charged to tx before any
+// execution takes place at block number.
+func IntrinsicGas(
+	config *params.ChainConfig,
+	number *big.Int,
+	tx *types.Transaction,
+) (uint64, error) {
+	active := rules(config, number)
+	gas, err := core.IntrinsicGas(
+		tx.Data(),
+		tx.AccessList(),
+		tx.To() == nil,
+		active.IsHomestead,
+		active.IsIstanbul,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%w: unable to compute intrinsic gas of %s", err, tx.Hash().Hex())
+	}
+
+	return gas, nil
+}
+
+// CheckGas returns an error if gas is below the
+// intrinsic gas of tx at block number.
+func CheckGas(
+	config *params.ChainConfig,
+	number *big.Int,
+	tx *types.Transaction,
+	gas uint64,
+) error {
+	intrinsic, err := IntrinsicGas(config, number, tx)
+	if err != nil {
+		return err
+	}
+
+	if gas < intrinsic {
+		return fmt.Errorf(
+			"%w: %d < %d for %s",
+			ErrGasBelowIntrinsic,
+			gas,
+			intrinsic,
+			tx.Hash().Hex(),
+		)
+	}
+
+	return nil
+}
+
+// EffectiveGasPrice returns the price of gas charged to tx
+// when included in a block with baseFee. baseFee is nil
+// before EIP-1559 is active.
+func EffectiveGasPrice(tx *types.Transaction, baseFee *big.Int) (*big.Int, error) {
+	if tx.Type() != types.DynamicFeeTxType {
+		return tx.GasPrice(), nil
+	}
+	if baseFee == nil {
+		return nil, fmt.Errorf("dynamic fee transaction %s in block without base fee", tx.Hash().Hex())
+	}
+
+	// For EIP-1559 the gas price is determined by the base fee & miner tip instead
+	// of the tx-specified gas price.
+	tip, err := tx.EffectiveGasTip(baseFee)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).Add(tip, baseFee), nil
+}
+
+// Calculate returns the fee paid by tx when it uses gasUsed
+// in a block with baseFee. The base fee of a block header is
+// only set once EIP-1559 is active, so it determines whether
+// any of the fee is burned.
+//
+// gasUsed must be the gas used reported in the receipt of tx,
+// which is net of gas refunds (SSTORE clears and, before London,
+// SELFDESTRUCT). The gas used reported by a trace is computed
+// before refunds are applied and overstates the fee.
+func Calculate(tx *types.Transaction, gasUsed uint64, baseFee *big.Int) (*Fee, error) {
+	gasPrice, err := EffectiveGasPrice(tx, baseFee)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failure getting effective gas price", err)
+	}
+
+	used := new(big.Int).SetUint64(gasUsed)
+	fee := &Fee{
+		Amount: new(big.Int).Mul(used, gasPrice),
+	}
+	if baseFee != nil { // EIP-1559
+		fee.Burned = new(big.Int).Mul(used, baseFee)
+	}
+
+	return fee, nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fees
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/assert"
+)
+
+var recipient = common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+
+func TestIntrinsicGas(t *testing.T) {
+	// 2 zero bytes and 2 non-zero bytes
+	data := []byte{0x00, 0x01, 0x00, 0x02}
+	call := types.NewTransaction(0, recipient, big.NewInt(1), 100000, big.NewInt(1), data)
+	create := types.NewContractCreation(0, big.NewInt(0), 100000, big.NewInt(1), data)
+
+	tests := map[string]struct {
+		number *big.Int
+		tx     *types.Transaction
+		gas    uint64
+	}{
+		"frontier call": {
+			number: big.NewInt(0),
+			tx:     call,
+			gas:    21000 + 2*4 + 2*68,
+		},
+		"frontier create": {
+			number: big.NewInt(0),
+			tx:     create,
+			gas:    21000 + 2*4 + 2*68,
+		},
+		"homestead create": {
+			number: params.MainnetChainConfig.HomesteadBlock,
+			tx:     create,
+			gas:    53000 + 2*4 + 2*68,
+		},
+		"istanbul call": {
+			number: params.MainnetChainConfig.IstanbulBlock,
+			tx:     call,
+			gas:    21000 + 2*4 + 2*16,
+		},
+		"latest call": {
+			tx:  call,
+			gas: 21000 + 2*4 + 2*16,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gas, err := IntrinsicGas(params.MainnetChainConfig, test.number, test.tx)
+			assert.NoError(t, err)
+			assert.Equal(t, test.gas, gas)
+		})
+	}
+}
+
+func TestCheckGas(t *testing.T) {
+	tx := types.NewTransaction(0, recipient, big.NewInt(1), 21000, big.NewInt(1), nil)
+
+	assert.NoError(t, CheckGas(params.MainnetChainConfig, nil, tx, 21000))
+	err := CheckGas(params.MainnetChainConfig, nil, tx, 20999)
+	assert.True(t, errors.Is(err, ErrGasBelowIntrinsic))
+}
+
+func TestCalculate(t *testing.T) {
+	legacy := types.NewTransaction(0, recipient, big.NewInt(1), 21000, big.NewInt(10), nil)
+	dynamic := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		To:        &recipient,
+		Gas:       21000,
+		GasTipCap: big.NewInt(2),
+		GasFeeCap: big.NewInt(10),
+	})
+
+	tests := map[string]struct {
+		tx      *types.Transaction
+		baseFee *big.Int
+		fee     *Fee
+		err     bool
+	}{
+		"legacy": {
+			tx:  legacy,
+			fee: &Fee{Amount: big.NewInt(210000)},
+		},
+		"legacy with base fee": {
+			tx:      legacy,
+			baseFee: big.NewInt(7),
+			fee: &Fee{
+				Amount: big.NewInt(210000),
+				Burned: big.NewInt(147000),
+			},
+		},
+		"dynamic": {
+			tx:      dynamic,
+			baseFee: big.NewInt(7),
+			fee: &Fee{
+				Amount: big.NewInt(189000),
+				Burned: big.NewInt(147000),
+			},
+		},
+		"dynamic capped tip": {
+			tx:      dynamic,
+			baseFee: big.NewInt(9),
+			fee: &Fee{
+				Amount: big.NewInt(210000),
+				Burned: big.NewInt(189000),
+			},
+		},
+		"dynamic without base fee": {
+			tx:  dynamic,
+			err: true,
+		},
+		"dynamic below base fee": {
+			tx:      dynamic,
+			baseFee: big.NewInt(11),
+			err:     true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			fee, err := Calculate(test.tx, 21000, test.baseFee)
+			if test.err {
+				assert.Error(t, err)
+				assert.Nil(t, fee)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.fee, fee)
+		})
+	}
+}
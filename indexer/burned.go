@@ -0,0 +1,122 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexer
+
+import (
+	"math/big"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+var burnedKey = []byte("burned")
+
+// BurnedSupply is the CORE burned up to Block: the fees burned
+// by transactions (the base fee) and the net amount sent to the
+// Burn system contract. Indexes created before burns were tracked
+// only count burns from FeesSince and ContractSince.
+type BurnedSupply struct {
+	Block    *types.BlockIdentifier `json:"block_identifier,omitempty"`
+	Fees     *big.Int               `json:"fees"`
+	Contract *big.Int               `json:"contract"`
+
+	FeesSince     int64 `json:"fees_since"`
+	ContractSince int64 `json:"contract_since"`
+}
+
+// Total returns the total CORE burned.
+func (b *BurnedSupply) Total() *big.Int {
+	return new(big.Int).Add(b.Fees, b.Contract)
+}
+
+// Burned returns the CORE burned up to the watermark.
+func (i *Indexer) Burned() (*BurnedSupply, error) {
+	burned := &BurnedSupply{}
+	found, err := i.get(burnedKey, burned)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		// Burns of an index that already has blocks
+		// are only counted from the next block.
+		watermark, err := i.Watermark()
+		if err != nil {
+			return nil, err
+		}
+		if watermark != nil {
+			burned.Block = watermark
+			burned.FeesSince = watermark.Index + 1
+			burned.ContractSince = watermark.Index + 1
+		}
+	}
+
+	if burned.Fees == nil {
+		burned.Fees = new(big.Int)
+	}
+	if burned.Contract == nil {
+		burned.Contract = new(big.Int)
+	}
+
+	return burned, nil
+}
+
+// addBurns adds the CORE burned by tx to burned. Fee operations
+// balance except for the burned fee, so the burned fee is the
+// negated sum of the fee operations.
+func addBurns(burned *BurnedSupply, tx *types.Transaction) {
+	fees := new(big.Int)
+	for _, op := range tx.Operations {
+		value, ok := nativeAmount(op)
+		if !ok {
+			continue
+		}
+
+		if op.Type == ethereum.FeeOpType {
+			fees.Sub(fees, value)
+		}
+
+		if op.Account != nil &&
+			common.IsHexAddress(op.Account.Address) &&
+			common.HexToAddress(op.Account.Address) == ethereum.BurnContract {
+			burned.Contract.Add(burned.Contract, value)
+		}
+	}
+
+	burned.Fees.Add(burned.Fees, fees)
+}
+
+// backfillBurnContract counts the CORE sent to the Burn contract
+// before burns were tracked, using the summary of the contract.
+func backfillBurnContract(i *Indexer, batch ethdb.Batch, address common.Address, summary *AccountSummary) error {
+	if address != ethereum.BurnContract {
+		return nil
+	}
+
+	burned, err := i.Burned()
+	if err != nil {
+		return err
+	}
+
+	// The summary is updated with the burns of every block, so it
+	// includes the burns counted since the index was upgraded.
+	burned.Contract = new(big.Int).Sub(summary.TotalReceived, summary.TotalSent)
+	burned.ContractSince = 0
+
+	return putJSON(batch, burnedKey, burned)
+}
@@ -0,0 +1,123 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexer
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/indexer"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/stretchr/testify/assert"
+)
+
+func fee(address string, value string) *types.Operation {
+	o := op(address, value, ethereum.SuccessStatus)
+	o.Type = ethereum.FeeOpType
+	return o
+}
+
+func TestBurned(t *testing.T) {
+	i := New(memorydb.New(), &mocks.Client{}, nil, false)
+	burn := ethereum.BurnContract.Hex()
+
+	burned, err := i.Burned()
+	assert.NoError(t, err)
+	assert.Nil(t, burned.Block)
+	assert.Equal(t, int64(0), burned.Total().Int64())
+
+	assert.NoError(t, i.IndexBlock(block(0, &types.Transaction{
+		Operations: []*types.Operation{
+			// 10 of the 30 paid in fees is burned.
+			fee(sender, "-20"),
+			fee(miner, "20"),
+			fee(sender, "-10"),
+			op(sender, "-100", ethereum.SuccessStatus),
+			op(burn, "100", ethereum.SuccessStatus),
+		},
+	})))
+	assert.NoError(t, i.IndexBlock(block(1, &types.Transaction{
+		Operations: []*types.Operation{
+			fee(sender, "-5"),
+			op(sender, "-50", ethereum.FailureStatus),
+			op(burn, "50", ethereum.FailureStatus),
+		},
+	})))
+
+	burned, err = i.Burned()
+	assert.NoError(t, err)
+	assert.Equal(t, &BurnedSupply{
+		Block:    blockIdentifier(1),
+		Fees:     big.NewInt(15),
+		Contract: big.NewInt(100),
+	}, burned)
+	assert.Equal(t, big.NewInt(115), burned.Total())
+}
+
+func TestMigrate_Burned(t *testing.T) {
+	db := memorydb.New()
+	i := New(db, &mocks.Client{}, nil, false)
+	burn := ethereum.BurnContract.Hex()
+	assert.NoError(t, i.IndexBlock(block(0, &types.Transaction{
+		Operations: []*types.Operation{
+			fee(sender, "-10"),
+			op(sender, "-100", ethereum.SuccessStatus),
+			op(burn, "100", ethereum.SuccessStatus),
+		},
+	})))
+
+	// Rewrite the index in the layout of version 2.
+	assert.NoError(t, db.Delete(burnedKey))
+	assert.NoError(t, db.Put(schemaVersionKey, []byte("2")))
+
+	// Burns are counted from the first block indexed
+	// after the upgrade.
+	assert.NoError(t, i.IndexBlock(block(1, &types.Transaction{
+		Operations: []*types.Operation{
+			fee(sender, "-5"),
+			op(sender, "-20", ethereum.SuccessStatus),
+			op(burn, "20", ethereum.SuccessStatus),
+		},
+	})))
+	burned, err := i.Burned()
+	assert.NoError(t, err)
+	assert.Equal(t, &BurnedSupply{
+		Block:         blockIdentifier(1),
+		Fees:          big.NewInt(5),
+		Contract:      big.NewInt(20),
+		FeesSince:     1,
+		ContractSince: 1,
+	}, burned)
+
+	// The migration backfills the burns of the
+	// Burn contract from its summary.
+	migrateAll(t, i)
+	burned, err = i.Burned()
+	assert.NoError(t, err)
+	assert.Equal(t, &BurnedSupply{
+		Block:     blockIdentifier(1),
+		Fees:      big.NewInt(5),
+		Contract:  big.NewInt(120),
+		FeesSince: 1,
+	}, burned)
+
+	done, err := i.migrate(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, done)
+}
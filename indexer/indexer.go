@@ -0,0 +1,435 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/amount"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+)
+
+const (
+	// Confirmations is the number of blocks a block must be
+	// buried under before it is indexed. Only indexing confirmed
+	// blocks means the index never needs to handle reorgs.
+	Confirmations = 30
+
+	// pollInterval is how long the indexer waits
+	// before checking for new blocks once it has
+	// caught up with the chain.
+	pollInterval = 5 * time.Second
+
+	// leveldb tuning used for the index database.
+	databaseCache   = 256
+	databaseHandles = 256
+)
+
+var (
+	watermarkKey  = []byte("watermark")
+	summaryPrefix = []byte("summary/")
+
+	// ErrParentMismatch is returned when a block does not
+	// build on the last indexed block.
+	ErrParentMismatch = errors.New("block does not build on last indexed block")
+)
+
+// Client is used by the indexer to fetch blocks.
+type Client interface {
+	Status(context.Context) (
+		*types.BlockIdentifier,
+		int64,
+		*types.SyncStatus,
+		[]*types.Peer,
+		error,
+	)
+
+	Block(
+		context.Context,
+		*types.PartialBlockIdentifier,
+	) (*types.Block, error)
+
+	TokenTransfers(
+		ctx context.Context,
+		start int64,
+		end int64,
+	) ([]*ethereum.TokenTransfer, error)
+}
+
+// AccountSummary is the indexed activity of an address.
+type AccountSummary struct {
+	FirstSeen        *types.BlockIdentifier `json:"first_seen_block_identifier,omitempty"`
+	LastActivity     *types.BlockIdentifier `json:"last_activity_block_identifier,omitempty"`
+	TransactionCount int64                  `json:"transaction_count"`
+	TotalReceived    *big.Int               `json:"total_received"`
+	TotalSent        *big.Int               `json:"total_sent"`
+}
+
+// Indexer follows the canonical chain and maintains
+// a local index of confirmed blocks.
+type Indexer struct {
+	db           ethdb.KeyValueStore
+	client       Client
+	retention    *Retention
+	tokenHolders bool
+
+	lastCompaction time.Time
+}
+
+// New creates an *Indexer backed by db. If retention
+// is nil, the index grows without bound. If tokenHolders
+// is true, the holders of ERC-20 tokens are indexed too
+// (see TokenHoldings).
+func New(
+	db ethdb.KeyValueStore,
+	client Client,
+	retention *Retention,
+	tokenHolders bool,
+) *Indexer {
+	return &Indexer{
+		db:           db,
+		client:       client,
+		retention:    retention,
+		tokenHolders: tokenHolders,
+	}
+}
+
+// Open creates an *Indexer persisted in a
+// leveldb database at path.
+func Open(
+	path string,
+	client Client,
+	retention *Retention,
+	tokenHolders bool,
+) (*Indexer, error) {
+	db, err := leveldb.New(path, databaseCache, databaseHandles, "", false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to open index database %s", err, path)
+	}
+
+	i := New(db, client, retention, tokenHolders)
+	if err := i.checkSchema(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// OpenReadOnly opens the index persisted at path without
+// writing to it and checks that its schema is supported. The
+// returned *Indexer has no client, so it cannot be run.
+func OpenReadOnly(path string) (*Indexer, error) {
+	db, err := leveldb.New(path, databaseCache, databaseHandles, "", true)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to open index database %s", err, path)
+	}
+
+	i := New(db, nil, nil, false)
+	if err := i.checkSchema(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// Close closes the underlying database.
+func (i *Indexer) Close() error {
+	return i.db.Close()
+}
+
+// Run indexes confirmed blocks until ctx is canceled. If
+// the index was written by an older binary, it is migrated
+// to SchemaVersion in the background, between syncs.
+func (i *Indexer) Run(ctx context.Context) error {
+	if err := i.checkSchema(); err != nil {
+		return err
+	}
+
+	migrated := false
+	for {
+		if err := i.sync(ctx); err != nil {
+			if errors.Is(err, ErrParentMismatch) {
+				return err
+			}
+
+			if ctx.Err() == nil {
+				log.Printf("index sync failed: %s", err.Error())
+			}
+		}
+
+		if !migrated {
+			done, err := i.migrate(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("index migration failed: %s", err.Error())
+			}
+			migrated = done
+
+			// Keep backfilling without waiting for new blocks.
+			if err == nil && !done {
+				continue
+			}
+		}
+
+		if i.retention != nil && time.Since(i.lastCompaction) >= compactionInterval {
+			if err := i.compact(); err != nil {
+				log.Printf("index compaction failed: %s", err.Error())
+			}
+			i.lastCompaction = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// sync indexes all blocks that are confirmed
+// but not yet indexed.
+func (i *Indexer) sync(ctx context.Context) error {
+	head, _, _, _, err := i.client.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: unable to get chain head", err)
+	}
+
+	watermark, err := i.Watermark()
+	if err != nil {
+		return err
+	}
+
+	next := int64(0)
+	if watermark != nil {
+		next = watermark.Index + 1
+	}
+
+	for ; next <= head.Index-Confirmations; next++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		index := next
+		block, err := i.client.Block(ctx, &types.PartialBlockIdentifier{Index: &index})
+		if err != nil {
+			return fmt.Errorf("%w: unable to get block %d", err, index)
+		}
+
+		if err := i.IndexBlock(block); err != nil {
+			return fmt.Errorf("%w: unable to index block %d", err, index)
+		}
+	}
+
+	if i.tokenHolders {
+		return i.syncTokenHolders(ctx)
+	}
+
+	return nil
+}
+
+// IndexBlock adds a block to the index. The block must
+// build on the last indexed block.
+func (i *Indexer) IndexBlock(block *types.Block) error {
+	watermark, err := i.Watermark()
+	if err != nil {
+		return err
+	}
+
+	if watermark != nil &&
+		(block.BlockIdentifier.Index != watermark.Index+1 ||
+			block.ParentBlockIdentifier.Hash != watermark.Hash) {
+		return fmt.Errorf(
+			"%w: %s does not build on %s",
+			ErrParentMismatch,
+			types.PrintStruct(block.BlockIdentifier),
+			types.PrintStruct(watermark),
+		)
+	}
+
+	burned, err := i.Burned()
+	if err != nil {
+		return err
+	}
+
+	summaries := map[common.Address]*AccountSummary{}
+	batch := i.db.NewBatch()
+	for _, tx := range block.Transactions {
+		addBurns(burned, tx)
+
+		touched := map[common.Address]struct{}{}
+		for _, op := range tx.Operations {
+			if op.Account == nil || !common.IsHexAddress(op.Account.Address) {
+				continue
+			}
+
+			address := common.HexToAddress(op.Account.Address)
+			if !i.retention.indexed(address) {
+				continue
+			}
+
+			summary, ok := summaries[address]
+			if !ok {
+				summary, err = i.Summary(address)
+				if err != nil {
+					return err
+				}
+
+				summaries[address] = summary
+			}
+
+			if _, ok := touched[address]; !ok {
+				touched[address] = struct{}{}
+				summary.TransactionCount++
+			}
+
+			if summary.FirstSeen == nil {
+				summary.FirstSeen = block.BlockIdentifier
+			}
+			summary.LastActivity = block.BlockIdentifier
+
+			addAmount(summary, op)
+		}
+	}
+
+	for address, summary := range summaries {
+		value, err := json.Marshal(summary)
+		if err != nil {
+			return err
+		}
+
+		if err := batch.Put(summaryKey(address), value); err != nil {
+			return err
+		}
+
+		if err := putFirstSeen(batch, address, summary); err != nil {
+			return err
+		}
+	}
+
+	burned.Block = block.BlockIdentifier
+	if err := putJSON(batch, burnedKey, burned); err != nil {
+		return err
+	}
+
+	// The first block of an index is written
+	// in the layout of SchemaVersion.
+	if watermark == nil {
+		if err := putJSON(batch, schemaVersionKey, SchemaVersion); err != nil {
+			return err
+		}
+	}
+
+	value, err := json.Marshal(block.BlockIdentifier)
+	if err != nil {
+		return err
+	}
+	if err := batch.Put(watermarkKey, value); err != nil {
+		return err
+	}
+
+	return batch.Write()
+}
+
+// nativeAmount returns the native currency
+// amount of a successful operation.
+func nativeAmount(op *types.Operation) (*big.Int, bool) {
+	if op.Amount == nil ||
+		op.Status == nil ||
+		*op.Status != ethereum.SuccessStatus ||
+		types.Hash(op.Amount.Currency) != types.Hash(ethereum.Currency) {
+		return nil, false
+	}
+
+	value, err := amount.Parse(op.Amount.Value)
+	return value, err == nil
+}
+
+// addAmount adds the native currency amount of a
+// successful operation to the totals of summary.
+func addAmount(summary *AccountSummary, op *types.Operation) {
+	value, ok := nativeAmount(op)
+	if !ok {
+		return
+	}
+
+	if value.Sign() > 0 {
+		summary.TotalReceived = new(big.Int).Add(summary.TotalReceived, value)
+	} else {
+		summary.TotalSent = new(big.Int).Sub(summary.TotalSent, value)
+	}
+}
+
+// Watermark returns the last indexed block. If
+// no block has been indexed, nil is returned.
+func (i *Indexer) Watermark() (*types.BlockIdentifier, error) {
+	var watermark types.BlockIdentifier
+	found, err := i.get(watermarkKey, &watermark)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &watermark, nil
+}
+
+// Summary returns the indexed activity of address.
+func (i *Indexer) Summary(address common.Address) (*AccountSummary, error) {
+	summary := &AccountSummary{}
+	if _, err := i.get(summaryKey(address), summary); err != nil {
+		return nil, err
+	}
+
+	if summary.TotalReceived == nil {
+		summary.TotalReceived = new(big.Int)
+	}
+	if summary.TotalSent == nil {
+		summary.TotalSent = new(big.Int)
+	}
+
+	return summary, nil
+}
+
+func (i *Indexer) get(key []byte, output interface{}) (bool, error) {
+	has, err := i.db.Has(key)
+	if err != nil || !has {
+		return false, err
+	}
+
+	value, err := i.db.Get(key)
+	if err != nil {
+		return false, err
+	}
+
+	if err := json.Unmarshal(value, output); err != nil {
+		return false, fmt.Errorf("%w: unable to decode %s", err, string(key))
+	}
+
+	return true, nil
+}
+
+func summaryKey(address common.Address) []byte {
+	return append(append([]byte{}, summaryPrefix...), address.Bytes()...)
+}
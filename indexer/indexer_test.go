@@ -0,0 +1,181 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/indexer"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	sender    = "0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"
+	recipient = "0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d"
+	miner     = "0x0000000000000000000000000000000000001000"
+)
+
+func blockIdentifier(index int64) *types.BlockIdentifier {
+	return &types.BlockIdentifier{
+		Index: index,
+		Hash:  fmt.Sprintf("block %d", index),
+	}
+}
+
+func op(address string, value string, status string) *types.Operation {
+	return &types.Operation{
+		Type:   ethereum.CallOpType,
+		Status: types.String(status),
+		Account: &types.AccountIdentifier{
+			Address: address,
+		},
+		Amount: &types.Amount{
+			Value:    value,
+			Currency: ethereum.Currency,
+		},
+	}
+}
+
+func block(index int64, txs ...*types.Transaction) *types.Block {
+	parent := blockIdentifier(index - 1)
+	if index == 0 {
+		parent = blockIdentifier(0)
+	}
+
+	return &types.Block{
+		BlockIdentifier:       blockIdentifier(index),
+		ParentBlockIdentifier: parent,
+		Transactions:          txs,
+	}
+}
+
+func TestIndexBlock(t *testing.T) {
+	i := New(memorydb.New(), &mocks.Client{}, nil, false)
+
+	watermark, err := i.Watermark()
+	assert.NoError(t, err)
+	assert.Nil(t, watermark)
+
+	assert.NoError(t, i.IndexBlock(block(0)))
+	assert.NoError(t, i.IndexBlock(block(
+		1,
+		&types.Transaction{
+			Operations: []*types.Operation{
+				op(sender, "-21", ethereum.SuccessStatus),
+				op(miner, "21", ethereum.SuccessStatus),
+				op(sender, "-100", ethereum.SuccessStatus),
+				op(recipient, "100", ethereum.SuccessStatus),
+			},
+		},
+	)))
+	assert.NoError(t, i.IndexBlock(block(
+		2,
+		&types.Transaction{
+			Operations: []*types.Operation{
+				op(recipient, "-21", ethereum.SuccessStatus),
+				op(miner, "21", ethereum.SuccessStatus),
+				op(recipient, "-50", ethereum.FailureStatus),
+				op(sender, "50", ethereum.FailureStatus),
+			},
+		},
+	)))
+
+	watermark, err = i.Watermark()
+	assert.NoError(t, err)
+	assert.Equal(t, blockIdentifier(2), watermark)
+
+	summary, err := i.Summary(common.HexToAddress(sender))
+	assert.NoError(t, err)
+	assert.Equal(t, &AccountSummary{
+		FirstSeen:        blockIdentifier(1),
+		LastActivity:     blockIdentifier(2),
+		TransactionCount: 2,
+		TotalReceived:    big.NewInt(0),
+		TotalSent:        big.NewInt(121),
+	}, summary)
+
+	summary, err = i.Summary(common.HexToAddress(recipient))
+	assert.NoError(t, err)
+	assert.Equal(t, &AccountSummary{
+		FirstSeen:        blockIdentifier(1),
+		LastActivity:     blockIdentifier(2),
+		TransactionCount: 2,
+		TotalReceived:    big.NewInt(100),
+		TotalSent:        big.NewInt(21),
+	}, summary)
+
+	summary, err = i.Summary(common.HexToAddress(miner))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), summary.TransactionCount)
+	assert.Equal(t, big.NewInt(42), summary.TotalReceived)
+
+	// Unknown addresses have an empty summary
+	summary, err = i.Summary(common.HexToAddress("0x01"))
+	assert.NoError(t, err)
+	assert.Equal(t, &AccountSummary{
+		TotalReceived: big.NewInt(0),
+		TotalSent:     big.NewInt(0),
+	}, summary)
+
+	// Blocks must build on the watermark
+	err = i.IndexBlock(&types.Block{
+		BlockIdentifier:       blockIdentifier(3),
+		ParentBlockIdentifier: &types.BlockIdentifier{Index: 2, Hash: "other"},
+	})
+	assert.True(t, errors.Is(err, ErrParentMismatch))
+	err = i.IndexBlock(block(4))
+	assert.True(t, errors.Is(err, ErrParentMismatch))
+}
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	mockClient := &mocks.Client{}
+	i := New(memorydb.New(), mockClient, nil, false)
+
+	mockClient.On("Status", ctx).Return(
+		blockIdentifier(Confirmations+1),
+		int64(0),
+		nil,
+		nil,
+		nil,
+	).Once()
+	for index := int64(0); index <= 1; index++ {
+		blockIndex := index
+		mockClient.On(
+			"Block",
+			ctx,
+			&types.PartialBlockIdentifier{Index: &blockIndex},
+		).Return(
+			block(index),
+			nil,
+		).Once()
+	}
+
+	assert.NoError(t, i.sync(ctx))
+	watermark, err := i.Watermark()
+	assert.NoError(t, err)
+	assert.Equal(t, blockIdentifier(1), watermark)
+
+	mockClient.AssertExpectations(t)
+}
@@ -0,0 +1,170 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexer
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// compactionInterval is how often the index is pruned
+// and compacted when a retention policy is configured.
+const compactionInterval = time.Hour
+
+// Retention bounds the size of the index on long-running
+// deployments.
+type Retention struct {
+	// Blocks is the number of blocks an address is kept in the
+	// index after its last activity. Addresses that become
+	// active again after being pruned are indexed from scratch.
+	// If 0, addresses are never pruned.
+	Blocks int64
+
+	// AddressPrefixes limits the index to addresses whose
+	// lowercase hex encoding (without 0x) starts with one of
+	// the prefixes. If empty, all addresses are indexed.
+	AddressPrefixes []string
+}
+
+// ParseAddressPrefixes parses a comma-separated list of
+// hex address prefixes (with or without 0x).
+func ParseAddressPrefixes(s string) ([]string, error) {
+	prefixes := []string{}
+	for _, prefix := range strings.Split(s, ",") {
+		prefix = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(prefix), "0x"))
+		if len(prefix) == 0 {
+			continue
+		}
+
+		if len(prefix) > common.AddressLength*2 { // nolint:gomnd
+			return nil, fmt.Errorf("address prefix %s is too long", prefix)
+		}
+
+		// Pad odd-length prefixes to validate them as hex.
+		if _, err := hex.DecodeString(prefix + strings.Repeat("0", len(prefix)%2)); err != nil {
+			return nil, fmt.Errorf("%w: address prefix %s is not hex", err, prefix)
+		}
+
+		prefixes = append(prefixes, prefix)
+	}
+
+	return prefixes, nil
+}
+
+// indexed returns true if address should be indexed.
+func (r *Retention) indexed(address common.Address) bool {
+	if r == nil || len(r.AddressPrefixes) == 0 {
+		return true
+	}
+
+	encoded := hex.EncodeToString(address.Bytes())
+	for _, prefix := range r.AddressPrefixes {
+		if strings.HasPrefix(encoded, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expired returns true if summary has had no activity
+// in the last r.Blocks blocks before watermark.
+func (r *Retention) expired(summary *AccountSummary, watermark int64) bool {
+	if r == nil || r.Blocks <= 0 || summary.LastActivity == nil {
+		return false
+	}
+
+	return summary.LastActivity.Index <= watermark-r.Blocks
+}
+
+// compact removes all summaries (and token holdings) that are no longer retained
+// and compacts the underlying database to reclaim their space.
+func (i *Indexer) compact() error {
+	watermark, err := i.Watermark()
+	if err != nil || watermark == nil {
+		return err
+	}
+
+	iterator := i.db.NewIterator(summaryPrefix, nil)
+	defer iterator.Release()
+
+	batch := i.db.NewBatch()
+	pruned := 0
+	for iterator.Next() {
+		address := common.BytesToAddress(iterator.Key()[len(summaryPrefix):])
+
+		var summary AccountSummary
+		if err := json.Unmarshal(iterator.Value(), &summary); err != nil {
+			return fmt.Errorf("%w: unable to decode summary of %s", err, address.Hex())
+		}
+
+		if i.retention.indexed(address) && !i.retention.expired(&summary, watermark.Index) {
+			continue
+		}
+
+		if err := batch.Delete(append([]byte{}, iterator.Key()...)); err != nil {
+			return err
+		}
+		if summary.FirstSeen != nil {
+			if err := batch.Delete(firstSeenKey(address, &summary)); err != nil {
+				return err
+			}
+		}
+		pruned++
+	}
+	if err := iterator.Error(); err != nil {
+		return fmt.Errorf("%w: unable to iterate summaries", err)
+	}
+
+	// Token holdings are only pruned when their holder no
+	// longer matches the address prefixes, as tokens can be
+	// held for longer than the retention without activity.
+	if err := i.pruneTokenHolders(batch); err != nil {
+		return err
+	}
+
+	if err := batch.Write(); err != nil {
+		return fmt.Errorf("%w: unable to prune summaries", err)
+	}
+
+	for _, prefix := range [][]byte{summaryPrefix, firstSeenPrefix, tokenHolderPrefix} {
+		if err := i.db.Compact(prefix, prefixEnd(prefix)); err != nil {
+			return fmt.Errorf("%w: unable to compact index", err)
+		}
+	}
+
+	log.Printf("pruned %d addresses from index at block %d", pruned, watermark.Index)
+	return nil
+}
+
+// prefixEnd returns the first key after all
+// keys starting with prefix.
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for j := len(end) - 1; j >= 0; j-- {
+		if end[j] < 0xff { // nolint:gomnd
+			end[j]++
+			return end[:j+1]
+		}
+	}
+
+	return nil
+}
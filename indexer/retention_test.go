@@ -0,0 +1,123 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexer
+
+import (
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/indexer"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAddressPrefixes(t *testing.T) {
+	prefixes, err := ParseAddressPrefixes(" 0xE3a5,57b,, ")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e3a5", "57b"}, prefixes)
+
+	_, err = ParseAddressPrefixes("0xzz")
+	assert.Error(t, err)
+
+	_, err = ParseAddressPrefixes("0x" + common.Bytes2Hex(make([]byte, 21)))
+	assert.Error(t, err)
+}
+
+func TestIndexBlock_AddressPrefixes(t *testing.T) {
+	i := New(memorydb.New(), &mocks.Client{}, &Retention{
+		AddressPrefixes: []string{"e3a5"},
+	}, false)
+
+	assert.NoError(t, i.IndexBlock(block(
+		0,
+		&types.Transaction{
+			Operations: []*types.Operation{
+				op(sender, "-100", ethereum.SuccessStatus),
+				op(recipient, "100", ethereum.SuccessStatus),
+			},
+		},
+	)))
+
+	summary, err := i.Summary(common.HexToAddress(sender))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), summary.TransactionCount)
+
+	// Addresses without a matching prefix are not indexed
+	summary, err = i.Summary(common.HexToAddress(recipient))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), summary.TransactionCount)
+}
+
+func TestCompact(t *testing.T) {
+	db := memorydb.New()
+	i := New(db, &mocks.Client{}, nil, false)
+
+	assert.NoError(t, i.IndexBlock(block(
+		0,
+		&types.Transaction{
+			Operations: []*types.Operation{
+				op(sender, "-100", ethereum.SuccessStatus),
+				op(recipient, "100", ethereum.SuccessStatus),
+			},
+		},
+	)))
+	for index := int64(1); index < 10; index++ {
+		assert.NoError(t, i.IndexBlock(block(index)))
+	}
+	assert.NoError(t, i.IndexBlock(block(
+		10,
+		&types.Transaction{
+			Operations: []*types.Operation{
+				op(recipient, "-50", ethereum.SuccessStatus),
+				op(miner, "50", ethereum.SuccessStatus),
+			},
+		},
+	)))
+
+	// Prefixes and retention can be added to an existing index
+	i = New(db, &mocks.Client{}, &Retention{
+		Blocks:          10,
+		AddressPrefixes: []string{"e3a5", "57b4"},
+	}, false)
+	assert.NoError(t, i.compact())
+
+	// sender was last active 10 blocks ago
+	summary, err := i.Summary(common.HexToAddress(sender))
+	assert.NoError(t, err)
+	assert.Nil(t, summary.FirstSeen)
+
+	summary, err = i.Summary(common.HexToAddress(recipient))
+	assert.NoError(t, err)
+	assert.Equal(t, blockIdentifier(0), summary.FirstSeen)
+	assert.Equal(t, blockIdentifier(10), summary.LastActivity)
+
+	// miner does not match any prefix
+	summary, err = i.Summary(common.HexToAddress(miner))
+	assert.NoError(t, err)
+	assert.Nil(t, summary.FirstSeen)
+
+	watermark, err := i.Watermark()
+	assert.NoError(t, err)
+	assert.Equal(t, blockIdentifier(10), watermark)
+}
+
+func TestPrefixEnd(t *testing.T) {
+	assert.Equal(t, []byte("summary0"), prefixEnd([]byte("summary/")))
+	assert.Equal(t, []byte{0x02}, prefixEnd([]byte{0x01, 0xff}))
+	assert.Nil(t, prefixEnd([]byte{0xff}))
+}
@@ -0,0 +1,285 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexer
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+const (
+	// SchemaVersion is the version of the layout of the index
+	// written by this binary. Indexes created by older binaries
+	// are migrated online (see migrate).
+	SchemaVersion = 3
+
+	// migrationBatchSize is the number of records backfilled
+	// between two syncs of the index.
+	migrationBatchSize = 10000
+)
+
+var (
+	schemaVersionKey = []byte("schema_version")
+	migrationPrefix  = []byte("migration/")
+	firstSeenPrefix  = []byte("first_seen/")
+
+	// ErrSchemaTooNew is returned when the index was written
+	// by a newer binary.
+	ErrSchemaTooNew = errors.New("index schema is newer than supported")
+
+	// ErrMigrationInProgress is returned when data is requested
+	// from a part of the index that is still being backfilled.
+	ErrMigrationInProgress = errors.New("index migration in progress")
+)
+
+// migration moves the index from version-1 to version.
+// IndexBlock always writes the layout of SchemaVersion, so
+// records of blocks indexed after the upgrade are dual-written
+// in the old and the new layout, and a migration only backfills
+// the records written before the upgrade. Backfills must be
+// idempotent, as records can be written by both.
+type migration struct {
+	version     int
+	description string
+
+	// prefix is the prefix of the keys of the
+	// records that are backfilled.
+	prefix []byte
+
+	backfill func(i *Indexer, batch ethdb.Batch, key []byte, value []byte) error
+}
+
+// migrations are the migrations to SchemaVersion, in order.
+var migrations = []*migration{
+	{
+		version:     2, // nolint:gomnd
+		description: "index addresses by first seen block",
+		prefix:      summaryPrefix,
+		backfill: func(i *Indexer, batch ethdb.Batch, key []byte, value []byte) error {
+			address, summary, err := decodeSummary(key, value)
+			if err != nil {
+				return err
+			}
+
+			return putFirstSeen(batch, address, summary)
+		},
+	},
+	{
+		version:     3, // nolint:gomnd
+		description: "track burned CORE",
+		prefix:      summaryPrefix,
+		backfill: func(i *Indexer, batch ethdb.Batch, key []byte, value []byte) error {
+			address, summary, err := decodeSummary(key, value)
+			if err != nil {
+				return err
+			}
+
+			return backfillBurnContract(i, batch, address, summary)
+		},
+	},
+}
+
+func decodeSummary(key []byte, value []byte) (common.Address, *AccountSummary, error) {
+	summary := &AccountSummary{}
+	if err := json.Unmarshal(value, summary); err != nil {
+		return common.Address{}, nil, fmt.Errorf("%w: unable to decode %s", err, string(key))
+	}
+	if summary.TotalReceived == nil {
+		summary.TotalReceived = new(big.Int)
+	}
+	if summary.TotalSent == nil {
+		summary.TotalSent = new(big.Int)
+	}
+
+	return common.BytesToAddress(key[len(summaryPrefix):]), summary, nil
+}
+
+// SchemaVersion returns the version of the layout of the index.
+// Indexes without a version were created before versioning and
+// are version 1, unless they are empty.
+func (i *Indexer) SchemaVersion() (int, error) {
+	var version int
+	found, err := i.get(schemaVersionKey, &version)
+	if err != nil || found {
+		return version, err
+	}
+
+	watermark, err := i.Watermark()
+	if err != nil {
+		return 0, err
+	}
+	if watermark == nil {
+		return SchemaVersion, nil
+	}
+
+	return 1, nil
+}
+
+// checkSchema returns an error if the index
+// cannot be read by this binary.
+func (i *Indexer) checkSchema() error {
+	version, err := i.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	if version > SchemaVersion {
+		return fmt.Errorf(
+			"%w: index is version %d but this binary supports up to version %d",
+			ErrSchemaTooNew,
+			version,
+			SchemaVersion,
+		)
+	}
+
+	return nil
+}
+
+// migrate backfills up to migrationBatchSize records of the
+// next pending migration and returns true once the index is
+// at SchemaVersion. The position of the backfill is written
+// with the records, so a migration resumes where it stopped.
+func (i *Indexer) migrate(ctx context.Context) (bool, error) {
+	version, err := i.SchemaVersion()
+	if err != nil {
+		return false, err
+	}
+	if version >= SchemaVersion {
+		return true, nil
+	}
+
+	m := migrations[version-1]
+	cursorKey := append(append([]byte{}, migrationPrefix...), strconv.Itoa(m.version)...)
+	var cursor []byte
+	if _, err := i.get(cursorKey, &cursor); err != nil {
+		return false, err
+	}
+	if cursor == nil {
+		log.Printf("migrating index to version %d: %s", m.version, m.description)
+	}
+
+	start := []byte{}
+	if cursor != nil {
+		start = append(cursor[len(m.prefix):], 0)
+	}
+	iterator := i.db.NewIterator(m.prefix, start)
+	defer iterator.Release()
+
+	batch := i.db.NewBatch()
+	count := 0
+	for count < migrationBatchSize && iterator.Next() {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+
+		if err := m.backfill(i, batch, iterator.Key(), iterator.Value()); err != nil {
+			return false, fmt.Errorf("%w: unable to migrate index to version %d", err, m.version)
+		}
+		cursor = append([]byte{}, iterator.Key()...)
+		count++
+	}
+	if err := iterator.Error(); err != nil {
+		return false, fmt.Errorf("%w: unable to iterate index", err)
+	}
+
+	if count < migrationBatchSize {
+		if err := batch.Delete(cursorKey); err != nil {
+			return false, err
+		}
+		if err := putJSON(batch, schemaVersionKey, m.version); err != nil {
+			return false, err
+		}
+		log.Printf("migrated index to version %d", m.version)
+	} else if err := putJSON(batch, cursorKey, cursor); err != nil {
+		return false, err
+	}
+
+	if err := batch.Write(); err != nil {
+		return false, fmt.Errorf("%w: unable to migrate index to version %d", err, m.version)
+	}
+
+	return m.version >= SchemaVersion && count < migrationBatchSize, nil
+}
+
+// FirstSeen returns the addresses first seen in
+// the blocks from start to end (inclusive).
+func (i *Indexer) FirstSeen(start int64, end int64) ([]common.Address, error) {
+	version, err := i.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	if version < 2 { // nolint:gomnd
+		return nil, ErrMigrationInProgress
+	}
+
+	iterator := i.db.NewIterator(firstSeenPrefix, firstSeenIndex(start))
+	defer iterator.Release()
+
+	addresses := []common.Address{}
+	for iterator.Next() {
+		key := iterator.Key()[len(firstSeenPrefix):]
+		if int64(binary.BigEndian.Uint64(key)) > end {
+			break
+		}
+
+		addresses = append(addresses, common.BytesToAddress(key[8:]))
+	}
+	if err := iterator.Error(); err != nil {
+		return nil, fmt.Errorf("%w: unable to iterate first seen addresses", err)
+	}
+
+	return addresses, nil
+}
+
+// putFirstSeen indexes address by the block it was first
+// seen in. Addresses that were never seen are skipped.
+func putFirstSeen(batch ethdb.Batch, address common.Address, summary *AccountSummary) error {
+	if summary.FirstSeen == nil {
+		return nil
+	}
+
+	return batch.Put(firstSeenKey(address, summary), []byte{})
+}
+
+// firstSeenIndex encodes index so that keys
+// are sorted by block.
+func firstSeenIndex(index int64) []byte {
+	encoded := make([]byte, 8) // nolint:gomnd
+	binary.BigEndian.PutUint64(encoded, uint64(index))
+	return encoded
+}
+
+func firstSeenKey(address common.Address, summary *AccountSummary) []byte {
+	key := append(append([]byte{}, firstSeenPrefix...), firstSeenIndex(summary.FirstSeen.Index)...)
+	return append(key, address.Bytes()...)
+}
+
+func putJSON(batch ethdb.Batch, key []byte, value interface{}) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return batch.Put(key, encoded)
+}
@@ -0,0 +1,212 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/indexer"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/stretchr/testify/assert"
+)
+
+func transfer(from string, to string) *types.Transaction {
+	return &types.Transaction{
+		Operations: []*types.Operation{
+			op(from, "-100", ethereum.SuccessStatus),
+			op(to, "100", ethereum.SuccessStatus),
+		},
+	}
+}
+
+// migrateAll runs the migrations of i to completion.
+func migrateAll(t *testing.T, i *Indexer) {
+	for {
+		done, err := i.migrate(context.Background())
+		assert.NoError(t, err)
+		if err != nil || done {
+			return
+		}
+	}
+}
+
+func TestSchemaVersion_New(t *testing.T) {
+	i := New(memorydb.New(), &mocks.Client{}, nil, false)
+
+	version, err := i.SchemaVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, SchemaVersion, version)
+
+	assert.NoError(t, i.IndexBlock(block(0, transfer(sender, recipient))))
+	assert.NoError(t, i.IndexBlock(block(1, transfer(recipient, miner))))
+
+	version, err = i.SchemaVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, SchemaVersion, version)
+
+	addresses, err := i.FirstSeen(1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []common.Address{common.HexToAddress(miner)}, addresses)
+
+	addresses, err = i.FirstSeen(0, 10)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []common.Address{
+		common.HexToAddress(sender),
+		common.HexToAddress(recipient),
+		common.HexToAddress(miner),
+	}, addresses)
+
+	done, err := i.migrate(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, done)
+}
+
+func TestMigrate(t *testing.T) {
+	db := memorydb.New()
+	i := New(db, &mocks.Client{}, nil, false)
+	assert.NoError(t, i.IndexBlock(block(0, transfer(sender, recipient))))
+
+	// Rewrite the index in the layout of version 1.
+	assert.NoError(t, db.Delete(schemaVersionKey))
+	iterator := db.NewIterator(firstSeenPrefix, nil)
+	for iterator.Next() {
+		assert.NoError(t, db.Delete(append([]byte{}, iterator.Key()...)))
+	}
+	iterator.Release()
+
+	version, err := i.SchemaVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version)
+	_, err = i.FirstSeen(0, 10)
+	assert.True(t, errors.Is(err, ErrMigrationInProgress))
+
+	// Blocks indexed during the migration are dual-written.
+	assert.NoError(t, i.IndexBlock(block(1, transfer(recipient, miner))))
+	summary, err := i.Summary(common.HexToAddress(miner))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), summary.TransactionCount)
+
+	migrateAll(t, i)
+
+	version, err = i.SchemaVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, SchemaVersion, version)
+
+	addresses, err := i.FirstSeen(0, 0)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []common.Address{
+		common.HexToAddress(sender),
+		common.HexToAddress(recipient),
+	}, addresses)
+	addresses, err = i.FirstSeen(1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []common.Address{common.HexToAddress(miner)}, addresses)
+
+	// Existing data is unchanged.
+	summary, err = i.Summary(common.HexToAddress(recipient))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), summary.TransactionCount)
+}
+
+func TestMigrate_Resume(t *testing.T) {
+	db := memorydb.New()
+	i := New(db, &mocks.Client{}, nil, false)
+	assert.NoError(t, i.IndexBlock(block(0, transfer(sender, recipient))))
+	assert.NoError(t, db.Delete(schemaVersionKey))
+	assert.NoError(t, db.Delete(firstSeenKey(common.HexToAddress(recipient), &AccountSummary{
+		FirstSeen: blockIdentifier(0),
+	})))
+
+	// The migration already backfilled up to the summary of
+	// sender (the summary of recipient sorts before it), so
+	// the summary of recipient is not backfilled again.
+	cursor, err := json.Marshal(summaryKey(common.HexToAddress(sender)))
+	assert.NoError(t, err)
+	assert.NoError(t, db.Put([]byte("migration/2"), cursor))
+
+	migrateAll(t, i)
+
+	addresses, err := i.FirstSeen(0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []common.Address{common.HexToAddress(sender)}, addresses)
+
+	has, err := db.Has([]byte("migration/2"))
+	assert.NoError(t, err)
+	assert.False(t, has)
+}
+
+func TestOpen_SchemaTooNew(t *testing.T) {
+	dir, err := ioutil.TempDir("", "index")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "index")
+	db, err := leveldb.New(path, databaseCache, databaseHandles, "", false)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Put(schemaVersionKey, []byte(strconv.Itoa(SchemaVersion+1))))
+	assert.NoError(t, db.Close())
+
+	i, err := Open(path, &mocks.Client{}, nil, false)
+	assert.Nil(t, i)
+	assert.True(t, errors.Is(err, ErrSchemaTooNew))
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "index")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// A missing index is not created.
+	path := filepath.Join(dir, "index")
+	i, err := OpenReadOnly(path)
+	assert.Nil(t, i)
+	assert.Error(t, err)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	db, err := leveldb.New(path, databaseCache, databaseHandles, "", false)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Put(schemaVersionKey, []byte(strconv.Itoa(SchemaVersion))))
+	assert.NoError(t, db.Close())
+
+	i, err = OpenReadOnly(path)
+	assert.NoError(t, err)
+	version, err := i.SchemaVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, SchemaVersion, version)
+	assert.Error(t, i.db.Put([]byte("key"), []byte("value")))
+	assert.NoError(t, i.Close())
+
+	db, err = leveldb.New(path, databaseCache, databaseHandles, "", false)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Put(schemaVersionKey, []byte(strconv.Itoa(SchemaVersion+1))))
+	assert.NoError(t, db.Close())
+
+	i, err = OpenReadOnly(path)
+	assert.Nil(t, i)
+	assert.True(t, errors.Is(err, ErrSchemaTooNew))
+}
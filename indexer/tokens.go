@@ -0,0 +1,194 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// tokenTransferRange is the number of blocks
+// whose token transfers are fetched at once.
+const tokenTransferRange = 1000
+
+var (
+	tokenWatermarkKey = []byte("token_watermark")
+	tokenHolderPrefix = []byte("token_holder/")
+
+	// ErrTokenHoldersDisabled is returned when token
+	// holdings are requested but not indexed.
+	ErrTokenHoldersDisabled = errors.New("token holders are not indexed")
+
+	// ErrTokenHoldersNotIndexed is returned when token holdings
+	// are requested before any token transfer was indexed.
+	ErrTokenHoldersNotIndexed = errors.New("no token transfers have been indexed")
+)
+
+// TokenHolding is an ERC-20 token held by an address,
+// with the last block that changed its balance.
+type TokenHolding struct {
+	Token        common.Address         `json:"token_address"`
+	LastActivity *types.BlockIdentifier `json:"last_activity_block_identifier"`
+}
+
+// TokenHoldings are all the ERC-20 tokens an address
+// ever held, indexed up to (and including) Index.
+type TokenHoldings struct {
+	Index    int64           `json:"indexed_through"`
+	Holdings []*TokenHolding `json:"holdings"`
+}
+
+// syncTokenHolders indexes the token transfers of all blocks
+// up to the watermark. An index that did not track token
+// holders is backfilled from the genesis block.
+func (i *Indexer) syncTokenHolders(ctx context.Context) error {
+	watermark, err := i.Watermark()
+	if err != nil || watermark == nil {
+		return err
+	}
+
+	next := int64(0)
+	var tokenWatermark int64
+	found, err := i.get(tokenWatermarkKey, &tokenWatermark)
+	if err != nil {
+		return err
+	}
+	if found {
+		next = tokenWatermark + 1
+	}
+
+	for next <= watermark.Index {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		end := next + tokenTransferRange - 1
+		if end > watermark.Index {
+			end = watermark.Index
+		}
+
+		transfers, err := i.client.TokenTransfers(ctx, next, end)
+		if err != nil {
+			return err
+		}
+
+		if err := i.indexTokenTransfers(end, transfers); err != nil {
+			return fmt.Errorf("%w: unable to index token transfers of blocks %d to %d", err, next, end)
+		}
+		next = end + 1
+	}
+
+	return nil
+}
+
+// indexTokenTransfers records the sender and recipient of every
+// transfer as holders of the token, and moves the token watermark
+// to end. Mints and burns only record the account that holds the
+// tokens, not the zero address.
+func (i *Indexer) indexTokenTransfers(end int64, transfers []*ethereum.TokenTransfer) error {
+	batch := i.db.NewBatch()
+	for _, transfer := range transfers {
+		for _, holder := range []common.Address{transfer.From, transfer.To} {
+			if holder == (common.Address{}) || !i.retention.indexed(holder) {
+				continue
+			}
+
+			if err := putJSON(batch, tokenHolderKey(holder, transfer.Token), transfer.Block); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := putJSON(batch, tokenWatermarkKey, end); err != nil {
+		return err
+	}
+
+	return batch.Write()
+}
+
+// TokenHoldings returns every ERC-20 token address held
+// since the token holders have been indexed, whatever its
+// current balance.
+func (i *Indexer) TokenHoldings(address common.Address) (*TokenHoldings, error) {
+	if !i.tokenHolders {
+		return nil, ErrTokenHoldersDisabled
+	}
+
+	holdings := &TokenHoldings{Holdings: []*TokenHolding{}}
+	found, err := i.get(tokenWatermarkKey, &holdings.Index)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrTokenHoldersNotIndexed
+	}
+
+	prefix := append(append([]byte{}, tokenHolderPrefix...), address.Bytes()...)
+	iterator := i.db.NewIterator(prefix, nil)
+	defer iterator.Release()
+
+	for iterator.Next() {
+		holding := &TokenHolding{
+			Token: common.BytesToAddress(iterator.Key()[len(prefix):]),
+		}
+		if err := json.Unmarshal(iterator.Value(), &holding.LastActivity); err != nil {
+			return nil, fmt.Errorf("%w: unable to decode token holding of %s", err, address.Hex())
+		}
+
+		holdings.Holdings = append(holdings.Holdings, holding)
+	}
+	if err := iterator.Error(); err != nil {
+		return nil, fmt.Errorf("%w: unable to iterate token holdings", err)
+	}
+
+	return holdings, nil
+}
+
+// pruneTokenHolders removes the holdings of the
+// addresses that are no longer indexed.
+func (i *Indexer) pruneTokenHolders(batch ethdb.Batch) error {
+	iterator := i.db.NewIterator(tokenHolderPrefix, nil)
+	defer iterator.Release()
+
+	for iterator.Next() {
+		key := iterator.Key()
+		holder := common.BytesToAddress(key[len(tokenHolderPrefix) : len(tokenHolderPrefix)+common.AddressLength])
+		if i.retention.indexed(holder) {
+			continue
+		}
+
+		if err := batch.Delete(append([]byte{}, key...)); err != nil {
+			return err
+		}
+	}
+	if err := iterator.Error(); err != nil {
+		return fmt.Errorf("%w: unable to iterate token holdings", err)
+	}
+
+	return nil
+}
+
+func tokenHolderKey(holder common.Address, token common.Address) []byte {
+	key := append(append([]byte{}, tokenHolderPrefix...), holder.Bytes()...)
+	return append(key, token.Bytes()...)
+}
@@ -0,0 +1,129 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/indexer"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	token      = common.HexToAddress("0x40375C92d9FAf44d2f9db9Bd9ba41a3317a2404f")
+	otherToken = common.HexToAddress("0x900101d06A7426441Ae63e9AB3B9b0F63Be145F1")
+)
+
+func TestTokenHoldings(t *testing.T) {
+	ctx := context.Background()
+	mockClient := &mocks.Client{}
+	i := New(memorydb.New(), mockClient, nil, true)
+
+	// Nothing is indexed before the first block
+	_, err := i.TokenHoldings(common.HexToAddress(sender))
+	assert.Equal(t, ErrTokenHoldersNotIndexed, err)
+	assert.NoError(t, i.syncTokenHolders(ctx))
+
+	assert.NoError(t, i.IndexBlock(block(0)))
+	assert.NoError(t, i.IndexBlock(block(1)))
+	mockClient.On("TokenTransfers", ctx, int64(0), int64(1)).Return(
+		[]*ethereum.TokenTransfer{
+			{
+				// Mints do not index the zero address
+				Block: blockIdentifier(0),
+				Token: token,
+				To:    common.HexToAddress(sender),
+			},
+			{
+				Block: blockIdentifier(1),
+				Token: otherToken,
+				From:  common.HexToAddress(sender),
+				To:    common.HexToAddress(recipient),
+			},
+			{
+				Block: blockIdentifier(1),
+				Token: token,
+				From:  common.HexToAddress(sender),
+				To:    common.HexToAddress(recipient),
+			},
+		},
+		nil,
+	).Once()
+	assert.NoError(t, i.syncTokenHolders(ctx))
+
+	holdings, err := i.TokenHoldings(common.HexToAddress(sender))
+	assert.NoError(t, err)
+	assert.Equal(t, &TokenHoldings{
+		Index: 1,
+		Holdings: []*TokenHolding{
+			{Token: token, LastActivity: blockIdentifier(1)},
+			{Token: otherToken, LastActivity: blockIdentifier(1)},
+		},
+	}, holdings)
+
+	holdings, err = i.TokenHoldings(common.Address{})
+	assert.NoError(t, err)
+	assert.Equal(t, &TokenHoldings{Index: 1, Holdings: []*TokenHolding{}}, holdings)
+
+	// Only new blocks are fetched
+	assert.NoError(t, i.IndexBlock(block(2)))
+	mockClient.On("TokenTransfers", ctx, int64(2), int64(2)).Return(
+		[]*ethereum.TokenTransfer{},
+		nil,
+	).Once()
+	assert.NoError(t, i.syncTokenHolders(ctx))
+
+	holdings, err = i.TokenHoldings(common.HexToAddress(recipient))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), holdings.Index)
+	assert.Len(t, holdings.Holdings, 2)
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestTokenHoldings_Backfill(t *testing.T) {
+	ctx := context.Background()
+	mockClient := &mocks.Client{}
+	db := memorydb.New()
+	i := New(db, mockClient, nil, true)
+
+	// Token holders are backfilled in ranges
+	value, err := json.Marshal(blockIdentifier(2500))
+	assert.NoError(t, err)
+	assert.NoError(t, db.Put(watermarkKey, value))
+	mockClient.On("TokenTransfers", ctx, int64(0), int64(999)).Return(nil, nil).Once()
+	mockClient.On("TokenTransfers", ctx, int64(1000), int64(1999)).Return(nil, nil).Once()
+	mockClient.On("TokenTransfers", ctx, int64(2000), int64(2500)).Return(nil, nil).Once()
+	assert.NoError(t, i.syncTokenHolders(ctx))
+
+	holdings, err := i.TokenHoldings(common.HexToAddress(sender))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2500), holdings.Index)
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestTokenHoldings_Disabled(t *testing.T) {
+	i := New(memorydb.New(), &mocks.Client{}, nil, false)
+
+	_, err := i.TokenHoldings(common.HexToAddress(sender))
+	assert.Equal(t, ErrTokenHoldersDisabled, err)
+}
@@ -18,6 +18,7 @@ import (
 	"os"
 
 	"github.com/coinbase/rosetta-ethereum/cmd"
+	_ "github.com/coinbase/rosetta-ethereum/plugins/uniswapv2"
 
 	"github.com/fatih/color"
 )
@@ -0,0 +1,85 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics contains the metrics exported by rosetta-core.
+// It is a thin wrapper around go-ethereum's metrics library so that
+// all metrics share a single registry and Prometheus endpoint.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	gethmetrics "github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/metrics/prometheus"
+)
+
+// Registry contains all metrics exported by rosetta-core.
+var Registry = gethmetrics.NewRegistry()
+
+// Enable makes all metrics functional. go-ethereum creates
+// no-op metrics until metrics are enabled, so Enable must be
+// called before any metric is used (metrics created before
+// remain no-ops). It also enables the metrics of go-ethereum
+// itself, so it is only called when metrics are exported.
+func Enable() {
+	gethmetrics.Enabled = true
+}
+
+// Counter returns the counter registered with the provided
+// name, creating it if it does not exist.
+func Counter(name string) gethmetrics.Counter {
+	return gethmetrics.GetOrRegisterCounter(name, Registry)
+}
+
+// Gauge returns the gauge registered with the provided
+// name, creating it if it does not exist.
+func Gauge(name string) gethmetrics.Gauge {
+	return gethmetrics.GetOrRegisterGauge(name, Registry)
+}
+
+// Timer returns the timer registered with the provided
+// name, creating it if it does not exist.
+func Timer(name string) gethmetrics.Timer {
+	return gethmetrics.GetOrRegisterTimer(name, Registry)
+}
+
+// Snapshot returns the current value of every registered
+// counter and gauge, and the count and mean (in seconds)
+// of every registered timer, keyed by metric name.
+func Snapshot() map[string]interface{} {
+	snapshot := map[string]interface{}{}
+	Registry.Each(func(name string, metric interface{}) {
+		switch m := metric.(type) {
+		case gethmetrics.Counter:
+			snapshot[name] = m.Count()
+		case gethmetrics.Gauge:
+			snapshot[name] = m.Value()
+		case gethmetrics.Timer:
+			t := m.Snapshot()
+			snapshot[name] = map[string]interface{}{
+				"count":        t.Count(),
+				"mean_seconds": time.Duration(t.Mean()).Seconds(),
+			}
+		}
+	})
+
+	return snapshot
+}
+
+// Handler returns an http.Handler that serves all
+// registered metrics in the Prometheus format.
+func Handler() http.Handler {
+	return prometheus.Handler(Registry)
+}
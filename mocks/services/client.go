@@ -10,6 +10,8 @@ import (
 
 	coretypes "github.com/ethereum/go-ethereum/core/types"
 
+	ethereum "github.com/coinbase/rosetta-ethereum/ethereum"
+
 	mock "github.com/stretchr/testify/mock"
 
 	types "github.com/coinbase/rosetta-sdk-go/types"
@@ -66,6 +68,38 @@ func (_m *Client) Block(_a0 context.Context, _a1 *types.PartialBlockIdentifier)
 	return r0, r1
 }
 
+// BlockPage provides a mock function with given fields: _a0, _a1, _a2
+func (_m *Client) BlockPage(_a0 context.Context, _a1 *types.PartialBlockIdentifier, _a2 int) (*types.Block, []*types.TransactionIdentifier, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 *types.Block
+	if rf, ok := ret.Get(0).(func(context.Context, *types.PartialBlockIdentifier, int) *types.Block); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.Block)
+		}
+	}
+
+	var r1 []*types.TransactionIdentifier
+	if rf, ok := ret.Get(1).(func(context.Context, *types.PartialBlockIdentifier, int) []*types.TransactionIdentifier); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]*types.TransactionIdentifier)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, *types.PartialBlockIdentifier, int) error); ok {
+		r2 = rf(_a0, _a1, _a2)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // Call provides a mock function with given fields: ctx, request
 func (_m *Client) Call(ctx context.Context, request *types.CallRequest) (*types.CallResponse, error) {
 	ret := _m.Called(ctx, request)
@@ -89,6 +123,29 @@ func (_m *Client) Call(ctx context.Context, request *types.CallRequest) (*types.
 	return r0, r1
 }
 
+// CancelTarget provides a mock function with given fields: ctx, account, nonce
+func (_m *Client) CancelTarget(ctx context.Context, account common.Address, nonce uint64) (*ethereum.CancelTarget, error) {
+	ret := _m.Called(ctx, account, nonce)
+
+	var r0 *ethereum.CancelTarget
+	if rf, ok := ret.Get(0).(func(context.Context, common.Address, uint64) *ethereum.CancelTarget); ok {
+		r0 = rf(ctx, account, nonce)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ethereum.CancelTarget)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, common.Address, uint64) error); ok {
+		r1 = rf(ctx, account, nonce)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetMempool provides a mock function with given fields: ctx
 func (_m *Client) GetMempool(ctx context.Context) (*types.MempoolResponse, error) {
 	ret := _m.Called(ctx)
@@ -112,6 +169,45 @@ func (_m *Client) GetMempool(ctx context.Context) (*types.MempoolResponse, error
 	return r0, r1
 }
 
+// Hardforks provides a mock function with given fields: configured
+func (_m *Client) Hardforks(configured map[string]uint64) []*ethereum.Hardfork {
+	ret := _m.Called(configured)
+
+	var r0 []*ethereum.Hardfork
+	if rf, ok := ret.Get(0).(func(map[string]uint64) []*ethereum.Hardfork); ok {
+		r0 = rf(configured)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*ethereum.Hardfork)
+		}
+	}
+
+	return r0
+}
+
+// PendingBalanceAt provides a mock function with given fields: _a0, _a1
+func (_m *Client) PendingBalanceAt(_a0 context.Context, _a1 common.Address) (*big.Int, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *big.Int
+	if rf, ok := ret.Get(0).(func(context.Context, common.Address) *big.Int); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*big.Int)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, common.Address) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // PendingNonceAt provides a mock function with given fields: _a0, _a1
 func (_m *Client) PendingNonceAt(_a0 context.Context, _a1 common.Address) (uint64, error) {
 	ret := _m.Called(_a0, _a1)
@@ -147,6 +243,29 @@ func (_m *Client) SendTransaction(ctx context.Context, tx *coretypes.Transaction
 	return r0
 }
 
+// StakedBalance provides a mock function with given fields: ctx, address, block
+func (_m *Client) StakedBalance(ctx context.Context, address common.Address, block *types.BlockIdentifier) (*ethereum.StakedBalance, error) {
+	ret := _m.Called(ctx, address, block)
+
+	var r0 *ethereum.StakedBalance
+	if rf, ok := ret.Get(0).(func(context.Context, common.Address, *types.BlockIdentifier) *ethereum.StakedBalance); ok {
+		r0 = rf(ctx, address, block)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ethereum.StakedBalance)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, common.Address, *types.BlockIdentifier) error); ok {
+		r1 = rf(ctx, address, block)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Status provides a mock function with given fields: _a0
 func (_m *Client) Status(_a0 context.Context) (*types.BlockIdentifier, int64, *types.SyncStatus, []*types.Peer, error) {
 	ret := _m.Called(_a0)
@@ -218,6 +337,29 @@ func (_m *Client) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
 	return r0, r1
 }
 
+// TokenBalances provides a mock function with given fields: ctx, address, block, currencies
+func (_m *Client) TokenBalances(ctx context.Context, address common.Address, block *types.BlockIdentifier, currencies []*types.Currency) ([]*types.Amount, error) {
+	ret := _m.Called(ctx, address, block, currencies)
+
+	var r0 []*types.Amount
+	if rf, ok := ret.Get(0).(func(context.Context, common.Address, *types.BlockIdentifier, []*types.Currency) []*types.Amount); ok {
+		r0 = rf(ctx, address, block, currencies)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*types.Amount)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, common.Address, *types.BlockIdentifier, []*types.Currency) error); ok {
+		r1 = rf(ctx, address, block, currencies)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Transaction provides a mock function with given fields: _a0, _a1, _a2
 func (_m *Client) Transaction(_a0 context.Context, _a1 *types.BlockIdentifier, _a2 *types.TransactionIdentifier) (*types.Transaction, error) {
 	ret := _m.Called(_a0, _a1, _a2)
@@ -0,0 +1,145 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nonce
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+)
+
+const (
+	// DefaultLease is how long an allocated nonce is reserved
+	// for its sender. If no transaction consumes an allocation
+	// within the lease, the tracker falls back to the pending
+	// nonce reported by the node so that abandoned allocations
+	// do not leave a permanent gap.
+	DefaultLease = 10 * time.Minute
+
+	// leveldb tuning used for the tracker database. The
+	// tracker stores a single small record per sender.
+	databaseCache   = 16
+	databaseHandles = 16
+
+	keyPrefix = "nonce-"
+)
+
+// allocation is the persisted state of a sender.
+type allocation struct {
+	Next      uint64 `json:"next"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// Tracker hands out strictly increasing nonces per sender,
+// persisting every allocation so that concurrent (and
+// restarted) construction flows never reuse a nonce.
+type Tracker struct {
+	db    ethdb.KeyValueStore
+	lease time.Duration
+	now   func() time.Time
+
+	// The tracker is used by a single process, so a
+	// single lock is enough to serialize allocations.
+	mutex sync.Mutex
+}
+
+// NewTracker creates a *Tracker backed by db.
+func NewTracker(db ethdb.KeyValueStore, lease time.Duration) *Tracker {
+	return &Tracker{
+		db:    db,
+		lease: lease,
+		now:   time.Now,
+	}
+}
+
+// OpenTracker creates a *Tracker persisted in a
+// leveldb database at path.
+func OpenTracker(path string, lease time.Duration) (*Tracker, error) {
+	db, err := leveldb.New(path, databaseCache, databaseHandles, "", false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to open nonce database %s", err, path)
+	}
+
+	return NewTracker(db, lease), nil
+}
+
+// Close closes the underlying database.
+func (t *Tracker) Close() error {
+	return t.db.Close()
+}
+
+// Next allocates the next nonce for sender. pending is the
+// pending nonce reported by the node and is used whenever it
+// is ahead of the tracker (i.e. transactions were sent without
+// using the tracker) or the previous allocation has expired.
+func (t *Tracker) Next(sender common.Address, pending uint64) (uint64, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	key := append([]byte(keyPrefix), sender.Bytes()...)
+	now := t.now()
+
+	nonce := pending
+	existing, err := t.get(key)
+	if err != nil {
+		return 0, err
+	}
+	if existing != nil &&
+		existing.Next > pending &&
+		now.Sub(time.Unix(existing.UpdatedAt, 0)) < t.lease {
+		nonce = existing.Next
+	}
+
+	value, err := json.Marshal(&allocation{
+		Next:      nonce + 1,
+		UpdatedAt: now.Unix(),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := t.db.Put(key, value); err != nil {
+		return 0, fmt.Errorf("%w: unable to persist nonce for %s", err, sender.Hex())
+	}
+
+	return nonce, nil
+}
+
+func (t *Tracker) get(key []byte) (*allocation, error) {
+	has, err := t.db.Has(key)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, nil
+	}
+
+	value, err := t.db.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing allocation
+	if err := json.Unmarshal(value, &existing); err != nil {
+		return nil, fmt.Errorf("%w: unable to decode nonce allocation", err)
+	}
+
+	return &existing, nil
+}
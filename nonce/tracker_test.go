@@ -0,0 +1,110 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nonce
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	sender = common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	other  = common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+)
+
+func TestTracker_Next(t *testing.T) {
+	tracker := NewTracker(memorydb.New(), DefaultLease)
+	now := time.Unix(1600000000, 0)
+	tracker.now = func() time.Time { return now }
+
+	// First allocation uses the pending nonce
+	nonce, err := tracker.Next(sender, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), nonce)
+
+	// Subsequent allocations increase even if the
+	// node has not seen the previous transaction
+	nonce, err = tracker.Next(sender, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), nonce)
+
+	// Senders are tracked independently
+	nonce, err = tracker.Next(other, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), nonce)
+
+	// The node is ahead of the tracker
+	nonce, err = tracker.Next(sender, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), nonce)
+
+	// The allocation expires
+	now = now.Add(DefaultLease)
+	nonce, err = tracker.Next(sender, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), nonce)
+}
+
+func TestTracker_Concurrent(t *testing.T) {
+	tracker := NewTracker(memorydb.New(), DefaultLease)
+
+	const allocations = 100
+	nonces := make([]int, allocations)
+	var wg sync.WaitGroup
+	for i := 0; i < allocations; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			nonce, err := tracker.Next(sender, 0)
+			assert.NoError(t, err)
+			nonces[i] = int(nonce)
+		}(i)
+	}
+	wg.Wait()
+
+	sort.Ints(nonces)
+	for i, nonce := range nonces {
+		assert.Equal(t, i, nonce)
+	}
+}
+
+func TestTracker_Persistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nonce")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tracker, err := OpenTracker(dir, DefaultLease)
+	assert.NoError(t, err)
+	nonce, err := tracker.Next(sender, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), nonce)
+	assert.NoError(t, tracker.Close())
+
+	tracker, err = OpenTracker(dir, DefaultLease)
+	assert.NoError(t, err)
+	nonce, err = tracker.Next(sender, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), nonce)
+	assert.NoError(t, tracker.Close())
+}
@@ -0,0 +1,128 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uniswapv2
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	EthTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// routerABIDefinition is the swap methods of the Uniswap V2
+// Router02, which the routers of Uniswap V2 forks share. The
+// native currency methods are named after ETH in every fork.
+const routerABIDefinition = `[
+	{"type":"function","name":"swapExactTokensForTokens","inputs":[{"name":"amountIn","type":"uint256"},{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"swapTokensForExactTokens","inputs":[{"name":"amountOut","type":"uint256"},{"name":"amountInMax","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"swapExactETHForTokens","inputs":[{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"swapTokensForExactETH","inputs":[{"name":"amountOut","type":"uint256"},{"name":"amountInMax","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"swapExactTokensForETH","inputs":[{"name":"amountIn","type":"uint256"},{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"swapETHForExactTokens","inputs":[{"name":"amountOut","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"swapExactTokensForTokensSupportingFeeOnTransferTokens","inputs":[{"name":"amountIn","type":"uint256"},{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"swapExactETHForTokensSupportingFeeOnTransferTokens","inputs":[{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"swapExactTokensForETHSupportingFeeOnTransferTokens","inputs":[{"name":"amountIn","type":"uint256"},{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}],"outputs":[]}
+]`
+
+var routerABI = mustParseABI(routerABIDefinition)
+
+// SwapSummary summarizes a transaction that swaps through the
+// router of a Uniswap V2 compatible DEX. TokenIn and TokenOut are
+// the first and last tokens of the path. When NativeIn or NativeOut
+// is set, that token is the wrapped native currency (i.e. WCORE),
+// which the router wraps or unwraps. Amounts are decimal strings
+// of the amounts actually swapped, taken from the Swap events of
+// the Pools traversed.
+type SwapSummary struct {
+	Router    string   `json:"router"`
+	Method    string   `json:"method"`
+	TokenIn   string   `json:"token_in"`
+	TokenOut  string   `json:"token_out"`
+	NativeIn  bool     `json:"native_in"`
+	NativeOut bool     `json:"native_out"`
+	AmountIn  string   `json:"amount_in"`
+	AmountOut string   `json:"amount_out"`
+	Pools     []string `json:"pools"`
+	Recipient string   `json:"recipient"`
+}
+
+// summarizeSwap returns the summary of raw if it calls a swap
+// method of a router and swaps has a swap for every hop of its
+// path. Otherwise, it returns nil.
+func summarizeSwap(raw *EthTypes.Transaction, swaps []*Swap) *SwapSummary {
+	if raw == nil || raw.To() == nil || len(raw.Data()) < 4 { // nolint:gomnd
+		return nil
+	}
+
+	method, err := routerABI.MethodById(raw.Data()[:4])
+	if err != nil {
+		return nil
+	}
+
+	args := map[string]interface{}{}
+	if err := method.Inputs.UnpackIntoMap(args, raw.Data()[4:]); err != nil {
+		return nil
+	}
+
+	path, ok := args["path"].([]common.Address)
+	if !ok || len(path) < 2 || len(swaps) != len(path)-1 { // nolint:gomnd
+		return nil
+	}
+	recipient, ok := args["to"].(common.Address)
+	if !ok {
+		return nil
+	}
+
+	pools := make([]string, len(swaps))
+	for i, swap := range swaps {
+		pools[i] = swap.Pool
+	}
+
+	first := swaps[0]
+	last := swaps[len(swaps)-1]
+
+	return &SwapSummary{
+		Router:    raw.To().Hex(),
+		Method:    method.Name,
+		TokenIn:   path[0].Hex(),
+		TokenOut:  path[len(path)-1].Hex(),
+		NativeIn:  strings.HasPrefix(method.Name, "swapExactETH") || strings.HasPrefix(method.Name, "swapETH"),
+		NativeOut: strings.Contains(method.Name, "ForETH") || strings.Contains(method.Name, "ForExactETH"),
+		AmountIn:  sum(first.Amount0In, first.Amount1In),
+		AmountOut: sum(last.Amount0Out, last.Amount1Out),
+		Pools:     pools,
+		Recipient: recipient.Hex(),
+	}
+}
+
+// sum returns the sum of two decimal amounts. Only one
+// of the amounts of a side of a swap is usually set.
+func sum(a string, b string) string {
+	x, _ := new(big.Int).SetString(a, 10) // nolint:gomnd
+	y, _ := new(big.Int).SetString(b, 10) // nolint:gomnd
+	return new(big.Int).Add(x, y).String()
+}
+
+func mustParseABI(definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(fmt.Sprintf("unable to parse router ABI: %s", err.Error()))
+	}
+
+	return parsed
+}
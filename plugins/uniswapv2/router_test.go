@@ -0,0 +1,127 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uniswapv2
+
+import (
+	"math/big"
+	"testing"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	EthTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	usdt  = common.HexToAddress("0x900101d06A7426441Ae63e9AB3B9b0F63Be145F1")
+	wcore = common.HexToAddress("0x40375C92d9FAf44d2f9db9Bd9ba41a3317a2404f")
+	token = common.HexToAddress("0x1234567890123456789012345678901234567890")
+)
+
+func routerTx(t *testing.T, value int64, method string, args ...interface{}) *EthTypes.Transaction {
+	data, err := routerABI.Pack(method, args...)
+	assert.NoError(t, err)
+
+	return EthTypes.NewTransaction(0, router, big.NewInt(value), 200000, big.NewInt(1), data)
+}
+
+func TestTransform_SwapSummary(t *testing.T) {
+	transformer := &Transformer{}
+	receipt := &EthTypes.Receipt{
+		Logs: []*EthTypes.Log{
+			swapLog(3, 1000, 0, 0, 1990),
+			swapLog(5, 0, 1990, 995, 0),
+		},
+	}
+
+	tx := &RosettaTypes.Transaction{}
+	raw := routerTx(
+		t,
+		0,
+		"swapExactTokensForTokens",
+		big.NewInt(1000),
+		big.NewInt(900),
+		[]common.Address{usdt, wcore, token},
+		trader,
+		big.NewInt(1700000000),
+	)
+	assert.NoError(t, transformer.TransformTransaction(tx, raw, receipt))
+	assert.Equal(t, &SwapSummary{
+		Router:    router.Hex(),
+		Method:    "swapExactTokensForTokens",
+		TokenIn:   usdt.Hex(),
+		TokenOut:  token.Hex(),
+		AmountIn:  "1000",
+		AmountOut: "995",
+		Pools:     []string{pool.Hex(), pool.Hex()},
+		Recipient: trader.Hex(),
+	}, tx.Metadata[SwapSummaryMetadataKey])
+
+	// Swaps of the native currency
+	tx = &RosettaTypes.Transaction{}
+	raw = routerTx(
+		t,
+		1000,
+		"swapExactETHForTokens",
+		big.NewInt(900),
+		[]common.Address{wcore, usdt, token},
+		trader,
+		big.NewInt(1700000000),
+	)
+	assert.NoError(t, transformer.TransformTransaction(tx, raw, receipt))
+	summary := tx.Metadata[SwapSummaryMetadataKey].(*SwapSummary)
+	assert.True(t, summary.NativeIn)
+	assert.False(t, summary.NativeOut)
+	assert.Equal(t, wcore.Hex(), summary.TokenIn)
+
+	tx = &RosettaTypes.Transaction{}
+	raw = routerTx(
+		t,
+		0,
+		"swapExactTokensForETHSupportingFeeOnTransferTokens",
+		big.NewInt(1000),
+		big.NewInt(900),
+		[]common.Address{token, usdt, wcore},
+		trader,
+		big.NewInt(1700000000),
+	)
+	assert.NoError(t, transformer.TransformTransaction(tx, raw, receipt))
+	summary = tx.Metadata[SwapSummaryMetadataKey].(*SwapSummary)
+	assert.False(t, summary.NativeIn)
+	assert.True(t, summary.NativeOut)
+
+	// Swaps that do not match the path are not summarized
+	tx = &RosettaTypes.Transaction{}
+	raw = routerTx(
+		t,
+		0,
+		"swapExactTokensForTokens",
+		big.NewInt(1000),
+		big.NewInt(900),
+		[]common.Address{usdt, token},
+		trader,
+		big.NewInt(1700000000),
+	)
+	assert.NoError(t, transformer.TransformTransaction(tx, raw, receipt))
+	assert.Contains(t, tx.Metadata, SwapsMetadataKey)
+	assert.NotContains(t, tx.Metadata, SwapSummaryMetadataKey)
+
+	// Calls that are not router swaps are not summarized
+	tx = &RosettaTypes.Transaction{}
+	raw = EthTypes.NewTransaction(0, router, big.NewInt(0), 200000, big.NewInt(1), []byte{0x01, 0x02, 0x03, 0x04})
+	assert.NoError(t, transformer.TransformTransaction(tx, raw, receipt))
+	assert.Contains(t, tx.Metadata, SwapsMetadataKey)
+	assert.NotContains(t, tx.Metadata, SwapSummaryMetadataKey)
+}
@@ -0,0 +1,159 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package uniswapv2 is an example ethereum.BlockTransformer that
+// decodes the swaps of Uniswap V2 compatible pools and routers,
+// which most Core DEXes (i.e. IceCreamSwap and ArcherSwap) are
+// forks of. Routers are recognized by their methods rather than
+// their address, so the routers of every fork are decoded. It is
+// registered as "uniswap_v2".
+package uniswapv2
+
+import (
+	"math/big"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	EthTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// Name is the name the transformer is registered under.
+	Name = "uniswap_v2"
+
+	// SwapsMetadataKey is the transaction metadata key
+	// populated with the swaps of a transaction.
+	SwapsMetadataKey = "uniswap_v2_swaps"
+
+	// SwapSummaryMetadataKey is the transaction metadata key
+	// populated with the SwapSummary of transactions that swap
+	// through the router of a Uniswap V2 compatible DEX.
+	SwapSummaryMetadataKey = "uniswap_v2_swap_summary"
+
+	// SwapCountMetadataKey is the block metadata key
+	// populated with the number of swaps in a block.
+	SwapCountMetadataKey = "uniswap_v2_swap_count"
+
+	// swapDataLength is the length of the non-indexed
+	// arguments of a Swap event (4 uint256).
+	swapDataLength = 4 * common.HashLength
+)
+
+// swapTopic is the topic of the Swap event of Uniswap V2 pairs:
+// Swap(address indexed sender, uint amount0In, uint amount1In,
+// uint amount0Out, uint amount1Out, address indexed to).
+var swapTopic = crypto.Keccak256Hash(
+	[]byte("Swap(address,uint256,uint256,uint256,uint256,address)"),
+)
+
+func init() {
+	ethereum.RegisterBlockTransformer(Name, &Transformer{})
+}
+
+// Swap is a swap through a Uniswap V2 pool. Amounts are decimal
+// strings in the units of token0 and token1 of the pool.
+type Swap struct {
+	LogIndex   uint   `json:"log_index"`
+	Pool       string `json:"pool"`
+	Sender     string `json:"sender"`
+	To         string `json:"to"`
+	Amount0In  string `json:"amount0_in"`
+	Amount1In  string `json:"amount1_in"`
+	Amount0Out string `json:"amount0_out"`
+	Amount1Out string `json:"amount1_out"`
+}
+
+// Transformer adds the swaps of every transaction (and a summary of
+// swaps made through a router) to its metadata and the number of
+// swaps of every block to its metadata. It does not add operations,
+// so balances are unaffected.
+type Transformer struct{}
+
+// TransformTransaction implements ethereum.BlockTransformer.
+func (t *Transformer) TransformTransaction(
+	tx *RosettaTypes.Transaction,
+	raw *EthTypes.Transaction,
+	receipt *EthTypes.Receipt,
+) error {
+	if receipt == nil {
+		return nil
+	}
+
+	swaps := []*Swap{}
+	for _, log := range receipt.Logs {
+		if swap := decodeSwap(log); swap != nil {
+			swaps = append(swaps, swap)
+		}
+	}
+	if len(swaps) == 0 {
+		return nil
+	}
+
+	if tx.Metadata == nil {
+		tx.Metadata = map[string]interface{}{}
+	}
+	tx.Metadata[SwapsMetadataKey] = swaps
+
+	if summary := summarizeSwap(raw, swaps); summary != nil {
+		tx.Metadata[SwapSummaryMetadataKey] = summary
+	}
+
+	return nil
+}
+
+// TransformBlock implements ethereum.BlockTransformer.
+func (t *Transformer) TransformBlock(block *RosettaTypes.Block, raw *EthTypes.Block) error {
+	count := 0
+	for _, tx := range block.Transactions {
+		if swaps, ok := tx.Metadata[SwapsMetadataKey].([]*Swap); ok {
+			count += len(swaps)
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+
+	if block.Metadata == nil {
+		block.Metadata = map[string]interface{}{}
+	}
+	block.Metadata[SwapCountMetadataKey] = count
+
+	return nil
+}
+
+// decodeSwap returns the swap logged by log,
+// or nil if log is not a Swap event.
+func decodeSwap(log *EthTypes.Log) *Swap {
+	if len(log.Topics) != 3 || log.Topics[0] != swapTopic || len(log.Data) != swapDataLength {
+		return nil
+	}
+
+	amount := func(i int) string {
+		return new(big.Int).SetBytes(log.Data[i*common.HashLength : (i+1)*common.HashLength]).String()
+	}
+
+	return &Swap{
+		LogIndex:   log.Index,
+		Pool:       log.Address.Hex(),
+		Sender:     common.BytesToAddress(log.Topics[1].Bytes()).Hex(),
+		To:         common.BytesToAddress(log.Topics[2].Bytes()).Hex(),
+		Amount0In:  amount(0),
+		Amount1In:  amount(1),
+		Amount0Out: amount(2),
+		Amount1Out: amount(3),
+	}
+}
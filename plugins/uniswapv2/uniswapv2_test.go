@@ -0,0 +1,105 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uniswapv2
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+
+	RosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	EthTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	pool   = common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	router = common.HexToAddress("0x9cD4A6f3f1a5E1b05C0eC5C6a8f8E5b0ff1B2f10")
+	trader = common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+)
+
+func swapLog(index uint, amounts ...int64) *EthTypes.Log {
+	data := []byte{}
+	for _, amount := range amounts {
+		data = append(data, common.LeftPadBytes(big.NewInt(amount).Bytes(), common.HashLength)...)
+	}
+
+	return &EthTypes.Log{
+		Address: pool,
+		Topics: []common.Hash{
+			swapTopic,
+			common.BytesToHash(router.Bytes()),
+			common.BytesToHash(trader.Bytes()),
+		},
+		Data:  data,
+		Index: index,
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	transformer, ok := ethereum.LookupBlockTransformer(Name)
+	assert.True(t, ok)
+	assert.Equal(t, &Transformer{}, transformer)
+}
+
+func TestTransform(t *testing.T) {
+	transformer := &Transformer{}
+	swapTx := &RosettaTypes.Transaction{Metadata: map[string]interface{}{}}
+	otherTx := &RosettaTypes.Transaction{Metadata: map[string]interface{}{}}
+
+	assert.NoError(t, transformer.TransformTransaction(swapTx, nil, &EthTypes.Receipt{
+		Logs: []*EthTypes.Log{
+			swapLog(3, 1000, 0, 0, 1990),
+			// Logs that are not swaps are ignored.
+			{Address: pool, Topics: []common.Hash{swapTopic}, Index: 4},
+			swapLog(5, 0, 1990, 995, 0),
+		},
+	}))
+	assert.Equal(t, []*Swap{
+		{
+			LogIndex:   3,
+			Pool:       pool.Hex(),
+			Sender:     router.Hex(),
+			To:         trader.Hex(),
+			Amount0In:  "1000",
+			Amount1In:  "0",
+			Amount0Out: "0",
+			Amount1Out: "1990",
+		},
+		{
+			LogIndex:   5,
+			Pool:       pool.Hex(),
+			Sender:     router.Hex(),
+			To:         trader.Hex(),
+			Amount0In:  "0",
+			Amount1In:  "1990",
+			Amount0Out: "995",
+			Amount1Out: "0",
+		},
+	}, swapTx.Metadata[SwapsMetadataKey])
+
+	assert.NoError(t, transformer.TransformTransaction(otherTx, nil, &EthTypes.Receipt{}))
+	assert.NotContains(t, otherTx.Metadata, SwapsMetadataKey)
+
+	block := &RosettaTypes.Block{Transactions: []*RosettaTypes.Transaction{swapTx, otherTx}}
+	assert.NoError(t, transformer.TransformBlock(block, nil))
+	assert.Equal(t, map[string]interface{}{SwapCountMetadataKey: 2}, block.Metadata)
+
+	block = &RosettaTypes.Block{Transactions: []*RosettaTypes.Transaction{otherTx}}
+	assert.NoError(t, transformer.TransformBlock(block, nil))
+	assert.Nil(t, block.Metadata)
+}
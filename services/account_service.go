@@ -16,10 +16,14 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
 
 	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
 )
 
 // AccountAPIService implements the server.AccountAPIServicer interface.
@@ -53,13 +57,116 @@ func (s *AccountAPIService) AccountBalance(
 		request.AccountIdentifier,
 		request.BlockIdentifier,
 	)
+	if errors.Is(err, ethereum.ErrNodeLagging) {
+		return nil, wrapErr(ErrNodeLagging, err)
+	}
 	if err != nil {
 		return nil, wrapErr(ErrGeth, err)
 	}
 
+	if len(request.Currencies) > 0 {
+		balances, err := s.currencyBalances(ctx, request, balanceResponse)
+		if errors.Is(err, ethereum.ErrCurrencyUnsupported) ||
+			errors.Is(err, ethereum.ErrCurrencyDuplicated) {
+			return nil, wrapErr(ErrInvalidInput, err)
+		}
+		if err != nil {
+			return nil, wrapErr(ErrGeth, err)
+		}
+
+		// The response may be cached by the client,
+		// so it is copied rather than modified.
+		response := *balanceResponse
+		response.Balances = balances
+		balanceResponse = &response
+	}
+
+	// Staked balances are returned as metadata (rather than as
+	// additional currencies) because operations do not track
+	// them, so they could never be reconciled.
+	if s.config.EnableStakedBalances {
+		staked, err := s.client.StakedBalance(
+			ctx,
+			common.HexToAddress(request.AccountIdentifier.Address),
+			balanceResponse.BlockIdentifier,
+		)
+		if err != nil {
+			return nil, wrapErr(ErrGeth, err)
+		}
+
+		if balanceResponse.Metadata == nil {
+			balanceResponse.Metadata = map[string]interface{}{}
+		}
+		balanceResponse.Metadata[ethereum.StakedBalanceMetadataKey] = staked.Staked.String()
+		balanceResponse.Metadata[ethereum.UnclaimedRewardsMetadataKey] = staked.UnclaimedRewards.String()
+	}
+
 	return balanceResponse, nil
 }
 
+// currencyBalances returns the balance of every currency in
+// request, in the order they are requested. Token balances are
+// read at the block of the native balance in response and are
+// returned with the currency as requested, so they can be
+// reconciled against it. Tokens are identified by their
+// contract rather than their symbol, so several tokens can
+// share a symbol but a contract can only be requested once.
+func (s *AccountAPIService) currencyBalances(
+	ctx context.Context,
+	request *types.AccountBalanceRequest,
+	response *types.AccountBalanceResponse,
+) ([]*types.Amount, error) {
+	balances := make([]*types.Amount, len(request.Currencies))
+	tokens := []*types.Currency{}
+	tokenIndexes := []int{}
+	requested := map[common.Address]struct{}{}
+	for i, currency := range request.Currencies {
+		if types.Hash(currency) == types.Hash(ethereum.Currency) {
+			balances[i] = response.Balances[0]
+			continue
+		}
+
+		if address, ok := ethereum.TokenAddress(currency); ok {
+			if _, ok := requested[address]; ok {
+				return nil, fmt.Errorf(
+					"%w: token %s is requested more than once",
+					ethereum.ErrCurrencyDuplicated,
+					address.Hex(),
+				)
+			}
+			requested[address] = struct{}{}
+			currency = ethereum.CanonicalCurrency(currency)
+		}
+
+		tokens = append(tokens, currency)
+		tokenIndexes = append(tokenIndexes, i)
+	}
+
+	if len(tokens) == 0 {
+		return balances, nil
+	}
+
+	amounts, err := s.client.TokenBalances(
+		ctx,
+		common.HexToAddress(request.AccountIdentifier.Address),
+		response.BlockIdentifier,
+		tokens,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, amount := range amounts {
+		balances[tokenIndexes[i]] = &types.Amount{
+			Value:    amount.Value,
+			Currency: request.Currencies[tokenIndexes[i]],
+			Metadata: amount.Metadata,
+		}
+	}
+
+	return balances, nil
+}
+
 // AccountCoins implements /account/coins.
 func (s *AccountAPIService) AccountCoins(
 	ctx context.Context,
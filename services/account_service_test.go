@@ -16,6 +16,8 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"math/big"
 	"testing"
 
 	"github.com/coinbase/rosetta-ethereum/configuration"
@@ -23,6 +25,7 @@ import (
 	mocks "github.com/coinbase/rosetta-ethereum/mocks/services"
 
 	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -95,3 +98,312 @@ func TestAccountBalance_Online(t *testing.T) {
 
 	mockClient.AssertExpectations(t)
 }
+
+func TestAccountBalance_StakedBalances(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode:                 configuration.Online,
+		EnableStakedBalances: true,
+	}
+	mockClient := &mocks.Client{}
+	servicer := NewAccountAPIService(cfg, mockClient)
+	ctx := context.Background()
+
+	account := &types.AccountIdentifier{
+		Address: "0x1234567890123456789012345678901234567890",
+	}
+
+	block := &types.BlockIdentifier{
+		Index: 1000,
+		Hash:  "block 1000",
+	}
+
+	mockClient.On(
+		"Balance",
+		ctx,
+		account,
+		(*types.PartialBlockIdentifier)(nil),
+	).Return(&types.AccountBalanceResponse{
+		BlockIdentifier: block,
+		Balances: []*types.Amount{
+			{
+				Value:    "25",
+				Currency: ethereum.Currency,
+			},
+		},
+	}, nil).Once()
+	mockClient.On(
+		"StakedBalance",
+		ctx,
+		common.HexToAddress(account.Address),
+		block,
+	).Return(&ethereum.StakedBalance{
+		Staked:           big.NewInt(100),
+		UnclaimedRewards: big.NewInt(7),
+	}, nil).Once()
+
+	bal, err := servicer.AccountBalance(ctx, &types.AccountBalanceRequest{
+		AccountIdentifier: account,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, &types.AccountBalanceResponse{
+		BlockIdentifier: block,
+		Balances: []*types.Amount{
+			{
+				Value:    "25",
+				Currency: ethereum.Currency,
+			},
+		},
+		Metadata: map[string]interface{}{
+			ethereum.StakedBalanceMetadataKey:    "100",
+			ethereum.UnclaimedRewardsMetadataKey: "7",
+		},
+	}, bal)
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestAccountBalance_Currencies(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode: configuration.Online,
+	}
+	mockClient := &mocks.Client{}
+	servicer := NewAccountAPIService(cfg, mockClient)
+	ctx := context.Background()
+
+	account := &types.AccountIdentifier{
+		Address: "0x1234567890123456789012345678901234567890",
+	}
+
+	block := &types.BlockIdentifier{
+		Index: 1000,
+		Hash:  "block 1000",
+	}
+
+	native := &types.Amount{
+		Value:    "25",
+		Currency: ethereum.Currency,
+	}
+	resp := &types.AccountBalanceResponse{
+		BlockIdentifier: block,
+		Balances:        []*types.Amount{native},
+	}
+	mockClient.On(
+		"Balance",
+		ctx,
+		account,
+		(*types.PartialBlockIdentifier)(nil),
+	).Return(resp, nil).Twice()
+
+	usdt := &types.Currency{
+		Symbol:   "USDT",
+		Decimals: 6,
+		Metadata: map[string]interface{}{
+			ethereum.TokenAddressMetadataKey: "0x900101d06A7426441Ae63e9AB3B9b0F63Be145F1",
+		},
+	}
+	wcore := &types.Currency{
+		Symbol:   "WCORE",
+		Decimals: 18,
+		Metadata: map[string]interface{}{
+			ethereum.TokenAddressMetadataKey: "0x40375C92d9FAf44d2f9db9Bd9ba41a3317a2404f",
+		},
+	}
+	tokenBalances := []*types.Amount{
+		{Value: "100", Currency: usdt},
+		{Value: "7", Currency: wcore},
+	}
+	mockClient.On(
+		"TokenBalances",
+		ctx,
+		common.HexToAddress(account.Address),
+		block,
+		[]*types.Currency{usdt, wcore},
+	).Return(tokenBalances, nil).Once()
+
+	bal, err := servicer.AccountBalance(ctx, &types.AccountBalanceRequest{
+		AccountIdentifier: account,
+		Currencies:        []*types.Currency{usdt, ethereum.Currency, wcore},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, &types.AccountBalanceResponse{
+		BlockIdentifier: block,
+		Balances:        []*types.Amount{tokenBalances[0], native, tokenBalances[1]},
+	}, bal)
+
+	// The response of the client is not modified.
+	assert.Equal(t, []*types.Amount{native}, resp.Balances)
+
+	// Only the native currency is requested
+	bal, err = servicer.AccountBalance(ctx, &types.AccountBalanceRequest{
+		AccountIdentifier: account,
+		Currencies:        []*types.Currency{ethereum.Currency},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, resp, bal)
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestAccountBalance_SymbolCollision(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode: configuration.Online,
+	}
+	mockClient := &mocks.Client{}
+	servicer := NewAccountAPIService(cfg, mockClient)
+	ctx := context.Background()
+
+	account := &types.AccountIdentifier{
+		Address: "0x1234567890123456789012345678901234567890",
+	}
+
+	block := &types.BlockIdentifier{
+		Index: 1000,
+		Hash:  "block 1000",
+	}
+
+	mockClient.On(
+		"Balance",
+		ctx,
+		account,
+		(*types.PartialBlockIdentifier)(nil),
+	).Return(&types.AccountBalanceResponse{
+		BlockIdentifier: block,
+		Balances:        []*types.Amount{{Value: "25", Currency: ethereum.Currency}},
+	}, nil).Twice()
+
+	// Two contracts share the USDT symbol, and the address
+	// of one of them is not checksummed.
+	usdtAddress := common.HexToAddress("0x900101d06A7426441Ae63e9AB3B9b0F63Be145F1")
+	fakeUSDTAddress := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	usdt := ethereum.TokenCurrency("USDT", 6, usdtAddress)
+	fakeUSDT := ethereum.TokenCurrency("USDT", 6, fakeUSDTAddress)
+	requested := &types.Currency{
+		Symbol:   "USDT",
+		Decimals: 6,
+		Metadata: map[string]interface{}{
+			ethereum.TokenAddressMetadataKey: "0x57b414a0332b5cab885a451c2a28a07d1e9b8a8d",
+		},
+	}
+	tokenBalances := []*types.Amount{
+		{Value: "100", Currency: usdt},
+		{Value: "1", Currency: fakeUSDT},
+	}
+	mockClient.On(
+		"TokenBalances",
+		ctx,
+		common.HexToAddress(account.Address),
+		block,
+		[]*types.Currency{usdt, fakeUSDT},
+	).Return(tokenBalances, nil).Once()
+
+	bal, err := servicer.AccountBalance(ctx, &types.AccountBalanceRequest{
+		AccountIdentifier: account,
+		Currencies:        []*types.Currency{usdt, requested},
+	})
+	assert.Nil(t, err)
+
+	// Balances are returned with the requested currencies,
+	// so they can be reconciled against them.
+	assert.Equal(t, []*types.Amount{
+		{Value: "100", Currency: usdt},
+		{Value: "1", Currency: requested},
+	}, bal.Balances)
+	assert.NotEqual(t, types.Hash(bal.Balances[0].Currency), types.Hash(bal.Balances[1].Currency))
+
+	// The request is not modified.
+	assert.Equal(
+		t,
+		"0x57b414a0332b5cab885a451c2a28a07d1e9b8a8d",
+		requested.Metadata[ethereum.TokenAddressMetadataKey],
+	)
+
+	// A contract can only be requested once.
+	bal, err = servicer.AccountBalance(ctx, &types.AccountBalanceRequest{
+		AccountIdentifier: account,
+		Currencies:        []*types.Currency{fakeUSDT, requested},
+	})
+	assert.Nil(t, bal)
+	assert.Equal(t, ErrInvalidInput.Code, err.Code)
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestAccountBalance_UnsupportedCurrency(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode: configuration.Online,
+	}
+	mockClient := &mocks.Client{}
+	servicer := NewAccountAPIService(cfg, mockClient)
+	ctx := context.Background()
+
+	account := &types.AccountIdentifier{
+		Address: "0x1234567890123456789012345678901234567890",
+	}
+
+	block := &types.BlockIdentifier{
+		Index: 1000,
+		Hash:  "block 1000",
+	}
+
+	mockClient.On(
+		"Balance",
+		ctx,
+		account,
+		(*types.PartialBlockIdentifier)(nil),
+	).Return(&types.AccountBalanceResponse{
+		BlockIdentifier: block,
+		Balances: []*types.Amount{
+			{
+				Value:    "25",
+				Currency: ethereum.Currency,
+			},
+		},
+	}, nil).Once()
+
+	currency := &types.Currency{Symbol: "BTC", Decimals: 8}
+	mockClient.On(
+		"TokenBalances",
+		ctx,
+		common.HexToAddress(account.Address),
+		block,
+		[]*types.Currency{currency},
+	).Return(nil, fmt.Errorf("%w: BTC has no valid token_address", ethereum.ErrCurrencyUnsupported)).Once()
+
+	bal, err := servicer.AccountBalance(ctx, &types.AccountBalanceRequest{
+		AccountIdentifier: account,
+		Currencies:        []*types.Currency{currency},
+	})
+	assert.Nil(t, bal)
+	assert.Equal(t, ErrInvalidInput.Code, err.Code)
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestAccountBalance_NodeLagging(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode: configuration.Online,
+	}
+	mockClient := &mocks.Client{}
+	servicer := NewAccountAPIService(cfg, mockClient)
+	ctx := context.Background()
+
+	account := &types.AccountIdentifier{
+		Address: "hello",
+	}
+
+	mockClient.On(
+		"Balance",
+		ctx,
+		account,
+		(*types.PartialBlockIdentifier)(nil),
+	).Return(nil, fmt.Errorf("%w: reference head is 100", ethereum.ErrNodeLagging)).Once()
+
+	bal, err := servicer.AccountBalance(ctx, &types.AccountBalanceRequest{
+		AccountIdentifier: account,
+	})
+	assert.Nil(t, bal)
+	assert.Equal(t, ErrNodeLagging.Code, err.Code)
+	assert.True(t, err.Retriable)
+
+	mockClient.AssertExpectations(t)
+}
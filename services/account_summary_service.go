@@ -0,0 +1,170 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/coinbase/rosetta-ethereum/amount"
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+
+	"github.com/coinbase/rosetta-sdk-go/asserter"
+	"github.com/coinbase/rosetta-sdk-go/server"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AccountSummaryRequest is the request body of
+// the /account/summary extension.
+type AccountSummaryRequest struct {
+	NetworkIdentifier *types.NetworkIdentifier `json:"network_identifier"`
+	AccountIdentifier *types.AccountIdentifier `json:"account_identifier"`
+}
+
+// AccountSummaryResponse is the response body of the
+// /account/summary extension. BlockIdentifier is the
+// last block included in the summary.
+type AccountSummaryResponse struct {
+	BlockIdentifier   *types.BlockIdentifier   `json:"block_identifier"`
+	AccountIdentifier *types.AccountIdentifier `json:"account_identifier"`
+
+	FirstSeenBlockIdentifier    *types.BlockIdentifier `json:"first_seen_block_identifier,omitempty"`
+	LastActivityBlockIdentifier *types.BlockIdentifier `json:"last_activity_block_identifier,omitempty"`
+	TransactionCount            int64                  `json:"transaction_count"`
+	TotalReceived               *types.Amount          `json:"total_received"`
+	TotalSent                   *types.Amount          `json:"total_sent"`
+}
+
+// AccountSummaryAPIService implements the
+// /account/summary extension.
+type AccountSummaryAPIService struct {
+	config *configuration.Configuration
+	index  AccountIndex
+}
+
+// NewAccountSummaryAPIService creates a new instance
+// of an AccountSummaryAPIService.
+func NewAccountSummaryAPIService(
+	cfg *configuration.Configuration,
+	index AccountIndex,
+) *AccountSummaryAPIService {
+	return &AccountSummaryAPIService{
+		config: cfg,
+		index:  index,
+	}
+}
+
+// AccountSummary implements the /account/summary endpoint.
+func (s *AccountSummaryAPIService) AccountSummary(
+	ctx context.Context,
+	request *AccountSummaryRequest,
+) (*AccountSummaryResponse, *types.Error) {
+	if s.config.Mode != configuration.Online {
+		return nil, ErrUnavailableOffline
+	}
+
+	if !common.IsHexAddress(request.AccountIdentifier.Address) {
+		return nil, wrapErr(
+			ErrInvalidAddress,
+			fmt.Errorf("%s is not a valid address", request.AccountIdentifier.Address),
+		)
+	}
+
+	watermark, err := s.index.Watermark()
+	if err != nil {
+		return nil, wrapErr(ErrIndexUnavailable, err)
+	}
+	if watermark == nil {
+		return nil, wrapErr(ErrIndexUnavailable, errors.New("no blocks have been indexed"))
+	}
+
+	summary, err := s.index.Summary(common.HexToAddress(request.AccountIdentifier.Address))
+	if err != nil {
+		return nil, wrapErr(ErrIndexUnavailable, err)
+	}
+
+	return &AccountSummaryResponse{
+		BlockIdentifier:             watermark,
+		AccountIdentifier:           request.AccountIdentifier,
+		FirstSeenBlockIdentifier:    summary.FirstSeen,
+		LastActivityBlockIdentifier: summary.LastActivity,
+		TransactionCount:            summary.TransactionCount,
+		TotalReceived:               amount.New(summary.TotalReceived, ethereum.Currency),
+		TotalSent:                   amount.New(summary.TotalSent, ethereum.Currency),
+	}, nil
+}
+
+// AccountSummaryAPIController binds the /account/summary
+// extension to an http.Handler.
+type AccountSummaryAPIController struct {
+	service  *AccountSummaryAPIService
+	asserter *asserter.Asserter
+}
+
+// NewAccountSummaryAPIController creates a server.Router
+// serving the /account/summary extension.
+func NewAccountSummaryAPIController(
+	service *AccountSummaryAPIService,
+	asserter *asserter.Asserter,
+) server.Router {
+	return &AccountSummaryAPIController{
+		service:  service,
+		asserter: asserter,
+	}
+}
+
+// Routes returns all the api routes for the AccountSummaryAPIController.
+func (c *AccountSummaryAPIController) Routes() server.Routes {
+	return server.Routes{
+		{
+			Name:        "AccountSummary",
+			Method:      http.MethodPost,
+			Pattern:     "/account/summary",
+			HandlerFunc: c.AccountSummary,
+		},
+	}
+}
+
+// AccountSummary - Get an Account Summary
+func (c *AccountSummaryAPIController) AccountSummary(w http.ResponseWriter, r *http.Request) {
+	request := &AccountSummaryRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		server.EncodeJSONResponse(wrapErr(ErrInvalidInput, err), http.StatusInternalServerError, w)
+		return
+	}
+
+	if err := c.asserter.ValidSupportedNetwork(request.NetworkIdentifier); err != nil {
+		server.EncodeJSONResponse(wrapErr(ErrInvalidInput, err), http.StatusInternalServerError, w)
+		return
+	}
+
+	if err := asserter.AccountIdentifier(request.AccountIdentifier); err != nil {
+		server.EncodeJSONResponse(wrapErr(ErrInvalidInput, err), http.StatusInternalServerError, w)
+		return
+	}
+
+	result, serviceErr := c.service.AccountSummary(r.Context(), request)
+	if serviceErr != nil {
+		server.EncodeJSONResponse(serviceErr, http.StatusInternalServerError, w)
+		return
+	}
+
+	server.EncodeJSONResponse(result, http.StatusOK, w)
+}
@@ -0,0 +1,193 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	"github.com/coinbase/rosetta-ethereum/indexer"
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/services"
+
+	"github.com/coinbase/rosetta-sdk-go/asserter"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountSummary_Offline(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode: configuration.Offline,
+	}
+	mockIndex := &mocks.AccountIndex{}
+	servicer := NewAccountSummaryAPIService(cfg, mockIndex)
+
+	resp, err := servicer.AccountSummary(context.Background(), &AccountSummaryRequest{})
+	assert.Nil(t, resp)
+	assert.Equal(t, ErrUnavailableOffline.Code, err.Code)
+
+	mockIndex.AssertExpectations(t)
+}
+
+func TestAccountSummary_Online(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode: configuration.Online,
+	}
+	mockIndex := &mocks.AccountIndex{}
+	servicer := NewAccountSummaryAPIService(cfg, mockIndex)
+	ctx := context.Background()
+
+	account := &types.AccountIdentifier{
+		Address: "0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309",
+	}
+	watermark := &types.BlockIdentifier{Index: 1000, Hash: "block 1000"}
+
+	// Invalid address
+	resp, err := servicer.AccountSummary(ctx, &AccountSummaryRequest{
+		AccountIdentifier: &types.AccountIdentifier{Address: "hello"},
+	})
+	assert.Nil(t, resp)
+	assert.Equal(t, ErrInvalidAddress.Code, err.Code)
+
+	// Nothing indexed yet
+	mockIndex.On("Watermark").Return(nil, nil).Once()
+	resp, err = servicer.AccountSummary(ctx, &AccountSummaryRequest{
+		AccountIdentifier: account,
+	})
+	assert.Nil(t, resp)
+	assert.Equal(t, ErrIndexUnavailable.Code, err.Code)
+
+	// Index error
+	mockIndex.On("Watermark").Return(watermark, nil).Once()
+	mockIndex.On(
+		"Summary",
+		common.HexToAddress(account.Address),
+	).Return(
+		nil,
+		errors.New("corrupt"),
+	).Once()
+	resp, err = servicer.AccountSummary(ctx, &AccountSummaryRequest{
+		AccountIdentifier: account,
+	})
+	assert.Nil(t, resp)
+	assert.Equal(t, ErrIndexUnavailable.Code, err.Code)
+
+	// Success
+	mockIndex.On("Watermark").Return(watermark, nil).Once()
+	mockIndex.On(
+		"Summary",
+		common.HexToAddress(account.Address),
+	).Return(
+		&indexer.AccountSummary{
+			FirstSeen:        &types.BlockIdentifier{Index: 10, Hash: "block 10"},
+			LastActivity:     &types.BlockIdentifier{Index: 900, Hash: "block 900"},
+			TransactionCount: 3,
+			TotalReceived:    big.NewInt(100),
+			TotalSent:        big.NewInt(42),
+		},
+		nil,
+	).Once()
+	resp, err = servicer.AccountSummary(ctx, &AccountSummaryRequest{
+		AccountIdentifier: account,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, &AccountSummaryResponse{
+		BlockIdentifier:             watermark,
+		AccountIdentifier:           account,
+		FirstSeenBlockIdentifier:    &types.BlockIdentifier{Index: 10, Hash: "block 10"},
+		LastActivityBlockIdentifier: &types.BlockIdentifier{Index: 900, Hash: "block 900"},
+		TransactionCount:            3,
+		TotalReceived: &types.Amount{
+			Value:    "100",
+			Currency: ethereum.Currency,
+		},
+		TotalSent: &types.Amount{
+			Value:    "42",
+			Currency: ethereum.Currency,
+		},
+	}, resp)
+
+	mockIndex.AssertExpectations(t)
+}
+
+func TestAccountSummary_Router(t *testing.T) {
+	networkIdentifier := &types.NetworkIdentifier{
+		Network:    ethereum.CoreNetwork,
+		Blockchain: ethereum.Blockchain,
+	}
+	serverAsserter, err := asserter.NewServer(
+		ethereum.OperationTypes,
+		ethereum.HistoricalBalanceSupported,
+		[]*types.NetworkIdentifier{networkIdentifier},
+		ethereum.CallMethods,
+		ethereum.IncludeMempoolCoins,
+		"",
+	)
+	assert.NoError(t, err)
+
+	request, err := json.Marshal(&AccountSummaryRequest{
+		NetworkIdentifier: networkIdentifier,
+		AccountIdentifier: &types.AccountIdentifier{
+			Address: "0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309",
+		},
+	})
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		enabled bool
+
+		expectedStatus int
+	}{
+		"disabled": {
+			expectedStatus: http.StatusNotFound,
+		},
+		"enabled": {
+			enabled:        true,
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := &configuration.Configuration{
+				Mode:                 configuration.Online,
+				Network:              networkIdentifier,
+				EnableAccountSummary: test.enabled,
+			}
+			mockIndex := &mocks.AccountIndex{}
+			if test.enabled {
+				mockIndex.On("Watermark").Return(nil, nil).Once()
+			}
+
+			router := NewBlockchainRouter(cfg, &mocks.Client{}, nil, mockIndex, nil, nil, nil, serverAsserter)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(
+				recorder,
+				httptest.NewRequest(http.MethodPost, "/account/summary", bytes.NewReader(request)),
+			)
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+
+			mockIndex.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/coinbase/rosetta-sdk-go/server"
+)
+
+// ReloadResponse is returned by /admin/reload
+// once the configuration has been reloaded.
+type ReloadResponse struct {
+	Reloaded bool `json:"reloaded"`
+}
+
+// ReloadHandler returns an http.Handler serving POST
+// /admin/reload. Every request calls reload, which is
+// expected to reload the configuration like SIGHUP does.
+func ReloadHandler(reload func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		if err := reload(); err != nil {
+			log.Printf("unable to reload configuration: %s", err.Error())
+			server.EncodeJSONResponse(wrapErr(ErrReloadFailed, err), http.StatusInternalServerError, w)
+			return
+		}
+
+		server.EncodeJSONResponse(&ReloadResponse{Reloaded: true}, http.StatusOK, w)
+	})
+}
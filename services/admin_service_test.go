@@ -0,0 +1,59 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReloadHandler(t *testing.T) {
+	reloads := 0
+	var reloadErr error
+	handler := ReloadHandler(func() error {
+		reloads++
+		return reloadErr
+	})
+
+	// Only POST is allowed
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/reload", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	assert.Equal(t, 0, reloads)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/admin/reload", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var response ReloadResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.True(t, response.Reloaded)
+	assert.Equal(t, 1, reloads)
+
+	reloadErr = errors.New("bad runtime config")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/admin/reload", nil))
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	var rErr types.Error
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rErr))
+	assert.Equal(t, ErrReloadFailed.Code, rErr.Code)
+	assert.Equal(t, "bad runtime config", rErr.Details["context"])
+	assert.Equal(t, 2, reloads)
+}
@@ -0,0 +1,52 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+
+	"github.com/coinbase/rosetta-sdk-go/asserter"
+)
+
+// AsserterConfiguration returns the asserter configuration
+// (i.e. what rosetta-cli loads from its asserter configuration
+// file) matching the responses served with cfg. It is also used
+// to validate responses in STRICT validation mode, so the two can
+// never drift apart.
+func AsserterConfiguration(cfg *configuration.Configuration) *asserter.Configuration {
+	return &asserter.Configuration{
+		NetworkIdentifier:        cfg.Network,
+		GenesisBlockIdentifier:   cfg.GenesisBlockIdentifier,
+		AllowedOperationTypes:    ethereum.OperationTypes,
+		AllowedOperationStatuses: ethereum.OperationStatuses,
+		AllowedErrors:            Errors,
+
+		AllowedTimestampStartIndex: defaultTimestampStartIndex(cfg),
+	}
+}
+
+// defaultTimestampStartIndex returns cfg.TimestampStartIndex,
+// which rosetta-core finds from the node at startup in online
+// mode when it is not configured. When it is not populated, the
+// block after genesis is returned because the genesis block has
+// no timestamp.
+func defaultTimestampStartIndex(cfg *configuration.Configuration) int64 {
+	if cfg.TimestampStartIndex != nil {
+		return *cfg.TimestampStartIndex
+	}
+
+	return cfg.GenesisBlockIdentifier.Index + 1
+}
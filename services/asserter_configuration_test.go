@@ -0,0 +1,64 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+
+	"github.com/coinbase/rosetta-sdk-go/asserter"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/coinbase/rosetta-sdk-go/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsserterConfiguration(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Network: &types.NetworkIdentifier{
+			Network:    ethereum.MainnetNetwork,
+			Blockchain: ethereum.Blockchain,
+		},
+		GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+	}
+
+	asserterConfig := AsserterConfiguration(cfg)
+	assert.Equal(t, int64(1), asserterConfig.AllowedTimestampStartIndex)
+	assert.Equal(t, Errors, asserterConfig.AllowedErrors)
+
+	// The exported file can be loaded by rosetta-cli
+	dir, err := ioutil.TempDir("", "asserter")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "asserter.json")
+	assert.NoError(t, utils.SerializeAndWrite(path, asserterConfig))
+
+	clientAsserter, err := asserter.NewClientWithFile(path)
+	assert.NoError(t, err)
+
+	loaded, err := clientAsserter.ClientConfiguration()
+	assert.NoError(t, err)
+	assert.Equal(t, cfg.Network, loaded.NetworkIdentifier)
+	assert.Equal(t, cfg.GenesisBlockIdentifier, loaded.GenesisBlockIdentifier)
+	assert.ElementsMatch(t, ethereum.OperationTypes, loaded.AllowedOperationTypes)
+	assert.ElementsMatch(t, ethereum.OperationStatuses, loaded.AllowedOperationStatuses)
+	assert.ElementsMatch(t, Errors, loaded.AllowedErrors)
+	assert.Equal(t, int64(1), loaded.AllowedTimestampStartIndex)
+}
@@ -0,0 +1,72 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/coinbase/rosetta-ethereum/amount"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// checkBalance returns ErrInsufficientFunds if the sender of the
+// transaction described by input cannot pay for its value and its
+// fees (the gas price times the gas limit) at the pending state of
+// the node, so the transaction is not rejected once it is signed.
+func (s *ConstructionAPIService) checkBalance(
+	ctx context.Context,
+	input *options,
+	fees *metadataFees,
+) *types.Error {
+	value := new(big.Int)
+	if len(input.Value) > 0 {
+		var err error
+		value, err = amount.ParseUnsigned(input.Value)
+		if err != nil {
+			return wrapErr(
+				ErrUnableToParseIntermediateResult,
+				fmt.Errorf("%w: %s is not a valid amount", err, input.Value),
+			)
+		}
+	}
+
+	fee := new(big.Int).Mul(fees.gasPrice, new(big.Int).SetUint64(fees.gasLimit))
+	required := new(big.Int).Add(value, fee)
+
+	balance, err := s.client.PendingBalanceAt(ctx, common.HexToAddress(input.From))
+	if err != nil {
+		return wrapErr(ErrGeth, err)
+	}
+
+	if balance.Cmp(required) < 0 {
+		return wrapErr(
+			ErrInsufficientFunds,
+			fmt.Errorf(
+				"%s has a pending balance of %s but %s is required (%s value and %s fee)",
+				input.From,
+				balance.String(),
+				required.String(),
+				value.String(),
+				fee.String(),
+			),
+		)
+	}
+
+	return nil
+}
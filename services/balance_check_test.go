@@ -0,0 +1,137 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/services"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstructionMetadata_CheckBalance(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    ethereum.RopstenNetwork,
+		Blockchain: ethereum.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:    configuration.Online,
+		Network: networkIdentifier,
+		Params:  params.RopstenChainConfig,
+	}
+
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, nil, nil, nil)
+	ctx := context.Background()
+
+	from := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	to := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	ops := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{Index: 0},
+			Type:                ethereum.CallOpType,
+			Account:             &types.AccountIdentifier{Address: from.Hex()},
+			Amount:              &types.Amount{Value: "-1000", Currency: ethereum.Currency},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{Index: 1},
+			Type:                ethereum.CallOpType,
+			Account:             &types.AccountIdentifier{Address: to.Hex()},
+			Amount:              &types.Amount{Value: "1000", Currency: ethereum.Currency},
+		},
+	}
+
+	// The value of transfers is only populated
+	// when the balance is checked.
+	preprocessResponse, err := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          map[string]interface{}{"check_balance": true},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, forceMarshalMap(t, &options{
+		From:         from.Hex(),
+		Value:        "1000",
+		CheckBalance: true,
+	}), preprocessResponse.Options)
+
+	// The fee is 21000 * 1000 = 21000000.
+	mockClient.On("SuggestGasPrice", ctx).Return(big.NewInt(1000), nil).Twice()
+	mockClient.On("PendingBalanceAt", ctx, from).Return(big.NewInt(21001000), nil).Once()
+	mockClient.On("PendingNonceAt", ctx, from).Return(uint64(3), nil).Once()
+	metadataResponse, err := servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           preprocessResponse.Options,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, forceMarshalMap(t, &metadata{
+		Nonce:    3,
+		GasPrice: big.NewInt(1000),
+	}), metadataResponse.Metadata)
+
+	// No nonce is fetched when the balance is insufficient.
+	mockClient.On("PendingBalanceAt", ctx, from).Return(big.NewInt(21000999), nil).Once()
+	metadataResponse, err = servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           preprocessResponse.Options,
+	})
+	assert.Nil(t, metadataResponse)
+	assert.Equal(t, ErrInsufficientFunds.Code, err.Code)
+	assert.Equal(
+		t,
+		from.Hex()+" has a pending balance of 21000999 but 21001000 is required (1000 value and 21000000 fee)",
+		err.Details["context"],
+	)
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestConstructionPreprocess_CheckBalanceDelegate(t *testing.T) {
+	servicer := NewConstructionAPIService(&configuration.Configuration{}, nil, nil, nil, nil)
+
+	from := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	validator := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	preprocessResponse, err := servicer.ConstructionPreprocess(
+		context.Background(),
+		&types.ConstructionPreprocessRequest{
+			Operations: []*types.Operation{
+				{
+					OperationIdentifier: &types.OperationIdentifier{Index: 0},
+					Type:                ethereum.DelegateOpType,
+					Account:             &types.AccountIdentifier{Address: from.Hex()},
+					Amount:              &types.Amount{Value: "-1000", Currency: ethereum.Currency},
+					Metadata:            map[string]interface{}{"validator": validator.Hex()},
+				},
+			},
+			Metadata: map[string]interface{}{"check_balance": true},
+		},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, forceMarshalMap(t, &options{
+		From:         from.Hex(),
+		Validator:    validator.Hex(),
+		Value:        "1000",
+		CheckBalance: true,
+	}), preprocessResponse.Options)
+}
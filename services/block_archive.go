@@ -0,0 +1,156 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/archive"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+const (
+	// archiveManifestTTL is how long the head of the
+	// manifest of the archive is used before it is
+	// read again.
+	archiveManifestTTL = time.Minute
+)
+
+// archiveClient is a Client that serves
+// archived blocks from a BlockArchive.
+type archiveClient struct {
+	Client
+
+	archive BlockArchive
+	now     func() time.Time
+
+	// head is the index of the head of the manifest
+	// (-1 if nothing is archived), read at most once
+	// per archiveManifestTTL.
+	mutex      sync.Mutex
+	head       int64
+	headReadAt time.Time
+}
+
+// ArchiveClient returns a Client that serves blocks requested
+// by index from blockArchive, so /block keeps working for
+// blocks the node no longer has. Blocks that are not archived
+// (and requests by hash only or for the current block) are
+// fetched from client. Archived blocks are always returned
+// in full, regardless of the inline transaction limit. Blocks
+// above the head of the manifest are not looked up in the
+// archive.
+func ArchiveClient(client Client, blockArchive BlockArchive) Client {
+	return &archiveClient{
+		Client:  client,
+		archive: blockArchive,
+		now:     time.Now,
+		head:    -1,
+	}
+}
+
+// Block implements Client.
+func (c *archiveClient) Block(
+	ctx context.Context,
+	blockIdentifier *types.PartialBlockIdentifier,
+) (*types.Block, error) {
+	if block := c.archived(ctx, blockIdentifier); block != nil {
+		return block, nil
+	}
+
+	return c.Client.Block(ctx, blockIdentifier)
+}
+
+// BlockPage implements Client.
+func (c *archiveClient) BlockPage(
+	ctx context.Context,
+	blockIdentifier *types.PartialBlockIdentifier,
+	limit int,
+) (*types.Block, []*types.TransactionIdentifier, error) {
+	if block := c.archived(ctx, blockIdentifier); block != nil {
+		return block, nil, nil
+	}
+
+	return c.Client.BlockPage(ctx, blockIdentifier, limit)
+}
+
+// archived returns the archived block at blockIdentifier.
+// If the block is not archived (or the archive cannot be
+// read), nil is returned.
+func (c *archiveClient) archived(
+	ctx context.Context,
+	blockIdentifier *types.PartialBlockIdentifier,
+) *types.Block {
+	if blockIdentifier == nil || blockIdentifier.Index == nil {
+		return nil
+	}
+
+	if *blockIdentifier.Index > c.archiveHead(ctx) {
+		return nil
+	}
+
+	block, err := c.archive.Block(ctx, *blockIdentifier.Index)
+	if err != nil {
+		if !errors.Is(err, archive.ErrNotFound) && ctx.Err() == nil {
+			log.Printf("unable to read block %d from archive: %s", *blockIdentifier.Index, err.Error())
+		}
+
+		return nil
+	}
+
+	if blockIdentifier.Hash != nil && *blockIdentifier.Hash != block.BlockIdentifier.Hash {
+		return nil
+	}
+
+	return block
+}
+
+// archiveHead returns the index of the head of the manifest
+// of the archive. If the manifest cannot be read, the head
+// last read is returned.
+func (c *archiveClient) archiveHead(ctx context.Context) int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.now().Sub(c.headReadAt) < archiveManifestTTL {
+		return c.head
+	}
+
+	manifest, err := c.archive.Manifest(ctx)
+	if err != nil {
+		// Failures are not retried before the ttl
+		// either, so the store is not polled on
+		// every request while it is down.
+		if ctx.Err() == nil {
+			log.Printf("unable to read archive manifest: %s", err.Error())
+			c.headReadAt = c.now()
+		}
+
+		return c.head
+	}
+
+	c.headReadAt = c.now()
+	c.head = -1
+	if manifest.Head != nil {
+		c.head = manifest.Head.Index
+	}
+
+	return c.head
+}
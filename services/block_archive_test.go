@@ -0,0 +1,111 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/archive"
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/services"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveClient(t *testing.T) {
+	ctx := context.Background()
+	mockClient := &mocks.Client{}
+	mockArchive := &mocks.BlockArchive{}
+	client := ArchiveClient(mockClient, mockArchive)
+	now := time.Unix(1600000000, 0)
+	client.(*archiveClient).now = func() time.Time { return now }
+	mockArchive.On("Manifest", ctx).Return(&archive.Manifest{
+		Head: &types.BlockIdentifier{Index: 11, Hash: "block 11"},
+	}, nil).Once()
+
+	archived := &types.Block{
+		BlockIdentifier: &types.BlockIdentifier{
+			Index: 10,
+			Hash:  "block 10",
+		},
+	}
+	fetched := &types.Block{
+		BlockIdentifier: &types.BlockIdentifier{
+			Index: 11,
+			Hash:  "block 11",
+		},
+	}
+
+	// Archived blocks are served from the archive.
+	mockArchive.On("Block", ctx, int64(10)).Return(archived, nil)
+	block, err := client.Block(ctx, &types.PartialBlockIdentifier{Index: types.Int64(10)})
+	assert.NoError(t, err)
+	assert.Equal(t, archived, block)
+
+	block, other, err := client.BlockPage(ctx, &types.PartialBlockIdentifier{
+		Index: types.Int64(10),
+		Hash:  types.String("block 10"),
+	}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, archived, block)
+	assert.Nil(t, other)
+
+	// A hash that does not match the archived
+	// block is looked up on the node.
+	wrongHash := &types.PartialBlockIdentifier{
+		Index: types.Int64(10),
+		Hash:  types.String("orphan"),
+	}
+	mockClient.On("Block", ctx, wrongHash).Return(nil, errors.New("not found")).Once()
+	_, err = client.Block(ctx, wrongHash)
+	assert.Error(t, err)
+
+	// Blocks that are not archived are fetched from the node.
+	notArchived := &types.PartialBlockIdentifier{Index: types.Int64(11)}
+	mockArchive.On("Block", ctx, int64(11)).Return(nil, fmt.Errorf("%w: 11", archive.ErrNotFound))
+	mockClient.On("Block", ctx, notArchived).Return(fetched, nil).Once()
+	block, err = client.Block(ctx, notArchived)
+	assert.NoError(t, err)
+	assert.Equal(t, fetched, block)
+
+	// Blocks above the head of the manifest are
+	// fetched from the node without looking them
+	// up in the archive.
+	aboveHead := &types.PartialBlockIdentifier{Index: types.Int64(12)}
+	mockClient.On("Block", ctx, aboveHead).Return(fetched, nil).Once()
+	_, err = client.Block(ctx, aboveHead)
+	assert.NoError(t, err)
+
+	// The manifest is read again after the ttl,
+	// and its last head is kept if it cannot be.
+	now = now.Add(archiveManifestTTL)
+	mockArchive.On("Manifest", ctx).Return(nil, errors.New("unavailable")).Once()
+	block, err = client.Block(ctx, &types.PartialBlockIdentifier{Index: types.Int64(10)})
+	assert.NoError(t, err)
+	assert.Equal(t, archived, block)
+
+	// The current block is always fetched from the node.
+	mockClient.On("BlockPage", ctx, (*types.PartialBlockIdentifier)(nil), 1).Return(fetched, nil, nil).Once()
+	block, _, err = client.BlockPage(ctx, nil, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, fetched, block)
+
+	mockClient.AssertExpectations(t)
+	mockArchive.AssertExpectations(t)
+}
@@ -0,0 +1,232 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/coinbase/rosetta-ethereum/canonical"
+
+	"github.com/coinbase/rosetta-sdk-go/asserter"
+	"github.com/coinbase/rosetta-sdk-go/server"
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+const (
+	// blockEncoderBufferSize is the size of the buffer
+	// /block responses are streamed through.
+	blockEncoderBufferSize = 64 * 1024
+)
+
+// blockRouter serves /block with a streaming encoder and
+// the other routes of the block API with server.BlockAPIController.
+type blockRouter struct {
+	router   server.Router
+	service  server.BlockAPIServicer
+	asserter *asserter.Asserter
+}
+
+// newBlockRouter creates a server.Router serving the block API.
+func newBlockRouter(
+	service server.BlockAPIServicer,
+	asserter *asserter.Asserter,
+) server.Router {
+	return &blockRouter{
+		router:   server.NewBlockAPIController(service, asserter),
+		service:  service,
+		asserter: asserter,
+	}
+}
+
+// Routes returns the routes of the block API.
+func (c *blockRouter) Routes() server.Routes {
+	routes := c.router.Routes()
+	for i := range routes {
+		if routes[i].Pattern == "/block" {
+			routes[i].HandlerFunc = c.Block
+		}
+	}
+
+	return routes
+}
+
+// Block - Get a Block
+func (c *blockRouter) Block(w http.ResponseWriter, r *http.Request) {
+	blockRequest := &types.BlockRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&blockRequest); err != nil {
+		server.EncodeJSONResponse(&types.Error{
+			Message: err.Error(),
+		}, http.StatusInternalServerError, w)
+		return
+	}
+
+	if err := c.asserter.BlockRequest(blockRequest); err != nil {
+		server.EncodeJSONResponse(&types.Error{
+			Message: err.Error(),
+		}, http.StatusInternalServerError, w)
+		return
+	}
+
+	result, serviceErr := c.service.Block(r.Context(), blockRequest)
+	if serviceErr != nil {
+		server.EncodeJSONResponse(serviceErr, http.StatusInternalServerError, w)
+		return
+	}
+
+	// The middleware that would otherwise buffer the
+	// response to validate or re-encode it leaves that
+	// to the router (see blockStream).
+	stream := blockStreamFrom(r.Context())
+	if stream != nil && stream.validator != nil && result.Block != nil {
+		if err := stream.validator.asserter.Block(result.Block); err != nil {
+			stream.validator.reject(w, r, err)
+			return
+		}
+	}
+
+	// Once the status is written, encoding errors can
+	// only be surfaced by truncating the response.
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := encodeBlockResponse(w, result, stream != nil && stream.canonical); err != nil {
+		log.Printf("unable to encode block response: %s", err.Error())
+		return
+	}
+
+	if stream != nil {
+		for _, served := range stream.served {
+			served(result)
+		}
+	}
+}
+
+// blockField is a key of a block and
+// the function that encodes its value.
+type blockField struct {
+	key    string
+	encode func() error
+}
+
+// encodeBlockResponse writes response to w one transaction at
+// a time, so that the encoding of the whole response is never
+// held in memory. The output is identical to the output of
+// json.Encoder or, if canonicalJSON is true, to the output of
+// canonical.Transform.
+func encodeBlockResponse( // nolint:gocyclo
+	w io.Writer,
+	response *types.BlockResponse,
+	canonicalJSON bool,
+) error {
+	bw := bufio.NewWriterSize(w, blockEncoderBufferSize)
+	enc := json.NewEncoder(valueWriter{w: bw})
+	encode := enc.Encode
+	if canonicalJSON {
+		encode = func(v interface{}) error {
+			encoded, err := canonical.Marshal(v)
+			if err != nil {
+				return err
+			}
+
+			_, err = bw.Write(encoded)
+			return err
+		}
+	}
+
+	bw.WriteByte('{')
+	if block := response.Block; block != nil {
+		fields := []blockField{
+			{"block_identifier", func() error { return encode(block.BlockIdentifier) }},
+			{"parent_block_identifier", func() error { return encode(block.ParentBlockIdentifier) }},
+			{"timestamp", func() error {
+				_, err := bw.WriteString(strconv.FormatInt(block.Timestamp, 10))
+				return err
+			}},
+			{"transactions", func() error {
+				if block.Transactions == nil {
+					_, err := bw.WriteString("null")
+					return err
+				}
+
+				bw.WriteByte('[')
+				for i, tx := range block.Transactions {
+					if i > 0 {
+						bw.WriteByte(',')
+					}
+					if err := encode(tx); err != nil {
+						return err
+					}
+				}
+				return bw.WriteByte(']')
+			}},
+		}
+		if len(block.Metadata) > 0 {
+			fields = append(fields, blockField{"metadata", func() error { return encode(block.Metadata) }})
+		}
+		if canonicalJSON {
+			// The keys are ASCII, so sorting them by their bytes
+			// sorts them by their UTF-16 code units too.
+			sort.Slice(fields, func(i, j int) bool { return fields[i].key < fields[j].key })
+		}
+
+		bw.WriteString(`"block":{`)
+		for i, field := range fields {
+			if i > 0 {
+				bw.WriteByte(',')
+			}
+			bw.WriteString(`"` + field.key + `":`)
+			if err := field.encode(); err != nil {
+				return err
+			}
+		}
+		bw.WriteByte('}')
+	}
+	if len(response.OtherTransactions) > 0 {
+		if response.Block != nil {
+			bw.WriteByte(',')
+		}
+		bw.WriteString(`"other_transactions":`)
+		if err := encode(response.OtherTransactions); err != nil {
+			return err
+		}
+	}
+	bw.WriteByte('}')
+	if !canonicalJSON {
+		bw.WriteByte('\n')
+	}
+
+	return bw.Flush()
+}
+
+// valueWriter writes the values encoded by a json.Encoder
+// without the newline the encoder terminates them with.
+type valueWriter struct {
+	w io.Writer
+}
+
+// Write implements io.Writer.
+func (v valueWriter) Write(b []byte) (int, error) {
+	if _, err := v.w.Write(bytes.TrimSuffix(b, []byte("\n"))); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
@@ -0,0 +1,347 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/canonical"
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/services"
+
+	"github.com/coinbase/rosetta-sdk-go/asserter"
+	"github.com/coinbase/rosetta-sdk-go/server"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// testEncoderBlock returns a block with txs transactions
+// of 10 operations each.
+func testEncoderBlock(txs int) *types.Block {
+	block := &types.Block{
+		BlockIdentifier: &types.BlockIdentifier{
+			Index: 100,
+			Hash:  "0x0bd47de6a8d5a0ed0e5ee4c6ae2a0c0a2ad5a0ed0e5ee4c6ae2a0c0a2ad5a0e",
+		},
+		ParentBlockIdentifier: &types.BlockIdentifier{
+			Index: 99,
+			Hash:  "0x1fa2b6c8d5a0ed0e5ee4c6ae2a0c0a2ad5a0ed0e5ee4c6ae2a0c0a2ad5a0ed0",
+		},
+		Timestamp:    1609459200000,
+		Transactions: []*types.Transaction{},
+		Metadata:     map[string]interface{}{"gas_used": "0x1c9c380"},
+	}
+	for i := 0; i < txs; i++ {
+		tx := &types.Transaction{
+			TransactionIdentifier: &types.TransactionIdentifier{
+				Hash: fmt.Sprintf("0x%064x", i),
+			},
+			Metadata: map[string]interface{}{"gas_limit": "0x5208", "gas_price": "0x3b9aca00"},
+		}
+		for j := 0; j < 10; j++ {
+			tx.Operations = append(tx.Operations, &types.Operation{
+				OperationIdentifier: &types.OperationIdentifier{Index: int64(j)},
+				Type:                ethereum.CallOpType,
+				Status:              types.String(ethereum.SuccessStatus),
+				Account:             &types.AccountIdentifier{Address: fmt.Sprintf("0x%040x", i*10+j)},
+				Amount: &types.Amount{
+					Value:    "-1000000000000000000",
+					Currency: ethereum.Currency,
+				},
+				Metadata: map[string]interface{}{"<html>": "escaped & encoded"},
+			})
+		}
+		block.Transactions = append(block.Transactions, tx)
+	}
+
+	return block
+}
+
+func TestEncodeBlockResponse(t *testing.T) {
+	tests := map[string]*types.BlockResponse{
+		"block": {
+			Block: testEncoderBlock(3),
+		},
+		"empty block": {
+			Block: &types.Block{
+				BlockIdentifier:       &types.BlockIdentifier{Index: 1, Hash: "0x1"},
+				ParentBlockIdentifier: &types.BlockIdentifier{Index: 0, Hash: "0x0"},
+				Transactions:          []*types.Transaction{},
+			},
+		},
+		"nil transactions": {
+			Block: &types.Block{
+				BlockIdentifier:       &types.BlockIdentifier{Index: 1, Hash: "0x1"},
+				ParentBlockIdentifier: &types.BlockIdentifier{Index: 0, Hash: "0x0"},
+			},
+		},
+		"other transactions": {
+			Block: testEncoderBlock(1),
+			OtherTransactions: []*types.TransactionIdentifier{
+				{Hash: "0x2"},
+				{Hash: "0x3"},
+			},
+		},
+		"only other transactions": {
+			OtherTransactions: []*types.TransactionIdentifier{
+				{Hash: "0x2"},
+			},
+		},
+		"empty": {},
+	}
+
+	for name, response := range tests {
+		t.Run(name, func(t *testing.T) {
+			var expected bytes.Buffer
+			assert.NoError(t, json.NewEncoder(&expected).Encode(response))
+
+			var encoded bytes.Buffer
+			assert.NoError(t, encodeBlockResponse(&encoded, response, false))
+			assert.Equal(t, expected.String(), encoded.String())
+
+			expectedCanonical, err := canonical.Transform(expected.Bytes())
+			assert.NoError(t, err)
+
+			encoded.Reset()
+			assert.NoError(t, encodeBlockResponse(&encoded, response, true))
+			assert.Equal(t, string(expectedCanonical), encoded.String())
+		})
+	}
+
+	t.Run("invalid metadata", func(t *testing.T) {
+		block := testEncoderBlock(2)
+		block.Transactions[1].Metadata["fee"] = math.NaN()
+
+		var encoded bytes.Buffer
+		assert.Error(t, encodeBlockResponse(&encoded, &types.BlockResponse{Block: block}, false))
+		assert.Error(t, encodeBlockResponse(&encoded, &types.BlockResponse{Block: block}, true))
+	})
+}
+
+func TestBlockchainRouter_Block(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode:                   configuration.Online,
+		Network:                networkIdentifier,
+		GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+	}
+
+	serverAsserter, err := asserter.NewServer(
+		ethereum.OperationTypes,
+		ethereum.HistoricalBalanceSupported,
+		[]*types.NetworkIdentifier{networkIdentifier},
+		ethereum.CallMethods,
+		ethereum.IncludeMempoolCoins,
+		"",
+	)
+	assert.NoError(t, err)
+
+	mockClient := &mocks.Client{}
+	router := NewBlockchainRouter(cfg, mockClient, nil, nil, nil, nil, nil, serverAsserter)
+
+	block := testEncoderBlock(5)
+	index := int64(100)
+	missing := int64(101)
+	mockClient.On(
+		"Block",
+		mock.Anything,
+		&types.PartialBlockIdentifier{Index: &index},
+	).Return(block, nil).Once()
+	mockClient.On(
+		"Block",
+		mock.Anything,
+		&types.PartialBlockIdentifier{Index: &missing},
+	).Return(nil, ethereum.ErrBlockOrphaned).Once()
+
+	serve := func(index int64) *httptest.ResponseRecorder {
+		body, err := json.Marshal(&types.BlockRequest{
+			NetworkIdentifier: networkIdentifier,
+			BlockIdentifier:   &types.PartialBlockIdentifier{Index: &index},
+		})
+		assert.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/block", bytes.NewReader(body)))
+		return w
+	}
+
+	// Streamed responses are identical to the
+	// responses of server.BlockAPIController.
+	expected := httptest.NewRecorder()
+	server.EncodeJSONResponse(&types.BlockResponse{Block: block}, http.StatusOK, expected)
+
+	w := serve(index)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, expected.Header(), w.Header())
+	assert.Equal(t, expected.Body.String(), w.Body.String())
+
+	w = serve(missing)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	var rosettaErr types.Error
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rosettaErr))
+	assert.Equal(t, ErrBlockOrphaned.Code, rosettaErr.Code)
+
+	// Requests are still asserted.
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/block", bytes.NewReader([]byte(`{}`))))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	mockClient.AssertExpectations(t)
+}
+
+// discardResponseWriter is an http.ResponseWriter
+// discarding everything written to it.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *discardResponseWriter) WriteHeader(int) {}
+
+// releaseEncoderBuffers empties the sync.Pool of encoding
+// buffers of encoding/json, which takes two collections.
+func releaseEncoderBuffers(b *testing.B) {
+	b.StopTimer()
+	runtime.GC()
+	runtime.GC()
+	b.StartTimer()
+}
+
+// BenchmarkBlockResponse compares the allocations of encoding a
+// multi-megabyte /block response with server.EncodeJSONResponse
+// and with encodeBlockResponse. The buffers json.Encoder pools
+// are released before every iteration, as they are between
+// requests for large blocks on a live server.
+func BenchmarkBlockResponse(b *testing.B) {
+	response := &types.BlockResponse{Block: testEncoderBlock(2000)}
+	var encoded bytes.Buffer
+	assert.NoError(b, json.NewEncoder(&encoded).Encode(response))
+	b.Logf("response size: %d bytes", encoded.Len())
+
+	b.Run("EncodeJSONResponse", func(b *testing.B) {
+		w := &discardResponseWriter{header: http.Header{}}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			releaseEncoderBuffers(b)
+			server.EncodeJSONResponse(response, http.StatusOK, w)
+		}
+	})
+
+	b.Run("encodeBlockResponse", func(b *testing.B) {
+		w := &discardResponseWriter{header: http.Header{}}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			releaseEncoderBuffers(b)
+			if err := encodeBlockResponse(w, response, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkBlockHandlerChain measures the allocations of serving
+// a multi-megabyte block through the middleware that rosetta-core
+// wraps the block API with (see cmd/run.go), with STRICT validation,
+// canonical JSON, served block watermarks, and public mode enabled.
+// Every request is for a different block, so cached responses are
+// never served.
+func BenchmarkBlockHandlerChain(b *testing.B) {
+	block := testEncoderBlock(2000)
+	serverAsserter, err := asserter.NewServer(
+		ethereum.OperationTypes,
+		ethereum.HistoricalBalanceSupported,
+		[]*types.NetworkIdentifier{networkIdentifier},
+		ethereum.CallMethods,
+		ethereum.IncludeMempoolCoins,
+		"",
+	)
+	assert.NoError(b, err)
+
+	for name, cacheSize := range map[string]int{"uncached": 0, "cached": 1} {
+		b.Run(name, func(b *testing.B) {
+			cfg := &configuration.Configuration{
+				Mode:                   configuration.Online,
+				Network:                networkIdentifier,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				ValidationMode:         configuration.StrictValidation,
+				CanonicalJSON:          true,
+				PublicMode:             true,
+				ResponseCacheSize:      cacheSize,
+				ResponseCacheTTL:       time.Minute,
+			}
+
+			mockClient := &mocks.Client{}
+			mockClient.On("Block", mock.Anything, mock.Anything).Return(block, nil)
+			mockClient.On("Status", mock.Anything).Return(
+				&types.BlockIdentifier{Index: 1000, Hash: "0x1000"},
+				int64(0),
+				nil,
+				nil,
+				nil,
+			)
+			mockWatermarks := &mocks.Watermarks{}
+			mockWatermarks.On("Served", mock.Anything)
+
+			validated, err := ValidationMiddleware(
+				cfg,
+				NewBlockchainRouter(cfg, mockClient, nil, nil, nil, nil, nil, serverAsserter),
+			)
+			assert.NoError(b, err)
+			handler := PublicMiddleware(cfg, CacheMiddleware(cfg, mockClient, nil, CanonicalJSONMiddleware(
+				cfg,
+				WatermarkMiddleware(mockWatermarks, validated),
+			)))
+
+			requests := make([][]byte, b.N+1)
+			for i := range requests {
+				index := int64(i)
+				requests[i], err = json.Marshal(&types.BlockRequest{
+					NetworkIdentifier: networkIdentifier,
+					BlockIdentifier:   &types.PartialBlockIdentifier{Index: &index},
+				})
+				assert.NoError(b, err)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/block", bytes.NewReader(requests[b.N])))
+			assert.Equal(b, http.StatusOK, w.Code)
+			b.Logf("response size: %d bytes", w.Body.Len())
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				releaseEncoderBuffers(b)
+				w := &discardResponseWriter{header: http.Header{}}
+				handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/block", bytes.NewReader(requests[i])))
+			}
+		})
+	}
+}
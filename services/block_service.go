@@ -26,8 +26,9 @@ import (
 
 // BlockAPIService implements the server.BlockAPIServicer interface.
 type BlockAPIService struct {
-	config *configuration.Configuration
-	client Client
+	config   *configuration.Configuration
+	client   Client
+	watchdog *blockWatchdog
 }
 
 // NewBlockAPIService creates a new instance of a BlockAPIService.
@@ -36,8 +37,9 @@ func NewBlockAPIService(
 	client Client,
 ) *BlockAPIService {
 	return &BlockAPIService{
-		config: cfg,
-		client: client,
+		config:   cfg,
+		client:   client,
+		watchdog: newBlockWatchdog(),
 	}
 }
 
@@ -50,16 +52,41 @@ func (s *BlockAPIService) Block(
 		return nil, ErrUnavailableOffline
 	}
 
-	block, err := s.client.Block(ctx, request.BlockIdentifier)
+	if err := s.watchdog.check(request.BlockIdentifier); err != nil {
+		return nil, err
+	}
+
+	// Transactions that are not inlined are only traced
+	// when they are fetched with /block/transaction.
+	var block *types.Block
+	var otherTransactions []*types.TransactionIdentifier
+	var err error
+	if limit := s.config.InlineTransactions(); limit > 0 {
+		block, otherTransactions, err = s.client.BlockPage(ctx, request.BlockIdentifier, limit)
+	} else {
+		block, err = s.client.Block(ctx, request.BlockIdentifier)
+	}
 	if errors.Is(err, ethereum.ErrBlockOrphaned) {
 		return nil, wrapErr(ErrBlockOrphaned, err)
 	}
 	if err != nil {
+		// Failures caused by the client going away say
+		// nothing about the block.
+		if ctx.Err() == nil {
+			s.watchdog.failed(request.BlockIdentifier, err)
+		}
+
+		if errors.Is(err, ethereum.ErrInvariantViolated) {
+			return nil, wrapErr(ErrInvariantViolated, err)
+		}
+
 		return nil, wrapErr(ErrGeth, err)
 	}
+	s.watchdog.succeeded(request.BlockIdentifier)
 
 	return &types.BlockResponse{
-		Block: block,
+		Block:             block,
+		OtherTransactions: otherTransactions,
 	}, nil
 }
 
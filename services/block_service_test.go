@@ -17,7 +17,9 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/coinbase/rosetta-ethereum/configuration"
 	"github.com/coinbase/rosetta-ethereum/ethereum"
@@ -91,6 +93,22 @@ func TestBlockService_Online(t *testing.T) {
 		assert.Equal(t, blockResponse, b)
 	})
 
+	t.Run("invariant violated", func(t *testing.T) {
+		pbIdentifier := types.ConstructPartialBlockIdentifier(block.BlockIdentifier)
+		mockClient.On("Block", ctx, pbIdentifier).Return(
+			nil,
+			fmt.Errorf("%w: block 100", ethereum.ErrInvariantViolated),
+		).Once()
+		b, err := servicer.Block(ctx, &types.BlockRequest{
+			BlockIdentifier: pbIdentifier,
+		})
+
+		assert.Nil(t, b)
+		assert.Equal(t, ErrInvariantViolated.Code, err.Code)
+		assert.Equal(t, ErrInvariantViolated.Message, err.Message)
+		assert.Contains(t, err.Details["context"], "block 100")
+	})
+
 	t.Run("orphaned block", func(t *testing.T) {
 		pbIdentifier := types.ConstructPartialBlockIdentifier(block.BlockIdentifier)
 		mockClient.On("Block", ctx, pbIdentifier).Return(nil, ethereum.ErrBlockOrphaned).Once()
@@ -107,6 +125,168 @@ func TestBlockService_Online(t *testing.T) {
 	mockClient.AssertExpectations(t)
 }
 
+func TestBlockService_InlineTransactionLimit(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode:                    configuration.Online,
+		BlockInlineTransactions: 2,
+	}
+	mockClient := &mocks.Client{}
+	servicer := NewBlockAPIService(cfg, mockClient)
+	ctx := context.Background()
+
+	blockIdentifier := &types.BlockIdentifier{
+		Index: 100,
+		Hash:  "block 100",
+	}
+	parentBlockIdentifier := &types.BlockIdentifier{
+		Index: 99,
+		Hash:  "block 99",
+	}
+	transactions := []*types.Transaction{
+		{TransactionIdentifier: &types.TransactionIdentifier{Hash: "block 100"}},
+		{TransactionIdentifier: &types.TransactionIdentifier{Hash: "tx 1"}},
+		{TransactionIdentifier: &types.TransactionIdentifier{Hash: "tx 2"}},
+		{TransactionIdentifier: &types.TransactionIdentifier{Hash: "tx 3"}},
+	}
+
+	t.Run("under limit", func(t *testing.T) {
+		block := &types.Block{
+			BlockIdentifier:       blockIdentifier,
+			ParentBlockIdentifier: parentBlockIdentifier,
+			Timestamp:             1000,
+			Transactions:          transactions[:2],
+		}
+		pbIdentifier := types.ConstructPartialBlockIdentifier(blockIdentifier)
+		mockClient.On("BlockPage", ctx, pbIdentifier, 2).Return(block, nil, nil).Once()
+		b, err := servicer.Block(ctx, &types.BlockRequest{
+			BlockIdentifier: pbIdentifier,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, &types.BlockResponse{Block: block}, b)
+	})
+
+	t.Run("over limit", func(t *testing.T) {
+		block := &types.Block{
+			BlockIdentifier:       blockIdentifier,
+			ParentBlockIdentifier: parentBlockIdentifier,
+			Timestamp:             1000,
+			Transactions:          transactions[:2],
+		}
+		pbIdentifier := types.ConstructPartialBlockIdentifier(blockIdentifier)
+		mockClient.On("BlockPage", ctx, pbIdentifier, 2).Return(
+			block,
+			[]*types.TransactionIdentifier{
+				transactions[2].TransactionIdentifier,
+				transactions[3].TransactionIdentifier,
+			},
+			nil,
+		).Once()
+		b, err := servicer.Block(ctx, &types.BlockRequest{
+			BlockIdentifier: pbIdentifier,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, &types.BlockResponse{
+			Block: &types.Block{
+				BlockIdentifier:       blockIdentifier,
+				ParentBlockIdentifier: parentBlockIdentifier,
+				Timestamp:             1000,
+				Transactions:          transactions[:2],
+			},
+			OtherTransactions: []*types.TransactionIdentifier{
+				{Hash: "tx 2"},
+				{Hash: "tx 3"},
+			},
+		}, b)
+	})
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestBlockService_Poisoned(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode: configuration.Online,
+	}
+	mockClient := &mocks.Client{}
+	servicer := NewBlockAPIService(cfg, mockClient)
+	now := time.Unix(1600000000, 0)
+	servicer.watchdog.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	block := &types.Block{
+		BlockIdentifier: &types.BlockIdentifier{
+			Index: 100,
+			Hash:  "block 100",
+		},
+	}
+	pbIdentifier := &types.PartialBlockIdentifier{Index: &block.BlockIdentifier.Index}
+	request := &types.BlockRequest{BlockIdentifier: pbIdentifier}
+	traceErr := fmt.Errorf("%w: out of memory", ethereum.ErrBlockUnconvertible)
+
+	// Failures to fetch the block are not counted
+	fetchErr := errors.New("connection refused")
+	mockClient.On("Block", ctx, pbIdentifier).Return(nil, fetchErr).Times(maxBlockFailures)
+	for i := 0; i < maxBlockFailures; i++ {
+		b, err := servicer.Block(ctx, request)
+		assert.Nil(t, b)
+		assert.Equal(t, ErrGeth.Code, err.Code)
+	}
+	mockClient.AssertExpectations(t)
+
+	// The block is fetched until it has failed maxBlockFailures times
+	mockClient.On("Block", ctx, pbIdentifier).Return(nil, traceErr).Times(maxBlockFailures)
+	for i := 0; i < maxBlockFailures; i++ {
+		b, err := servicer.Block(ctx, request)
+		assert.Nil(t, b)
+		assert.Equal(t, ErrGeth.Code, err.Code)
+	}
+
+	// Poisoned blocks are not fetched
+	b, err := servicer.Block(ctx, request)
+	assert.Nil(t, b)
+	assert.Equal(t, ErrBlockPoisoned.Code, err.Code)
+	assert.Equal(t, ErrBlockPoisoned.Message, err.Message)
+	assert.True(t, err.Retriable)
+	assert.Equal(t, maxBlockFailures, err.Details["attempts"])
+	assert.Equal(t, traceErr.Error(), err.Details["context"])
+	mockClient.AssertExpectations(t)
+
+	// A single attempt is made once the cooldown elapses
+	now = now.Add(poisonCooldown)
+	mockClient.On("Block", ctx, pbIdentifier).Return(nil, traceErr).Once()
+	_, err = servicer.Block(ctx, request)
+	assert.Equal(t, ErrGeth.Code, err.Code)
+	_, err = servicer.Block(ctx, request)
+	assert.Equal(t, ErrBlockPoisoned.Code, err.Code)
+	mockClient.AssertExpectations(t)
+
+	// A successful attempt removes the block from the poison list
+	now = now.Add(poisonCooldown)
+	mockClient.On("Block", ctx, pbIdentifier).Return(block, nil).Twice()
+	for i := 0; i < 2; i++ {
+		b, err = servicer.Block(ctx, request)
+		assert.Nil(t, err)
+		assert.Equal(t, block, b.Block)
+	}
+	mockClient.AssertExpectations(t)
+
+	// Failures of other blocks and of the tip are tracked independently
+	otherIndex := int64(101)
+	other := &types.PartialBlockIdentifier{Index: &otherIndex}
+	mockClient.On("Block", ctx, other).Return(nil, traceErr).Once()
+	_, err = servicer.Block(ctx, &types.BlockRequest{BlockIdentifier: other})
+	assert.Equal(t, ErrGeth.Code, err.Code)
+	mockClient.On(
+		"Block",
+		ctx,
+		(*types.PartialBlockIdentifier)(nil),
+	).Return(nil, traceErr).Times(maxBlockFailures + 1)
+	for i := 0; i <= maxBlockFailures; i++ {
+		_, err = servicer.Block(ctx, &types.BlockRequest{})
+		assert.Equal(t, ErrGeth.Code, err.Code)
+	}
+	mockClient.AssertExpectations(t)
+}
+
 func TestBlockTransactionService_Offline(t *testing.T) {
 	cfg := &configuration.Configuration{
 		Mode: configuration.Online,
@@ -0,0 +1,429 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/metrics"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+const (
+	// cacheFinalityDepth is the number of blocks a block must
+	// be buried under before its responses are cached forever.
+	cacheFinalityDepth = 30
+
+	// maxCachedResponseSize is the size of the
+	// largest response that is cached.
+	maxCachedResponseSize = 16 * 1024 * 1024
+
+	cacheHitsMetric   = "cache/hits"
+	cacheMissesMetric = "cache/misses"
+)
+
+// cacheEntry is a cached response. Entries with
+// a zero expiration never expire. Entries for a
+// block hold its identifier and the sequence of
+// the next head event when they were served.
+type cacheEntry struct {
+	key        [sha256.Size]byte
+	header     http.Header
+	body       []byte
+	expiration time.Time
+	block      *types.BlockIdentifier
+	sequence   int64
+}
+
+// ResponseCache caches the responses of idempotent endpoints,
+// evicting the least recently used entries once it is full
+// (see CacheMiddleware).
+type ResponseCache struct {
+	next   http.Handler
+	client Client
+	events *HeadEvents
+	size   int
+	ttl    time.Duration
+	now    func() time.Time
+
+	mutex   sync.Mutex
+	entries map[[sha256.Size]byte]*list.Element
+	order   *list.List
+
+	// head is the last chain head fetched from the
+	// client and is refreshed at most once per ttl.
+	head          int64
+	headFetchedAt time.Time
+}
+
+// CacheMiddleware returns a handler that caches successful
+// /block, /block/transaction, and /network/options responses
+// served by next, keyed by a hash of the path and the canonical
+// request body. Responses for blocks buried under
+// cacheFinalityDepth blocks never expire, while responses for
+// blocks near the tip expire after cfg.ResponseCacheTTL or,
+// if events is not nil, as soon as the block is reorged out.
+// /network/options responses also expire after
+// cfg.ResponseCacheTTL.
+// Responses are written through as they are served, and those
+// larger than maxCachedResponseSize are not cached. While
+// cfg.ResponseCacheSize is 0, requests are passed to next
+// unchanged.
+func CacheMiddleware(
+	cfg *configuration.Configuration,
+	client Client,
+	events *HeadEvents,
+	next http.Handler,
+) *ResponseCache {
+	c := &ResponseCache{
+		next:    next,
+		client:  client,
+		events:  events,
+		now:     time.Now,
+		entries: map[[sha256.Size]byte]*list.Element{},
+		order:   list.New(),
+	}
+	c.Reload(cfg)
+
+	return c
+}
+
+// Reload applies the ResponseCacheSize and ResponseCacheTTL of
+// cfg. Cached responses are kept, except for the least recently
+// used ones over the new size.
+func (c *ResponseCache) Reload(cfg *configuration.Configuration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.size, c.ttl = cfg.ResponseCacheConfig()
+	c.evict()
+}
+
+// ServeHTTP implements http.Handler.
+func (c *ResponseCache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if size, _ := c.settings(); size == 0 || !cacheable(r.URL.Path) {
+		c.next.ServeHTTP(w, r)
+		return
+	}
+
+	requestBody, err := readRequestBody(r)
+	if err != nil {
+		c.next.ServeHTTP(w, r)
+		return
+	}
+
+	key, ok := requestKey(r.URL.Path, requestBody)
+	if !ok {
+		c.next.ServeHTTP(w, r)
+		return
+	}
+
+	if entry := c.get(key); entry != nil {
+		metrics.Counter(cacheHitsMetric).Inc(1)
+		for k, v := range entry.header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(entry.body)
+		return
+	}
+	metrics.Counter(cacheMissesMetric).Inc(1)
+
+	// The sequence is read before the response is served so
+	// that blocks reorged out while it is being served are
+	// never cached as canonical.
+	var sequence int64
+	if c.events != nil {
+		sequence = c.events.Sequence()
+	}
+
+	writer := newCacheWriter(w)
+	c.next.ServeHTTP(writer, r)
+	if writer.status != http.StatusOK || writer.truncated {
+		return
+	}
+
+	expiration, block, ok := c.expiration(
+		r.Context(),
+		r.URL.Path,
+		requestBody,
+		writer.body.Bytes(),
+	)
+	if ok {
+		c.put(&cacheEntry{
+			key:        key,
+			header:     writer.header,
+			body:       writer.body.Bytes(),
+			expiration: expiration,
+			block:      block,
+			sequence:   sequence,
+		})
+	}
+}
+
+// cacheWriter writes a response through to w as it is
+// served while copying it, so that it can be cached
+// without delaying it. Responses larger than
+// maxCachedResponseSize are not copied in full and
+// are marked as truncated.
+type cacheWriter struct {
+	responseRecorder
+
+	w           http.ResponseWriter
+	wroteHeader bool
+	truncated   bool
+}
+
+func newCacheWriter(w http.ResponseWriter) *cacheWriter {
+	return &cacheWriter{
+		responseRecorder: *newResponseRecorder(),
+		w:                w,
+	}
+}
+
+// Write implements http.ResponseWriter.
+func (c *cacheWriter) Write(b []byte) (int, error) {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+
+	if !c.truncated && c.status == http.StatusOK {
+		if c.body.Len()+len(b) > maxCachedResponseSize {
+			c.truncated = true
+			c.body = bytes.Buffer{}
+		} else {
+			c.body.Write(b)
+		}
+	}
+
+	return c.w.Write(b)
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (c *cacheWriter) WriteHeader(status int) {
+	if c.wroteHeader {
+		return
+	}
+	c.wroteHeader = true
+	c.status = status
+
+	// The headers are cloned so that the cached
+	// headers are not changed once served.
+	for k, v := range c.header.Clone() {
+		c.w.Header()[k] = v
+	}
+	c.w.WriteHeader(status)
+}
+
+// Flush implements http.Flusher.
+func (c *cacheWriter) Flush() {
+	if flusher, ok := c.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// cacheable returns true if the responses
+// of path can be cached.
+func cacheable(path string) bool {
+	switch path {
+	case "/block", "/block/transaction", "/network/options":
+		return true
+	default:
+		return false
+	}
+}
+
+// requestKey hashes path and the canonical encoding of body
+// (object keys are sorted and whitespace is removed) so that
+// equivalent requests share a cache entry.
+func requestKey(path string, body []byte) ([sha256.Size]byte, bool) {
+	var request interface{}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return [sha256.Size]byte{}, false
+	}
+
+	canonical, err := json.Marshal(request)
+	if err != nil {
+		return [sha256.Size]byte{}, false
+	}
+
+	return sha256.Sum256(append([]byte(path+"\n"), canonical...)), true
+}
+
+// expiration returns when a response to path should expire
+// and the block it is for. A zero time means the response
+// never expires. If the response should not be cached, false
+// is returned.
+func (c *ResponseCache) expiration(
+	ctx context.Context,
+	path string,
+	requestBody []byte,
+	body []byte,
+) (time.Time, *types.BlockIdentifier, bool) {
+	_, ttl := c.settings()
+	var block *types.BlockIdentifier
+	switch path {
+	case "/network/options":
+		// Options change at runtime (i.e. with the
+		// hardforks and capabilities of the node).
+		return c.now().Add(ttl), nil, true
+	case "/block":
+		var ok bool
+		if block, ok = blockResponseIdentifier(body); !ok {
+			return time.Time{}, nil, false
+		}
+	case "/block/transaction":
+		var req types.BlockTransactionRequest
+		if err := json.Unmarshal(requestBody, &req); err != nil || req.BlockIdentifier == nil {
+			return time.Time{}, nil, false
+		}
+		block = req.BlockIdentifier
+	}
+
+	if head, ok := c.chainHead(ctx); ok && block.Index <= head-cacheFinalityDepth {
+		return time.Time{}, block, true
+	}
+
+	return c.now().Add(ttl), block, true
+}
+
+// settings returns the size and ttl of c, which
+// can change while it is serving (see Reload).
+func (c *ResponseCache) settings() (int, time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.size, c.ttl
+}
+
+// blockResponseIdentifier decodes the identifier of the block
+// of a /block response without decoding its transactions, as
+// encodeBlockResponse encodes the identifier first (whether it
+// encodes canonically or not). If the response has no block,
+// false is returned.
+func blockResponseIdentifier(body []byte) (*types.BlockIdentifier, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	for _, expected := range []json.Token{
+		json.Delim('{'),
+		"block",
+		json.Delim('{'),
+		"block_identifier",
+	} {
+		token, err := decoder.Token()
+		if err != nil || token != expected {
+			return nil, false
+		}
+	}
+
+	var identifier types.BlockIdentifier
+	if err := decoder.Decode(&identifier); err != nil {
+		return nil, false
+	}
+
+	return &identifier, true
+}
+
+// chainHead returns the index of the chain head, fetching
+// it from the client at most once per ttl.
+func (c *ResponseCache) chainHead(ctx context.Context) (int64, bool) {
+	c.mutex.Lock()
+	if c.now().Sub(c.headFetchedAt) < c.ttl {
+		head := c.head
+		c.mutex.Unlock()
+		return head, true
+	}
+	c.mutex.Unlock()
+
+	head, _, _, _, err := c.client.Status(ctx)
+	if err != nil {
+		return 0, false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.head = head.Index
+	c.headFetchedAt = c.now()
+
+	return c.head, true
+}
+
+// get returns the entry cached for key. If there is no
+// entry (or it has expired, or its block was reorged
+// out), nil is returned.
+func (c *ResponseCache) get(key [sha256.Size]byte) *cacheEntry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if !entry.expiration.IsZero() && (!c.now().Before(entry.expiration) || c.reorged(entry)) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil
+	}
+
+	c.order.MoveToFront(element)
+	return entry
+}
+
+// reorged returns true if the block of entry was removed
+// from the canonical chain after entry was served.
+func (c *ResponseCache) reorged(entry *cacheEntry) bool {
+	if c.events == nil || entry.block == nil {
+		return false
+	}
+
+	return c.events.Removed(entry.block, entry.sequence)
+}
+
+// put caches entry, evicting the least recently
+// used entry if the cache is full.
+func (c *ResponseCache) put(entry *cacheEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[entry.key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[entry.key] = c.order.PushFront(entry)
+	c.evict()
+}
+
+// evict removes the least recently used entries
+// over the size of c. It must be called with the
+// mutex held.
+func (c *ResponseCache) evict() {
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
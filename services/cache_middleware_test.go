@@ -0,0 +1,216 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/services"
+
+	"github.com/coinbase/rosetta-sdk-go/server"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCacheMiddleware(t *testing.T) {
+	calls := 0
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		if r.URL.Path != "/block" {
+			server.EncodeJSONResponse(&types.NetworkOptionsResponse{}, http.StatusOK, w)
+			return
+		}
+
+		var request types.BlockRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		index := *request.BlockIdentifier.Index
+		if index < 0 {
+			server.EncodeJSONResponse(ErrGeth, http.StatusInternalServerError, w)
+			return
+		}
+
+		server.EncodeJSONResponse(&types.BlockResponse{
+			Block: &types.Block{
+				BlockIdentifier: &types.BlockIdentifier{Index: index},
+			},
+		}, http.StatusOK, w)
+	})
+
+	mockClient := &mocks.Client{}
+	mockClient.On("Status", mock.Anything).Return(
+		&types.BlockIdentifier{Index: 100},
+		int64(0),
+		nil,
+		nil,
+		nil,
+	)
+
+	handler := CacheMiddleware(&configuration.Configuration{
+		ResponseCacheSize: 3,
+		ResponseCacheTTL:  time.Minute,
+	}, mockClient, nil, next)
+	now := time.Unix(1600000000, 0)
+	handler.now = func() time.Time { return now }
+
+	serve := func(path string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return w
+	}
+
+	// Equivalent requests share an entry
+	w := serve("/block", `{"block_identifier": {"index": 10}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"index":10`)
+	w = serve("/block", `{ "block_identifier" : { "index" : 10 } }`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"index":10`)
+	assert.Equal(t, "application/json; charset=UTF-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, 1, calls)
+
+	// Errors are not cached
+	serve("/block", `{"block_identifier": {"index": -1}}`)
+	w = serve("/block", `{"block_identifier": {"index": -1}}`)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, 3, calls)
+
+	// Blocks near the tip expire
+	serve("/block", `{"block_identifier": {"index": 99}}`)
+	serve("/block", `{"block_identifier": {"index": 99}}`)
+	assert.Equal(t, 4, calls)
+	now = now.Add(time.Minute)
+	serve("/block", `{"block_identifier": {"index": 99}}`)
+	assert.Equal(t, 5, calls)
+
+	// Finalized blocks never expire
+	serve("/block", `{"block_identifier": {"index": 10}}`)
+	assert.Equal(t, 5, calls)
+
+	// Other endpoints are never cached
+	serve("/network/status", `{}`)
+	serve("/network/status", `{}`)
+	assert.Equal(t, 7, calls)
+
+	serve("/network/options", `{}`)
+	serve("/network/options", `{}`)
+	assert.Equal(t, 8, calls)
+
+	// Options expire, since they change at runtime
+	now = now.Add(time.Minute)
+	serve("/network/options", `{}`)
+	assert.Equal(t, 9, calls)
+
+	// The least recently used entry is evicted
+	serve("/block", `{"block_identifier": {"index": 20}}`)
+	assert.Equal(t, 10, calls)
+	serve("/block", `{"block_identifier": {"index": 10}}`)
+	assert.Equal(t, 10, calls)
+	serve("/block", `{"block_identifier": {"index": 99}}`)
+	assert.Equal(t, 11, calls)
+
+	// Reloading keeps the most recently used entries that fit
+	handler.Reload(&configuration.Configuration{
+		ResponseCacheSize: 1,
+		ResponseCacheTTL:  time.Minute,
+	})
+	serve("/block", `{"block_identifier": {"index": 99}}`)
+	assert.Equal(t, 11, calls)
+	serve("/block", `{"block_identifier": {"index": 10}}`)
+	assert.Equal(t, 12, calls)
+
+	// The cache is disabled without a size
+	handler.Reload(&configuration.Configuration{})
+	serve("/block", `{"block_identifier": {"index": 10}}`)
+	assert.Equal(t, 13, calls)
+}
+
+func TestCacheMiddleware_Large(t *testing.T) {
+	calls := 0
+	chunk := bytes.Repeat([]byte(" "), 1024*1024)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		_, _ = w.Write([]byte(`{"block":{"block_identifier":{"index":10,"hash":"0x10"},"transactions":[`))
+		for i := 0; i <= maxCachedResponseSize/len(chunk); i++ {
+			_, _ = w.Write(chunk)
+		}
+		_, _ = w.Write([]byte(`]}}`))
+	})
+
+	mockClient := &mocks.Client{}
+	mockClient.On("Status", mock.Anything).Return(
+		&types.BlockIdentifier{Index: 100},
+		int64(0),
+		nil,
+		nil,
+		nil,
+	)
+
+	handler := CacheMiddleware(&configuration.Configuration{
+		ResponseCacheSize: 3,
+		ResponseCacheTTL:  time.Minute,
+	}, mockClient, nil, next)
+
+	// Responses larger than maxCachedResponseSize
+	// are served in full but not cached.
+	for i := 1; i <= 2; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(
+			http.MethodPost,
+			"/block",
+			strings.NewReader(`{"block_identifier": {"index": 10}}`),
+		))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Greater(t, w.Body.Len(), maxCachedResponseSize)
+		assert.Equal(t, i, calls)
+	}
+}
+
+func TestBlockResponseIdentifier(t *testing.T) {
+	block := &types.BlockResponse{Block: testEncoderBlock(2)}
+
+	var encoded bytes.Buffer
+	assert.NoError(t, encodeBlockResponse(&encoded, block, false))
+	identifier, ok := blockResponseIdentifier(encoded.Bytes())
+	assert.True(t, ok)
+	assert.Equal(t, block.Block.BlockIdentifier, identifier)
+
+	encoded.Reset()
+	assert.NoError(t, encodeBlockResponse(&encoded, block, true))
+	identifier, ok = blockResponseIdentifier(encoded.Bytes())
+	assert.True(t, ok)
+	assert.Equal(t, block.Block.BlockIdentifier, identifier)
+
+	for _, body := range []string{
+		`{}`,
+		`{"block":null}`,
+		`{"other_transactions":[{"hash":"0x2"}]}`,
+		`{"block":{"block_identifier":`,
+		`not json`,
+	} {
+		_, ok := blockResponseIdentifier([]byte(body))
+		assert.False(t, ok, body)
+	}
+}
@@ -17,24 +17,39 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/coinbase/rosetta-ethereum/configuration"
 	"github.com/coinbase/rosetta-ethereum/ethereum"
+	"github.com/coinbase/rosetta-ethereum/ethereum/networks"
+	"github.com/coinbase/rosetta-ethereum/submit"
 
 	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 )
 
 // CallAPIService implements the server.CallAPIServicer interface.
 type CallAPIService struct {
-	config *configuration.Configuration
-	client Client
+	config      *configuration.Configuration
+	client      Client
+	index       AccountIndex
+	submitQueue SubmitQueue
 }
 
 // NewCallAPIService creates a new instance of a CallAPIService.
-func NewCallAPIService(cfg *configuration.Configuration, client Client) *CallAPIService {
+// index and submitQueue may be nil if they are not configured.
+func NewCallAPIService(
+	cfg *configuration.Configuration,
+	client Client,
+	index AccountIndex,
+	submitQueue SubmitQueue,
+) *CallAPIService {
 	return &CallAPIService{
-		config: cfg,
-		client: client,
+		config:      cfg,
+		client:      client,
+		index:       index,
+		submitQueue: submitQueue,
 	}
 }
 
@@ -47,6 +62,19 @@ func (s *CallAPIService) Call(
 		return nil, ErrUnavailableOffline
 	}
 
+	if request.Method == ethereum.BurnedSupplyMethod {
+		return s.burnedSupply()
+	}
+	if request.Method == ethereum.TokenInventoryMethod {
+		return s.tokenInventory(ctx, request.Parameters)
+	}
+	if request.Method == ethereum.FirstSeenAccountsMethod {
+		return s.firstSeenAccounts(request.Parameters)
+	}
+	if request.Method == ethereum.SubmissionStatusMethod {
+		return s.submissionStatus(request.Parameters)
+	}
+
 	response, err := s.client.Call(ctx, request)
 	if errors.Is(err, ethereum.ErrCallParametersInvalid) {
 		return nil, wrapErr(ErrCallParametersInvalid, err)
@@ -63,3 +91,216 @@ func (s *CallAPIService) Call(
 
 	return response, nil
 }
+
+// burnedSupply serves the BurnedSupplyMethod from the index.
+func (s *CallAPIService) burnedSupply() (*types.CallResponse, *types.Error) {
+	if s.index == nil {
+		return nil, wrapErr(ErrIndexUnavailable, errors.New("no index is configured"))
+	}
+
+	burned, err := s.index.Burned()
+	if err != nil {
+		return nil, wrapErr(ErrIndexUnavailable, err)
+	}
+	if burned.Block == nil {
+		return nil, wrapErr(ErrIndexUnavailable, errors.New("no blocks have been indexed"))
+	}
+
+	return &types.CallResponse{
+		Result: map[string]interface{}{
+			"block_identifier": burned.Block,
+			"fees":             burned.Fees.String(),
+			"contract":         burned.Contract.String(),
+			"total":            burned.Total().String(),
+			"fees_since":       burned.FeesSince,
+			"contract_since":   burned.ContractSince,
+		},
+		Idempotent: false,
+	}, nil
+}
+
+// tokenInventory serves the TokenInventoryMethod: the tokens
+// held by an address are looked up in the index and only the
+// tokens with a nonzero balance at the head are returned.
+func (s *CallAPIService) tokenInventory(
+	ctx context.Context,
+	params map[string]interface{},
+) (*types.CallResponse, *types.Error) {
+	if s.index == nil {
+		return nil, wrapErr(ErrIndexUnavailable, errors.New("no index is configured"))
+	}
+
+	var input ethereum.TokenInventoryInput
+	if err := types.UnmarshalMap(params, &input); err != nil {
+		return nil, wrapErr(ErrCallParametersInvalid, err)
+	}
+	if !common.IsHexAddress(input.Address) {
+		return nil, wrapErr(ErrCallParametersInvalid, fmt.Errorf("%s is not an address", input.Address))
+	}
+	address := common.HexToAddress(input.Address)
+
+	holdings, err := s.index.TokenHoldings(address)
+	if err != nil {
+		return nil, wrapErr(ErrIndexUnavailable, err)
+	}
+
+	head, _, _, _, err := s.client.Status(ctx)
+	if err != nil {
+		return nil, wrapErr(ErrGeth, err)
+	}
+
+	tokens := []map[string]interface{}{}
+	if len(holdings.Holdings) > 0 {
+		// Tokens that are not in the token list of the
+		// network are named by their address.
+		known := map[common.Address]*networks.Token{}
+		for _, token := range s.config.Tokens {
+			known[token.Address] = token
+		}
+
+		currencies := make([]*types.Currency, len(holdings.Holdings))
+		for i, holding := range holdings.Holdings {
+			if token, ok := known[holding.Token]; ok {
+				currencies[i] = ethereum.TokenCurrency(token.Symbol, token.Decimals, holding.Token)
+				continue
+			}
+
+			token := ethereum.MustChecksum(holding.Token.Hex())
+			currencies[i] = ethereum.TokenCurrency(token, 0, holding.Token)
+		}
+
+		balances, err := s.client.TokenBalances(ctx, address, head, currencies)
+		if err != nil {
+			return nil, wrapErr(ErrGeth, err)
+		}
+
+		for i, balance := range balances {
+			if balance.Value == "0" {
+				continue
+			}
+
+			token := map[string]interface{}{
+				"token_address":                  ethereum.MustChecksum(holdings.Holdings[i].Token.Hex()),
+				"balance":                        balance.Value,
+				"last_activity_block_identifier": holdings.Holdings[i].LastActivity,
+			}
+			if known, ok := known[holdings.Holdings[i].Token]; ok {
+				token["symbol"] = known.Symbol
+				token["decimals"] = known.Decimals
+			}
+			tokens = append(tokens, token)
+		}
+	}
+
+	return &types.CallResponse{
+		Result: map[string]interface{}{
+			"block_identifier": head,
+			"indexed_through":  holdings.Index,
+			"tokens":           tokens,
+		},
+		Idempotent: false,
+	}, nil
+}
+
+// firstSeenAccounts serves the FirstSeenAccountsMethod from
+// the index. Blocks above the last indexed block are not
+// returned: the result covers the blocks up to end_index or
+// indexed_through, whichever is lower.
+func (s *CallAPIService) firstSeenAccounts(
+	params map[string]interface{},
+) (*types.CallResponse, *types.Error) {
+	if s.index == nil {
+		return nil, wrapErr(ErrIndexUnavailable, errors.New("no index is configured"))
+	}
+
+	var input ethereum.FirstSeenAccountsInput
+	if err := types.UnmarshalMap(params, &input); err != nil {
+		return nil, wrapErr(ErrCallParametersInvalid, err)
+	}
+	if input.StartIndex < 0 || input.EndIndex < input.StartIndex {
+		return nil, wrapErr(
+			ErrCallParametersInvalid,
+			fmt.Errorf("%d-%d is not a valid block range", input.StartIndex, input.EndIndex),
+		)
+	}
+	if input.EndIndex-input.StartIndex >= ethereum.MaxFirstSeenAccountsRange {
+		return nil, wrapErr(
+			ErrCallParametersInvalid,
+			fmt.Errorf("at most %d blocks can be requested", ethereum.MaxFirstSeenAccountsRange),
+		)
+	}
+
+	watermark, err := s.index.Watermark()
+	if err != nil {
+		return nil, wrapErr(ErrIndexUnavailable, err)
+	}
+	if watermark == nil {
+		return nil, wrapErr(ErrIndexUnavailable, errors.New("no blocks have been indexed"))
+	}
+
+	addresses := []string{}
+	if input.StartIndex <= watermark.Index {
+		end := input.EndIndex
+		if end > watermark.Index {
+			end = watermark.Index
+		}
+
+		firstSeen, err := s.index.FirstSeen(input.StartIndex, end)
+		if err != nil {
+			return nil, wrapErr(ErrIndexUnavailable, err)
+		}
+		for _, address := range firstSeen {
+			addresses = append(addresses, ethereum.MustChecksum(address.Hex()))
+		}
+	}
+
+	return &types.CallResponse{
+		Result: map[string]interface{}{
+			"indexed_through": watermark.Index,
+			"addresses":       addresses,
+		},
+		Idempotent: false,
+	}, nil
+}
+
+// submissionStatus serves the SubmissionStatusMethod
+// from the submit queue.
+func (s *CallAPIService) submissionStatus(
+	params map[string]interface{},
+) (*types.CallResponse, *types.Error) {
+	if s.submitQueue == nil {
+		return nil, wrapErr(ErrSubmitQueueUnavailable, errors.New("no submit queue is configured"))
+	}
+
+	var input ethereum.SubmissionStatusInput
+	if err := types.UnmarshalMap(params, &input); err != nil {
+		return nil, wrapErr(ErrCallParametersInvalid, err)
+	}
+
+	hash, err := hexutil.Decode(input.TxHash)
+	if err != nil || len(hash) != common.HashLength {
+		return nil, wrapErr(ErrCallParametersInvalid, fmt.Errorf("%s is not a transaction hash", input.TxHash))
+	}
+
+	entry, err := s.submitQueue.Status(common.BytesToHash(hash))
+	if errors.Is(err, submit.ErrNotFound) {
+		return nil, wrapErr(ErrCallParametersInvalid, err)
+	}
+	if err != nil {
+		return nil, wrapErr(ErrSubmitQueueUnavailable, err)
+	}
+
+	result, err := types.MarshalMap(entry)
+	if err != nil {
+		return nil, wrapErr(ErrCallOutputMarshal, err)
+	}
+
+	// The signed transaction is not returned, as the
+	// caller already has it.
+	delete(result, "transaction")
+
+	return &types.CallResponse{
+		Result:     result,
+		Idempotent: false,
+	}, nil
+}
@@ -16,12 +16,18 @@ package services
 
 import (
 	"context"
+	"math/big"
 	"testing"
 
 	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	"github.com/coinbase/rosetta-ethereum/ethereum/networks"
+	"github.com/coinbase/rosetta-ethereum/indexer"
 	mocks "github.com/coinbase/rosetta-ethereum/mocks/services"
+	"github.com/coinbase/rosetta-ethereum/submit"
 
 	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -30,7 +36,7 @@ func TestCall_Offline(t *testing.T) {
 		Mode: configuration.Offline,
 	}
 	mockClient := &mocks.Client{}
-	servicer := NewCallAPIService(cfg, mockClient)
+	servicer := NewCallAPIService(cfg, mockClient, nil, nil)
 	ctx := context.Background()
 
 	resp, err := servicer.Call(ctx, &types.CallRequest{})
@@ -45,7 +51,7 @@ func TestCall_Online(t *testing.T) {
 		Mode: configuration.Online,
 	}
 	mockClient := &mocks.Client{}
-	servicer := NewCallAPIService(cfg, mockClient)
+	servicer := NewCallAPIService(cfg, mockClient, nil, nil)
 	ctx := context.Background()
 
 	request := &types.CallRequest{
@@ -65,3 +71,258 @@ func TestCall_Online(t *testing.T) {
 
 	mockClient.AssertExpectations(t)
 }
+
+func TestCall_BurnedSupply(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode: configuration.Online,
+	}
+	mockClient := &mocks.Client{}
+	ctx := context.Background()
+	request := &types.CallRequest{
+		Method: ethereum.BurnedSupplyMethod,
+	}
+
+	// No index is configured.
+	servicer := NewCallAPIService(cfg, mockClient, nil, nil)
+	resp, err := servicer.Call(ctx, request)
+	assert.Nil(t, resp)
+	assert.Equal(t, ErrIndexUnavailable.Code, err.Code)
+
+	// No blocks have been indexed.
+	mockIndex := &mocks.AccountIndex{}
+	servicer = NewCallAPIService(cfg, mockClient, mockIndex, nil)
+	mockIndex.On("Burned").Return(&indexer.BurnedSupply{
+		Fees:     new(big.Int),
+		Contract: new(big.Int),
+	}, nil).Once()
+	resp, err = servicer.Call(ctx, request)
+	assert.Nil(t, resp)
+	assert.Equal(t, ErrIndexUnavailable.Code, err.Code)
+
+	block := &types.BlockIdentifier{Index: 100, Hash: "block 100"}
+	mockIndex.On("Burned").Return(&indexer.BurnedSupply{
+		Block:     block,
+		Fees:      big.NewInt(15),
+		Contract:  big.NewInt(100),
+		FeesSince: 10,
+	}, nil).Once()
+	resp, err = servicer.Call(ctx, request)
+	assert.Nil(t, err)
+	assert.Equal(t, &types.CallResponse{
+		Result: map[string]interface{}{
+			"block_identifier": block,
+			"fees":             "15",
+			"contract":         "100",
+			"total":            "115",
+			"fees_since":       int64(10),
+			"contract_since":   int64(0),
+		},
+	}, resp)
+
+	mockClient.AssertExpectations(t)
+	mockIndex.AssertExpectations(t)
+}
+
+func TestCall_TokenInventory(t *testing.T) {
+	holder := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	token := common.HexToAddress("0x40375C92d9FAf44d2f9db9Bd9ba41a3317a2404f")
+	otherToken := common.HexToAddress("0x900101d06A7426441Ae63e9AB3B9b0F63Be145F1")
+	unlistedToken := common.HexToAddress("0xa4151B2B3e269645181dCcF2D426cE75fcbDeca9")
+
+	cfg := &configuration.Configuration{
+		Mode: configuration.Online,
+		Tokens: []*networks.Token{
+			{Symbol: "WCORE", Decimals: 18, Address: token},
+			{Symbol: "USDT", Decimals: 6, Address: otherToken},
+		},
+	}
+	mockClient := &mocks.Client{}
+	mockIndex := &mocks.AccountIndex{}
+	servicer := NewCallAPIService(cfg, mockClient, mockIndex, nil)
+	ctx := context.Background()
+
+	request := &types.CallRequest{
+		Method:     ethereum.TokenInventoryMethod,
+		Parameters: map[string]interface{}{"address": holder.Hex()},
+	}
+
+	// The address must be valid.
+	resp, err := servicer.Call(ctx, &types.CallRequest{
+		Method:     ethereum.TokenInventoryMethod,
+		Parameters: map[string]interface{}{"address": "0x1234"},
+	})
+	assert.Nil(t, resp)
+	assert.Equal(t, ErrCallParametersInvalid.Code, err.Code)
+
+	// Token holders are not indexed.
+	mockIndex.On("TokenHoldings", holder).Return(nil, indexer.ErrTokenHoldersDisabled).Once()
+	resp, err = servicer.Call(ctx, request)
+	assert.Nil(t, resp)
+	assert.Equal(t, ErrIndexUnavailable.Code, err.Code)
+
+	// Tokens with a zero balance are not returned, and
+	// tokens that are not listed are named by their address.
+	head := &types.BlockIdentifier{Index: 200, Hash: "block 200"}
+	lastActivity := &types.BlockIdentifier{Index: 100, Hash: "block 100"}
+	mockIndex.On("TokenHoldings", holder).Return(&indexer.TokenHoldings{
+		Index: 170,
+		Holdings: []*indexer.TokenHolding{
+			{Token: token, LastActivity: lastActivity},
+			{Token: otherToken, LastActivity: lastActivity},
+			{Token: unlistedToken, LastActivity: lastActivity},
+		},
+	}, nil).Once()
+	mockClient.On("Status", ctx).Return(head, int64(0), nil, nil, nil).Once()
+	currencies := []*types.Currency{
+		ethereum.TokenCurrency("WCORE", 18, token),
+		ethereum.TokenCurrency("USDT", 6, otherToken),
+		ethereum.TokenCurrency(unlistedToken.Hex(), 0, unlistedToken),
+	}
+	mockClient.On("TokenBalances", ctx, holder, head, currencies).Return([]*types.Amount{
+		{Value: "1000", Currency: currencies[0]},
+		{Value: "0", Currency: currencies[1]},
+		{Value: "5", Currency: currencies[2]},
+	}, nil).Once()
+	resp, err = servicer.Call(ctx, request)
+	assert.Nil(t, err)
+	assert.Equal(t, &types.CallResponse{
+		Result: map[string]interface{}{
+			"block_identifier": head,
+			"indexed_through":  int64(170),
+			"tokens": []map[string]interface{}{
+				{
+					"token_address":                  token.Hex(),
+					"symbol":                         "WCORE",
+					"decimals":                       int32(18),
+					"balance":                        "1000",
+					"last_activity_block_identifier": lastActivity,
+				},
+				{
+					"token_address":                  unlistedToken.Hex(),
+					"balance":                        "5",
+					"last_activity_block_identifier": lastActivity,
+				},
+			},
+		},
+	}, resp)
+
+	mockClient.AssertExpectations(t)
+	mockIndex.AssertExpectations(t)
+}
+
+func TestCall_FirstSeenAccounts(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode: configuration.Online,
+	}
+	mockIndex := &mocks.AccountIndex{}
+	servicer := NewCallAPIService(cfg, &mocks.Client{}, mockIndex, nil)
+	ctx := context.Background()
+	request := func(start int64, end int64) *types.CallRequest {
+		return &types.CallRequest{
+			Method: ethereum.FirstSeenAccountsMethod,
+			Parameters: map[string]interface{}{
+				"start_index": start,
+				"end_index":   end,
+			},
+		}
+	}
+
+	// The range must be valid.
+	for _, invalid := range [][2]int64{{-1, 10}, {10, 9}, {0, ethereum.MaxFirstSeenAccountsRange}} {
+		resp, err := servicer.Call(ctx, request(invalid[0], invalid[1]))
+		assert.Nil(t, resp)
+		assert.Equal(t, ErrCallParametersInvalid.Code, err.Code)
+	}
+
+	// Blocks above the watermark are not returned.
+	address := common.HexToAddress("0xe3a5b4d7f79d64088c8d4ef153a7dde2b2d47309")
+	mockIndex.On("Watermark").Return(&types.BlockIdentifier{Index: 150, Hash: "block 150"}, nil).Twice()
+	mockIndex.On("FirstSeen", int64(100), int64(150)).Return([]common.Address{address}, nil).Once()
+	resp, err := servicer.Call(ctx, request(100, 199))
+	assert.Nil(t, err)
+	assert.Equal(t, &types.CallResponse{
+		Result: map[string]interface{}{
+			"indexed_through": int64(150),
+			"addresses":       []string{"0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"},
+		},
+	}, resp)
+
+	resp, err = servicer.Call(ctx, request(151, 199))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, resp.Result["addresses"])
+
+	// The index is still being migrated.
+	mockIndex.On("Watermark").Return(&types.BlockIdentifier{Index: 150, Hash: "block 150"}, nil).Once()
+	mockIndex.On("FirstSeen", int64(0), int64(10)).Return(nil, indexer.ErrMigrationInProgress).Once()
+	resp, err = servicer.Call(ctx, request(0, 10))
+	assert.Nil(t, resp)
+	assert.Equal(t, ErrIndexUnavailable.Code, err.Code)
+	assert.True(t, err.Retriable)
+
+	mockIndex.AssertExpectations(t)
+}
+
+func TestCall_SubmissionStatus(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode: configuration.Online,
+	}
+	mockClient := &mocks.Client{}
+	ctx := context.Background()
+	hash := common.HexToHash("0x3e8d6a9e8e3f2d7c1d6b1e3c6d4c1a0f8c3b9f2e1d4c7b6a5f4e3d2c1b0a9f8e")
+	request := &types.CallRequest{
+		Method:     ethereum.SubmissionStatusMethod,
+		Parameters: map[string]interface{}{"tx_hash": hash.Hex()},
+	}
+
+	// No submit queue is configured.
+	servicer := NewCallAPIService(cfg, mockClient, nil, nil)
+	resp, err := servicer.Call(ctx, request)
+	assert.Nil(t, resp)
+	assert.Equal(t, ErrSubmitQueueUnavailable.Code, err.Code)
+
+	mockQueue := &mocks.SubmitQueue{}
+	servicer = NewCallAPIService(cfg, mockClient, nil, mockQueue)
+
+	resp, err = servicer.Call(ctx, &types.CallRequest{
+		Method:     ethereum.SubmissionStatusMethod,
+		Parameters: map[string]interface{}{"tx_hash": "0x1234"},
+	})
+	assert.Nil(t, resp)
+	assert.Equal(t, ErrCallParametersInvalid.Code, err.Code)
+
+	mockQueue.On("Status", hash).Return(nil, submit.ErrNotFound).Once()
+	resp, err = servicer.Call(ctx, request)
+	assert.Nil(t, resp)
+	assert.Equal(t, ErrCallParametersInvalid.Code, err.Code)
+
+	mockQueue.On("Status", hash).Return(&submit.Entry{
+		TransactionHash: hash.Hex(),
+		Transaction:     []byte{1, 2, 3},
+		From:            "0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309",
+		Nonce:           4,
+		Status:          submit.QueuedStatus,
+		Attempts:        2,
+		LastError:       "connection refused",
+		CreatedAt:       1600000000,
+		UpdatedAt:       1600000010,
+		NextAttemptAt:   1600000020,
+	}, nil).Once()
+	resp, err = servicer.Call(ctx, request)
+	assert.Nil(t, err)
+	assert.Equal(t, &types.CallResponse{
+		Result: map[string]interface{}{
+			"transaction_hash": hash.Hex(),
+			"from":             "0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309",
+			"nonce":            uint64(4),
+			"status":           submit.QueuedStatus,
+			"attempts":         int64(2),
+			"last_error":       "connection refused",
+			"created_at":       int64(1600000000),
+			"updated_at":       int64(1600000010),
+			"next_attempt_at":  int64(1600000020),
+		},
+	}, resp)
+
+	mockClient.AssertExpectations(t)
+	mockQueue.AssertExpectations(t)
+}
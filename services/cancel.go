@@ -0,0 +1,249 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+
+	"github.com/coinbase/rosetta-sdk-go/parser"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// cancelIntent is a request to cancel the
+// transaction of From with Nonce.
+type cancelIntent struct {
+	From  string
+	Nonce uint64
+}
+
+// matchCancel returns the cancellation described by ops. If ops
+// is not a cancellation (a single CANCEL operation), nil is
+// returned so that ops can be matched as another intent.
+func matchCancel(ops []*types.Operation) (*cancelIntent, *types.Error) {
+	if len(ops) != 1 || ops[0].Type != ethereum.CancelOpType {
+		return nil, nil
+	}
+
+	descriptions := &parser.Descriptions{
+		OperationDescriptions: []*parser.OperationDescription{
+			{
+				Type: ethereum.CancelOpType,
+				Account: &parser.AccountDescription{
+					Exists: true,
+				},
+				Amount: &parser.AmountDescription{
+					Exists: false,
+				},
+			},
+		},
+		ErrUnmatched: true,
+	}
+
+	matches, err := parser.MatchOperations(descriptions, ops)
+	if err != nil {
+		return nil, wrapErr(ErrUnclearIntent, err)
+	}
+
+	op, _ := matches[0].First()
+	from, ok := ethereum.ChecksumAddress(op.Account.Address)
+	if !ok {
+		return nil, wrapErr(ErrInvalidAddress, fmt.Errorf("%s is not a valid address", op.Account.Address))
+	}
+
+	nonce, ok := parseNonce(op.Metadata[ethereum.NonceMetadataKey])
+	if !ok {
+		return nil, wrapErr(
+			ErrUnclearIntent,
+			fmt.Errorf("%v is not a valid nonce", op.Metadata[ethereum.NonceMetadataKey]),
+		)
+	}
+
+	return &cancelIntent{
+		From:  from,
+		Nonce: nonce,
+	}, nil
+}
+
+// parseNonce parses a nonce provided as a JSON number
+// or as a decimal or hex-encoded string.
+func parseNonce(value interface{}) (uint64, bool) {
+	switch v := value.(type) {
+	case float64:
+		if v < 0 || v != float64(uint64(v)) {
+			return 0, false
+		}
+
+		return uint64(v), true
+	case string:
+		nonce, err := strconv.ParseUint(v, 0, 64)
+		return nonce, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// isCancel returns true if a transaction from from to to with
+// value and data can be a cancellation (a zero-value transfer
+// to its sender). Only transactions marked as a cancellation
+// (see transaction.Cancel) are parsed as one.
+func isCancel(from string, to string, value *big.Int, data []byte) bool {
+	return from == to && (value == nil || value.Sign() == 0) && len(data) == 0
+}
+
+// cancelMark is the field that marks a signed
+// transaction as a cancellation (see markCancel).
+type cancelMark struct {
+	Cancel bool `json:"cancel,omitempty"`
+}
+
+// markCancel adds the cancel field to the JSON encoding of
+// a signed transaction. The field is ignored when the
+// transaction is decoded for /construction/hash and
+// /construction/submit.
+func markCancel(signedTxJSON []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(signedTxJSON, &fields); err != nil {
+		return nil, err
+	}
+
+	mark, err := json.Marshal(true)
+	if err != nil {
+		return nil, err
+	}
+	fields["cancel"] = mark
+
+	return json.Marshal(fields)
+}
+
+// isMarkedCancel returns true if signedTxJSON
+// was marked as a cancellation by markCancel.
+func isMarkedCancel(signedTxJSON []byte) (bool, error) {
+	var mark cancelMark
+	if err := json.Unmarshal(signedTxJSON, &mark); err != nil {
+		return false, err
+	}
+
+	return mark.Cancel, nil
+}
+
+// cancelOperations returns the operations of
+// a cancellation parsed by /construction/parse.
+func cancelOperations(from string, nonce uint64) []*types.Operation {
+	return []*types.Operation{
+		{
+			Type: ethereum.CancelOpType,
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 0,
+			},
+			Account: &types.AccountIdentifier{
+				Address: from,
+			},
+			Metadata: map[string]interface{}{
+				ethereum.NonceMetadataKey: strconv.FormatUint(nonce, 10),
+			},
+		},
+	}
+}
+
+// cancelMetadata returns the metadata of the cancellation described
+// by input. The gas price is bumped above the gas price of the
+// cancelled transaction, if it is in the mempool, so that the
+// txpool replaces it.
+func (s *ConstructionAPIService) cancelMetadata(
+	ctx context.Context,
+	input *options,
+) (*types.ConstructionMetadataResponse, *types.Error) {
+	nonce, err := strconv.ParseUint(input.CancelNonce, 10, 64)
+	if err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	target, err := s.client.CancelTarget(ctx, common.HexToAddress(input.From), nonce)
+	if err != nil {
+		return nil, wrapErr(ErrGeth, err)
+	}
+	if nonce < target.Nonce {
+		return nil, wrapErr(
+			ErrCancelledTransactionMined,
+			fmt.Errorf(
+				"the transaction of %s with nonce %d is mined (the nonce of %s is %d)",
+				input.From,
+				nonce,
+				input.From,
+				target.Nonce,
+			),
+		)
+	}
+
+	gasPrice, err := s.client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, wrapErr(ErrGeth, err)
+	}
+	if target.GasPrice != nil {
+		replacement := ethereum.ReplacementGasPrice(target.GasPrice)
+		if replacement.Cmp(gasPrice) > 0 {
+			gasPrice = replacement
+		}
+	}
+
+	metadata := &metadata{
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+	}
+
+	gasLimit := s.config.GasLimits.Default(configuration.TransferGasLimit)
+	if gasLimit != uint64(ethereum.TransferGasLimit) {
+		metadata.GasLimit = gasLimit
+	}
+
+	return metadataResponse(metadata, gasLimit)
+}
+
+// cancelPayloads builds the zero-value transfer to its
+// sender that replaces the transaction cancelled by intent.
+func (s *ConstructionAPIService) cancelPayloads(
+	request *types.ConstructionPayloadsRequest,
+	intent *cancelIntent,
+) (*types.ConstructionPayloadsResponse, *types.Error) {
+	var metadata metadata
+	if err := unmarshalJSONMap(request.Metadata, &metadata); err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	gasLimit := uint64(ethereum.TransferGasLimit)
+	if metadata.GasLimit > 0 {
+		gasLimit = metadata.GasLimit
+	}
+
+	return s.payloads(&transaction{
+		From:     intent.From,
+		To:       intent.From,
+		Value:    big.NewInt(0),
+		Data:     []byte{},
+		Nonce:    intent.Nonce,
+		GasPrice: metadata.GasPrice,
+		GasLimit: gasLimit,
+		ChainID:  s.config.Params.ChainID,
+		Cancel:   true,
+	})
+}
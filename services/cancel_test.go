@@ -0,0 +1,261 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/services"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstructionService_Cancel(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    ethereum.RopstenNetwork,
+		Blockchain: ethereum.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:    configuration.Online,
+		Network: networkIdentifier,
+		Params:  params.RopstenChainConfig,
+	}
+
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, nil, nil, nil)
+	ctx := context.Background()
+
+	from := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	ops := func(nonce interface{}) []*types.Operation {
+		return []*types.Operation{
+			{
+				OperationIdentifier: &types.OperationIdentifier{Index: 0},
+				Type:                ethereum.CancelOpType,
+				Account:             &types.AccountIdentifier{Address: from.Hex()},
+				Metadata: map[string]interface{}{
+					ethereum.NonceMetadataKey: nonce,
+				},
+			},
+		}
+	}
+
+	// The nonce must be provided
+	preprocessResponse, err := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops(nil),
+	})
+	assert.Nil(t, preprocessResponse)
+	assert.Equal(t, ErrUnclearIntent.Code, err.Code)
+
+	// The nonce can be a number or a decimal or hex string
+	for _, nonce := range []interface{}{float64(5), "5", "0x5"} {
+		preprocessResponse, err = servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+			NetworkIdentifier: networkIdentifier,
+			Operations:        ops(nonce),
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"from":         from.Hex(),
+			"cancel_nonce": "5",
+		}, preprocessResponse.Options)
+	}
+
+	// The gas price is bumped above the gas
+	// price of the cancelled transaction
+	mockClient.On("CancelTarget", ctx, from, uint64(5)).Return(&ethereum.CancelTarget{
+		Nonce:    5,
+		GasPrice: big.NewInt(1000000000),
+	}, nil).Once()
+	mockClient.On("SuggestGasPrice", ctx).Return(big.NewInt(1000000000), nil).Once()
+	metadataResponse, err := servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           preprocessResponse.Options,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"nonce":     "0x5",
+		"gas_price": "0x4190ab00",
+	}, metadataResponse.Metadata)
+	assert.Equal(t, "23100000000000", metadataResponse.SuggestedFee[0].Value)
+
+	payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops("5"),
+		Metadata:          metadataResponse.Metadata,
+	})
+	assert.Nil(t, err)
+
+	var unsignedTx transaction
+	assert.NoError(t, json.Unmarshal([]byte(payloadsResponse.UnsignedTransaction), &unsignedTx))
+	assert.Equal(t, 0, unsignedTx.Value.Sign())
+	unsignedTx.Value = nil
+	assert.Equal(t, &transaction{
+		From:     from.Hex(),
+		To:       from.Hex(),
+		Data:     []byte{},
+		Nonce:    5,
+		GasPrice: big.NewInt(1100000000),
+		GasLimit: uint64(ethereum.TransferGasLimit),
+		ChainID:  params.RopstenChainConfig.ChainID,
+		Cancel:   true,
+	}, &unsignedTx)
+
+	// The cancellation round trips through /construction/parse
+	parseResponse, err := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: networkIdentifier,
+		Signed:            false,
+		Transaction:       payloadsResponse.UnsignedTransaction,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, ops("5"), parseResponse.Operations)
+
+	// The suggested gas price is used if it is higher
+	mockClient.On("CancelTarget", ctx, from, uint64(5)).Return(&ethereum.CancelTarget{
+		Nonce:    5,
+		GasPrice: big.NewInt(1000000000),
+	}, nil).Once()
+	mockClient.On("SuggestGasPrice", ctx).Return(big.NewInt(2000000000), nil).Once()
+	metadataResponse, err = servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           preprocessResponse.Options,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "0x77359400", metadataResponse.Metadata["gas_price"])
+
+	// Mined transactions cannot be cancelled
+	mockClient.On("CancelTarget", ctx, from, uint64(5)).Return(&ethereum.CancelTarget{
+		Nonce: 6,
+	}, nil).Once()
+	metadataResponse, err = servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           preprocessResponse.Options,
+	})
+	assert.Nil(t, metadataResponse)
+	assert.Equal(t, ErrCancelledTransactionMined.Code, err.Code)
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestConstructionParse_Cancel(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    ethereum.RopstenNetwork,
+		Blockchain: ethereum.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:    configuration.Online,
+		Network: networkIdentifier,
+		Params:  params.RopstenChainConfig,
+	}
+
+	servicer := NewConstructionAPIService(cfg, &mocks.Client{}, nil, nil, nil)
+	ctx := context.Background()
+
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	signer := ethTypes.NewEIP155Signer(params.RopstenChainConfig.ChainID)
+	selfTransfer, err := ethTypes.SignTx(
+		ethTypes.NewTransaction(5, from, big.NewInt(0), 21000, big.NewInt(1), nil),
+		signer,
+		key,
+	)
+	assert.NoError(t, err)
+	signedRaw, err := selfTransfer.MarshalJSON()
+	assert.NoError(t, err)
+
+	// /construction/combine marks cancellations
+	unsignedRaw, err := json.Marshal(&transaction{
+		From:     from.Hex(),
+		To:       from.Hex(),
+		Value:    big.NewInt(0),
+		Data:     []byte{},
+		Nonce:    5,
+		GasPrice: big.NewInt(1),
+		GasLimit: 21000,
+		ChainID:  params.RopstenChainConfig.ChainID,
+		Cancel:   true,
+	})
+	assert.NoError(t, err)
+	signature, err := crypto.Sign(signer.Hash(selfTransfer).Bytes(), key)
+	assert.NoError(t, err)
+	combineResponse, rErr := servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: string(unsignedRaw),
+		Signatures:          []*types.Signature{{Bytes: signature}},
+	})
+	assert.Nil(t, rErr)
+	markedRaw := []byte(combineResponse.SignedTransaction)
+	marked, err := isMarkedCancel(markedRaw)
+	assert.NoError(t, err)
+	assert.True(t, marked)
+
+	// Zero-value transfers to their sender are
+	// only cancellations if they are marked
+	parseResponse, rErr := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: networkIdentifier,
+		Signed:            true,
+		Transaction:       string(signedRaw),
+	})
+	assert.Nil(t, rErr)
+	assert.Len(t, parseResponse.Operations, 2)
+	assert.Equal(t, ethereum.CallOpType, parseResponse.Operations[0].Type)
+
+	parseResponse, rErr = servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: networkIdentifier,
+		Signed:            true,
+		Transaction:       string(markedRaw),
+	})
+	assert.Nil(t, rErr)
+	assert.Equal(t, cancelOperations(from.Hex(), 5), parseResponse.Operations)
+
+	// The mark does not change the transaction
+	hashResponse, rErr := servicer.ConstructionHash(ctx, &types.ConstructionHashRequest{
+		NetworkIdentifier: networkIdentifier,
+		SignedTransaction: string(markedRaw),
+	})
+	assert.Nil(t, rErr)
+	assert.Equal(t, selfTransfer.Hash().Hex(), hashResponse.TransactionIdentifier.Hash)
+
+	// Marked transactions must be cancellations
+	transfer, err := ethTypes.SignTx(
+		ethTypes.NewTransaction(5, from, big.NewInt(1), 21000, big.NewInt(1), nil),
+		signer,
+		key,
+	)
+	assert.NoError(t, err)
+	transferRaw, err := transfer.MarshalJSON()
+	assert.NoError(t, err)
+	markedRaw, err = markCancel(transferRaw)
+	assert.NoError(t, err)
+	parseResponse, rErr = servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: networkIdentifier,
+		Signed:            true,
+		Transaction:       string(markedRaw),
+	})
+	assert.Nil(t, parseResponse)
+	assert.Equal(t, ErrUnableToParseIntermediateResult.Code, rErr.Code)
+}
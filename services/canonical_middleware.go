@@ -0,0 +1,86 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/coinbase/rosetta-ethereum/canonical"
+	"github.com/coinbase/rosetta-ethereum/configuration"
+)
+
+// CanonicalJSONMiddleware returns a handler that re-encodes
+// every JSON response served by next canonically (see
+// canonical.Transform) when CanonicalJSON is configured, so
+// responses for the same request are byte-for-byte identical
+// across versions and platforms. Responses that are not JSON,
+// or that cannot be re-encoded, are served unchanged.
+func CanonicalJSONMiddleware(
+	cfg *configuration.Configuration,
+	next http.Handler,
+) http.Handler {
+	if !cfg.CanonicalJSON {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if streamed(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Successful /block responses are encoded canonically
+		// by the block router, as they are too large to buffer.
+		var recorder *responseRecorder
+		if r.URL.Path == "/block" {
+			var stream *blockStream
+			r, stream = withBlockStream(r)
+			stream.canonical = true
+
+			streaming := newStreamingRecorder(w, unsuccessful)
+			next.ServeHTTP(streaming, r)
+			if recorder = streaming.recorded(); recorder == nil {
+				return
+			}
+		} else {
+			recorder = newResponseRecorder()
+			next.ServeHTTP(recorder, r)
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(recorder.header.Get("Content-Type"))
+		if mediaType != "application/json" {
+			recorder.flush(w)
+			return
+		}
+
+		encoded, err := canonical.Transform(recorder.body.Bytes())
+		if err != nil {
+			log.Printf("unable to canonicalize response for %s: %s", r.URL.Path, err.Error())
+			recorder.flush(w)
+			return
+		}
+
+		recorder.body.Reset()
+		recorder.body.Write(encoded)
+		if len(recorder.header.Get("Content-Length")) > 0 {
+			recorder.header.Set("Content-Length", strconv.Itoa(len(encoded)))
+		}
+
+		recorder.flush(w)
+	})
+}
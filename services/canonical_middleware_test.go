@@ -0,0 +1,141 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+
+	"github.com/coinbase/rosetta-sdk-go/asserter"
+	"github.com/coinbase/rosetta-sdk-go/server"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalJSONMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/block/transaction":
+			server.EncodeJSONResponse(&types.BlockTransactionResponse{
+				Transaction: &types.Transaction{
+					TransactionIdentifier: &types.TransactionIdentifier{Hash: "0x10"},
+					Metadata:              map[string]interface{}{"b": 1.50, "a": "<>"},
+				},
+			}, http.StatusOK, w)
+		case "/invalid":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(`{"truncated":`))
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(`{ "b": 1, "a": 2 }`))
+		}
+	})
+
+	serve := func(handler http.Handler, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader("{}")))
+		return w
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		handler := CanonicalJSONMiddleware(&configuration.Configuration{}, next)
+		w := serve(handler, "/block/transaction")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"transaction_identifier":{"hash":"0x10"}`)
+	})
+
+	handler := CanonicalJSONMiddleware(&configuration.Configuration{CanonicalJSON: true}, next)
+
+	t.Run("json", func(t *testing.T) {
+		w := serve(handler, "/block/transaction")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json; charset=UTF-8", w.Header().Get("Content-Type"))
+		assert.Equal(
+			t,
+			`{"transaction":{"metadata":{"a":"<>","b":1.5},"operations":null,`+
+				`"transaction_identifier":{"hash":"0x10"}}}`,
+			w.Body.String(),
+		)
+	})
+
+	// Successful /block responses are encoded
+	// canonically by the block router.
+	t.Run("block", func(t *testing.T) {
+		network := &types.NetworkIdentifier{
+			Blockchain: ethereum.Blockchain,
+			Network:    ethereum.CoreNetwork,
+		}
+		serverAsserter, err := asserter.NewServer(
+			ethereum.OperationTypes,
+			ethereum.HistoricalBalanceSupported,
+			[]*types.NetworkIdentifier{network},
+			ethereum.CallMethods,
+			ethereum.IncludeMempoolCoins,
+			"",
+		)
+		assert.NoError(t, err)
+
+		router := &blockRouter{
+			service: staticBlockService(&types.BlockResponse{
+				Block: &types.Block{
+					BlockIdentifier: &types.BlockIdentifier{Index: 10, Hash: "0x10"},
+					Metadata:        map[string]interface{}{"b": 1.50, "a": "<>"},
+				},
+			}),
+			asserter: serverAsserter,
+		}
+		handler := CanonicalJSONMiddleware(
+			&configuration.Configuration{CanonicalJSON: true},
+			http.HandlerFunc(router.Block),
+		)
+
+		index := int64(10)
+		body, err := json.Marshal(&types.BlockRequest{
+			NetworkIdentifier: network,
+			BlockIdentifier:   &types.PartialBlockIdentifier{Index: &index},
+		})
+		assert.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/block", bytes.NewReader(body)))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json; charset=UTF-8", w.Header().Get("Content-Type"))
+		assert.Equal(
+			t,
+			`{"block":{"block_identifier":{"hash":"0x10","index":10},"metadata":{"a":"<>","b":1.5},`+
+				`"parent_block_identifier":null,"timestamp":0,"transactions":null}}`,
+			w.Body.String(),
+		)
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		w := serve(handler, "/invalid")
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+		assert.Equal(t, `{"truncated":`, w.Body.String())
+	})
+
+	t.Run("not json", func(t *testing.T) {
+		w := serve(handler, "/text")
+		assert.Equal(t, `{ "b": 1, "a": 2 }`, w.Body.String())
+	})
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+
+	"github.com/coinbase/rosetta-sdk-go/server"
+)
+
+// constructionPrefix is the path prefix
+// of the /construction endpoints.
+const constructionPrefix = "/construction/"
+
+// ConstructionAccessMiddleware returns a handler that rejects
+// /construction requests with ErrAccessDenied unless they come
+// from one of cfg.HTTPServer.ConstructionAllowedNetworks and, if
+// cfg.HTTPServer.ConstructionClientCAFile is set, present a
+// client certificate verified against it. Other requests are
+// served by next regardless, so data endpoints can be served
+// broadly while signing infrastructure stays isolated.
+func ConstructionAccessMiddleware(cfg *configuration.Configuration, next http.Handler) http.Handler {
+	networks := cfg.HTTPServer.ConstructionAllowedNetworks
+	requireCert := len(cfg.HTTPServer.ConstructionClientCAFile) > 0
+	if len(networks) == 0 && !requireCert {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, constructionPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if err := constructionAccess(r, networks, requireCert); err != nil {
+			server.EncodeJSONResponse(wrapErr(ErrAccessDenied, err), http.StatusForbidden, w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// constructionAccess returns an error if r is not allowed
+// to call the /construction endpoints.
+func constructionAccess(r *http.Request, networks []*net.IPNet, requireCert bool) error {
+	if len(networks) > 0 {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return fmt.Errorf("%s is not an IP address", host)
+		}
+
+		allowed := false
+		for _, network := range networks {
+			if network.Contains(ip) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%s is not allowed", ip.String())
+		}
+	}
+
+	// The server only requests client certificates, so
+	// VerifiedChains is empty if none was presented.
+	if requireCert && (r.TLS == nil || len(r.TLS.VerifiedChains) == 0) {
+		return errors.New("a verified client certificate is required")
+	}
+
+	return nil
+}
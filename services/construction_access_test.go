@@ -0,0 +1,143 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstructionAccessMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	_, private, _ := net.ParseCIDR("10.0.0.0/8")
+	_, loopback, _ := net.ParseCIDR("::1/128")
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+
+	tests := map[string]struct {
+		server     configuration.HTTPServerConfig
+		path       string
+		remoteAddr string
+		tls        *tls.ConnectionState
+		allowed    bool
+	}{
+		"no restrictions": {
+			path:       "/construction/submit",
+			remoteAddr: "192.0.2.1:1000",
+			allowed:    true,
+		},
+		"allowed address": {
+			server:     configuration.HTTPServerConfig{ConstructionAllowedNetworks: []*net.IPNet{private, loopback}},
+			path:       "/construction/submit",
+			remoteAddr: "10.1.2.3:1000",
+			allowed:    true,
+		},
+		"allowed ipv6 address": {
+			server:     configuration.HTTPServerConfig{ConstructionAllowedNetworks: []*net.IPNet{private, loopback}},
+			path:       "/construction/payloads",
+			remoteAddr: "[::1]:1000",
+			allowed:    true,
+		},
+		"denied address": {
+			server:     configuration.HTTPServerConfig{ConstructionAllowedNetworks: []*net.IPNet{private, loopback}},
+			path:       "/construction/submit",
+			remoteAddr: "192.0.2.1:1000",
+		},
+		"data endpoint from denied address": {
+			server:     configuration.HTTPServerConfig{ConstructionAllowedNetworks: []*net.IPNet{private}},
+			path:       "/block",
+			remoteAddr: "192.0.2.1:1000",
+			allowed:    true,
+		},
+		"verified client certificate": {
+			server:     configuration.HTTPServerConfig{ConstructionClientCAFile: "ca.pem"},
+			path:       "/construction/submit",
+			remoteAddr: "192.0.2.1:1000",
+			tls:        verified,
+			allowed:    true,
+		},
+		"unverified client certificate": {
+			server:     configuration.HTTPServerConfig{ConstructionClientCAFile: "ca.pem"},
+			path:       "/construction/submit",
+			remoteAddr: "192.0.2.1:1000",
+			tls:        &tls.ConnectionState{},
+		},
+		"no tls": {
+			server:     configuration.HTTPServerConfig{ConstructionClientCAFile: "ca.pem"},
+			path:       "/construction/submit",
+			remoteAddr: "192.0.2.1:1000",
+		},
+		"data endpoint without client certificate": {
+			server:     configuration.HTTPServerConfig{ConstructionClientCAFile: "ca.pem"},
+			path:       "/account/balance",
+			remoteAddr: "192.0.2.1:1000",
+			tls:        &tls.ConnectionState{},
+			allowed:    true,
+		},
+		"certificate from denied address": {
+			server: configuration.HTTPServerConfig{
+				ConstructionClientCAFile:    "ca.pem",
+				ConstructionAllowedNetworks: []*net.IPNet{private},
+			},
+			path:       "/construction/submit",
+			remoteAddr: "192.0.2.1:1000",
+			tls:        verified,
+		},
+		"certificate from allowed address": {
+			server: configuration.HTTPServerConfig{
+				ConstructionClientCAFile:    "ca.pem",
+				ConstructionAllowedNetworks: []*net.IPNet{private},
+			},
+			path:       "/construction/submit",
+			remoteAddr: "10.1.2.3:1000",
+			tls:        verified,
+			allowed:    true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			handler := ConstructionAccessMiddleware(&configuration.Configuration{HTTPServer: test.server}, next)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, test.path, strings.NewReader("{}"))
+			r.RemoteAddr = test.remoteAddr
+			r.TLS = test.tls
+			handler.ServeHTTP(w, r)
+
+			if test.allowed {
+				assert.Equal(t, http.StatusOK, w.Code)
+				return
+			}
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			var rErr types.Error
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rErr))
+			assert.Equal(t, ErrAccessDenied.Code, rErr.Code)
+		})
+	}
+}
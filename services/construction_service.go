@@ -15,16 +15,23 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"strconv"
+	"strings"
 
+	"github.com/coinbase/rosetta-ethereum/amount"
+	"github.com/coinbase/rosetta-ethereum/audit"
 	"github.com/coinbase/rosetta-ethereum/configuration"
 	"github.com/coinbase/rosetta-ethereum/ethereum"
+	"github.com/coinbase/rosetta-ethereum/fees"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	ethTypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 
@@ -34,22 +41,46 @@ import (
 
 // ConstructionAPIService implements the server.ConstructionAPIServicer interface.
 type ConstructionAPIService struct {
-	config *configuration.Configuration
-	client Client
+	config       *configuration.Configuration
+	client       Client
+	nonceTracker NonceTracker
+	auditLog     AuditLog
+	submitQueue  SubmitQueue
+
+	// metadataCache is nil unless the fees of
+	// /construction/metadata are cached.
+	metadataCache *metadataCache
 }
 
 // NewConstructionAPIService creates a new instance of a ConstructionAPIService.
+// If nonceTracker is nil, /construction/metadata returns the pending
+// nonce reported by the node. If auditLog is nil, /construction/submit
+// does not record submitted transactions. If submitQueue is nil,
+// /construction/submit only broadcasts transactions once. If
+// cfg.MetadataCacheTTL is not 0, the fees (but not the nonces) of
+// /construction/metadata are cached (see metadataCache).
 func NewConstructionAPIService(
 	cfg *configuration.Configuration,
 	client Client,
+	nonceTracker NonceTracker,
+	auditLog AuditLog,
+	submitQueue SubmitQueue,
 ) *ConstructionAPIService {
 	return &ConstructionAPIService{
-		config: cfg,
-		client: client,
+		config:        cfg,
+		client:        client,
+		nonceTracker:  nonceTracker,
+		auditLog:      auditLog,
+		submitQueue:   submitQueue,
+		metadataCache: newMetadataCache(cfg.MetadataCacheTTL),
 	}
 }
 
 // ConstructionDerive implements the /construction/derive endpoint.
+// If the request metadata has an xpub, the address of a child of
+// the key is derived instead (see deriveHDAccount), and if it has
+// a factory, the address of a counterfactual contract wallet owned
+// by the key is (see create2Wallet). Any other metadata is ignored.
 func (s *ConstructionAPIService) ConstructionDerive(
 	ctx context.Context,
 	request *types.ConstructionDeriveRequest,
@@ -59,11 +90,50 @@ func (s *ConstructionAPIService) ConstructionDerive(
 		return nil, wrapErr(ErrUnableToDecompressPubkey, err)
 	}
 
+	// The metadata describes a child of the
+	// extended public key of the key.
+	if _, ok := request.Metadata["xpub"]; ok {
+		if !s.config.HDDerivation {
+			return nil, wrapErr(
+				ErrInvalidInput,
+				fmt.Errorf("HD derivation is disabled (see %s)", configuration.HDDerivationEnv),
+			)
+		}
+
+		child, metadata, hdErr := deriveHDAccount(pubkey, request.Metadata)
+		if hdErr != nil {
+			return nil, hdErr
+		}
+
+		return &types.ConstructionDeriveResponse{
+			AccountIdentifier: &types.AccountIdentifier{
+				Address: child.Hex(),
+			},
+			Metadata: metadata,
+		}, nil
+	}
+
 	addr := crypto.PubkeyToAddress(*pubkey)
+	if _, ok := request.Metadata["factory"]; !ok {
+		return &types.ConstructionDeriveResponse{
+			AccountIdentifier: &types.AccountIdentifier{
+				Address: addr.Hex(),
+			},
+		}, nil
+	}
+
+	// The metadata describes a counterfactual
+	// contract wallet owned by the key.
+	wallet, metadata, walletErr := deriveCreate2Wallet(addr, request.Metadata)
+	if walletErr != nil {
+		return nil, walletErr
+	}
+
 	return &types.ConstructionDeriveResponse{
 		AccountIdentifier: &types.AccountIdentifier{
-			Address: addr.Hex(),
+			Address: wallet.Hex(),
 		},
+		Metadata: metadata,
 	}, nil
 }
 
@@ -73,6 +143,54 @@ func (s *ConstructionAPIService) ConstructionPreprocess(
 	ctx context.Context,
 	request *types.ConstructionPreprocessRequest,
 ) (*types.ConstructionPreprocessResponse, *types.Error) {
+	if err := checkAmounts(request.Operations); err != nil {
+		return nil, err
+	}
+
+	cancel, intentErr := matchCancel(request.Operations)
+	if intentErr != nil {
+		return nil, intentErr
+	}
+	if cancel != nil {
+		marshaled, err := marshalJSONMap(&options{
+			From:        cancel.From,
+			CancelNonce: strconv.FormatUint(cancel.Nonce, 10),
+		})
+		if err != nil {
+			return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+		}
+
+		return &types.ConstructionPreprocessResponse{
+			Options: marshaled,
+		}, nil
+	}
+
+	var input preprocessMetadata
+	if err := unmarshalJSONMap(request.Metadata, &input); err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	intent, intentErr := matchDelegate(request.Operations)
+	if intentErr != nil {
+		return nil, intentErr
+	}
+	if intent != nil {
+		marshaled, err := marshalJSONMap(&options{
+			From:         intent.From,
+			Validator:    intent.Validator,
+			Value:        amount.Format(intent.Amount),
+			Fresh:        input.Fresh,
+			CheckBalance: input.CheckBalance,
+		})
+		if err != nil {
+			return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+		}
+
+		return &types.ConstructionPreprocessResponse{
+			Options: marshaled,
+		}, nil
+	}
+
 	descriptions := &parser.Descriptions{
 		OperationDescriptions: []*parser.OperationDescription{
 			{
@@ -118,13 +236,31 @@ func (s *ConstructionAPIService) ConstructionPreprocess(
 	}
 
 	// Ensure valid to address
-	_, ok = ethereum.ChecksumAddress(toAdd)
+	checkTo, ok := ethereum.ChecksumAddress(toAdd)
 	if !ok {
 		return nil, wrapErr(ErrInvalidAddress, fmt.Errorf("%s is not a valid address", toAdd))
 	}
 
 	preprocessOutput := &options{
-		From: checkFrom,
+		From:  checkFrom,
+		Fresh: input.Fresh,
+	}
+
+	if input.CheckBalance {
+		value, err := amount.Parse(fromOp.Amount.Value)
+		if err != nil {
+			return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+		}
+
+		preprocessOutput.Value = amount.Format(new(big.Int).Neg(value))
+		preprocessOutput.CheckBalance = true
+	}
+
+	// State overrides can only be applied by estimating the
+	// gas limit, which requires the recipient.
+	if len(input.StateOverrides) > 0 {
+		preprocessOutput.To = checkTo
+		preprocessOutput.StateOverrides = input.StateOverrides
 	}
 
 	marshaled, err := marshalJSONMap(preprocessOutput)
@@ -151,44 +287,164 @@ func (s *ConstructionAPIService) ConstructionMetadata(
 		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
 	}
 
-	nonce, err := s.client.PendingNonceAt(ctx, common.HexToAddress(input.From))
+	if len(input.CancelNonce) > 0 {
+		return s.cancelMetadata(ctx, &input)
+	}
+
+	fees, rErr := s.metadataCache.fees(ctx, &input, func(ctx context.Context) (*metadataFees, *types.Error) {
+		return s.metadataFees(ctx, &input)
+	})
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	// The balance is checked before a nonce is
+	// allocated, so a failed check does not
+	// leave a gap in the allocated nonces.
+	if input.CheckBalance {
+		if err := s.checkBalance(ctx, &input, fees); err != nil {
+			return nil, err
+		}
+	}
+
+	from := common.HexToAddress(input.From)
+	nonce, err := s.client.PendingNonceAt(ctx, from)
 	if err != nil {
 		return nil, wrapErr(ErrGeth, err)
 	}
+	if s.nonceTracker != nil {
+		nonce, err = s.nonceTracker.Next(from, nonce)
+		if err != nil {
+			return nil, wrapErr(ErrNonceAllocationFailed, err)
+		}
+	}
+
+	return metadataResponse(&metadata{
+		Nonce:    nonce,
+		GasPrice: fees.gasPrice,
+		GasLimit: fees.metadataGasLimit,
+	}, fees.gasLimit)
+}
+
+// metadataFees returns the gas price and the gas
+// limit of the transaction described by input.
+func (s *ConstructionAPIService) metadataFees(
+	ctx context.Context,
+	input *options,
+) (*metadataFees, *types.Error) {
 	gasPrice, err := s.client.SuggestGasPrice(ctx)
 	if err != nil {
 		return nil, wrapErr(ErrGeth, err)
 	}
 
-	metadata := &metadata{
-		Nonce:    nonce,
-		GasPrice: gasPrice,
+	fees := &metadataFees{
+		gasPrice: gasPrice,
+		gasLimit: s.config.GasLimits.Default(configuration.TransferGasLimit),
+	}
+	if len(input.Validator) > 0 {
+		if err := s.checkDelegation(ctx, input); err != nil {
+			return nil, err
+		}
+
+		fees.gasLimit = s.config.GasLimits.Default(configuration.DelegateGasLimit)
+		fees.metadataGasLimit = fees.gasLimit
+	} else if len(input.StateOverrides) > 0 {
+		estimate, err := s.estimateGas(ctx, input)
+		if err != nil {
+			return nil, wrapErr(ErrGeth, err)
+		}
+
+		fees.gasLimit, err = s.config.GasLimits.Estimate(estimate)
+		if err != nil {
+			return nil, wrapErr(ErrGasLimitCapExceeded, err)
+		}
+		fees.metadataGasLimit = fees.gasLimit
+	} else if fees.gasLimit != uint64(ethereum.TransferGasLimit) {
+		fees.metadataGasLimit = fees.gasLimit
 	}
 
+	return fees, nil
+}
+
+// metadataResponse returns the /construction/metadata response
+// of a transaction with metadata and gasLimit.
+func metadataResponse(
+	metadata *metadata,
+	gasLimit uint64,
+) (*types.ConstructionMetadataResponse, *types.Error) {
 	metadataMap, err := marshalJSONMap(metadata)
 	if err != nil {
 		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
 	}
 
 	// Find suggested gas usage
-	suggestedFee := metadata.GasPrice.Int64() * ethereum.TransferGasLimit
+	suggestedFee := new(big.Int).Mul(metadata.GasPrice, new(big.Int).SetUint64(gasLimit))
 
 	return &types.ConstructionMetadataResponse{
 		Metadata: metadataMap,
 		SuggestedFee: []*types.Amount{
-			{
-				Value:    strconv.FormatInt(suggestedFee, 10),
-				Currency: ethereum.Currency,
-			},
+			amount.New(suggestedFee, ethereum.Currency),
 		},
 	}, nil
 }
 
+// estimateGas estimates the gas limit of a transfer with the
+// state overrides provided to /construction/preprocess.
+func (s *ConstructionAPIService) estimateGas(
+	ctx context.Context,
+	input *options,
+) (uint64, error) {
+	params, err := marshalJSONMap(&ethereum.GetCallInput{
+		From:           input.From,
+		To:             input.To,
+		Data:           hexutil.Encode([]byte{}),
+		StateOverrides: input.StateOverrides,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := s.client.Call(ctx, &types.CallRequest{
+		Method:     "eth_estimateGas",
+		Parameters: params,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	estimate, ok := resp.Result["data"].(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected gas estimate %v", resp.Result["data"])
+	}
+
+	return hexutil.DecodeUint64(estimate)
+}
+
 // ConstructionPayloads implements the /construction/payloads endpoint.
 func (s *ConstructionAPIService) ConstructionPayloads(
 	ctx context.Context,
 	request *types.ConstructionPayloadsRequest,
 ) (*types.ConstructionPayloadsResponse, *types.Error) {
+	if err := checkAmounts(request.Operations); err != nil {
+		return nil, err
+	}
+
+	cancel, intentErr := matchCancel(request.Operations)
+	if intentErr != nil {
+		return nil, intentErr
+	}
+	if cancel != nil {
+		return s.cancelPayloads(request, cancel)
+	}
+
+	intent, intentErr := matchDelegate(request.Operations)
+	if intentErr != nil {
+		return nil, intentErr
+	}
+	if intent != nil {
+		return s.delegatePayloads(request, intent)
+	}
+
 	descriptions := &parser.Descriptions{
 		OperationDescriptions: []*parser.OperationDescription{
 			{
@@ -228,12 +484,15 @@ func (s *ConstructionAPIService) ConstructionPayloads(
 	}
 
 	// Required Fields for constructing a real Ethereum transaction
-	toOp, amount := matches[1].First()
+	toOp, value := matches[1].First()
 	toAdd := toOp.Account.Address
 	nonce := metadata.Nonce
 	gasPrice := metadata.GasPrice
 	chainID := s.config.Params.ChainID
 	transferGasLimit := uint64(ethereum.TransferGasLimit)
+	if metadata.GasLimit > 0 {
+		transferGasLimit = metadata.GasLimit
+	}
 	transferData := []byte{}
 
 	// Additional Fields for constructing custom Ethereum tx struct
@@ -252,30 +511,48 @@ func (s *ConstructionAPIService) ConstructionPayloads(
 		return nil, wrapErr(ErrInvalidAddress, fmt.Errorf("%s is not a valid address", toAdd))
 	}
 
-	tx := ethTypes.NewTransaction(
-		nonce,
-		common.HexToAddress(checkTo),
-		amount,
-		transferGasLimit,
-		gasPrice,
-		transferData,
-	)
-
-	unsignedTx := &transaction{
+	return s.payloads(&transaction{
 		From:     checkFrom,
 		To:       checkTo,
-		Value:    amount,
-		Data:     tx.Data(),
-		Nonce:    tx.Nonce(),
+		Value:    value,
+		Data:     transferData,
+		Nonce:    nonce,
 		GasPrice: gasPrice,
-		GasLimit: tx.Gas(),
+		GasLimit: transferGasLimit,
 		ChainID:  chainID,
+	})
+}
+
+// payloads returns the signing payload of unsignedTx.
+func (s *ConstructionAPIService) payloads(
+	unsignedTx *transaction,
+) (*types.ConstructionPayloadsResponse, *types.Error) {
+	tx := ethTypes.NewTransaction(
+		unsignedTx.Nonce,
+		common.HexToAddress(unsignedTx.To),
+		unsignedTx.Value,
+		unsignedTx.GasLimit,
+		unsignedTx.GasPrice,
+		unsignedTx.Data,
+	)
+
+	// The gas limit is fixed (or estimated by the node), so this
+	// only fails if the configured hardforks increase the cost
+	// of a transfer.
+	if err := fees.CheckGas(s.config.Params, nil, tx, tx.Gas()); err != nil {
+		return nil, wrapErr(ErrGasLimitTooLow, err)
+	}
+
+	// The network is embedded so that /construction/combine can
+	// refuse transactions constructed for another network.
+	if s.config.GenesisBlockIdentifier != nil {
+		unsignedTx.GenesisHash = s.config.GenesisBlockIdentifier.Hash
 	}
 
 	// Construct SigningPayload
-	signer := ethTypes.NewEIP155Signer(chainID)
+	signer := ethTypes.NewEIP155Signer(unsignedTx.ChainID)
 	payload := &types.SigningPayload{
-		AccountIdentifier: &types.AccountIdentifier{Address: checkFrom},
+		AccountIdentifier: &types.AccountIdentifier{Address: unsignedTx.From},
 		Bytes:             signer.Hash(tx).Bytes(),
 		SignatureType:     types.EcdsaRecovery,
 	}
@@ -302,6 +579,10 @@ func (s *ConstructionAPIService) ConstructionCombine(
 		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
 	}
 
+	if err := s.checkNetwork(&unsignedTx); err != nil {
+		return nil, err
+	}
+
 	ethTransaction := ethTypes.NewTransaction(
 		unsignedTx.Nonce,
 		common.HexToAddress(unsignedTx.To),
@@ -311,22 +592,103 @@ func (s *ConstructionAPIService) ConstructionCombine(
 		unsignedTx.Data,
 	)
 
+	// Catch wallet-side signing bugs before the
+	// transaction is broadcast.
+	if err := fees.CheckGas(s.config.Params, nil, ethTransaction, ethTransaction.Gas()); err != nil {
+		return nil, wrapErr(ErrGasLimitTooLow, err)
+	}
+
+	if len(request.Signatures) != 1 {
+		return nil, wrapErr(
+			ErrSignatureInvalid,
+			fmt.Errorf("expected 1 signature but got %d", len(request.Signatures)),
+		)
+	}
+
 	signer := ethTypes.NewEIP155Signer(unsignedTx.ChainID)
-	signedTx, err := ethTransaction.WithSignature(signer, request.Signatures[0].Bytes)
+	signature := request.Signatures[0]
+	if signature.SigningPayload != nil &&
+		!bytes.Equal(signature.SigningPayload.Bytes, signer.Hash(ethTransaction).Bytes()) {
+		return nil, wrapErr(
+			ErrSigningPayloadMismatch,
+			fmt.Errorf(
+				"signature is for payload %x but transaction hashes to %x",
+				signature.SigningPayload.Bytes,
+				signer.Hash(ethTransaction).Bytes(),
+			),
+		)
+	}
+
+	// WithSignature panics on signatures of the wrong size.
+	if len(signature.Bytes) != crypto.SignatureLength {
+		return nil, wrapErr(
+			ErrSignatureInvalid,
+			fmt.Errorf("expected %d signature bytes but got %d", crypto.SignatureLength, len(signature.Bytes)),
+		)
+	}
+
+	signedTx, err := ethTransaction.WithSignature(signer, signature.Bytes)
+	if err != nil {
+		return nil, wrapErr(ErrSignatureInvalid, err)
+	}
+
+	sender, err := ethTypes.Sender(signer, signedTx)
 	if err != nil {
 		return nil, wrapErr(ErrSignatureInvalid, err)
 	}
+	if sender != common.HexToAddress(unsignedTx.From) {
+		return nil, wrapErr(
+			ErrSignerMismatch,
+			fmt.Errorf("transaction is from %s but was signed by %s", unsignedTx.From, sender.Hex()),
+		)
+	}
 
 	signedTxJSON, err := signedTx.MarshalJSON()
 	if err != nil {
 		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
 	}
 
+	if unsignedTx.Cancel {
+		signedTxJSON, err = markCancel(signedTxJSON)
+		if err != nil {
+			return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+		}
+	}
+
 	return &types.ConstructionCombineResponse{
 		SignedTransaction: string(signedTxJSON),
 	}, nil
 }
 
+// checkNetwork ensures unsignedTx was constructed for the
+// configured network, so that transactions signed offline
+// are not combined for another network. Transactions
+// constructed without a genesis hash only have their
+// chain ID checked.
+func (s *ConstructionAPIService) checkNetwork(unsignedTx *transaction) *types.Error {
+	chainID := s.config.Params.ChainID
+	if unsignedTx.ChainID.Cmp(chainID) != 0 {
+		return wrapErr(
+			ErrChainIDMismatch,
+			fmt.Errorf("transaction is constructed for chain %s but network is chain %s", unsignedTx.ChainID, chainID),
+		)
+	}
+
+	if len(unsignedTx.GenesisHash) > 0 && s.config.GenesisBlockIdentifier != nil &&
+		!strings.EqualFold(unsignedTx.GenesisHash, s.config.GenesisBlockIdentifier.Hash) {
+		return wrapErr(
+			ErrGenesisMismatch,
+			fmt.Errorf(
+				"transaction is constructed for genesis block %s but network has genesis block %s",
+				unsignedTx.GenesisHash,
+				s.config.GenesisBlockIdentifier.Hash,
+			),
+		)
+	}
+
+	return nil
+}
+
 // ConstructionHash implements the /construction/hash endpoint.
 func (s *ConstructionAPIService) ConstructionHash(
 	ctx context.Context,
@@ -337,6 +699,20 @@ func (s *ConstructionAPIService) ConstructionHash(
 		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
 	}
 
+	// Transactions signed for another network would hash
+	// successfully but could never be broadcast on this one.
+	chainID := s.config.Params.ChainID
+	if signedTx.ChainId().Cmp(chainID) != 0 {
+		return nil, wrapErr(
+			ErrChainIDMismatch,
+			fmt.Errorf("transaction is signed for chain %s but network is chain %s", signedTx.ChainId(), chainID),
+		)
+	}
+
+	if _, err := ethTypes.Sender(ethTypes.NewEIP155Signer(chainID), &signedTx); err != nil {
+		return nil, wrapErr(ErrUnableToRecoverSender, err)
+	}
+
 	hash := signedTx.Hash().Hex()
 
 	return &types.TransactionIdentifierResponse{
@@ -364,6 +740,13 @@ func (s *ConstructionAPIService) ConstructionParse(
 			return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
 		}
 
+		if t.To() == nil {
+			return nil, wrapErr(
+				ErrUnableToParseIntermediateResult,
+				errors.New("contract creation transactions are not supported"),
+			)
+		}
+
 		tx.To = t.To().String()
 		tx.Value = t.Value()
 		tx.Data = t.Data()
@@ -372,6 +755,11 @@ func (s *ConstructionAPIService) ConstructionParse(
 		tx.GasLimit = t.Gas()
 		tx.ChainID = t.ChainId()
 
+		tx.Cancel, err = isMarkedCancel([]byte(request.Transaction))
+		if err != nil {
+			return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+		}
+
 		msg, err := t.AsMessage(ethTypes.NewEIP155Signer(t.ChainId()), nil)
 		if err != nil {
 			return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
@@ -392,44 +780,29 @@ func (s *ConstructionAPIService) ConstructionParse(
 		return nil, wrapErr(ErrInvalidAddress, fmt.Errorf("%s is not a valid address", tx.To))
 	}
 
-	ops := []*types.Operation{
-		{
-			Type: ethereum.CallOpType,
-			OperationIdentifier: &types.OperationIdentifier{
-				Index: 0,
-			},
-			Account: &types.AccountIdentifier{
-				Address: checkFrom,
-			},
-			Amount: &types.Amount{
-				Value:    new(big.Int).Neg(tx.Value).String(),
-				Currency: ethereum.Currency,
-			},
-		},
-		{
-			Type: ethereum.CallOpType,
-			OperationIdentifier: &types.OperationIdentifier{
-				Index: 1,
-			},
-			RelatedOperations: []*types.OperationIdentifier{
-				{
-					Index: 0,
-				},
-			},
-			Account: &types.AccountIdentifier{
-				Address: checkTo,
-			},
-			Amount: &types.Amount{
-				Value:    tx.Value.String(),
-				Currency: ethereum.Currency,
-			},
-		},
+	var ops []*types.Operation
+	validator, isDelegation := ethereum.ParseDelegateCoinData(tx.Data)
+	switch {
+	case isDelegation && common.HexToAddress(checkTo) == ethereum.PledgeAgentContract:
+		ops = delegateOperations(checkFrom, validator, tx.Value)
+	case tx.Cancel:
+		if !isCancel(checkFrom, checkTo, tx.Value, tx.Data) {
+			return nil, wrapErr(
+				ErrUnableToParseIntermediateResult,
+				errors.New("cancellation is not a zero-value transfer to its sender"),
+			)
+		}
+
+		ops = cancelOperations(checkFrom, tx.Nonce)
+	default:
+		ops = transferOperations(checkFrom, checkTo, tx.Value)
 	}
 
 	metadata := &parseMetadata{
-		Nonce:    tx.Nonce,
-		GasPrice: tx.GasPrice,
-		ChainID:  tx.ChainID,
+		Nonce:       tx.Nonce,
+		GasPrice:    tx.GasPrice,
+		ChainID:     tx.ChainID,
+		GenesisHash: tx.GenesisHash,
 	}
 	metaMap, err := marshalJSONMap(metadata)
 	if err != nil {
@@ -466,19 +839,211 @@ func (s *ConstructionAPIService) ConstructionSubmit(
 		return nil, ErrUnavailableOffline
 	}
 
+	// Transactions are never broadcast in public mode.
+	if s.config.PublicMode {
+		return nil, ErrEndpointDisabled
+	}
+
 	var signedTx ethTypes.Transaction
 	if err := signedTx.UnmarshalJSON([]byte(request.SignedTransaction)); err != nil {
 		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
 	}
 
-	if err := s.client.SendTransaction(ctx, &signedTx); err != nil {
-		return nil, wrapErr(ErrBroadcastFailed, err)
+	// Transactions are recorded before they are broadcast
+	// so that no transaction is ever broadcast unaudited.
+	if s.auditLog != nil {
+		entry, err := auditEntry(&signedTx)
+		if err != nil {
+			return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+		}
+
+		if err := s.auditLog.Record(entry); err != nil {
+			return nil, wrapErr(ErrAuditLogUnavailable, err)
+		}
+	}
+
+	// Transactions are queued before they are broadcast so
+	// that a restart never loses an accepted transaction.
+	if s.submitQueue != nil {
+		if err := s.submitQueue.Enqueue(&signedTx); err != nil {
+			return nil, wrapErr(ErrSubmitQueueUnavailable, err)
+		}
 	}
 
-	txIdentifier := &types.TransactionIdentifier{
-		Hash: signedTx.Hash().Hex(),
+	sendErr := s.client.SendTransaction(ctx, &signedTx)
+	if sendErr != nil && s.submitQueue == nil {
+		return nil, submitError(&signedTx, sendErr)
+	}
+
+	response := &types.TransactionIdentifierResponse{
+		TransactionIdentifier: &types.TransactionIdentifier{
+			Hash: signedTx.Hash().Hex(),
+		},
+	}
+	if s.submitQueue == nil {
+		return response, nil
+	}
+
+	queued, rErr := s.recordSubmission(&signedTx, sendErr)
+	if rErr != nil {
+		return nil, rErr
+	}
+	if queued {
+		response.Metadata = map[string]interface{}{"queued": true}
+	}
+
+	return response, nil
+}
+
+// recordSubmission records the broadcast of signedTx, which
+// failed with sendErr (or succeeded if sendErr is nil), in the
+// submit queue. Transactions that failed with an error that a
+// rebroadcast cannot fix are removed from the queue and the error
+// is returned. Other failed transactions remain queued and are
+// reported as queued, as the queue rebroadcasts them.
+func (s *ConstructionAPIService) recordSubmission(
+	signedTx *ethTypes.Transaction,
+	sendErr error,
+) (bool, *types.Error) {
+	hash := signedTx.Hash()
+
+	var rErr *types.Error
+	if sendErr != nil {
+		rErr = submitError(signedTx, sendErr)
+	}
+
+	switch {
+	case rErr == nil, rErr.Code == ErrTransactionAlreadyKnown.Code:
+		if err := s.submitQueue.RecordAttempt(hash, sendErr); err != nil {
+			return false, wrapErr(ErrSubmitQueueUnavailable, err)
+		}
+
+		return false, nil
+	case rErr.Code == ErrBroadcastFailed.Code, rErr.Code == ErrTxPoolFull.Code:
+		if err := s.submitQueue.RecordAttempt(hash, sendErr); err != nil {
+			return false, wrapErr(ErrSubmitQueueUnavailable, err)
+		}
+
+		return true, nil
+	}
+
+	if err := s.submitQueue.Remove(hash); err != nil {
+		return false, wrapErr(ErrSubmitQueueUnavailable, err)
+	}
+
+	return false, rErr
+}
+
+// auditEntry returns the audit log entry of signedTx.
+// Delegations are recorded as a transfer to the validator.
+func auditEntry(signedTx *ethTypes.Transaction) (*audit.Entry, error) {
+	sender, err := ethTypes.Sender(ethTypes.NewLondonSigner(signedTx.ChainId()), signedTx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to recover sender", err)
+	}
+
+	entry := &audit.Entry{
+		TransactionHash: signedTx.Hash().Hex(),
+		Sender:          sender.Hex(),
+		Recipients:      []*audit.Recipient{},
+		Intent:          auditIntent(signedTx),
+	}
+	if signedTx.To() == nil {
+		return entry, nil
+	}
+
+	recipient := *signedTx.To()
+	validator, isDelegation := ethereum.ParseDelegateCoinData(signedTx.Data())
+	if isDelegation && recipient == ethereum.PledgeAgentContract {
+		recipient = validator
+	}
+
+	entry.Recipients = append(entry.Recipients, &audit.Recipient{
+		Address: recipient.Hex(),
+		Amount:  signedTx.Value().String(),
+	})
+	return entry, nil
+}
+
+// auditIntent returns every signed field of signedTx.
+func auditIntent(signedTx *ethTypes.Transaction) *audit.Intent {
+	intent := &audit.Intent{
+		Type:    signedTx.Type(),
+		ChainID: signedTx.ChainId().String(),
+		Nonce:   signedTx.Nonce(),
+		Value:   signedTx.Value().String(),
+		Data:    hexutil.Encode(signedTx.Data()),
+		Gas:     signedTx.Gas(),
+	}
+	if signedTx.To() != nil {
+		intent.To = signedTx.To().Hex()
+	}
+
+	if signedTx.Type() == ethTypes.DynamicFeeTxType {
+		intent.GasFeeCap = signedTx.GasFeeCap().String()
+		intent.GasTipCap = signedTx.GasTipCap().String()
+	} else {
+		intent.GasPrice = signedTx.GasPrice().String()
+	}
+
+	for _, tuple := range signedTx.AccessList() {
+		accessTuple := &audit.AccessTuple{
+			Address:     tuple.Address.Hex(),
+			StorageKeys: make([]string, len(tuple.StorageKeys)),
+		}
+		for i, key := range tuple.StorageKeys {
+			accessTuple.StorageKeys[i] = key.Hex()
+		}
+		intent.AccessList = append(intent.AccessList, accessTuple)
+	}
+
+	return intent
+}
+
+// checkAmounts ensures the amounts of ops are canonical
+// and fit in 256 bits before their intent is matched.
+func checkAmounts(ops []*types.Operation) *types.Error {
+	for _, op := range ops {
+		if op.Amount == nil {
+			continue
+		}
+
+		if _, err := amount.Value(op.Amount, nil); err != nil {
+			return wrapErr(ErrInvalidInput, err)
+		}
+	}
+
+	return nil
+}
+
+// transferOperations returns the operations of
+// a transfer parsed by /construction/parse.
+func transferOperations(from string, to string, value *big.Int) []*types.Operation {
+	return []*types.Operation{
+		{
+			Type: ethereum.CallOpType,
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 0,
+			},
+			Account: &types.AccountIdentifier{
+				Address: from,
+			},
+			Amount: amount.NewNeg(value, ethereum.Currency),
+		},
+		{
+			Type: ethereum.CallOpType,
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 1,
+			},
+			RelatedOperations: []*types.OperationIdentifier{
+				{
+					Index: 0,
+				},
+			},
+			Account: &types.AccountIdentifier{
+				Address: to,
+			},
+			Amount: amount.New(value, ethereum.Currency),
+		},
 	}
-	return &types.TransactionIdentifierResponse{
-		TransactionIdentifier: txIdentifier,
-	}, nil
 }
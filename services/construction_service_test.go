@@ -15,18 +15,33 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 
+	"github.com/coinbase/rosetta-ethereum/audit"
 	"github.com/coinbase/rosetta-ethereum/configuration"
 	"github.com/coinbase/rosetta-ethereum/ethereum"
 	mocks "github.com/coinbase/rosetta-ethereum/mocks/services"
+	"github.com/coinbase/rosetta-ethereum/redact"
 
+	"github.com/coinbase/rosetta-sdk-go/asserter"
+	"github.com/coinbase/rosetta-sdk-go/server"
 	"github.com/coinbase/rosetta-sdk-go/types"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -63,7 +78,7 @@ func TestConstructionService(t *testing.T) {
 	}
 
 	mockClient := &mocks.Client{}
-	servicer := NewConstructionAPIService(cfg, mockClient)
+	servicer := NewConstructionAPIService(cfg, mockClient, nil, nil, nil)
 	ctx := context.Background()
 
 	// Test Derive
@@ -239,3 +254,1007 @@ func TestConstructionService(t *testing.T) {
 
 	mockClient.AssertExpectations(t)
 }
+
+func TestConstructionService_Redaction(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    ethereum.RopstenNetwork,
+		Blockchain: ethereum.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:                   configuration.Online,
+		Network:                networkIdentifier,
+		GenesisBlockIdentifier: ethereum.RopstenGenesisBlockIdentifier,
+		Params:                 params.RopstenChainConfig,
+		ValidationMode:         configuration.StrictValidation,
+	}
+
+	redactor, err := redact.New(nil)
+	assert.NoError(t, err)
+	var logs bytes.Buffer
+	log.SetOutput(redactor.Writer(&logs))
+	defer log.SetOutput(os.Stderr)
+
+	serverAsserter, err := asserter.NewServer(
+		ethereum.OperationTypes,
+		ethereum.HistoricalBalanceSupported,
+		[]*types.NetworkIdentifier{networkIdentifier},
+		ethereum.CallMethods,
+		ethereum.IncludeMempoolCoins,
+		"",
+	)
+	assert.NoError(t, err)
+
+	mockClient := &mocks.Client{}
+	router, err := ValidationMiddleware(cfg, NewBlockchainRouter(cfg, mockClient, nil, nil, nil, nil, nil, serverAsserter))
+	assert.NoError(t, err)
+	handler := server.LoggerMiddleware(router)
+
+	unsignedRaw := `{"from":"0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309","to":"0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d","value":"0x9864aac3510d02","data":"0x","nonce":"0x0","gas_price":"0x3b9aca00","gas":"0x5208","chain_id":"0x3"}`                                                                                                                                                                                                                                                                                                                                                                       // nolint
+	signaturesRaw := `[{"hex_bytes":"8c712c64bc65c4a88707fa93ecd090144dffb1bf133805a10a51d354c2f9f2b25a63cea6989f4c58372c41f31164036a6b25dce1d5c05e1d31c16c0590c176e801","signing_payload":{"address":"0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309","hex_bytes":"b682f3e39c512ff57471f482eab264551487320cbd3b34485f4779a89e5612d1","account_identifier":{"address":"0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"},"signature_type":"ecdsa_recovery"},"public_key":{"hex_bytes":"03d3d3358e7f69cbe45bde38d7d6f24660c7eeeaee5c5590cfab985c8839b21fd5","curve_type":"secp256k1"},"signature_type":"ecdsa_recovery"}]` // nolint
+	signedRaw := `{"type":"0x0","nonce":"0x0","gasPrice":"0x3b9aca00","maxPriorityFeePerGas":null,"maxFeePerGas":null,"gas":"0x5208","value":"0x9864aac3510d02","input":"0x","v":"0x2a","r":"0x8c712c64bc65c4a88707fa93ecd090144dffb1bf133805a10a51d354c2f9f2b2","s":"0x5a63cea6989f4c58372c41f31164036a6b25dce1d5c05e1d31c16c0590c176e8","to":"0x57b414a0332b5cab885a451c2a28a07d1e9b8a8d","hash":"0x424969b1a98757bcd748c60bad2a7de9745cfb26bfefb4550e780a098feada42"}`                                                                                                                                        // nolint
+	leaks := []string{
+		"8c712c64bc65c4a88707fa93ecd090144dffb1bf133805a10a51d354c2f9f2b2",
+		"5a63cea6989f4c58372c41f31164036a6b25dce1d5c05e1d31c16c0590c176e8",
+	}
+
+	var signatures []*types.Signature
+	assert.NoError(t, json.Unmarshal([]byte(signaturesRaw), &signatures))
+
+	// Simulate a node that echoes the submitted
+	// payload in its error message.
+	mockClient.On(
+		"SendTransaction",
+		mock.Anything,
+		mock.Anything,
+	).Return(
+		fmt.Errorf("rejected transaction {\"signed_transaction\":%q}", signedRaw),
+	).Once()
+
+	requests := map[string]interface{}{
+		"/construction/combine": &types.ConstructionCombineRequest{
+			NetworkIdentifier:   networkIdentifier,
+			UnsignedTransaction: unsignedRaw,
+			Signatures:          signatures,
+		},
+		"/construction/hash": &types.ConstructionHashRequest{
+			NetworkIdentifier: networkIdentifier,
+			SignedTransaction: signedRaw,
+		},
+		"/construction/parse": &types.ConstructionParseRequest{
+			NetworkIdentifier: networkIdentifier,
+			Signed:            true,
+			Transaction:       signedRaw,
+		},
+		"/construction/submit": &types.ConstructionSubmitRequest{
+			NetworkIdentifier: networkIdentifier,
+			SignedTransaction: signedRaw,
+		},
+	}
+
+	for path, request := range requests {
+		body, err := json.Marshal(request)
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body)))
+
+		if recorder.Code != http.StatusOK {
+			for _, leak := range leaks {
+				assert.NotContains(t, recorder.Body.String(), leak, path)
+			}
+		}
+	}
+
+	assert.NotEmpty(t, logs.String())
+	for _, leak := range leaks {
+		assert.NotContains(t, logs.String(), leak)
+	}
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestConstructionMetadata_NonceTracker(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    ethereum.RopstenNetwork,
+		Blockchain: ethereum.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:    configuration.Online,
+		Network: networkIdentifier,
+		Params:  params.RopstenChainConfig,
+	}
+
+	mockClient := &mocks.Client{}
+	mockNonceTracker := &mocks.NonceTracker{}
+	servicer := NewConstructionAPIService(cfg, mockClient, mockNonceTracker, nil, nil)
+	ctx := context.Background()
+
+	from := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	optionsMap := forceMarshalMap(t, &options{From: from.Hex()})
+
+	mockClient.On("PendingNonceAt", ctx, from).Return(uint64(3), nil).Twice()
+	mockClient.On("SuggestGasPrice", ctx).Return(big.NewInt(1000000000), nil).Twice()
+	mockNonceTracker.On("Next", from, uint64(3)).Return(uint64(5), nil).Once()
+	metadataResponse, err := servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           optionsMap,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, forceMarshalMap(t, &metadata{
+		Nonce:    5,
+		GasPrice: big.NewInt(1000000000),
+	}), metadataResponse.Metadata)
+
+	mockNonceTracker.On("Next", from, uint64(3)).Return(uint64(0), errors.New("disk full")).Once()
+	metadataResponse, err = servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           optionsMap,
+	})
+	assert.Nil(t, metadataResponse)
+	assert.Equal(t, ErrNonceAllocationFailed.Code, err.Code)
+
+	mockClient.AssertExpectations(t)
+	mockNonceTracker.AssertExpectations(t)
+}
+
+func TestConstructionMetadata_StateOverrides(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    ethereum.RopstenNetwork,
+		Blockchain: ethereum.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:    configuration.Online,
+		Network: networkIdentifier,
+		Params:  params.RopstenChainConfig,
+	}
+
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, nil, nil, nil)
+	ctx := context.Background()
+
+	from := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	to := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	ops := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{Index: 0},
+			Type:                ethereum.CallOpType,
+			Account:             &types.AccountIdentifier{Address: from.Hex()},
+			Amount:              &types.Amount{Value: "-1000", Currency: ethereum.Currency},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{Index: 1},
+			Type:                ethereum.CallOpType,
+			Account:             &types.AccountIdentifier{Address: to.Hex()},
+			Amount:              &types.Amount{Value: "1000", Currency: ethereum.Currency},
+		},
+	}
+	overrides := map[string]interface{}{
+		from.Hex(): map[string]interface{}{
+			"balance": "0xde0b6b3a7640000",
+		},
+	}
+
+	// Without overrides, the options are unchanged
+	preprocessResponse, err := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, forceMarshalMap(t, &options{From: from.Hex()}), preprocessResponse.Options)
+
+	preprocessResponse, err = servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata: map[string]interface{}{
+			"state_overrides": overrides,
+		},
+	})
+	assert.Nil(t, err)
+	// Overridden addresses are normalized
+	normalizedOverrides := map[string]interface{}{
+		strings.ToLower(from.Hex()): overrides[from.Hex()],
+	}
+	assert.Equal(t, map[string]interface{}{
+		"from":            from.Hex(),
+		"to":              to.Hex(),
+		"state_overrides": normalizedOverrides,
+	}, preprocessResponse.Options)
+
+	mockClient.On("PendingNonceAt", ctx, from).Return(uint64(0), nil).Once()
+	mockClient.On("SuggestGasPrice", ctx).Return(big.NewInt(1000000000), nil).Once()
+	mockClient.On(
+		"Call",
+		ctx,
+		mock.MatchedBy(func(request *types.CallRequest) bool {
+			return request.Method == "eth_estimateGas" &&
+				request.Parameters["from"] == from.Hex() &&
+				request.Parameters["to"] == to.Hex() &&
+				assert.ObjectsAreEqual(normalizedOverrides, request.Parameters["state_overrides"])
+		}),
+	).Return(
+		&types.CallResponse{Result: map[string]interface{}{"data": "0x6270"}},
+		nil,
+	).Once()
+	metadataResponse, err := servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           preprocessResponse.Options,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"nonce":     "0x0",
+		"gas_price": "0x3b9aca00",
+		"gas_limit": "0x6270",
+	}, metadataResponse.Metadata)
+	assert.Equal(t, "25200000000000", metadataResponse.SuggestedFee[0].Value)
+
+	// The estimated gas limit is used by /construction/payloads
+	payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          metadataResponse.Metadata,
+	})
+	assert.Nil(t, err)
+	var unsignedTx transaction
+	assert.NoError(t, json.Unmarshal([]byte(payloadsResponse.UnsignedTransaction), &unsignedTx))
+	assert.Equal(t, uint64(25200), unsignedTx.GasLimit)
+
+	// Estimation failures are surfaced before a nonce is fetched
+	mockClient.On("SuggestGasPrice", ctx).Return(big.NewInt(1000000000), nil).Once()
+	mockClient.On("Call", ctx, mock.Anything).Return(nil, errors.New("too many arguments")).Once()
+	metadataResponse, err = servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           preprocessResponse.Options,
+	})
+	assert.Nil(t, metadataResponse)
+	assert.Equal(t, ErrGeth.Code, err.Code)
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestConstructionPreprocess_InvalidAmounts(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode:    configuration.Offline,
+		Network: networkIdentifier,
+		Params:  params.RopstenChainConfig,
+	}
+	servicer := NewConstructionAPIService(cfg, &mocks.Client{}, nil, nil, nil)
+	ctx := context.Background()
+
+	from := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	to := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	overflow := new(big.Int).Lsh(big.NewInt(1), 256)
+
+	tests := map[string][2]string{
+		"leading zero":  {"-01000", "01000"},
+		"negative zero": {"-0", "0"},
+		"overflow":      {"-" + overflow.String(), overflow.String()},
+	}
+
+	for name, values := range tests {
+		t.Run(name, func(t *testing.T) {
+			ops := []*types.Operation{
+				{
+					OperationIdentifier: &types.OperationIdentifier{Index: 0},
+					Type:                ethereum.CallOpType,
+					Account:             &types.AccountIdentifier{Address: from.Hex()},
+					Amount:              &types.Amount{Value: values[0], Currency: ethereum.Currency},
+				},
+				{
+					OperationIdentifier: &types.OperationIdentifier{Index: 1},
+					Type:                ethereum.CallOpType,
+					Account:             &types.AccountIdentifier{Address: to.Hex()},
+					Amount:              &types.Amount{Value: values[1], Currency: ethereum.Currency},
+				},
+			}
+
+			preprocessResponse, err := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+				NetworkIdentifier: networkIdentifier,
+				Operations:        ops,
+			})
+			assert.Nil(t, preprocessResponse)
+			assert.Equal(t, ErrInvalidInput.Code, err.Code)
+
+			payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+				NetworkIdentifier: networkIdentifier,
+				Operations:        ops,
+			})
+			assert.Nil(t, payloadsResponse)
+			assert.Equal(t, ErrInvalidInput.Code, err.Code)
+		})
+	}
+}
+
+func TestConstructionMetadata_GasLimits(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode:   configuration.Online,
+		Params: params.RopstenChainConfig,
+		GasLimits: &configuration.GasLimits{
+			Multiplier: 1.5,
+			Cap:        30000,
+			Defaults: map[configuration.GasLimitType]uint64{
+				configuration.TransferGasLimit: 25000,
+			},
+		},
+	}
+
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, nil, nil, nil)
+	ctx := context.Background()
+
+	from := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	to := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+
+	// Transfers use the configured default
+	mockClient.On("PendingNonceAt", ctx, from).Return(uint64(0), nil).Once()
+	mockClient.On("SuggestGasPrice", ctx).Return(big.NewInt(1000000000), nil).Once()
+	metadataResponse, err := servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		Options: forceMarshalMap(t, &options{From: from.Hex()}),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"nonce":     "0x0",
+		"gas_price": "0x3b9aca00",
+		"gas_limit": "0x61a8",
+	}, metadataResponse.Metadata)
+	assert.Equal(t, "25000000000000", metadataResponse.SuggestedFee[0].Value)
+
+	// Estimates are multiplied and rejected above the cap
+	mockClient.On("PendingNonceAt", ctx, from).Return(uint64(1), nil).Once()
+	mockClient.On("SuggestGasPrice", ctx).Return(big.NewInt(1000000000), nil).Twice()
+	mockClient.On(
+		"Call",
+		ctx,
+		mock.MatchedBy(func(request *types.CallRequest) bool {
+			return request.Method == "eth_estimateGas"
+		}),
+	).Return(
+		&types.CallResponse{Result: map[string]interface{}{"data": "0x3e80"}},
+		nil,
+	).Once()
+	mockClient.On(
+		"Call",
+		ctx,
+		mock.MatchedBy(func(request *types.CallRequest) bool {
+			return request.Method == "eth_estimateGas"
+		}),
+	).Return(
+		&types.CallResponse{Result: map[string]interface{}{"data": "0x6270"}},
+		nil,
+	).Once()
+	overridesOptions := forceMarshalMap(t, &options{
+		From: from.Hex(),
+		To:   to.Hex(),
+		StateOverrides: ethereum.StateOverride{
+			from: ethereum.OverrideAccount{
+				Balance: (*hexutil.Big)(big.NewInt(1000000000000000000)),
+			},
+		},
+	})
+	metadataResponse, err = servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		Options: overridesOptions,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "0x5dc0", metadataResponse.Metadata["gas_limit"])
+
+	metadataResponse, err = servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		Options: overridesOptions,
+	})
+	assert.Nil(t, metadataResponse)
+	assert.Equal(t, ErrGasLimitCapExceeded.Code, err.Code)
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestConstructionCombine_Invalid(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    ethereum.RopstenNetwork,
+		Blockchain: ethereum.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:                   configuration.Offline,
+		Network:                networkIdentifier,
+		GenesisBlockIdentifier: ethereum.RopstenGenesisBlockIdentifier,
+		Params:                 params.RopstenChainConfig,
+	}
+
+	servicer := NewConstructionAPIService(cfg, &mocks.Client{}, nil, nil, nil)
+	ctx := context.Background()
+
+	unsignedRaw := `{"from":"0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309","to":"0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d","value":"0x9864aac3510d02","data":"0x","nonce":"0x0","gas_price":"0x3b9aca00","gas":"0x5208","chain_id":"0x3"}`                                                                                                                                                                                                                                                                                                                                                                       // nolint
+	signaturesRaw := `[{"hex_bytes":"8c712c64bc65c4a88707fa93ecd090144dffb1bf133805a10a51d354c2f9f2b25a63cea6989f4c58372c41f31164036a6b25dce1d5c05e1d31c16c0590c176e801","signing_payload":{"address":"0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309","hex_bytes":"b682f3e39c512ff57471f482eab264551487320cbd3b34485f4779a89e5612d1","account_identifier":{"address":"0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"},"signature_type":"ecdsa_recovery"},"public_key":{"hex_bytes":"03d3d3358e7f69cbe45bde38d7d6f24660c7eeeaee5c5590cfab985c8839b21fd5","curve_type":"secp256k1"},"signature_type":"ecdsa_recovery"}]` // nolint
+	signatures := func() []*types.Signature {
+		var signatures []*types.Signature
+		assert.NoError(t, json.Unmarshal([]byte(signaturesRaw), &signatures))
+		return signatures
+	}
+
+	tests := map[string]struct {
+		unsignedTx string
+		signatures []*types.Signature
+		err        *types.Error
+	}{
+		"nonce mismatch": {
+			unsignedTx: strings.Replace(unsignedRaw, `"nonce":"0x0"`, `"nonce":"0x1"`, 1),
+			signatures: signatures(),
+			err:        ErrSigningPayloadMismatch,
+		},
+		"sender mismatch": {
+			unsignedTx: strings.Replace(
+				unsignedRaw,
+				"0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309",
+				"0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d",
+				1,
+			),
+			signatures: signatures(),
+			err:        ErrSignerMismatch,
+		},
+		"gas limit below intrinsic gas": {
+			unsignedTx: strings.Replace(unsignedRaw, `"gas":"0x5208"`, `"gas":"0x5207"`, 1),
+			signatures: signatures(),
+			err:        ErrGasLimitTooLow,
+		},
+		"multiple signatures": {
+			unsignedTx: unsignedRaw,
+			signatures: append(signatures(), signatures()...),
+			err:        ErrSignatureInvalid,
+		},
+		"signature too long": {
+			unsignedTx: unsignedRaw,
+			signatures: func() []*types.Signature {
+				s := signatures()
+				s[0].Bytes = append(s[0].Bytes, 0x00)
+				return s
+			}(),
+			err: ErrSignatureInvalid,
+		},
+		"chain ID mismatch": {
+			unsignedTx: strings.Replace(unsignedRaw, `"chain_id":"0x3"`, `"chain_id":"0x45c"`, 1),
+			signatures: signatures(),
+			err:        ErrChainIDMismatch,
+		},
+		"genesis hash mismatch": {
+			unsignedTx: strings.Replace(
+				unsignedRaw,
+				`"chain_id":"0x3"`,
+				fmt.Sprintf(`"chain_id":"0x3","genesis_hash":"%s"`, ethereum.MainnetGenesisBlockIdentifier.Hash),
+				1,
+			),
+			signatures: signatures(),
+			err:        ErrGenesisMismatch,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			combineResponse, err := servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+				NetworkIdentifier:   networkIdentifier,
+				UnsignedTransaction: test.unsignedTx,
+				Signatures:          test.signatures,
+			})
+			assert.Nil(t, combineResponse)
+			assert.Equal(t, test.err.Code, err.Code)
+		})
+	}
+}
+
+func TestConstructionCombine_GenesisHash(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    ethereum.RopstenNetwork,
+		Blockchain: ethereum.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:                   configuration.Offline,
+		Network:                networkIdentifier,
+		GenesisBlockIdentifier: ethereum.RopstenGenesisBlockIdentifier,
+		Params:                 params.RopstenChainConfig,
+	}
+	servicer := NewConstructionAPIService(cfg, &mocks.Client{}, nil, nil, nil)
+	ctx := context.Background()
+
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	payloads, rErr := servicer.payloads(&transaction{
+		From:     sender.Hex(),
+		To:       "0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d",
+		Value:    big.NewInt(1000),
+		Data:     []byte{},
+		GasPrice: big.NewInt(1),
+		GasLimit: 21000,
+		ChainID:  params.RopstenChainConfig.ChainID,
+	})
+	assert.Nil(t, rErr)
+
+	var unsignedTx map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(payloads.UnsignedTransaction), &unsignedTx))
+	assert.Equal(t, ethereum.RopstenGenesisBlockIdentifier.Hash, unsignedTx["genesis_hash"])
+
+	parseResponse, rErr := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: networkIdentifier,
+		Transaction:       payloads.UnsignedTransaction,
+	})
+	assert.Nil(t, rErr)
+	assert.Equal(t, ethereum.RopstenGenesisBlockIdentifier.Hash, parseResponse.Metadata["genesis_hash"])
+
+	signature, err := crypto.Sign(payloads.Payloads[0].Bytes, key)
+	assert.NoError(t, err)
+	combineResponse, rErr := servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: payloads.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				SigningPayload: payloads.Payloads[0],
+				SignatureType:  types.EcdsaRecovery,
+				Bytes:          signature,
+			},
+		},
+	})
+	assert.Nil(t, rErr)
+	assert.NotEmpty(t, combineResponse.SignedTransaction)
+
+	// The same transaction is refused by a deployment
+	// of another network with the same chain ID.
+	other := NewConstructionAPIService(&configuration.Configuration{
+		Mode:                   configuration.Offline,
+		Network:                networkIdentifier,
+		GenesisBlockIdentifier: ethereum.GoerliGenesisBlockIdentifier,
+		Params:                 params.RopstenChainConfig,
+	}, &mocks.Client{}, nil, nil, nil)
+	combineResponse, rErr = other.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: payloads.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				SigningPayload: payloads.Payloads[0],
+				SignatureType:  types.EcdsaRecovery,
+				Bytes:          signature,
+			},
+		},
+	})
+	assert.Nil(t, combineResponse)
+	assert.Equal(t, ErrGenesisMismatch.Code, rErr.Code)
+}
+
+func TestConstructionSubmit_AuditLog(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    ethereum.RopstenNetwork,
+		Blockchain: ethereum.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:    configuration.Online,
+		Network: networkIdentifier,
+		Params:  params.RopstenChainConfig,
+	}
+
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	to := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	signedTx, err := ethTypes.SignTx(
+		ethTypes.NewTransaction(0, to, big.NewInt(1000), 21000, big.NewInt(1), nil),
+		ethTypes.NewEIP155Signer(params.RopstenChainConfig.ChainID),
+		key,
+	)
+	assert.NoError(t, err)
+	signedRaw, err := signedTx.MarshalJSON()
+	assert.NoError(t, err)
+
+	expectedEntry := &audit.Entry{
+		TransactionHash: signedTx.Hash().Hex(),
+		Sender:          sender.Hex(),
+		Recipients: []*audit.Recipient{
+			{
+				Address: to.Hex(),
+				Amount:  "1000",
+			},
+		},
+		Intent: &audit.Intent{
+			Type:     ethTypes.LegacyTxType,
+			ChainID:  params.RopstenChainConfig.ChainID.String(),
+			Nonce:    0,
+			To:       to.Hex(),
+			Value:    "1000",
+			Data:     "0x",
+			Gas:      21000,
+			GasPrice: "1",
+		},
+	}
+
+	t.Run("recorded before broadcast", func(t *testing.T) {
+		mockClient := &mocks.Client{}
+		mockAuditLog := &mocks.AuditLog{}
+		servicer := NewConstructionAPIService(cfg, mockClient, nil, mockAuditLog, nil)
+
+		mockAuditLog.On("Record", expectedEntry).Return(nil).Once()
+		mockClient.On("SendTransaction", mock.Anything, mock.Anything).Return(nil).Once()
+		resp, rErr := servicer.ConstructionSubmit(context.Background(), &types.ConstructionSubmitRequest{
+			NetworkIdentifier: networkIdentifier,
+			SignedTransaction: string(signedRaw),
+		})
+		assert.Nil(t, rErr)
+		assert.Equal(t, signedTx.Hash().Hex(), resp.TransactionIdentifier.Hash)
+
+		mockClient.AssertExpectations(t)
+		mockAuditLog.AssertExpectations(t)
+	})
+
+	t.Run("not broadcast if not recorded", func(t *testing.T) {
+		mockClient := &mocks.Client{}
+		mockAuditLog := &mocks.AuditLog{}
+		servicer := NewConstructionAPIService(cfg, mockClient, nil, mockAuditLog, nil)
+
+		mockAuditLog.On("Record", expectedEntry).Return(errors.New("disk full")).Once()
+		resp, rErr := servicer.ConstructionSubmit(context.Background(), &types.ConstructionSubmitRequest{
+			NetworkIdentifier: networkIdentifier,
+			SignedTransaction: string(signedRaw),
+		})
+		assert.Nil(t, resp)
+		assert.Equal(t, ErrAuditLogUnavailable.Code, rErr.Code)
+
+		mockClient.AssertExpectations(t)
+		mockAuditLog.AssertExpectations(t)
+	})
+}
+
+func TestAuditEntry_DynamicFee(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	to := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	storageKey := common.HexToHash("0x01")
+	signedTx, err := ethTypes.SignNewTx(
+		key,
+		ethTypes.NewLondonSigner(params.RopstenChainConfig.ChainID),
+		&ethTypes.DynamicFeeTx{
+			ChainID:   params.RopstenChainConfig.ChainID,
+			Nonce:     3,
+			GasTipCap: big.NewInt(1000000000),
+			GasFeeCap: big.NewInt(2000000000),
+			Gas:       50000,
+			To:        &to,
+			Value:     big.NewInt(1000),
+			Data:      []byte{0xde, 0xad},
+			AccessList: ethTypes.AccessList{
+				{Address: to, StorageKeys: []common.Hash{storageKey}},
+			},
+		},
+	)
+	assert.NoError(t, err)
+
+	entry, err := auditEntry(signedTx)
+	assert.NoError(t, err)
+	assert.Equal(t, &audit.Entry{
+		TransactionHash: signedTx.Hash().Hex(),
+		Sender:          sender.Hex(),
+		Recipients: []*audit.Recipient{
+			{
+				Address: to.Hex(),
+				Amount:  "1000",
+			},
+		},
+		Intent: &audit.Intent{
+			Type:      ethTypes.DynamicFeeTxType,
+			ChainID:   params.RopstenChainConfig.ChainID.String(),
+			Nonce:     3,
+			To:        to.Hex(),
+			Value:     "1000",
+			Data:      "0xdead",
+			Gas:       50000,
+			GasFeeCap: "2000000000",
+			GasTipCap: "1000000000",
+			AccessList: []*audit.AccessTuple{
+				{
+					Address:     to.Hex(),
+					StorageKeys: []string{storageKey.Hex()},
+				},
+			},
+		},
+	}, entry)
+}
+
+func TestConstructionSubmit_Queue(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    ethereum.RopstenNetwork,
+		Blockchain: ethereum.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:    configuration.Online,
+		Network: networkIdentifier,
+		Params:  params.RopstenChainConfig,
+	}
+
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	signedTx, err := ethTypes.SignTx(
+		ethTypes.NewTransaction(
+			0,
+			common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d"),
+			big.NewInt(1000),
+			21000,
+			big.NewInt(1),
+			nil,
+		),
+		ethTypes.NewEIP155Signer(params.RopstenChainConfig.ChainID),
+		key,
+	)
+	assert.NoError(t, err)
+	signedRaw, err := signedTx.MarshalJSON()
+	assert.NoError(t, err)
+	request := &types.ConstructionSubmitRequest{
+		NetworkIdentifier: networkIdentifier,
+		SignedTransaction: string(signedRaw),
+	}
+	isSignedTx := mock.MatchedBy(func(tx *ethTypes.Transaction) bool {
+		return tx.Hash() == signedTx.Hash()
+	})
+
+	t.Run("broadcast", func(t *testing.T) {
+		mockClient := &mocks.Client{}
+		mockQueue := &mocks.SubmitQueue{}
+		servicer := NewConstructionAPIService(cfg, mockClient, nil, nil, mockQueue)
+
+		mockQueue.On("Enqueue", isSignedTx).Return(nil).Once()
+		mockClient.On("SendTransaction", mock.Anything, isSignedTx).Return(nil).Once()
+		mockQueue.On("RecordAttempt", signedTx.Hash(), nil).Return(nil).Once()
+		resp, rErr := servicer.ConstructionSubmit(context.Background(), request)
+		assert.Nil(t, rErr)
+		assert.Equal(t, &types.TransactionIdentifierResponse{
+			TransactionIdentifier: &types.TransactionIdentifier{Hash: signedTx.Hash().Hex()},
+		}, resp)
+
+		mockClient.AssertExpectations(t)
+		mockQueue.AssertExpectations(t)
+	})
+
+	t.Run("queued if broadcast fails", func(t *testing.T) {
+		mockClient := &mocks.Client{}
+		mockQueue := &mocks.SubmitQueue{}
+		servicer := NewConstructionAPIService(cfg, mockClient, nil, nil, mockQueue)
+
+		sendErr := errors.New("connection refused")
+		mockQueue.On("Enqueue", isSignedTx).Return(nil).Once()
+		mockClient.On("SendTransaction", mock.Anything, isSignedTx).Return(sendErr).Once()
+		mockQueue.On("RecordAttempt", signedTx.Hash(), sendErr).Return(nil).Once()
+		resp, rErr := servicer.ConstructionSubmit(context.Background(), request)
+		assert.Nil(t, rErr)
+		assert.Equal(t, &types.TransactionIdentifierResponse{
+			TransactionIdentifier: &types.TransactionIdentifier{Hash: signedTx.Hash().Hex()},
+			Metadata:              map[string]interface{}{"queued": true},
+		}, resp)
+
+		mockClient.AssertExpectations(t)
+		mockQueue.AssertExpectations(t)
+	})
+
+	t.Run("removed if rejected", func(t *testing.T) {
+		mockClient := &mocks.Client{}
+		mockQueue := &mocks.SubmitQueue{}
+		servicer := NewConstructionAPIService(cfg, mockClient, nil, nil, mockQueue)
+
+		mockQueue.On("Enqueue", isSignedTx).Return(nil).Once()
+		mockClient.On("SendTransaction", mock.Anything, isSignedTx).Return(errors.New("nonce too low")).Once()
+		mockQueue.On("Remove", signedTx.Hash()).Return(nil).Once()
+		resp, rErr := servicer.ConstructionSubmit(context.Background(), request)
+		assert.Nil(t, resp)
+		assert.Equal(t, ErrNonceTooLow.Code, rErr.Code)
+
+		mockClient.AssertExpectations(t)
+		mockQueue.AssertExpectations(t)
+	})
+
+	t.Run("not broadcast if not queued", func(t *testing.T) {
+		mockClient := &mocks.Client{}
+		mockQueue := &mocks.SubmitQueue{}
+		servicer := NewConstructionAPIService(cfg, mockClient, nil, nil, mockQueue)
+
+		mockQueue.On("Enqueue", isSignedTx).Return(errors.New("disk full")).Once()
+		resp, rErr := servicer.ConstructionSubmit(context.Background(), request)
+		assert.Nil(t, resp)
+		assert.Equal(t, ErrSubmitQueueUnavailable.Code, rErr.Code)
+
+		mockClient.AssertExpectations(t)
+		mockQueue.AssertExpectations(t)
+	})
+}
+
+func TestConstructionParse_ContractCreation(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    ethereum.RopstenNetwork,
+		Blockchain: ethereum.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:    configuration.Offline,
+		Network: networkIdentifier,
+		Params:  params.RopstenChainConfig,
+	}
+	servicer := NewConstructionAPIService(cfg, &mocks.Client{}, nil, nil, nil)
+
+	// A signed transaction without a recipient
+	signedRaw := `{"type":"0x0","nonce":"0x0","gasPrice":"0x3b9aca00","maxPriorityFeePerGas":null,"maxFeePerGas":null,"gas":"0x5208","value":"0x9864aac3510d02","input":"0x","v":"0x2a","r":"0x8c712c64bc65c4a88707fa93ecd090144dffb1bf133805a10a51d354c2f9f2b2","s":"0x5a63cea6989f4c58372c41f31164036a6b25dce1d5c05e1d31c16c0590c176e8","hash":"0x424969b1a98757bcd748c60bad2a7de9745cfb26bfefb4550e780a098feada42"}` // nolint
+	resp, err := servicer.ConstructionParse(context.Background(), &types.ConstructionParseRequest{
+		NetworkIdentifier: networkIdentifier,
+		Signed:            true,
+		Transaction:       signedRaw,
+	})
+	assert.Nil(t, resp)
+	assert.Equal(t, ErrUnableToParseIntermediateResult.Code, err.Code)
+}
+
+func TestConstructionHash(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    ethereum.RopstenNetwork,
+		Blockchain: ethereum.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:    configuration.Offline,
+		Network: networkIdentifier,
+		Params:  params.RopstenChainConfig,
+	}
+	servicer := NewConstructionAPIService(cfg, &mocks.Client{}, nil, nil, nil)
+
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	sign := func(chainID *big.Int) (*ethTypes.Transaction, string) {
+		signedTx, err := ethTypes.SignTx(
+			ethTypes.NewTransaction(
+				0,
+				common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d"),
+				big.NewInt(1000),
+				21000,
+				big.NewInt(1),
+				nil,
+			),
+			ethTypes.NewEIP155Signer(chainID),
+			key,
+		)
+		assert.NoError(t, err)
+		signedRaw, err := signedTx.MarshalJSON()
+		assert.NoError(t, err)
+		return signedTx, string(signedRaw)
+	}
+
+	signedTx, signedRaw := sign(params.RopstenChainConfig.ChainID)
+	_, goerliRaw := sign(params.GoerliChainConfig.ChainID)
+
+	// r is not the x coordinate of a point on the curve,
+	// so no public key can be recovered.
+	var fields map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(signedRaw), &fields))
+	fields["r"] = "0x5"
+	unrecoverableRaw, err := json.Marshal(fields)
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		signedTx string
+		hash     string
+		err      *types.Error
+	}{
+		"valid": {
+			signedTx: signedRaw,
+			hash:     signedTx.Hash().Hex(),
+		},
+		"signed for another network": {
+			signedTx: goerliRaw,
+			err:      ErrChainIDMismatch,
+		},
+		"unrecoverable sender": {
+			signedTx: string(unrecoverableRaw),
+			err:      ErrUnableToRecoverSender,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, err := servicer.ConstructionHash(context.Background(), &types.ConstructionHashRequest{
+				NetworkIdentifier: networkIdentifier,
+				SignedTransaction: test.signedTx,
+			})
+			if test.err != nil {
+				assert.Nil(t, resp)
+				assert.Equal(t, test.err.Code, err.Code)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, test.hash, resp.TransactionIdentifier.Hash)
+			}
+		})
+	}
+}
+
+func TestConstructionSubmit_Errors(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    ethereum.RopstenNetwork,
+		Blockchain: ethereum.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:    configuration.Online,
+		Network: networkIdentifier,
+		Params:  params.RopstenChainConfig,
+	}
+
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	signedTx, err := ethTypes.SignTx(
+		ethTypes.NewTransaction(
+			7,
+			common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d"),
+			big.NewInt(1000),
+			21000,
+			big.NewInt(1),
+			nil,
+		),
+		ethTypes.NewEIP155Signer(params.RopstenChainConfig.ChainID),
+		key,
+	)
+	assert.NoError(t, err)
+	signedRaw, err := signedTx.MarshalJSON()
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		err      error
+		expected *types.Error
+	}{
+		"nonce too low": {
+			err:      errors.New("nonce too low"),
+			expected: ErrNonceTooLow,
+		},
+		"replacement underpriced": {
+			err:      errors.New("replacement transaction underpriced"),
+			expected: ErrReplacementUnderpriced,
+		},
+		"already known": {
+			err:      errors.New("already known"),
+			expected: ErrTransactionAlreadyKnown,
+		},
+		"known transaction (legacy)": {
+			err:      errors.New("known transaction: 0x1234"),
+			expected: ErrTransactionAlreadyKnown,
+		},
+		"insufficient funds": {
+			err:      errors.New("insufficient funds for gas * price + value"),
+			expected: ErrInsufficientFunds,
+		},
+		"txpool full": {
+			err:      errors.New("txpool is full"),
+			expected: ErrTxPoolFull,
+		},
+		"unknown": {
+			err:      errors.New("connection refused"),
+			expected: ErrBroadcastFailed,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockClient := &mocks.Client{}
+			servicer := NewConstructionAPIService(cfg, mockClient, nil, nil, nil)
+
+			mockClient.On("SendTransaction", mock.Anything, mock.Anything).Return(test.err).Once()
+			resp, rErr := servicer.ConstructionSubmit(context.Background(), &types.ConstructionSubmitRequest{
+				NetworkIdentifier: networkIdentifier,
+				SignedTransaction: string(signedRaw),
+			})
+			assert.Nil(t, resp)
+			assert.Equal(t, test.expected.Code, rErr.Code)
+			assert.Equal(t, test.expected.Retriable, rErr.Retriable)
+			assert.Equal(t, map[string]interface{}{
+				"context":          test.err.Error(),
+				"transaction_hash": signedTx.Hash().Hex(),
+				"nonce":            uint64(7),
+				"sender":           crypto.PubkeyToAddress(key.PublicKey).Hex(),
+			}, rErr.Details)
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
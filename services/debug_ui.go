@@ -0,0 +1,171 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"bytes"
+	"context"
+	_ "embed" // used to embed the debug UI page
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/metrics"
+
+	"github.com/coinbase/rosetta-sdk-go/server"
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+// debugStatusTimeout is the maximum amount of time
+// spent fetching the status of the node.
+const debugStatusTimeout = 5 * time.Second
+
+//go:embed debug_ui.html
+var debugPage []byte
+
+// DebugStatus is returned by /admin/debug/status.
+type DebugStatus struct {
+	CurrentBlockIdentifier *types.BlockIdentifier `json:"current_block_identifier,omitempty"`
+	CurrentBlockTimestamp  int64                  `json:"current_block_timestamp,omitempty"`
+	SyncStatus             *types.SyncStatus      `json:"sync_status,omitempty"`
+	Peers                  int                    `json:"peers"`
+	NodeError              string                 `json:"node_error,omitempty"`
+	Upstream               *UpstreamReport        `json:"upstream,omitempty"`
+	Metrics                map[string]interface{} `json:"metrics"`
+}
+
+// DebugUIHandler returns an http.Handler serving a debug UI
+// under /admin/debug/. The page shows the recent blocks as they
+// are converted, the status of the node, the upstream calls made
+// per endpoint (if tracker is not nil), and all metrics (including
+// the cache hit rates). It is backed by:
+//
+//	GET /admin/debug/status        the DebugStatus
+//	GET /admin/debug/block?index=  the /block response served by api
+//	GET /admin/debug/block?hash=
+//
+// Blocks are requested from api like any other client would, so
+// they are validated and cached as configured.
+func DebugUIHandler(
+	cfg *configuration.Configuration,
+	client Client,
+	tracker *UpstreamTracker,
+	api http.Handler,
+) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/debug/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/debug/" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(debugPage)
+	})
+	mux.HandleFunc("/admin/debug/status", func(w http.ResponseWriter, r *http.Request) {
+		server.EncodeJSONResponse(debugStatus(r.Context(), cfg, client, tracker), http.StatusOK, w)
+	})
+	mux.HandleFunc("/admin/debug/block", func(w http.ResponseWriter, r *http.Request) {
+		serveDebugBlock(w, r, cfg, api)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// debugStatus returns the status of the node (in online
+// mode), the upstream report, and a snapshot of all metrics.
+func debugStatus(
+	ctx context.Context,
+	cfg *configuration.Configuration,
+	client Client,
+	tracker *UpstreamTracker,
+) *DebugStatus {
+	status := &DebugStatus{Metrics: metrics.Snapshot()}
+	if tracker != nil {
+		status.Upstream = tracker.Report()
+	}
+
+	if cfg.Mode != configuration.Online {
+		return status
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, debugStatusTimeout)
+	defer cancel()
+
+	current, timestamp, syncStatus, peers, err := client.Status(ctx)
+	if err != nil {
+		status.NodeError = err.Error()
+		return status
+	}
+
+	status.CurrentBlockIdentifier = current
+	status.CurrentBlockTimestamp = timestamp
+	status.SyncStatus = syncStatus
+	status.Peers = len(peers)
+
+	return status
+}
+
+// serveDebugBlock serves the /block response of api for the
+// block identified by the index or hash query parameter.
+func serveDebugBlock(
+	w http.ResponseWriter,
+	r *http.Request,
+	cfg *configuration.Configuration,
+	api http.Handler,
+) {
+	query := r.URL.Query()
+	block := &types.PartialBlockIdentifier{}
+	if hash := query.Get("hash"); len(hash) > 0 {
+		block.Hash = &hash
+	}
+	if index := query.Get("index"); len(index) > 0 {
+		val, err := strconv.ParseInt(index, 10, 64)
+		if err != nil || val < 0 {
+			http.Error(w, "index must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		block.Index = &val
+	}
+
+	body, err := json.Marshal(&types.BlockRequest{
+		NetworkIdentifier: cfg.Network,
+		BlockIdentifier:   block,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	request, err := http.NewRequestWithContext(r.Context(), http.MethodPost, "/block", bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	api.ServeHTTP(w, request)
+}
@@ -0,0 +1,155 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	"github.com/coinbase/rosetta-ethereum/metrics"
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/services"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDebugUIHandler(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode: configuration.Online,
+		Network: &types.NetworkIdentifier{
+			Blockchain: ethereum.Blockchain,
+			Network:    ethereum.MainnetNetwork,
+		},
+	}
+	mockClient := &mocks.Client{}
+
+	var blockRequests []*types.BlockRequest
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/block", r.URL.Path)
+
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		var request types.BlockRequest
+		assert.NoError(t, json.Unmarshal(body, &request))
+		blockRequests = append(blockRequests, &request)
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"block":{}}`))
+	})
+
+	tracker := NewUpstreamTracker()
+	handler := DebugUIHandler(cfg, mockClient, tracker, api)
+
+	// The page is served at /admin/debug/.
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/debug/", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.True(t, strings.Contains(recorder.Body.String(), "rosetta-core debug"))
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/debug/other", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	// Only GET is allowed.
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/admin/debug/status", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+
+	// Blocks are requested from the Rosetta API.
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/debug/block?index=10", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `{"block":{}}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/debug/block?hash=0xabc", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	index := int64(10)
+	hash := "0xabc"
+	assert.Equal(t, []*types.BlockRequest{
+		{
+			NetworkIdentifier: cfg.Network,
+			BlockIdentifier:   &types.PartialBlockIdentifier{Index: &index},
+		},
+		{
+			NetworkIdentifier: cfg.Network,
+			BlockIdentifier:   &types.PartialBlockIdentifier{Hash: &hash},
+		},
+	}, blockRequests)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/debug/block?index=-1", nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Len(t, blockRequests, 2)
+
+	// The status includes the node status and the metrics.
+	metrics.Counter(cacheHitsMetric).Inc(1)
+	mockClient.On("Status", mock.Anything).Return(
+		&types.BlockIdentifier{Index: 10, Hash: "0xabc"},
+		int64(1000),
+		&types.SyncStatus{CurrentIndex: types.Int64(10)},
+		[]*types.Peer{{PeerID: "peer"}},
+		nil,
+	).Once()
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/debug/status", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var status DebugStatus
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	assert.Equal(t, &types.BlockIdentifier{Index: 10, Hash: "0xabc"}, status.CurrentBlockIdentifier)
+	assert.Equal(t, int64(1000), status.CurrentBlockTimestamp)
+	assert.Equal(t, 1, status.Peers)
+	assert.Empty(t, status.NodeError)
+	assert.Equal(t, &UpstreamReport{Endpoints: []*EndpointUpstreamCalls{}}, status.Upstream)
+	assert.Contains(t, status.Metrics, cacheHitsMetric)
+
+	// Node errors are reported rather than failing the request.
+	mockClient.On("Status", mock.Anything).Return(
+		nil,
+		int64(-1),
+		nil,
+		nil,
+		errors.New("connection refused"),
+	).Once()
+	status = DebugStatus{}
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/debug/status", nil))
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	assert.Equal(t, "connection refused", status.NodeError)
+	assert.Nil(t, status.CurrentBlockIdentifier)
+
+	// The node is not queried offline.
+	cfg.Mode = configuration.Offline
+	status = DebugStatus{}
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/debug/status", nil))
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	assert.Nil(t, status.CurrentBlockIdentifier)
+	assert.Empty(t, status.NodeError)
+
+	mockClient.AssertExpectations(t)
+}
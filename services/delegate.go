@@ -0,0 +1,198 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/coinbase/rosetta-ethereum/amount"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+
+	"github.com/coinbase/rosetta-sdk-go/parser"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// delegateIntent is a request to delegate Amount
+// CORE from From to Validator.
+type delegateIntent struct {
+	From      string
+	Validator string
+	Amount    *big.Int
+}
+
+// matchDelegate returns the delegation described by ops. If ops
+// is not a delegation (a single DELEGATE operation), nil is
+// returned so that ops can be matched as a transfer.
+func matchDelegate(ops []*types.Operation) (*delegateIntent, *types.Error) {
+	if len(ops) != 1 || ops[0].Type != ethereum.DelegateOpType {
+		return nil, nil
+	}
+
+	descriptions := &parser.Descriptions{
+		OperationDescriptions: []*parser.OperationDescription{
+			{
+				Type: ethereum.DelegateOpType,
+				Account: &parser.AccountDescription{
+					Exists: true,
+				},
+				Amount: &parser.AmountDescription{
+					Exists:   true,
+					Sign:     parser.NegativeAmountSign,
+					Currency: ethereum.Currency,
+				},
+			},
+		},
+		ErrUnmatched: true,
+	}
+
+	matches, err := parser.MatchOperations(descriptions, ops)
+	if err != nil {
+		return nil, wrapErr(ErrUnclearIntent, err)
+	}
+
+	op, value := matches[0].First()
+	from, ok := ethereum.ChecksumAddress(op.Account.Address)
+	if !ok {
+		return nil, wrapErr(ErrInvalidAddress, fmt.Errorf("%s is not a valid address", op.Account.Address))
+	}
+
+	validator, _ := op.Metadata[ethereum.ValidatorMetadataKey].(string)
+	checkValidator, ok := ethereum.ChecksumAddress(validator)
+	if !ok {
+		return nil, wrapErr(
+			ErrInvalidAddress,
+			fmt.Errorf("%s is not a valid validator address", validator),
+		)
+	}
+
+	return &delegateIntent{
+		From:      from,
+		Validator: checkValidator,
+		Amount:    new(big.Int).Neg(value),
+	}, nil
+}
+
+// minimumDelegation returns the minimum amount of
+// CORE that can be delegated to a validator.
+func (s *ConstructionAPIService) minimumDelegation(ctx context.Context) (*big.Int, error) {
+	resp, err := s.client.Call(ctx, &types.CallRequest{
+		Method: "eth_call",
+		Parameters: map[string]interface{}{
+			"to":   ethereum.PledgeAgentContract.Hex(),
+			"data": hexutil.Encode(ethereum.RequiredCoinDepositData()),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := resp.Result["data"].(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected requiredCoinDeposit output %v", resp.Result["data"])
+	}
+
+	output, err := hexutil.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return ethereum.ParseRequiredCoinDeposit(output)
+}
+
+// delegateOperations returns the operations of
+// a delegation parsed by /construction/parse.
+func delegateOperations(from string, validator common.Address, value *big.Int) []*types.Operation {
+	return []*types.Operation{
+		{
+			Type: ethereum.DelegateOpType,
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 0,
+			},
+			Account: &types.AccountIdentifier{
+				Address: from,
+			},
+			Amount: amount.NewNeg(value, ethereum.Currency),
+			Metadata: map[string]interface{}{
+				ethereum.ValidatorMetadataKey: validator.Hex(),
+			},
+		},
+	}
+}
+
+// checkDelegation ensures the delegation described by input
+// is at least the minimum accepted by PledgeAgent.
+func (s *ConstructionAPIService) checkDelegation(
+	ctx context.Context,
+	input *options,
+) *types.Error {
+	value, err := amount.ParseUnsigned(input.Value)
+	if err != nil {
+		return wrapErr(
+			ErrUnableToParseIntermediateResult,
+			fmt.Errorf("%w: %s is not a valid delegation amount", err, input.Value),
+		)
+	}
+
+	minimum, err := s.minimumDelegation(ctx)
+	if err != nil {
+		return wrapErr(ErrGeth, err)
+	}
+
+	if value.Cmp(minimum) < 0 {
+		return wrapErr(
+			ErrDelegationBelowMinimum,
+			fmt.Errorf("%s is below the minimum delegation of %s", value.String(), minimum.String()),
+		)
+	}
+
+	return nil
+}
+
+// delegatePayloads builds the PledgeAgent
+// delegateCoin call described by intent.
+func (s *ConstructionAPIService) delegatePayloads(
+	request *types.ConstructionPayloadsRequest,
+	intent *delegateIntent,
+) (*types.ConstructionPayloadsResponse, *types.Error) {
+	var metadata metadata
+	if err := unmarshalJSONMap(request.Metadata, &metadata); err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	data, err := ethereum.DelegateCoinData(common.HexToAddress(intent.Validator))
+	if err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	gasLimit := uint64(ethereum.DelegateGasLimit)
+	if metadata.GasLimit > 0 {
+		gasLimit = metadata.GasLimit
+	}
+
+	return s.payloads(&transaction{
+		From:     intent.From,
+		To:       ethereum.PledgeAgentContract.Hex(),
+		Value:    intent.Amount,
+		Data:     data,
+		Nonce:    metadata.Nonce,
+		GasPrice: metadata.GasPrice,
+		GasLimit: gasLimit,
+		ChainID:  s.config.Params.ChainID,
+	})
+}
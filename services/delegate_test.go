@@ -0,0 +1,164 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/services"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestConstructionService_Delegate(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    ethereum.RopstenNetwork,
+		Blockchain: ethereum.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:    configuration.Online,
+		Network: networkIdentifier,
+		Params:  params.RopstenChainConfig,
+	}
+
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, nil, nil, nil)
+	ctx := context.Background()
+
+	from := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	validator := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	ops := func(value string) []*types.Operation {
+		return []*types.Operation{
+			{
+				OperationIdentifier: &types.OperationIdentifier{Index: 0},
+				Type:                ethereum.DelegateOpType,
+				Account:             &types.AccountIdentifier{Address: from.Hex()},
+				Amount:              &types.Amount{Value: value, Currency: ethereum.Currency},
+				Metadata: map[string]interface{}{
+					ethereum.ValidatorMetadataKey: validator.Hex(),
+				},
+			},
+		}
+	}
+	minimum := hexutil.Encode(math.U256Bytes(big.NewInt(1000)))
+	isRequiredCoinDeposit := mock.MatchedBy(func(request *types.CallRequest) bool {
+		return request.Method == "eth_call" &&
+			request.Parameters["to"] == ethereum.PledgeAgentContract.Hex() &&
+			request.Parameters["data"] == hexutil.Encode(ethereum.RequiredCoinDepositData())
+	})
+
+	// The validator must be a valid address
+	invalid := ops("-1000")
+	invalid[0].Metadata = nil
+	preprocessResponse, err := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        invalid,
+	})
+	assert.Nil(t, preprocessResponse)
+	assert.Equal(t, ErrInvalidAddress.Code, err.Code)
+
+	preprocessResponse, err = servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops("-1000"),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"from":      from.Hex(),
+		"validator": validator.Hex(),
+		"value":     "1000",
+	}, preprocessResponse.Options)
+
+	mockClient.On("PendingNonceAt", ctx, from).Return(uint64(0), nil).Once()
+	mockClient.On("SuggestGasPrice", ctx).Return(big.NewInt(1000000000), nil).Once()
+	mockClient.On("Call", ctx, isRequiredCoinDeposit).Return(
+		&types.CallResponse{Result: map[string]interface{}{"data": minimum}},
+		nil,
+	).Once()
+	metadataResponse, err := servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           preprocessResponse.Options,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"nonce":     "0x0",
+		"gas_price": "0x3b9aca00",
+		"gas_limit": "0x493e0",
+	}, metadataResponse.Metadata)
+	assert.Equal(t, "300000000000000", metadataResponse.SuggestedFee[0].Value)
+
+	payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops("-1000"),
+		Metadata:          metadataResponse.Metadata,
+	})
+	assert.Nil(t, err)
+
+	data, dataErr := ethereum.DelegateCoinData(validator)
+	assert.NoError(t, dataErr)
+	var unsignedTx transaction
+	assert.NoError(t, json.Unmarshal([]byte(payloadsResponse.UnsignedTransaction), &unsignedTx))
+	assert.Equal(t, &transaction{
+		From:     from.Hex(),
+		To:       ethereum.PledgeAgentContract.Hex(),
+		Value:    big.NewInt(1000),
+		Data:     data,
+		Nonce:    0,
+		GasPrice: big.NewInt(1000000000),
+		GasLimit: uint64(ethereum.DelegateGasLimit),
+		ChainID:  params.RopstenChainConfig.ChainID,
+	}, &unsignedTx)
+
+	// The delegation round trips through /construction/parse
+	parseResponse, err := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: networkIdentifier,
+		Signed:            false,
+		Transaction:       payloadsResponse.UnsignedTransaction,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, ops("-1000"), parseResponse.Operations)
+
+	// Delegations below the minimum are rejected
+	preprocessResponse, err = servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops("-999"),
+	})
+	assert.Nil(t, err)
+
+	mockClient.On("SuggestGasPrice", ctx).Return(big.NewInt(1000000000), nil).Once()
+	mockClient.On("Call", ctx, isRequiredCoinDeposit).Return(
+		&types.CallResponse{Result: map[string]interface{}{"data": minimum}},
+		nil,
+	).Once()
+	metadataResponse, err = servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           preprocessResponse.Options,
+	})
+	assert.Nil(t, metadataResponse)
+	assert.Equal(t, ErrDelegationBelowMinimum.Code, err.Code)
+
+	mockClient.AssertExpectations(t)
+}
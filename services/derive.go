@@ -0,0 +1,357 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// create2Wallet is the /construction/derive metadata of a
+// counterfactual contract wallet (i.e. an ERC-4337 smart
+// account), deployed by Factory with CREATE2. The init code
+// of the wallet is provided either as is or hashed.
+type create2Wallet struct {
+	Factory      string `json:"factory"`
+	Salt         string `json:"salt"`
+	InitCode     string `json:"init_code,omitempty"`
+	InitCodeHash string `json:"init_code_hash,omitempty"`
+}
+
+// deriveCreate2Wallet returns the address the wallet described by
+// metadata is (or will be) deployed at, and the response metadata
+// linking it to owner. The address does not depend on owner: the
+// factory is expected to commit to the owner in the salt or in
+// the init code.
+func deriveCreate2Wallet(
+	owner common.Address,
+	metadata map[string]interface{},
+) (common.Address, map[string]interface{}, *types.Error) {
+	var wallet create2Wallet
+	if err := types.UnmarshalMap(metadata, &wallet); err != nil {
+		return common.Address{}, nil, wrapErr(ErrInvalidInput, err)
+	}
+
+	factory, ok := ethereum.ChecksumAddress(wallet.Factory)
+	if !ok {
+		return common.Address{}, nil, wrapErr(
+			ErrInvalidAddress,
+			fmt.Errorf("%s is not a valid factory address", wallet.Factory),
+		)
+	}
+
+	salt, err := hexutil.Decode(wallet.Salt)
+	if err != nil || len(salt) != common.HashLength {
+		return common.Address{}, nil, wrapErr(
+			ErrInvalidInput,
+			fmt.Errorf("salt %s is not a 32-byte hex string", wallet.Salt),
+		)
+	}
+
+	var initCodeHash []byte
+	switch {
+	case len(wallet.InitCode) > 0 && len(wallet.InitCodeHash) > 0:
+		return common.Address{}, nil, wrapErr(
+			ErrInvalidInput,
+			errors.New("only one of init_code and init_code_hash can be populated"),
+		)
+	case len(wallet.InitCode) > 0:
+		initCode, err := hexutil.Decode(wallet.InitCode)
+		if err != nil || len(initCode) == 0 {
+			return common.Address{}, nil, wrapErr(
+				ErrInvalidInput,
+				fmt.Errorf("init_code %s is not a hex string", wallet.InitCode),
+			)
+		}
+		initCodeHash = crypto.Keccak256(initCode)
+	case len(wallet.InitCodeHash) > 0:
+		initCodeHash, err = hexutil.Decode(wallet.InitCodeHash)
+		if err != nil || len(initCodeHash) != common.HashLength {
+			return common.Address{}, nil, wrapErr(
+				ErrInvalidInput,
+				fmt.Errorf("init_code_hash %s is not a 32-byte hex string", wallet.InitCodeHash),
+			)
+		}
+	default:
+		return common.Address{}, nil, wrapErr(
+			ErrInvalidInput,
+			errors.New("one of init_code and init_code_hash must be populated"),
+		)
+	}
+
+	address := crypto.CreateAddress2(common.HexToAddress(factory), common.BytesToHash(salt), initCodeHash)
+	return address, map[string]interface{}{
+		"owner":          owner.Hex(),
+		"factory":        factory,
+		"salt":           hexutil.Encode(salt),
+		"init_code_hash": hexutil.Encode(initCodeHash),
+	}, nil
+}
+
+const (
+	// extendedKeyLength is the length of a serialized BIP-32
+	// extended key, without its checksum.
+	extendedKeyLength = 78
+
+	// hardenedKeyStart is the index of the first
+	// hardened child of a BIP-32 extended key.
+	hardenedKeyStart = 1 << 31
+)
+
+var (
+	// extendedPublicKeyVersions are the versions of the
+	// extended public keys accepted in /construction/derive
+	// (xpub and tpub).
+	extendedPublicKeyVersions = [][]byte{
+		{0x04, 0x88, 0xb2, 0x1e},
+		{0x04, 0x35, 0x87, 0xcf},
+	}
+
+	// extendedPrivateKeyVersions are the versions of extended
+	// private keys (xprv and tprv), which are rejected.
+	extendedPrivateKeyVersions = [][]byte{
+		{0x04, 0x88, 0xad, 0xe4},
+		{0x04, 0x35, 0x83, 0x94},
+	}
+
+	// base58Alphabet is the alphabet of the Base58 encoding
+	// of extended keys.
+	base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+)
+
+// hdAccount is the /construction/derive metadata of a child of
+// an extended public key: Path is relative to XPub and only has
+// non-hardened indices (i.e. m/0/5 or 0/5).
+type hdAccount struct {
+	XPub string `json:"xpub"`
+	Path string `json:"path"`
+}
+
+// extendedPublicKey is a parsed BIP-32 extended public key.
+type extendedPublicKey struct {
+	key       *ecdsa.PublicKey
+	chainCode []byte
+	depth     uint8
+}
+
+// deriveHDAccount returns the address of the child of the extended
+// public key in metadata at its path, and the response metadata
+// describing the child. publicKey must be the key of the extended
+// public key.
+func deriveHDAccount(
+	publicKey *ecdsa.PublicKey,
+	metadata map[string]interface{},
+) (common.Address, map[string]interface{}, *types.Error) {
+	var account hdAccount
+	if err := types.UnmarshalMap(metadata, &account); err != nil {
+		return common.Address{}, nil, wrapErr(ErrInvalidInput, err)
+	}
+
+	xpub, err := parseExtendedPublicKey(account.XPub)
+	if err != nil {
+		return common.Address{}, nil, wrapErr(ErrInvalidInput, err)
+	}
+	if !xpub.key.Equal(publicKey) {
+		return common.Address{}, nil, wrapErr(
+			ErrInvalidInput,
+			errors.New("public key is not the key of xpub"),
+		)
+	}
+
+	path, err := parseDerivationPath(account.Path)
+	if err != nil {
+		return common.Address{}, nil, wrapErr(ErrInvalidInput, err)
+	}
+	if int(xpub.depth)+len(path) > math.MaxUint8 {
+		return common.Address{}, nil, wrapErr(
+			ErrInvalidInput,
+			fmt.Errorf("path %s is too deep", account.Path),
+		)
+	}
+
+	child := xpub
+	for _, index := range path {
+		child, err = child.derive(index)
+		if err != nil {
+			return common.Address{}, nil, wrapErr(ErrInvalidInput, err)
+		}
+	}
+
+	return crypto.PubkeyToAddress(*child.key), map[string]interface{}{
+		"xpub":       account.XPub,
+		"path":       formatDerivationPath(path),
+		"public_key": hexutil.Encode(crypto.CompressPubkey(child.key)),
+	}, nil
+}
+
+// parseExtendedPublicKey parses a Base58Check encoded
+// extended public key.
+func parseExtendedPublicKey(encoded string) (*extendedPublicKey, error) {
+	decoded, err := base58CheckDecode(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to decode xpub", err)
+	}
+	if len(decoded) != extendedKeyLength {
+		return nil, fmt.Errorf("xpub is %d bytes long instead of %d", len(decoded), extendedKeyLength)
+	}
+
+	version := decoded[:4]
+	for _, private := range extendedPrivateKeyVersions {
+		if bytes.Equal(version, private) {
+			return nil, errors.New("extended private keys are not accepted")
+		}
+	}
+	supported := false
+	for _, public := range extendedPublicKeyVersions {
+		supported = supported || bytes.Equal(version, public)
+	}
+	if !supported {
+		return nil, fmt.Errorf("xpub version %x is not supported", version)
+	}
+
+	key, err := crypto.DecompressPubkey(decoded[45:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to decompress xpub key", err)
+	}
+
+	return &extendedPublicKey{
+		key:       key,
+		chainCode: decoded[13:45],
+		depth:     decoded[4],
+	}, nil
+}
+
+// derive returns the non-hardened child of k at index
+// (CKDpub in BIP-32).
+func (k *extendedPublicKey) derive(index uint32) (*extendedPublicKey, error) {
+	if index >= hardenedKeyStart {
+		return nil, fmt.Errorf("hardened child %d cannot be derived from xpub", index-hardenedKeyStart)
+	}
+
+	data := make([]byte, 0, 37)
+	data = append(data, crypto.CompressPubkey(k.key)...)
+	data = append(data, byte(index>>24), byte(index>>16), byte(index>>8), byte(index))
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	// Indices yielding an invalid key are skipped by wallets,
+	// which is up to the client.
+	curve := crypto.S256()
+	tweak := new(big.Int).SetBytes(sum[:32])
+	if tweak.Cmp(curve.Params().N) >= 0 {
+		return nil, fmt.Errorf("child %d is not a valid key", index)
+	}
+	tweakX, tweakY := curve.ScalarBaseMult(sum[:32])
+	x, y := curve.Add(tweakX, tweakY, k.key.X, k.key.Y)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, fmt.Errorf("child %d is not a valid key", index)
+	}
+
+	return &extendedPublicKey{
+		key:       &ecdsa.PublicKey{Curve: curve, X: x, Y: y},
+		chainCode: sum[32:],
+		depth:     k.depth + 1,
+	}, nil
+}
+
+// parseDerivationPath parses a path of non-hardened indices
+// relative to an extended public key, with or without the
+// leading m (i.e. m/0/5 or 0/5).
+func parseDerivationPath(path string) ([]uint32, error) {
+	components := strings.Split(strings.TrimSpace(path), "/")
+	if components[0] == "m" {
+		components = components[1:]
+	}
+	if len(components) == 0 {
+		return nil, fmt.Errorf("path %s is empty", path)
+	}
+
+	indices := make([]uint32, len(components))
+	for i, component := range components {
+		if strings.HasSuffix(component, "'") || strings.HasSuffix(component, "h") {
+			return nil, fmt.Errorf("path %s has hardened indices, which cannot be derived from xpub", path)
+		}
+
+		index, err := strconv.ParseUint(component, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse path %s", err, path)
+		}
+		indices[i] = uint32(index)
+	}
+
+	return indices, nil
+}
+
+// formatDerivationPath formats a path relative
+// to an extended public key.
+func formatDerivationPath(path []uint32) string {
+	components := []string{"m"}
+	for _, index := range path {
+		components = append(components, strconv.FormatUint(uint64(index), 10))
+	}
+
+	return strings.Join(components, "/")
+}
+
+// base58CheckDecode decodes a Base58 string and
+// verifies and strips its 4-byte checksum.
+func base58CheckDecode(encoded string) ([]byte, error) {
+	value := new(big.Int)
+	radix := big.NewInt(int64(len(base58Alphabet)))
+	for _, c := range encoded {
+		digit := strings.IndexRune(base58Alphabet, c)
+		if digit < 0 {
+			return nil, fmt.Errorf("%q is not a Base58 character", c)
+		}
+		value.Mul(value, radix)
+		value.Add(value, big.NewInt(int64(digit)))
+	}
+
+	// Leading zeros are encoded as leading 1s.
+	zeros := 0
+	for zeros < len(encoded) && encoded[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	decoded := append(make([]byte, zeros), value.Bytes()...)
+	if len(decoded) < 4 {
+		return nil, errors.New("checksum is missing")
+	}
+
+	payload, checksum := decoded[:len(decoded)-4], decoded[len(decoded)-4:]
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	if !bytes.Equal(checksum, second[:4]) {
+		return nil, errors.New("checksum is invalid")
+	}
+
+	return payload, nil
+}
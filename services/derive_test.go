@@ -0,0 +1,296 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/services"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstructionDerive_Create2(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode: configuration.Offline,
+	}
+	servicer := NewConstructionAPIService(cfg, &mocks.Client{}, nil, nil, nil)
+	ctx := context.Background()
+
+	publicKey := &types.PublicKey{
+		Bytes: forceHexDecode(
+			t,
+			"03d3d3358e7f69cbe45bde38d7d6f24660c7eeeaee5c5590cfab985c8839b21fd5",
+		),
+		CurveType: types.Secp256k1,
+	}
+	owner := "0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"
+	salt := "0x0000000000000000000000000000000000000000000000000000000000000000"
+	initCodeHash := "0xbc36789e7a1e281436464229828f817d6612f7b477d66591ff96a9e064bcc98a"
+
+	// Example 2 of EIP-1014 (init code 0x00).
+	tests := map[string]struct {
+		metadata map[string]interface{}
+		address  string
+		err      *types.Error
+	}{
+		"init code": {
+			metadata: map[string]interface{}{
+				"factory":   "0xdeadbeef00000000000000000000000000000000",
+				"salt":      salt,
+				"init_code": "0x00",
+			},
+			address: "0xB928f69Bb1D91Cd65274e3c79d8986362984fDA3",
+		},
+		"init code hash": {
+			metadata: map[string]interface{}{
+				"factory":        "0xdeadbeef00000000000000000000000000000000",
+				"salt":           salt,
+				"init_code_hash": initCodeHash,
+			},
+			address: "0xB928f69Bb1D91Cd65274e3c79d8986362984fDA3",
+		},
+		"invalid factory": {
+			metadata: map[string]interface{}{
+				"factory":   "0xdeadbeef",
+				"salt":      salt,
+				"init_code": "0x00",
+			},
+			err: ErrInvalidAddress,
+		},
+		"short salt": {
+			metadata: map[string]interface{}{
+				"factory":   "0xdeadbeef00000000000000000000000000000000",
+				"salt":      "0x01",
+				"init_code": "0x00",
+			},
+			err: ErrInvalidInput,
+		},
+		"missing init code": {
+			metadata: map[string]interface{}{
+				"factory": "0xdeadbeef00000000000000000000000000000000",
+				"salt":    salt,
+			},
+			err: ErrInvalidInput,
+		},
+		"init code and hash": {
+			metadata: map[string]interface{}{
+				"factory":        "0xdeadbeef00000000000000000000000000000000",
+				"salt":           salt,
+				"init_code":      "0x00",
+				"init_code_hash": initCodeHash,
+			},
+			err: ErrInvalidInput,
+		},
+		"invalid init code hash": {
+			metadata: map[string]interface{}{
+				"factory":        "0xdeadbeef00000000000000000000000000000000",
+				"salt":           salt,
+				"init_code_hash": "0x1234",
+			},
+			err: ErrInvalidInput,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			response, err := servicer.ConstructionDerive(ctx, &types.ConstructionDeriveRequest{
+				NetworkIdentifier: networkIdentifier,
+				PublicKey:         publicKey,
+				Metadata:          test.metadata,
+			})
+			if test.err != nil {
+				assert.Nil(t, response)
+				assert.Equal(t, test.err.Code, err.Code)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, &types.ConstructionDeriveResponse{
+				AccountIdentifier: &types.AccountIdentifier{
+					Address: test.address,
+				},
+				Metadata: map[string]interface{}{
+					"owner":          owner,
+					"factory":        "0xdEADBEeF00000000000000000000000000000000",
+					"salt":           salt,
+					"init_code_hash": initCodeHash,
+				},
+			}, response)
+		})
+	}
+}
+
+func TestConstructionDerive_Metadata(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode: configuration.Offline,
+	}
+	servicer := NewConstructionAPIService(cfg, &mocks.Client{}, nil, nil, nil)
+
+	// Metadata without a factory (i.e. added by
+	// clients for their own use) does not describe
+	// a wallet, so the address of the key is derived.
+	response, err := servicer.ConstructionDerive(context.Background(), &types.ConstructionDeriveRequest{
+		NetworkIdentifier: networkIdentifier,
+		PublicKey: &types.PublicKey{
+			Bytes: forceHexDecode(
+				t,
+				"03d3d3358e7f69cbe45bde38d7d6f24660c7eeeaee5c5590cfab985c8839b21fd5",
+			),
+			CurveType: types.Secp256k1,
+		},
+		Metadata: map[string]interface{}{
+			"salt":  "0x0000000000000000000000000000000000000000000000000000000000000000",
+			"label": "hot wallet",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, &types.ConstructionDeriveResponse{
+		AccountIdentifier: &types.AccountIdentifier{
+			Address: "0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309",
+		},
+	}, response)
+}
+
+func TestConstructionDerive_HD(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode:         configuration.Offline,
+		HDDerivation: true,
+	}
+	servicer := NewConstructionAPIService(cfg, &mocks.Client{}, nil, nil, nil)
+	ctx := context.Background()
+
+	// Test vectors 1 and 2 of BIP-32.
+	const (
+		vector1 = "xpub6D4BDPcP2GT577Vvch3R8wDkScZWzQzMMUm3PWbmWvVJrZwQY4VUNgqFJPMM3No2dFDFGTsxxpG5uJh7n7epu4trkrX7x7DogT5Uv6fcLW5"
+		child1  = "xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy"
+		vector2 = "xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUapSCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduB"
+		child2  = "xpub69H7F5d8KSRgmmdJg2KhpAK8SR3DjMwAdkxj3ZuxV27CprR9LgpeyGmXUbC6wb7ERfvrnKZjXoUmmDznezpbZb7ap6r1D3tgFxHmwMkQTPH"
+		xprv    = "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LJbkUxdyJbrCZJx8AHfxNSedBcSkGEHGVe"
+	)
+	publicKey := func(xpub string) *types.PublicKey {
+		key, err := parseExtendedPublicKey(xpub)
+		assert.NoError(t, err)
+		return &types.PublicKey{
+			Bytes:     crypto.CompressPubkey(key.key),
+			CurveType: types.Secp256k1,
+		}
+	}
+	child := func(xpub string) *ecdsa.PublicKey {
+		key, err := parseExtendedPublicKey(xpub)
+		assert.NoError(t, err)
+		return key.key
+	}
+
+	tests := map[string]struct {
+		publicKey *types.PublicKey
+		metadata  map[string]interface{}
+		child     *ecdsa.PublicKey
+		path      string
+		err       *types.Error
+	}{
+		"multiple indices": {
+			publicKey: publicKey(vector1),
+			metadata:  map[string]interface{}{"xpub": vector1, "path": "2/1000000000"},
+			child:     child(child1),
+			path:      "m/2/1000000000",
+		},
+		"single index": {
+			publicKey: publicKey(vector2),
+			metadata:  map[string]interface{}{"xpub": vector2, "path": "m/0"},
+			child:     child(child2),
+			path:      "m/0",
+		},
+		"hardened index": {
+			publicKey: publicKey(vector2),
+			metadata:  map[string]interface{}{"xpub": vector2, "path": "m/0'"},
+			err:       ErrInvalidInput,
+		},
+		"index out of range": {
+			publicKey: publicKey(vector2),
+			metadata:  map[string]interface{}{"xpub": vector2, "path": "m/2147483648"},
+			err:       ErrInvalidInput,
+		},
+		"empty path": {
+			publicKey: publicKey(vector2),
+			metadata:  map[string]interface{}{"xpub": vector2, "path": "m"},
+			err:       ErrInvalidInput,
+		},
+		"other key": {
+			publicKey: publicKey(vector1),
+			metadata:  map[string]interface{}{"xpub": vector2, "path": "m/0"},
+			err:       ErrInvalidInput,
+		},
+		"invalid checksum": {
+			publicKey: publicKey(vector2),
+			metadata:  map[string]interface{}{"xpub": vector2[:len(vector2)-1] + "C", "path": "m/0"},
+			err:       ErrInvalidInput,
+		},
+		"extended private key": {
+			publicKey: publicKey(vector2),
+			metadata:  map[string]interface{}{"xpub": xprv, "path": "m/0"},
+			err:       ErrInvalidInput,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			response, err := servicer.ConstructionDerive(ctx, &types.ConstructionDeriveRequest{
+				NetworkIdentifier: networkIdentifier,
+				PublicKey:         test.publicKey,
+				Metadata:          test.metadata,
+			})
+			if test.err != nil {
+				assert.Nil(t, response)
+				assert.Equal(t, test.err.Code, err.Code)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, &types.ConstructionDeriveResponse{
+				AccountIdentifier: &types.AccountIdentifier{
+					Address: crypto.PubkeyToAddress(*test.child).Hex(),
+				},
+				Metadata: map[string]interface{}{
+					"xpub":       test.metadata["xpub"],
+					"path":       test.path,
+					"public_key": hexutil.Encode(crypto.CompressPubkey(test.child)),
+				},
+			}, response)
+		})
+	}
+
+	// HD derivation is disabled by default.
+	servicer = NewConstructionAPIService(
+		&configuration.Configuration{Mode: configuration.Offline},
+		&mocks.Client{},
+		nil,
+		nil,
+		nil,
+	)
+	response, err := servicer.ConstructionDerive(ctx, &types.ConstructionDeriveRequest{
+		NetworkIdentifier: networkIdentifier,
+		PublicKey:         publicKey(vector2),
+		Metadata:          map[string]interface{}{"xpub": vector2, "path": "m/0"},
+	})
+	assert.Nil(t, response)
+	assert.Equal(t, ErrInvalidInput.Code, err.Code)
+}
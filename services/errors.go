@@ -15,6 +15,8 @@
 package services
 
 import (
+	"github.com/coinbase/rosetta-ethereum/redact"
+
 	"github.com/coinbase/rosetta-sdk-go/types"
 )
 
@@ -37,6 +39,37 @@ var (
 		ErrInvalidAddress,
 		ErrGethNotReady,
 		ErrInvalidInput,
+		ErrResponseInvalid,
+		ErrNonceAllocationFailed,
+		ErrIndexUnavailable,
+		ErrGasLimitTooLow,
+		ErrReloadFailed,
+		ErrSigningPayloadMismatch,
+		ErrSignerMismatch,
+		ErrNodeLagging,
+		ErrBlockPoisoned,
+		ErrInvariantViolated,
+		ErrDelegationBelowMinimum,
+		ErrAuditLogUnavailable,
+		ErrChainIDMismatch,
+		ErrUnableToRecoverSender,
+		ErrEndpointDisabled,
+		ErrNonceTooLow,
+		ErrReplacementUnderpriced,
+		ErrTransactionAlreadyKnown,
+		ErrInsufficientFunds,
+		ErrTxPoolFull,
+		ErrChainMismatch,
+		ErrRequestTooLarge,
+		ErrCancelledTransactionMined,
+		ErrMaintenance,
+		ErrGenesisMismatch,
+		ErrEventsUnavailable,
+		ErrSubmitQueueUnavailable,
+		ErrRateLimited,
+		ErrAccessDenied,
+		ErrChainUnverified,
+		ErrGasLimitCapExceeded,
 	}
 
 	// ErrUnimplemented is returned when an endpoint
@@ -152,6 +185,250 @@ var (
 		Code:    14, //nolint
 		Message: "invalid input",
 	}
+
+	// ErrResponseInvalid is returned in strict validation
+	// mode when a response does not conform to the Rosetta
+	// specification.
+	ErrResponseInvalid = &types.Error{
+		Code:    15, //nolint
+		Message: "Response failed validation",
+	}
+
+	// ErrNonceAllocationFailed is returned when the
+	// nonce tracker cannot allocate a nonce.
+	ErrNonceAllocationFailed = &types.Error{
+		Code:      16, //nolint
+		Message:   "Unable to allocate nonce",
+		Retriable: true,
+	}
+
+	// ErrIndexUnavailable is returned when the local
+	// index cannot serve a query (i.e. it is still
+	// indexing its first blocks).
+	ErrIndexUnavailable = &types.Error{
+		Code:      17, //nolint
+		Message:   "Index unavailable",
+		Retriable: true,
+	}
+
+	// ErrGasLimitTooLow is returned when the gas limit
+	// of a constructed transaction does not cover its
+	// intrinsic gas.
+	ErrGasLimitTooLow = &types.Error{
+		Code:    18, //nolint
+		Message: "Gas limit below intrinsic gas",
+	}
+
+	// ErrReloadFailed is returned when the configuration
+	// cannot be reloaded. The previous configuration
+	// remains in use.
+	ErrReloadFailed = &types.Error{
+		Code:    19, //nolint
+		Message: "Unable to reload configuration",
+	}
+
+	// ErrSigningPayloadMismatch is returned when a signature
+	// provided to /construction/combine was not created for
+	// the signing payload of the unsigned transaction.
+	ErrSigningPayloadMismatch = &types.Error{
+		Code:    20, //nolint
+		Message: "Signing payload does not match unsigned transaction",
+	}
+
+	// ErrSignerMismatch is returned when the sender recovered
+	// from a signature is not the sender of the unsigned
+	// transaction.
+	ErrSignerMismatch = &types.Error{
+		Code:    21, //nolint
+		Message: "Signer does not match transaction sender",
+	}
+
+	// ErrNodeLagging is returned when a query relative to
+	// the tip is rejected because the node is behind the
+	// reference nodes.
+	ErrNodeLagging = &types.Error{
+		Code:      22, //nolint
+		Message:   "Node is lagging behind reference nodes",
+		Retriable: true,
+	}
+
+	// ErrBlockPoisoned is returned when a block has repeatedly
+	// failed to be converted and is temporarily not fetched
+	// from the node.
+	ErrBlockPoisoned = &types.Error{
+		Code:      23, //nolint
+		Message:   "Block repeatedly failed to be fetched",
+		Retriable: true,
+	}
+
+	// ErrInvariantViolated is returned when the operations
+	// of a block do not balance. This indicates a bug in
+	// block conversion.
+	ErrInvariantViolated = &types.Error{
+		Code:    24, //nolint
+		Message: "Block operations do not balance",
+	}
+
+	// ErrDelegationBelowMinimum is returned when a delegation
+	// is below the minimum amount accepted by PledgeAgent.
+	ErrDelegationBelowMinimum = &types.Error{
+		Code:    25, //nolint
+		Message: "Delegation is below the minimum amount",
+	}
+
+	// ErrAuditLogUnavailable is returned when a submitted
+	// transaction cannot be recorded in the audit log. The
+	// transaction is not broadcast.
+	ErrAuditLogUnavailable = &types.Error{
+		Code:    26, //nolint
+		Message: "Unable to record transaction in audit log",
+	}
+
+	// ErrChainIDMismatch is returned when a signed transaction
+	// is signed for a different chain ID than the network.
+	ErrChainIDMismatch = &types.Error{
+		Code:    27, //nolint
+		Message: "Transaction chain ID does not match network",
+	}
+
+	// ErrUnableToRecoverSender is returned when the sender
+	// of a signed transaction cannot be recovered from its
+	// signature.
+	ErrUnableToRecoverSender = &types.Error{
+		Code:    28, //nolint
+		Message: "Unable to recover sender from signature",
+	}
+
+	// ErrEndpointDisabled is returned when an endpoint
+	// of a disabled module is called.
+	ErrEndpointDisabled = &types.Error{
+		Code:    29, //nolint
+		Message: "Endpoint disabled",
+	}
+
+	// ErrNonceTooLow is returned when a transaction is
+	// broadcast with a nonce that was already used.
+	ErrNonceTooLow = &types.Error{
+		Code:    30, //nolint
+		Message: "Nonce too low",
+	}
+
+	// ErrReplacementUnderpriced is returned when a transaction
+	// replacing a pending transaction does not pay enough more
+	// than the transaction it replaces.
+	ErrReplacementUnderpriced = &types.Error{
+		Code:    31, //nolint
+		Message: "Replacement transaction underpriced",
+	}
+
+	// ErrTransactionAlreadyKnown is returned when a transaction
+	// is already in the mempool of the node.
+	ErrTransactionAlreadyKnown = &types.Error{
+		Code:    32, //nolint
+		Message: "Transaction already known",
+	}
+
+	// ErrInsufficientFunds is returned when the sender of a
+	// transaction cannot pay for its value and fee.
+	ErrInsufficientFunds = &types.Error{
+		Code:    33, //nolint
+		Message: "Insufficient funds for gas * price + value",
+	}
+
+	// ErrTxPoolFull is returned when the mempool of the
+	// node is full.
+	ErrTxPoolFull = &types.Error{
+		Code:      34, //nolint
+		Message:   "Transaction pool is full",
+		Retriable: true,
+	}
+
+	// ErrChainMismatch is returned by all endpoints when
+	// the node is on a different chain than the configured
+	// network and CHAIN_MISMATCH_ACTION is QUARANTINE.
+	ErrChainMismatch = &types.Error{
+		Code:    35, //nolint
+		Message: "Node is on a different chain than configured",
+	}
+
+	// ErrRequestTooLarge is returned when the body of a
+	// request exceeds the configured maximum size.
+	ErrRequestTooLarge = &types.Error{
+		Code:    36, //nolint
+		Message: "Request body is too large",
+	}
+
+	// ErrCancelledTransactionMined is returned by
+	// /construction/metadata when the transaction
+	// to cancel is already mined.
+	ErrCancelledTransactionMined = &types.Error{
+		Code:    37, //nolint
+		Message: "Transaction to cancel is already mined",
+	}
+
+	// ErrMaintenance is returned for every request
+	// while rosetta-core is in maintenance mode.
+	ErrMaintenance = &types.Error{
+		Code:      38, //nolint
+		Message:   "Service is in maintenance mode",
+		Retriable: true,
+	}
+
+	// ErrGenesisMismatch is returned when an unsigned
+	// transaction is constructed for a network with a
+	// different genesis block.
+	ErrGenesisMismatch = &types.Error{
+		Code:    39, //nolint
+		Message: "Transaction genesis hash does not match network",
+	}
+
+	// ErrEventsUnavailable is returned by /events/blocks
+	// when the requested events are no longer kept (or
+	// were published before rosetta-core was restarted).
+	// Clients must sync again from a block instead.
+	ErrEventsUnavailable = &types.Error{
+		Code:    40, //nolint
+		Message: "Events are not available",
+	}
+
+	// ErrSubmitQueueUnavailable is returned when a transaction
+	// cannot be persisted in the submit queue, or when its
+	// status is requested and no submit queue is configured.
+	ErrSubmitQueueUnavailable = &types.Error{
+		Code:    41, //nolint
+		Message: "Submit queue unavailable",
+	}
+
+	// ErrRateLimited is returned when a client
+	// exceeds the configured rate limit.
+	ErrRateLimited = &types.Error{
+		Code:      42, //nolint
+		Message:   "Rate limit exceeded",
+		Retriable: true,
+	}
+
+	// ErrAccessDenied is returned when a client that is not
+	// allowed to call the /construction endpoints calls them.
+	ErrAccessDenied = &types.Error{
+		Code:    43, //nolint
+		Message: "Access denied",
+	}
+
+	// ErrChainUnverified is returned until the node
+	// is verified to be on the configured chain.
+	ErrChainUnverified = &types.Error{
+		Code:      44, //nolint
+		Message:   "Chain of the node is not verified yet",
+		Retriable: true,
+	}
+
+	// ErrGasLimitCapExceeded is returned when an estimated
+	// gas limit (after GAS_LIMIT_MULTIPLIER is applied) is
+	// above GAS_LIMIT_CAP.
+	ErrGasLimitCapExceeded = &types.Error{
+		Code:    45, //nolint
+		Message: "Estimated gas limit exceeds cap",
+	}
 )
 
 // wrapErr adds details to the types.Error provided. We use a function
@@ -165,7 +442,7 @@ func wrapErr(rErr *types.Error, err error) *types.Error {
 	}
 	if err != nil {
 		newErr.Details = map[string]interface{}{
-			"context": err.Error(),
+			"context": redact.String(err.Error()),
 		}
 	}
 
@@ -0,0 +1,79 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"context"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+
+	"github.com/coinbase/rosetta-sdk-go/server"
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+const (
+	// defaultEventsLimit is the number of events returned
+	// by /events/blocks when the request has no limit.
+	defaultEventsLimit = 100
+
+	// maxEventsLimit is the maximum number of
+	// events returned by /events/blocks.
+	maxEventsLimit = 1000
+)
+
+// EventsAPIService implements the server.EventsAPIServicer interface.
+type EventsAPIService struct {
+	config *configuration.Configuration
+	events *HeadEvents
+}
+
+// NewEventsAPIService creates a new instance of an EventsAPIService.
+func NewEventsAPIService(
+	config *configuration.Configuration,
+	events *HeadEvents,
+) server.EventsAPIServicer {
+	return &EventsAPIService{
+		config: config,
+		events: events,
+	}
+}
+
+// EventsBlocks implements the /events/blocks endpoint.
+func (s *EventsAPIService) EventsBlocks(
+	ctx context.Context,
+	request *types.EventsBlocksRequest,
+) (*types.EventsBlocksResponse, *types.Error) {
+	if s.config.Mode != configuration.Online {
+		return nil, ErrUnavailableOffline
+	}
+
+	limit := int64(defaultEventsLimit)
+	if request.Limit != nil && *request.Limit > 0 {
+		limit = *request.Limit
+	}
+	if limit > maxEventsLimit {
+		limit = maxEventsLimit
+	}
+
+	maxSequence, events, err := s.events.Events(request.Offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.EventsBlocksResponse{
+		MaxSequence: maxSequence,
+		Events:      events,
+	}, nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventsService_Offline(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode: configuration.Offline,
+	}
+	servicer := NewEventsAPIService(cfg, NewHeadEvents(0))
+
+	resp, err := servicer.EventsBlocks(context.Background(), &types.EventsBlocksRequest{})
+	assert.Nil(t, resp)
+	assert.Equal(t, ErrUnavailableOffline.Code, err.Code)
+}
+
+func TestEventsService_EventsBlocks(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode: configuration.Online,
+	}
+	headEvents := NewHeadEvents(2 * maxEventsLimit)
+	servicer := NewEventsAPIService(cfg, headEvents)
+	ctx := context.Background()
+
+	for i := int64(0); i < maxEventsLimit+defaultEventsLimit; i++ {
+		headEvents.Publish(blockEvent(i, types.ADDED))
+	}
+	headEvents.Publish(blockEvent(maxEventsLimit+defaultEventsLimit-1, types.REMOVED))
+
+	t.Run("default limit", func(t *testing.T) {
+		resp, err := servicer.EventsBlocks(ctx, &types.EventsBlocksRequest{})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(maxEventsLimit+defaultEventsLimit), resp.MaxSequence)
+		assert.Len(t, resp.Events, defaultEventsLimit)
+		assert.Equal(t, types.REMOVED, resp.Events[defaultEventsLimit-1].Type)
+	})
+
+	t.Run("limit is capped", func(t *testing.T) {
+		offset := int64(0)
+		limit := int64(maxEventsLimit * 2)
+		resp, err := servicer.EventsBlocks(ctx, &types.EventsBlocksRequest{
+			Offset: &offset,
+			Limit:  &limit,
+		})
+		assert.Nil(t, err)
+		assert.Len(t, resp.Events, maxEventsLimit)
+		assert.Equal(t, int64(0), resp.Events[0].Sequence)
+	})
+
+	t.Run("catch up on reorg", func(t *testing.T) {
+		offset := int64(maxEventsLimit + defaultEventsLimit - 1)
+		resp, err := servicer.EventsBlocks(ctx, &types.EventsBlocksRequest{
+			Offset: &offset,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []*types.BlockEvent{
+			{
+				Sequence:        offset,
+				BlockIdentifier: blockEvent(offset, types.ADDED).BlockIdentifier,
+				Type:            types.ADDED,
+			},
+			{
+				Sequence:        offset + 1,
+				BlockIdentifier: blockEvent(offset, types.REMOVED).BlockIdentifier,
+				Type:            types.REMOVED,
+			},
+		}, resp.Events)
+	})
+
+	t.Run("events dropped", func(t *testing.T) {
+		headEvents := NewHeadEvents(10)
+		for i := int64(0); i < 20; i++ {
+			headEvents.Publish(blockEvent(i, types.ADDED))
+		}
+
+		offset := int64(5)
+		resp, err := NewEventsAPIService(cfg, headEvents).EventsBlocks(ctx, &types.EventsBlocksRequest{
+			Offset: &offset,
+		})
+		assert.Nil(t, resp)
+		assert.Equal(t, ErrEventsUnavailable.Code, err.Code)
+		assert.Equal(t, "offset 5 is not in [10, 20]", err.Details["context"])
+	})
+}
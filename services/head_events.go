@@ -0,0 +1,308 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+const (
+	// headEventsHistory is the number of recent events kept
+	// when no history size is configured.
+	headEventsHistory = 1024
+
+	// headEventsBuffer is the number of events buffered for a
+	// client. Clients that fall further behind are disconnected
+	// and catch up when they reconnect.
+	headEventsBuffer = 64
+
+	// headEventsStreamDuration is how long a stream stays open
+	// before the client is asked to reconnect. It is shorter
+	// than the write timeout of the server, which would
+	// otherwise cut the stream off.
+	headEventsStreamDuration = 100 * time.Second
+
+	// headEventsKeepAlive is how often a comment is sent
+	// to keep idle streams open through proxies.
+	headEventsKeepAlive = 15 * time.Second
+
+	// headEventsRetry is the reconnection delay
+	// (in milliseconds) sent to clients.
+	headEventsRetry = 1000
+
+	// maxHeadEventsStreams is the maximum number of streams
+	// open at once. Clients connecting beyond it are asked
+	// to retry later.
+	maxHeadEventsStreams = 256
+
+	// HeadEventsPath is the path of the head events stream.
+	HeadEventsPath = "/events/heads"
+)
+
+// HeadEvents broadcasts the blocks added to and removed from
+// the canonical chain to the clients of GET /events/heads and
+// keeps the most recent events for POST /events/blocks.
+type HeadEvents struct {
+	mutex    sync.Mutex
+	sequence int64
+
+	// history is a ring buffer of the most recent events,
+	// the event with sequence s is at s % len(history).
+	history []*types.BlockEvent
+
+	subscribers map[chan *types.BlockEvent]struct{}
+}
+
+// NewHeadEvents creates an empty *HeadEvents keeping the
+// last history events (or 1024 events if history is 0).
+func NewHeadEvents(history int) *HeadEvents {
+	if history <= 0 {
+		history = headEventsHistory
+	}
+
+	return &HeadEvents{
+		history:     make([]*types.BlockEvent, history),
+		subscribers: map[chan *types.BlockEvent]struct{}{},
+	}
+}
+
+// Publish assigns the next sequence to event and
+// sends it to every subscriber.
+func (h *HeadEvents) Publish(event *types.BlockEvent) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	event = &types.BlockEvent{
+		Sequence:        h.sequence,
+		BlockIdentifier: event.BlockIdentifier,
+		Type:            event.Type,
+	}
+	h.history[event.Sequence%int64(len(h.history))] = event
+	h.sequence++
+
+	for subscriber := range h.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			// The subscriber is too slow.
+			delete(h.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+// subscribe returns a channel receiving every event after
+// the event with sequence after (or every new event if
+// after is negative), and a function to unsubscribe.
+func (h *HeadEvents) subscribe(after int64) (<-chan *types.BlockEvent, func()) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	subscriber := make(chan *types.BlockEvent, headEventsBuffer+len(h.history))
+	if after >= 0 {
+		for sequence := h.oldest(); sequence < h.sequence; sequence++ {
+			if sequence > after {
+				subscriber <- h.event(sequence)
+			}
+		}
+	}
+	h.subscribers[subscriber] = struct{}{}
+
+	return subscriber, func() {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+
+		if _, ok := h.subscribers[subscriber]; ok {
+			delete(h.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+// Events returns the sequence of the last event and at most
+// limit events, starting from the event with sequence offset
+// or, if offset is nil, ending with the last event. If offset
+// is before the oldest event kept or after the next event,
+// ErrEventsUnavailable is returned.
+func (h *HeadEvents) Events(offset *int64, limit int64) (int64, []*types.BlockEvent, *types.Error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	maxSequence := h.sequence - 1
+	if maxSequence < 0 {
+		maxSequence = 0
+	}
+
+	start := h.sequence - limit
+	if offset != nil {
+		start = *offset
+		if start < h.oldest() || start > h.sequence {
+			// The events were dropped from the history,
+			// or published by a server that was restarted.
+			return 0, nil, wrapErr(ErrEventsUnavailable, fmt.Errorf(
+				"offset %d is not in [%d, %d]",
+				start,
+				h.oldest(),
+				h.sequence,
+			))
+		}
+	}
+	if start < h.oldest() {
+		start = h.oldest()
+	}
+
+	events := []*types.BlockEvent{}
+	for sequence := start; sequence < h.sequence && int64(len(events)) < limit; sequence++ {
+		events = append(events, h.event(sequence))
+	}
+
+	return maxSequence, events, nil
+}
+
+// Sequence returns the sequence of the next event.
+func (h *HeadEvents) Sequence() int64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.sequence
+}
+
+// Removed returns true if block was removed from the canonical
+// chain by an event with a sequence of at least since. If some
+// of these events are no longer kept, block is assumed to have
+// been removed.
+func (h *HeadEvents) Removed(block *types.BlockIdentifier, since int64) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if since < h.oldest() {
+		return true
+	}
+
+	for sequence := since; sequence < h.sequence; sequence++ {
+		event := h.event(sequence)
+		if event.Type == types.REMOVED && types.Hash(event.BlockIdentifier) == types.Hash(block) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// oldest returns the sequence of the oldest event kept.
+func (h *HeadEvents) oldest() int64 {
+	if oldest := h.sequence - int64(len(h.history)); oldest > 0 {
+		return oldest
+	}
+
+	return 0
+}
+
+// event returns the kept event with sequence.
+func (h *HeadEvents) event(sequence int64) *types.BlockEvent {
+	return h.history[sequence%int64(len(h.history))]
+}
+
+// HeadEventsHandler returns an http.Handler serving GET
+// /events/heads, a Server-Sent Events stream of the blocks added
+// to (block_added events) and removed from (block_removed events)
+// the canonical chain. The data of every event is a JSON encoded
+// *types.BlockEvent and its id is the sequence of the event, so
+// clients that reconnect with a Last-Event-ID header receive the
+// events they missed. At most maxHeadEventsStreams streams are
+// open at once.
+func HeadEventsHandler(events *HeadEvents) http.Handler {
+	return headEventsHandler(events, maxHeadEventsStreams)
+}
+
+func headEventsHandler(events *HeadEvents, maxStreams int) http.Handler {
+	streams := make(chan struct{}, maxStreams)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		select {
+		case streams <- struct{}{}:
+			defer func() { <-streams }()
+		default:
+			w.Header().Set("Retry-After", strconv.Itoa(headEventsRetry/1000))
+			http.Error(w, "too many streams", http.StatusServiceUnavailable)
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		after := int64(-1)
+		if lastEventID := r.Header.Get("Last-Event-ID"); len(lastEventID) > 0 {
+			id, err := strconv.ParseInt(lastEventID, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+				return
+			}
+			after = id
+		}
+
+		subscriber, unsubscribe := events.subscribe(after)
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "retry: %d\n\n", headEventsRetry)
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(headEventsKeepAlive)
+		defer keepAlive.Stop()
+		deadline := time.NewTimer(headEventsStreamDuration)
+		defer deadline.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-deadline.C:
+				return
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			case event, ok := <-subscriber:
+				if !ok {
+					return
+				}
+
+				data, err := json.Marshal(event)
+				if err != nil {
+					return
+				}
+				fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Sequence, event.Type, data)
+			}
+			flusher.Flush()
+		}
+	})
+}
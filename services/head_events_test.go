@@ -0,0 +1,275 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func blockEvent(index int64, eventType types.BlockEventType) *types.BlockEvent {
+	return &types.BlockEvent{
+		BlockIdentifier: &types.BlockIdentifier{
+			Index: index,
+			Hash:  "block",
+		},
+		Type: eventType,
+	}
+}
+
+// readEvents reads count events from a stream.
+func readEvents(t *testing.T, reader *bufio.Reader, count int) []string {
+	var events []string
+	var event []string
+	for len(events) < count {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			assert.NoError(t, err)
+			break
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case len(line) == 0 && len(event) > 0:
+			events = append(events, strings.Join(event, "\n"))
+			event = nil
+		case strings.HasPrefix(line, "id:"):
+			event = append(event, line)
+		case len(event) > 0:
+			event = append(event, line)
+		}
+	}
+
+	return events
+}
+
+func TestHeadEventsHandler(t *testing.T) {
+	headEvents := NewHeadEvents(0)
+	server := httptest.NewServer(HeadEventsHandler(headEvents))
+	defer server.Close()
+
+	headEvents.Publish(blockEvent(1, types.ADDED))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Without a Last-Event-ID, only new events are streamed.
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	headEvents.Publish(blockEvent(1, types.REMOVED))
+	headEvents.Publish(blockEvent(2, types.ADDED))
+
+	reader := bufio.NewReader(resp.Body)
+	assert.Equal(t, []string{
+		"id: 1\nevent: block_removed\n" +
+			`data: {"sequence":1,"block_identifier":{"index":1,"hash":"block"},"type":"block_removed"}`,
+		"id: 2\nevent: block_added\n" +
+			`data: {"sequence":2,"block_identifier":{"index":2,"hash":"block"},"type":"block_added"}`,
+	}, readEvents(t, reader, 2))
+
+	// Events after the Last-Event-ID are replayed.
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "0")
+	resumed, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resumed.Body.Close()
+
+	events := readEvents(t, bufio.NewReader(resumed.Body), 2)
+	assert.True(t, strings.HasPrefix(events[0], "id: 1\n"))
+	assert.True(t, strings.HasPrefix(events[1], "id: 2\n"))
+}
+
+func TestHeadEventsHandler_Invalid(t *testing.T) {
+	handler := HeadEventsHandler(NewHeadEvents(0))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/events/heads", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/events/heads", nil)
+	req.Header.Set("Last-Event-ID", "latest")
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestHeadEventsHandler_MaxStreams(t *testing.T) {
+	server := httptest.NewServer(headEventsHandler(NewHeadEvents(0), 1))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Streams beyond the limit are asked to retry.
+	rejected, err := http.Get(server.URL)
+	assert.NoError(t, err)
+	rejected.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, rejected.StatusCode)
+	assert.Equal(t, "1", rejected.Header.Get("Retry-After"))
+
+	// Closed streams free their slot.
+	resp.Body.Close()
+	assert.Eventually(t, func() bool {
+		resp, err := http.Get(server.URL)
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+
+		return resp.StatusCode == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestHeadEventsHandler_Middleware(t *testing.T) {
+	headEvents := NewHeadEvents(0)
+	cfg := &configuration.Configuration{
+		PublicMode:    true,
+		CanonicalJSON: true,
+	}
+
+	// Middleware buffering responses passes streams through.
+	mux := http.NewServeMux()
+	mux.Handle(HeadEventsPath, HeadEventsHandler(headEvents))
+	server := httptest.NewServer(PublicMiddleware(cfg, CanonicalJSONMiddleware(cfg, mux)))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+HeadEventsPath, nil)
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	headEvents.Publish(blockEvent(1, types.ADDED))
+	events := readEvents(t, bufio.NewReader(resp.Body), 1)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "id: 0\nevent: block_added\n"+
+		`data: {"sequence":0,"block_identifier":{"index":1,"hash":"block"},"type":"block_added"}`,
+		strings.Join(events, ""))
+}
+
+func TestHeadEvents_SlowSubscriber(t *testing.T) {
+	headEvents := NewHeadEvents(0)
+	subscriber, unsubscribe := headEvents.subscribe(-1)
+	defer unsubscribe()
+
+	for i := 0; i <= headEventsBuffer+headEventsHistory; i++ {
+		headEvents.Publish(blockEvent(int64(i), types.ADDED))
+	}
+
+	// The subscriber is disconnected once its buffer is full.
+	count := 0
+	for range subscriber {
+		count++
+	}
+	assert.Equal(t, headEventsBuffer+headEventsHistory, count)
+	assert.Len(t, headEvents.history, headEventsHistory)
+}
+
+func TestHeadEvents_Events(t *testing.T) {
+	headEvents := NewHeadEvents(4)
+
+	maxSequence, events, err := headEvents.Events(nil, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), maxSequence)
+	assert.Empty(t, events)
+
+	for i := int64(0); i < 6; i++ {
+		headEvents.Publish(blockEvent(i, types.ADDED))
+	}
+
+	// Only the last 4 events are kept.
+	maxSequence, events, err = headEvents.Events(nil, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), maxSequence)
+	assert.Len(t, events, 4)
+	assert.Equal(t, int64(2), events[0].Sequence)
+	assert.Equal(t, int64(5), events[3].Sequence)
+
+	_, events, err = headEvents.Events(nil, 2)
+	assert.Nil(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, int64(4), events[0].Sequence)
+
+	offset := int64(3)
+	_, events, err = headEvents.Events(&offset, 2)
+	assert.Nil(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, int64(3), events[0].Sequence)
+	assert.Equal(t, int64(4), events[1].Sequence)
+
+	// A client that is caught up receives no events.
+	offset = 6
+	maxSequence, events, err = headEvents.Events(&offset, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), maxSequence)
+	assert.Empty(t, events)
+
+	for _, offset := range []int64{1, 7} {
+		offset := offset
+		_, events, err = headEvents.Events(&offset, 2)
+		assert.Nil(t, events)
+		assert.Equal(t, ErrEventsUnavailable.Code, err.Code)
+	}
+}
+
+func TestHeadEvents_Removed(t *testing.T) {
+	headEvents := NewHeadEvents(4)
+	block := blockEvent(1, types.ADDED).BlockIdentifier
+	other := &types.BlockIdentifier{Index: 1, Hash: "other"}
+
+	headEvents.Publish(blockEvent(1, types.ADDED))
+	assert.Equal(t, int64(1), headEvents.Sequence())
+	assert.False(t, headEvents.Removed(block, 0))
+
+	headEvents.Publish(blockEvent(1, types.REMOVED))
+	assert.True(t, headEvents.Removed(block, 0))
+	assert.True(t, headEvents.Removed(block, 1))
+	assert.False(t, headEvents.Removed(block, 2))
+	assert.False(t, headEvents.Removed(other, 0))
+
+	// Blocks are assumed removed once the
+	// events since are no longer kept.
+	for i := int64(2); i < 6; i++ {
+		headEvents.Publish(blockEvent(i, types.ADDED))
+	}
+	assert.True(t, headEvents.Removed(other, 1))
+	assert.False(t, headEvents.Removed(other, 2))
+}
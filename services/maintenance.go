@@ -0,0 +1,216 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+
+	"github.com/coinbase/rosetta-sdk-go/server"
+)
+
+const (
+	// defaultMaintenanceRetryAfter is the Retry-After sent
+	// when maintenance mode was entered without an end.
+	defaultMaintenanceRetryAfter = 60 * time.Second
+
+	// scheduledMaintenanceReason is the reason
+	// reported during a scheduled window.
+	scheduledMaintenanceReason = "scheduled maintenance"
+
+	// defaultMaintenanceReason is the reason reported
+	// when maintenance mode is entered without one.
+	defaultMaintenanceReason = "maintenance"
+)
+
+// MaintenanceStatus is returned by /admin/maintenance.
+type MaintenanceStatus struct {
+	Maintenance bool       `json:"maintenance"`
+	Reason      string     `json:"reason,omitempty"`
+	Until       *time.Time `json:"until,omitempty"`
+
+	// InFlightRequests is the number of requests still being
+	// served, so an upgrade can start once they are drained.
+	InFlightRequests int64 `json:"in_flight_requests"`
+}
+
+// MaintenanceRequest is the body of POST /admin/maintenance.
+// Without Until, maintenance mode lasts until it is left
+// with DELETE /admin/maintenance.
+type MaintenanceRequest struct {
+	Reason string     `json:"reason,omitempty"`
+	Until  *time.Time `json:"until,omitempty"`
+}
+
+// Maintenance rejects new requests while rosetta-core is in
+// maintenance mode, which is entered through /admin/maintenance
+// or during a scheduled window. Requests already being served
+// when it is entered are completed.
+type Maintenance struct {
+	windows  []*configuration.MaintenanceWindow
+	inFlight int64
+
+	mutex  sync.Mutex
+	manual *MaintenanceRequest
+
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+// NewMaintenance creates a *Maintenance
+// with the scheduled windows.
+func NewMaintenance(windows []*configuration.MaintenanceWindow) *Maintenance {
+	return &Maintenance{
+		windows: windows,
+		now:     time.Now,
+	}
+}
+
+// Enter puts rosetta-core in maintenance mode until Leave
+// is called or, if until is not nil, until then.
+func (m *Maintenance) Enter(reason string, until *time.Time) {
+	if len(reason) == 0 {
+		reason = defaultMaintenanceReason
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.manual = &MaintenanceRequest{Reason: reason, Until: until}
+	log.Printf("entered maintenance mode: %s", reason)
+}
+
+// Leave takes rosetta-core out of the maintenance mode
+// entered with Enter. It does not end scheduled windows.
+func (m *Maintenance) Leave() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.manual != nil {
+		m.manual = nil
+		log.Printf("left maintenance mode")
+	}
+}
+
+// Status returns whether rosetta-core is in maintenance mode. If
+// both Enter and a scheduled window apply, Enter takes precedence.
+func (m *Maintenance) Status() *MaintenanceStatus {
+	now := m.now()
+	status := &MaintenanceStatus{InFlightRequests: atomic.LoadInt64(&m.inFlight)}
+
+	m.mutex.Lock()
+	if m.manual != nil && m.manual.Until != nil && !now.Before(*m.manual.Until) {
+		m.manual = nil
+		log.Printf("left maintenance mode")
+	}
+	manual := m.manual
+	m.mutex.Unlock()
+
+	if manual != nil {
+		status.Maintenance = true
+		status.Reason = manual.Reason
+		status.Until = manual.Until
+		return status
+	}
+
+	for _, window := range m.windows {
+		if !now.Before(window.Start) && now.Before(window.End) {
+			end := window.End
+			status.Maintenance = true
+			status.Reason = scheduledMaintenanceReason
+			status.Until = &end
+			return status
+		}
+	}
+
+	return status
+}
+
+// retryAfter returns the number of seconds
+// until the maintenance in status ends.
+func (m *Maintenance) retryAfter(status *MaintenanceStatus) int64 {
+	if status.Until == nil {
+		return int64(defaultMaintenanceRetryAfter.Seconds())
+	}
+
+	seconds := int64(math.Ceil(status.Until.Sub(m.now()).Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+
+	return seconds
+}
+
+// MaintenanceMiddleware returns a handler that responds to all
+// requests with ErrMaintenance (with a Retry-After header) while
+// m is in maintenance mode. Otherwise, requests are served by next.
+func MaintenanceMiddleware(m *Maintenance, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status := m.Status(); status.Maintenance {
+			w.Header().Set("Retry-After", strconv.FormatInt(m.retryAfter(status), 10))
+			server.EncodeJSONResponse(
+				wrapErr(ErrMaintenance, errors.New(status.Reason)),
+				http.StatusServiceUnavailable,
+				w,
+			)
+			return
+		}
+
+		atomic.AddInt64(&m.inFlight, 1)
+		defer atomic.AddInt64(&m.inFlight, -1)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// MaintenanceHandler returns an http.Handler serving
+// /admin/maintenance. GET returns the MaintenanceStatus,
+// POST enters maintenance mode (with an optional
+// MaintenanceRequest body), and DELETE leaves it. All
+// methods respond with the resulting MaintenanceStatus.
+func MaintenanceHandler(m *Maintenance) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var request MaintenanceRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+				http.Error(w, fmt.Sprintf("invalid maintenance request: %s", err.Error()), http.StatusBadRequest)
+				return
+			}
+
+			m.Enter(request.Reason, request.Until)
+		case http.MethodDelete:
+			m.Leave()
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		server.EncodeJSONResponse(m.Status(), http.StatusOK, w)
+	})
+}
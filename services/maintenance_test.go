@@ -0,0 +1,146 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaintenanceMiddleware(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	maintenance := NewMaintenance([]*configuration.MaintenanceWindow{
+		{Start: now.Add(time.Hour), End: now.Add(2 * time.Hour)},
+	})
+	maintenance.now = func() time.Time { return now }
+
+	served := make(chan struct{})
+	release := make(chan struct{})
+	handler := MaintenanceMiddleware(maintenance, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/block" {
+			served <- struct{}{}
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/network/status", nil))
+		return recorder
+	}
+	assertMaintenance := func(recorder *httptest.ResponseRecorder, reason string, retryAfter string) {
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.Equal(t, retryAfter, recorder.Header().Get("Retry-After"))
+		var rErr types.Error
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rErr))
+		assert.Equal(t, ErrMaintenance.Code, rErr.Code)
+		assert.True(t, rErr.Retriable)
+		assert.Equal(t, reason, rErr.Details["context"])
+	}
+
+	assert.Equal(t, http.StatusOK, serve().Code)
+
+	// Requests in flight are completed
+	done := make(chan struct{})
+	go func() {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/block", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		close(done)
+	}()
+	<-served
+
+	until := now.Add(90 * time.Second)
+	maintenance.Enter("node upgrade", &until)
+	assertMaintenance(serve(), "node upgrade", "90")
+	assert.Equal(t, &MaintenanceStatus{
+		Maintenance:      true,
+		Reason:           "node upgrade",
+		Until:            &until,
+		InFlightRequests: 1,
+	}, maintenance.Status())
+
+	close(release)
+	<-done
+	assert.Equal(t, int64(0), maintenance.Status().InFlightRequests)
+
+	// Maintenance mode ends at until
+	now = until
+	assert.Equal(t, http.StatusOK, serve().Code)
+
+	// Without until, maintenance mode lasts until it is left
+	maintenance.Enter("", nil)
+	assertMaintenance(serve(), "maintenance", "60")
+	maintenance.Leave()
+	assert.Equal(t, http.StatusOK, serve().Code)
+
+	// Scheduled windows
+	now = now.Add(time.Hour)
+	assertMaintenance(serve(), "scheduled maintenance", "3510")
+	maintenance.Leave()
+	assertMaintenance(serve(), "scheduled maintenance", "3510")
+	now = now.Add(time.Hour)
+	assert.Equal(t, http.StatusOK, serve().Code)
+}
+
+func TestMaintenanceHandler(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	maintenance := NewMaintenance(nil)
+	maintenance.now = func() time.Time { return now }
+	handler := MaintenanceHandler(maintenance)
+
+	request := func(method string, body string) (int, *MaintenanceStatus) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, "/admin/maintenance", strings.NewReader(body)))
+
+		var status MaintenanceStatus
+		if recorder.Code == http.StatusOK {
+			assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+		}
+		return recorder.Code, &status
+	}
+
+	code, status := request(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, &MaintenanceStatus{}, status)
+
+	until := now.Add(time.Hour)
+	code, status = request(http.MethodPost, `{"reason":"node upgrade","until":"2024-01-01T01:00:00Z"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, &MaintenanceStatus{Maintenance: true, Reason: "node upgrade", Until: &until}, status)
+
+	code, status = request(http.MethodDelete, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, &MaintenanceStatus{}, status)
+
+	code, status = request(http.MethodPost, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, &MaintenanceStatus{Maintenance: true, Reason: "maintenance"}, status)
+
+	code, _ = request(http.MethodPost, `{"until":"tomorrow"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = request(http.MethodPut, "")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
@@ -0,0 +1,202 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	"github.com/coinbase/rosetta-ethereum/metrics"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// metadataCacheSize is the maximum number
+	// of cached /construction/metadata fees.
+	metadataCacheSize = 10000
+
+	// metadataComputeTimeout bounds the shared computation
+	// of fees, which is not tied to the requests waiting
+	// for it.
+	metadataComputeTimeout = time.Minute
+
+	metadataCacheHitsMetric   = "metadata_cache/hits"
+	metadataCacheMissesMetric = "metadata_cache/misses"
+)
+
+// metadataFees are the parameters of a /construction/metadata
+// response that do not depend on the nonce of the sender.
+type metadataFees struct {
+	gasPrice *big.Int
+	gasLimit uint64
+
+	// metadataGasLimit is the gas limit populated in
+	// the metadata (see metadata.GasLimit).
+	metadataGasLimit uint64
+}
+
+// metadataCacheEntry is a cached *metadataFees.
+type metadataCacheEntry struct {
+	fees       *metadataFees
+	expiration time.Time
+}
+
+// metadataResult is the result of a
+// computation shared by identical requests.
+type metadataResult struct {
+	fees *metadataFees
+	err  *types.Error
+}
+
+// metadataCache caches the fees of /construction/metadata
+// responses for ttl, keyed by a hash of the options of the
+// request, to absorb bursts of identical constructions.
+// Identical requests made while the fees are computed share
+// the computation. Nonces are never cached.
+type metadataCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mutex   sync.Mutex
+	entries map[[sha256.Size]byte]*metadataCacheEntry
+	group   singleflight.Group
+}
+
+// newMetadataCache returns a *metadataCache caching fees
+// for ttl. If ttl is 0, nil is returned and fees are
+// never cached.
+func newMetadataCache(ttl time.Duration) *metadataCache {
+	if ttl <= 0 {
+		return nil
+	}
+
+	return &metadataCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[[sha256.Size]byte]*metadataCacheEntry{},
+	}
+}
+
+// fees returns the fees cached for input or, if there are none
+// (or input requests fresh fees), computes them with compute.
+// Fees are only cached if they are computed successfully. A
+// computation shared by identical requests is made with a
+// context detached from ctx, so canceling the request that
+// started it does not fail the others.
+func (c *metadataCache) fees(
+	ctx context.Context,
+	input *options,
+	compute func(context.Context) (*metadataFees, *types.Error),
+) (*metadataFees, *types.Error) {
+	if c == nil || input.Fresh {
+		return compute(ctx)
+	}
+
+	key, err := metadataKey(input)
+	if err != nil {
+		return compute(ctx)
+	}
+
+	if fees := c.get(key); fees != nil {
+		metrics.Counter(metadataCacheHitsMetric).Inc(1)
+		return fees, nil
+	}
+	metrics.Counter(metadataCacheMissesMetric).Inc(1)
+
+	results := c.group.DoChan(string(key[:]), func() (interface{}, error) {
+		computeCtx, cancel := ethereum.Detach(ctx, metadataComputeTimeout)
+		defer cancel()
+
+		fees, err := compute(computeCtx)
+		if err == nil {
+			c.put(key, fees)
+		}
+
+		return &metadataResult{fees: fees, err: err}, nil
+	})
+
+	select {
+	case result := <-results:
+		shared := result.Val.(*metadataResult)
+		return shared.fees, shared.err
+	case <-ctx.Done():
+		return nil, wrapErr(ErrGeth, ctx.Err())
+	}
+}
+
+// metadataKey hashes the JSON encoding of input,
+// ignoring whether fresh fees were requested.
+func metadataKey(input *options) ([sha256.Size]byte, error) {
+	keyed := *input
+	keyed.Fresh = false
+
+	encoded, err := json.Marshal(&keyed)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+
+	return sha256.Sum256(encoded), nil
+}
+
+// get returns the unexpired fees cached for
+// key, or nil if there are none.
+func (c *metadataCache) get(key [sha256.Size]byte) *metadataFees {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	if !c.now().Before(entry.expiration) {
+		delete(c.entries, key)
+		return nil
+	}
+
+	return entry.fees
+}
+
+// put caches fees for key. Expired entries are evicted when
+// the cache is full, and fees are not cached if it is still
+// full.
+func (c *metadataCache) put(key [sha256.Size]byte, fees *metadataFees) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	if len(c.entries) >= metadataCacheSize {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expiration) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	if len(c.entries) >= metadataCacheSize {
+		return
+	}
+
+	c.entries[key] = &metadataCacheEntry{
+		fees:       fees,
+		expiration: now.Add(c.ttl),
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/services"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMetadataCache(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := newMetadataCache(2 * time.Second)
+	cache.now = func() time.Time { return now }
+
+	computed := 0
+	compute := func(context.Context) (*metadataFees, *types.Error) {
+		computed++
+		return &metadataFees{gasPrice: big.NewInt(int64(computed)), gasLimit: 21000}, nil
+	}
+
+	input := &options{From: "0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"}
+	fees, err := cache.fees(context.Background(), input, compute)
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(1), fees.gasPrice)
+
+	// Identical options are served from the cache.
+	fees, err = cache.fees(context.Background(), &options{From: input.From}, compute)
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(1), fees.gasPrice)
+	assert.Equal(t, 1, computed)
+
+	// Different options are not.
+	fees, err = cache.fees(context.Background(), &options{From: input.From, Value: "0x1"}, compute)
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(2), fees.gasPrice)
+
+	// Fresh fees bypass the cache (and are not cached).
+	fees, err = cache.fees(context.Background(), &options{From: input.From, Fresh: true}, compute)
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(3), fees.gasPrice)
+	fees, err = cache.fees(context.Background(), input, compute)
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(1), fees.gasPrice)
+
+	// Expired fees are recomputed.
+	now = now.Add(2 * time.Second)
+	fees, err = cache.fees(context.Background(), input, compute)
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(4), fees.gasPrice)
+	assert.Equal(t, 4, computed)
+}
+
+func TestMetadataCache_Errors(t *testing.T) {
+	cache := newMetadataCache(time.Minute)
+	input := &options{From: "0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"}
+
+	fees, err := cache.fees(context.Background(), input, func(context.Context) (*metadataFees, *types.Error) {
+		return nil, ErrGeth
+	})
+	assert.Nil(t, fees)
+	assert.Equal(t, ErrGeth, err)
+
+	fees, err = cache.fees(context.Background(), input, func(context.Context) (*metadataFees, *types.Error) {
+		return &metadataFees{gasPrice: big.NewInt(1)}, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(1), fees.gasPrice)
+}
+
+func TestMetadataCache_Disabled(t *testing.T) {
+	cache := newMetadataCache(0)
+	assert.Nil(t, cache)
+
+	computed := 0
+	for i := 0; i < 3; i++ {
+		_, err := cache.fees(context.Background(), &options{}, func(context.Context) (*metadataFees, *types.Error) {
+			computed++
+			return &metadataFees{gasPrice: big.NewInt(1)}, nil
+		})
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 3, computed)
+}
+
+func TestMetadataCache_Concurrent(t *testing.T) {
+	cache := newMetadataCache(time.Minute)
+	input := &options{From: "0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"}
+
+	var computed int32
+	release := make(chan struct{})
+	compute := func(context.Context) (*metadataFees, *types.Error) {
+		atomic.AddInt32(&computed, 1)
+		<-release
+		return &metadataFees{gasPrice: big.NewInt(1)}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fees, err := cache.fees(context.Background(), input, compute)
+			assert.Nil(t, err)
+			assert.Equal(t, big.NewInt(1), fees.gasPrice)
+		}()
+	}
+
+	// Requests either wait for the computation in
+	// flight or are served from the cache.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&computed))
+}
+
+func TestMetadataCache_Canceled(t *testing.T) {
+	cache := newMetadataCache(time.Minute)
+	input := &options{From: "0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	compute := func(ctx context.Context) (*metadataFees, *types.Error) {
+		close(started)
+		<-release
+		if ctx.Err() != nil {
+			return nil, wrapErr(ErrGeth, ctx.Err())
+		}
+
+		return &metadataFees{gasPrice: big.NewInt(1)}, nil
+	}
+
+	// The request that starts the computation
+	// goes away before it completes.
+	first, cancel := context.WithCancel(context.Background())
+	canceled := make(chan *types.Error)
+	go func() {
+		_, err := cache.fees(first, input, compute)
+		canceled <- err
+	}()
+	<-started
+
+	shared := make(chan *metadataFees)
+	go func() {
+		fees, err := cache.fees(context.Background(), input, compute)
+		assert.Nil(t, err)
+		shared <- fees
+	}()
+
+	cancel()
+	err := <-canceled
+	assert.Equal(t, ErrGeth.Code, err.Code)
+
+	// The computation is not canceled with it, so
+	// the requests sharing it still get the fees.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	assert.Equal(t, big.NewInt(1), (<-shared).gasPrice)
+
+	fees, err := cache.fees(context.Background(), input, compute)
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(1), fees.gasPrice)
+}
+
+func TestConstructionMetadata_Cache(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    ethereum.RopstenNetwork,
+		Blockchain: ethereum.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:             configuration.Online,
+		Network:          networkIdentifier,
+		Params:           params.RopstenChainConfig,
+		MetadataCacheTTL: time.Minute,
+	}
+
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, nil, nil, nil)
+	ctx := context.Background()
+
+	from := common.HexToAddress("0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309")
+	optionsMap := forceMarshalMap(t, &options{From: from.Hex()})
+
+	// Nonces are fetched for every request, but
+	// the gas price is only fetched once (with
+	// a context detached from the request).
+	mockClient.On("PendingNonceAt", ctx, from).Return(uint64(3), nil).Once()
+	mockClient.On("PendingNonceAt", ctx, from).Return(uint64(4), nil).Once()
+	mockClient.On("SuggestGasPrice", mock.Anything).Return(big.NewInt(1000000000), nil).Once()
+	for _, nonce := range []uint64{3, 4} {
+		metadataResponse, err := servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+			NetworkIdentifier: networkIdentifier,
+			Options:           optionsMap,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, forceMarshalMap(t, &metadata{
+			Nonce:    nonce,
+			GasPrice: big.NewInt(1000000000),
+		}), metadataResponse.Metadata)
+	}
+
+	// Callers needing fresh values bypass the cache
+	// with the fresh preprocess metadata.
+	to := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	ops := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{Index: 0},
+			Type:                ethereum.CallOpType,
+			Account:             &types.AccountIdentifier{Address: from.Hex()},
+			Amount:              &types.Amount{Value: "-1000", Currency: ethereum.Currency},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{Index: 1},
+			Type:                ethereum.CallOpType,
+			Account:             &types.AccountIdentifier{Address: to.Hex()},
+			Amount:              &types.Amount{Value: "1000", Currency: ethereum.Currency},
+		},
+	}
+	preprocessResponse, err := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          map[string]interface{}{"fresh": true},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, forceMarshalMap(t, &options{From: from.Hex(), Fresh: true}), preprocessResponse.Options)
+
+	mockClient.On("PendingNonceAt", ctx, from).Return(uint64(5), nil).Once()
+	mockClient.On("SuggestGasPrice", ctx).Return(big.NewInt(2000000000), nil).Once()
+	metadataResponse, err := servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           preprocessResponse.Options,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, forceMarshalMap(t, &metadata{
+		Nonce:    5,
+		GasPrice: big.NewInt(2000000000),
+	}), metadataResponse.Metadata)
+
+	mockClient.AssertExpectations(t)
+}
@@ -0,0 +1,217 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+// responseRecorder buffers a response so that middleware
+// can inspect (and possibly replace) it before it is
+// written to the client.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{
+		header: http.Header{},
+		status: http.StatusOK,
+	}
+}
+
+// Header implements http.ResponseWriter.
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+// Write implements http.ResponseWriter.
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+}
+
+// flush writes the recorded response to w.
+func (r *responseRecorder) flush(w http.ResponseWriter) {
+	for k, v := range r.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(r.status)
+	_, _ = w.Write(r.body.Bytes())
+}
+
+// streamingRecorder buffers a response like responseRecorder
+// if buffer returns true for its status, and otherwise writes
+// it through to w as it is served, so that middleware only
+// holds the responses it inspects (i.e. errors) in memory.
+type streamingRecorder struct {
+	responseRecorder
+
+	w           http.ResponseWriter
+	buffer      func(status int) bool
+	wroteHeader bool
+	buffered    bool
+}
+
+func newStreamingRecorder(
+	w http.ResponseWriter,
+	buffer func(status int) bool,
+) *streamingRecorder {
+	return &streamingRecorder{
+		responseRecorder: *newResponseRecorder(),
+		w:                w,
+		buffer:           buffer,
+	}
+}
+
+// Write implements http.ResponseWriter.
+func (r *streamingRecorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	if r.buffered {
+		return r.body.Write(b)
+	}
+
+	return r.w.Write(b)
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (r *streamingRecorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	r.status = status
+
+	r.buffered = r.buffer(status)
+	if !r.buffered {
+		for k, v := range r.header {
+			r.w.Header()[k] = v
+		}
+		r.w.WriteHeader(status)
+	}
+}
+
+// Flush implements http.Flusher.
+func (r *streamingRecorder) Flush() {
+	if !r.wroteHeader || r.buffered {
+		return
+	}
+
+	if flusher, ok := r.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// recorded returns the buffered response, or
+// nil if the response was written through.
+func (r *streamingRecorder) recorded() *responseRecorder {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	if !r.buffered {
+		return nil
+	}
+
+	return &r.responseRecorder
+}
+
+// unsuccessful returns true if status is not http.StatusOK.
+func unsuccessful(status int) bool {
+	return status != http.StatusOK
+}
+
+// blockStream is how the middleware that inspects or re-encodes
+// responses hands that work to the block router for successful
+// /block responses, which are streamed (see encodeBlockResponse)
+// rather than buffered: the router validates the block with
+// validator (if not nil), encodes it canonically if canonical
+// is true, and calls served once it is written.
+type blockStream struct {
+	validator *responseValidator
+	canonical bool
+	served    []func(*types.BlockResponse)
+}
+
+type blockStreamKey struct{}
+
+// withBlockStream returns the blockStream of r, adding one
+// to (a copy of) r if it has none.
+func withBlockStream(r *http.Request) (*http.Request, *blockStream) {
+	if stream := blockStreamFrom(r.Context()); stream != nil {
+		return r, stream
+	}
+
+	stream := &blockStream{}
+	return r.WithContext(context.WithValue(r.Context(), blockStreamKey{}, stream)), stream
+}
+
+// blockStreamFrom returns the blockStream
+// of ctx, or nil if it has none.
+func blockStreamFrom(ctx context.Context) *blockStream {
+	stream, _ := ctx.Value(blockStreamKey{}).(*blockStream)
+	return stream
+}
+
+// unknownEndpoint is the endpoint requests to
+// paths that are not endpoints are counted under.
+const unknownEndpoint = "/unknown"
+
+// endpoint returns the endpoint of r, or unknownEndpoint
+// if the path of r is not an endpoint of rosetta-core, so
+// that metrics and reports keyed by endpoint are bounded
+// whatever paths clients request.
+func endpoint(r *http.Request) string {
+	if _, ok := requestTypes[r.URL.Path]; ok || r.URL.Path == HeadEventsPath {
+		return r.URL.Path
+	}
+
+	return unknownEndpoint
+}
+
+// streamed returns true if the response to r is streamed, so
+// middleware must pass it through rather than buffer it.
+func streamed(r *http.Request) bool {
+	return r.URL.Path == HeadEventsPath
+}
+
+// readRequestBody reads the body of a request and replaces
+// it so that it can be read again by the next handler.
+func readRequestBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return []byte{}, nil
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
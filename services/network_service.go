@@ -55,18 +55,52 @@ func (s *NetworkAPIService) NetworkOptions(
 	ctx context.Context,
 	request *types.NetworkRequest,
 ) (*types.NetworkOptionsResponse, *types.Error) {
+	// Disabled call methods are not advertised.
+	callMethods := s.config.CallMethods()
+	if !s.config.ModuleEnabled(configuration.CallModule) {
+		callMethods = []string{}
+	}
+
+	// The index is the one responses are validated
+	// with (see AsserterConfiguration).
+	timestampStartIndex := defaultTimestampStartIndex(s.config)
+
+	// The hardforks scheduled by the node are only
+	// known online.
+	hardforks := ethereum.ScheduleHardforks(s.config.Hardforks)
+	if s.config.Mode == configuration.Online {
+		hardforks = s.client.Hardforks(s.config.Hardforks)
+	}
+
+	metadata := map[string]interface{}{}
+	if len(hardforks) > 0 {
+		metadata["hardforks"] = hardforks
+	}
+
+	// Blocks below the trace start index are
+	// served in degraded mode.
+	if s.config.TraceStartIndex > 0 {
+		metadata["trace_start_index"] = s.config.TraceStartIndex
+	}
+
+	if len(metadata) == 0 {
+		metadata = nil
+	}
+
 	return &types.NetworkOptionsResponse{
 		Version: &types.Version{
 			NodeVersion:       ethereum.NodeVersion,
 			RosettaVersion:    types.RosettaAPIVersion,
 			MiddlewareVersion: types.String(configuration.MiddlewareVersion),
+			Metadata:          metadata,
 		},
 		Allow: &types.Allow{
 			Errors:                  Errors,
 			OperationTypes:          ethereum.OperationTypes,
 			OperationStatuses:       ethereum.OperationStatuses,
 			HistoricalBalanceLookup: ethereum.HistoricalBalanceSupported,
-			CallMethods:             ethereum.CallMethods,
+			CallMethods:             callMethods,
+			TimestampStartIndex:     &timestampStartIndex,
 		},
 	}, nil
 }
@@ -85,6 +119,12 @@ func (s *NetworkAPIService) NetworkStatus(
 		return nil, wrapErr(ErrGeth, err)
 	}
 
+	// Peers reveal the addresses of the node and of the
+	// nodes it is connected to.
+	if s.config.PublicMode {
+		peers = []*types.Peer{}
+	}
+
 	return &types.NetworkStatusResponse{
 		CurrentBlockIdentifier: currentBlock,
 		CurrentBlockTimestamp:  currentTime,
@@ -40,6 +40,7 @@ var (
 			Errors:                  Errors,
 			HistoricalBalanceLookup: ethereum.HistoricalBalanceSupported,
 			CallMethods:             ethereum.CallMethods,
+			TimestampStartIndex:     types.Int64(1),
 		},
 	}
 
@@ -51,8 +52,9 @@ var (
 
 func TestNetworkEndpoints_Offline(t *testing.T) {
 	cfg := &configuration.Configuration{
-		Mode:    configuration.Offline,
-		Network: networkIdentifier,
+		Mode:                   configuration.Offline,
+		Network:                networkIdentifier,
+		GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
 	}
 	mockClient := &mocks.Client{}
 	servicer := NewNetworkAPIService(cfg, mockClient)
@@ -129,9 +131,148 @@ func TestNetworkEndpoints_Online(t *testing.T) {
 		SyncStatus:             syncStatus,
 	}, networkStatus)
 
+	mockClient.On("Hardforks", map[string]uint64(nil)).Return(nil).Once()
 	networkOptions, err := servicer.NetworkOptions(ctx, nil)
 	assert.Nil(t, err)
 	assert.Equal(t, defaultNetworkOptions, networkOptions)
 
+	// Peers are not served in public mode.
+	cfg.PublicMode = true
+	networkStatus, err = servicer.NetworkStatus(ctx, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []*types.Peer{}, networkStatus.Peers)
+	assert.Equal(t, currentBlock, networkStatus.CurrentBlockIdentifier)
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestNetworkOptions_TimestampStartIndex(t *testing.T) {
+	ctx := context.Background()
+	tests := map[string]struct {
+		mode     configuration.Mode
+		override *int64
+		mock     func(*mocks.Client)
+		index    int64
+	}{
+		"offline": {
+			mode:  configuration.Offline,
+			index: 1,
+		},
+		"offline with override": {
+			mode:     configuration.Offline,
+			override: types.Int64(100),
+			index:    100,
+		},
+		// The index found by rosetta-core at startup.
+		"online": {
+			mode:     configuration.Online,
+			override: types.Int64(5000),
+			mock: func(mockClient *mocks.Client) {
+				mockClient.On("Hardforks", map[string]uint64(nil)).Return(nil).Once()
+			},
+			index: 5000,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := &configuration.Configuration{
+				Mode:                   test.mode,
+				Network:                networkIdentifier,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				TimestampStartIndex:    test.override,
+			}
+			mockClient := &mocks.Client{}
+			if test.mock != nil {
+				test.mock(mockClient)
+			}
+
+			networkOptions, err := NewNetworkAPIService(cfg, mockClient).NetworkOptions(ctx, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, test.index, *networkOptions.Allow.TimestampStartIndex)
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestNetworkOptions_Hardforks(t *testing.T) {
+	ctx := context.Background()
+	configured := map[string]uint64{
+		"istanbul":  0,
+		"hashPower": 0,
+	}
+	tests := map[string]struct {
+		mode      configuration.Mode
+		mock      func(*mocks.Client)
+		hardforks []*ethereum.Hardfork
+	}{
+		"offline": {
+			mode: configuration.Offline,
+			hardforks: []*ethereum.Hardfork{
+				{Name: "hashPower", Block: 0, Supported: true},
+				{Name: "istanbul", Block: 0, Supported: true},
+			},
+		},
+		"online": {
+			mode: configuration.Online,
+			mock: func(mockClient *mocks.Client) {
+				mockClient.On("Hardforks", configured).Return([]*ethereum.Hardfork{
+					{Name: "hashPower", Block: 0, Supported: true},
+					{Name: "istanbul", Block: 0, Supported: true},
+					{Name: "future", Block: 2000000, Supported: false},
+				}).Once()
+			},
+			hardforks: []*ethereum.Hardfork{
+				{Name: "hashPower", Block: 0, Supported: true},
+				{Name: "istanbul", Block: 0, Supported: true},
+				{Name: "future", Block: 2000000, Supported: false},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := &configuration.Configuration{
+				Mode:                   test.mode,
+				Network:                networkIdentifier,
+				GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+				TimestampStartIndex:    types.Int64(1),
+				Hardforks:              configured,
+			}
+			mockClient := &mocks.Client{}
+			if test.mock != nil {
+				test.mock(mockClient)
+			}
+
+			networkOptions, err := NewNetworkAPIService(cfg, mockClient).NetworkOptions(ctx, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, map[string]interface{}{
+				"hardforks": test.hardforks,
+			}, networkOptions.Version.Metadata)
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestNetworkOptions_TraceStartIndex(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode:                   configuration.Offline,
+		Network:                networkIdentifier,
+		GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+		TraceStartIndex:        5000000,
+	}
+	mockClient := &mocks.Client{}
+
+	networkOptions, err := NewNetworkAPIService(cfg, mockClient).NetworkOptions(
+		context.Background(),
+		nil,
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"trace_start_index": int64(5000000),
+	}, networkOptions.Version.Metadata)
+
 	mockClient.AssertExpectations(t)
 }
@@ -0,0 +1,292 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"container/list"
+	"encoding/json"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/redact"
+
+	"github.com/coinbase/rosetta-sdk-go/server"
+)
+
+const (
+	// rateLimiterSize is the number of clients tracked by the
+	// rate limiter before the least recently seen client is
+	// forgotten.
+	rateLimiterSize = 100000
+)
+
+// nodeAddresses matches URLs and IPv4 addresses (with an
+// optional port), which is how the addresses of nodes appear
+// in the errors of the clients connected to them.
+var nodeAddresses = regexp.MustCompile(
+	`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]+|\b(?:\d{1,3}\.){3}\d{1,3}(?::\d+)?\b`,
+)
+
+// PublicMiddleware returns a handler that strips the addresses of
+// nodes from the errors served by next in public mode, so that
+// exposing rosetta-core does not expose the nodes behind it. The
+// hosts of the configured node URLs are stripped too, as they may
+// be named rather than addressed (i.e. in DNS errors).
+func PublicMiddleware(cfg *configuration.Configuration, next http.Handler) http.Handler {
+	if !cfg.PublicMode {
+		return next
+	}
+
+	hosts := nodeHosts(cfg)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if streamed(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Only errors are stripped, so successful
+		// responses are written through unbuffered.
+		streaming := newStreamingRecorder(w, unsuccessful)
+		next.ServeHTTP(streaming, r)
+		recorder := streaming.recorded()
+		if recorder == nil {
+			return
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(recorder.header.Get("Content-Type"))
+		if mediaType != "application/json" {
+			recorder.flush(w)
+			return
+		}
+
+		var body map[string]interface{}
+		if err := json.Unmarshal(recorder.body.Bytes(), &body); err != nil {
+			recorder.flush(w)
+			return
+		}
+
+		stripped, err := json.Marshal(stripNodeAddresses(body, hosts))
+		if err != nil {
+			recorder.flush(w)
+			return
+		}
+
+		recorder.body.Reset()
+		recorder.body.Write(stripped)
+		if len(recorder.header.Get("Content-Length")) > 0 {
+			recorder.header.Set("Content-Length", strconv.Itoa(len(stripped)))
+		}
+
+		recorder.flush(w)
+	})
+}
+
+// nodeHosts returns the hosts of the nodes rosetta-core
+// connects to that are not matched by nodeAddresses.
+func nodeHosts(cfg *configuration.Configuration) []string {
+	urls := append([]string{cfg.GethURL}, cfg.ArchiveURLs...)
+	if cfg.NodeLag != nil {
+		urls = append(urls, cfg.NodeLag.References...)
+	}
+
+	hosts := []string{}
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil || len(u.Host) == 0 {
+			continue
+		}
+
+		// Hosts without a port or a domain (i.e. "geth")
+		// are too likely to be part of other words.
+		if strings.ContainsAny(u.Host, ":.") {
+			hosts = append(hosts, u.Host, u.Hostname())
+		}
+	}
+
+	return hosts
+}
+
+// stripNodeAddresses replaces every node address
+// in the strings of value with redact.Placeholder.
+func stripNodeAddresses(value interface{}, hosts []string) interface{} {
+	switch v := value.(type) {
+	case string:
+		v = nodeAddresses.ReplaceAllString(v, redact.Placeholder)
+		for _, host := range hosts {
+			v = strings.ReplaceAll(v, host, redact.Placeholder)
+		}
+
+		return v
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = stripNodeAddresses(item, hosts)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = stripNodeAddresses(item, hosts)
+		}
+	}
+
+	return value
+}
+
+// RateLimitMiddleware returns a handler that responds with
+// ErrRateLimited (with a Retry-After header) to the requests of
+// clients exceeding cfg.RateLimit requests per second. Clients are
+// identified by their remote address, so rosetta-core must not be
+// behind a proxy that hides them. While cfg.RateLimit is 0,
+// requests are not rate limited.
+func RateLimitMiddleware(cfg *configuration.Configuration, next http.Handler) *RateLimitHandler {
+	h := &RateLimitHandler{next: next}
+	h.Reload(cfg)
+
+	return h
+}
+
+// RateLimitHandler rate limits the requests
+// to next (see RateLimitMiddleware).
+type RateLimitHandler struct {
+	next http.Handler
+
+	// limiter is nil when requests
+	// are not rate limited.
+	mutex   sync.RWMutex
+	limiter *rateLimiter
+}
+
+// Reload applies the RateLimit of cfg. The requests
+// clients made are only forgotten if the rate changes.
+func (h *RateLimitHandler) Reload(cfg *configuration.Configuration) {
+	rate := cfg.ClientRateLimit()
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	switch {
+	case rate == 0:
+		h.limiter = nil
+	case h.limiter == nil || h.limiter.rate != float64(rate):
+		h.limiter = newRateLimiter(rate)
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *RateLimitHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mutex.RLock()
+	limiter := h.limiter
+	h.mutex.RUnlock()
+
+	if limiter == nil {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	client, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		client = r.RemoteAddr
+	}
+
+	if !limiter.allow(client) {
+		w.Header().Set("Retry-After", "1")
+		server.EncodeJSONResponse(ErrRateLimited, http.StatusTooManyRequests, w)
+		return
+	}
+
+	h.next.ServeHTTP(w, r)
+}
+
+// rateLimiterBucket holds the requests a
+// client can make as of updated.
+type rateLimiterBucket struct {
+	client  string
+	tokens  float64
+	updated time.Time
+}
+
+// rateLimiter is a token bucket per client, refilled at
+// rate requests per second up to a burst of one second
+// of requests.
+type rateLimiter struct {
+	rate float64
+	now  func() time.Time
+
+	mutex   sync.Mutex
+	buckets map[string]*list.Element
+
+	// order holds the buckets from the most
+	// to the least recently seen client.
+	order *list.List
+}
+
+func newRateLimiter(rate int) *rateLimiter {
+	return &rateLimiter{
+		rate:    float64(rate),
+		now:     time.Now,
+		buckets: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// allow returns true if client can make a request.
+func (l *rateLimiter) allow(client string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	var bucket *rateLimiterBucket
+	if element, ok := l.buckets[client]; ok {
+		l.order.MoveToFront(element)
+		bucket = element.Value.(*rateLimiterBucket)
+	} else {
+		// The least recently seen client is the most likely
+		// to have a full bucket, which is indistinguishable
+		// from a new client.
+		if l.order.Len() >= rateLimiterSize {
+			oldest := l.order.Back()
+			l.order.Remove(oldest)
+			delete(l.buckets, oldest.Value.(*rateLimiterBucket).client)
+		}
+
+		bucket = &rateLimiterBucket{client: client, tokens: l.rate, updated: now}
+		l.buckets[client] = l.order.PushFront(bucket)
+	}
+
+	bucket.tokens = l.refill(bucket, now)
+	bucket.updated = now
+	if bucket.tokens < 1 {
+		return false
+	}
+
+	bucket.tokens--
+	return true
+}
+
+// refill returns the tokens of bucket at now.
+func (l *rateLimiter) refill(bucket *rateLimiterBucket, now time.Time) float64 {
+	tokens := bucket.tokens + now.Sub(bucket.updated).Seconds()*l.rate
+	if tokens > l.rate {
+		return l.rate
+	}
+
+	return tokens
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/coinbase/rosetta-sdk-go/server"
+)
+
+const (
+	// unverifiedRetryAfter is the Retry-After sent to requests
+	// rejected until the chain of the node is verified.
+	unverifiedRetryAfter = "5"
+)
+
+// Quarantine rejects all requests until the chain of the
+// node is verified (see Unverified) and once the node is
+// found on the wrong chain.
+type Quarantine struct {
+	mutex      sync.RWMutex
+	err        error
+	unverified bool
+}
+
+// Enter quarantines rosetta-core. err is
+// returned in the details of every error.
+func (q *Quarantine) Enter(err error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.err = err
+	q.unverified = false
+}
+
+// Unverified rejects all requests until
+// Verified (or Enter) is called.
+func (q *Quarantine) Unverified() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.unverified = true
+}
+
+// Verified serves requests once the node is
+// verified to be on the configured chain.
+func (q *Quarantine) Verified() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.unverified = false
+}
+
+// reason returns whether the chain of the node is still
+// to be verified, and the error passed to Enter (or nil if
+// rosetta-core is not quarantined).
+func (q *Quarantine) reason() (bool, error) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	return q.unverified, q.err
+}
+
+// QuarantineMiddleware returns a handler that responds to all
+// requests with ErrChainUnverified (with a Retry-After header)
+// until the chain of the node is verified, and with
+// ErrChainMismatch once q is entered. Otherwise, requests are
+// served by next.
+func QuarantineMiddleware(q *Quarantine, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		unverified, err := q.reason()
+		switch {
+		case err != nil:
+			server.EncodeJSONResponse(wrapErr(ErrChainMismatch, err), http.StatusInternalServerError, w)
+		case unverified:
+			w.Header().Set("Retry-After", unverifiedRetryAfter)
+			server.EncodeJSONResponse(ErrChainUnverified, http.StatusServiceUnavailable, w)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
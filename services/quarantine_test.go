@@ -0,0 +1,62 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuarantineMiddleware(t *testing.T) {
+	quarantine := &Quarantine{}
+	handler := QuarantineMiddleware(quarantine, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/network/status", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	// Requests are rejected until the chain is verified.
+	quarantine.Unverified()
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/network/status", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "5", recorder.Header().Get("Retry-After"))
+	var unverifiedErr types.Error
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &unverifiedErr))
+	assert.Equal(t, ErrChainUnverified.Code, unverifiedErr.Code)
+	assert.True(t, unverifiedErr.Retriable)
+
+	quarantine.Verified()
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/network/status", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	quarantine.Enter(errors.New("node has chain ID 1115"))
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/network/status", nil))
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	var rErr types.Error
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rErr))
+	assert.Equal(t, ErrChainMismatch.Code, rErr.Code)
+	assert.Equal(t, "node has chain ID 1115", rErr.Details["context"])
+}
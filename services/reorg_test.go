@@ -0,0 +1,434 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/services"
+
+	"github.com/coinbase/rosetta-sdk-go/asserter"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockChain is an in-memory chain served by a *mocks.Client
+// that can be reorged to any depth. Like the node followed by
+// ethereum.MonitorHeads, it publishes the blocks it removes
+// (from the highest) and then the blocks it adds (from the
+// lowest) to events.
+type mockChain struct {
+	mutex     sync.Mutex
+	canonical []*types.Block
+	blocks    map[string]*types.Block
+	fork      int
+	calls     int
+	events    *HeadEvents
+}
+
+// newMockChain returns a *mockChain with
+// length blocks, starting at genesis.
+func newMockChain(events *HeadEvents, length int64) *mockChain {
+	chain := &mockChain{
+		blocks: map[string]*types.Block{},
+		events: events,
+	}
+	chain.extend(length)
+
+	return chain
+}
+
+// extend adds count blocks on top of the head.
+func (c *mockChain) extend(count int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i := int64(0); i < count; i++ {
+		index := int64(len(c.canonical))
+		block := &types.Block{
+			BlockIdentifier: &types.BlockIdentifier{
+				Index: index,
+				Hash:  fmt.Sprintf("0x%02x%062x", c.fork, index),
+			},
+			ParentBlockIdentifier: &types.BlockIdentifier{
+				Index: index,
+				Hash:  fmt.Sprintf("0x%02x%062x", c.fork, index),
+			},
+			Timestamp: 1600000000000 + index*1000,
+		}
+		if index > 0 {
+			block.ParentBlockIdentifier = c.canonical[index-1].BlockIdentifier
+		}
+
+		c.canonical = append(c.canonical, block)
+		c.blocks[block.BlockIdentifier.Hash] = block
+		c.events.Publish(&types.BlockEvent{
+			BlockIdentifier: block.BlockIdentifier,
+			Type:            types.ADDED,
+		})
+	}
+}
+
+// reorg replaces the last depth blocks with
+// a new branch of depth+extra blocks.
+func (c *mockChain) reorg(depth int64, extra int64) []*types.BlockIdentifier {
+	c.mutex.Lock()
+	removed := []*types.BlockIdentifier{}
+	for i := int64(0); i < depth; i++ {
+		block := c.canonical[len(c.canonical)-1]
+		c.canonical = c.canonical[:len(c.canonical)-1]
+		removed = append(removed, block.BlockIdentifier)
+		c.events.Publish(&types.BlockEvent{
+			BlockIdentifier: block.BlockIdentifier,
+			Type:            types.REMOVED,
+		})
+	}
+	c.fork++
+	c.mutex.Unlock()
+
+	c.extend(depth + extra)
+	return removed
+}
+
+// head returns the identifier of the head.
+func (c *mockChain) head() *types.BlockIdentifier {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.canonical[len(c.canonical)-1].BlockIdentifier
+}
+
+// identifiers returns the identifiers of the
+// canonical blocks, from genesis.
+func (c *mockChain) identifiers() []*types.BlockIdentifier {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	identifiers := []*types.BlockIdentifier{}
+	for _, block := range c.canonical {
+		identifiers = append(identifiers, block.BlockIdentifier)
+	}
+
+	return identifiers
+}
+
+// block returns the block identified by identifier like
+// ethereum.Client: blocks that are no longer canonical can
+// be fetched by hash, but their receipts cannot, so they
+// are reported as orphaned.
+func (c *mockChain) block(identifier *types.PartialBlockIdentifier) (*types.Block, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var block *types.Block
+	switch {
+	case identifier == nil || (identifier.Hash == nil && identifier.Index == nil):
+		block = c.canonical[len(c.canonical)-1]
+	case identifier.Hash != nil:
+		block = c.blocks[*identifier.Hash]
+	case *identifier.Index < int64(len(c.canonical)):
+		block = c.canonical[*identifier.Index]
+	}
+	if block == nil {
+		return nil, errors.New("block not found")
+	}
+
+	index := block.BlockIdentifier.Index
+	if index >= int64(len(c.canonical)) || c.canonical[index] != block {
+		return nil, ethereum.ErrBlockOrphaned
+	}
+
+	return block, nil
+}
+
+// client returns a *mocks.Client serving c.
+func (c *mockChain) client() *mocks.Client {
+	client := &mocks.Client{}
+	client.On("Status", mock.Anything).Return(
+		func(context.Context) *types.BlockIdentifier {
+			return c.head()
+		},
+		func(context.Context) int64 {
+			c.mutex.Lock()
+			defer c.mutex.Unlock()
+
+			return c.canonical[len(c.canonical)-1].Timestamp
+		},
+		nil,
+		nil,
+		nil,
+	)
+	client.On("Block", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, identifier *types.PartialBlockIdentifier) *types.Block {
+			c.mutex.Lock()
+			c.calls++
+			c.mutex.Unlock()
+
+			block, _ := c.block(identifier)
+			return block
+		},
+		func(ctx context.Context, identifier *types.PartialBlockIdentifier) error {
+			_, err := c.block(identifier)
+			return err
+		},
+	)
+
+	return client
+}
+
+// blockCalls returns the number of blocks fetched.
+func (c *mockChain) blockCalls() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.calls
+}
+
+// replayEvents applies events to chain, as a client following
+// /events/blocks would, failing if a removed block is not the
+// tip or an added block does not extend it.
+func replayEvents(
+	t *testing.T,
+	chain []*types.BlockIdentifier,
+	events []*types.BlockEvent,
+) []*types.BlockIdentifier {
+	for _, event := range events {
+		switch event.Type {
+		case types.ADDED:
+			assert.Equal(t, int64(len(chain)), event.BlockIdentifier.Index)
+			chain = append(chain, event.BlockIdentifier)
+		case types.REMOVED:
+			if assert.NotEmpty(t, chain) {
+				assert.Equal(t, chain[len(chain)-1], event.BlockIdentifier)
+				chain = chain[:len(chain)-1]
+			}
+		}
+	}
+
+	return chain
+}
+
+// reorgServer returns a handler serving chain through the
+// router and the cache, as run does.
+func reorgServer(
+	t *testing.T,
+	chain *mockChain,
+	events *HeadEvents,
+	network *types.NetworkIdentifier,
+) http.Handler {
+	serverAsserter, err := asserter.NewServer(
+		ethereum.OperationTypes,
+		ethereum.HistoricalBalanceSupported,
+		[]*types.NetworkIdentifier{network},
+		ethereum.CallMethods,
+		ethereum.IncludeMempoolCoins,
+		"",
+	)
+	assert.NoError(t, err)
+
+	cfg := &configuration.Configuration{
+		Mode:                   configuration.Online,
+		Network:                network,
+		GenesisBlockIdentifier: chain.identifiers()[0],
+		ResponseCacheSize:      1000,
+		ResponseCacheTTL:       time.Hour,
+	}
+	router := NewBlockchainRouter(cfg, chain.client(), nil, nil, nil, events, nil, serverAsserter)
+
+	return CacheMiddleware(cfg, chain.client(), events, router)
+}
+
+func TestReorgs(t *testing.T) {
+	network := &types.NetworkIdentifier{
+		Blockchain: ethereum.Blockchain,
+		Network:    ethereum.MainnetNetwork,
+	}
+	networkRaw, err := json.Marshal(network)
+	assert.NoError(t, err)
+
+	const length = 64
+	tests := map[string]struct {
+		depth int64
+		extra int64
+	}{
+		"depth 1": {
+			depth: 1,
+		},
+		"depth 1 with a longer branch": {
+			depth: 1,
+			extra: 1,
+		},
+		"depth 2": {
+			depth: 2,
+		},
+		"depth 6 with a longer branch": {
+			depth: 6,
+			extra: 4,
+		},
+		"depth 16": {
+			depth: 16,
+		},
+		"deepest reorg before finality": {
+			depth: cacheFinalityDepth - 1,
+			extra: 2,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			events := NewHeadEvents(0)
+			chain := newMockChain(events, length)
+			handler := reorgServer(t, chain, events, network)
+
+			post := func(path string, body string, response interface{}) int {
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, httptest.NewRequest(
+					http.MethodPost,
+					path,
+					strings.NewReader(`{"network_identifier":`+string(networkRaw)+body+`}`),
+				))
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), response))
+				return w.Code
+			}
+			blockByIndex := func(index int64) *types.BlockResponse {
+				var resp types.BlockResponse
+				code := post("/block", fmt.Sprintf(`,"block_identifier":{"index":%d}`, index), &resp)
+				assert.Equal(t, http.StatusOK, code)
+				return &resp
+			}
+
+			// Warm up the cache with the blocks near the tip.
+			for index := int64(length - cacheFinalityDepth); index < length; index++ {
+				blockByIndex(index)
+			}
+			calls := chain.blockCalls()
+			sequence := events.Sequence()
+
+			removed := chain.reorg(test.depth, test.extra)
+			canonical := chain.identifiers()
+			forkPoint := int64(length) - test.depth
+
+			// /network/status follows the new branch.
+			var status types.NetworkStatusResponse
+			assert.Equal(t, http.StatusOK, post("/network/status", "", &status))
+			assert.Equal(t, chain.head(), status.CurrentBlockIdentifier)
+			assert.Equal(t, canonical[0], status.GenesisBlockIdentifier)
+
+			// Blocks below the fork point are still served
+			// from the cache, while reorged blocks are not.
+			for index := int64(length - cacheFinalityDepth); index < int64(len(canonical)); index++ {
+				resp := blockByIndex(index)
+				assert.Equal(t, canonical[index], resp.Block.BlockIdentifier)
+				assert.Equal(t, canonical[index-1], resp.Block.ParentBlockIdentifier)
+			}
+			assert.Equal(t, calls+int(test.depth+test.extra), chain.blockCalls())
+
+			// Reorged blocks are orphaned.
+			for _, block := range removed {
+				var rErr types.Error
+				code := post("/block", fmt.Sprintf(`,"block_identifier":{"hash":%q}`, block.Hash), &rErr)
+				assert.Equal(t, http.StatusInternalServerError, code)
+				assert.Equal(t, ErrBlockOrphaned.Code, rErr.Code)
+				assert.True(t, rErr.Retriable)
+			}
+
+			// The reorg is published as the removed blocks (from
+			// the highest) followed by the new branch.
+			var resp types.EventsBlocksResponse
+			assert.Equal(t, http.StatusOK, post("/events/blocks", fmt.Sprintf(`,"offset":%d`, sequence), &resp))
+			assert.Len(t, resp.Events, int(2*test.depth+test.extra))
+			for i, event := range resp.Events {
+				assert.Equal(t, sequence+int64(i), event.Sequence)
+				if int64(i) < test.depth {
+					assert.Equal(t, types.REMOVED, event.Type)
+					assert.Equal(t, removed[i], event.BlockIdentifier)
+					continue
+				}
+
+				assert.Equal(t, types.ADDED, event.Type)
+				assert.Equal(t, canonical[forkPoint+int64(i)-test.depth], event.BlockIdentifier)
+			}
+			assert.Equal(t, events.Sequence()-1, resp.MaxSequence)
+
+			// Replaying every event rebuilds the canonical chain.
+			offset := int64(0)
+			replayed := []*types.BlockIdentifier{}
+			for {
+				var resp types.EventsBlocksResponse
+				body := fmt.Sprintf(`,"offset":%d,"limit":50`, offset)
+				assert.Equal(t, http.StatusOK, post("/events/blocks", body, &resp))
+				if len(resp.Events) == 0 {
+					break
+				}
+
+				replayed = replayEvents(t, replayed, resp.Events)
+				offset = resp.Events[len(resp.Events)-1].Sequence + 1
+			}
+			assert.Equal(t, canonical, replayed)
+		})
+	}
+}
+
+func TestReorgs_Repeated(t *testing.T) {
+	events := NewHeadEvents(0)
+	chain := newMockChain(events, 16)
+	network := &types.NetworkIdentifier{
+		Blockchain: ethereum.Blockchain,
+		Network:    ethereum.MainnetNetwork,
+	}
+	networkRaw, err := json.Marshal(network)
+	assert.NoError(t, err)
+	handler := reorgServer(t, chain, events, network)
+
+	blockByIndex := func(index int64) *types.BlockIdentifier {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(
+			http.MethodPost,
+			"/block",
+			strings.NewReader(fmt.Sprintf(
+				`{"network_identifier":%s,"block_identifier":{"index":%d}}`,
+				networkRaw,
+				index,
+			)),
+		))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp types.BlockResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Block.BlockIdentifier
+	}
+
+	// The same height is reorged back and forth, and every
+	// branch is cached and evicted in turn.
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, chain.head(), blockByIndex(15))
+		assert.Equal(t, chain.head(), blockByIndex(15))
+		chain.reorg(1, 0)
+	}
+	assert.Equal(t, chain.head(), blockByIndex(15))
+	assert.Equal(t, 6, chain.blockCalls())
+}
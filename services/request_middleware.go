@@ -0,0 +1,153 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+
+	"github.com/coinbase/rosetta-sdk-go/server"
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+// requestTypes returns a new request of the type
+// served by each endpoint, for strict decoding.
+var requestTypes = map[string]func() interface{}{
+	"/network/list":            func() interface{} { return &types.MetadataRequest{} },
+	"/network/options":         func() interface{} { return &types.NetworkRequest{} },
+	"/network/status":          func() interface{} { return &types.NetworkRequest{} },
+	"/account/balance":         func() interface{} { return &types.AccountBalanceRequest{} },
+	"/account/coins":           func() interface{} { return &types.AccountCoinsRequest{} },
+	"/account/summary":         func() interface{} { return &AccountSummaryRequest{} },
+	"/block":                   func() interface{} { return &types.BlockRequest{} },
+	"/block/transaction":       func() interface{} { return &types.BlockTransactionRequest{} },
+	"/mempool":                 func() interface{} { return &types.NetworkRequest{} },
+	"/mempool/transaction":     func() interface{} { return &types.MempoolTransactionRequest{} },
+	"/construction/derive":     func() interface{} { return &types.ConstructionDeriveRequest{} },
+	"/construction/preprocess": func() interface{} { return &types.ConstructionPreprocessRequest{} },
+	"/construction/metadata":   func() interface{} { return &types.ConstructionMetadataRequest{} },
+	"/construction/payloads":   func() interface{} { return &types.ConstructionPayloadsRequest{} },
+	"/construction/combine":    func() interface{} { return &types.ConstructionCombineRequest{} },
+	"/construction/parse":      func() interface{} { return &types.ConstructionParseRequest{} },
+	"/construction/hash":       func() interface{} { return &types.ConstructionHashRequest{} },
+	"/construction/submit":     func() interface{} { return &types.ConstructionSubmitRequest{} },
+	"/call":                    func() interface{} { return &types.CallRequest{} },
+	"/events/blocks":           func() interface{} { return &types.EventsBlocksRequest{} },
+}
+
+// RequestMiddleware returns a handler that rejects requests
+// before they reach next if their body is larger than
+// cfg.MaxRequestBodySize (or the limit of their endpoint in
+// cfg.RequestBodySizeLimits) or is not a single JSON object.
+// If cfg.StrictJSONDecoding is true, request bodies with
+// fields that are unknown to their endpoint are rejected too.
+func RequestMiddleware(cfg *configuration.Configuration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := cfg.RequestBodySizeLimit(r.URL.Path)
+		if r.ContentLength > limit {
+			server.EncodeJSONResponse(
+				wrapErr(ErrRequestTooLarge, fmt.Errorf("request body exceeds %d bytes", limit)),
+				http.StatusInternalServerError,
+				w,
+			)
+			return
+		}
+
+		if r.Body != nil {
+			// Reading one more byte than the limit
+			// detects bodies that are too large without
+			// relying on Content-Length.
+			body, err := ioutil.ReadAll(io.LimitReader(r.Body, limit+1))
+			r.Body.Close()
+			if err != nil {
+				server.EncodeJSONResponse(wrapErr(ErrInvalidInput, err), http.StatusInternalServerError, w)
+				return
+			}
+			if int64(len(body)) > limit {
+				server.EncodeJSONResponse(
+					wrapErr(ErrRequestTooLarge, fmt.Errorf("request body exceeds %d bytes", limit)),
+					http.StatusInternalServerError,
+					w,
+				)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			if r.Method == http.MethodPost {
+				if err := checkRequestBody(r.URL.Path, body, cfg.StrictJSONDecoding); err != nil {
+					server.EncodeJSONResponse(wrapErr(ErrInvalidInput, err), http.StatusInternalServerError, w)
+					return
+				}
+			}
+		}
+
+		// Some handlers (including the request asserters of
+		// rosetta-sdk-go) panic on malformed values such as null
+		// array elements. net/http would recover the panic but
+		// close the connection without a response.
+		defer func() {
+			if p := recover(); p != nil {
+				log.Printf("panic serving %s: %v\n%s", r.URL.Path, p, debug.Stack())
+				server.EncodeJSONResponse(
+					wrapErr(ErrInvalidInput, errors.New("unable to process request")),
+					http.StatusInternalServerError,
+					w,
+				)
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkRequestBody returns an error if body is not a single JSON
+// object. If strict is true, it also returns an error if body has
+// fields that are unknown to the endpoint at path.
+func checkRequestBody(path string, body []byte, strict bool) error {
+	if !bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		return errors.New("request must be a JSON object")
+	}
+
+	var request interface{} = &map[string]json.RawMessage{}
+	newRequest, ok := requestTypes[path]
+	if strict && ok {
+		request = newRequest()
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(request); err != nil {
+		return fmt.Errorf("%w: unable to decode request", err)
+	}
+
+	// The Rosetta server decodes the first JSON value
+	// and silently ignores anything after it.
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("unexpected data after request")
+	}
+
+	return nil
+}
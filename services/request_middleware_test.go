@@ -0,0 +1,331 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/services"
+
+	"github.com/coinbase/rosetta-sdk-go/asserter"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestMiddleware(t *testing.T) {
+	body := `{"network_identifier":{"blockchain":"Corechain","network":"Ropsten"}}`
+	tests := map[string]struct {
+		cfg           *configuration.Configuration
+		path          string
+		body          string
+		contentLength int64
+		err           *types.Error
+	}{
+		"valid request": {
+			cfg:  &configuration.Configuration{},
+			path: "/network/status",
+			body: body,
+		},
+		"too large": {
+			cfg:  &configuration.Configuration{MaxRequestBodySize: 16},
+			path: "/network/status",
+			body: body,
+			err:  ErrRequestTooLarge,
+		},
+		"too large without content length": {
+			cfg:           &configuration.Configuration{MaxRequestBodySize: 16},
+			path:          "/network/status",
+			body:          body,
+			contentLength: -1,
+			err:           ErrRequestTooLarge,
+		},
+		"endpoint limit": {
+			cfg: &configuration.Configuration{
+				MaxRequestBodySize: 16,
+				RequestBodySizeLimits: map[string]int64{
+					"/network/status": 1024,
+				},
+			},
+			path: "/network/status",
+			body: body,
+		},
+		"not an object": {
+			cfg:  &configuration.Configuration{},
+			path: "/network/status",
+			body: `[{"network_identifier":{}}]`,
+			err:  ErrInvalidInput,
+		},
+		"null": {
+			cfg:  &configuration.Configuration{},
+			path: "/network/status",
+			body: `null`,
+			err:  ErrInvalidInput,
+		},
+		"malformed": {
+			cfg:  &configuration.Configuration{},
+			path: "/network/status",
+			body: `{"network_identifier":{"blockchain":"Corechain"`,
+			err:  ErrInvalidInput,
+		},
+		"trailing data": {
+			cfg:  &configuration.Configuration{},
+			path: "/network/status",
+			body: body + `{"network_identifier":{}}`,
+			err:  ErrInvalidInput,
+		},
+		"unknown field": {
+			cfg:  &configuration.Configuration{},
+			path: "/network/status",
+			body: `{"network_identifier":{"blockchain":"Corechain","network":"Ropsten","chain":1}}`,
+		},
+		"unknown field (strict)": {
+			cfg:  &configuration.Configuration{StrictJSONDecoding: true},
+			path: "/network/status",
+			body: `{"network_identifier":{"blockchain":"Corechain","network":"Ropsten","chain":1}}`,
+			err:  ErrInvalidInput,
+		},
+		"metadata (strict)": {
+			cfg:  &configuration.Configuration{StrictJSONDecoding: true},
+			path: "/construction/preprocess",
+			body: `{"network_identifier":{"blockchain":"Corechain","network":"Ropsten"},"operations":[],"metadata":{"anything":1}}`,
+		},
+		"unknown endpoint (strict)": {
+			cfg:  &configuration.Configuration{StrictJSONDecoding: true},
+			path: "/unknown",
+			body: `{"anything":1}`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			served := false
+			handler := RequestMiddleware(test.cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				served = true
+
+				// The body can still be read by next
+				var request map[string]interface{}
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			request := httptest.NewRequest(http.MethodPost, test.path, strings.NewReader(test.body))
+			if test.contentLength != 0 {
+				request.ContentLength = test.contentLength
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if test.err == nil {
+				assert.True(t, served)
+				assert.Equal(t, http.StatusOK, recorder.Code)
+				return
+			}
+
+			assert.False(t, served)
+			assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+			var rErr types.Error
+			assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rErr))
+			assert.Equal(t, test.err.Code, rErr.Code)
+		})
+	}
+}
+
+func TestRequestMiddleware_Panic(t *testing.T) {
+	handler := RequestMiddleware(&configuration.Configuration{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var signature *types.Signature
+		_ = signature.Bytes
+	}))
+
+	recorder := httptest.NewRecorder()
+	assert.NotPanics(t, func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/construction/combine", strings.NewReader(`{}`)))
+	})
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	var rErr types.Error
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rErr))
+	assert.Equal(t, ErrInvalidInput.Code, rErr.Code)
+}
+
+// fuzzIterations is the number of mutations
+// of each seed in TestRequestMiddleware_Fuzz.
+const fuzzIterations = 200
+
+// fuzzValues replace JSON values in mutated requests.
+var fuzzValues = []interface{}{
+	nil,
+	true,
+	float64(-1),
+	1e300,
+	"",
+	"0x",
+	"0xzz",
+	"-115792089237316195423570985008687907853269984665640564039457584007913129639936",
+	strings.Repeat("f", 4096),
+	[]interface{}{},
+	[]interface{}{nil},
+	map[string]interface{}{},
+}
+
+// mutateBytes returns a copy of seed with a random
+// byte-level mutation, which usually breaks the JSON.
+func mutateBytes(r *rand.Rand, seed []byte) []byte {
+	mutated := append([]byte{}, seed...)
+	i := r.Intn(len(mutated))
+	switch r.Intn(4) { // nolint:gomnd
+	case 0:
+		mutated[i] ^= byte(1 << r.Intn(8)) // nolint:gomnd
+	case 1:
+		mutated = append(mutated[:i], mutated[i+r.Intn(len(mutated)-i):]...)
+	case 2: // nolint:gomnd
+		insert := []byte(`{}[]",:-0e\`)[r.Intn(11)] // nolint:gomnd
+		mutated = append(mutated[:i], append([]byte{insert}, mutated[i:]...)...)
+	default:
+		mutated = mutated[:i]
+	}
+
+	return mutated
+}
+
+// mutateValue replaces a random value of a decoded
+// JSON document with one of fuzzValues.
+func mutateValue(r *rand.Rand, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 || r.Intn(4) == 0 { // nolint:gomnd
+			return fuzzValues[r.Intn(len(fuzzValues))]
+		}
+
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		key := keys[r.Intn(len(keys))]
+		v[key] = mutateValue(r, v[key])
+		return v
+	case []interface{}:
+		if len(v) == 0 || r.Intn(4) == 0 { // nolint:gomnd
+			return fuzzValues[r.Intn(len(fuzzValues))]
+		}
+
+		i := r.Intn(len(v))
+		v[i] = mutateValue(r, v[i])
+		return v
+	default:
+		return fuzzValues[r.Intn(len(fuzzValues))]
+	}
+}
+
+// TestRequestMiddleware_Fuzz sends mutations of a valid request
+// for every endpoint through RequestMiddleware and the router,
+// ensuring no request panics or receives a malformed response.
+// Go 1.16 does not support native fuzzing, so mutations are
+// generated from a fixed seed to keep the test reproducible.
+func TestRequestMiddleware_Fuzz(t *testing.T) {
+	network := &types.NetworkIdentifier{
+		Blockchain: ethereum.Blockchain,
+		Network:    ethereum.RopstenNetwork,
+	}
+	cfg := &configuration.Configuration{
+		Mode:                   configuration.Offline,
+		Network:                network,
+		GenesisBlockIdentifier: ethereum.RopstenGenesisBlockIdentifier,
+		Params:                 params.RopstenChainConfig,
+	}
+
+	serverAsserter, err := asserter.NewServer(
+		ethereum.OperationTypes,
+		ethereum.HistoricalBalanceSupported,
+		[]*types.NetworkIdentifier{network},
+		ethereum.CallMethods,
+		ethereum.IncludeMempoolCoins,
+		"",
+	)
+	assert.NoError(t, err)
+
+	mockClient := &mocks.Client{}
+	handler := RequestMiddleware(cfg, NewBlockchainRouter(cfg, mockClient, nil, nil, nil, nil, nil, serverAsserter))
+
+	networkRaw := `"network_identifier":{"blockchain":"Corechain","network":"Ropsten"}`
+	opsRaw := `[{"operation_identifier":{"index":0},"type":"CALL","account":{"address":"0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"},"amount":{"value":"-42894881044106498","currency":{"symbol":"CORE","decimals":18}}},{"operation_identifier":{"index":1},"type":"CALL","account":{"address":"0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d"},"amount":{"value":"42894881044106498","currency":{"symbol":"CORE","decimals":18}}}]`                                                                                                                                                                               // nolint
+	unsignedRaw := `{\"from\":\"0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309\",\"to\":\"0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d\",\"value\":\"0x9864aac3510d02\",\"data\":\"0x\",\"nonce\":\"0x0\",\"gas_price\":\"0x3b9aca00\",\"gas\":\"0x5208\",\"chain_id\":\"0x3\"}`                                                                                                                                                                                                                                                                                                                                       // nolint
+	signaturesRaw := `[{"hex_bytes":"8c712c64bc65c4a88707fa93ecd090144dffb1bf133805a10a51d354c2f9f2b25a63cea6989f4c58372c41f31164036a6b25dce1d5c05e1d31c16c0590c176e801","signing_payload":{"address":"0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309","hex_bytes":"b682f3e39c512ff57471f482eab264551487320cbd3b34485f4779a89e5612d1","account_identifier":{"address":"0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"},"signature_type":"ecdsa_recovery"},"public_key":{"hex_bytes":"03d3d3358e7f69cbe45bde38d7d6f24660c7eeeaee5c5590cfab985c8839b21fd5","curve_type":"secp256k1"},"signature_type":"ecdsa_recovery"}]` // nolint
+	signedRaw := `{\"type\":\"0x0\",\"nonce\":\"0x0\",\"gasPrice\":\"0x3b9aca00\",\"maxPriorityFeePerGas\":null,\"maxFeePerGas\":null,\"gas\":\"0x5208\",\"value\":\"0x9864aac3510d02\",\"input\":\"0x\",\"v\":\"0x2a\",\"r\":\"0x8c712c64bc65c4a88707fa93ecd090144dffb1bf133805a10a51d354c2f9f2b2\",\"s\":\"0x5a63cea6989f4c58372c41f31164036a6b25dce1d5c05e1d31c16c0590c176e8\",\"to\":\"0x57b414a0332b5cab885a451c2a28a07d1e9b8a8d\",\"hash\":\"0x424969b1a98757bcd748c60bad2a7de9745cfb26bfefb4550e780a098feada42\"}`                                                                                        // nolint
+	account := `"account_identifier":{"address":"0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"}`
+	seeds := map[string]string{
+		"/network/list":            `{}`,
+		"/network/options":         `{` + networkRaw + `}`,
+		"/network/status":          `{` + networkRaw + `}`,
+		"/account/balance":         `{` + networkRaw + `,` + account + `,"block_identifier":{"index":1}}`,
+		"/account/coins":           `{` + networkRaw + `,` + account + `,"include_mempool":false}`,
+		"/block":                   `{` + networkRaw + `,"block_identifier":{"index":1}}`,
+		"/block/transaction":       `{` + networkRaw + `,"block_identifier":{"index":1,"hash":"0x0"},"transaction_identifier":{"hash":"0x0"}}`,
+		"/mempool":                 `{` + networkRaw + `}`,
+		"/mempool/transaction":     `{` + networkRaw + `,"transaction_identifier":{"hash":"0x0"}}`,
+		"/construction/derive":     `{` + networkRaw + `,"public_key":{"hex_bytes":"03d3d3358e7f69cbe45bde38d7d6f24660c7eeeaee5c5590cfab985c8839b21fd5","curve_type":"secp256k1"}}`,
+		"/construction/preprocess": `{` + networkRaw + `,"operations":` + opsRaw + `}`,
+		"/construction/metadata":   `{` + networkRaw + `,"options":{"from":"0xe3a5B4d7f79d64088C8d4ef153A7DDe2B2d47309"}}`,
+		"/construction/payloads":   `{` + networkRaw + `,"operations":` + opsRaw + `,"metadata":{"nonce":"0x0","gas_price":"0x3b9aca00"}}`,
+		"/construction/combine":    `{` + networkRaw + `,"unsigned_transaction":"` + unsignedRaw + `","signatures":` + signaturesRaw + `}`,
+		"/construction/parse":      `{` + networkRaw + `,"signed":true,"transaction":"` + signedRaw + `"}`,
+		"/construction/hash":       `{` + networkRaw + `,"signed_transaction":"` + signedRaw + `"}`,
+		"/construction/submit":     `{` + networkRaw + `,"signed_transaction":"` + signedRaw + `"}`,
+		"/call":                    `{` + networkRaw + `,"method":"eth_getTransactionReceipt","parameters":{"tx_hash":"0x0"}}`,
+	}
+
+	paths := make([]string, 0, len(seeds))
+	for path := range seeds {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	r := rand.New(rand.NewSource(1)) // nolint:gosec
+	for _, path := range paths {
+		seed := []byte(seeds[path])
+		var decoded interface{}
+		assert.NoError(t, json.Unmarshal(seed, &decoded), path)
+
+		bodies := [][]byte{seed}
+		for i := 0; i < fuzzIterations; i++ {
+			bodies = append(bodies, mutateBytes(r, seed))
+
+			var value interface{}
+			assert.NoError(t, json.Unmarshal(seed, &value))
+			mutated, err := json.Marshal(mutateValue(r, value))
+			assert.NoError(t, err)
+			bodies = append(bodies, mutated)
+		}
+
+		for _, body := range bodies {
+			recorder := httptest.NewRecorder()
+			assert.NotPanics(t, func() {
+				handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body)))
+			}, "%s %s", path, body)
+			assert.Contains(t, []int{http.StatusOK, http.StatusInternalServerError}, recorder.Code)
+			assert.True(t, json.Valid(recorder.Body.Bytes()), "%s %s", path, body)
+		}
+	}
+}
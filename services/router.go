@@ -28,6 +28,11 @@ import (
 func NewBlockchainRouter(
 	config *configuration.Configuration,
 	client Client,
+	nonceTracker NonceTracker,
+	index AccountIndex,
+	auditLog AuditLog,
+	events *HeadEvents,
+	submitQueue SubmitQueue,
 	asserter *asserter.Asserter,
 ) http.Handler {
 	networkAPIService := NewNetworkAPIService(config, client)
@@ -43,35 +48,99 @@ func NewBlockchainRouter(
 	)
 
 	blockAPIService := NewBlockAPIService(config, client)
-	blockAPIController := server.NewBlockAPIController(
+	blockAPIController := newBlockRouter(
 		blockAPIService,
 		asserter,
 	)
 
-	constructionAPIService := NewConstructionAPIService(config, client)
-	constructionAPIController := server.NewConstructionAPIController(
-		constructionAPIService,
-		asserter,
+	constructionAPIService := NewConstructionAPIService(config, client, nonceTracker, auditLog, submitQueue)
+	constructionAPIController := moduleRouter(
+		config,
+		configuration.ConstructionModule,
+		server.NewConstructionAPIController(
+			constructionAPIService,
+			asserter,
+		),
 	)
 
 	mempoolAPIService := NewMempoolAPIService(config, client)
-	mempoolAPIController := server.NewMempoolAPIController(
-		mempoolAPIService,
-		asserter,
+	mempoolAPIController := moduleRouter(
+		config,
+		configuration.MempoolModule,
+		server.NewMempoolAPIController(
+			mempoolAPIService,
+			asserter,
+		),
 	)
 
-	callAPIService := NewCallAPIService(config, client)
-	callAPIController := server.NewCallAPIController(
-		callAPIService,
-		asserter,
+	callAPIService := NewCallAPIService(config, client, index, submitQueue)
+	callAPIController := moduleRouter(
+		config,
+		configuration.CallModule,
+		server.NewCallAPIController(
+			callAPIService,
+			asserter,
+		),
 	)
 
-	return server.NewRouter(
+	routers := []server.Router{
 		networkAPIController,
 		accountAPIController,
 		blockAPIController,
 		constructionAPIController,
 		mempoolAPIController,
 		callAPIController,
-	)
+	}
+
+	if config.EnableAccountSummary && index != nil {
+		accountSummaryAPIService := NewAccountSummaryAPIService(config, index)
+		accountSummaryAPIController := NewAccountSummaryAPIController(
+			accountSummaryAPIService,
+			asserter,
+		)
+		routers = append(routers, accountSummaryAPIController)
+	}
+
+	if events != nil {
+		eventsAPIService := NewEventsAPIService(config, events)
+		eventsAPIController := server.NewEventsAPIController(
+			eventsAPIService,
+			asserter,
+		)
+		routers = append(routers, eventsAPIController)
+	}
+
+	return server.NewRouter(routers...)
+}
+
+// disabledRouter serves the routes of a
+// disabled module with ErrEndpointDisabled.
+type disabledRouter struct {
+	router server.Router
+}
+
+// Routes returns the routes of the disabled module.
+func (r *disabledRouter) Routes() server.Routes {
+	routes := r.router.Routes()
+	for i := range routes {
+		routes[i].HandlerFunc = func(w http.ResponseWriter, req *http.Request) {
+			server.EncodeJSONResponse(ErrEndpointDisabled, http.StatusInternalServerError, w)
+		}
+	}
+
+	return routes
+}
+
+// moduleRouter returns router if module is enabled. Otherwise,
+// a server.Router serving ErrEndpointDisabled is returned.
+func moduleRouter(
+	config *configuration.Configuration,
+	module configuration.Module,
+	router server.Router,
+) server.Router {
+	if config.ModuleEnabled(module) {
+		return router
+	}
+
+	return &disabledRouter{router: router}
 }
@@ -0,0 +1,105 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/services"
+
+	"github.com/coinbase/rosetta-sdk-go/asserter"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockchainRouter_DisabledModules(t *testing.T) {
+	cfg := &configuration.Configuration{
+		Mode:                   configuration.Offline,
+		Network:                networkIdentifier,
+		GenesisBlockIdentifier: ethereum.MainnetGenesisBlockIdentifier,
+		DisabledModules:        []configuration.Module{configuration.ConstructionModule, configuration.CallModule},
+	}
+
+	serverAsserter, err := asserter.NewServer(
+		ethereum.OperationTypes,
+		ethereum.HistoricalBalanceSupported,
+		[]*types.NetworkIdentifier{networkIdentifier},
+		ethereum.CallMethods,
+		ethereum.IncludeMempoolCoins,
+		"",
+	)
+	assert.NoError(t, err)
+
+	mockClient := &mocks.Client{}
+	router := NewBlockchainRouter(cfg, mockClient, nil, nil, nil, nil, nil, serverAsserter)
+
+	tests := map[string]struct {
+		request interface{}
+		err     *types.Error
+	}{
+		"/construction/derive": {
+			request: &types.ConstructionDeriveRequest{
+				NetworkIdentifier: networkIdentifier,
+				PublicKey: &types.PublicKey{
+					Bytes:     []byte{0x02},
+					CurveType: types.Secp256k1,
+				},
+			},
+			err: ErrEndpointDisabled,
+		},
+		"/call": {
+			request: &types.CallRequest{
+				NetworkIdentifier: networkIdentifier,
+				Method:            ethereum.RewardScheduleMethod,
+			},
+			err: ErrEndpointDisabled,
+		},
+		"/mempool": {
+			request: &types.NetworkRequest{
+				NetworkIdentifier: networkIdentifier,
+			},
+			err: ErrUnavailableOffline,
+		},
+	}
+
+	for path, test := range tests {
+		t.Run(path, func(t *testing.T) {
+			body, err := json.Marshal(test.request)
+			assert.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body)))
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+			var rosettaErr types.Error
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rosettaErr))
+			assert.Equal(t, test.err.Code, rosettaErr.Code)
+		})
+	}
+
+	// Disabled call methods are not advertised
+	networkOptions, rosettaErr := NewNetworkAPIService(cfg, mockClient).NetworkOptions(context.Background(), nil)
+	assert.Nil(t, rosettaErr)
+	assert.Empty(t, networkOptions.Allow.CallMethods)
+
+	mockClient.AssertExpectations(t)
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"strings"
+
+	"github.com/coinbase/rosetta-ethereum/redact"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/core"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// submitErrors maps the messages of the errors returned by
+// eth_sendRawTransaction to their Rosetta error. Errors are
+// returned over JSON-RPC as strings, so they are matched on
+// their message.
+var submitErrors = []struct {
+	messages []string
+	err      *types.Error
+}{
+	{
+		messages: []string{core.ErrNonceTooLow.Error()},
+		err:      ErrNonceTooLow,
+	},
+	{
+		messages: []string{core.ErrReplaceUnderpriced.Error()},
+		err:      ErrReplacementUnderpriced,
+	},
+	{
+		// Nodes before geth v1.9.13 return "known transaction".
+		messages: []string{core.ErrAlreadyKnown.Error(), "known transaction"},
+		err:      ErrTransactionAlreadyKnown,
+	},
+	{
+		messages: []string{core.ErrInsufficientFunds.Error()},
+		err:      ErrInsufficientFunds,
+	},
+	{
+		messages: []string{core.ErrTxPoolOverflow.Error()},
+		err:      ErrTxPoolFull,
+	},
+}
+
+// submitError returns the Rosetta error of a failed broadcast
+// of signedTx. The details include the sender and nonce of
+// signedTx so that clients can act on the error without parsing
+// the transaction again. Unknown errors are returned as
+// ErrBroadcastFailed.
+func submitError(signedTx *ethTypes.Transaction, err error) *types.Error {
+	rErr := ErrBroadcastFailed
+	message := strings.ToLower(err.Error())
+	for _, submitErr := range submitErrors {
+		for _, m := range submitErr.messages {
+			if strings.Contains(message, m) {
+				rErr = submitErr.err
+				break
+			}
+		}
+		if rErr != ErrBroadcastFailed {
+			break
+		}
+	}
+
+	details := map[string]interface{}{
+		"context":          redact.String(err.Error()),
+		"transaction_hash": signedTx.Hash().Hex(),
+		"nonce":            signedTx.Nonce(),
+	}
+	if sender, err := ethTypes.Sender(ethTypes.NewEIP155Signer(signedTx.ChainId()), signedTx); err == nil {
+		details["sender"] = sender.Hex()
+	}
+
+	return &types.Error{
+		Code:      rErr.Code,
+		Message:   rErr.Message,
+		Retriable: rErr.Retriable,
+		Details:   details,
+	}
+}
@@ -19,6 +19,13 @@ import (
 	"encoding/json"
 	"math/big"
 
+	"github.com/coinbase/rosetta-ethereum/archive"
+	"github.com/coinbase/rosetta-ethereum/audit"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	"github.com/coinbase/rosetta-ethereum/indexer"
+	"github.com/coinbase/rosetta-ethereum/submit"
+	"github.com/coinbase/rosetta-ethereum/watermark"
+
 	"github.com/coinbase/rosetta-sdk-go/types"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -41,6 +48,12 @@ type Client interface {
 		*types.PartialBlockIdentifier,
 	) (*types.Block, error)
 
+	BlockPage(
+		context.Context,
+		*types.PartialBlockIdentifier,
+		int,
+	) (*types.Block, []*types.TransactionIdentifier, error)
+
 	Transaction(
 		context.Context,
 		*types.BlockIdentifier,
@@ -55,6 +68,8 @@ type Client interface {
 
 	PendingNonceAt(context.Context, common.Address) (uint64, error)
 
+	PendingBalanceAt(context.Context, common.Address) (*big.Int, error)
+
 	SuggestGasPrice(ctx context.Context) (*big.Int, error)
 
 	SendTransaction(ctx context.Context, tx *ethTypes.Transaction) error
@@ -65,20 +80,128 @@ type Client interface {
 		ctx context.Context,
 		request *types.CallRequest,
 	) (*types.CallResponse, error)
+
+	Hardforks(configured map[string]uint64) []*ethereum.Hardfork
+
+	StakedBalance(
+		ctx context.Context,
+		address common.Address,
+		block *types.BlockIdentifier,
+	) (*ethereum.StakedBalance, error)
+
+	TokenBalances(
+		ctx context.Context,
+		address common.Address,
+		block *types.BlockIdentifier,
+		currencies []*types.Currency,
+	) ([]*types.Amount, error)
+
+	CancelTarget(
+		ctx context.Context,
+		account common.Address,
+		nonce uint64,
+	) (*ethereum.CancelTarget, error)
+}
+
+// NonceTracker is used by /construction/metadata to
+// allocate strictly increasing nonces per sender.
+type NonceTracker interface {
+	Next(sender common.Address, pending uint64) (uint64, error)
+}
+
+// AuditLog is used by /construction/submit to record
+// every submitted transaction before it is broadcast.
+type AuditLog interface {
+	Record(entry *audit.Entry) error
+}
+
+// SubmitQueue is used by /construction/submit to persist
+// transactions so they are rebroadcast until they are mined,
+// and by /call to look up their status.
+type SubmitQueue interface {
+	Enqueue(tx *ethTypes.Transaction) error
+	RecordAttempt(hash common.Hash, err error) error
+	Remove(hash common.Hash) error
+	Status(hash common.Hash) (*submit.Entry, error)
+}
+
+// AccountIndex is used by the /account/summary
+// extension to look up indexed address activity
+// and by /call to look up the burned supply and
+// token holdings.
+type AccountIndex interface {
+	Watermark() (*types.BlockIdentifier, error)
+	Summary(common.Address) (*indexer.AccountSummary, error)
+	Burned() (*indexer.BurnedSupply, error)
+	TokenHoldings(common.Address) (*indexer.TokenHoldings, error)
+	FirstSeen(start int64, end int64) ([]common.Address, error)
+}
+
+// BlockArchive serves blocks exported
+// to a block archive (see ArchiveClient).
+type BlockArchive interface {
+	Manifest(ctx context.Context) (*archive.Manifest, error)
+	Block(ctx context.Context, index int64) (*types.Block, error)
+}
+
+// Watermarks is used by WatermarkMiddleware to record
+// the blocks that are served and to report the persisted
+// watermarks in /network/status.
+type Watermarks interface {
+	Served(block *types.BlockIdentifier)
+	Watermarks() watermark.Watermarks
+}
+
+// preprocessMetadata is the metadata accepted
+// by /construction/preprocess.
+type preprocessMetadata struct {
+	StateOverrides ethereum.StateOverride `json:"state_overrides,omitempty"`
+	Fresh          bool                   `json:"fresh,omitempty"`
+	CheckBalance   bool                   `json:"check_balance,omitempty"`
 }
 
 type options struct {
 	From string `json:"from"`
+
+	// To and StateOverrides are only populated when the gas
+	// limit must be estimated with state overrides.
+	To             string                 `json:"to,omitempty"`
+	StateOverrides ethereum.StateOverride `json:"state_overrides,omitempty"`
+
+	// Validator is only populated for delegations. Value is
+	// populated for delegations, and for transfers when the
+	// balance of the sender is checked. It is a decimal string.
+	Validator string `json:"validator,omitempty"`
+	Value     string `json:"value,omitempty"`
+
+	// CancelNonce is only populated for cancellations.
+	// It is a decimal string.
+	CancelNonce string `json:"cancel_nonce,omitempty"`
+
+	// Fresh is set when the fees of /construction/metadata
+	// must not be served from the cache (see metadataCache).
+	Fresh bool `json:"fresh,omitempty"`
+
+	// CheckBalance is set when /construction/metadata must
+	// check that the sender can pay for the value and the fees
+	// of the transaction (see checkBalance).
+	CheckBalance bool `json:"check_balance,omitempty"`
 }
 
 type metadata struct {
 	Nonce    uint64   `json:"nonce"`
 	GasPrice *big.Int `json:"gas_price"`
+
+	// GasLimit is only populated when it was estimated or
+	// differs from ethereum.TransferGasLimit. Otherwise,
+	// ethereum.TransferGasLimit is used.
+	GasLimit uint64 `json:"gas_limit,omitempty"`
 }
 
 type metadataWire struct {
 	Nonce    string `json:"nonce"`
 	GasPrice string `json:"gas_price"`
+	GasLimit string `json:"gas_limit,omitempty"`
 }
 
 func (m *metadata) MarshalJSON() ([]byte, error) {
@@ -86,6 +209,9 @@ func (m *metadata) MarshalJSON() ([]byte, error) {
 		Nonce:    hexutil.Uint64(m.Nonce).String(),
 		GasPrice: hexutil.EncodeBig(m.GasPrice),
 	}
+	if m.GasLimit > 0 {
+		mw.GasLimit = hexutil.EncodeUint64(m.GasLimit)
+	}
 
 	return json.Marshal(mw)
 }
@@ -106,28 +232,40 @@ func (m *metadata) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
+	if len(mw.GasLimit) > 0 {
+		gasLimit, err := hexutil.DecodeUint64(mw.GasLimit)
+		if err != nil {
+			return err
+		}
+
+		m.GasLimit = gasLimit
+	}
+
 	m.GasPrice = gasPrice
 	m.Nonce = nonce
 	return nil
 }
 
 type parseMetadata struct {
-	Nonce    uint64   `json:"nonce"`
-	GasPrice *big.Int `json:"gas_price"`
-	ChainID  *big.Int `json:"chain_id"`
+	Nonce       uint64   `json:"nonce"`
+	GasPrice    *big.Int `json:"gas_price"`
+	ChainID     *big.Int `json:"chain_id"`
+	GenesisHash string   `json:"genesis_hash,omitempty"`
 }
 
 type parseMetadataWire struct {
-	Nonce    string `json:"nonce"`
-	GasPrice string `json:"gas_price"`
-	ChainID  string `json:"chain_id"`
+	Nonce       string `json:"nonce"`
+	GasPrice    string `json:"gas_price"`
+	ChainID     string `json:"chain_id"`
+	GenesisHash string `json:"genesis_hash,omitempty"`
 }
 
 func (p *parseMetadata) MarshalJSON() ([]byte, error) {
 	pmw := &parseMetadataWire{
-		Nonce:    hexutil.Uint64(p.Nonce).String(),
-		GasPrice: hexutil.EncodeBig(p.GasPrice),
-		ChainID:  hexutil.EncodeBig(p.ChainID),
+		Nonce:       hexutil.Uint64(p.Nonce).String(),
+		GasPrice:    hexutil.EncodeBig(p.GasPrice),
+		ChainID:     hexutil.EncodeBig(p.ChainID),
+		GenesisHash: p.GenesisHash,
 	}
 
 	return json.Marshal(pmw)
@@ -142,29 +280,45 @@ type transaction struct {
 	GasPrice *big.Int `json:"gas_price"`
 	GasLimit uint64   `json:"gas"`
 	ChainID  *big.Int `json:"chain_id"`
+
+	// GenesisHash is the hash of the genesis block of the
+	// network the transaction is constructed for. It is
+	// not signed: it is only checked by /construction/combine.
+	GenesisHash string `json:"genesis_hash,omitempty"`
+
+	// Cancel is true if the transaction cancels the pending
+	// transaction of From with Nonce. It is not signed:
+	// /construction/combine copies it to the signed transaction
+	// (see markCancel) so that /construction/parse can tell a
+	// cancellation from a zero-value transfer to its sender.
+	Cancel bool `json:"cancel,omitempty"`
 }
 
 type transactionWire struct {
-	From     string `json:"from"`
-	To       string `json:"to"`
-	Value    string `json:"value"`
-	Data     string `json:"data"`
-	Nonce    string `json:"nonce"`
-	GasPrice string `json:"gas_price"`
-	GasLimit string `json:"gas"`
-	ChainID  string `json:"chain_id"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Value       string `json:"value"`
+	Data        string `json:"data"`
+	Nonce       string `json:"nonce"`
+	GasPrice    string `json:"gas_price"`
+	GasLimit    string `json:"gas"`
+	ChainID     string `json:"chain_id"`
+	GenesisHash string `json:"genesis_hash,omitempty"`
+	Cancel      bool   `json:"cancel,omitempty"`
 }
 
 func (t *transaction) MarshalJSON() ([]byte, error) {
 	tw := &transactionWire{
-		From:     t.From,
-		To:       t.To,
-		Value:    hexutil.EncodeBig(t.Value),
-		Data:     hexutil.Encode(t.Data),
-		Nonce:    hexutil.EncodeUint64(t.Nonce),
-		GasPrice: hexutil.EncodeBig(t.GasPrice),
-		GasLimit: hexutil.EncodeUint64(t.GasLimit),
-		ChainID:  hexutil.EncodeBig(t.ChainID),
+		From:        t.From,
+		To:          t.To,
+		Value:       hexutil.EncodeBig(t.Value),
+		Data:        hexutil.Encode(t.Data),
+		Nonce:       hexutil.EncodeUint64(t.Nonce),
+		GasPrice:    hexutil.EncodeBig(t.GasPrice),
+		GasLimit:    hexutil.EncodeUint64(t.GasLimit),
+		ChainID:     hexutil.EncodeBig(t.ChainID),
+		GenesisHash: t.GenesisHash,
+		Cancel:      t.Cancel,
 	}
 
 	return json.Marshal(tw)
@@ -215,5 +369,7 @@ func (t *transaction) UnmarshalJSON(data []byte) error {
 	t.GasLimit = gasLimit
 	t.ChainID = chainID
 	t.GasPrice = gasPrice
+	t.GenesisHash = tw.GenesisHash
+	t.Cancel = tw.Cancel
 	return nil
 }
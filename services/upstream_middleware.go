@@ -0,0 +1,162 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	"github.com/coinbase/rosetta-ethereum/metrics"
+
+	"github.com/coinbase/rosetta-sdk-go/server"
+)
+
+const (
+	// blockEndpoint is the path of the /block endpoint, whose
+	// requests are counted as blocks served in UpstreamReport.
+	blockEndpoint = "/block"
+
+	upstreamRequestsMetric = "upstream/requests"
+	upstreamEndpointMetric = "upstream/endpoint_calls"
+)
+
+// EndpointUpstreamCalls aggregates the upstream
+// calls made to serve an endpoint.
+type EndpointUpstreamCalls struct {
+	Endpoint        string           `json:"endpoint"`
+	Requests        int64            `json:"requests"`
+	Calls           int64            `json:"calls"`
+	CallsPerRequest float64          `json:"calls_per_request"`
+	Classes         map[string]int64 `json:"classes"`
+}
+
+// UpstreamReport is returned by /admin/upstream.
+type UpstreamReport struct {
+	Calls         int64                    `json:"calls"`
+	BlocksServed  int64                    `json:"blocks_served"`
+	CallsPerBlock float64                  `json:"calls_per_block"`
+	Endpoints     []*EndpointUpstreamCalls `json:"endpoints"`
+}
+
+// UpstreamTracker aggregates the upstream calls made
+// to serve each endpoint (see UpstreamMiddleware).
+type UpstreamTracker struct {
+	mutex     sync.Mutex
+	endpoints map[string]*EndpointUpstreamCalls
+}
+
+// NewUpstreamTracker returns an empty *UpstreamTracker.
+func NewUpstreamTracker() *UpstreamTracker {
+	return &UpstreamTracker{
+		endpoints: map[string]*EndpointUpstreamCalls{},
+	}
+}
+
+// UpstreamMiddleware returns a handler that counts the upstream
+// calls made by next to serve every request, by endpoint and
+// class (see ethereum.UpstreamCallClass), in tracker and in
+// the upstream metrics. Calls that are not made on behalf of
+// a request (i.e. by the indexer) are only counted in the
+// metrics. Requests to paths that are not endpoints are
+// counted under unknownEndpoint.
+func UpstreamMiddleware(tracker *UpstreamTracker, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, calls := ethereum.WithUpstreamCalls(r.Context())
+		next.ServeHTTP(w, r.WithContext(ctx))
+
+		tracker.record(endpoint(r), calls)
+	})
+}
+
+// record adds the calls made to serve a
+// request to endpoint to the aggregates.
+func (t *UpstreamTracker) record(endpoint string, calls *ethereum.UpstreamCalls) {
+	classes := calls.Classes()
+	total := calls.Total()
+
+	metrics.Counter(upstreamRequestsMetric + endpoint).Inc(1)
+	metrics.Counter(upstreamEndpointMetric + endpoint).Inc(total)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	aggregate, ok := t.endpoints[endpoint]
+	if !ok {
+		aggregate = &EndpointUpstreamCalls{
+			Endpoint: endpoint,
+			Classes:  map[string]int64{},
+		}
+		t.endpoints[endpoint] = aggregate
+	}
+
+	aggregate.Requests++
+	aggregate.Calls += total
+	for class, count := range classes {
+		aggregate.Classes[class] += count
+	}
+}
+
+// Report returns the aggregates of all
+// endpoints, sorted by endpoint.
+func (t *UpstreamTracker) Report() *UpstreamReport {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	report := &UpstreamReport{
+		Endpoints: []*EndpointUpstreamCalls{},
+	}
+	for _, aggregate := range t.endpoints {
+		endpoint := &EndpointUpstreamCalls{
+			Endpoint:        aggregate.Endpoint,
+			Requests:        aggregate.Requests,
+			Calls:           aggregate.Calls,
+			CallsPerRequest: float64(aggregate.Calls) / float64(aggregate.Requests),
+			Classes:         map[string]int64{},
+		}
+		for class, count := range aggregate.Classes {
+			endpoint.Classes[class] = count
+		}
+
+		report.Calls += endpoint.Calls
+		report.Endpoints = append(report.Endpoints, endpoint)
+
+		if endpoint.Endpoint == blockEndpoint {
+			report.BlocksServed = endpoint.Requests
+			report.CallsPerBlock = endpoint.CallsPerRequest
+		}
+	}
+
+	sort.Slice(report.Endpoints, func(i, j int) bool {
+		return report.Endpoints[i].Endpoint < report.Endpoints[j].Endpoint
+	})
+
+	return report
+}
+
+// UpstreamReportHandler returns an http.Handler
+// serving GET /admin/upstream.
+func UpstreamReportHandler(tracker *UpstreamTracker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		server.EncodeJSONResponse(tracker.Report(), http.StatusOK, w)
+	})
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpstreamMiddleware(t *testing.T) {
+	tracker := NewUpstreamTracker()
+	handler := UpstreamMiddleware(tracker, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Simulate the calls made by the client,
+		// which receives the request context.
+		calls, ok := ethereum.UpstreamCallsFromContext(r.Context())
+		assert.True(t, ok)
+		switch r.URL.Path {
+		case "/block":
+			calls.Add("eth", 1)
+			calls.Add("debug", 1)
+		case "/account/balance":
+			calls.Add(ethereum.GraphQLCallClass, 1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	paths := []string{"/block", "/block", "/account/balance", "/network/list", "/.env", "/wp-login.php"}
+	for _, path := range paths {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, path, nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+
+	expected := &UpstreamReport{
+		Calls:         5,
+		BlocksServed:  2,
+		CallsPerBlock: 2,
+		Endpoints: []*EndpointUpstreamCalls{
+			{
+				Endpoint:        "/account/balance",
+				Requests:        1,
+				Calls:           1,
+				CallsPerRequest: 1,
+				Classes:         map[string]int64{ethereum.GraphQLCallClass: 1},
+			},
+			{
+				Endpoint:        "/block",
+				Requests:        2,
+				Calls:           4,
+				CallsPerRequest: 2,
+				Classes:         map[string]int64{"eth": 2, "debug": 2},
+			},
+			{
+				Endpoint:        "/network/list",
+				Requests:        1,
+				Calls:           0,
+				CallsPerRequest: 0,
+				Classes:         map[string]int64{},
+			},
+			{
+				Endpoint:        "/unknown",
+				Requests:        2,
+				Calls:           0,
+				CallsPerRequest: 0,
+				Classes:         map[string]int64{},
+			},
+		},
+	}
+	assert.Equal(t, expected, tracker.Report())
+
+	reportHandler := UpstreamReportHandler(tracker)
+	recorder := httptest.NewRecorder()
+	reportHandler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/admin/upstream", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	reportHandler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/upstream", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var report UpstreamReport
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	assert.Equal(t, expected, &report)
+}
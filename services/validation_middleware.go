@@ -0,0 +1,258 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/metrics"
+
+	"github.com/coinbase/rosetta-sdk-go/asserter"
+	"github.com/coinbase/rosetta-sdk-go/server"
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+const (
+	validationViolationsMetric = "validation/violations"
+)
+
+// responseValidator validates responses using the same
+// checks rosetta-cli performs on the client side.
+type responseValidator struct {
+	asserter *asserter.Asserter
+}
+
+// ValidationMiddleware returns a handler that validates every
+// response served by next against the Rosetta specification when
+// the STRICT validation mode is configured. Invalid responses are
+// logged, counted, and replaced with ErrResponseInvalid. In
+// PERMISSIVE mode, next is returned unchanged to avoid the cost
+// of buffering and decoding every response. Successful /block
+// responses are validated before they are streamed rather than
+// buffered (see blockStream).
+func ValidationMiddleware(
+	cfg *configuration.Configuration,
+	next http.Handler,
+) (http.Handler, error) {
+	if cfg.ValidationMode != configuration.StrictValidation {
+		return next, nil
+	}
+
+	asserterConfig := AsserterConfiguration(cfg)
+	clientAsserter, err := asserter.NewClientWithOptions(
+		asserterConfig.NetworkIdentifier,
+		asserterConfig.GenesisBlockIdentifier,
+		asserterConfig.AllowedOperationTypes,
+		asserterConfig.AllowedOperationStatuses,
+		asserterConfig.AllowedErrors,
+		&asserterConfig.AllowedTimestampStartIndex,
+		&asserter.Validations{
+			Enabled: false,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not initialize response asserter", err)
+	}
+
+	v := &responseValidator{asserter: clientAsserter}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if streamed(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		requestBody, err := readRequestBody(r)
+		if err != nil {
+			server.EncodeJSONResponse(wrapErr(ErrInvalidInput, err), http.StatusInternalServerError, w)
+			return
+		}
+
+		// Successful /block responses are validated by the
+		// block router, as they are too large to buffer.
+		var recorder *responseRecorder
+		if r.URL.Path == "/block" {
+			var stream *blockStream
+			r, stream = withBlockStream(r)
+			stream.validator = v
+
+			streaming := newStreamingRecorder(w, unsuccessful)
+			next.ServeHTTP(streaming, r)
+			if recorder = streaming.recorded(); recorder == nil {
+				return
+			}
+		} else {
+			recorder = newResponseRecorder()
+			next.ServeHTTP(recorder, r)
+		}
+
+		if err := v.validate(
+			r.URL.Path,
+			requestBody,
+			recorder.status,
+			recorder.body.Bytes(),
+		); err != nil {
+			v.reject(w, r, err)
+			return
+		}
+
+		recorder.flush(w)
+	}), nil
+}
+
+// reject logs and counts a response to r that failed
+// validation with err, and serves ErrResponseInvalid
+// instead.
+func (v *responseValidator) reject(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("response for %s failed validation: %s", r.URL.Path, err.Error())
+	metrics.Counter(validationViolationsMetric).Inc(1)
+	metrics.Counter(validationViolationsMetric + endpoint(r)).Inc(1)
+
+	server.EncodeJSONResponse(
+		wrapErr(ErrResponseInvalid, err),
+		http.StatusInternalServerError,
+		w,
+	)
+}
+
+// validate decodes a response according to the
+// endpoint that served it and asserts it is valid.
+func (v *responseValidator) validate( // nolint:gocyclo
+	path string,
+	requestBody []byte,
+	status int,
+	body []byte,
+) error {
+	if status != http.StatusOK {
+		var rErr types.Error
+		if err := json.Unmarshal(body, &rErr); err != nil {
+			return fmt.Errorf("%w: unable to decode error", err)
+		}
+
+		return v.asserter.Error(&rErr)
+	}
+
+	switch path {
+	case "/network/list":
+		var resp types.NetworkListResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return err
+		}
+		return asserter.NetworkListResponse(&resp)
+	case "/network/options":
+		var resp types.NetworkOptionsResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return err
+		}
+		return asserter.NetworkOptionsResponse(&resp)
+	case "/network/status":
+		var resp types.NetworkStatusResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return err
+		}
+		return asserter.NetworkStatusResponse(&resp)
+	case "/account/balance":
+		var req types.AccountBalanceRequest
+		if err := json.Unmarshal(requestBody, &req); err != nil {
+			return err
+		}
+		var resp types.AccountBalanceResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return err
+		}
+		return asserter.AccountBalanceResponse(req.BlockIdentifier, &resp)
+	case "/block":
+		var resp types.BlockResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return err
+		}
+		if resp.Block == nil {
+			return nil
+		}
+		return v.asserter.Block(resp.Block)
+	case "/block/transaction":
+		var resp types.BlockTransactionResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return err
+		}
+		return v.asserter.Transaction(resp.Transaction)
+	case "/mempool":
+		var resp types.MempoolResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return err
+		}
+		return asserter.MempoolTransactions(resp.TransactionIdentifiers)
+	case "/construction/derive":
+		var resp types.ConstructionDeriveResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return err
+		}
+		return asserter.ConstructionDeriveResponse(&resp)
+	case "/construction/preprocess":
+		var resp types.ConstructionPreprocessResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return err
+		}
+		return asserter.ConstructionPreprocessResponse(&resp)
+	case "/construction/metadata":
+		var resp types.ConstructionMetadataResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return err
+		}
+		return asserter.ConstructionMetadataResponse(&resp)
+	case "/construction/payloads":
+		var resp types.ConstructionPayloadsResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return err
+		}
+		return asserter.ConstructionPayloadsResponse(&resp)
+	case "/construction/combine":
+		var resp types.ConstructionCombineResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return err
+		}
+		return asserter.ConstructionCombineResponse(&resp)
+	case "/construction/parse":
+		var req types.ConstructionParseRequest
+		if err := json.Unmarshal(requestBody, &req); err != nil {
+			return err
+		}
+		var resp types.ConstructionParseResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return err
+		}
+		return v.asserter.ConstructionParseResponse(&resp, req.Signed)
+	case "/construction/hash", "/construction/submit":
+		var resp types.TransactionIdentifierResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return err
+		}
+		return asserter.TransactionIdentifierResponse(&resp)
+	case "/call":
+		var resp types.CallResponse
+		return json.Unmarshal(body, &resp)
+	case "/events/blocks":
+		var resp types.EventsBlocksResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return err
+		}
+		return asserter.EventsBlocksResponse(&resp)
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/configuration"
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+
+	"github.com/coinbase/rosetta-sdk-go/asserter"
+	"github.com/coinbase/rosetta-sdk-go/server"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidationMiddleware(t *testing.T) {
+	validBlock := &types.BlockResponse{
+		Block: &types.Block{
+			BlockIdentifier: &types.BlockIdentifier{
+				Index: 100,
+				Hash:  "block 100",
+			},
+			ParentBlockIdentifier: &types.BlockIdentifier{
+				Index: 99,
+				Hash:  "block 99",
+			},
+			Timestamp: 1601450000000,
+		},
+	}
+	invalidBlock := &types.BlockResponse{
+		Block: &types.Block{
+			BlockIdentifier: &types.BlockIdentifier{
+				Index: 100,
+			},
+			ParentBlockIdentifier: &types.BlockIdentifier{
+				Index: 99,
+				Hash:  "block 99",
+			},
+			Timestamp: 1601450000000,
+		},
+	}
+
+	tests := map[string]struct {
+		mode     configuration.ValidationMode
+		response interface{}
+		status   int
+
+		expectedStatus int
+		expectedError  *types.Error
+	}{
+		"strict valid block": {
+			mode:           configuration.StrictValidation,
+			response:       validBlock,
+			status:         http.StatusOK,
+			expectedStatus: http.StatusOK,
+		},
+		"strict invalid block": {
+			mode:           configuration.StrictValidation,
+			response:       invalidBlock,
+			status:         http.StatusOK,
+			expectedStatus: http.StatusInternalServerError,
+			expectedError:  ErrResponseInvalid,
+		},
+		"strict known error": {
+			mode:           configuration.StrictValidation,
+			response:       wrapErr(ErrGeth, nil),
+			status:         http.StatusInternalServerError,
+			expectedStatus: http.StatusInternalServerError,
+			expectedError:  ErrGeth,
+		},
+		"strict unknown error": {
+			mode: configuration.StrictValidation,
+			response: &types.Error{
+				Code:    999,
+				Message: "unknown",
+			},
+			status:         http.StatusInternalServerError,
+			expectedStatus: http.StatusInternalServerError,
+			expectedError:  ErrResponseInvalid,
+		},
+		"permissive invalid block": {
+			mode:           configuration.PermissiveValidation,
+			response:       invalidBlock,
+			status:         http.StatusOK,
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := &configuration.Configuration{
+				Network: &types.NetworkIdentifier{
+					Blockchain: ethereum.Blockchain,
+					Network:    ethereum.CoreNetwork,
+				},
+				GenesisBlockIdentifier: ethereum.CoreGenesisBlockIdentifier,
+				ValidationMode:         test.mode,
+			}
+			serverAsserter, err := asserter.NewServer(
+				ethereum.OperationTypes,
+				ethereum.HistoricalBalanceSupported,
+				[]*types.NetworkIdentifier{cfg.Network},
+				ethereum.CallMethods,
+				ethereum.IncludeMempoolCoins,
+				"",
+			)
+			assert.NoError(t, err)
+
+			// Successful responses are served by the block
+			// router, which validates them as it streams them.
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if test.status != http.StatusOK {
+					server.EncodeJSONResponse(test.response, test.status, w)
+					return
+				}
+
+				router := &blockRouter{
+					service:  staticBlockService(test.response.(*types.BlockResponse)),
+					asserter: serverAsserter,
+				}
+				router.Block(w, r)
+			})
+
+			handler, err := ValidationMiddleware(cfg, next)
+			assert.NoError(t, err)
+
+			index := int64(100)
+			body, err := json.Marshal(&types.BlockRequest{
+				NetworkIdentifier: cfg.Network,
+				BlockIdentifier:   &types.PartialBlockIdentifier{Index: &index},
+			})
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, "/block", bytes.NewReader(body))
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+
+			if test.expectedError == nil {
+				var resp types.BlockResponse
+				assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				assert.Equal(t, test.response, &resp)
+				return
+			}
+
+			var rErr types.Error
+			assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rErr))
+			assert.Equal(t, test.expectedError.Code, rErr.Code)
+			assert.Equal(t, test.expectedError.Message, rErr.Message)
+		})
+	}
+}
+
+// blockServiceFunc is a server.BlockAPIServicer
+// serving /block with a function.
+type blockServiceFunc func(*types.BlockRequest) (*types.BlockResponse, *types.Error)
+
+// Block implements server.BlockAPIServicer.
+func (f blockServiceFunc) Block(
+	ctx context.Context,
+	request *types.BlockRequest,
+) (*types.BlockResponse, *types.Error) {
+	return f(request)
+}
+
+// BlockTransaction implements server.BlockAPIServicer.
+func (f blockServiceFunc) BlockTransaction(
+	context.Context,
+	*types.BlockTransactionRequest,
+) (*types.BlockTransactionResponse, *types.Error) {
+	return nil, ErrUnimplemented
+}
+
+// staticBlockService serves the same
+// response for every block request.
+func staticBlockService(response *types.BlockResponse) server.BlockAPIServicer {
+	return blockServiceFunc(func(*types.BlockRequest) (*types.BlockResponse, *types.Error) {
+		return response, nil
+	})
+}
@@ -0,0 +1,225 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	"github.com/coinbase/rosetta-ethereum/metrics"
+	"github.com/coinbase/rosetta-ethereum/redact"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+const (
+	// maxBlockFailures is the number of consecutive times a
+	// block can fail to be converted before it is poisoned.
+	maxBlockFailures = 3
+
+	// poisonCooldown is how long a poisoned block is served
+	// ErrBlockPoisoned before it is attempted again. If the
+	// attempt succeeds, the block is removed from the poison
+	// list.
+	poisonCooldown = 10 * time.Minute
+
+	// failureExpiry is how long a failure is remembered. A
+	// block that does not fail again within it starts over.
+	failureExpiry = time.Hour
+
+	// maxTrackedBlocks is the maximum number of blocks with
+	// failures that are remembered. Once it is reached, the
+	// block that failed least recently is forgotten.
+	maxTrackedBlocks = 1000
+
+	poisonedBlocksMetric = "block/poisoned"
+	poisonMetric         = "block/poison"
+)
+
+// blockFailure is an entry of the poison list.
+type blockFailure struct {
+	attempts   int
+	lastError  string
+	failedAt   time.Time
+	poisonedAt time.Time
+}
+
+// blockWatchdog detects blocks that repeatedly fail to be
+// converted (i.e. the trace runs out of memory) so that they
+// are not fetched from the node on every retry. Only failures
+// to trace or convert a block count (see
+// ethereum.ErrBlockUnconvertible): blocks that do not exist
+// yet or cannot be fetched are never poisoned.
+type blockWatchdog struct {
+	now func() time.Time
+
+	mutex    sync.Mutex
+	failures map[string]*blockFailure
+}
+
+func newBlockWatchdog() *blockWatchdog {
+	return &blockWatchdog{
+		now:      time.Now,
+		failures: map[string]*blockFailure{},
+	}
+}
+
+// blockKey returns the key of a requested block in the poison
+// list. Requests for the tip (without an index or hash) are
+// not tracked because the tip changes.
+func blockKey(identifier *types.PartialBlockIdentifier) (string, bool) {
+	if identifier == nil {
+		return "", false
+	}
+
+	if identifier.Index != nil {
+		return fmt.Sprintf("index:%d", *identifier.Index), true
+	}
+
+	if identifier.Hash != nil {
+		return fmt.Sprintf("hash:%s", *identifier.Hash), true
+	}
+
+	return "", false
+}
+
+// check returns ErrBlockPoisoned if the block is poisoned
+// and its cooldown has not elapsed.
+func (w *blockWatchdog) check(identifier *types.PartialBlockIdentifier) *types.Error {
+	key, ok := blockKey(identifier)
+	if !ok {
+		return nil
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	failure, ok := w.failures[key]
+	if !ok || failure.poisonedAt.IsZero() {
+		return nil
+	}
+
+	retryAt := failure.poisonedAt.Add(poisonCooldown)
+	if !w.now().Before(retryAt) {
+		// Allow a single attempt once the cooldown has elapsed.
+		// If it fails, the block is poisoned again.
+		failure.poisonedAt = time.Time{}
+		failure.attempts = maxBlockFailures - 1
+		return nil
+	}
+
+	return &types.Error{
+		Code:      ErrBlockPoisoned.Code,
+		Message:   ErrBlockPoisoned.Message,
+		Retriable: ErrBlockPoisoned.Retriable,
+		Details: map[string]interface{}{
+			"attempts":    failure.attempts,
+			"context":     failure.lastError,
+			"retry_after": retryAt.Unix(),
+		},
+	}
+}
+
+// failed records a failed attempt to convert a block. The
+// block is poisoned once it has failed maxBlockFailures times
+// in a row.
+func (w *blockWatchdog) failed(identifier *types.PartialBlockIdentifier, err error) {
+	key, ok := blockKey(identifier)
+	if !ok || !errors.Is(err, ethereum.ErrBlockUnconvertible) {
+		return
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	now := w.now()
+	failure, ok := w.failures[key]
+	if ok && now.Sub(failure.failedAt) > failureExpiry {
+		delete(w.failures, key)
+		ok = false
+	}
+	if !ok {
+		w.evict(now)
+		failure = &blockFailure{}
+		w.failures[key] = failure
+	}
+
+	failure.attempts++
+	failure.lastError = redact.String(err.Error())
+	failure.failedAt = now
+	if failure.attempts >= maxBlockFailures && failure.poisonedAt.IsZero() {
+		failure.poisonedAt = now
+		log.Printf("poisoning block %s after %d failures: %s", key, failure.attempts, failure.lastError)
+		metrics.Counter(poisonMetric).Inc(1)
+		w.updateMetric()
+	}
+}
+
+// succeeded removes a block from the poison list.
+func (w *blockWatchdog) succeeded(identifier *types.PartialBlockIdentifier) {
+	key, ok := blockKey(identifier)
+	if !ok {
+		return
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, ok := w.failures[key]; ok {
+		delete(w.failures, key)
+		w.updateMetric()
+	}
+}
+
+// evict forgets the failures that expired before now and, if
+// maxTrackedBlocks are still remembered, the failure of the
+// block that failed least recently. It must be called while
+// holding the mutex.
+func (w *blockWatchdog) evict(now time.Time) {
+	var oldest string
+	for key, failure := range w.failures {
+		if now.Sub(failure.failedAt) > failureExpiry {
+			delete(w.failures, key)
+			continue
+		}
+
+		if len(oldest) == 0 || failure.failedAt.Before(w.failures[oldest].failedAt) {
+			oldest = key
+		}
+	}
+
+	if len(w.failures) >= maxTrackedBlocks {
+		delete(w.failures, oldest)
+	}
+
+	w.updateMetric()
+}
+
+// updateMetric updates the number of poisoned blocks. It
+// must be called while holding the mutex.
+func (w *blockWatchdog) updateMetric() {
+	poisoned := int64(0)
+	for _, failure := range w.failures {
+		if failure.attempts >= maxBlockFailures {
+			poisoned++
+		}
+	}
+
+	metrics.Gauge(poisonedBlocksMetric).Update(poisoned)
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockWatchdog_Eviction(t *testing.T) {
+	w := newBlockWatchdog()
+	now := time.Unix(1600000000, 0)
+	w.now = func() time.Time { return now }
+	traceErr := fmt.Errorf("%w: out of memory", ethereum.ErrBlockUnconvertible)
+	identifier := func(index int64) *types.PartialBlockIdentifier {
+		return &types.PartialBlockIdentifier{Index: &index}
+	}
+
+	// Failures that are not repeated within failureExpiry
+	// are forgotten.
+	for i := 0; i < maxBlockFailures-1; i++ {
+		w.failed(identifier(1), traceErr)
+	}
+	now = now.Add(failureExpiry + time.Second)
+	w.failed(identifier(1), traceErr)
+	assert.Equal(t, 1, w.failures["index:1"].attempts)
+	assert.Nil(t, w.check(identifier(1)))
+
+	// At most maxTrackedBlocks blocks are remembered, and the
+	// block that failed least recently is forgotten first.
+	for i := int64(2); i <= maxTrackedBlocks+1; i++ {
+		now = now.Add(time.Millisecond)
+		w.failed(identifier(i), traceErr)
+	}
+	assert.Len(t, w.failures, maxTrackedBlocks)
+	assert.NotContains(t, w.failures, "index:1")
+	assert.Contains(t, w.failures, "index:2")
+	assert.Contains(t, w.failures, fmt.Sprintf("index:%d", maxTrackedBlocks+1))
+
+	// Expired failures are forgotten when another block fails.
+	now = now.Add(failureExpiry + time.Second)
+	w.failed(identifier(0), traceErr)
+	assert.Len(t, w.failures, 1)
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+// watermarksMetadataKey is the key of the watermarks
+// in the metadata of /network/status.
+const watermarksMetadataKey = "watermarks"
+
+// WatermarkMiddleware returns a handler that records the blocks
+// successfully served by next for /block in watermarks, and adds
+// the current watermarks to the metadata of /network/status.
+// Blocks with other_transactions are not fully served, so they
+// are not recorded. /block responses are streamed, so the block
+// router records them once they are served (see blockStream)
+// rather than this handler decoding them. If watermarks is nil,
+// next is returned.
+func WatermarkMiddleware(watermarks Watermarks, next http.Handler) http.Handler {
+	if watermarks == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/block" {
+			var stream *blockStream
+			r, stream = withBlockStream(r)
+			stream.served = append(stream.served, func(response *types.BlockResponse) {
+				if response.Block != nil &&
+					response.Block.BlockIdentifier != nil &&
+					len(response.OtherTransactions) == 0 {
+					watermarks.Served(response.Block.BlockIdentifier)
+				}
+			})
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.URL.Path != "/network/status" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := newResponseRecorder()
+		next.ServeHTTP(recorder, r)
+		if recorder.status != http.StatusOK {
+			recorder.flush(w)
+			return
+		}
+
+		var response map[string]json.RawMessage
+		if err := json.Unmarshal(recorder.body.Bytes(), &response); err != nil {
+			recorder.flush(w)
+			return
+		}
+
+		metadata, err := json.Marshal(map[string]interface{}{
+			watermarksMetadataKey: watermarks.Watermarks(),
+		})
+		if err != nil {
+			recorder.flush(w)
+			return
+		}
+		response["metadata"] = metadata
+
+		body, err := json.Marshal(response)
+		if err != nil {
+			recorder.flush(w)
+			return
+		}
+
+		recorder.body.Reset()
+		recorder.body.Write(body)
+		if len(recorder.header.Get("Content-Length")) > 0 {
+			recorder.header.Set("Content-Length", strconv.Itoa(len(body)))
+		}
+
+		recorder.flush(w)
+	})
+}
@@ -0,0 +1,154 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/services"
+	"github.com/coinbase/rosetta-ethereum/watermark"
+
+	"github.com/coinbase/rosetta-sdk-go/asserter"
+	"github.com/coinbase/rosetta-sdk-go/server"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatermarkMiddleware(t *testing.T) {
+	network := &types.NetworkIdentifier{
+		Blockchain: ethereum.Blockchain,
+		Network:    ethereum.CoreNetwork,
+	}
+	serverAsserter, err := asserter.NewServer(
+		ethereum.OperationTypes,
+		ethereum.HistoricalBalanceSupported,
+		[]*types.NetworkIdentifier{network},
+		ethereum.CallMethods,
+		ethereum.IncludeMempoolCoins,
+		"",
+	)
+	assert.NoError(t, err)
+
+	router := &blockRouter{
+		service: blockServiceFunc(func(request *types.BlockRequest) (*types.BlockResponse, *types.Error) {
+			switch *request.BlockIdentifier.Index {
+			case 10:
+				return &types.BlockResponse{
+					Block: &types.Block{
+						BlockIdentifier:       &types.BlockIdentifier{Index: 10, Hash: "0x10"},
+						ParentBlockIdentifier: &types.BlockIdentifier{Index: 9, Hash: "0x9"},
+					},
+				}, nil
+			case 11:
+				return &types.BlockResponse{
+					Block: &types.Block{
+						BlockIdentifier:       &types.BlockIdentifier{Index: 11, Hash: "0x11"},
+						ParentBlockIdentifier: &types.BlockIdentifier{Index: 10, Hash: "0x10"},
+					},
+					OtherTransactions: []*types.TransactionIdentifier{{Hash: "0xa"}},
+				}, nil
+			default:
+				return nil, ErrGeth
+			}
+		}),
+		asserter: serverAsserter,
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/network/status" {
+			router.Block(w, r)
+			return
+		}
+
+		server.EncodeJSONResponse(&types.NetworkStatusResponse{
+			CurrentBlockIdentifier: &types.BlockIdentifier{Index: 12, Hash: "0x12"},
+			Peers:                  []*types.Peer{},
+		}, http.StatusOK, w)
+	})
+
+	block := func(index int64) string {
+		body, err := json.Marshal(&types.BlockRequest{
+			NetworkIdentifier: network,
+			BlockIdentifier:   &types.PartialBlockIdentifier{Index: &index},
+		})
+		assert.NoError(t, err)
+
+		return string(body)
+	}
+
+	serve := func(handler http.Handler, path string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return w
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		w := serve(WatermarkMiddleware(nil, next), "/network/status", `{}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "metadata")
+	})
+
+	mockWatermarks := &mocks.Watermarks{}
+	handler := WatermarkMiddleware(mockWatermarks, next)
+
+	t.Run("served block", func(t *testing.T) {
+		mockWatermarks.On("Served", &types.BlockIdentifier{Index: 10, Hash: "0x10"}).Once()
+		w := serve(handler, "/block", block(10))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("partial block", func(t *testing.T) {
+		w := serve(handler, "/block", block(11))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		w := serve(handler, "/block", block(12))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("network status", func(t *testing.T) {
+		mockWatermarks.On("Watermarks").Return(watermark.Watermarks{
+			ServedBlock: &types.BlockIdentifier{Index: 10, Hash: "0x10"},
+			ReorgDepth:  2,
+			ReorgBlock:  &types.BlockIdentifier{Index: 8, Hash: "0x8"},
+			UpdatedAt:   1600000000000,
+		}).Once()
+		w := serve(handler, "/network/status", `{}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			types.NetworkStatusResponse
+			Metadata map[string]watermark.Watermarks `json:"metadata"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, int64(12), response.CurrentBlockIdentifier.Index)
+		assert.Equal(t, map[string]watermark.Watermarks{
+			"watermarks": {
+				ServedBlock: &types.BlockIdentifier{Index: 10, Hash: "0x10"},
+				ReorgDepth:  2,
+				ReorgBlock:  &types.BlockIdentifier{Index: 8, Hash: "0x8"},
+				UpdatedAt:   1600000000000,
+			},
+		}, response.Metadata)
+	})
+
+	mockWatermarks.AssertExpectations(t)
+}
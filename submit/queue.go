@@ -0,0 +1,520 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package submit persists transactions accepted by
+// /construction/submit and rebroadcasts them until
+// they are seen in the mempool or in a block.
+package submit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	"github.com/coinbase/rosetta-ethereum/metrics"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+)
+
+const (
+	// DefaultExpiry is how long a transaction is rebroadcast
+	// before the queue gives up on it. Entries that reached a
+	// final status are kept for the same duration so their
+	// status can still be queried.
+	DefaultExpiry = 24 * time.Hour
+
+	// QueuedStatus is the status of a transaction
+	// that has not been accepted by the node yet.
+	QueuedStatus = "queued"
+
+	// PendingStatus is the status of a transaction
+	// in the mempool of the node.
+	PendingStatus = "pending"
+
+	// MinedStatus is the status of a transaction
+	// in the canonical chain.
+	MinedStatus = "mined"
+
+	// ReplacedStatus is the status of a transaction whose
+	// nonce was used by another mined transaction.
+	ReplacedStatus = "replaced"
+
+	// ExpiredStatus is the status of a transaction that was
+	// not mined within the expiry of the queue.
+	ExpiredStatus = "expired"
+
+	// pollInterval is how often the queue looks
+	// for transactions that are due.
+	pollInterval = 5 * time.Second
+
+	// checkInterval is how often a pending transaction
+	// is checked, so that it is rebroadcast if the node
+	// drops it from its mempool.
+	checkInterval = 30 * time.Second
+
+	// Rebroadcasts are retried with an exponential
+	// backoff from minBackoff to maxBackoff.
+	minBackoff = 5 * time.Second
+	maxBackoff = 5 * time.Minute
+
+	// leveldb tuning used for the queue database. The
+	// queue stores a single small record per transaction.
+	databaseCache   = 16
+	databaseHandles = 16
+
+	keyPrefix = "submit-"
+
+	sizeMetric = "submit_queue/size"
+)
+
+// ErrNotFound is returned when a transaction is not queued.
+var ErrNotFound = errors.New("transaction is not queued")
+
+// rejectedErrors are the broadcast errors that a rebroadcast
+// cannot fix. Errors are returned over JSON-RPC as strings, so
+// they are matched on their message.
+var rejectedErrors = []error{
+	core.ErrNonceTooLow,
+	core.ErrReplaceUnderpriced,
+	core.ErrInsufficientFunds,
+}
+
+// Client is used by the queue to broadcast transactions
+// and to track them with ethereum.TransactionStatusMethod.
+type Client interface {
+	SendTransaction(ctx context.Context, tx *ethTypes.Transaction) error
+
+	Call(
+		ctx context.Context,
+		request *types.CallRequest,
+	) (*types.CallResponse, error)
+}
+
+// Entry is the persisted state of a queued transaction.
+type Entry struct {
+	TransactionHash string        `json:"transaction_hash"`
+	Transaction     hexutil.Bytes `json:"transaction"`
+	From            string        `json:"from"`
+	Nonce           uint64        `json:"nonce"`
+	Status          string        `json:"status"`
+
+	// Attempts is the number of broadcasts and LastError
+	// is the error of the last failed broadcast.
+	Attempts  int64  `json:"attempts"`
+	LastError string `json:"last_error,omitempty"`
+
+	// BlockIdentifier is only populated for mined transactions.
+	BlockIdentifier *types.BlockIdentifier `json:"block_identifier,omitempty"`
+
+	// Times are in seconds since the epoch.
+	CreatedAt     int64 `json:"created_at"`
+	UpdatedAt     int64 `json:"updated_at"`
+	NextAttemptAt int64 `json:"next_attempt_at"`
+}
+
+// Final returns true if the queue no longer tracks e.
+func (e *Entry) Final() bool {
+	return e.Status == MinedStatus || e.Status == ReplacedStatus || e.Status == ExpiredStatus
+}
+
+// Queue persists accepted transactions and rebroadcasts
+// them (see Run) until they are mined, so broadcasts
+// that fail transiently survive process restarts.
+type Queue struct {
+	db     ethdb.KeyValueStore
+	client Client
+	expiry time.Duration
+	now    func() time.Time
+
+	// The queue is used by a single process, so a
+	// single lock is enough to serialize updates.
+	mutex sync.Mutex
+}
+
+// NewQueue creates a *Queue backed by db.
+func NewQueue(db ethdb.KeyValueStore, client Client, expiry time.Duration) *Queue {
+	return &Queue{
+		db:     db,
+		client: client,
+		expiry: expiry,
+		now:    time.Now,
+	}
+}
+
+// OpenQueue creates a *Queue persisted in a
+// leveldb database at path.
+func OpenQueue(path string, client Client, expiry time.Duration) (*Queue, error) {
+	db, err := leveldb.New(path, databaseCache, databaseHandles, "", false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to open submit queue database %s", err, path)
+	}
+
+	return NewQueue(db, client, expiry), nil
+}
+
+// OpenQueueReadOnly opens the queue persisted at path
+// without writing to it. The returned *Queue has no
+// client, so it cannot be run.
+func OpenQueueReadOnly(path string) (*Queue, error) {
+	db, err := leveldb.New(path, databaseCache, databaseHandles, "", true)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to open submit queue database %s", err, path)
+	}
+
+	return NewQueue(db, nil, 0), nil
+}
+
+// Close closes the underlying database.
+func (q *Queue) Close() error {
+	return q.db.Close()
+}
+
+// Enqueue persists tx so that it is rebroadcast until it is
+// mined. The queue does not broadcast tx until minBackoff has
+// passed, so the caller can broadcast it first and report the
+// outcome with RecordAttempt. Enqueueing a transaction that is
+// already queued does nothing.
+func (q *Queue) Enqueue(tx *ethTypes.Transaction) error {
+	sender, err := ethTypes.Sender(ethTypes.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return fmt.Errorf("%w: unable to recover sender", err)
+	}
+
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("%w: unable to encode transaction", err)
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	existing, err := q.get(tx.Hash())
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return nil
+	}
+
+	now := q.now()
+	return q.put(&Entry{
+		TransactionHash: tx.Hash().Hex(),
+		Transaction:     raw,
+		From:            sender.Hex(),
+		Nonce:           tx.Nonce(),
+		Status:          QueuedStatus,
+		CreatedAt:       now.Unix(),
+		UpdatedAt:       now.Unix(),
+		NextAttemptAt:   now.Add(minBackoff).Unix(),
+	})
+}
+
+// RecordAttempt records the outcome of a broadcast of the
+// transaction with hash. A nil err means the node accepted it.
+func (q *Queue) RecordAttempt(hash common.Hash, err error) error {
+	return q.update(hash, func(entry *Entry) {
+		q.attempted(entry, err)
+	})
+}
+
+// Remove stops tracking the transaction with hash.
+func (q *Queue) Remove(hash common.Hash) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.db.Delete(key(hash))
+}
+
+// Status returns the entry of the transaction with hash.
+// If the transaction is not queued, ErrNotFound is returned.
+func (q *Queue) Status(hash common.Hash) (*Entry, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	entry, err := q.get(hash)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, hash.Hex())
+	}
+
+	return entry, nil
+}
+
+// Run tracks and rebroadcasts queued transactions
+// until ctx is canceled.
+func (q *Queue) Run(ctx context.Context) error {
+	for {
+		if err := q.process(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("submit queue processing failed: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// process checks every transaction that is due and
+// deletes final entries older than the expiry.
+func (q *Queue) process(ctx context.Context) error {
+	due, err := q.due()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err := q.check(ctx, entry); err != nil {
+			log.Printf("unable to check queued transaction %s: %s", entry.TransactionHash, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// due returns the entries that must be checked and
+// deletes final entries older than the expiry.
+func (q *Queue) due() ([]*Entry, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	now := q.now()
+	due := []*Entry{}
+	size := int64(0)
+
+	iterator := q.db.NewIterator([]byte(keyPrefix), nil)
+	defer iterator.Release()
+	for iterator.Next() {
+		var entry Entry
+		if err := json.Unmarshal(iterator.Value(), &entry); err != nil {
+			return nil, fmt.Errorf("%w: unable to decode queued transaction", err)
+		}
+
+		if entry.Final() {
+			if now.Sub(time.Unix(entry.UpdatedAt, 0)) > q.expiry {
+				if err := q.db.Delete(append([]byte{}, iterator.Key()...)); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		size++
+		if entry.NextAttemptAt <= now.Unix() {
+			due = append(due, &entry)
+		}
+	}
+	if err := iterator.Error(); err != nil {
+		return nil, err
+	}
+	metrics.Gauge(sizeMetric).Update(size)
+
+	return due, nil
+}
+
+// check updates the status of entry and rebroadcasts it if
+// the node does not know it. If the node rejects the
+// rebroadcast (see rejected), entry is removed.
+func (q *Queue) check(ctx context.Context, entry *Entry) error {
+	status, err := q.status(ctx, entry)
+	if err != nil {
+		return err
+	}
+
+	hash := common.HexToHash(entry.TransactionHash)
+	switch {
+	case status.Status == ethereum.MinedTransactionStatus:
+		return q.update(hash, func(e *Entry) {
+			e.Status = MinedStatus
+			e.BlockIdentifier = status.BlockIdentifier
+		})
+	case status.Replaced:
+		return q.update(hash, func(e *Entry) {
+			e.Status = ReplacedStatus
+		})
+	case q.now().Sub(time.Unix(entry.CreatedAt, 0)) > q.expiry:
+		return q.update(hash, func(e *Entry) {
+			e.Status = ExpiredStatus
+		})
+	case status.Status == ethereum.PendingTransactionStatus:
+		return q.update(hash, func(e *Entry) {
+			e.Status = PendingStatus
+			e.NextAttemptAt = q.now().Add(checkInterval).Unix()
+		})
+	}
+
+	var tx ethTypes.Transaction
+	if err := tx.UnmarshalBinary(entry.Transaction); err != nil {
+		return fmt.Errorf("%w: unable to decode transaction", err)
+	}
+
+	sendErr := q.client.SendTransaction(ctx, &tx)
+	if sendErr != nil {
+		log.Printf("unable to rebroadcast transaction %s: %s", entry.TransactionHash, sendErr.Error())
+		if rejected(sendErr) {
+			return q.Remove(hash)
+		}
+	}
+
+	return q.RecordAttempt(hash, sendErr)
+}
+
+// status returns the ethereum.TransactionStatus of entry.
+func (q *Queue) status(ctx context.Context, entry *Entry) (*ethereum.TransactionStatus, error) {
+	params, err := types.MarshalMap(&ethereum.TransactionStatusInput{
+		TxHash: entry.TransactionHash,
+		From:   entry.From,
+		Nonce:  &entry.Nonce,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := q.client.Call(ctx, &types.CallRequest{
+		Method:     ethereum.TransactionStatusMethod,
+		Parameters: params,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get transaction status", err)
+	}
+
+	var status ethereum.TransactionStatus
+	if err := types.UnmarshalMap(response.Result, &status); err != nil {
+		return nil, fmt.Errorf("%w: unable to decode transaction status", err)
+	}
+
+	return &status, nil
+}
+
+// attempted records a broadcast of entry that failed with
+// err (or succeeded if err is nil). A transaction the node
+// already knows is in its mempool.
+func (q *Queue) attempted(entry *Entry, err error) {
+	now := q.now()
+	entry.Attempts++
+
+	if err == nil || strings.Contains(strings.ToLower(err.Error()), core.ErrAlreadyKnown.Error()) {
+		entry.Status = PendingStatus
+		entry.LastError = ""
+		entry.NextAttemptAt = now.Add(checkInterval).Unix()
+		return
+	}
+
+	entry.LastError = err.Error()
+	entry.NextAttemptAt = now.Add(backoff(entry.Attempts)).Unix()
+}
+
+// rejected returns true if err is a broadcast
+// error that a rebroadcast cannot fix.
+func rejected(err error) bool {
+	message := strings.ToLower(err.Error())
+	for _, rejectedErr := range rejectedErrors {
+		if strings.Contains(message, rejectedErr.Error()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// update applies fn to the entry of the transaction with
+// hash, if it is still queued.
+func (q *Queue) update(hash common.Hash, fn func(*Entry)) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	entry, err := q.get(hash)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return nil
+	}
+
+	fn(entry)
+	entry.UpdatedAt = q.now().Unix()
+	return q.put(entry)
+}
+
+func (q *Queue) get(hash common.Hash) (*Entry, error) {
+	has, err := q.db.Has(key(hash))
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, nil
+	}
+
+	value, err := q.db.Get(key(hash))
+	if err != nil {
+		return nil, err
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(value, &entry); err != nil {
+		return nil, fmt.Errorf("%w: unable to decode queued transaction", err)
+	}
+
+	return &entry, nil
+}
+
+func (q *Queue) put(entry *Entry) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := q.db.Put(key(common.HexToHash(entry.TransactionHash)), value); err != nil {
+		return fmt.Errorf("%w: unable to persist transaction %s", err, entry.TransactionHash)
+	}
+
+	return nil
+}
+
+// backoff returns the delay before the next broadcast
+// of a transaction that was broadcast attempts times.
+func backoff(attempts int64) time.Duration {
+	delay := minBackoff
+	for i := int64(1); i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+
+	return delay
+}
+
+func key(hash common.Hash) []byte {
+	return append([]byte(keyPrefix), hash.Bytes()...)
+}
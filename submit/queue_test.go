@@ -0,0 +1,368 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package submit
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coinbase/rosetta-ethereum/ethereum"
+	mocks "github.com/coinbase/rosetta-ethereum/mocks/submit"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func signedTransaction(t *testing.T, nonce uint64) (*ethTypes.Transaction, common.Address) {
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+
+	tx, err := ethTypes.SignTx(
+		ethTypes.NewTransaction(
+			nonce,
+			common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d"),
+			big.NewInt(1000),
+			21000,
+			big.NewInt(1),
+			nil,
+		),
+		ethTypes.NewEIP155Signer(params.RopstenChainConfig.ChainID),
+		key,
+	)
+	assert.NoError(t, err)
+
+	return tx, crypto.PubkeyToAddress(key.PublicKey)
+}
+
+// mockStatus makes mockClient report status for tx once.
+func mockStatus(
+	t *testing.T,
+	mockClient *mocks.Client,
+	tx *ethTypes.Transaction,
+	sender common.Address,
+	status *ethereum.TransactionStatus,
+) {
+	params, err := types.MarshalMap(&ethereum.TransactionStatusInput{
+		TxHash: tx.Hash().Hex(),
+		From:   sender.Hex(),
+		Nonce:  func() *uint64 { n := tx.Nonce(); return &n }(),
+	})
+	assert.NoError(t, err)
+
+	result, err := types.MarshalMap(status)
+	assert.NoError(t, err)
+
+	mockClient.On(
+		"Call",
+		mock.Anything,
+		&types.CallRequest{
+			Method:     ethereum.TransactionStatusMethod,
+			Parameters: params,
+		},
+	).Return(&types.CallResponse{Result: result}, nil).Once()
+}
+
+func TestQueue_Enqueue(t *testing.T) {
+	queue := NewQueue(memorydb.New(), &mocks.Client{}, DefaultExpiry)
+	now := time.Unix(1600000000, 0)
+	queue.now = func() time.Time { return now }
+
+	tx, sender := signedTransaction(t, 3)
+	assert.NoError(t, queue.Enqueue(tx))
+
+	entry, err := queue.Status(tx.Hash())
+	assert.NoError(t, err)
+	raw, err := tx.MarshalBinary()
+	assert.NoError(t, err)
+	assert.Equal(t, &Entry{
+		TransactionHash: tx.Hash().Hex(),
+		Transaction:     raw,
+		From:            sender.Hex(),
+		Nonce:           3,
+		Status:          QueuedStatus,
+		CreatedAt:       now.Unix(),
+		UpdatedAt:       now.Unix(),
+		NextAttemptAt:   now.Add(minBackoff).Unix(),
+	}, entry)
+
+	// A failed broadcast is retried with backoff
+	assert.NoError(t, queue.RecordAttempt(tx.Hash(), errors.New("connection refused")))
+	entry, err = queue.Status(tx.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, QueuedStatus, entry.Status)
+	assert.Equal(t, int64(1), entry.Attempts)
+	assert.Equal(t, "connection refused", entry.LastError)
+
+	// Enqueueing the transaction again does not reset it
+	assert.NoError(t, queue.Enqueue(tx))
+	entry, err = queue.Status(tx.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), entry.Attempts)
+
+	// A transaction the node already knows is pending
+	assert.NoError(t, queue.RecordAttempt(tx.Hash(), errors.New("already known")))
+	entry, err = queue.Status(tx.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, PendingStatus, entry.Status)
+	assert.Equal(t, int64(2), entry.Attempts)
+	assert.Empty(t, entry.LastError)
+	assert.Equal(t, now.Add(checkInterval).Unix(), entry.NextAttemptAt)
+
+	assert.NoError(t, queue.Remove(tx.Hash()))
+	entry, err = queue.Status(tx.Hash())
+	assert.Nil(t, entry)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestQueue_Process(t *testing.T) {
+	ctx := context.Background()
+	mockClient := &mocks.Client{}
+	queue := NewQueue(memorydb.New(), mockClient, DefaultExpiry)
+	now := time.Unix(1600000000, 0)
+	queue.now = func() time.Time { return now }
+
+	tx, sender := signedTransaction(t, 0)
+	assert.NoError(t, queue.Enqueue(tx))
+	assert.NoError(t, queue.RecordAttempt(tx.Hash(), errors.New("connection refused")))
+
+	// Nothing is due before the backoff
+	assert.NoError(t, queue.process(ctx))
+
+	// The node does not know the transaction,
+	// so it is rebroadcast
+	now = now.Add(minBackoff)
+	mockStatus(t, mockClient, tx, sender, &ethereum.TransactionStatus{
+		TransactionHash: tx.Hash().Hex(),
+		Status:          ethereum.DroppedTransactionStatus,
+	})
+	mockClient.On("SendTransaction", mock.Anything, mock.Anything).Return(errors.New("timeout")).Once()
+	assert.NoError(t, queue.process(ctx))
+	entry, err := queue.Status(tx.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, QueuedStatus, entry.Status)
+	assert.Equal(t, int64(2), entry.Attempts)
+	assert.Equal(t, "timeout", entry.LastError)
+	assert.Equal(t, now.Add(2*minBackoff).Unix(), entry.NextAttemptAt)
+
+	now = now.Add(2 * minBackoff)
+	mockStatus(t, mockClient, tx, sender, &ethereum.TransactionStatus{
+		TransactionHash: tx.Hash().Hex(),
+		Status:          ethereum.DroppedTransactionStatus,
+	})
+	mockClient.On(
+		"SendTransaction",
+		mock.Anything,
+		mock.MatchedBy(func(sent *ethTypes.Transaction) bool { return sent.Hash() == tx.Hash() }),
+	).Return(nil).Once()
+	assert.NoError(t, queue.process(ctx))
+	entry, err = queue.Status(tx.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, PendingStatus, entry.Status)
+	assert.Equal(t, int64(3), entry.Attempts)
+
+	// The transaction is seen in the mempool
+	now = now.Add(checkInterval)
+	mockStatus(t, mockClient, tx, sender, &ethereum.TransactionStatus{
+		TransactionHash: tx.Hash().Hex(),
+		Status:          ethereum.PendingTransactionStatus,
+	})
+	assert.NoError(t, queue.process(ctx))
+	entry, err = queue.Status(tx.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, PendingStatus, entry.Status)
+	assert.Equal(t, int64(3), entry.Attempts)
+
+	// The transaction is mined
+	now = now.Add(checkInterval)
+	block := &types.BlockIdentifier{Index: 10, Hash: "0xabc"}
+	mockStatus(t, mockClient, tx, sender, &ethereum.TransactionStatus{
+		TransactionHash: tx.Hash().Hex(),
+		Status:          ethereum.MinedTransactionStatus,
+		BlockIdentifier: block,
+	})
+	assert.NoError(t, queue.process(ctx))
+	entry, err = queue.Status(tx.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, MinedStatus, entry.Status)
+	assert.Equal(t, block, entry.BlockIdentifier)
+
+	// Mined transactions are no longer checked and
+	// are deleted after the expiry
+	now = now.Add(checkInterval)
+	assert.NoError(t, queue.process(ctx))
+	now = now.Add(DefaultExpiry + time.Second)
+	assert.NoError(t, queue.process(ctx))
+	_, err = queue.Status(tx.Hash())
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestQueue_Enqueue_DynamicFee(t *testing.T) {
+	queue := NewQueue(memorydb.New(), &mocks.Client{}, DefaultExpiry)
+
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	recipient := common.HexToAddress("0x57B414a0332B5CaB885a451c2a28a07d1e9b8a8d")
+	tx, err := ethTypes.SignNewTx(
+		key,
+		ethTypes.LatestSignerForChainID(params.RopstenChainConfig.ChainID),
+		&ethTypes.DynamicFeeTx{
+			ChainID:   params.RopstenChainConfig.ChainID,
+			Nonce:     7,
+			GasTipCap: big.NewInt(1),
+			GasFeeCap: big.NewInt(2),
+			Gas:       21000,
+			To:        &recipient,
+			Value:     big.NewInt(1000),
+		},
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, queue.Enqueue(tx))
+
+	entry, err := queue.Status(tx.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(key.PublicKey).Hex(), entry.From)
+	assert.Equal(t, uint64(7), entry.Nonce)
+
+	var decoded ethTypes.Transaction
+	assert.NoError(t, decoded.UnmarshalBinary(entry.Transaction))
+	assert.Equal(t, tx.Hash(), decoded.Hash())
+}
+
+func TestQueue_Process_Rejected(t *testing.T) {
+	ctx := context.Background()
+	mockClient := &mocks.Client{}
+	queue := NewQueue(memorydb.New(), mockClient, DefaultExpiry)
+	now := time.Unix(1600000000, 0)
+	queue.now = func() time.Time { return now }
+
+	tx, sender := signedTransaction(t, 0)
+	assert.NoError(t, queue.Enqueue(tx))
+
+	// A rebroadcast the node rejects is not retried
+	now = now.Add(minBackoff)
+	mockStatus(t, mockClient, tx, sender, &ethereum.TransactionStatus{
+		TransactionHash: tx.Hash().Hex(),
+		Status:          ethereum.DroppedTransactionStatus,
+	})
+	mockClient.On("SendTransaction", mock.Anything, mock.Anything).Return(errors.New("nonce too low")).Once()
+	assert.NoError(t, queue.process(ctx))
+	_, err := queue.Status(tx.Hash())
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestQueue_Process_Final(t *testing.T) {
+	ctx := context.Background()
+	mockClient := &mocks.Client{}
+	queue := NewQueue(memorydb.New(), mockClient, DefaultExpiry)
+	now := time.Unix(1600000000, 0)
+	queue.now = func() time.Time { return now }
+
+	replaced, replacedSender := signedTransaction(t, 0)
+	assert.NoError(t, queue.Enqueue(replaced))
+	expired, expiredSender := signedTransaction(t, 0)
+	assert.NoError(t, queue.Enqueue(expired))
+
+	now = now.Add(DefaultExpiry + time.Second)
+	mockStatus(t, mockClient, replaced, replacedSender, &ethereum.TransactionStatus{
+		TransactionHash: replaced.Hash().Hex(),
+		Status:          ethereum.DroppedTransactionStatus,
+		Replaced:        true,
+	})
+	mockStatus(t, mockClient, expired, expiredSender, &ethereum.TransactionStatus{
+		TransactionHash: expired.Hash().Hex(),
+		Status:          ethereum.DroppedTransactionStatus,
+	})
+	assert.NoError(t, queue.process(ctx))
+
+	entry, err := queue.Status(replaced.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, ReplacedStatus, entry.Status)
+	entry, err = queue.Status(expired.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, ExpiredStatus, entry.Status)
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestOpenQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "submit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "queue")
+	queue, err := OpenQueue(path, &mocks.Client{}, DefaultExpiry)
+	assert.NoError(t, err)
+
+	tx, _ := signedTransaction(t, 0)
+	assert.NoError(t, queue.Enqueue(tx))
+	assert.NoError(t, queue.Close())
+
+	// Queued transactions survive a restart
+	queue, err = OpenQueue(path, &mocks.Client{}, DefaultExpiry)
+	assert.NoError(t, err)
+	defer queue.Close()
+
+	entry, err := queue.Status(tx.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, QueuedStatus, entry.Status)
+}
+
+func TestOpenQueueReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "submit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "queue")
+	queue, err := OpenQueue(path, &mocks.Client{}, DefaultExpiry)
+	assert.NoError(t, err)
+
+	tx, _ := signedTransaction(t, 0)
+	assert.NoError(t, queue.Enqueue(tx))
+	assert.NoError(t, queue.Close())
+
+	queue, err = OpenQueueReadOnly(path)
+	assert.NoError(t, err)
+	defer queue.Close()
+
+	entry, err := queue.Status(tx.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, QueuedStatus, entry.Status)
+	assert.Error(t, queue.Remove(tx.Hash()))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, minBackoff, backoff(0))
+	assert.Equal(t, minBackoff, backoff(1))
+	assert.Equal(t, 2*minBackoff, backoff(2))
+	assert.Equal(t, 4*minBackoff, backoff(3))
+	assert.Equal(t, maxBackoff, backoff(10))
+	assert.Equal(t, maxBackoff, backoff(1000))
+}
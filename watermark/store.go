@@ -0,0 +1,200 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package watermark persists how far rosetta-core has progressed,
+// so that orchestration tooling can make restart and rollback
+// decisions without scraping logs.
+package watermark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+// flushInterval is how often updated watermarks are
+// written to disk. Watermarks are also written when
+// the store stops running.
+const flushInterval = time.Second
+
+// Watermarks are the persisted watermarks.
+type Watermarks struct {
+	// ServedBlock is the highest block that was
+	// served (after it was validated) by /block.
+	ServedBlock *types.BlockIdentifier `json:"served_block,omitempty"`
+
+	// ReorgDepth is the number of blocks removed
+	// by the last observed reorg and ReorgBlock is
+	// the first block added by it.
+	ReorgDepth int64                  `json:"last_reorg_depth"`
+	ReorgBlock *types.BlockIdentifier `json:"last_reorg_block,omitempty"`
+
+	// UpdatedAt is the time of the last update, in
+	// milliseconds since the Unix Epoch.
+	UpdatedAt int64 `json:"updated_at,omitempty"`
+}
+
+// Store tracks the Watermarks and persists them
+// in a JSON file.
+type Store struct {
+	path string
+	now  func() time.Time
+
+	mutex      sync.Mutex
+	watermarks Watermarks
+	dirty      bool
+
+	// removed is the number of consecutive
+	// block_removed events observed so far.
+	removed int64
+}
+
+// Open creates a *Store persisted at path, loading
+// the watermarks persisted by a previous run.
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path: path,
+		now:  time.Now,
+	}
+
+	body, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to read watermarks %s", err, path)
+	}
+
+	if err := json.Unmarshal(body, &s.watermarks); err != nil {
+		return nil, fmt.Errorf("%w: unable to decode watermarks %s", err, path)
+	}
+
+	return s, nil
+}
+
+// Watermarks returns the current watermarks.
+func (s *Store) Watermarks() Watermarks {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.watermarks
+}
+
+// Served records that block was served. Only
+// blocks higher than the current watermark
+// advance it.
+func (s *Store) Served(block *types.BlockIdentifier) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.watermarks.ServedBlock != nil && block.Index <= s.watermarks.ServedBlock.Index {
+		return
+	}
+
+	s.watermarks.ServedBlock = block
+	s.update()
+}
+
+// Observe records a head event. A reorg is observed
+// when blocks are added after blocks were removed.
+func (s *Store) Observe(event *types.BlockEvent) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if event.Type == types.REMOVED {
+		s.removed++
+		return
+	}
+
+	if s.removed == 0 {
+		return
+	}
+
+	s.watermarks.ReorgDepth = s.removed
+	s.watermarks.ReorgBlock = event.BlockIdentifier
+	s.removed = 0
+	s.update()
+}
+
+func (s *Store) update() {
+	s.watermarks.UpdatedAt = s.now().UnixNano() / int64(time.Millisecond)
+	s.dirty = true
+}
+
+// Flush writes the watermarks to disk if they were
+// updated. They are written to a temporary file first,
+// so a crash never leaves partial watermarks behind.
+func (s *Store) Flush() error {
+	s.mutex.Lock()
+	if !s.dirty {
+		s.mutex.Unlock()
+		return nil
+	}
+	watermarks := s.watermarks
+	s.dirty = false
+	s.mutex.Unlock()
+
+	body, err := json.Marshal(&watermarks)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), os.ModePerm); err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, body, 0600); err != nil { // nolint:gomnd
+		s.markDirty()
+		return err
+	}
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		s.markDirty()
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) markDirty() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.dirty = true
+}
+
+// Run flushes the watermarks every flushInterval
+// until ctx is done, and once more before returning.
+func (s *Store) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return s.Flush()
+		case <-time.After(flushInterval):
+		}
+
+		if err := s.Flush(); err != nil {
+			log.Printf("unable to persist watermarks: %s", err.Error())
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2020 Coinbase, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watermark
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func block(index int64, hash string) *types.BlockIdentifier {
+	return &types.BlockIdentifier{Index: index, Hash: hash}
+}
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watermark")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "watermarks", "watermarks.json")
+	store, err := Open(path)
+	assert.NoError(t, err)
+	store.now = func() time.Time { return time.Unix(1600000000, 0) }
+	assert.Equal(t, Watermarks{}, store.Watermarks())
+
+	// Nothing is written until a watermark is updated
+	assert.NoError(t, store.Flush())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	// Only higher blocks advance the watermark
+	store.Served(block(10, "0xa"))
+	store.Served(block(5, "0xb"))
+	assert.Equal(t, block(10, "0xa"), store.Watermarks().ServedBlock)
+
+	// Blocks added without removals are not reorgs
+	store.Observe(&types.BlockEvent{Type: types.ADDED, BlockIdentifier: block(11, "0xc")})
+	assert.Equal(t, int64(0), store.Watermarks().ReorgDepth)
+
+	// The depth of a reorg is the number of removed blocks
+	store.Observe(&types.BlockEvent{Type: types.REMOVED, BlockIdentifier: block(11, "0xc")})
+	store.Observe(&types.BlockEvent{Type: types.REMOVED, BlockIdentifier: block(10, "0xa")})
+	store.Observe(&types.BlockEvent{Type: types.ADDED, BlockIdentifier: block(10, "0xd")})
+	store.Observe(&types.BlockEvent{Type: types.ADDED, BlockIdentifier: block(11, "0xe")})
+	assert.Equal(t, Watermarks{
+		ServedBlock: block(10, "0xa"),
+		ReorgDepth:  2,
+		ReorgBlock:  block(10, "0xd"),
+		UpdatedAt:   1600000000000,
+	}, store.Watermarks())
+
+	// Watermarks are loaded after a restart
+	assert.NoError(t, store.Flush())
+	restarted, err := Open(path)
+	assert.NoError(t, err)
+	assert.Equal(t, store.Watermarks(), restarted.Watermarks())
+}
+
+func TestStore_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watermark")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "watermarks.json")
+	store, err := Open(path)
+	assert.NoError(t, err)
+
+	// Watermarks are written when the store stops
+	ctx, cancel := context.WithCancel(context.Background())
+	store.Served(block(10, "0xa"))
+	cancel()
+	assert.NoError(t, store.Run(ctx))
+
+	restarted, err := Open(path)
+	assert.NoError(t, err)
+	assert.Equal(t, block(10, "0xa"), restarted.Watermarks().ServedBlock)
+}
+
+func TestOpen_Invalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watermark")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "watermarks.json")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("{"), 0600))
+
+	store, err := Open(path)
+	assert.Nil(t, store)
+	assert.Contains(t, err.Error(), "unable to decode watermarks")
+}